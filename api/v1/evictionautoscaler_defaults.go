@@ -0,0 +1,80 @@
+package v1
+
+import (
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+const (
+	// StrategySurge unblocks evictions by adding replicas to the target, unless that can't unblock them.
+	StrategySurge = "Surge"
+	// StrategySurgeAlways adds replicas even when they can't unblock the eviction.
+	StrategySurgeAlways = "SurgeAlways"
+)
+
+// spec.hpaPolicy values.
+const (
+	// HPAPolicySkip leaves targets scaled by an HPA alone.
+	HPAPolicySkip = "Skip"
+	// HPAPolicyAdjustMinReplicas surges targets scaled by an HPA through the HPA's minReplicas.
+	HPAPolicyAdjustMinReplicas = "AdjustMinReplicas"
+)
+
+// spec.pausedPolicy values.
+const (
+	// PausedPolicySkip doesn't surge paused targets.
+	PausedPolicySkip = "Skip"
+	// PausedPolicyDefer surges paused targets once they are unpaused.
+	PausedPolicyDefer = "Defer"
+)
+
+// spec.surgePolicy values.
+const (
+	// SurgePolicyStep surges by spec.surge, or enough for the pdb to allow one disruption.
+	SurgePolicyStep = "Step"
+	// SurgePolicyPDBGap surges enough for the pdb to allow a disruption for every pod of it on a draining node.
+	SurgePolicyPDBGap = "PDBGap"
+)
+
+// spec.scaleDownPolicy values.
+const (
+	// ScaleDownPolicyAll restores the baseline in one write.
+	ScaleDownPolicyAll = "All"
+	// ScaleDownPolicyStepped takes spec.surge replicas off per cooldown.
+	ScaleDownPolicyStepped = "Stepped"
+)
+
+// DefaultSurge is the surge when spec.surge is unset.
+var DefaultSurge = intstr.FromInt32(1)
+
+// SetStaticDefaults fills in unset spec fields with what the controller assumes for them.
+// The defaulting webhook persists these on create and the reconcilers apply them in memory
+// so EvictionAutoScalers created without the webhook behave the same. spec.cooldownSeconds, spec.surge and
+// spec.surgePolicy are left unset since their defaults come from the controller's flags and EvictionAutoScalerConfig,
+// which can change after the EvictionAutoScaler is created.
+func (in *EvictionAutoScaler) SetStaticDefaults() {
+	spec := &in.Spec
+	if spec.ScaleDownPolicy == "" {
+		spec.ScaleDownPolicy = ScaleDownPolicyAll
+	}
+	if spec.Strategy == "" {
+		spec.Strategy = StrategySurge
+	}
+	if spec.HPAPolicy == "" {
+		spec.HPAPolicy = HPAPolicySkip
+	}
+	if spec.PausedPolicy == "" {
+		spec.PausedPolicy = PausedPolicySkip
+	}
+	if spec.TargetPDBName == "" && spec.PodSelector == nil {
+		spec.TargetPDBName = in.Name
+	}
+}
+
+// PDBName returns the name of the pdb the EvictionAutoScaler acts on, spec.targetPDBName or its own name
+// if that is unset. Use it rather than SetStaticDefaults where the spec is read back undefaulted.
+func (in *EvictionAutoScaler) PDBName() string {
+	if in.Spec.TargetPDBName != "" {
+		return in.Spec.TargetPDBName
+	}
+	return in.Name
+}
@@ -2,32 +2,302 @@ package v1
 
 import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
 )
 
 // EvictionLog defines a log entry for pod evictions
 type Eviction struct {
 	PodName      string      `json:"podName,omitempty"`
 	EvictionTime metav1.Time `json:"evictionTime,omitempty"`
+	// Source is what signaled the eviction. Empty for evictions recorded before it was tracked.
+	// +optional
+	Source EvictionSource `json:"source,omitempty"`
+	// Node the pod was on, if known.
+	// +optional
+	Node string `json:"node,omitempty"`
+}
+
+// EvictionSource is what anticipated an eviction.
+// +kubebuilder:validation:Enum=NodeCordon;EvictionAPI;Manual
+type EvictionSource string
+
+const (
+	// EvictionSourceNodeCordon is the node controller seeing a pod on a node it drains for, cordoned or otherwise.
+	EvictionSourceNodeCordon EvictionSource = "NodeCordon"
+	// EvictionSourceEvictionAPI is the eviction webhook seeing an eviction request.
+	EvictionSourceEvictionAPI EvictionSource = "EvictionAPI"
+	// EvictionSourceManual is anything else writing the eviction, by hand or through the deprecated spec.lastEviction.
+	EvictionSourceManual EvictionSource = "Manual"
+)
+
+// MaxRecentEvictions is how many entries status.recentEvictions keeps before dropping the oldest.
+const MaxRecentEvictions = 20
+
+// EvictionRecord is one entry of status.recentEvictions.
+type EvictionRecord struct {
+	PodName string `json:"podName"`
+	// Node the pod was on, if known.
+	// +optional
+	Node         string         `json:"node,omitempty"`
+	EvictionTime metav1.Time    `json:"evictionTime"`
+	Source       EvictionSource `json:"source"`
+	// Expired is set once the eviction went past spec.evictionTTLSeconds with its pod still running,
+	// or its node was uncordoned before the drain got to it.
+	// +optional
+	Expired bool `json:"expired,omitempty"`
+}
+
+// Eviction is what status.lastEviction holds for the record.
+func (r EvictionRecord) Eviction() Eviction {
+	return Eviction{PodName: r.PodName, EvictionTime: r.EvictionTime, Source: r.Source, Node: r.Node}
+}
+
+// Matches reports whether eviction is the record's, by pod and time alone since one recorded before
+// Eviction had a source and node has neither.
+func (r EvictionRecord) Matches(eviction Eviction) bool {
+	return r.PodName == eviction.PodName && r.EvictionTime.Equal(&eviction.EvictionTime)
+}
+
+// TargetReference mirrors the HorizontalPodAutoscaler's scaleTargetRef.
+type TargetReference struct {
+	// APIVersion of the target, e.g. apps/v1 or argoproj.io/v1alpha1
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	Name       string `json:"name"`
 }
 
 // EvictionAutoScalerSpec defines the desired state of EvictionAutoScaler
+// +kubebuilder:validation:XValidation:rule="!has(self.minReplicas) || !has(self.maxReplicas) || self.minReplicas <= self.maxReplicas",message="minReplicas must not be greater than maxReplicas"
 type EvictionAutoScalerSpec struct {
-	//todo make this mirror horizontalpodautoscaler's target reference
-	TargetName   string   `json:"targetName"`
-	TargetKind   string   `json:"targetKind"` //deployment or statefulset (anything with an update statedgy)
+	// TargetName and TargetKind pick a deployment, statefulset or replicaset no deployment owns. Ignored when TargetRef is set.
+	// Left unset, the deployment or statefulset whose pod template the pdb selects is used, see status.resolvedTarget.
+	// +optional
+	TargetName string `json:"targetName"`
+	// +optional
+	TargetKind string `json:"targetKind"` //deployment, statefulset or replicaset
+	// TargetRef points at any workload exposing the scale subresource (Argo Rollouts, CloneSets, custom operators).
+	// It is scaled through /scale and takes precedence over TargetName and TargetKind.
+	// +optional
+	TargetRef *TargetReference `json:"targetRef,omitempty"`
+	// TargetPDBName is the pdb whose blocked evictions are surged for, for pdbs named by a chart you don't control.
+	// Unset uses the EvictionAutoScaler's own name. No two EvictionAutoScalers in a namespace may point at the same pdb.
+	// +optional
+	TargetPDBName string `json:"targetPDBName,omitempty"`
+	// PodSelector matches pods directly, for workloads with no pdb at all. They are treated as always blocked:
+	// every anticipated eviction surges one more replica, still capped by maxReplicas. TargetPDBName is ignored when set.
+	// A pod also selected by an EvictionAutoScaler going through a pdb is left to that one.
+	// +optional
+	PodSelector *metav1.LabelSelector `json:"podSelector,omitempty"`
+	// Deprecated: LastEviction is observed state and now lives in status.lastEviction.
+	// It is still read (and migrated to status) for one release so older writers keep working.
 	LastEviction Eviction `json:"lastEviction,omitempty"`
+	// CooldownSeconds is how long to wait after the last eviction before scaling back down.
+	// It takes precedence over the controller's --cooldown, and zero or unset uses that.
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	CooldownSeconds *int32 `json:"cooldownSeconds,omitempty"`
+	// MaxReplicas caps how far the target is surged. It must not be lower than the target's baseline replicas.
+	// Unset means no cap.
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	MaxReplicas *int32 `json:"maxReplicas,omitempty"`
+	// MinReplicas is the floor the target is never scaled down below after a surge, even if its baseline is lower.
+	// Unset means scale down to the baseline.
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	MinReplicas *int32 `json:"minReplicas,omitempty"`
+	// Surge is how many replicas to add when an eviction is blocked, a count or a percentage of current replicas rounded up like maxSurge.
+	// Unset surges by one replica. More are added if the pdb needs them to allow a disruption and maxReplicas still caps it.
+	// +optional
+	// +kubebuilder:validation:XIntOrString
+	Surge *intstr.IntOrString `json:"surge,omitempty"`
+	// SurgePolicy is how big a surge the pdb is asked for. Step, the default, adds spec.surge or enough for the pdb
+	// to allow one disruption. PDBGap adds enough, going by the pdb's status, to allow a disruption for every pod
+	// of the pdb still on a draining node, so evicting one doesn't leave the next one blocked. maxReplicas still caps it.
+	// +optional
+	// +kubebuilder:validation:Enum=Step;PDBGap
+	SurgePolicy string `json:"surgePolicy,omitempty"`
+	// ScaleDownStabilizationSeconds is how long to keep a surge after the last draining node is done,
+	// on top of the cooldown, so a node cordoned right after doesn't scale down and back up. Unset or zero doesn't wait.
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	ScaleDownStabilizationSeconds *int32 `json:"scaleDownStabilizationSeconds,omitempty"`
+	// ScaleDownPolicy is how a surge is given back. All, the default, restores the baseline in one write. Stepped takes
+	// spec.surge replicas off at a time, one step per cooldown or stabilization window if that is longer, and pauses
+	// while a step would leave the pdb fewer healthy pods than it wants. Autoscaler surges are always restored at once.
+	// +optional
+	// +kubebuilder:validation:Enum=All;Stepped
+	ScaleDownPolicy string `json:"scaleDownPolicy,omitempty"`
+	// ScaleUpIntervalSeconds is the least time between two surges of the target, counted from status.lastScaleTime,
+	// so a slow drain doesn't surge again before the last surge's pods are even scheduled. Evictions in between wait
+	// and are surged for together in one step once it is up. Unset or zero doesn't wait.
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	ScaleUpIntervalSeconds *int32 `json:"scaleUpIntervalSeconds,omitempty"`
+	// EvictionTTLSeconds is how long the last eviction may go without its pod going away before it is stale.
+	// A stale eviction no longer holds a surge for cooldown or draining nodes and is kept in status.expiredEviction.
+	// Zero or unset uses the controller's default.
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	EvictionTTLSeconds *int32 `json:"evictionTTLSeconds,omitempty"`
+	// SurgeScheduleTimeoutSeconds is how long pods a surge added may stay unschedulable before the SurgeUnschedulable condition is set.
+	// Zero or unset waits five minutes, room for a cluster autoscaler to add a node.
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	SurgeScheduleTimeoutSeconds *int32 `json:"surgeScheduleTimeoutSeconds,omitempty"`
+	// RevertUnschedulableSurge scales the target back to its baseline once its surge is unschedulable,
+	// instead of holding replicas that add no capacity. The next eviction surges again.
+	// +optional
+	RevertUnschedulableSurge bool `json:"revertUnschedulableSurge,omitempty"`
+	// MaxDrainDurationSeconds is how long a node may keep pods for the EvictionAutoScaler, from the first eviction
+	// recorded for it, before its drain is taken as not going to finish: the ExceededDrainDeadline condition is set
+	// and the node and EvictionAutoScaler get a DrainDeadlineExceeded event. Unset or zero has no deadline.
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	MaxDrainDurationSeconds *int32 `json:"maxDrainDurationSeconds,omitempty"`
+	// DrainDeadlineSurge surges one more step, still capped by maxReplicas, once a node goes past
+	// maxDrainDurationSeconds, a last attempt at unblocking it. Only once per node.
+	// +optional
+	DrainDeadlineSurge bool `json:"drainDeadlineSurge,omitempty"`
+	// Strategy is how blocked evictions are unblocked. Surge, the default, adds replicas but not to a StatefulSet
+	// that can't create them in time to help, see the SurgeIneffective condition. SurgeAlways adds them anyway.
+	// +optional
+	// +kubebuilder:validation:Enum=Surge;SurgeAlways
+	Strategy string `json:"strategy,omitempty"`
+	// HPAPolicy is what to do when a HorizontalPodAutoscaler scales the target, since it would revert a surge right away.
+	// Skip, the default, only sets the ConflictingAutoscaler condition. AdjustMinReplicas raises the HPA's minReplicas
+	// for the surge and puts it back afterwards.
+	// +optional
+	// +kubebuilder:validation:Enum=Skip;AdjustMinReplicas
+	HPAPolicy string `json:"hpaPolicy,omitempty"`
+	// PausedPolicy is what to do when the target is a paused Deployment, since scaling it adds no pods to unblock the drain.
+	// Skip, the default, doesn't surge and handles the eviction. Defer keeps the eviction and surges once the Deployment is unpaused.
+	// Either way the TargetPaused condition says the pause is what the drain is waiting on.
+	// +optional
+	// +kubebuilder:validation:Enum=Skip;Defer
+	PausedPolicy string `json:"pausedPolicy,omitempty"`
+	// KEDA looks for a KEDA ScaledObject scaling the target and surges through its minReplicaCount,
+	// since KEDA overrides replicas written to the target. Off so clusters without KEDA never look.
+	// +optional
+	KEDA bool `json:"keda,omitempty"`
+	// Suspend stops the EvictionAutoScaler acting on its target, like a CronJob's suspend, without losing its status.
+	// Evictions aren't recorded and the target isn't surged or scaled back down while set. Evictions from before
+	// it is unset are dropped instead of surged for.
+	// +optional
+	Suspend bool `json:"suspend,omitempty"`
+}
+
+// AutoscalerSurge is an HPA or ScaledObject whose minimum replicas we raised, kept in status so they are
+// restored after a controller restart.
+type AutoscalerSurge struct {
+	// Kind is HorizontalPodAutoscaler or ScaledObject.
+	Kind string `json:"kind"`
+	Name string `json:"name"`
+	// OriginalMinReplicas is the minReplicas or minReplicaCount before the surge. Unset if there was none.
+	// +optional
+	OriginalMinReplicas *int32 `json:"originalMinReplicas,omitempty"`
+}
+
+// NodeDrain is one of status.drainingNodes and when we started draining it for the EvictionAutoScaler.
+type NodeDrain struct {
+	Node      string      `json:"node"`
+	StartTime metav1.Time `json:"startTime"`
+	// DeadlineExceeded is set once the drain took longer than spec.maxDrainDurationSeconds.
+	// +optional
+	DeadlineExceeded bool `json:"deadlineExceeded,omitempty"`
+	// DeadlineSurged is set once spec.drainDeadlineSurge surged for the exceeded deadline.
+	// +optional
+	DeadlineSurged bool `json:"deadlineSurged,omitempty"`
 }
 
 // EvictionAutoScalerStatus defines the observed state of EvictionAutoScaler
 type EvictionAutoScalerStatus struct {
-	LastEviction     Eviction           `json:"lastEviction,omitempty"` //this is the last one the controller has processed.
-	MinReplicas      int32              `json:"minReplicas"`            // Minimum number of replicas to maintain
-	TargetGeneration int64              `json:"deploymentGeneration"`   // generation (spec hash) of deployment or statefulse
+	LastEviction    Eviction `json:"lastEviction,omitempty"`    // most recent eviction signaled by the node controller or webhook
+	HandledEviction Eviction `json:"handledEviction,omitempty"` //this is the last one the controller has processed.
+	// MigratedEviction is the deprecated spec.lastEviction already folded into status. Goes away with spec.lastEviction.
+	MigratedEviction Eviction `json:"migratedEviction,omitempty"`
+	// ExpiredEviction is the last eviction that went past spec.evictionTTLSeconds with its pod still running,
+	// or whose drain was aborted by uncordoning its node with no other node draining.
+	// +optional
+	ExpiredEviction  Eviction           `json:"expiredEviction,omitempty"`
+	MinReplicas      int32              `json:"minReplicas"`          // Minimum number of replicas to maintain
+	TargetGeneration int64              `json:"deploymentGeneration"` // generation (spec hash) of deployment or statefulse
 	Conditions       []metav1.Condition `json:"conditions,omitempty"`
+	// SurgeReplicas is what the target was last surged to, zero while it is at its baseline minReplicas.
+	// A target changed to any other replicas was scaled by someone else and those become the baseline.
+	// +optional
+	SurgeReplicas int32 `json:"surgeReplicas,omitempty"`
+	// ResolvedTarget is the kind/name of the workload discovered for an EvictionAutoScaler naming no target, the one
+	// Deployment or StatefulSet whose pod template labels the pdb's selector matches.
+	// +optional
+	ResolvedTarget string `json:"resolvedTarget,omitempty"`
+	// SurgedTarget is the kind/name of the workload surgeReplicas were set on, so a surge is scaled back down there
+	// even if the pdb's eviction-autoscaler.azure.com/target annotation has since named a different one.
+	// +optional
+	SurgedTarget string `json:"surgedTarget,omitempty"`
+	// Target is the kind/name of the workload being scaled, however it was named, discovered or annotated on the pdb.
+	// +optional
+	Target string `json:"target,omitempty"`
+	// Reason is the reason of the Degraded condition if there is one, else of the Ready condition.
+	// +optional
+	Reason string `json:"reason,omitempty"`
+	// BaselineReplicas mirrors minReplicas, the replicas a surge is restored to.
+	// +optional
+	BaselineReplicas int32 `json:"baselineReplicas,omitempty"`
+	// TargetReplicas is what we last left the target at, surgeReplicas during a surge and baselineReplicas otherwise.
+	// +optional
+	TargetReplicas int32 `json:"targetReplicas,omitempty"`
+	// CurrentSurge is how many replicas targetReplicas is above baselineReplicas.
+	// +optional
+	CurrentSurge int32 `json:"currentSurge,omitempty"`
+	// SurgeReady is true once the target has surgeReplicas available replicas, so the surge is serving and not only
+	// asked for. It goes back to false if replicas stop being available during the surge, a crashlooping pod say.
+	// +optional
+	SurgeReady bool `json:"surgeReady,omitempty"`
+	// ObservedGeneration is the spec generation the controller last acted on. It trails metadata.generation
+	// while a spec change, say a new maxReplicas, is still to be picked up.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// DrainingNodes are the cordoned nodes that still have pods for this EvictionAutoScaler.
+	// We don't scale back down while any are left and a node is dropped when its pods are gone or it is deleted.
+	// +optional
+	// +listType=set
+	DrainingNodes []string `json:"drainingNodes,omitempty"`
+	// NodeDrains has when each of drainingNodes started draining, for spec.maxDrainDurationSeconds.
+	// +optional
+	// +listType=map
+	// +listMapKey=node
+	NodeDrains []NodeDrain `json:"nodeDrains,omitempty"`
+	// ScaleDownBlockingNode is a cordoned or drain tainted node that still had pods the pdb selects when the surge
+	// was due to scale back down, so it was held. Empty once the surge may go.
+	// +optional
+	ScaleDownBlockingNode string `json:"scaleDownBlockingNode,omitempty"`
+	// DrainedTime is when the last of DrainingNodes was released. The stabilization window starts from it.
+	// +optional
+	DrainedTime metav1.Time `json:"drainedTime,omitempty"`
+	// LastScaleTime is when we last surged the target, or raised its autoscaler's minimum, for spec.scaleUpIntervalSeconds.
+	// +optional
+	LastScaleTime metav1.Time `json:"lastScaleTime,omitempty"`
+	// LastScaleDownTime is when a spec.scaleDownPolicy Stepped scale down last took a step.
+	// +optional
+	LastScaleDownTime metav1.Time `json:"lastScaleDownTime,omitempty"`
+	// AutoscalerSurge is set while an HPA's minReplicas or a ScaledObject's minReplicaCount is raised.
+	// +optional
+	AutoscalerSurge *AutoscalerSurge `json:"autoscalerSurge,omitempty"`
+	// RecentEvictions are the last MaxRecentEvictions evictions, oldest first. lastEviction mirrors the newest.
+	// +optional
+	// +kubebuilder:validation:MaxItems=20
+	RecentEvictions []EvictionRecord `json:"recentEvictions,omitempty"`
 }
 
 // +kubebuilder:object:root=true
 // +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Target",type=string,JSONPath=`.status.target`
+// +kubebuilder:printcolumn:name="Baseline",type=integer,JSONPath=`.status.baselineReplicas`
+// +kubebuilder:printcolumn:name="Surge",type=integer,JSONPath=`.status.currentSurge`
+// +kubebuilder:printcolumn:name="Last Eviction",type=date,JSONPath=`.status.lastEviction.evictionTime`
+// +kubebuilder:printcolumn:name="Reason",type=string,JSONPath=`.status.reason`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
 
 // EvictionAutoScaler is the Schema for the EvictionAutoScalers API
 type EvictionAutoScaler struct {
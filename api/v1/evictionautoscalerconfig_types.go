@@ -0,0 +1,74 @@
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// EvictionAutoScalerConfigName is the name of the one EvictionAutoScalerConfig the controllers read.
+const EvictionAutoScalerConfigName = "default"
+
+// EvictionAutoScalerConfigSpec overrides the controller's flags of the same name while it runs. Unset fields keep the
+// flag's value, and the spec fields of an EvictionAutoScaler still win over the defaults here.
+type EvictionAutoScalerConfigSpec struct {
+	// CooldownSeconds is the cooldown of EvictionAutoScalers without spec.cooldownSeconds, overriding --cooldown.
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	CooldownSeconds *int32 `json:"cooldownSeconds,omitempty"`
+	// Surge is the spec.surge of EvictionAutoScalers without one.
+	// +optional
+	// +kubebuilder:validation:XIntOrString
+	Surge *intstr.IntOrString `json:"surge,omitempty"`
+	// SurgePolicy is the spec.surgePolicy of EvictionAutoScalers without one.
+	// +optional
+	// +kubebuilder:validation:Enum=Step;PDBGap
+	SurgePolicy string `json:"surgePolicy,omitempty"`
+	// NamespaceAllowlist overrides --namespace-allowlist, an empty list allows every namespace.
+	// +optional
+	// +listType=set
+	NamespaceAllowlist []string `json:"namespaceAllowlist,omitempty"`
+	// NamespaceDenylist overrides --namespace-denylist.
+	// +optional
+	// +listType=set
+	NamespaceDenylist []string `json:"namespaceDenylist,omitempty"`
+	// MaxConcurrentNodeDrains overrides --max-concurrent-node-drains, zero assists every node at once.
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	MaxConcurrentNodeDrains *int32 `json:"maxConcurrentNodeDrains,omitempty"`
+	// NodePodConcurrency overrides --node-pod-concurrency.
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	NodePodConcurrency *int32 `json:"nodePodConcurrency,omitempty"`
+	// DisablePodConditionWrites overrides --disable-pod-condition-writes.
+	// +optional
+	DisablePodConditionWrites *bool `json:"disablePodConditionWrites,omitempty"`
+	// NodeDrainProgress overrides --node-drain-progress.
+	// +optional
+	NodeDrainProgress *bool `json:"nodeDrainProgress,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:scope=Cluster
+// +kubebuilder:validation:XValidation:rule="self.metadata.name == 'default'",message="the EvictionAutoScalerConfig must be named default"
+
+// EvictionAutoScalerConfig is the cluster wide defaults of the controllers, changed without restarting them. Only the
+// one named default is read.
+type EvictionAutoScalerConfig struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec EvictionAutoScalerConfigSpec `json:"spec,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// EvictionAutoScalerConfigList contains a list of EvictionAutoScalerConfig
+type EvictionAutoScalerConfigList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []EvictionAutoScalerConfig `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&EvictionAutoScalerConfig{}, &EvictionAutoScalerConfigList{})
+}
@@ -0,0 +1,92 @@
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// NodeDrainPhase is how far along a NodeDrainProgress is.
+// +kubebuilder:validation:Enum=Draining;Complete
+type NodeDrainPhase string
+
+const (
+	// NodeDrainPhaseDraining is a node with pods still left for EvictionAutoScalers.
+	NodeDrainPhaseDraining NodeDrainPhase = "Draining"
+	// NodeDrainPhaseComplete is a node all of whose pods for EvictionAutoScalers have left while it is still draining.
+	NodeDrainPhaseComplete NodeDrainPhase = "Complete"
+)
+
+// NodeDrainProgressSpec names the node a NodeDrainProgress is for.
+type NodeDrainProgressSpec struct {
+	// NodeName is the draining node, the same as the NodeDrainProgress's name.
+	NodeName string `json:"nodeName"`
+}
+
+// NodeDrainEvictionAutoScaler is one of the EvictionAutoScalers helping a node drain.
+type NodeDrainEvictionAutoScaler struct {
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	// PodsRemaining is how many of its pods are still on the node.
+	PodsRemaining int32 `json:"podsRemaining"`
+	// CurrentSurge is the EvictionAutoScaler's status.currentSurge, how many replicas its target is surged by.
+	// +optional
+	CurrentSurge int32 `json:"currentSurge,omitempty"`
+}
+
+// NodeDrainProgressStatus is where the drain of a node is at, kept by the node controller.
+type NodeDrainProgressStatus struct {
+	// +optional
+	Phase NodeDrainPhase `json:"phase,omitempty"`
+	// Trigger is why the node counts as draining, like the trigger label of eviction_autoscaler_node_drain_triggers_total.
+	// +optional
+	Trigger string `json:"trigger,omitempty"`
+	// StartTime is when pods for an EvictionAutoScaler were first seen on the draining node.
+	// +optional
+	StartTime metav1.Time `json:"startTime,omitempty"`
+	// LastActivityTime is when a pod last left the node, or the drain started if none has.
+	// +optional
+	LastActivityTime metav1.Time `json:"lastActivityTime,omitempty"`
+	// PodsRemaining is how many pods blocked by their pdb are still on the node, over all EvictionAutoScalers.
+	PodsRemaining int32 `json:"podsRemaining"`
+	// PodsMoved is how many of them have left the node since the drain started.
+	PodsMoved int32 `json:"podsMoved"`
+	// EvictionAutoScalers are the ones with pods still on the node.
+	// +optional
+	// +listType=map
+	// +listMapKey=namespace
+	// +listMapKey=name
+	EvictionAutoScalers []NodeDrainEvictionAutoScaler `json:"evictionAutoScalers,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:scope=Cluster
+// +kubebuilder:printcolumn:name="Phase",type=string,JSONPath=`.status.phase`
+// +kubebuilder:printcolumn:name="Trigger",type=string,JSONPath=`.status.trigger`
+// +kubebuilder:printcolumn:name="Remaining",type=integer,JSONPath=`.status.podsRemaining`
+// +kubebuilder:printcolumn:name="Moved",type=integer,JSONPath=`.status.podsMoved`
+// +kubebuilder:printcolumn:name="Started",type=date,JSONPath=`.status.startTime`
+// +kubebuilder:printcolumn:name="Last Activity",type=date,JSONPath=`.status.lastActivityTime`
+
+// NodeDrainProgress is the drain of one node, named after it, as seen by the node controller. It is created once
+// pods for an EvictionAutoScaler are found on the draining node, marked Complete when they are all gone and deleted
+// when the node is uncordoned or deleted.
+type NodeDrainProgress struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   NodeDrainProgressSpec   `json:"spec,omitempty"`
+	Status NodeDrainProgressStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// NodeDrainProgressList contains a list of NodeDrainProgress
+type NodeDrainProgressList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []NodeDrainProgress `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&NodeDrainProgress{}, &NodeDrainProgressList{})
+}
@@ -19,8 +19,29 @@ package v1
 import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AutoscalerSurge) DeepCopyInto(out *AutoscalerSurge) {
+	*out = *in
+	if in.OriginalMinReplicas != nil {
+		in, out := &in.OriginalMinReplicas, &out.OriginalMinReplicas
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AutoscalerSurge.
+func (in *AutoscalerSurge) DeepCopy() *AutoscalerSurge {
+	if in == nil {
+		return nil
+	}
+	out := new(AutoscalerSurge)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Eviction) DeepCopyInto(out *Eviction) {
 	*out = *in
@@ -64,6 +85,119 @@ func (in *EvictionAutoScaler) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *EvictionAutoScalerConfig) DeepCopyInto(out *EvictionAutoScalerConfig) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EvictionAutoScalerConfig.
+func (in *EvictionAutoScalerConfig) DeepCopy() *EvictionAutoScalerConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(EvictionAutoScalerConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *EvictionAutoScalerConfig) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *EvictionAutoScalerConfigList) DeepCopyInto(out *EvictionAutoScalerConfigList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]EvictionAutoScalerConfig, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EvictionAutoScalerConfigList.
+func (in *EvictionAutoScalerConfigList) DeepCopy() *EvictionAutoScalerConfigList {
+	if in == nil {
+		return nil
+	}
+	out := new(EvictionAutoScalerConfigList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *EvictionAutoScalerConfigList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *EvictionAutoScalerConfigSpec) DeepCopyInto(out *EvictionAutoScalerConfigSpec) {
+	*out = *in
+	if in.CooldownSeconds != nil {
+		in, out := &in.CooldownSeconds, &out.CooldownSeconds
+		*out = new(int32)
+		**out = **in
+	}
+	if in.Surge != nil {
+		in, out := &in.Surge, &out.Surge
+		*out = new(intstr.IntOrString)
+		**out = **in
+	}
+	if in.NamespaceAllowlist != nil {
+		in, out := &in.NamespaceAllowlist, &out.NamespaceAllowlist
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.NamespaceDenylist != nil {
+		in, out := &in.NamespaceDenylist, &out.NamespaceDenylist
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.MaxConcurrentNodeDrains != nil {
+		in, out := &in.MaxConcurrentNodeDrains, &out.MaxConcurrentNodeDrains
+		*out = new(int32)
+		**out = **in
+	}
+	if in.NodePodConcurrency != nil {
+		in, out := &in.NodePodConcurrency, &out.NodePodConcurrency
+		*out = new(int32)
+		**out = **in
+	}
+	if in.DisablePodConditionWrites != nil {
+		in, out := &in.DisablePodConditionWrites, &out.DisablePodConditionWrites
+		*out = new(bool)
+		**out = **in
+	}
+	if in.NodeDrainProgress != nil {
+		in, out := &in.NodeDrainProgress, &out.NodeDrainProgress
+		*out = new(bool)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EvictionAutoScalerConfigSpec.
+func (in *EvictionAutoScalerConfigSpec) DeepCopy() *EvictionAutoScalerConfigSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(EvictionAutoScalerConfigSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *EvictionAutoScalerList) DeepCopyInto(out *EvictionAutoScalerList) {
 	*out = *in
@@ -99,7 +233,62 @@ func (in *EvictionAutoScalerList) DeepCopyObject() runtime.Object {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *EvictionAutoScalerSpec) DeepCopyInto(out *EvictionAutoScalerSpec) {
 	*out = *in
+	if in.TargetRef != nil {
+		in, out := &in.TargetRef, &out.TargetRef
+		*out = new(TargetReference)
+		**out = **in
+	}
+	if in.PodSelector != nil {
+		in, out := &in.PodSelector, &out.PodSelector
+		*out = new(metav1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
 	in.LastEviction.DeepCopyInto(&out.LastEviction)
+	if in.CooldownSeconds != nil {
+		in, out := &in.CooldownSeconds, &out.CooldownSeconds
+		*out = new(int32)
+		**out = **in
+	}
+	if in.MaxReplicas != nil {
+		in, out := &in.MaxReplicas, &out.MaxReplicas
+		*out = new(int32)
+		**out = **in
+	}
+	if in.MinReplicas != nil {
+		in, out := &in.MinReplicas, &out.MinReplicas
+		*out = new(int32)
+		**out = **in
+	}
+	if in.Surge != nil {
+		in, out := &in.Surge, &out.Surge
+		*out = new(intstr.IntOrString)
+		**out = **in
+	}
+	if in.ScaleDownStabilizationSeconds != nil {
+		in, out := &in.ScaleDownStabilizationSeconds, &out.ScaleDownStabilizationSeconds
+		*out = new(int32)
+		**out = **in
+	}
+	if in.ScaleUpIntervalSeconds != nil {
+		in, out := &in.ScaleUpIntervalSeconds, &out.ScaleUpIntervalSeconds
+		*out = new(int32)
+		**out = **in
+	}
+	if in.EvictionTTLSeconds != nil {
+		in, out := &in.EvictionTTLSeconds, &out.EvictionTTLSeconds
+		*out = new(int32)
+		**out = **in
+	}
+	if in.SurgeScheduleTimeoutSeconds != nil {
+		in, out := &in.SurgeScheduleTimeoutSeconds, &out.SurgeScheduleTimeoutSeconds
+		*out = new(int32)
+		**out = **in
+	}
+	if in.MaxDrainDurationSeconds != nil {
+		in, out := &in.MaxDrainDurationSeconds, &out.MaxDrainDurationSeconds
+		*out = new(int32)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EvictionAutoScalerSpec.
@@ -116,6 +305,9 @@ func (in *EvictionAutoScalerSpec) DeepCopy() *EvictionAutoScalerSpec {
 func (in *EvictionAutoScalerStatus) DeepCopyInto(out *EvictionAutoScalerStatus) {
 	*out = *in
 	in.LastEviction.DeepCopyInto(&out.LastEviction)
+	in.HandledEviction.DeepCopyInto(&out.HandledEviction)
+	in.MigratedEviction.DeepCopyInto(&out.MigratedEviction)
+	in.ExpiredEviction.DeepCopyInto(&out.ExpiredEviction)
 	if in.Conditions != nil {
 		in, out := &in.Conditions, &out.Conditions
 		*out = make([]metav1.Condition, len(*in))
@@ -123,6 +315,33 @@ func (in *EvictionAutoScalerStatus) DeepCopyInto(out *EvictionAutoScalerStatus)
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.DrainingNodes != nil {
+		in, out := &in.DrainingNodes, &out.DrainingNodes
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.NodeDrains != nil {
+		in, out := &in.NodeDrains, &out.NodeDrains
+		*out = make([]NodeDrain, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	in.DrainedTime.DeepCopyInto(&out.DrainedTime)
+	in.LastScaleTime.DeepCopyInto(&out.LastScaleTime)
+	in.LastScaleDownTime.DeepCopyInto(&out.LastScaleDownTime)
+	if in.AutoscalerSurge != nil {
+		in, out := &in.AutoscalerSurge, &out.AutoscalerSurge
+		*out = new(AutoscalerSurge)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.RecentEvictions != nil {
+		in, out := &in.RecentEvictions, &out.RecentEvictions
+		*out = make([]EvictionRecord, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EvictionAutoScalerStatus.
@@ -134,3 +353,161 @@ func (in *EvictionAutoScalerStatus) DeepCopy() *EvictionAutoScalerStatus {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *EvictionRecord) DeepCopyInto(out *EvictionRecord) {
+	*out = *in
+	in.EvictionTime.DeepCopyInto(&out.EvictionTime)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EvictionRecord.
+func (in *EvictionRecord) DeepCopy() *EvictionRecord {
+	if in == nil {
+		return nil
+	}
+	out := new(EvictionRecord)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NodeDrain) DeepCopyInto(out *NodeDrain) {
+	*out = *in
+	in.StartTime.DeepCopyInto(&out.StartTime)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodeDrain.
+func (in *NodeDrain) DeepCopy() *NodeDrain {
+	if in == nil {
+		return nil
+	}
+	out := new(NodeDrain)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NodeDrainEvictionAutoScaler) DeepCopyInto(out *NodeDrainEvictionAutoScaler) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodeDrainEvictionAutoScaler.
+func (in *NodeDrainEvictionAutoScaler) DeepCopy() *NodeDrainEvictionAutoScaler {
+	if in == nil {
+		return nil
+	}
+	out := new(NodeDrainEvictionAutoScaler)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NodeDrainProgress) DeepCopyInto(out *NodeDrainProgress) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	out.Spec = in.Spec
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodeDrainProgress.
+func (in *NodeDrainProgress) DeepCopy() *NodeDrainProgress {
+	if in == nil {
+		return nil
+	}
+	out := new(NodeDrainProgress)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *NodeDrainProgress) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NodeDrainProgressList) DeepCopyInto(out *NodeDrainProgressList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]NodeDrainProgress, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodeDrainProgressList.
+func (in *NodeDrainProgressList) DeepCopy() *NodeDrainProgressList {
+	if in == nil {
+		return nil
+	}
+	out := new(NodeDrainProgressList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *NodeDrainProgressList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NodeDrainProgressSpec) DeepCopyInto(out *NodeDrainProgressSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodeDrainProgressSpec.
+func (in *NodeDrainProgressSpec) DeepCopy() *NodeDrainProgressSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(NodeDrainProgressSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NodeDrainProgressStatus) DeepCopyInto(out *NodeDrainProgressStatus) {
+	*out = *in
+	in.StartTime.DeepCopyInto(&out.StartTime)
+	in.LastActivityTime.DeepCopyInto(&out.LastActivityTime)
+	if in.EvictionAutoScalers != nil {
+		in, out := &in.EvictionAutoScalers, &out.EvictionAutoScalers
+		*out = make([]NodeDrainEvictionAutoScaler, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodeDrainProgressStatus.
+func (in *NodeDrainProgressStatus) DeepCopy() *NodeDrainProgressStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(NodeDrainProgressStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TargetReference) DeepCopyInto(out *TargetReference) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TargetReference.
+func (in *TargetReference) DeepCopy() *TargetReference {
+	if in == nil {
+		return nil
+	}
+	out := new(TargetReference)
+	in.DeepCopyInto(out)
+	return out
+}
@@ -17,29 +17,39 @@ limitations under the License.
 package main
 
 import (
+	"context"
 	"crypto/tls"
 	"flag"
 	"log"
 	"os"
+	"strings"
 	"time"
 
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
 	// to ensure that exec-entrypoint and run can make use of them.
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
 
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/discovery"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+	"sigs.k8s.io/controller-runtime/pkg/metrics/filters"
 	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
 	"sigs.k8s.io/controller-runtime/pkg/webhook"
 	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
 
 	appsv1 "github.com/azure/eviction-autoscaler/api/v1"
 	controllers "github.com/azure/eviction-autoscaler/internal/controller"
-	_ "github.com/azure/eviction-autoscaler/internal/metrics"
+	"github.com/azure/eviction-autoscaler/internal/events"
+	"github.com/azure/eviction-autoscaler/internal/metrics"
+	"github.com/azure/eviction-autoscaler/internal/namespacefilter"
+	"github.com/azure/eviction-autoscaler/internal/selectorcache"
+	"github.com/azure/eviction-autoscaler/internal/tracing"
 	evictinwebhook "github.com/azure/eviction-autoscaler/internal/webhook"
 	// +kubebuilder:scaffold:imports
 )
@@ -63,6 +73,43 @@ func main() {
 	var secureMetrics bool
 	var enableHTTP2 bool
 	var evictionWebhook bool
+	var waitForSurge time.Duration
+	var validatingWebhook bool
+	var surgeAffinityWebhook bool
+	var pdbWebhook bool
+	var surgeAffinityRequired bool
+	var drainTaints string
+	var drainAnnotations string
+	var drainBlockingAnnotations string
+	var nodeFailureTriggers bool
+	var notReadyWindow time.Duration
+	var maintenanceGVK string
+	var maintenanceNodeField string
+	var maintenanceCompletedPhases string
+	var maxDrainResync time.Duration
+	var staleCordonThreshold time.Duration
+	var stuckTerminatingThreshold time.Duration
+	var nodeLabelSelector string
+	var namespaceAllowlist string
+	var namespaceDenylist string
+	var dryRun bool
+	var disablePodConditionWrites bool
+	var nodeDrainProgress bool
+	var autoCreate bool
+	var pdbMissingGracePeriod time.Duration
+	var pdbMissingAction string
+	var cooldown time.Duration
+	var defaultEvictionTTL time.Duration
+	var nodeConcurrency, podConcurrency, crConcurrency int
+	var maxConcurrentNodeDrains int
+	var nodeRetryBaseDelay, nodeRetryMaxDelay time.Duration
+	var nodeShards, nodeShardIndex int
+	var debugState bool
+	var otlpEndpoint string
+	var otlpInsecure bool
+	var traceSamplingRatio float64
+	var maxScaleUpsPerMinute int
+	var maxTotalSurgeReplicas int
 	flag.StringVar(&metricsAddr, "metrics-bind-address", "0", "The address the metric endpoint binds to. "+
 		"Use the port :8080. If not set, it will be 0 in order to disable the metrics server")
 	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
@@ -76,6 +123,111 @@ func main() {
 	flag.BoolVar(&evictionWebhook, "eviction-webhook", false,
 		"create a webhook that intercepts evictions and updates the EvictionAutoScaler, "+
 			"if false will rely on node cordon for signal")
+	flag.DurationVar(&waitForSurge, "eviction-webhook-wait-for-surge", 0,
+		"with --eviction-webhook, deny evictions with a 429 while their EvictionAutoScaler's surge isn't available yet, "+
+			"for at most this long after the surge. 0 never delays evictions")
+	flag.BoolVar(&validatingWebhook, "evictionautoscaler-webhook", false,
+		"serve /validate-evictionautoscaler, a validating webhook that rejects broken EvictionAutoScalers on create and update, "+
+			"/mutate-evictionautoscaler, a mutating webhook that fills in their defaults on create, "+
+			"and /validate-evictionautoscalerconfig, which rejects broken EvictionAutoScalerConfigs")
+	flag.BoolVar(&surgeAffinityWebhook, "surge-affinity-webhook", false,
+		"serve /mutate-pod, a mutating webhook for pod creates that gives pods created during their EvictionAutoScaler's surge "+
+			"node affinity away from cordoned and drain tainted nodes")
+	flag.BoolVar(&surgeAffinityRequired, "surge-affinity-required", false,
+		"with --surge-affinity-webhook, inject required instead of preferred node affinity. Surge pods then stay pending while every node drains")
+	flag.BoolVar(&pdbWebhook, "pdb-webhook", false,
+		"serve /validate-pdb, a validating webhook for pdb creates and updates that warns, never denies, when a pdb allows no "+
+			"disruption with the replicas of the workloads it selects")
+	flag.StringVar(&drainTaints, "drain-taints", strings.Join(controllers.DefaultDrainTaints, ","),
+		"comma separated taint keys that signal an upcoming drain and are treated the same as a cordon")
+	flag.StringVar(&drainAnnotations, "drain-annotations", "",
+		"comma separated node annotation keys, or key=regex to match their whole value, that signal an upcoming drain, "+
+			"like a cloud provider's scheduled event, and are treated the same as a cordon till they are removed")
+	flag.StringVar(&drainBlockingAnnotations, "drain-blocking-annotations", strings.Join(controllers.DefaultDrainBlockingAnnotations, ","),
+		"comma separated key=value pod annotations a drain won't evict a pod with, so no surge is made for it")
+	flag.BoolVar(&nodeFailureTriggers, "node-failure-triggers", false,
+		"also treat nodes with the node.kubernetes.io/out-of-service taint or NotReady for --not-ready-window as draining, "+
+			"since a failed node is never cordoned")
+	flag.DurationVar(&notReadyWindow, "not-ready-window", 2*time.Minute,
+		"how long a node must stay NotReady before --node-failure-triggers surges for its pods, so flapping nodes are ignored")
+	flag.StringVar(&maintenanceGVK, "maintenance-gvk", "",
+		"group/version/Kind of maintenance CRs, like nodemaintenance.medik8s.io/v1beta1/NodeMaintenance, whose nodes are "+
+			"treated as draining before they are cordoned. Watched once the CRD is installed. Empty watches none")
+	flag.StringVar(&maintenanceNodeField, "maintenance-node-field", controllers.DefaultMaintenanceNodeField,
+		"with --maintenance-gvk, the dotted path of the node name in a maintenance CR")
+	flag.StringVar(&maintenanceCompletedPhases, "maintenance-completed-phases", controllers.DefaultMaintenanceCompletedPhases,
+		"with --maintenance-gvk, comma separated status.phase values of a maintenance CR that is over")
+	flag.DurationVar(&maxDrainResync, "max-drain-resync", time.Hour,
+		"how far the 10m recheck of a cordoned node is backed off, doubling each time none of its pods left. "+
+			"10m or less never backs off")
+	flag.DurationVar(&staleCordonThreshold, "stale-cordon-threshold", 0,
+		"how long a cordoned node may go without any of its pods leaving before we stop surging for it, "+
+			"till it is uncordoned or annotated "+controllers.NodeRearmAnnotationKey+"=true. 0 never stands down")
+	flag.DurationVar(&stuckTerminatingThreshold, "stuck-terminating-threshold", controllers.DefaultStuckTerminatingThreshold,
+		"how long past its grace period a pod on a draining node may be terminating before it is reported with a "+
+			"PodStuckTerminating event and the eviction_autoscaler_pods_stuck_terminating gauge. 0 never reports them")
+	flag.StringVar(&nodeLabelSelector, "node-label-selector", "",
+		"label selector (e.g. agentpool=user,env!=test) scoping which nodes' cordons are acted on, empty means all nodes")
+	flag.StringVar(&namespaceAllowlist, "namespace-allowlist", "",
+		"comma separated namespaces the controllers may touch, empty means all namespaces")
+	flag.StringVar(&namespaceDenylist, "namespace-denylist", "",
+		"comma separated namespaces the controllers never touch, wins over the allowlist")
+	flag.BoolVar(&dryRun, "dry-run", false,
+		"log, count and emit events for what would be done without writing to pods, EvictionAutoScalers or workloads")
+	flag.BoolVar(&disablePodConditionWrites, "disable-pod-condition-writes", false,
+		"don't set the DisruptionTarget condition on pods of draining nodes, for clusters not granting patch on pods/status. "+
+			"Evictions are still surged for. A forbidden pods/status write turns this on by itself")
+	flag.BoolVar(&nodeDrainProgress, "node-drain-progress", true,
+		"keep a NodeDrainProgress for every node whose drain is assisted, with its pods remaining and moved and the "+
+			"EvictionAutoScalers surged for it. Needs the NodeDrainProgress CRD installed")
+	flag.BoolVar(&autoCreate, "auto-create-evictionautoscalers", false,
+		"create an EvictionAutoScaler for every pdb that doesn't have one. "+
+			"Pdbs annotated "+controllers.OptOutAnnotationKey+" or "+controllers.DoNotRecreateAnnotationKey+" are skipped")
+	flag.DurationVar(&pdbMissingGracePeriod, "pdb-missing-grace-period", 10*time.Minute,
+		"how long an EvictionAutoScaler's pdb may be missing (e.g. recreated by a helm upgrade) before --pdb-missing-action is taken")
+	flag.DurationVar(&cooldown, "cooldown", controllers.DefaultCooldown,
+		"how long to wait after the last eviction before scaling back down for EvictionAutoScalers without spec.cooldownSeconds")
+	flag.DurationVar(&cooldown, "default-cooldown", controllers.DefaultCooldown, "deprecated: use --cooldown")
+	flag.DurationVar(&defaultEvictionTTL, "default-eviction-ttl", controllers.DefaultEvictionTTL,
+		"how long an eviction may go without its pod going away before it stops holding a surge, "+
+			"for EvictionAutoScalers without spec.evictionTTLSeconds. Zero never expires evictions")
+	flag.StringVar(&pdbMissingAction, "pdb-missing-action", "",
+		"what to do with an EvictionAutoScaler whose pdb is missing past the grace period: "+
+			controllers.PDBMissingDelete+", "+controllers.PDBMissingSuspend+" or empty to only set the PDBMissing condition")
+	flag.IntVar(&nodeConcurrency, "node-reconcile-concurrency", 1,
+		"how many nodes are reconciled at once, raise it for clusters that cordon many nodes together during upgrades")
+	flag.IntVar(&podConcurrency, "node-pod-concurrency", 1,
+		"how many pods of a draining node have their DisruptionTarget and eviction written at once, "+
+			"raise it for nodes with hundreds of pods")
+	flag.DurationVar(&nodeRetryBaseDelay, "node-retry-base-delay", controllers.DefaultNodeRetryBaseDelay,
+		"how long a node whose reconcile failed waits before it is retried, doubling each time it fails again")
+	flag.DurationVar(&nodeRetryMaxDelay, "node-retry-max-delay", controllers.DefaultNodeRetryMaxDelay,
+		"the longest a failing node waits between retries")
+	flag.IntVar(&maxConcurrentNodeDrains, "max-concurrent-node-drains", 0,
+		"how many node drains are assisted at once, other cordoned nodes are queued in order till one finishes. "+
+			"Counted per --node-shards replica. Zero assists them all")
+	flag.IntVar(&crConcurrency, "cr-reconcile-concurrency", 1,
+		"how many EvictionAutoScalers are reconciled at once")
+	flag.IntVar(&nodeShards, "node-shards", 1,
+		"how many replicas split the nodes between them. Each runs the node controller for its share, "+
+			"not just the leader, and every replica must be started with the same count")
+	flag.IntVar(&nodeShardIndex, "node-shard-index", 0,
+		"which of --node-shards this replica is, from 0, e.g. a StatefulSet's pod index")
+	flag.BoolVar(&debugState, "debug-state", false,
+		"serve "+controllers.DebugStatePath+" on the metrics server, json of the drains being assisted. "+
+			"Needs --metrics-secure and makes the metrics server authenticate and authorize every request")
+	flag.IntVar(&maxScaleUpsPerMinute, "max-scaleups-per-minute", 0,
+		"how many surges all EvictionAutoScalers may start a minute together, the rest wait with a ScaleUpThrottled condition. "+
+			"Zero doesn't limit them")
+	flag.IntVar(&maxTotalSurgeReplicas, "max-total-surge-replicas", 0,
+		"how many replicas all EvictionAutoScalers may hold above their baselines together, whatever their maxReplicas. "+
+			"Surges that don't fit wait with a SurgeBudgetExhausted condition till others scale down. Zero doesn't limit them")
+	flag.StringVar(&otlpEndpoint, "otlp-endpoint", "",
+		"host:port of an OTLP grpc collector to send reconcile traces to, empty disables tracing")
+	flag.BoolVar(&otlpInsecure, "otlp-insecure", false,
+		"send traces to --otlp-endpoint without TLS")
+	flag.Float64Var(&traceSamplingRatio, "trace-sampling-ratio", 1,
+		"fraction of reconciles traced, from 0 to 1. Spans with a sampled parent are always kept")
 
 	opts := zap.Options{
 		Development: true,
@@ -85,6 +237,104 @@ func main() {
 
 	ctrl.SetLogger(zap.New(zap.UseFlagOptions(&opts)))
 
+	if pdbMissingAction != "" && pdbMissingAction != controllers.PDBMissingDelete && pdbMissingAction != controllers.PDBMissingSuspend {
+		setupLog.Error(nil, "invalid --pdb-missing-action", "action", pdbMissingAction)
+		os.Exit(1)
+	}
+
+	if nodeConcurrency < 1 || podConcurrency < 1 || crConcurrency < 1 {
+		setupLog.Error(nil, "--node-reconcile-concurrency, --node-pod-concurrency and --cr-reconcile-concurrency must be at least 1",
+			"node", nodeConcurrency, "pod", podConcurrency, "cr", crConcurrency)
+		os.Exit(1)
+	}
+
+	if debugState && (!secureMetrics || metricsAddr == "0") {
+		setupLog.Error(nil, "--debug-state needs a --metrics-bind-address and --metrics-secure")
+		os.Exit(1)
+	}
+
+	if notReadyWindow < 0 {
+		setupLog.Error(nil, "--not-ready-window must not be negative", "window", notReadyWindow)
+		os.Exit(1)
+	}
+
+	parsedDrainAnnotations, err := controllers.ParseDrainAnnotations(splitList(drainAnnotations))
+	if err != nil {
+		setupLog.Error(err, "invalid --drain-annotations")
+		os.Exit(1)
+	}
+
+	var maintenance *controllers.NodeMaintenance
+	if maintenanceGVK != "" {
+		gvk, err := controllers.ParseMaintenanceGVK(maintenanceGVK)
+		if err != nil {
+			setupLog.Error(err, "invalid --maintenance-gvk")
+			os.Exit(1)
+		}
+		maintenance = &controllers.NodeMaintenance{
+			GVK:             gvk,
+			NodeField:       maintenanceNodeField,
+			CompletedPhases: splitList(maintenanceCompletedPhases),
+		}
+	}
+
+	if maxScaleUpsPerMinute < 0 {
+		setupLog.Error(nil, "--max-scaleups-per-minute must not be negative", "limit", maxScaleUpsPerMinute)
+		os.Exit(1)
+	}
+
+	if maxTotalSurgeReplicas < 0 {
+		setupLog.Error(nil, "--max-total-surge-replicas must not be negative", "limit", maxTotalSurgeReplicas)
+		os.Exit(1)
+	}
+
+	if traceSamplingRatio < 0 || traceSamplingRatio > 1 {
+		setupLog.Error(nil, "--trace-sampling-ratio must be between 0 and 1", "ratio", traceSamplingRatio)
+		os.Exit(1)
+	}
+
+	nodeShard, err := controllers.NewNodeShard(nodeShardIndex, nodeShards)
+	if err != nil {
+		setupLog.Error(err, "invalid --node-shard-index or --node-shards")
+		os.Exit(1)
+	}
+
+	if cooldown < time.Second {
+		setupLog.Error(nil, "--cooldown must be at least a second", "cooldown", cooldown)
+		os.Exit(1)
+	}
+	for _, annotation := range splitList(drainBlockingAnnotations) {
+		if !strings.Contains(annotation, "=") {
+			setupLog.Error(nil, "--drain-blocking-annotations must be key=value pairs", "annotation", annotation)
+			os.Exit(1)
+		}
+	}
+	// the EvictionAutoScalerConfig overrides the flags from here while we run
+	global := &controllers.GlobalConfig{}
+	config := controllers.Config{Cooldown: cooldown, EvictionTTL: defaultEvictionTTL, Global: global}
+
+	if defaultEvictionTTL < 0 {
+		setupLog.Error(nil, "--default-eviction-ttl must not be negative", "ttl", defaultEvictionTTL)
+		os.Exit(1)
+	}
+	if defaultEvictionTTL == 0 {
+		config.EvictionTTL = -1 // never expire, the zero Config uses DefaultEvictionTTL
+	}
+
+	nodeSelector, err := labels.Parse(nodeLabelSelector)
+	if err != nil {
+		setupLog.Error(err, "invalid node label selector", "selector", nodeLabelSelector)
+		os.Exit(1)
+	}
+	namespaces := namespacefilter.New(splitList(namespaceAllowlist), splitList(namespaceDenylist))
+	// the node controller and eviction webhook both match pods against every pdb in a namespace
+	selectors := selectorcache.New()
+	// one bucket for every scale-up path in the process
+	scaleUps := controllers.NewScaleUpLimiter(maxScaleUpsPerMinute)
+	scaleUps.Register()
+	// one budget for every surge path in the process, only the leader surges
+	surgeBudget := controllers.NewSurgeBudget(maxTotalSurgeReplicas)
+
 	// if the enable-http2 flag is false (the default), http/2 should be disabled
 	// due to its vulnerabilities. More specifically, disabling http/2 will
 	// prevent from being vulnerable to the HTTP/2 Stream Cancellation and
@@ -107,14 +357,19 @@ func main() {
 		CertDir: "/etc/webhook/tls",
 		TLSOpts: tlsOpts,
 	})
+	metricsOptions := metricsserver.Options{
+		BindAddress:   metricsAddr,
+		SecureServing: secureMetrics,
+		TLSOpts:       tlsOpts,
+	}
+	if debugState {
+		// callers need get on the nonResourceURL, the debug state names nodes, workloads and errors
+		metricsOptions.FilterProvider = filters.WithAuthenticationAndAuthorization
+	}
 	shutdown := time.Duration(-1) //wait until pod termination grace period sends sig kill or webhook shuts down
 	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
-		Scheme: scheme,
-		Metrics: metricsserver.Options{
-			BindAddress:   metricsAddr,
-			SecureServing: secureMetrics,
-			TLSOpts:       tlsOpts,
-		},
+		Scheme:  scheme,
+		Metrics: metricsOptions,
 		//WebhookServer:           hookServer,
 		GracefulShutdownTimeout: &shutdown,
 		HealthProbeBindAddress:  probeAddr,
@@ -137,9 +392,47 @@ func main() {
 		os.Exit(1)
 	}
 
+	// namespace opt-outs are read from the cache so every pod and EvictionAutoScaler can be checked
+	namespaces.Reader = mgr.GetCache()
+
+	// dry run writes still go to the api server so they are validated but nothing is persisted.
+	// our calls are counted by verb and resource so api server and RBAC failures show up in metrics
+	controllerClient := metrics.InstrumentClient(mgr.GetClient())
+	if dryRun {
+		setupLog.Info("dry-run mode, no changes will be persisted")
+		controllerClient = client.NewDryRunClient(controllerClient)
+	}
+	if disablePodConditionWrites {
+		setupLog.Info("not writing DisruptionTarget pod conditions")
+		metrics.PodConditionWritesDisabledGauge.Set(1)
+	}
+	// shared by the node controller and the eviction webhook so a Forbidden write from either stops both
+	podConditionWrites := &controllers.PodConditionWrites{Disabled: disablePodConditionWrites, Global: global}
+
+	scales, err := controllers.NewScalesGetter(mgr.GetConfig(), mgr.GetRESTMapper())
+	if err != nil {
+		setupLog.Error(err, "unable to create scale client")
+		os.Exit(1)
+	}
+	scales = metrics.InstrumentScales(scales)
+
+	// one recorder so the rate limit holds across controllers
+	recorder := events.NewRateLimitedRecorder(mgr.GetEventRecorderFor("eviction-autoscaler"), events.DefaultInterval)
 	if err = (&controllers.EvictionAutoScalerReconciler{
-		Client: mgr.GetClient(),
-		Scheme: mgr.GetScheme(),
+		Client:     controllerClient,
+		Scheme:     mgr.GetScheme(),
+		Recorder:   recorder,
+		Namespaces: namespaces,
+		DryRun:     dryRun,
+		Scales:     scales,
+
+		PDBMissingGracePeriod:   pdbMissingGracePeriod,
+		PDBMissingAction:        pdbMissingAction,
+		MaxConcurrentReconciles: crConcurrency,
+		ScaleUps:                scaleUps,
+		SurgeBudget:             surgeBudget,
+		DrainTaints:             splitList(drainTaints),
+		Config:                  config,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "EvictionAutoScaler")
 		os.Exit(1)
@@ -147,39 +440,131 @@ func main() {
 	setupLog.Info("EvictionAutoScalerReconciler  setup completed")
 
 	if err = (&controllers.DeploymentToPDBReconciler{
-		Client: mgr.GetClient(),
-		Scheme: mgr.GetScheme(),
+		Client:     controllerClient,
+		Scheme:     mgr.GetScheme(),
+		Namespaces: namespaces,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "DeploymentToPDBReconciler")
 		os.Exit(1)
 	}
 	setupLog.Info("DeploymentToPDBReconciler  setup completed")
 
-	if err = (&controllers.PDBToEvictionAutoScalerReconciler{
-		Client: mgr.GetClient(),
-		Scheme: mgr.GetScheme(),
+	if err = (&controllers.EvictionAutoScalerConfigReconciler{
+		Client:     mgr.GetClient(),
+		Global:     global,
+		Namespaces: namespaces,
 	}).SetupWithManager(mgr); err != nil {
-		setupLog.Error(err, "unable to create controller", "controller", "PDBToEvictionAutoScalerReconciler")
+		setupLog.Error(err, "unable to create controller", "controller", "EvictionAutoScalerConfig")
 		os.Exit(1)
 	}
-	setupLog.Info("PDBToEvictionAutoScalerReconciler  setup completed")
 
-	if err = (&controllers.NodeReconciler{
-		Client: mgr.GetClient(),
-		Scheme: mgr.GetScheme(),
-	}).SetupWithManager(mgr); err != nil {
+	if autoCreate {
+		if err = (&controllers.PDBToEvictionAutoScalerReconciler{
+			Client:     controllerClient,
+			Scheme:     mgr.GetScheme(),
+			Namespaces: namespaces,
+		}).SetupWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create controller", "controller", "PDBToEvictionAutoScalerReconciler")
+			os.Exit(1)
+		}
+		setupLog.Info("PDBToEvictionAutoScalerReconciler  setup completed")
+	}
+
+	nodeReconciler := &controllers.NodeReconciler{
+		Client:       controllerClient,
+		Scheme:       mgr.GetScheme(),
+		Recorder:     recorder,
+		DrainTaints:  splitList(drainTaints),
+		NodeSelector: nodeSelector,
+		Namespaces:   namespaces,
+		Selectors:    selectors,
+		DryRun:       dryRun,
+
+		DrainAnnotations:          parsedDrainAnnotations,
+		PodConditionWrites:        podConditionWrites,
+		DrainProgress:             nodeDrainProgress,
+		MaxConcurrentReconciles:   nodeConcurrency,
+		RetryBaseDelay:            nodeRetryBaseDelay,
+		RetryMaxDelay:             nodeRetryMaxDelay,
+		PodConcurrency:            podConcurrency,
+		Shard:                     nodeShard,
+		NodeFailureTriggers:       nodeFailureTriggers,
+		NotReadyWindow:            notReadyWindow,
+		Maintenance:               maintenance,
+		MaxDrainResync:            maxDrainResync,
+		StaleCordonThreshold:      staleCordonThreshold,
+		StuckTerminatingThreshold: stuckTerminatingThreshold,
+		MaxConcurrentNodeDrains:   maxConcurrentNodeDrains,
+		Config:                    config,
+		DrainBlockingAnnotations:  splitList(drainBlockingAnnotations),
+	}
+	if err = nodeReconciler.SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "EvictionAutoScaler")
 		os.Exit(1)
 	}
+	if debugState {
+		if err := mgr.AddMetricsServerExtraHandler(controllers.DebugStatePath, nodeReconciler.DebugStateHandler()); err != nil {
+			setupLog.Error(err, "unable to serve debug state")
+			os.Exit(1)
+		}
+	}
 	// +kubebuilder:scaffold:builder
 
 	// Register the webhook handler
 	if evictionWebhook {
 		hookServer.Register("/validate-eviction", &admission.Webhook{
 			Handler: &evictinwebhook.EvictionHandler{
-				Client: mgr.GetClient(),
+				Client:             controllerClient,
+				Namespaces:         namespaces,
+				Selectors:          selectors,
+				DryRun:             dryRun,
+				PodConditionWrites: podConditionWrites,
+				WaitForSurge:       waitForSurge,
+				DefaultCooldown:    cooldown,
+				Global:             global,
+			},
+		})
+	}
+	if validatingWebhook {
+		discoveryClient, err := discovery.NewDiscoveryClientForConfig(mgr.GetConfig())
+		if err != nil {
+			setupLog.Error(err, "unable to create discovery client")
+			os.Exit(1)
+		}
+		hookServer.Register("/validate-evictionautoscaler", &admission.Webhook{
+			Handler: &evictinwebhook.EvictionAutoScalerValidator{
+				Client:    mgr.GetClient(),
+				Discovery: discoveryClient,
+			},
+		})
+		hookServer.Register("/mutate-evictionautoscaler", &admission.Webhook{
+			Handler: &evictinwebhook.EvictionAutoScalerDefaulter{},
+		})
+		hookServer.Register("/validate-evictionautoscalerconfig", &admission.Webhook{
+			Handler: &evictinwebhook.EvictionAutoScalerConfigValidator{},
+		})
+	}
+	if surgeAffinityWebhook {
+		hookServer.Register("/mutate-pod", &admission.Webhook{
+			Handler: &evictinwebhook.SurgePodAffinityMutator{
+				Client:      controllerClient,
+				Namespaces:  namespaces,
+				Selectors:   selectors,
+				DrainTaints: splitList(drainTaints),
+				Required:    surgeAffinityRequired,
+			},
+		})
+	}
+	if pdbWebhook {
+		hookServer.Register("/validate-pdb", &admission.Webhook{
+			Handler: &evictinwebhook.PDBValidator{
+				Client:     controllerClient,
+				Namespaces: namespaces,
+				AutoCreate: autoCreate,
 			},
 		})
+	}
+	if evictionWebhook || validatingWebhook || surgeAffinityWebhook || pdbWebhook {
 		// Add the webhook server to the manager
 		if err := mgr.Add(hookServer); err != nil {
 			log.Printf("Unable to add webhook server to manager: %v", err)
@@ -191,14 +576,51 @@ func main() {
 		setupLog.Error(err, "unable to set up health check")
 		os.Exit(1)
 	}
-	if err := mgr.AddReadyzCheck("readyz", healthz.Ping); err != nil {
-		setupLog.Error(err, "unable to set up ready check")
+	// the body of a failing readyz names the check, so each is named after what isn't ready yet
+	readyChecks := map[string]healthz.Checker{
+		"readyz":          healthz.Ping,
+		"informer-caches": controllers.CacheSyncChecker(mgr.GetCache()),
+		"pod-node-index":  controllers.PodNodeIndexChecker(mgr.GetCache()),
+	}
+	if evictionWebhook || validatingWebhook || surgeAffinityWebhook || pdbWebhook {
+		// only ready once serving with the mounted certs so a rolling upgrade doesn't get webhook calls it can't answer
+		readyChecks["webhook-server"] = hookServer.StartedChecker()
+	}
+	for name, check := range readyChecks {
+		if err := mgr.AddReadyzCheck(name, check); err != nil {
+			setupLog.Error(err, "unable to set up ready check", "check", name)
+			os.Exit(1)
+		}
+	}
+
+	ctx := ctrl.SetupSignalHandler()
+	shutdownTracing, err := tracing.Setup(ctx, otlpEndpoint, otlpInsecure, traceSamplingRatio)
+	if err != nil {
+		setupLog.Error(err, "unable to set up tracing", "endpoint", otlpEndpoint)
 		os.Exit(1)
 	}
 
 	setupLog.Info("starting manager")
-	if err := mgr.Start(ctrl.SetupSignalHandler()); err != nil {
+	err = mgr.Start(ctx)
+	// ctx is done by now so flush what is left with a fresh deadline
+	flushCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	if err := shutdownTracing(flushCtx); err != nil {
+		setupLog.Error(err, "unable to flush traces")
+	}
+	cancel()
+	if err != nil {
 		setupLog.Error(err, "problem running manager")
 		os.Exit(1)
 	}
 }
+
+// splitList splits a comma separated flag value dropping empty entries.
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,309 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	myappsv1 "github.com/azure/eviction-autoscaler/api/v1"
+	"github.com/azure/eviction-autoscaler/internal/events"
+	"github.com/azure/eviction-autoscaler/internal/metrics"
+	"github.com/azure/eviction-autoscaler/internal/tracing"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// ConditionConflictingAutoscaler is true while an HPA or KEDA ScaledObject scales the target.
+const ConditionConflictingAutoscaler = "ConflictingAutoscaler"
+
+// +kubebuilder:rbac:groups=autoscaling,resources=horizontalpodautoscalers,verbs=get;list;watch;update
+// +kubebuilder:rbac:groups=keda.sh,resources=scaledobjects,verbs=get;list;update
+
+// findAutoscaler returns the HPA, or with spec.keda the KEDA ScaledObject, scaling the EvictionAutoScaler's target.
+// A ScaledObject wins over the HPA KEDA makes for it. While status.autoscalerSurge is set that is the one we raised, nil once it is deleted.
+func (r *EvictionAutoScalerReconciler) findAutoscaler(ctx context.Context, EvictionAutoScaler *myappsv1.EvictionAutoScaler) (Autoscaler, error) {
+	if surge := EvictionAutoScaler.Status.AutoscalerSurge; surge != nil {
+		autoscaler, err := newAutoscaler(surge.Kind)
+		if err != nil {
+			return nil, err
+		}
+		err = r.Get(ctx, types.NamespacedName{Namespace: EvictionAutoScaler.Namespace, Name: surge.Name}, autoscaler.Obj())
+		if errors.IsNotFound(err) || meta.IsNoMatchError(err) {
+			return nil, nil
+		}
+		return autoscaler, err
+	}
+
+	if EvictionAutoScaler.Spec.KEDA {
+		scaledObjects := &unstructured.UnstructuredList{}
+		scaledObjects.SetGroupVersionKind(scaledObjectGVK.GroupVersion().WithKind(scaledObjectGVK.Kind + "List"))
+		err := r.List(ctx, scaledObjects, client.InNamespace(EvictionAutoScaler.Namespace))
+		if err != nil && !meta.IsNoMatchError(err) {
+			return nil, err
+		}
+		if err != nil {
+			log.FromContext(ctx).V(1).Info("spec.keda is set but KEDA is not installed")
+		}
+		for i := range scaledObjects.Items {
+			scaledObject := &ScaledObjectWrapper{obj: &scaledObjects.Items[i]}
+			if autoscalerTargets(scaledObject, EvictionAutoScaler) {
+				return scaledObject, nil
+			}
+		}
+	}
+
+	hpaList := &autoscalingv2.HorizontalPodAutoscalerList{}
+	if err := r.List(ctx, hpaList, client.InNamespace(EvictionAutoScaler.Namespace)); err != nil {
+		return nil, err
+	}
+	for i := range hpaList.Items {
+		hpa := &HPAWrapper{obj: &hpaList.Items[i]}
+		if autoscalerTargets(hpa, EvictionAutoScaler) {
+			return hpa, nil
+		}
+	}
+	return nil, nil
+}
+
+// autoscalerTargets is true if autoscaler's scale target is the EvictionAutoScaler's target.
+func autoscalerTargets(autoscaler Autoscaler, EvictionAutoScaler *myappsv1.EvictionAutoScaler) bool {
+	apiVersion, refKind, refName := autoscaler.ScaleTargetRef()
+	kind, name := targetKindAndName(EvictionAutoScaler)
+	if refName != name || !strings.EqualFold(refKind, kind) {
+		return false
+	}
+	if targetRef := EvictionAutoScaler.Spec.TargetRef; targetRef != nil {
+		refGV, err := schema.ParseGroupVersion(apiVersion)
+		if err != nil {
+			return false
+		}
+		targetGV, _ := schema.ParseGroupVersion(targetRef.APIVersion)
+		return refGV.Group == targetGV.Group
+	}
+	return true
+}
+
+// reconcileAutoscaler handles targets scaled by autoscaler, or a nil autoscaler when the one in status.autoscalerSurge is gone.
+// Their replicas are never written since the autoscaler would just revert them. With spec.hpaPolicy AdjustMinReplicas,
+// or spec.keda for ScaledObjects, its minimum replicas are raised instead and status.autoscalerSurge remembers what to put back.
+func (r *EvictionAutoScalerReconciler) reconcileAutoscaler(ctx context.Context, EvictionAutoScaler *myappsv1.EvictionAutoScaler, pdb *policyv1.PodDisruptionBudget,
+	target Surger, autoscaler Autoscaler, statusChanged bool) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+	status := &EvictionAutoScaler.Status
+	key := client.ObjectKeyFromObject(EvictionAutoScaler)
+	targetKind, targetName := targetKindAndName(EvictionAutoScaler)
+
+	if autoscaler == nil {
+		// deleted mid surge so there are no minimum replicas left to restore. Whatever it left the target at becomes the baseline.
+		logger.Info("Autoscaler we surged is gone, forgetting its minimum replicas", "kind", status.AutoscalerSurge.Kind, "name", status.AutoscalerSurge.Name)
+		message := fmt.Sprintf("%s %s deleted", status.AutoscalerSurge.Kind, status.AutoscalerSurge.Name)
+		status.AutoscalerSurge = nil
+		status.TargetGeneration = 0
+		clearCondition(&status.Conditions, ConditionConflictingAutoscaler, "AutoscalerDeleted", message)
+		if clearCondition(&status.Conditions, ConditionScalingUp, "AutoscalerDeleted", message) {
+			setCondition(&status.Conditions, ConditionIdle, metav1.ConditionTrue, "AutoscalerDeleted", message)
+		}
+		clearCondition(&status.Conditions, ConditionCoolingDown, "AutoscalerDeleted", message)
+		r.setSurge(ctx, key, 0)
+		return ctrl.Result{Requeue: true}, r.updateStatus(ctx, EvictionAutoScaler)
+	}
+	name := autoscaler.Obj().GetName()
+
+	if autoscaler.Kind() == hpaKind && EvictionAutoScaler.Spec.HPAPolicy != myappsv1.HPAPolicyAdjustMinReplicas {
+		statusChanged = setCondition(&status.Conditions, ConditionConflictingAutoscaler, metav1.ConditionTrue, "HPAScalesTarget",
+			fmt.Sprintf("hpa %s scales %s %s so it is not surged, set spec.hpaPolicy to AdjustMinReplicas to raise the hpa's minReplicas instead", name, targetKind, targetName)) || statusChanged
+		if status.AutoscalerSurge != nil {
+			// hpaPolicy changed mid surge
+			return r.restoreAutoscaler(ctx, EvictionAutoScaler, autoscaler)
+		}
+		if status.LastEviction != status.HandledEviction {
+			logger.Info("Not surging target scaled by an HPA", "hpa", name, "kind", targetKind, "targetname", targetName)
+			status.HandledEviction = status.LastEviction
+			statusChanged = true
+		}
+		if statusChanged {
+			return ctrl.Result{}, r.updateStatus(ctx, EvictionAutoScaler)
+		}
+		return ctrl.Result{}, nil
+	}
+	statusChanged = setCondition(&status.Conditions, ConditionConflictingAutoscaler, metav1.ConditionTrue, autoscaler.Kind()+"ScalesTarget",
+		fmt.Sprintf("%s %s scales %s %s so it is surged through its minimum replicas", autoscaler.Kind(), name, targetKind, targetName)) || statusChanged
+
+	if status.AutoscalerSurge != nil {
+		return r.holdAutoscalerSurge(ctx, EvictionAutoScaler, pdb, autoscaler, statusChanged)
+	}
+	if status.LastEviction == status.HandledEviction {
+		ready(&status.Conditions, "Reconciled", "no unhandled eviction")
+		return ctrl.Result{}, r.updateStatus(ctx, EvictionAutoScaler)
+	}
+	if evictionExpired(status) {
+		status.HandledEviction = status.LastEviction
+		ready(&status.Conditions, "Reconciled", "last eviction is stale")
+		return ctrl.Result{}, r.updateStatus(ctx, EvictionAutoScaler)
+	}
+	if pdb.Status.DisruptionsAllowed > 0 {
+		status.HandledEviction = status.LastEviction
+		ready(&status.Conditions, "Reconciled", "last eviction did not need scaling")
+		return ctrl.Result{}, r.updateStatus(ctx, EvictionAutoScaler)
+	}
+	metrics.BlockedEvictionCounter.WithLabelValues(EvictionAutoScaler.Namespace, pdb.Name).Inc()
+
+	disruptions, err := r.disruptionsNeeded(ctx, EvictionAutoScaler, pdb)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	unblock, ok := replicasToUnblock(pdb, target.GetReplicas(), disruptions)
+	if !ok {
+		message := fmt.Sprintf("pdb %s won't allow a disruption however many replicas are added", pdb.Name)
+		degraded(&status.Conditions, "SurgeCannotUnblock", message)
+		status.HandledEviction = status.LastEviction
+		return ctrl.Result{}, r.updateStatus(ctx, EvictionAutoScaler)
+	}
+	surged, err := calculateSurge(r.Config.surgeFor(EvictionAutoScaler), target.GetReplicas())
+	if err != nil {
+		degraded(&status.Conditions, "InvalidSurge", err.Error())
+		return ctrl.Result{}, r.updateStatus(ctx, EvictionAutoScaler)
+	}
+	minReplicas := max(surged, target.GetReplicas()+unblock)
+	if maxReplicas := EvictionAutoScaler.Spec.MaxReplicas; maxReplicas != nil && minReplicas > *maxReplicas {
+		minReplicas = *maxReplicas
+	}
+	// both reject a minimum above their maximum
+	minReplicas = min(minReplicas, autoscaler.MaxReplicas())
+	originalMinReplicas := autoscaler.MinReplicas()
+	if minReplicas <= originalMinReplicas {
+		logger.Info("Autoscaler minimum replicas are already as high as the surge", "kind", autoscaler.Kind(), "name", name, "minReplicas", originalMinReplicas)
+		status.HandledEviction = status.LastEviction
+		return ctrl.Result{}, r.updateStatus(ctx, EvictionAutoScaler)
+	}
+	action := fmt.Sprintf("raise %s %s minimum replicas to %d", autoscaler.Kind(), name, minReplicas)
+	if fits, err := r.spendSurgeBudget(ctx, EvictionAutoScaler, minReplicas-originalMinReplicas, action); err != nil {
+		return ctrl.Result{}, err
+	} else if !fits {
+		return ctrl.Result{RequeueAfter: r.Config.CooldownFor(EvictionAutoScaler)}, r.updateStatus(ctx, EvictionAutoScaler)
+	}
+	if delay := r.throttleScaleUp(ctx, EvictionAutoScaler, action); delay > 0 {
+		// give back the budget till the token is there
+		r.setSurge(ctx, key, 0)
+		return ctrl.Result{RequeueAfter: delay}, r.updateStatus(ctx, EvictionAutoScaler)
+	}
+	if r.DryRun {
+		events.DryRun(ctx, r.Recorder, EvictionAutoScaler, metrics.DryRunScaleTarget, "raise %s %s minimum replicas to %d for %s %s",
+			autoscaler.Kind(), name, minReplicas, targetKind, targetName)
+		// nothing was raised, so give back the budget reserved for it
+		r.setSurge(ctx, key, 0)
+		return ctrl.Result{RequeueAfter: r.Config.CooldownFor(EvictionAutoScaler)}, nil
+	}
+
+	// record the original first so a crash after raising it still restores it
+	status.AutoscalerSurge = &myappsv1.AutoscalerSurge{Kind: autoscaler.Kind(), Name: name, OriginalMinReplicas: autoscaler.GetMinReplicas()}
+	status.LastScaleTime = metav1.Now()
+	if err := r.updateStatus(ctx, EvictionAutoScaler); err != nil {
+		return ctrl.Result{}, err
+	}
+	autoscaler.SetMinReplicas(&minReplicas)
+	tracing.Decide(ctx, "scale-up")
+	if err := r.updateAutoscaler(ctx, autoscaler); err != nil {
+		logger.Error(err, "failed to raise autoscaler minimum replicas", "kind", autoscaler.Kind(), "name", name)
+		return ctrl.Result{}, err
+	}
+
+	metrics.ScaleUpCounter.WithLabelValues(EvictionAutoScaler.Namespace, strings.ToLower(targetKind)).Inc()
+	metrics.ScaleUpReplicasCounter.WithLabelValues(EvictionAutoScaler.Namespace, strings.ToLower(targetKind)).Add(float64(minReplicas - originalMinReplicas))
+	r.setSurge(ctx, key, minReplicas-originalMinReplicas)
+	logger.Info(fmt.Sprintf("Raised %s %s/%s minimum replicas from %d to %d", autoscaler.Kind(), EvictionAutoScaler.Namespace, name, originalMinReplicas, minReplicas))
+	events.Eventf(r.Recorder, EvictionAutoScaler, corev1.EventTypeNormal, events.ReasonSurgeScaledUp,
+		"Raised %s %s minimum replicas to %d for eviction of pod %s", autoscaler.Kind(), name, minReplicas, status.LastEviction.PodName)
+	setCondition(&status.Conditions, ConditionScalingUp, metav1.ConditionTrue, "Surged",
+		fmt.Sprintf("raised %s %s minimum replicas from %d to %d for eviction of pod %s", autoscaler.Kind(), name, originalMinReplicas, minReplicas, status.LastEviction.PodName))
+	setCondition(&status.Conditions, ConditionIdle, metav1.ConditionFalse, "Surged", fmt.Sprintf("%s minimum replicas raised to %d", autoscaler.Kind(), minReplicas))
+	ready(&status.Conditions, "Reconciled", "eviction with autoscaler minimum replicas raised")
+	return ctrl.Result{RequeueAfter: r.Config.CooldownFor(EvictionAutoScaler)}, r.updateScaleStatus(ctx, EvictionAutoScaler)
+}
+
+// holdAutoscalerSurge keeps autoscaler's minimum replicas raised for the same draining nodes, pods left on them, cooldown and
+// stabilization window a surge is held for and then restores them.
+func (r *EvictionAutoScalerReconciler) holdAutoscalerSurge(ctx context.Context, EvictionAutoScaler *myappsv1.EvictionAutoScaler,
+	pdb *policyv1.PodDisruptionBudget, autoscaler Autoscaler, statusChanged bool) (ctrl.Result, error) {
+	status := &EvictionAutoScaler.Status
+	var requeue time.Duration
+	var reason, message, decision string
+	switch {
+	case evictionExpired(status):
+		tracing.Decide(ctx, "scale-down")
+		return r.restoreAutoscaler(ctx, EvictionAutoScaler, autoscaler)
+	case len(status.DrainingNodes) > 0:
+		requeue, reason, decision = r.Config.CooldownFor(EvictionAutoScaler), "NodesDraining", "hold-draining"
+		message = fmt.Sprintf("holding the surge till draining nodes %v are done", status.DrainingNodes)
+	case time.Since(status.LastEviction.EvictionTime.Time) < r.Config.CooldownFor(EvictionAutoScaler):
+		requeue, reason, decision = r.Config.CooldownFor(EvictionAutoScaler), "RecentEviction", "cooldown"
+		message = fmt.Sprintf("last eviction at %s, scaling down after %s without more", status.LastEviction.EvictionTime.UTC().Format(time.RFC3339), r.Config.CooldownFor(EvictionAutoScaler))
+	case stabilizationFor(EvictionAutoScaler)-time.Since(status.DrainedTime.Time) > 0:
+		requeue, reason, decision = stabilizationFor(EvictionAutoScaler)-time.Since(status.DrainedTime.Time), "Stabilizing", "stabilizing"
+		message = fmt.Sprintf("drain finished at %s, scaling down after %s without another", status.DrainedTime.UTC().Format(time.RFC3339), stabilizationFor(EvictionAutoScaler))
+	default:
+		node, err := r.drainingNodeWithPods(ctx, pdb)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		if node != "" {
+			return r.holdForDrainingNode(ctx, EvictionAutoScaler, pdb, node, statusChanged)
+		}
+		status.ScaleDownBlockingNode = ""
+		tracing.Decide(ctx, "scale-down")
+		return r.restoreAutoscaler(ctx, EvictionAutoScaler, autoscaler)
+	}
+	tracing.Decide(ctx, decision)
+	if setCondition(&status.Conditions, ConditionCoolingDown, metav1.ConditionTrue, reason, message) || statusChanged {
+		return ctrl.Result{RequeueAfter: requeue}, r.updateStatus(ctx, EvictionAutoScaler)
+	}
+	return ctrl.Result{RequeueAfter: requeue}, nil
+}
+
+// restoreAutoscaler puts back the minimum replicas recorded in status.autoscalerSurge.
+func (r *EvictionAutoScalerReconciler) restoreAutoscaler(ctx context.Context, EvictionAutoScaler *myappsv1.EvictionAutoScaler, autoscaler Autoscaler) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+	status := &EvictionAutoScaler.Status
+	targetKind, _ := targetKindAndName(EvictionAutoScaler)
+	name := autoscaler.Obj().GetName()
+	if r.DryRun {
+		events.DryRun(ctx, r.Recorder, EvictionAutoScaler, metrics.DryRunScaleTarget, "restore %s %s minimum replicas", autoscaler.Kind(), name)
+		return ctrl.Result{}, nil
+	}
+	autoscaler.SetMinReplicas(status.AutoscalerSurge.OriginalMinReplicas)
+	if err := r.updateAutoscaler(ctx, autoscaler); err != nil {
+		logger.Error(err, "failed to restore autoscaler minimum replicas", "kind", autoscaler.Kind(), "name", name)
+		return ctrl.Result{}, err
+	}
+
+	metrics.ScaleDownCounter.WithLabelValues(EvictionAutoScaler.Namespace, strings.ToLower(targetKind)).Inc()
+	r.setSurge(ctx, client.ObjectKeyFromObject(EvictionAutoScaler), 0)
+	logger.Info(fmt.Sprintf("Restored %s %s/%s minimum replicas to %d", autoscaler.Kind(), EvictionAutoScaler.Namespace, name, autoscaler.MinReplicas()))
+	events.Eventf(r.Recorder, EvictionAutoScaler, corev1.EventTypeNormal, events.ReasonSurgeScaledDown,
+		"Restored %s %s minimum replicas to %d after cooldown", autoscaler.Kind(), name, autoscaler.MinReplicas())
+	status.AutoscalerSurge = nil
+	status.HandledEviction = status.LastEviction
+	clearCondition(&status.Conditions, ConditionScalingUp, "ScaledDown", fmt.Sprintf("restored %s %s minimum replicas to %d", autoscaler.Kind(), name, autoscaler.MinReplicas()))
+	clearCondition(&status.Conditions, ConditionCoolingDown, "CooldownElapsed", "no evictions for "+r.Config.CooldownFor(EvictionAutoScaler).String())
+	setCondition(&status.Conditions, ConditionIdle, metav1.ConditionTrue, "ScaledDown", fmt.Sprintf("%s %s minimum replicas back at %d", autoscaler.Kind(), name, autoscaler.MinReplicas()))
+	ready(&status.Conditions, "Reconciled", "evictions hit cooldown so restored autoscaler minimum replicas")
+	return ctrl.Result{}, r.updateScaleStatus(ctx, EvictionAutoScaler)
+}
+
+// updateAutoscaler writes autoscaler's new minimum replicas back.
+func (r *EvictionAutoScalerReconciler) updateAutoscaler(ctx context.Context, autoscaler Autoscaler) error {
+	return tracing.Span(ctx, "UpdateAutoscaler", func(ctx context.Context) error {
+		return r.Update(ctx, autoscaler.Obj())
+	}, tracing.ReplicasKey.Int(int(autoscaler.MinReplicas())))
+}
@@ -0,0 +1,65 @@
+package controllers
+
+import (
+	"testing"
+
+	v1 "github.com/azure/eviction-autoscaler/api/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestScaledObjectWrapper(t *testing.T) {
+	scaledObject := &ScaledObjectWrapper{obj: &unstructured.Unstructured{Object: map[string]interface{}{
+		"spec": map[string]interface{}{
+			"scaleTargetRef": map[string]interface{}{"name": "web"},
+		},
+	}}}
+	if apiVersion, kind, name := scaledObject.ScaleTargetRef(); apiVersion != "apps/v1" || kind != "Deployment" || name != "web" {
+		t.Errorf("got scaleTargetRef %s %s %s, want KEDA's apps/v1 Deployment default", apiVersion, kind, name)
+	}
+	if scaledObject.GetMinReplicas() != nil || scaledObject.MinReplicas() != 0 || scaledObject.MaxReplicas() != 100 {
+		t.Errorf("got min %v/%d max %d, want KEDA's defaults of 0 and 100", scaledObject.GetMinReplicas(), scaledObject.MinReplicas(), scaledObject.MaxReplicas())
+	}
+
+	original := scaledObject.obj
+	three := int32(3)
+	scaledObject.SetMinReplicas(&three)
+	if scaledObject.MinReplicas() != 3 {
+		t.Errorf("got minReplicaCount %d after setting 3", scaledObject.MinReplicas())
+	}
+	if _, found, _ := unstructured.NestedInt64(original.Object, "spec", "minReplicaCount"); found {
+		t.Error("SetMinReplicas mutated the object it was read from")
+	}
+	scaledObject.SetMinReplicas(nil)
+	if _, found, _ := unstructured.NestedInt64(scaledObject.obj.Object, "spec", "minReplicaCount"); found {
+		t.Error("SetMinReplicas(nil) left minReplicaCount set")
+	}
+}
+
+func TestAutoscalerTargets(t *testing.T) {
+	hpa := func(apiVersion, kind, name string) Autoscaler {
+		return &HPAWrapper{obj: &autoscalingv2.HorizontalPodAutoscaler{Spec: autoscalingv2.HorizontalPodAutoscalerSpec{
+			ScaleTargetRef: autoscalingv2.CrossVersionObjectReference{APIVersion: apiVersion, Kind: kind, Name: name},
+		}}}
+	}
+	deployment := v1.EvictionAutoScalerSpec{TargetName: "web", TargetKind: "deployment"}
+	rollout := v1.EvictionAutoScalerSpec{TargetRef: &v1.TargetReference{APIVersion: "argoproj.io/v1alpha1", Kind: "Rollout", Name: "web"}}
+	tests := []struct {
+		autoscaler Autoscaler
+		spec       v1.EvictionAutoScalerSpec
+		want       bool
+	}{
+		{autoscaler: hpa("apps/v1", "Deployment", "web"), spec: deployment, want: true},
+		{autoscaler: hpa("apps/v1", "Deployment", "api"), spec: deployment},
+		{autoscaler: hpa("apps/v1", "StatefulSet", "web"), spec: deployment},
+		{autoscaler: hpa("argoproj.io/v1alpha1", "Rollout", "web"), spec: rollout, want: true},
+		{autoscaler: hpa("example.com/v1", "Rollout", "web"), spec: rollout},
+	}
+	for _, test := range tests {
+		EvictionAutoScaler := &v1.EvictionAutoScaler{Spec: test.spec}
+		if got := autoscalerTargets(test.autoscaler, EvictionAutoScaler); got != test.want {
+			apiVersion, kind, name := test.autoscaler.ScaleTargetRef()
+			t.Errorf("%s %s %s targeting %+v: got %v want %v", apiVersion, kind, name, test.spec, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package controllers
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	v1 "github.com/azure/eviction-autoscaler/api/v1"
+	"github.com/azure/eviction-autoscaler/internal/events"
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	policyv1 "k8s.io/api/policy/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// TestBaselineAdoption checks replicas someone else set during a surge become the baseline instead of being
+// scaled back, while a change that leaves the surge in place keeps it.
+func TestBaselineAdoption(t *testing.T) {
+	ctx := context.Background()
+	testScheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(testScheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := v1.AddToScheme(testScheme); err != nil {
+		t.Fatal(err)
+	}
+	key := types.NamespacedName{Namespace: "default", Name: "web"}
+	lastEviction := v1.Eviction{PodName: "web-1", EvictionTime: metav1.NewTime(time.Now().Add(-time.Second).Truncate(time.Second))}
+
+	tests := []struct {
+		name     string
+		replicas int32 // someone else set during the surge from 5 to 7
+		hpa      bool
+		baseline int32
+		surge    int32
+		adopted  bool
+	}{
+		{name: "manual scale up", replicas: 10, baseline: 10, adopted: true},
+		{name: "manual scale down", replicas: 6, baseline: 6, adopted: true},
+		{name: "new image", replicas: 7, baseline: 5, surge: 7},
+		// the hpa owns replicas so we neither adopt nor scale back what it set
+		{name: "hpa", replicas: 9, hpa: true, baseline: 5, surge: 7},
+	}
+	for _, test := range tests {
+		objects := []client.Object{
+			&appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default", Generation: 3},
+				Spec:       appsv1.DeploymentSpec{Replicas: ptr.To(test.replicas)},
+			},
+			&policyv1.PodDisruptionBudget{
+				ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+				Status:     policyv1.PodDisruptionBudgetStatus{DisruptionsAllowed: 1},
+			},
+			&v1.EvictionAutoScaler{
+				ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+				Spec:       v1.EvictionAutoScalerSpec{TargetName: "web", TargetKind: deploymentKind},
+				Status: v1.EvictionAutoScalerStatus{MinReplicas: 5, SurgeReplicas: 7, TargetGeneration: 2, LastEviction: lastEviction,
+					Conditions: []metav1.Condition{{Type: ConditionScalingUp, Status: metav1.ConditionTrue, Reason: "Surged", LastTransitionTime: metav1.Now()}}},
+			},
+		}
+		if test.hpa {
+			objects = append(objects, &autoscalingv2.HorizontalPodAutoscaler{
+				ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+				Spec: autoscalingv2.HorizontalPodAutoscalerSpec{
+					ScaleTargetRef: autoscalingv2.CrossVersionObjectReference{APIVersion: "apps/v1", Kind: "Deployment", Name: "web"},
+					MaxReplicas:    10,
+				},
+			})
+		}
+		fakeClient := fake.NewClientBuilder().WithScheme(testScheme).
+			WithStatusSubresource(&v1.EvictionAutoScaler{}).WithObjects(objects...).Build()
+		recorder := record.NewFakeRecorder(10)
+		r := &EvictionAutoScalerReconciler{Client: fakeClient, Scheme: testScheme, Recorder: recorder}
+		if _, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: key}); err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+
+		deployment := &appsv1.Deployment{}
+		if err := fakeClient.Get(ctx, key, deployment); err != nil {
+			t.Fatal(err)
+		}
+		if *deployment.Spec.Replicas != test.replicas {
+			t.Errorf("%s: got %d replicas, want %d left alone", test.name, *deployment.Spec.Replicas, test.replicas)
+		}
+		EvictionAutoScaler := &v1.EvictionAutoScaler{}
+		if err := fakeClient.Get(ctx, key, EvictionAutoScaler); err != nil {
+			t.Fatal(err)
+		}
+		if EvictionAutoScaler.Status.MinReplicas != test.baseline || EvictionAutoScaler.Status.SurgeReplicas != test.surge {
+			t.Errorf("%s: got baseline %d surge %d, want %d and %d", test.name,
+				EvictionAutoScaler.Status.MinReplicas, EvictionAutoScaler.Status.SurgeReplicas, test.baseline, test.surge)
+		}
+		if surging := meta.IsStatusConditionTrue(EvictionAutoScaler.Status.Conditions, ConditionScalingUp); surging == test.adopted {
+			t.Errorf("%s: got %s %v after adopting %v", test.name, ConditionScalingUp, surging, test.adopted)
+		}
+		adoptedEvent := false
+		for len(recorder.Events) > 0 {
+			if strings.Contains(<-recorder.Events, events.ReasonBaselineAdopted) {
+				adoptedEvent = true
+			}
+		}
+		if adoptedEvent != test.adopted {
+			t.Errorf("%s: got %s event %v, want %v", test.name, events.ReasonBaselineAdopted, adoptedEvent, test.adopted)
+		}
+	}
+}
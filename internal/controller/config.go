@@ -0,0 +1,65 @@
+package controllers
+
+import (
+	"time"
+
+	myappsv1 "github.com/azure/eviction-autoscaler/api/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// DefaultCooldown is the compiled in cooldown of EvictionAutoScalers without spec.cooldownSeconds when --cooldown isn't set.
+const DefaultCooldown = time.Minute
+
+// Config is what the reconcilers share from the command line. It is injected rather than global so tests can vary it,
+// and the zero value uses the compiled defaults.
+type Config struct {
+	// Cooldown is the cooldown of EvictionAutoScalers without spec.cooldownSeconds, from --cooldown. Zero uses DefaultCooldown.
+	Cooldown time.Duration
+	// EvictionTTL is the eviction ttl of EvictionAutoScalers without spec.evictionTTLSeconds, from --default-eviction-ttl.
+	// Zero uses DefaultEvictionTTL and a negative one never expires evictions.
+	EvictionTTL time.Duration
+	// Global is the EvictionAutoScalerConfig, whose fields win over the flags. Nil only uses the flags.
+	Global *GlobalConfig
+}
+
+// cooldown returns the EvictionAutoScalerConfig's cooldown, else the configured one or DefaultCooldown.
+func (c Config) cooldown() time.Duration {
+	if seconds := c.Global.Spec().CooldownSeconds; seconds != nil && *seconds > 0 {
+		return time.Duration(*seconds) * time.Second
+	}
+	if c.Cooldown <= 0 {
+		return DefaultCooldown
+	}
+	return c.Cooldown
+}
+
+// CooldownFor returns the EvictionAutoScaler's spec.cooldownSeconds, falling back to the configured cooldown when unset or zero.
+// It is the one place the cooldown order is decided, the eviction webhook uses it too.
+func (c Config) CooldownFor(EvictionAutoScaler *myappsv1.EvictionAutoScaler) time.Duration {
+	if EvictionAutoScaler.Spec.CooldownSeconds == nil || *EvictionAutoScaler.Spec.CooldownSeconds <= 0 {
+		return c.cooldown()
+	}
+	return time.Duration(*EvictionAutoScaler.Spec.CooldownSeconds) * time.Second
+}
+
+// surgeFor returns the EvictionAutoScaler's spec.surge, else the EvictionAutoScalerConfig's or DefaultSurge.
+func (c Config) surgeFor(EvictionAutoScaler *myappsv1.EvictionAutoScaler) intstr.IntOrString {
+	if EvictionAutoScaler.Spec.Surge != nil {
+		return *EvictionAutoScaler.Spec.Surge
+	}
+	if surge := c.Global.Spec().Surge; surge != nil {
+		return *surge
+	}
+	return myappsv1.DefaultSurge
+}
+
+// surgePolicyFor returns the EvictionAutoScaler's spec.surgePolicy, else the EvictionAutoScalerConfig's or Step.
+func (c Config) surgePolicyFor(EvictionAutoScaler *myappsv1.EvictionAutoScaler) string {
+	if EvictionAutoScaler.Spec.SurgePolicy != "" {
+		return EvictionAutoScaler.Spec.SurgePolicy
+	}
+	if policy := c.Global.Spec().SurgePolicy; policy != "" {
+		return policy
+	}
+	return myappsv1.SurgePolicyStep
+}
@@ -0,0 +1,244 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	v1 "github.com/azure/eviction-autoscaler/api/v1"
+	"github.com/azure/eviction-autoscaler/internal/namespacefilter"
+	appsv1 "k8s.io/api/apps/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func TestCooldownFor(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   Config
+		seconds  *int32
+		cooldown time.Duration
+	}{
+		{name: "compiled default", cooldown: DefaultCooldown},
+		{name: "flag", config: Config{Cooldown: 5 * time.Second}, cooldown: 5 * time.Second},
+		{name: "spec over flag", config: Config{Cooldown: 5 * time.Second}, seconds: ptr.To(int32(120)), cooldown: 2 * time.Minute},
+		{name: "spec over compiled default", seconds: ptr.To(int32(30)), cooldown: 30 * time.Second},
+		{name: "zero spec uses flag", config: Config{Cooldown: 5 * time.Second}, seconds: ptr.To(int32(0)), cooldown: 5 * time.Second},
+	}
+	for _, test := range tests {
+		EvictionAutoScaler := &v1.EvictionAutoScaler{Spec: v1.EvictionAutoScalerSpec{CooldownSeconds: test.seconds}}
+		if got := test.config.CooldownFor(EvictionAutoScaler); got != test.cooldown {
+			t.Errorf("%s: got %s want %s", test.name, got, test.cooldown)
+		}
+	}
+
+	// defaulting leaves the cooldown to the config, so a changed flag applies to existing EvictionAutoScalers
+	EvictionAutoScaler := &v1.EvictionAutoScaler{}
+	config := Config{Cooldown: 5 * time.Second}
+	EvictionAutoScaler.SetStaticDefaults()
+	if got := config.CooldownFor(EvictionAutoScaler); got != 5*time.Second {
+		t.Errorf("got %s after defaulting, want the flag's 5s", got)
+	}
+}
+
+// TestDefaultedEvictionAutoScaler checks the defaulting webhook no longer persists a cooldown or surge on create, as
+// it did with --cooldown and a surge of 1, and the controller resolves both from its config on every reconcile instead.
+func TestDefaultedEvictionAutoScaler(t *testing.T) {
+	EvictionAutoScaler := &v1.EvictionAutoScaler{ObjectMeta: metav1.ObjectMeta{Name: "web"}}
+	// what the defaulting webhook persists on create
+	EvictionAutoScaler.SetStaticDefaults()
+	if EvictionAutoScaler.Spec.CooldownSeconds != nil || EvictionAutoScaler.Spec.Surge != nil || EvictionAutoScaler.Spec.SurgePolicy != "" {
+		t.Fatalf("got defaulted spec %+v, want cooldownSeconds, surge and surgePolicy unset", EvictionAutoScaler.Spec)
+	}
+
+	config := Config{Cooldown: 2 * time.Minute}
+	if got := config.CooldownFor(EvictionAutoScaler); got != 2*time.Minute {
+		t.Errorf("got cooldown %s, want --cooldown's 2m", got)
+	}
+	if got := config.surgeFor(EvictionAutoScaler); got != v1.DefaultSurge || got.IntValue() != 1 {
+		t.Errorf("got surge %s, want 1", got.String())
+	}
+	if got := config.surgePolicyFor(EvictionAutoScaler); got != v1.SurgePolicyStep {
+		t.Errorf("got surge policy %s, want %s", got, v1.SurgePolicyStep)
+	}
+
+	// a later --cooldown applies to it too, which a cooldown persisted at creation wouldn't
+	config.Cooldown = 10 * time.Minute
+	if got := config.CooldownFor(EvictionAutoScaler); got != 10*time.Minute {
+		t.Errorf("got cooldown %s after changing --cooldown, want 10m", got)
+	}
+}
+
+func TestEvictionTTLFor(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  Config
+		seconds *int32
+		ttl     time.Duration
+	}{
+		{name: "compiled default", ttl: DefaultEvictionTTL},
+		{name: "flag", config: Config{EvictionTTL: 10 * time.Minute}, ttl: 10 * time.Minute},
+		{name: "never expire", config: Config{EvictionTTL: -1}, ttl: -1},
+		{name: "spec over flag", config: Config{EvictionTTL: 10 * time.Minute}, seconds: ptr.To(int32(60)), ttl: time.Minute},
+		{name: "zero spec uses flag", config: Config{EvictionTTL: -1}, seconds: ptr.To(int32(0)), ttl: -1},
+	}
+	for _, test := range tests {
+		EvictionAutoScaler := &v1.EvictionAutoScaler{Spec: v1.EvictionAutoScalerSpec{EvictionTTLSeconds: test.seconds}}
+		if got := test.config.evictionTTLFor(EvictionAutoScaler); got != test.ttl {
+			t.Errorf("%s: got %s want %s", test.name, got, test.ttl)
+		}
+	}
+}
+
+// TestGlobalConfig checks an EvictionAutoScalerConfig applies over the flags as it changes, loses to spec fields and
+// goes back to the flags once deleted.
+func TestGlobalConfig(t *testing.T) {
+	ctx := context.Background()
+	testScheme := runtime.NewScheme()
+	if err := v1.AddToScheme(testScheme); err != nil {
+		t.Fatal(err)
+	}
+	global := &v1.EvictionAutoScalerConfig{
+		ObjectMeta: metav1.ObjectMeta{Name: v1.EvictionAutoScalerConfigName},
+		Spec: v1.EvictionAutoScalerConfigSpec{CooldownSeconds: ptr.To(int32(300)), Surge: ptr.To(intstr.FromString("20%")),
+			NamespaceDenylist: []string{"tenant-b"}, MaxConcurrentNodeDrains: ptr.To(int32(2))},
+	}
+	fakeClient := fake.NewClientBuilder().WithScheme(testScheme).WithObjects(global).Build()
+	namespaces := namespacefilter.New(nil, []string{"kube-system"})
+	reconciler := &EvictionAutoScalerConfigReconciler{Client: fakeClient, Global: &GlobalConfig{}, Namespaces: namespaces}
+	config := Config{Cooldown: 5 * time.Second, Global: reconciler.Global}
+	nodeReconciler := &NodeReconciler{Config: config, Namespaces: namespaces, MaxConcurrentNodeDrains: 10}
+	reconcileConfig := func() {
+		t.Helper()
+		if _, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: types.NamespacedName{Name: v1.EvictionAutoScalerConfigName}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	reconcileConfig()
+	if got := config.CooldownFor(&v1.EvictionAutoScaler{}); got != 5*time.Minute {
+		t.Errorf("got cooldown %s, want the config's 5m over the flag", got)
+	}
+	if got := config.CooldownFor(&v1.EvictionAutoScaler{Spec: v1.EvictionAutoScalerSpec{CooldownSeconds: ptr.To(int32(30))}}); got != 30*time.Second {
+		t.Errorf("got cooldown %s, want spec.cooldownSeconds over the config", got)
+	}
+	pdbGap := &v1.EvictionAutoScaler{Spec: v1.EvictionAutoScalerSpec{SurgePolicy: v1.SurgePolicyPDBGap}}
+	if surge := config.surgeFor(pdbGap); surge.String() != "20%" || config.surgePolicyFor(pdbGap) != v1.SurgePolicyPDBGap {
+		t.Errorf("got surge %s and surge policy %s, want the config's surge and the spec's surge policy", surge.String(), config.surgePolicyFor(pdbGap))
+	}
+	if namespaces.Allowed("tenant-b") || !namespaces.Allowed("kube-system") {
+		t.Error("got the flag's denylist, want the config's")
+	}
+	effective := nodeReconciler.effectiveConfig()
+	if effective.MaxConcurrentNodeDrains != 2 || effective.CooldownSeconds != 300 || effective.Surge != "20%" || effective.Generation != global.Generation {
+		t.Errorf("got effective config %+v, want the config's", effective)
+	}
+
+	if err := fakeClient.Delete(ctx, global); err != nil {
+		t.Fatal(err)
+	}
+	reconcileConfig()
+	if got := config.CooldownFor(&v1.EvictionAutoScaler{}); got != 5*time.Second {
+		t.Errorf("got cooldown %s once the config was deleted, want the flag's", got)
+	}
+	if got := nodeReconciler.maxConcurrentNodeDrains(); got != 10 {
+		t.Errorf("got %d concurrent node drains once the config was deleted, want the flag's", got)
+	}
+	if !namespaces.Allowed("tenant-b") || namespaces.Allowed("kube-system") {
+		t.Error("got the config's denylist once it was deleted, want the flag's")
+	}
+	if surge := config.surgeFor(&v1.EvictionAutoScaler{}); surge != v1.DefaultSurge {
+		t.Errorf("got surge %s once the config was deleted, want the default", surge.String())
+	}
+}
+
+// TestGlobalConfigChangedCooldown changes the EvictionAutoScalerConfig's cooldown after an EvictionAutoScaler was
+// created and defaulted, and checks the next reconcile waits the new cooldown rather than one persisted at creation.
+func TestGlobalConfigChangedCooldown(t *testing.T) {
+	ctx := context.Background()
+	testScheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(testScheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := v1.AddToScheme(testScheme); err != nil {
+		t.Fatal(err)
+	}
+	key := types.NamespacedName{Namespace: "default", Name: "web"}
+	EvictionAutoScaler := &v1.EvictionAutoScaler{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+		Spec:       v1.EvictionAutoScalerSpec{TargetName: "web", TargetKind: deploymentKind},
+		Status: v1.EvictionAutoScalerStatus{
+			MinReplicas:      3,
+			TargetGeneration: 2,
+			LastEviction:     v1.Eviction{PodName: "web-1", EvictionTime: metav1.NewTime(time.Now().Add(-2 * time.Minute))},
+		},
+	}
+	// what the defaulting webhook persists on create
+	EvictionAutoScaler.SetStaticDefaults()
+	global := &v1.EvictionAutoScalerConfig{
+		ObjectMeta: metav1.ObjectMeta{Name: v1.EvictionAutoScalerConfigName},
+		Spec:       v1.EvictionAutoScalerConfigSpec{CooldownSeconds: ptr.To(int32(600))},
+	}
+	fakeClient := fake.NewClientBuilder().WithScheme(testScheme).
+		WithStatusSubresource(&v1.EvictionAutoScaler{}).
+		WithObjects(
+			EvictionAutoScaler,
+			global,
+			&appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default", Generation: 2},
+				Spec:       appsv1.DeploymentSpec{Replicas: ptr.To(int32(4))},
+			},
+			&policyv1.PodDisruptionBudget{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"}},
+		).
+		Build()
+	configReconciler := &EvictionAutoScalerConfigReconciler{Client: fakeClient, Global: &GlobalConfig{}}
+	reconcileConfig := func() {
+		t.Helper()
+		if _, err := configReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: types.NamespacedName{Name: v1.EvictionAutoScalerConfigName}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	r := &EvictionAutoScalerReconciler{Client: fakeClient, Scheme: testScheme, Recorder: record.NewFakeRecorder(10),
+		Config: Config{Cooldown: time.Hour, Global: configReconciler.Global}}
+	replicas := func() int32 {
+		t.Helper()
+		deployment := &appsv1.Deployment{}
+		if err := fakeClient.Get(ctx, key, deployment); err != nil {
+			t.Fatal(err)
+		}
+		return *deployment.Spec.Replicas
+	}
+
+	reconcileConfig()
+	result, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.RequeueAfter != 10*time.Minute || replicas() != 4 {
+		t.Errorf("got requeue after %s with %d replicas, want the config's 10m cooldown holding the surge", result.RequeueAfter, replicas())
+	}
+
+	if err := fakeClient.Get(ctx, client.ObjectKeyFromObject(global), global); err != nil {
+		t.Fatal(err)
+	}
+	global.Spec.CooldownSeconds = ptr.To(int32(60))
+	if err := fakeClient.Update(ctx, global); err != nil {
+		t.Fatal(err)
+	}
+	reconcileConfig()
+	if _, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: key}); err != nil {
+		t.Fatal(err)
+	}
+	if got := replicas(); got != 3 {
+		t.Errorf("got %d replicas, want the surge scaled down once the config's cooldown dropped to 1m", got)
+	}
+}
@@ -0,0 +1,158 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	myappsv1 "github.com/azure/eviction-autoscaler/api/v1"
+	"github.com/azure/eviction-autoscaler/internal/metrics"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// DebugStatePath is where DebugStateHandler is served on the metrics server.
+const DebugStatePath = "/debug/state"
+
+// the metrics server authenticates and authorizes /debug/state callers with these.
+// +kubebuilder:rbac:groups=authentication.k8s.io,resources=tokenreviews,verbs=create
+// +kubebuilder:rbac:groups=authorization.k8s.io,resources=subjectaccessreviews,verbs=create
+
+// assistedNode is what the last reconcile of a draining node found.
+type assistedNode struct {
+	// PodsRemaining counts the node's pods per EvictionAutoScaler, keyed namespace/name.
+	PodsRemaining map[string]int
+	LastError     string
+	Updated       time.Time
+	// Trigger and Resync are what the last reconcile drained for and requeued after, to back off while nothing changes.
+	Trigger string
+	Resync  time.Duration
+	// Progressed is when PodsRemaining last changed, zero if it hasn't since the drain started.
+	Progressed time.Time
+}
+
+// DebugState is the body of /debug/state.
+type DebugState struct {
+	Nodes               []DebugNode               `json:"nodes"`
+	EvictionAutoScalers []DebugEvictionAutoScaler `json:"evictionAutoScalers"`
+	// Config is the flags with the EvictionAutoScalerConfig applied over them.
+	Config EffectiveConfig `json:"config"`
+}
+
+// DebugNode is a cordoned node we are draining for, or failed to reconcile.
+type DebugNode struct {
+	Name          string         `json:"name"`
+	DrainStart    *time.Time     `json:"drainStart,omitempty"`
+	PodsRemaining map[string]int `json:"podsRemaining,omitempty"`
+	LastError     string         `json:"lastError,omitempty"`
+	Updated       time.Time      `json:"updated"`
+}
+
+// DebugEvictionAutoScaler is an EvictionAutoScaler triggered by a node, draining or still surged.
+type DebugEvictionAutoScaler struct {
+	Namespace     string     `json:"namespace"`
+	Name          string     `json:"name"`
+	DrainingNodes []string   `json:"drainingNodes,omitempty"`
+	Baseline      int32      `json:"baselineReplicas"`
+	Surge         int32      `json:"surgeReplicas"`
+	LastEviction  *time.Time `json:"lastEviction,omitempty"`
+	CooldownUntil *time.Time `json:"cooldownUntil,omitempty"`
+	// Conditions are the True ones, type to reason.
+	Conditions map[string]string `json:"conditions,omitempty"`
+	LastError  string            `json:"lastError,omitempty"`
+}
+
+// recordError keeps the last reconcile error of nodeName for /debug/state.
+func (r *NodeReconciler) recordError(nodeName string, err error) {
+	if err == nil {
+		return
+	}
+	var state assistedNode
+	if last, found := r.assisted.Load(nodeName); found {
+		state = last.(assistedNode)
+	}
+	state.LastError = err.Error()
+	state.Updated = time.Now()
+	r.assisted.Store(nodeName, state)
+}
+
+// DebugStateHandler serves the drains we are assisting as json, from what the node controller last saw
+// and EvictionAutoScaler status in the cache, so hitting it during an incident costs no api calls.
+func (r *NodeReconciler) DebugStateHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		state, err := r.debugState(req)
+		if err != nil {
+			log.FromContext(req.Context()).Error(err, "Unable to assemble debug state")
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		_ = encoder.Encode(state)
+	})
+}
+
+func (r *NodeReconciler) debugState(req *http.Request) (*DebugState, error) {
+	state := &DebugState{Nodes: []DebugNode{}, EvictionAutoScalers: []DebugEvictionAutoScaler{}, Config: r.effectiveConfig()}
+	triggered := map[string]bool{}
+	r.assisted.Range(func(name, value any) bool {
+		assisted := value.(assistedNode)
+		node := DebugNode{
+			Name:          name.(string),
+			PodsRemaining: assisted.PodsRemaining,
+			LastError:     assisted.LastError,
+			Updated:       assisted.Updated,
+		}
+		if start, found := r.drainStarts.Load(name); found {
+			drainStart := start.(time.Time)
+			node.DrainStart = &drainStart
+		}
+		for key := range assisted.PodsRemaining {
+			triggered[key] = true
+		}
+		state.Nodes = append(state.Nodes, node)
+		return true
+	})
+	slices.SortFunc(state.Nodes, func(a, b DebugNode) int { return strings.Compare(a.Name, b.Name) })
+
+	EvictionAutoScalerList := &myappsv1.EvictionAutoScalerList{}
+	if err := r.List(req.Context(), EvictionAutoScalerList); err != nil {
+		return nil, err
+	}
+	for i := range EvictionAutoScalerList.Items {
+		EvictionAutoScaler := &EvictionAutoScalerList.Items[i]
+		key := client.ObjectKeyFromObject(EvictionAutoScaler)
+		surge := metrics.SurgeReplicas(key)
+		if !triggered[key.String()] && len(EvictionAutoScaler.Status.DrainingNodes) == 0 && surge == 0 {
+			continue
+		}
+		debug := DebugEvictionAutoScaler{
+			Namespace:     EvictionAutoScaler.Namespace,
+			Name:          EvictionAutoScaler.Name,
+			DrainingNodes: EvictionAutoScaler.Status.DrainingNodes,
+			Baseline:      EvictionAutoScaler.Status.MinReplicas,
+			Surge:         surge,
+			Conditions:    map[string]string{},
+		}
+		if evictionTime := EvictionAutoScaler.Status.LastEviction.EvictionTime; !evictionTime.IsZero() {
+			lastEviction := evictionTime.Time
+			cooldownUntil := lastEviction.Add(r.Config.CooldownFor(EvictionAutoScaler))
+			debug.LastEviction, debug.CooldownUntil = &lastEviction, &cooldownUntil
+		}
+		for _, condition := range EvictionAutoScaler.Status.Conditions {
+			if condition.Status == metav1.ConditionTrue {
+				debug.Conditions[condition.Type] = condition.Reason
+			}
+		}
+		if degraded := meta.FindStatusCondition(EvictionAutoScaler.Status.Conditions, "Degraded"); degraded != nil && degraded.Status == metav1.ConditionTrue {
+			debug.LastError = degraded.Message
+		}
+		state.EvictionAutoScalers = append(state.EvictionAutoScalers, debug)
+	}
+	return state, nil
+}
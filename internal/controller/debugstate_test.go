@@ -0,0 +1,103 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"testing"
+
+	v1 "github.com/azure/eviction-autoscaler/api/v1"
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func TestDebugStateHandler(t *testing.T) {
+	ctx := context.Background()
+	testScheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(testScheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := v1.AddToScheme(testScheme); err != nil {
+		t.Fatal(err)
+	}
+	pod := func(name string) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", Labels: map[string]string{"app": "web"}},
+			Spec:       corev1.PodSpec{NodeName: "cordoned"},
+		}
+	}
+	fakeClient := fake.NewClientBuilder().
+		WithScheme(testScheme).
+		WithIndex(&corev1.Pod{}, NodeNameIndex, podNodeName).
+		WithStatusSubresource(&corev1.Pod{}, &v1.EvictionAutoScaler{}).
+		WithInterceptorFuncs(interceptor.Funcs{SubResourcePatch: fakeApplyPodStatus()}).
+		WithObjects(
+			&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "cordoned"}, Spec: corev1.NodeSpec{Unschedulable: true}},
+			pod("web-1"), pod("web-2"),
+			&v1.EvictionAutoScaler{
+				ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+				Spec:       v1.EvictionAutoScalerSpec{TargetName: "web", TargetKind: deploymentKind},
+				Status:     v1.EvictionAutoScalerStatus{MinReplicas: 3},
+			},
+			&v1.EvictionAutoScaler{ObjectMeta: metav1.ObjectMeta{Name: "idle", Namespace: "default"}},
+			&policyv1.PodDisruptionBudget{
+				ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+				Spec:       policyv1.PodDisruptionBudgetSpec{Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}}},
+			}).
+		Build()
+	nodeReconciler := &NodeReconciler{Client: fakeClient, Scheme: testScheme}
+	if _, err := nodeReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: types.NamespacedName{Name: "cordoned"}}); err != nil {
+		t.Fatal(err)
+	}
+	nodeReconciler.recordError("broken", errors.New("boom"))
+
+	recorder := httptest.NewRecorder()
+	nodeReconciler.DebugStateHandler().ServeHTTP(recorder, httptest.NewRequest("GET", DebugStatePath, nil))
+	state := DebugState{}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &state); err != nil {
+		t.Fatalf("%v: %s", err, recorder.Body)
+	}
+
+	if len(state.Nodes) != 2 || state.Nodes[0].Name != "broken" || state.Nodes[1].Name != "cordoned" {
+		t.Fatalf("got nodes %+v, want broken and cordoned", state.Nodes)
+	}
+	if state.Nodes[0].LastError != "boom" {
+		t.Errorf("got last error %q for broken", state.Nodes[0].LastError)
+	}
+	if cordoned := state.Nodes[1]; cordoned.PodsRemaining["default/web"] != 2 || cordoned.DrainStart == nil {
+		t.Errorf("got %+v for cordoned, want 2 pods remaining for default/web and a drain start", cordoned)
+	}
+	if len(state.EvictionAutoScalers) != 1 {
+		t.Fatalf("got EvictionAutoScalers %+v, want only web", state.EvictionAutoScalers)
+	}
+	web := state.EvictionAutoScalers[0]
+	if web.Name != "web" || web.Baseline != 3 || len(web.DrainingNodes) != 1 || web.CooldownUntil == nil ||
+		!web.CooldownUntil.Equal(web.LastEviction.Add(DefaultCooldown)) {
+		t.Errorf("got %+v for web", web)
+	}
+
+	// an uncordoned node is forgotten
+	node := &corev1.Node{}
+	if err := fakeClient.Get(ctx, client.ObjectKey{Name: "cordoned"}, node); err != nil {
+		t.Fatal(err)
+	}
+	node.Spec.Unschedulable = false
+	if err := fakeClient.Update(ctx, node); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := nodeReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: types.NamespacedName{Name: "cordoned"}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, found := nodeReconciler.assisted.Load("cordoned"); found {
+		t.Error("uncordoned node still in debug state")
+	}
+}
@@ -0,0 +1,47 @@
+package controllers
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// DrainAnnotation is a node annotation, such as a node problem agent's for a cloud provider's scheduled freeze or
+// redeploy event, that means the node is about to be drained. Value nil matches any value.
+type DrainAnnotation struct {
+	Key   string
+	Value *regexp.Regexp
+}
+
+// ParseDrainAnnotations parses --drain-annotations entries, each a key or key=regex matched against the whole value.
+func ParseDrainAnnotations(entries []string) ([]DrainAnnotation, error) {
+	var annotations []DrainAnnotation
+	for _, entry := range entries {
+		key, pattern, hasValue := strings.Cut(entry, "=")
+		if key == "" {
+			return nil, fmt.Errorf("drain annotation %q has no key", entry)
+		}
+		annotation := DrainAnnotation{Key: key}
+		if hasValue {
+			value, err := regexp.Compile("^(?:" + pattern + ")$")
+			if err != nil {
+				return nil, fmt.Errorf("drain annotation %s: %w", key, err)
+			}
+			annotation.Value = value
+		}
+		annotations = append(annotations, annotation)
+	}
+	return annotations, nil
+}
+
+// drainAnnotation returns the key of the first of DrainAnnotations node has, empty if none.
+func (r *NodeReconciler) drainAnnotation(node *corev1.Node) string {
+	for _, annotation := range r.DrainAnnotations {
+		if value, found := node.Annotations[annotation.Key]; found && (annotation.Value == nil || annotation.Value.MatchString(value)) {
+			return annotation.Key
+		}
+	}
+	return ""
+}
@@ -0,0 +1,41 @@
+package controllers
+
+import (
+	"testing"
+
+	"github.com/azure/eviction-autoscaler/internal/metrics"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestDrainAnnotations(t *testing.T) {
+	annotations, err := ParseDrainAnnotations([]string{"example.com/scheduled-event=Freeze|Redeploy", "example.com/retiring"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := &NodeReconciler{DrainAnnotations: annotations}
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		trigger     string
+	}{
+		{name: "matching value", annotations: map[string]string{"example.com/scheduled-event": "Redeploy"}, trigger: metrics.DrainTriggerAnnotation},
+		// the regex has to match the whole value
+		{name: "other value", annotations: map[string]string{"example.com/scheduled-event": "Reboot"}},
+		{name: "partial value", annotations: map[string]string{"example.com/scheduled-event": "FreezeLater"}},
+		{name: "any value", annotations: map[string]string{"example.com/retiring": ""}, trigger: metrics.DrainTriggerAnnotation},
+		{name: "removed"},
+	}
+	for _, test := range tests {
+		node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node", Annotations: test.annotations}}
+		if got := r.drainTrigger(node); got != test.trigger {
+			t.Errorf("%s: got trigger %q, want %q", test.name, got, test.trigger)
+		}
+	}
+
+	for _, entry := range []string{"=Freeze", "example.com/scheduled-event=("} {
+		if _, err := ParseDrainAnnotations([]string{entry}); err == nil {
+			t.Errorf("%q: got no error", entry)
+		}
+	}
+}
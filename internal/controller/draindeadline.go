@@ -0,0 +1,161 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	myappsv1 "github.com/azure/eviction-autoscaler/api/v1"
+	"github.com/azure/eviction-autoscaler/internal/events"
+	"github.com/azure/eviction-autoscaler/internal/evictionutil"
+	"github.com/azure/eviction-autoscaler/internal/metrics"
+	"github.com/azure/eviction-autoscaler/internal/tracing"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// maxDrainDurationFor returns spec.maxDrainDurationSeconds, zero for no deadline.
+func maxDrainDurationFor(EvictionAutoScaler *myappsv1.EvictionAutoScaler) time.Duration {
+	if deadline := EvictionAutoScaler.Spec.MaxDrainDurationSeconds; deadline != nil && *deadline > 0 {
+		return time.Duration(*deadline) * time.Second
+	}
+	return 0
+}
+
+// checkDrainDeadlines marks node's drain on each of the EvictionAutoScalers it still has pods for once it is past
+// their spec.maxDrainDurationSeconds, with a DrainDeadlineExceeded event on both. Returns how long till the next of
+// the deadlines not yet reached, zero if there is none.
+func (r *NodeReconciler) checkDrainDeadlines(ctx context.Context, node *corev1.Node, draining []*myappsv1.EvictionAutoScaler) (time.Duration, error) {
+	logger := log.FromContext(ctx)
+	var next time.Duration
+	for _, EvictionAutoScaler := range draining {
+		deadline := maxDrainDurationFor(EvictionAutoScaler)
+		drain := evictionutil.NodeDrainOf(&EvictionAutoScaler.Status, node.Name)
+		if deadline == 0 || drain == nil || drain.DeadlineExceeded {
+			continue
+		}
+		if wait := time.Until(drain.StartTime.Add(deadline)); wait > 0 {
+			if next == 0 || wait < next {
+				next = wait
+			}
+			continue
+		}
+		message := fmt.Sprintf("node %s is still draining %s after it started at %s, past maxDrainDurationSeconds",
+			node.Name, deadline, drain.StartTime.UTC().Format(time.RFC3339))
+		key := client.ObjectKeyFromObject(EvictionAutoScaler)
+		_, exceeded, err := evictionutil.ExceedDrainDeadline(ctx, r.Client, key, node.Name, message)
+		if err != nil {
+			if errors.IsNotFound(err) {
+				continue
+			}
+			logger.Error(err, "unable to mark drain deadline exceeded on EvictionAutoScaler", "name", key.Name)
+			return 0, err
+		}
+		if !exceeded {
+			continue
+		}
+		logger.Info("Node drain exceeded spec.maxDrainDurationSeconds", "name", key.Name, "namespace", key.Namespace, "node", node.Name, "deadline", deadline)
+		metrics.DrainDeadlineExceededCounter.WithLabelValues(key.Namespace, node.Name).Inc()
+		events.Eventf(r.Recorder, EvictionAutoScaler, corev1.EventTypeWarning, events.ReasonDrainDeadlineExceeded,
+			"Node %s is still draining after %s, past maxDrainDurationSeconds", node.Name, deadline)
+		events.Eventf(r.Recorder, node, corev1.EventTypeWarning, events.ReasonDrainDeadlineExceeded,
+			"Drain still has pods for EvictionAutoScaler %s/%s after %s, past its maxDrainDurationSeconds", key.Namespace, key.Name, deadline)
+	}
+	return next, nil
+}
+
+// surgeForDrainDeadline surges one more spec.surge step, still capped by maxReplicas, for nodes past
+// spec.maxDrainDurationSeconds that weren't surged for yet, a last try at unblocking them. Reports false if there
+// were none. Each node is surged for once, whether there was room or not.
+func (r *EvictionAutoScalerReconciler) surgeForDrainDeadline(ctx context.Context, EvictionAutoScaler *myappsv1.EvictionAutoScaler,
+	target Surger, targetKind, targetName string) (ctrl.Result, bool, error) {
+	logger := log.FromContext(ctx)
+	status := &EvictionAutoScaler.Status
+	var overdue []string
+	for _, drain := range status.NodeDrains {
+		if drain.DeadlineExceeded && !drain.DeadlineSurged {
+			overdue = append(overdue, drain.Node)
+		}
+	}
+	if len(overdue) == 0 {
+		return ctrl.Result{}, false, nil
+	}
+	tracing.Decide(ctx, "drain-deadline-surge")
+	surgedFor := func() {
+		for i := range status.NodeDrains {
+			if status.NodeDrains[i].DeadlineExceeded {
+				status.NodeDrains[i].DeadlineSurged = true
+			}
+		}
+	}
+	surge := r.Config.surgeFor(EvictionAutoScaler)
+	newReplicas, err := calculateSurge(surge, target.GetReplicas())
+	if err != nil {
+		logger.Error(err, "invalid surge", "surge", surge)
+		degraded(&status.Conditions, "InvalidSurge", err.Error())
+		return ctrl.Result{}, true, r.updateStatus(ctx, EvictionAutoScaler)
+	}
+	if maxReplicas := EvictionAutoScaler.Spec.MaxReplicas; maxReplicas != nil && newReplicas > *maxReplicas {
+		newReplicas = *maxReplicas
+	}
+	if newReplicas <= target.GetReplicas() {
+		message := fmt.Sprintf("nodes %v are past their drain deadline but maxReplicas leaves no room to surge again", overdue)
+		logger.Info(message)
+		events.Eventf(r.Recorder, EvictionAutoScaler, corev1.EventTypeWarning, events.ReasonSurgeLimited, message)
+		surgedFor()
+		return ctrl.Result{RequeueAfter: r.Config.CooldownFor(EvictionAutoScaler)}, true, r.updateStatus(ctx, EvictionAutoScaler)
+	}
+	// without room the nodes aren't marked surged for, so it is tried again once there is
+	if fits, err := r.spendSurgeBudget(ctx, EvictionAutoScaler, newReplicas-status.MinReplicas,
+		fmt.Sprintf("scale up %s %s to %d replicas for nodes %v past their drain deadline", targetKind, targetName, newReplicas, overdue)); err != nil || !fits {
+		if err != nil {
+			return ctrl.Result{}, true, err
+		}
+		return ctrl.Result{RequeueAfter: r.Config.CooldownFor(EvictionAutoScaler)}, true, r.updateStatus(ctx, EvictionAutoScaler)
+	}
+	if r.DryRun {
+		events.DryRun(ctx, r.Recorder, EvictionAutoScaler, metrics.DryRunScaleTarget, "scale up %s %s to %d replicas for nodes %v past their drain deadline",
+			targetKind, targetName, newReplicas, overdue)
+		// nothing was scaled, so give back the budget reserved for it
+		r.setSurge(ctx, client.ObjectKeyFromObject(EvictionAutoScaler), target.GetReplicas()-status.MinReplicas)
+		return ctrl.Result{RequeueAfter: r.Config.CooldownFor(EvictionAutoScaler)}, true, nil
+	}
+	added := newReplicas - target.GetReplicas()
+	target.SetReplicas(newReplicas)
+	target.AddAnnotation(EvictionSurgeReplicasAnnotationKey, strconv.FormatInt(int64(newReplicas), 10))
+	if err := r.updateTarget(ctx, target); err != nil {
+		logger.Error(err, "failed to update Target", "kind", targetKind, "targetname", targetName)
+		return ctrl.Result{}, true, err
+	}
+	metrics.ActualScalingCounter.WithLabelValues(EvictionAutoScaler.Namespace, targetName, metrics.ScaleUpAction).Inc()
+	metrics.ScaleUpCounter.WithLabelValues(EvictionAutoScaler.Namespace, strings.ToLower(targetKind)).Inc()
+	metrics.ScaleUpReplicasCounter.WithLabelValues(EvictionAutoScaler.Namespace, strings.ToLower(targetKind)).Add(float64(added))
+	r.setSurge(ctx, client.ObjectKeyFromObject(EvictionAutoScaler), newReplicas-status.MinReplicas)
+	logger.Info(fmt.Sprintf("Scaled up %s %s to %d replicas for nodes %v past their drain deadline", targetKind, targetName, newReplicas, overdue))
+	events.Eventf(r.Recorder, EvictionAutoScaler, corev1.EventTypeNormal, events.ReasonSurgeScaledUp,
+		"Scaled up %s %s to %d replicas, a last surge for nodes %v past their drain deadline", targetKind, targetName, newReplicas, overdue)
+	status.TargetGeneration = target.Obj().GetGeneration()
+	status.SurgeReplicas = newReplicas
+	status.LastScaleTime = metav1.Now()
+	surgePending(EvictionAutoScaler, targetKind, targetName, newReplicas)
+	surgedFor()
+	return ctrl.Result{RequeueAfter: r.Config.CooldownFor(EvictionAutoScaler)}, true, r.updateScaleStatus(ctx, EvictionAutoScaler)
+}
+
+// exceededDrains counts the nodes past their drain deadline, for the watch predicate to pick up the node controller
+// marking one.
+func exceededDrains(status *myappsv1.EvictionAutoScalerStatus) int {
+	exceeded := 0
+	for _, drain := range status.NodeDrains {
+		if drain.DeadlineExceeded {
+			exceeded++
+		}
+	}
+	return exceeded
+}
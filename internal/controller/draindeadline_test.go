@@ -0,0 +1,197 @@
+package controllers
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	v1 "github.com/azure/eviction-autoscaler/api/v1"
+	"github.com/azure/eviction-autoscaler/internal/events"
+	"github.com/azure/eviction-autoscaler/internal/evictionutil"
+	"github.com/azure/eviction-autoscaler/internal/metrics"
+	"github.com/azure/eviction-autoscaler/internal/selectorcache"
+	dto "github.com/prometheus/client_model/go"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// TestDrainDeadline reconciles a node draining for longer than spec.maxDrainDurationSeconds. The drain is marked
+// exceeded once, with events on the node and EvictionAutoScaler, and the condition goes back to false once the node is
+// done.
+func TestDrainDeadline(t *testing.T) {
+	ctx := context.Background()
+	testScheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(testScheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := v1.AddToScheme(testScheme); err != nil {
+		t.Fatal(err)
+	}
+	key := types.NamespacedName{Name: "web", Namespace: "deadline"}
+	started := metav1.NewTime(time.Now().Add(-2 * time.Minute).Truncate(time.Second))
+	fakeClient := fake.NewClientBuilder().
+		WithScheme(testScheme).
+		WithIndex(&corev1.Pod{}, NodeNameIndex, podNodeName).
+		WithStatusSubresource(&corev1.Pod{}, &v1.EvictionAutoScaler{}).
+		WithInterceptorFuncs(interceptor.Funcs{SubResourcePatch: fakeApplyPodStatus()}).
+		WithObjects(
+			&v1.EvictionAutoScaler{
+				ObjectMeta: metav1.ObjectMeta{Name: key.Name, Namespace: key.Namespace},
+				Spec:       v1.EvictionAutoScalerSpec{TargetName: "web", TargetKind: deploymentKind, MaxDrainDurationSeconds: ptr.To(int32(60))},
+				Status: v1.EvictionAutoScalerStatus{
+					DrainingNodes: []string{"draining"},
+					NodeDrains:    []v1.NodeDrain{{Node: "draining", StartTime: started}},
+				},
+			},
+			&policyv1.PodDisruptionBudget{
+				ObjectMeta: metav1.ObjectMeta{Name: key.Name, Namespace: key.Namespace},
+				Spec: policyv1.PodDisruptionBudgetSpec{
+					Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}},
+				},
+			},
+			&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "draining"}, Spec: corev1.NodeSpec{Unschedulable: true}},
+			&corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Name: "web-1", Namespace: key.Namespace, Labels: map[string]string{"app": "web"}},
+				Spec:       corev1.PodSpec{NodeName: "draining"},
+			},
+		).
+		Build()
+	recorder := record.NewFakeRecorder(20)
+	nodeReconciler := &NodeReconciler{Client: fakeClient, Scheme: testScheme, Recorder: recorder, Selectors: selectorcache.New()}
+	request := reconcile.Request{NamespacedName: types.NamespacedName{Name: "draining"}}
+	for range 2 {
+		if _, err := nodeReconciler.Reconcile(ctx, request); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	EvictionAutoScaler := &v1.EvictionAutoScaler{}
+	if err := fakeClient.Get(ctx, key, EvictionAutoScaler); err != nil {
+		t.Fatal(err)
+	}
+	if drain := evictionutil.NodeDrainOf(&EvictionAutoScaler.Status, "draining"); drain == nil || !drain.DeadlineExceeded || !drain.StartTime.Equal(&started) {
+		t.Errorf("got node drain %+v, want the deadline exceeded from %v", drain, started)
+	}
+	if !meta.IsStatusConditionTrue(EvictionAutoScaler.Status.Conditions, evictionutil.ConditionExceededDrainDeadline) {
+		t.Errorf("got conditions %+v, want %s", EvictionAutoScaler.Status.Conditions, evictionutil.ConditionExceededDrainDeadline)
+	}
+	m := &dto.Metric{}
+	if err := metrics.DrainDeadlineExceededCounter.WithLabelValues(key.Namespace, "draining").Write(m); err != nil {
+		t.Fatal(err)
+	}
+	if got := m.GetCounter().GetValue(); got != 1 {
+		t.Errorf("got %v drains counted past their deadline, want 1", got)
+	}
+	exceeded := 0
+	for len(recorder.Events) > 0 {
+		if strings.Contains(<-recorder.Events, events.ReasonDrainDeadlineExceeded) {
+			exceeded++
+		}
+	}
+	if exceeded != 2 {
+		t.Errorf("got %d %s events, want one on the node and one on the EvictionAutoScaler", exceeded, events.ReasonDrainDeadlineExceeded)
+	}
+
+	if err := fakeClient.Delete(ctx, &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "web-1", Namespace: key.Namespace}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := nodeReconciler.Reconcile(ctx, request); err != nil {
+		t.Fatal(err)
+	}
+	if err := fakeClient.Get(ctx, key, EvictionAutoScaler); err != nil {
+		t.Fatal(err)
+	}
+	if len(EvictionAutoScaler.Status.NodeDrains) != 0 || !meta.IsStatusConditionFalse(EvictionAutoScaler.Status.Conditions, evictionutil.ConditionExceededDrainDeadline) {
+		t.Errorf("got node drains %+v and conditions %+v after the drain, want none and %s false",
+			EvictionAutoScaler.Status.NodeDrains, EvictionAutoScaler.Status.Conditions, evictionutil.ConditionExceededDrainDeadline)
+	}
+}
+
+// TestDrainDeadlineSurge checks spec.drainDeadlineSurge surges one more step for a node past its deadline, and only once.
+func TestDrainDeadlineSurge(t *testing.T) {
+	ctx := context.Background()
+	testScheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(testScheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := v1.AddToScheme(testScheme); err != nil {
+		t.Fatal(err)
+	}
+	key := types.NamespacedName{Name: "web", Namespace: "deadline-surge"}
+	surged := metav1.NewTime(time.Now().Add(-time.Hour).Truncate(time.Second))
+	fakeClient := fake.NewClientBuilder().
+		WithScheme(testScheme).
+		WithStatusSubresource(&v1.EvictionAutoScaler{}, &appsv1.Deployment{}).
+		WithObjects(
+			&v1.EvictionAutoScaler{
+				ObjectMeta: metav1.ObjectMeta{Name: key.Name, Namespace: key.Namespace},
+				Spec: v1.EvictionAutoScalerSpec{TargetName: "web", TargetKind: deploymentKind,
+					MaxDrainDurationSeconds: ptr.To(int32(60)), DrainDeadlineSurge: true},
+				Status: v1.EvictionAutoScalerStatus{
+					MinReplicas:      3,
+					SurgeReplicas:    4,
+					TargetGeneration: 1,
+					LastEviction:     v1.Eviction{PodName: "web-1", EvictionTime: surged, Source: v1.EvictionSourceNodeCordon, Node: "draining"},
+					DrainingNodes:    []string{"draining"},
+					NodeDrains:       []v1.NodeDrain{{Node: "draining", StartTime: surged, DeadlineExceeded: true}},
+					Conditions:       []metav1.Condition{{Type: ConditionScalingUp, Status: metav1.ConditionTrue, Reason: "Surged", LastTransitionTime: surged}},
+				},
+			},
+			&appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: key.Namespace, Generation: 1},
+				Spec:       appsv1.DeploymentSpec{Replicas: ptr.To(int32(4))},
+				Status:     appsv1.DeploymentStatus{AvailableReplicas: 4},
+			},
+			&policyv1.PodDisruptionBudget{
+				ObjectMeta: metav1.ObjectMeta{Name: key.Name, Namespace: key.Namespace},
+				Spec:       policyv1.PodDisruptionBudgetSpec{Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}}},
+			},
+		).
+		Build()
+	r := &EvictionAutoScalerReconciler{Client: fakeClient, Scheme: testScheme, Recorder: record.NewFakeRecorder(20)}
+	for range 2 {
+		if _, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: key}); err != nil {
+			t.Fatal(err)
+		}
+		// the surge comes up and takes the target's new generation with it
+		deployment := &appsv1.Deployment{}
+		if err := fakeClient.Get(ctx, client.ObjectKey{Name: "web", Namespace: key.Namespace}, deployment); err != nil {
+			t.Fatal(err)
+		}
+		deployment.Status.AvailableReplicas = *deployment.Spec.Replicas
+		if err := fakeClient.Status().Update(ctx, deployment); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	deployment := &appsv1.Deployment{}
+	if err := fakeClient.Get(ctx, client.ObjectKey{Name: "web", Namespace: key.Namespace}, deployment); err != nil {
+		t.Fatal(err)
+	}
+	if got := *deployment.Spec.Replicas; got != 5 {
+		t.Errorf("got %d replicas, want one more step to 5", got)
+	}
+	EvictionAutoScaler := &v1.EvictionAutoScaler{}
+	if err := fakeClient.Get(ctx, key, EvictionAutoScaler); err != nil {
+		t.Fatal(err)
+	}
+	if drain := evictionutil.NodeDrainOf(&EvictionAutoScaler.Status, "draining"); drain == nil || !drain.DeadlineSurged {
+		t.Errorf("got node drain %+v, want it surged for", drain)
+	}
+	if EvictionAutoScaler.Status.SurgeReplicas != 5 || EvictionAutoScaler.Status.MinReplicas != 3 {
+		t.Errorf("got surge %d over baseline %d, want 5 over 3", EvictionAutoScaler.Status.SurgeReplicas, EvictionAutoScaler.Status.MinReplicas)
+	}
+}
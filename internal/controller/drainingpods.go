@@ -0,0 +1,83 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	myappsv1 "github.com/azure/eviction-autoscaler/api/v1"
+	"github.com/azure/eviction-autoscaler/internal/tracing"
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// +kubebuilder:rbac:groups=core,resources=nodes,verbs=get;list;watch
+
+// drainingNodeWithPods returns the first cordoned or DrainTaints tainted node by name that still has pods pdb selects,
+// empty if none has. It doesn't wait for the node controller to add the node to status.drainingNodes, so a surge
+// isn't scaled down when one node finishes while another node still has the workload's pods to drain. Nodes
+// stood down from as stale cordons or disabled are left out since the node controller no longer drains for them.
+// Pods are listed per draining node through the NodeNameIndex, so only the few draining nodes cost a list.
+func (r *EvictionAutoScalerReconciler) drainingNodeWithPods(ctx context.Context, pdb *policyv1.PodDisruptionBudget) (string, error) {
+	selector, err := metav1.LabelSelectorAsSelector(pdb.Spec.Selector)
+	if err != nil || selector.Empty() {
+		return "", nil // invalid selectors never match anything, empty ones would hold for every pod of the namespace
+	}
+	nodes := &corev1.NodeList{}
+	if err := r.List(ctx, nodes); err != nil {
+		return "", err
+	}
+	slices.SortFunc(nodes.Items, func(a, b corev1.Node) int { return strings.Compare(a.Name, b.Name) })
+	for i := range nodes.Items {
+		node := &nodes.Items[i]
+		if !r.nodeDraining(node) {
+			continue
+		}
+		pods := &corev1.PodList{}
+		if err := r.List(ctx, pods, client.InNamespace(pdb.Namespace), client.MatchingLabelsSelector{Selector: selector},
+			client.MatchingFields{NodeNameIndex: node.Name}); err != nil {
+			return "", err
+		}
+		for _, pod := range pods.Items {
+			// evicted already, its replacement is what the surge made room for
+			if pod.DeletionTimestamp == nil && pod.Status.Phase != corev1.PodSucceeded && pod.Status.Phase != corev1.PodFailed {
+				return node.Name, nil
+			}
+		}
+	}
+	return "", nil
+}
+
+// nodeDraining returns true if node is cordoned or has one of the DrainTaints, unless it was stood down from.
+func (r *EvictionAutoScalerReconciler) nodeDraining(node *corev1.Node) bool {
+	if _, stood := node.Annotations[StaleCordonAnnotationKey]; stood || disabled(node) {
+		return false
+	}
+	if node.Spec.Unschedulable {
+		return true
+	}
+	return slices.ContainsFunc(node.Spec.Taints, func(taint corev1.Taint) bool { return slices.Contains(r.DrainTaints, taint.Key) })
+}
+
+// holdForDrainingNode keeps EvictionAutoScaler's surge, recording node in status.scaleDownBlockingNode, while node
+// still has pods pdb selects and checks again after the cooldown.
+func (r *EvictionAutoScalerReconciler) holdForDrainingNode(ctx context.Context, EvictionAutoScaler *myappsv1.EvictionAutoScaler,
+	pdb *policyv1.PodDisruptionBudget, node string, statusChanged bool) (ctrl.Result, error) {
+	status := &EvictionAutoScaler.Status
+	message := fmt.Sprintf("holding the surge till draining node %s has no more pods pdb %s selects", node, pdb.Name)
+	log.FromContext(ctx).Info("Holding surge for pods on draining node", "node", node, "pdb", pdb.Name)
+	tracing.Decide(ctx, "hold-draining-pods")
+	if status.ScaleDownBlockingNode != node {
+		status.ScaleDownBlockingNode = node
+		statusChanged = true
+	}
+	if setCondition(&status.Conditions, ConditionCoolingDown, metav1.ConditionTrue, "PodsOnDrainingNode", message) || statusChanged {
+		return ctrl.Result{RequeueAfter: r.Config.CooldownFor(EvictionAutoScaler)}, r.updateStatus(ctx, EvictionAutoScaler)
+	}
+	return ctrl.Result{RequeueAfter: r.Config.CooldownFor(EvictionAutoScaler)}, nil
+}
@@ -0,0 +1,119 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	v1 "github.com/azure/eviction-autoscaler/api/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// TestHoldForDrainingPods drains two nodes hosting pods of the same workload. The surge stays up once the first is
+// done while the second, which the node controller hasn't recorded, still has one, and a drain tainted node holds it too.
+func TestHoldForDrainingPods(t *testing.T) {
+	ctx := context.Background()
+	testScheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(testScheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := v1.AddToScheme(testScheme); err != nil {
+		t.Fatal(err)
+	}
+	key := types.NamespacedName{Namespace: "default", Name: "web"}
+	pod := func(name, nodeName string, phase corev1.PodPhase) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", Labels: map[string]string{"app": "web"}},
+			Spec:       corev1.PodSpec{NodeName: nodeName},
+			Status:     corev1.PodStatus{Phase: phase},
+		}
+	}
+	cordoned := func(name string) *corev1.Node {
+		return &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: name}, Spec: corev1.NodeSpec{Unschedulable: true}}
+	}
+	lastEviction := v1.Eviction{PodName: "web-1", EvictionTime: metav1.NewTime(time.Now().Add(-10 * time.Minute).Truncate(time.Second))}
+	fakeClient := fake.NewClientBuilder().WithScheme(testScheme).
+		WithStatusSubresource(&v1.EvictionAutoScaler{}).
+		WithIndex(&corev1.Pod{}, NodeNameIndex, podNodeName).
+		WithObjects(
+			&appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default", Generation: 2},
+				Spec:       appsv1.DeploymentSpec{Replicas: ptr.To(int32(4))},
+			},
+			&policyv1.PodDisruptionBudget{
+				ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+				Spec:       policyv1.PodDisruptionBudgetSpec{Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}}},
+				Status:     policyv1.PodDisruptionBudgetStatus{DisruptionsAllowed: 1},
+			},
+			&v1.EvictionAutoScaler{
+				ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+				Spec:       v1.EvictionAutoScalerSpec{TargetName: "web", TargetKind: deploymentKind, CooldownSeconds: ptr.To(int32(60))},
+				Status:     v1.EvictionAutoScalerStatus{MinReplicas: 3, SurgeReplicas: 4, TargetGeneration: 2, LastEviction: lastEviction},
+			},
+			// a is drained, a finished pod doesn't count
+			cordoned("node-a"),
+			pod("web-job", "node-a", corev1.PodSucceeded),
+			cordoned("node-b"),
+			pod("web-2", "node-b", corev1.PodRunning),
+			&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-c"}},
+			pod("web-3", "node-c", corev1.PodRunning),
+			&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-d"},
+				Spec: corev1.NodeSpec{Taints: []corev1.Taint{{Key: "ToBeDeletedByClusterAutoscaler", Effect: corev1.TaintEffectNoSchedule}}}},
+		).Build()
+	r := &EvictionAutoScalerReconciler{Client: fakeClient, Scheme: testScheme, Recorder: record.NewFakeRecorder(10), DrainTaints: DefaultDrainTaints}
+	reconcileWeb := func() (int32, *v1.EvictionAutoScaler) {
+		t.Helper()
+		if _, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: key}); err != nil {
+			t.Fatal(err)
+		}
+		deployment := &appsv1.Deployment{}
+		if err := fakeClient.Get(ctx, key, deployment); err != nil {
+			t.Fatal(err)
+		}
+		EvictionAutoScaler := &v1.EvictionAutoScaler{}
+		if err := fakeClient.Get(ctx, key, EvictionAutoScaler); err != nil {
+			t.Fatal(err)
+		}
+		return *deployment.Spec.Replicas, EvictionAutoScaler
+	}
+	held := func(node string) {
+		t.Helper()
+		replicas, EvictionAutoScaler := reconcileWeb()
+		condition := meta.FindStatusCondition(EvictionAutoScaler.Status.Conditions, ConditionCoolingDown)
+		if replicas != 4 || EvictionAutoScaler.Status.ScaleDownBlockingNode != node || condition == nil || condition.Reason != "PodsOnDrainingNode" {
+			t.Fatalf("got %d replicas, scaleDownBlockingNode %q and %s %+v, want the surge of 4 held for %s",
+				replicas, EvictionAutoScaler.Status.ScaleDownBlockingNode, ConditionCoolingDown, condition, node)
+		}
+	}
+
+	held("node-b")
+
+	// b is drained while its replacement pod goes to the drain tainted node
+	if err := fakeClient.Delete(ctx, pod("web-2", "node-b", corev1.PodRunning)); err != nil {
+		t.Fatal(err)
+	}
+	if err := fakeClient.Create(ctx, pod("web-4", "node-d", corev1.PodRunning)); err != nil {
+		t.Fatal(err)
+	}
+	held("node-d")
+
+	if err := fakeClient.Delete(ctx, pod("web-4", "node-d", corev1.PodRunning)); err != nil {
+		t.Fatal(err)
+	}
+	replicas, EvictionAutoScaler := reconcileWeb()
+	if replicas != 3 || EvictionAutoScaler.Status.ScaleDownBlockingNode != "" {
+		t.Errorf("got %d replicas and scaleDownBlockingNode %q once no draining node had pods, want it scaled down to 3 and cleared",
+			replicas, EvictionAutoScaler.Status.ScaleDownBlockingNode)
+	}
+}
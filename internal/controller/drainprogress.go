@@ -0,0 +1,164 @@
+package controllers
+
+import (
+	"context"
+	"slices"
+	"strings"
+
+	pdbautoscaler "github.com/azure/eviction-autoscaler/api/v1"
+	corev1 "k8s.io/api/core/v1"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// reportDrainProgress creates or updates the NodeDrainProgress of node from the pods of assists, the ones still left
+// on it for an EvictionAutoScaler. Pods gone from an EvictionAutoScaler since the last report count as moved.
+func (r *NodeReconciler) reportDrainProgress(ctx context.Context, node *corev1.Node, trigger string, assists []podAssist) error {
+	if !r.drainProgressEnabled() || r.DryRun {
+		return nil
+	}
+	now := metav1.Now()
+	status := pdbautoscaler.NodeDrainProgressStatus{
+		Phase:         pdbautoscaler.NodeDrainPhaseDraining,
+		Trigger:       trigger,
+		PodsRemaining: int32(len(assists)),
+	}
+	for _, assist := range assists {
+		i := slices.IndexFunc(status.EvictionAutoScalers, func(scaler pdbautoscaler.NodeDrainEvictionAutoScaler) bool {
+			return scaler.Namespace == assist.EvictionAutoScaler.Namespace && scaler.Name == assist.EvictionAutoScaler.Name
+		})
+		if i < 0 {
+			status.EvictionAutoScalers = append(status.EvictionAutoScalers, pdbautoscaler.NodeDrainEvictionAutoScaler{
+				Namespace:    assist.EvictionAutoScaler.Namespace,
+				Name:         assist.EvictionAutoScaler.Name,
+				CurrentSurge: assist.EvictionAutoScaler.Status.CurrentSurge,
+			})
+			i = len(status.EvictionAutoScalers) - 1
+		}
+		status.EvictionAutoScalers[i].PodsRemaining++
+	}
+	slices.SortFunc(status.EvictionAutoScalers, func(a, b pdbautoscaler.NodeDrainEvictionAutoScaler) int {
+		return strings.Compare(a.Namespace+"/"+a.Name, b.Namespace+"/"+b.Name)
+	})
+
+	progress := &pdbautoscaler.NodeDrainProgress{}
+	err := r.Get(ctx, types.NamespacedName{Name: node.Name}, progress)
+	if errors.IsNotFound(err) {
+		start := now
+		if started, found := drainStart(node); found {
+			start = metav1.NewTime(started)
+		}
+		status.StartTime, status.LastActivityTime = start, start
+		progress = &pdbautoscaler.NodeDrainProgress{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: node.Name,
+				// garbage collected with the node should we miss its delete
+				OwnerReferences: []metav1.OwnerReference{{APIVersion: "v1", Kind: "Node", Name: node.Name, UID: node.UID}},
+			},
+			Spec: pdbautoscaler.NodeDrainProgressSpec{NodeName: node.Name},
+		}
+		if err := r.Create(ctx, progress); err != nil {
+			return r.drainProgressError(ctx, node.Name, "create", err)
+		}
+		r.progressCreated.Store(node.Name, true)
+		progress.Status = status
+		return r.drainProgressError(ctx, node.Name, "update", r.Status().Update(ctx, progress))
+	}
+	if err != nil {
+		return r.drainProgressError(ctx, node.Name, "get", err)
+	}
+
+	status.StartTime, status.LastActivityTime = progress.Status.StartTime, progress.Status.LastActivityTime
+	status.PodsMoved = progress.Status.PodsMoved
+	for _, previous := range progress.Status.EvictionAutoScalers {
+		left := previous.PodsRemaining
+		if i := slices.IndexFunc(status.EvictionAutoScalers, func(scaler pdbautoscaler.NodeDrainEvictionAutoScaler) bool {
+			return scaler.Namespace == previous.Namespace && scaler.Name == previous.Name
+		}); i >= 0 {
+			left -= status.EvictionAutoScalers[i].PodsRemaining
+		}
+		status.PodsMoved += max(left, 0)
+	}
+	if status.PodsMoved > progress.Status.PodsMoved {
+		status.LastActivityTime = now
+	}
+	return r.writeDrainProgress(ctx, progress, status)
+}
+
+// completeDrainProgress marks the NodeDrainProgress of node Complete once none of its pods are left for an
+// EvictionAutoScaler, counting the pods still on it at the last report as moved.
+func (r *NodeReconciler) completeDrainProgress(ctx context.Context, node *corev1.Node) error {
+	if !r.drainProgressEnabled() || r.DryRun {
+		return nil
+	}
+	progress := &pdbautoscaler.NodeDrainProgress{}
+	if err := r.Get(ctx, types.NamespacedName{Name: node.Name}, progress); err != nil {
+		if errors.IsNotFound(err) {
+			return nil
+		}
+		return r.drainProgressError(ctx, node.Name, "get", err)
+	}
+	if progress.Status.Phase == pdbautoscaler.NodeDrainPhaseComplete {
+		return nil
+	}
+	status := *progress.Status.DeepCopy()
+	status.Phase = pdbautoscaler.NodeDrainPhaseComplete
+	if status.PodsRemaining > 0 {
+		status.PodsMoved += status.PodsRemaining
+		status.LastActivityTime = metav1.Now()
+	}
+	status.PodsRemaining = 0
+	status.EvictionAutoScalers = nil
+	return r.writeDrainProgress(ctx, progress, status)
+}
+
+// writeDrainProgress updates the status of progress to status unless it already is.
+func (r *NodeReconciler) writeDrainProgress(ctx context.Context, progress *pdbautoscaler.NodeDrainProgress, status pdbautoscaler.NodeDrainProgressStatus) error {
+	if apiequality.Semantic.DeepEqual(progress.Status, status) {
+		return nil
+	}
+	progress.Status = status
+	return r.drainProgressError(ctx, progress.Name, "update", r.Status().Update(ctx, progress))
+}
+
+// deleteDrainProgress deletes the NodeDrainProgress of nodeName once its drain is over, uncordoned, disabled, stood
+// down from or the node deleted. Most nodes reconciled here never drained, so it is only deleted if we created it or
+// the cache has it, rather than sending a delete for every node on each start and resync.
+func (r *NodeReconciler) deleteDrainProgress(ctx context.Context, nodeName string) error {
+	if !r.drainProgressEnabled() || r.DryRun {
+		return nil
+	}
+	// the cache may not have one we just created yet
+	if _, created := r.progressCreated.LoadAndDelete(nodeName); !created {
+		err := r.Get(ctx, types.NamespacedName{Name: nodeName}, &pdbautoscaler.NodeDrainProgress{})
+		if errors.IsNotFound(err) {
+			return nil
+		}
+		if err != nil {
+			return r.drainProgressError(ctx, nodeName, "get", err)
+		}
+	}
+	err := r.Delete(ctx, &pdbautoscaler.NodeDrainProgress{ObjectMeta: metav1.ObjectMeta{Name: nodeName}})
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	return r.drainProgressError(ctx, nodeName, "delete", err)
+}
+
+// drainProgressError logs and returns a failed NodeDrainProgress operation. A missing NodeDrainProgress CRD or a
+// conflict with the next reconcile's write only costs the report, not the drain, so those are dropped.
+func (r *NodeReconciler) drainProgressError(ctx context.Context, nodeName, operation string, err error) error {
+	if err == nil || errors.IsConflict(err) {
+		return nil
+	}
+	if meta.IsNoMatchError(err) {
+		log.FromContext(ctx).V(1).Info("NodeDrainProgress is not installed, not reporting drain progress", "node", nodeName)
+		return nil
+	}
+	log.FromContext(ctx).Error(err, "unable to "+operation+" NodeDrainProgress", "node", nodeName)
+	return err
+}
@@ -0,0 +1,160 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	v1 "github.com/azure/eviction-autoscaler/api/v1"
+	"github.com/azure/eviction-autoscaler/internal/metrics"
+	"github.com/azure/eviction-autoscaler/internal/selectorcache"
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// TestDrainProgress follows the NodeDrainProgress of a node from its cordon, through a pod leaving and the last one
+// going, to its uncordon.
+func TestDrainProgress(t *testing.T) {
+	ctx := context.Background()
+	testScheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(testScheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := v1.AddToScheme(testScheme); err != nil {
+		t.Fatal(err)
+	}
+	namespace := "progress"
+	pod := func(name string) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace, Labels: map[string]string{"app": "web"}},
+			Spec:       corev1.PodSpec{NodeName: "draining"},
+		}
+	}
+	fakeClient := fake.NewClientBuilder().
+		WithScheme(testScheme).
+		WithIndex(&corev1.Pod{}, NodeNameIndex, podNodeName).
+		WithIndex(&v1.EvictionAutoScaler{}, PDBIndex, evictionAutoScalerPDB).
+		WithStatusSubresource(&corev1.Pod{}, &v1.EvictionAutoScaler{}, &v1.NodeDrainProgress{}).
+		WithInterceptorFuncs(interceptor.Funcs{SubResourcePatch: fakeApplyPodStatus()}).
+		WithObjects(
+			&v1.EvictionAutoScaler{
+				ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: namespace},
+				Spec:       v1.EvictionAutoScalerSpec{TargetName: "web", TargetKind: deploymentKind},
+				Status:     v1.EvictionAutoScalerStatus{CurrentSurge: 2},
+			},
+			&policyv1.PodDisruptionBudget{
+				ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: namespace},
+				Spec: policyv1.PodDisruptionBudgetSpec{
+					Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}},
+				},
+			},
+			&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "draining", UID: "draining-uid"}, Spec: corev1.NodeSpec{Unschedulable: true}},
+			pod("web-1"),
+			pod("web-2"),
+		).
+		Build()
+	nodeReconciler := &NodeReconciler{Client: fakeClient, Scheme: testScheme, Recorder: record.NewFakeRecorder(10),
+		Selectors: selectorcache.New(), DrainProgress: true}
+	progress := func() *v1.NodeDrainProgress {
+		t.Helper()
+		if _, err := nodeReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: types.NamespacedName{Name: "draining"}}); err != nil {
+			t.Fatal(err)
+		}
+		progress := &v1.NodeDrainProgress{}
+		if err := fakeClient.Get(ctx, types.NamespacedName{Name: "draining"}, progress); err != nil {
+			if errors.IsNotFound(err) {
+				return nil
+			}
+			t.Fatal(err)
+		}
+		return progress
+	}
+
+	started := progress()
+	if started == nil {
+		t.Fatal("got no NodeDrainProgress once the node was cordoned")
+	}
+	if len(started.OwnerReferences) != 1 || started.OwnerReferences[0].UID != "draining-uid" {
+		t.Errorf("got owner references %v, want the node", started.OwnerReferences)
+	}
+	want := v1.NodeDrainEvictionAutoScaler{Namespace: namespace, Name: "web", PodsRemaining: 2, CurrentSurge: 2}
+	status := started.Status
+	if status.Phase != v1.NodeDrainPhaseDraining || status.Trigger != metrics.DrainTriggerCordon || status.PodsRemaining != 2 ||
+		status.PodsMoved != 0 || status.StartTime.IsZero() || len(status.EvictionAutoScalers) != 1 || status.EvictionAutoScalers[0] != want {
+		t.Errorf("got status %+v once the node was cordoned", status)
+	}
+
+	if err := fakeClient.Delete(ctx, pod("web-1")); err != nil {
+		t.Fatal(err)
+	}
+	status = progress().Status
+	if status.PodsRemaining != 1 || status.PodsMoved != 1 || status.EvictionAutoScalers[0].PodsRemaining != 1 || !status.StartTime.Equal(&started.Status.StartTime) {
+		t.Errorf("got status %+v once a pod left, want one remaining and one moved", status)
+	}
+
+	if err := fakeClient.Delete(ctx, pod("web-2")); err != nil {
+		t.Fatal(err)
+	}
+	status = progress().Status
+	if status.Phase != v1.NodeDrainPhaseComplete || status.PodsRemaining != 0 || status.PodsMoved != 2 || len(status.EvictionAutoScalers) != 0 {
+		t.Errorf("got status %+v once the last pod left, want it complete with both moved", status)
+	}
+
+	node := &corev1.Node{}
+	if err := fakeClient.Get(ctx, types.NamespacedName{Name: "draining"}, node); err != nil {
+		t.Fatal(err)
+	}
+	node.Spec.Unschedulable = false
+	if err := fakeClient.Update(ctx, node); err != nil {
+		t.Fatal(err)
+	}
+	if got := progress(); got != nil {
+		t.Errorf("got NodeDrainProgress %+v once the node was uncordoned, want it deleted", got.Status)
+	}
+}
+
+// TestDeleteDrainProgress only deletes a NodeDrainProgress that exists, so reconciling nodes that never drained sends
+// no deletes, while one left by a previous process is still cleaned up.
+func TestDeleteDrainProgress(t *testing.T) {
+	ctx := context.Background()
+	testScheme := runtime.NewScheme()
+	if err := v1.AddToScheme(testScheme); err != nil {
+		t.Fatal(err)
+	}
+	deletes := 0
+	fakeClient := fake.NewClientBuilder().
+		WithScheme(testScheme).
+		WithObjects(&v1.NodeDrainProgress{ObjectMeta: metav1.ObjectMeta{Name: "left-over"}}).
+		WithInterceptorFuncs(interceptor.Funcs{Delete: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.DeleteOption) error {
+			deletes++
+			return c.Delete(ctx, obj, opts...)
+		}}).
+		Build()
+	nodeReconciler := &NodeReconciler{Client: fakeClient, Scheme: testScheme, DrainProgress: true}
+
+	if err := nodeReconciler.deleteDrainProgress(ctx, "never-drained"); err != nil {
+		t.Fatal(err)
+	}
+	if deletes != 0 {
+		t.Errorf("got %d deletes for a node without a NodeDrainProgress, want none", deletes)
+	}
+	if err := nodeReconciler.deleteDrainProgress(ctx, "left-over"); err != nil {
+		t.Fatal(err)
+	}
+	if deletes != 1 {
+		t.Errorf("got %d deletes for a node with a NodeDrainProgress, want one", deletes)
+	}
+	err := fakeClient.Get(ctx, types.NamespacedName{Name: "left-over"}, &v1.NodeDrainProgress{})
+	if !errors.IsNotFound(err) {
+		t.Errorf("got %v getting the deleted NodeDrainProgress, want not found", err)
+	}
+}
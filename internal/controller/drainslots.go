@@ -0,0 +1,71 @@
+package controllers
+
+import (
+	"slices"
+	"sync"
+
+	"github.com/azure/eviction-autoscaler/internal/metrics"
+)
+
+// drainSlots is the set of nodes whose drains we are assisting and the queue of cordoned nodes waiting for one of
+// them to finish, for MaxConcurrentNodeDrains. The zero value is ready to use.
+type drainSlots struct {
+	mu     sync.Mutex
+	active map[string]bool
+	queued []string
+}
+
+// admit returns whether nodeName may be assisted with at most limit nodes at once, else its 1 based place in the
+// queue it is added to. A node is admitted only if it is queued ahead of every other node still waiting for the
+// free slots, so nodes go in the order they were cordoned. force admits it even when full, for a drain we were
+// already assisting before a restart. A limit of zero admits every node.
+func (s *drainSlots) admit(nodeName string, limit int, force bool) (bool, int) {
+	if limit <= 0 {
+		return true, 0
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	defer s.observe()
+	if s.active[nodeName] {
+		return true, 0
+	}
+	position := slices.Index(s.queued, nodeName)
+	if position < 0 {
+		s.queued = append(s.queued, nodeName)
+		position = len(s.queued) - 1
+	}
+	if !force && position >= limit-len(s.active) {
+		return false, position + 1
+	}
+	s.queued = slices.Delete(s.queued, position, position+1)
+	if s.active == nil {
+		s.active = map[string]bool{}
+	}
+	s.active[nodeName] = true
+	return true, 0
+}
+
+// release frees the slot or queue place of nodeName and returns the queued nodes that now fit in the free slots,
+// they have to be reconciled again to be admitted.
+func (s *drainSlots) release(nodeName string, limit int) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// the limit can change at runtime, a node admitted under an old one still gives its slot back
+	if !s.active[nodeName] && !slices.Contains(s.queued, nodeName) {
+		return nil
+	}
+	defer s.observe()
+	delete(s.active, nodeName)
+	s.queued = slices.DeleteFunc(s.queued, func(queued string) bool { return queued == nodeName })
+	if limit <= 0 {
+		return nil
+	}
+	free := min(max(limit-len(s.active), 0), len(s.queued))
+	return slices.Clone(s.queued[:free])
+}
+
+// observe sets metrics.NodeDrainAssistsGauge, with mu held.
+func (s *drainSlots) observe() {
+	metrics.NodeDrainAssistsGauge.WithLabelValues(metrics.DrainAssistActive).Set(float64(len(s.active)))
+	metrics.NodeDrainAssistsGauge.WithLabelValues(metrics.DrainAssistQueued).Set(float64(len(s.queued)))
+}
@@ -3,29 +3,38 @@ package controllers
 import (
 	"context"
 	"fmt"
-	"math"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
 
 	myappsv1 "github.com/azure/eviction-autoscaler/api/v1"
+	"github.com/azure/eviction-autoscaler/internal/events"
+	"github.com/azure/eviction-autoscaler/internal/evictionutil"
 	"github.com/azure/eviction-autoscaler/internal/metrics"
+	"github.com/azure/eviction-autoscaler/internal/namespacefilter"
+	"github.com/azure/eviction-autoscaler/internal/tracing"
+	"go.opentelemetry.io/otel/trace"
 
-	//v1 "k8s.io/api/apps/v1"
-
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
 	policyv1 "k8s.io/api/policy/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
-	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/scale"
 	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 )
 
 const EvictionSurgeReplicasAnnotationKey = "evictionSurgeReplicas"
@@ -35,182 +44,780 @@ type EvictionAutoScalerReconciler struct {
 	client.Client
 	Scheme   *runtime.Scheme
 	Recorder record.EventRecorder
+	// Namespaces excludes EvictionAutoScalers whose targets we must not touch. Nil allows all.
+	Namespaces *namespacefilter.Filter
+	// DryRun logs and counts target scaling instead of doing it.
+	DryRun bool
+	// Scales scales spec.targetRef targets through their scale subresource.
+	Scales scale.ScalesGetter
+	// PDBMissingGracePeriod is how long a pdb may be gone, say while helm recreates it, before PDBMissingAction is taken.
+	PDBMissingGracePeriod time.Duration
+	// PDBMissingAction is PDBMissingDelete, PDBMissingSuspend or empty to only report the missing pdb.
+	PDBMissingAction string
+	// MaxConcurrentReconciles is how many EvictionAutoScalers are reconciled at once. Zero reconciles one at a time.
+	MaxConcurrentReconciles int
+	// ScaleUps rate limits surges across every EvictionAutoScaler. Nil doesn't limit them.
+	ScaleUps *ScaleUpLimiter
+	// DrainTaints are taint keys treated the same as a cordon when holding a surge for pods still on draining
+	// nodes, as for NodeReconciler. Nil means only cordons hold it.
+	DrainTaints []string
+	// SurgeBudget caps the surge replicas of every EvictionAutoScaler together. Nil doesn't cap them.
+	SurgeBudget *SurgeBudget
+	Config      Config
+	// budgetReleased requeues EvictionAutoScalers waiting on SurgeBudget once replicas are given back. Nil without a
+	// SurgeBudget or a manager.
+	budgetReleased chan event.GenericEvent
 }
 
-const cooldown = 1 * time.Minute
+// Condition types besides Ready and Degraded. Each is set True while it applies and
+// flipped to False once it no longer does, so they can be watched for instead of reading logs.
+const (
+	// ConditionScalingUp is true from surging the target till it is scaled back down.
+	ConditionScalingUp = "ScalingUp"
+	// ConditionCoolingDown is true while a surge is held for the cooldown after the last eviction.
+	ConditionCoolingDown = "CoolingDown"
+	// ConditionIdle is false from surging the target and true again once it is back at its baseline.
+	ConditionIdle = "Idle"
+	// ConditionTargetMissing is true while the target deployment, statefulset or targetRef can't be found.
+	ConditionTargetMissing = "TargetMissing"
+	// ConditionPDBMissing is true while there is no pdb of the same name.
+	ConditionPDBMissing = "PDBMissing"
+)
+
+// What to do with an EvictionAutoScaler once its pdb has been missing for the grace period.
+// Auto-created ones are owned by their pdb and garbage collected with it anyway.
+const (
+	PDBMissingDelete  = "delete"
+	PDBMissingSuspend = "suspend"
+)
 
 // +kubebuilder:rbac:groups=eviction-autoscaler.azure.com,resources=evictionautoscalers,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=eviction-autoscaler.azure.com,resources=evictionautoscalers/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=eviction-autoscaler.azure.com,resources=evictionautoscalers/finalizers,verbs=update
 // +kubebuilder:rbac:groups=apps,resources=statefulsets,verbs=watch;get;list;update
+// +kubebuilder:rbac:groups=apps,resources=replicasets,verbs=watch;get;list;update
+// +kubebuilder:rbac:groups=*,resources=*/scale,verbs=get;update
 // +kubebuilder:rbac:groups=core,resources=pods,verbs=watch;get;list
-// +kubebuilder:rbac:groups=core,resources=pods/status,verbs=update
+// +kubebuilder:rbac:groups=core,resources=pods/status,verbs=update;patch
+// +kubebuilder:rbac:groups=core,resources=resourcequotas,verbs=get;list;watch
 
 func (r *EvictionAutoScalerReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	ctx, span := tracing.Tracer.Start(ctx, "EvictionAutoScalerReconciler.Reconcile", trace.WithAttributes(
+		tracing.NamespaceKey.String(req.Namespace), tracing.EvictionAutoScalerKey.String(req.Name)))
+	result, err := r.reconcile(ctx, req)
+	tracing.End(span, err)
+	return result, err
+}
+
+func (r *EvictionAutoScalerReconciler) reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	logger := log.FromContext(ctx)
 
+	if r.Namespaces.Skip(ctx, req.Namespace, "evictionautoscaler") {
+		tracing.Decide(ctx, "namespace-skipped")
+		metrics.ForgetPDBBlocked(req.NamespacedName)
+		return ctrl.Result{}, nil
+	}
+
 	// Fetch the EvictionAutoScaler instance
 	EvictionAutoScaler := &myappsv1.EvictionAutoScaler{}
 	err := r.Get(ctx, req.NamespacedName, EvictionAutoScaler)
 	if err != nil {
 		//should we use a finalizer to scale back down on deletion?
 		if errors.IsNotFound(err) {
+			tracing.Decide(ctx, "deleted")
+			r.setSurge(ctx, req.NamespacedName, 0)
+			metrics.ForgetPDBBlocked(req.NamespacedName)
 			return ctrl.Result{}, nil // EvictionAutoScaler not found, could be deleted, nothing to do
 		}
 		return ctrl.Result{}, err // Error fetching EvictionAutoScaler
 	}
 	EvictionAutoScaler = EvictionAutoScaler.DeepCopy() //don't mutate the cache
 
-	// Fetch the PDB using a 1:1 name mapping
-	pdb := &policyv1.PodDisruptionBudget{}
-	err = r.Get(ctx, types.NamespacedName{Name: EvictionAutoScaler.Name, Namespace: EvictionAutoScaler.Namespace}, pdb)
-	if err != nil {
-		if errors.IsNotFound(err) {
-			degraded(&EvictionAutoScaler.Status.Conditions, "NoPdb", "PDB of same name not found")
-			logger.Error(err, "no matching pdb", "namespace", EvictionAutoScaler.Namespace, "name", EvictionAutoScaler.Name)
-			return ctrl.Result{}, r.Status().Update(ctx, EvictionAutoScaler)
+	if migrateSpecEviction(EvictionAutoScaler) {
+		logger.Info("Migrated deprecated spec.lastEviction to status", "lastEviction", EvictionAutoScaler.Status.LastEviction)
+		if err := r.Status().Update(ctx, EvictionAutoScaler); err != nil {
+			return ctrl.Result{}, err
 		}
-		return ctrl.Result{}, err
 	}
 
-	if EvictionAutoScaler.Spec.TargetName == "" {
-		degraded(&EvictionAutoScaler.Status.Conditions, "EmptyTarget", "no specified target")
-		logger.Error(err, "no specified target name", "targetname", EvictionAutoScaler.Spec.TargetName)
-		return ctrl.Result{}, r.Status().Update(ctx, EvictionAutoScaler)
+	if EvictionAutoScaler.Spec.Suspend {
+		return r.suspended(ctx, EvictionAutoScaler)
+	}
+	if resumed(EvictionAutoScaler) {
+		logger.Info("EvictionAutoScaler is no longer suspended")
+		if err := r.Status().Update(ctx, EvictionAutoScaler); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	// Fetch the PDB, by default the one of the same name. spec.podSelector goes without one and is always blocked.
+	pdb, found := evictionutil.PodSelectorPDB(EvictionAutoScaler), "spec.podSelector is used instead of a pdb"
+	if pdb != nil {
+		metrics.ForgetPDBBlocked(req.NamespacedName)
+	} else {
+		pdb = &policyv1.PodDisruptionBudget{}
+		err = r.Get(ctx, types.NamespacedName{Name: EvictionAutoScaler.PDBName(), Namespace: EvictionAutoScaler.Namespace}, pdb)
+		if err != nil {
+			if errors.IsNotFound(err) {
+				metrics.ForgetPDBBlocked(req.NamespacedName)
+				return r.pdbMissing(ctx, EvictionAutoScaler)
+			}
+			return ctrl.Result{}, err
+		}
+		metrics.SetPDBBlocked(req.NamespacedName, pdb.Status.DisruptionsAllowed == 0)
+		found = "pdb " + pdb.Name + " found"
+	}
+	if clearCondition(&EvictionAutoScaler.Status.Conditions, ConditionPDBMissing, "Found", found) {
+		logger.Info("pdb is back", "name", pdb.Name)
+		meta.RemoveStatusCondition(&EvictionAutoScaler.Status.Conditions, ConditionSuspended)
+		if err := r.Status().Update(ctx, EvictionAutoScaler); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	// after the status updates above since they read back the stored, possibly undefaulted, spec
+	explicitTarget := EvictionAutoScaler.Spec.TargetRef != nil || EvictionAutoScaler.Spec.TargetName != ""
+	EvictionAutoScaler.SetStaticDefaults()
+	targetResolved := false
+	if _, annotated := pdb.Annotations[TargetAnnotationKey]; explicitTarget || annotated {
+		targetResolved = clearCondition(&EvictionAutoScaler.Status.Conditions, ConditionAmbiguousTarget, "TargetSet", "target is set explicitly") ||
+			EvictionAutoScaler.Status.ResolvedTarget != ""
+		EvictionAutoScaler.Status.ResolvedTarget = ""
+	} else {
+		changed, found, err := r.discoverTarget(ctx, EvictionAutoScaler, pdb)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		if !found {
+			tracing.Decide(ctx, "target-undiscovered")
+			r.setSurge(ctx, req.NamespacedName, 0)
+			// workloads created later are mapped to us by their pod template, the requeue is a fallback
+			return ctrl.Result{RequeueAfter: r.Config.CooldownFor(EvictionAutoScaler)}, r.updateStatus(ctx, EvictionAutoScaler)
+		}
+		targetResolved = changed
+	}
+	if err := applyTargetAnnotation(ctx, EvictionAutoScaler, pdb); err != nil {
+		logger.Error(err, "invalid target annotation on pdb", "name", pdb.Name)
+		message := fmt.Sprintf("pdb %s has an invalid %s annotation: %s", pdb.Name, TargetAnnotationKey, err)
+		setCondition(&EvictionAutoScaler.Status.Conditions, ConditionInvalidTargetAnnotation, metav1.ConditionTrue, "InvalidValue", message)
+		degraded(&EvictionAutoScaler.Status.Conditions, "InvalidTargetAnnotation", message)
+		tracing.Decide(ctx, "invalid-target-annotation")
+		return ctrl.Result{}, r.updateStatus(ctx, EvictionAutoScaler)
+	}
+	annotationFixed := clearCondition(&EvictionAutoScaler.Status.Conditions, ConditionInvalidTargetAnnotation, "Valid", "target annotation is valid or unset")
+	targetKind, targetName := targetKindAndName(EvictionAutoScaler)
+	var target Surger
+	if ref := EvictionAutoScaler.Spec.TargetRef; ref != nil {
+		// anything exposing /scale
+		target, err = r.getScaleTarget(ctx, EvictionAutoScaler.Namespace, ref)
+		if err != nil {
+			if isTargetNotFound(err) {
+				logger.Error(err, "can't resolve targetRef", "apiVersion", ref.APIVersion, "kind", ref.Kind, "targetname", ref.Name)
+				message := fmt.Sprintf("can't resolve %s %s %s: %s", ref.APIVersion, ref.Kind, ref.Name, err)
+				degraded(&EvictionAutoScaler.Status.Conditions, "TargetNotFound", message)
+				setCondition(&EvictionAutoScaler.Status.Conditions, ConditionTargetMissing, metav1.ConditionTrue, "TargetNotFound", message)
+				tracing.Decide(ctx, "target-missing")
+				r.setSurge(ctx, req.NamespacedName, 0)
+				// requeue since the target or its CRD may show up later and we don't watch either
+				return ctrl.Result{RequeueAfter: r.Config.CooldownFor(EvictionAutoScaler)}, r.updateStatus(ctx, EvictionAutoScaler)
+			}
+			return ctrl.Result{}, err
+		}
+	} else {
+		// Fetch the Deployment or Statefulset
+		// TODO enum validation https://book.kubebuilder.io/reference/generating-crd#validation
+		target, err = GetSurger(EvictionAutoScaler.Spec.TargetKind)
+		if err != nil {
+			logger.Error(err, "invalid target kind", "kind", EvictionAutoScaler.Spec.TargetKind)
+			degraded(&EvictionAutoScaler.Status.Conditions, "InvalidTarget", "Invalid Target Kind: "+EvictionAutoScaler.Spec.TargetKind)
+			return ctrl.Result{}, r.updateStatus(ctx, EvictionAutoScaler)
+		}
+		err = r.Get(ctx, types.NamespacedName{Name: EvictionAutoScaler.Spec.TargetName, Namespace: EvictionAutoScaler.Namespace}, target.Obj())
+		if err != nil {
+			if errors.IsNotFound(err) {
+				logger.Error(err, "pdb watcher target does not exist", "kind", EvictionAutoScaler.Spec.TargetKind, "targetname", EvictionAutoScaler.Spec.TargetName)
+				degraded(&EvictionAutoScaler.Status.Conditions, "MissingTarget", "Misssing  Target "+EvictionAutoScaler.Spec.TargetName)
+				setCondition(&EvictionAutoScaler.Status.Conditions, ConditionTargetMissing, metav1.ConditionTrue, "MissingTarget",
+					fmt.Sprintf("no %s %s", EvictionAutoScaler.Spec.TargetKind, EvictionAutoScaler.Spec.TargetName))
+				tracing.Decide(ctx, "target-missing")
+				r.setSurge(ctx, req.NamespacedName, 0)
+				return ctrl.Result{}, r.updateStatus(ctx, EvictionAutoScaler)
+			}
+			return ctrl.Result{}, err
+		}
 	}
 
-	// Fetch the Deployment or Statefulset
-	// TODO enum validation https://book.kubebuilder.io/reference/generating-crd#validation
-	target, err := GetSurger(EvictionAutoScaler.Spec.TargetKind)
+	// persisted with whatever status update comes next
+	statusChanged := clearCondition(&EvictionAutoScaler.Status.Conditions, ConditionTargetMissing, "Found", fmt.Sprintf("found %s %s", targetKind, targetName)) ||
+		EvictionAutoScaler.Status.ObservedGeneration != EvictionAutoScaler.Generation || annotationFixed || targetResolved ||
+		EvictionAutoScaler.Status.Target != summaryTarget(EvictionAutoScaler)
+
+	expired, err := r.expireStaleEviction(ctx, EvictionAutoScaler)
 	if err != nil {
-		logger.Error(err, "invalid target kind", "kind", EvictionAutoScaler.Spec.TargetKind)
-		degraded(&EvictionAutoScaler.Status.Conditions, "InvalidTarget", "Invalid Target Kind: "+EvictionAutoScaler.Spec.TargetKind)
-		return ctrl.Result{}, r.Status().Update(ctx, EvictionAutoScaler)
+		return ctrl.Result{}, err
 	}
-	err = r.Get(ctx, types.NamespacedName{Name: EvictionAutoScaler.Spec.TargetName, Namespace: EvictionAutoScaler.Namespace}, target.Obj())
+	statusChanged = expired || statusChanged
+	selectedChanged, err := r.checkPodsSelected(ctx, EvictionAutoScaler, pdb, target)
 	if err != nil {
-		if errors.IsNotFound(err) {
-			logger.Error(err, "pdb watcher target does not exist", "kind", EvictionAutoScaler.Spec.TargetKind, "targetname", EvictionAutoScaler.Spec.TargetName)
-			degraded(&EvictionAutoScaler.Status.Conditions, "MissingTarget", "Misssing  Target "+EvictionAutoScaler.Spec.TargetName)
-			return ctrl.Result{}, r.Status().Update(ctx, EvictionAutoScaler)
+		return ctrl.Result{}, err
+	}
+	statusChanged = selectedChanged || statusChanged
+
+	// we don't watch targetRef targets so poll till the step is done. Evictions stay unhandled and are surged for after.
+	if step := rolloutInProgress(target); step != "" {
+		logger.Info("Holding scale changes while rollout is in progress", "targetname", targetName, "step", step)
+		tracing.Decide(ctx, "rollout-in-progress")
+		if setCondition(&EvictionAutoScaler.Status.Conditions, ConditionRolloutInProgress, metav1.ConditionTrue, "StepInProgress",
+			fmt.Sprintf("%s, not scaling %s %s till it is done", step, targetKind, targetName)) || statusChanged {
+			return ctrl.Result{RequeueAfter: r.Config.CooldownFor(EvictionAutoScaler)}, r.updateStatus(ctx, EvictionAutoScaler)
 		}
+		return ctrl.Result{RequeueAfter: r.Config.CooldownFor(EvictionAutoScaler)}, nil
+	}
+	statusChanged = clearCondition(&EvictionAutoScaler.Status.Conditions, ConditionRolloutInProgress, "StepComplete", "rollout is promoted") || statusChanged
+
+	autoscaler, err := r.findAutoscaler(ctx, EvictionAutoScaler)
+	if err != nil {
 		return ctrl.Result{}, err
 	}
+	if autoscaler != nil || EvictionAutoScaler.Status.AutoscalerSurge != nil {
+		return r.reconcileAutoscaler(ctx, EvictionAutoScaler, pdb, target, autoscaler, statusChanged)
+	}
+	statusChanged = clearCondition(&EvictionAutoScaler.Status.Conditions, ConditionConflictingAutoscaler, "NoAutoscaler", "no hpa or ScaledObject scales the target") || statusChanged
 
 	// TODO: Move PDB configuration tracking to PDB controller with aggregate labels
 	// Consider tracking: maxUnavailable==0 and minAvailable==replicas as PDBGauge labels
 
 	// Check if the resource version has changed or if it's empty (initial state)
+	if EvictionAutoScaler.Status.TargetGeneration != 0 && EvictionAutoScaler.Status.TargetGeneration != target.Obj().GetGeneration() &&
+		target.GetReplicas() == expectedReplicas(EvictionAutoScaler) {
+		// someone else changed the target, a new image say, but left replicas where we put them. Keep the baseline and any surge.
+		logger.Info("Target changed without being scaled, keeping min replicas", "kind", targetKind, "targetname", targetName, "currentGeneration", target.Obj().GetGeneration(), "previousGeneration", EvictionAutoScaler.Status.TargetGeneration)
+		EvictionAutoScaler.Status.TargetGeneration = target.Obj().GetGeneration()
+		statusChanged = true
+	}
 	if EvictionAutoScaler.Status.TargetGeneration == 0 || EvictionAutoScaler.Status.TargetGeneration != target.Obj().GetGeneration() {
-		logger.Info("Target resource version changed resetting min replicas", "kind", EvictionAutoScaler.Spec.TargetKind, "targetname", EvictionAutoScaler.Spec.TargetName, "currentGeneration", target.Obj().GetGeneration(), "previousGeneration", EvictionAutoScaler.Status.TargetGeneration)
+		logger.Info("Target resource version changed resetting min replicas", "kind", targetKind, "targetname", targetName, "currentGeneration", target.Obj().GetGeneration(), "previousGeneration", EvictionAutoScaler.Status.TargetGeneration)
 		// The resource version has changed, which means someone else has modified the Target.
 		// To avoid conflicts, we update our status to reflect the new state and avoid making further changes.
+		if EvictionAutoScaler.Status.TargetGeneration != 0 {
+			events.Eventf(r.Recorder, EvictionAutoScaler, corev1.EventTypeNormal, events.ReasonBaselineAdopted,
+				"%s %s was scaled from %d to %d replicas by someone else, adopting %d as the baseline", targetKind, targetName, expectedReplicas(EvictionAutoScaler), target.GetReplicas(), target.GetReplicas())
+		}
 		EvictionAutoScaler.Status.TargetGeneration = target.Obj().GetGeneration()
 		EvictionAutoScaler.Status.MinReplicas = target.GetReplicas()
+		EvictionAutoScaler.Status.SurgeReplicas = 0
+		surgeGone(EvictionAutoScaler, "TargetSpecChange", "target changed so its replicas are the new baseline")
+		// someone scaled the target during a surge. Their replicas are adopted as the baseline instead of being scaled back down.
+		if clearCondition(&EvictionAutoScaler.Status.Conditions, ConditionScalingUp, "TargetSpecChange", "target changed so its replicas are the new baseline") {
+			setCondition(&EvictionAutoScaler.Status.Conditions, ConditionIdle, metav1.ConditionTrue, "TargetSpecChange", "target changed so its replicas are the new baseline")
+		}
+		clearCondition(&EvictionAutoScaler.Status.Conditions, ConditionCoolingDown, "TargetSpecChange", "target changed so its replicas are the new baseline")
 		ready(&EvictionAutoScaler.Status.Conditions, "TargetSpecChange", fmt.Sprintf("resetting min replicas to %d", EvictionAutoScaler.Status.MinReplicas))
-		return ctrl.Result{}, r.Status().Update(ctx, EvictionAutoScaler) //should we go rety in case there is also an eviction or just wait till the next eviction
+		tracing.Decide(ctx, "baseline-reset")
+		r.setSurge(ctx, req.NamespacedName, 0)
+		return ctrl.Result{}, r.updateStatus(ctx, EvictionAutoScaler) //should we go rety in case there is also an eviction or just wait till the next eviction
+	}
+
+	// from status and the live target so a restarted controller reports surges it made before
+	r.setSurge(ctx, req.NamespacedName, target.GetReplicas()-EvictionAutoScaler.Status.MinReplicas)
+
+	if EvictionAutoScaler.Spec.MaxReplicas != nil && *EvictionAutoScaler.Spec.MaxReplicas < EvictionAutoScaler.Status.MinReplicas {
+		logger.Info("maxReplicas is below the target's baseline", "maxReplicas", *EvictionAutoScaler.Spec.MaxReplicas, "minReplicas", EvictionAutoScaler.Status.MinReplicas)
+		degraded(&EvictionAutoScaler.Status.Conditions, "InvalidMaxReplicas",
+			fmt.Sprintf("maxReplicas %d is lower than the target's %d replicas", *EvictionAutoScaler.Spec.MaxReplicas, EvictionAutoScaler.Status.MinReplicas))
+		return ctrl.Result{}, r.updateStatus(ctx, EvictionAutoScaler)
 	}
 
 	// Log current state before checks
 	logger.Info(fmt.Sprintf("Checking PDB for %s: DisruptionsAllowed=%d, MinReplicas=%d", pdb.Name, pdb.Status.DisruptionsAllowed, EvictionAutoScaler.Status.MinReplicas))
 
+	if !targetPaused(target) {
+		statusChanged = clearCondition(&EvictionAutoScaler.Status.Conditions, ConditionTargetPaused, "Unpaused", fmt.Sprintf("%s %s is not paused", targetKind, targetName)) || statusChanged
+	}
+
 	// Have we processed all evictions okay don't do anything else
-	if EvictionAutoScaler.Spec.LastEviction == EvictionAutoScaler.Status.LastEviction {
+	if EvictionAutoScaler.Status.LastEviction == EvictionAutoScaler.Status.HandledEviction {
 		logger.Info("No unhandled eviction ", "pdbname", pdb.Name)
+		tracing.Decide(ctx, "no-eviction")
 		ready(&EvictionAutoScaler.Status.Conditions, "Reconciled", "no unhandled eviction")
-		return ctrl.Result{}, r.Status().Update(ctx, EvictionAutoScaler)
+		return ctrl.Result{}, r.updateStatus(ctx, EvictionAutoScaler)
 	}
 
 	// Last eviction already tracked above so we can just log it
 	logger.V(1).Info("Detected new eviction",
-		"podName", EvictionAutoScaler.Spec.LastEviction.PodName,
-		"evictionTime", EvictionAutoScaler.Spec.LastEviction.EvictionTime)
-	metrics.EvictionCounter.WithLabelValues(EvictionAutoScaler.Namespace).Inc()
+		"podName", EvictionAutoScaler.Status.LastEviction.PodName,
+		"evictionTime", EvictionAutoScaler.Status.LastEviction.EvictionTime)
+	// the pod outlived the eviction ttl so the eviction never happened. Don't surge or hold a surge for it.
+	stale := evictionExpired(&EvictionAutoScaler.Status)
 
 	//if we're not scaled up and theres new evictions we haven't proceesed
-	if pdb.Status.DisruptionsAllowed == 0 && target.GetReplicas() == EvictionAutoScaler.Status.MinReplicas {
+	// podSelector ones also surge again for every eviction past the first
+	if !stale && pdb.Status.DisruptionsAllowed == 0 && (target.GetReplicas() == EvictionAutoScaler.Status.MinReplicas || podSelectorReplicas(EvictionAutoScaler) > target.GetReplicas()) {
 		//What if the evict went through because the pod being evicted wasn't ready anyways? Handle that in webhook or here?
 		// TODO later. Surge more slowly based on number of evitions (need to move back to capturing them all)
-		logger.Info("No disruptions allowed, scaling up", "pdb", pdb.Name, "lastEviction", EvictionAutoScaler.Spec.LastEviction)
+		logger.Info("No disruptions allowed, scaling up", "pdb", pdb.Name, "lastEviction", EvictionAutoScaler.Status.LastEviction)
 
 		// Track blocked eviction if the PDB is blocking the eviction
 		metrics.BlockedEvictionCounter.WithLabelValues(EvictionAutoScaler.Namespace, pdb.Name).Inc()
 
+		if targetPaused(target) {
+			return r.pausedTarget(ctx, EvictionAutoScaler, targetKind, targetName)
+		}
+
 		// Track scaling opportunity with signal label
 		signalLabel := metrics.GetScalingSignal(pdb)
-		metrics.ScalingOpportunityCounter.WithLabelValues(EvictionAutoScaler.Namespace, EvictionAutoScaler.Spec.TargetName, metrics.ScaleUpAction, signalLabel).Inc()
+		metrics.ScalingOpportunityCounter.WithLabelValues(EvictionAutoScaler.Namespace, targetName, metrics.ScaleUpAction, signalLabel).Inc()
 
-		newReplicas := calculateSurge(ctx, target, EvictionAutoScaler.Status.MinReplicas)
+		disruptions, err := r.disruptionsNeeded(ctx, EvictionAutoScaler, pdb)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		unblock, ok := replicasToUnblock(pdb, target.GetReplicas(), disruptions)
+		if !ok {
+			message := fmt.Sprintf("pdb %s won't allow a disruption however many replicas are added", pdb.Name)
+			logger.Info(message, "minAvailable", pdb.Spec.MinAvailable, "maxUnavailable", pdb.Spec.MaxUnavailable)
+			degraded(&EvictionAutoScaler.Status.Conditions, "SurgeCannotUnblock", message)
+			tracing.Decide(ctx, "surge-cannot-unblock")
+			// nothing to scale down later so the eviction is handled. The next one checks again.
+			EvictionAutoScaler.Status.HandledEviction = EvictionAutoScaler.Status.LastEviction
+			return ctrl.Result{}, r.updateStatus(ctx, EvictionAutoScaler)
+		}
+		if message := ineffectiveSurge(target); message == "" {
+			clearCondition(&EvictionAutoScaler.Status.Conditions, ConditionSurgeIneffective, "SurgeEffective", "a surge can unblock the pdb")
+		} else if EvictionAutoScaler.Spec.Strategy != myappsv1.StrategySurgeAlways {
+			return r.surgeIneffective(ctx, EvictionAutoScaler, message)
+		} else {
+			setCondition(&EvictionAutoScaler.Status.Conditions, ConditionSurgeIneffective, metav1.ConditionTrue, "SurgeAlways", message+", surging anyway for strategy SurgeAlways")
+		}
+		surge := r.Config.surgeFor(EvictionAutoScaler)
+		surged, err := calculateSurge(surge, target.GetReplicas())
+		if err != nil {
+			logger.Error(err, "invalid surge", "surge", surge)
+			degraded(&EvictionAutoScaler.Status.Conditions, "InvalidSurge", err.Error())
+			return ctrl.Result{}, r.updateStatus(ctx, EvictionAutoScaler)
+		}
+		// at least the step in spec.surge and at least enough to let a disruption through
+		newReplicas := max(surged, target.GetReplicas()+unblock, podSelectorReplicas(EvictionAutoScaler))
+		if maxReplicas := EvictionAutoScaler.Spec.MaxReplicas; maxReplicas != nil && newReplicas > *maxReplicas {
+			message := fmt.Sprintf("surge to %d replicas capped by maxReplicas %d, evictions may stay blocked", newReplicas, *maxReplicas)
+			logger.Info(message, "pdb", pdb.Name)
+			surgeLimited(&EvictionAutoScaler.Status.Conditions, message)
+			events.Eventf(r.Recorder, EvictionAutoScaler, corev1.EventTypeWarning, events.ReasonSurgeLimited, message)
+			newReplicas = *maxReplicas
+			if newReplicas <= target.GetReplicas() {
+				// no room to surge at all. Cooldown will mark the eviction handled.
+				tracing.Decide(ctx, "surge-limited")
+				return ctrl.Result{RequeueAfter: r.Config.CooldownFor(EvictionAutoScaler)}, r.updateStatus(ctx, EvictionAutoScaler)
+			}
+		} else {
+			meta.RemoveStatusCondition(&EvictionAutoScaler.Status.Conditions, "SurgeLimited")
+		}
+		exceeded, err := r.surgeQuotaExceeded(ctx, EvictionAutoScaler.Namespace, podTemplate(target), newReplicas-target.GetReplicas())
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		if exceeded != "" {
+			// the pods would be rejected at admission. Try again after the cooldown in case the quota frees up.
+			message := fmt.Sprintf("not surging %s %s: %s", targetKind, targetName, exceeded)
+			logger.Info(message)
+			tracing.Decide(ctx, "quota-exceeded")
+			if setCondition(&EvictionAutoScaler.Status.Conditions, ConditionQuotaExceeded, metav1.ConditionTrue, "SurgeExceedsQuota", message) {
+				events.Eventf(r.Recorder, EvictionAutoScaler, corev1.EventTypeWarning, events.ReasonQuotaExceeded, message)
+			}
+			return ctrl.Result{RequeueAfter: r.Config.CooldownFor(EvictionAutoScaler)}, r.updateStatus(ctx, EvictionAutoScaler)
+		}
+		clearCondition(&EvictionAutoScaler.Status.Conditions, ConditionQuotaExceeded, "SurgeFits", "the surge fits the namespace's ResourceQuotas")
+		action := fmt.Sprintf("scale up %s %s to %d replicas", targetKind, targetName, newReplicas)
+		if fits, err := r.spendSurgeBudget(ctx, EvictionAutoScaler, newReplicas-EvictionAutoScaler.Status.MinReplicas, action); err != nil {
+			return ctrl.Result{}, err
+		} else if !fits {
+			return ctrl.Result{RequeueAfter: r.Config.CooldownFor(EvictionAutoScaler)}, r.updateStatus(ctx, EvictionAutoScaler)
+		}
+		if delay := r.throttleScaleUp(ctx, EvictionAutoScaler, action); delay > 0 {
+			// give back the budget till the token is there
+			r.setSurge(ctx, req.NamespacedName, target.GetReplicas()-EvictionAutoScaler.Status.MinReplicas)
+			return ctrl.Result{RequeueAfter: delay}, r.updateStatus(ctx, EvictionAutoScaler)
+		}
+		tracing.Decide(ctx, "scale-up")
+		if r.DryRun {
+			events.DryRun(ctx, r.Recorder, EvictionAutoScaler, metrics.DryRunScaleTarget, "scale up %s %s to %d replicas",
+				targetKind, target.Obj().GetName(), newReplicas)
+			// nothing was scaled, so give back the budget reserved for it
+			r.setSurge(ctx, req.NamespacedName, target.GetReplicas()-EvictionAutoScaler.Status.MinReplicas)
+			return ctrl.Result{RequeueAfter: r.Config.CooldownFor(EvictionAutoScaler)}, nil
+		}
 		target.SetReplicas(newReplicas)
 		//adding annotations here is an atomic operation;
 		//EvictionAutoScaler can fail between updating deployment and EvictionAutoScaler targetGeneration;
 		//hence we need to rely on checking if annotation exists and compare with deployment.Spec.Replicas
 		// this is to solve customer scaling up deployment manually so EvictionAutoScaler minAvailable needs to be updated
 		target.AddAnnotation(EvictionSurgeReplicasAnnotationKey, strconv.FormatInt(int64(newReplicas), 10))
-		err = r.Update(ctx, target.Obj())
+		err = r.updateTarget(ctx, target)
 		if err != nil {
-			logger.Error(err, "failed to update Target", "kind", EvictionAutoScaler.Spec.TargetKind, "targetname", EvictionAutoScaler.Spec.TargetName)
+			logger.Error(err, "failed to update Target", "kind", targetKind, "targetname", targetName)
 			return ctrl.Result{}, err
 		}
 
 		// Track actual scaling action
-		metrics.ActualScalingCounter.WithLabelValues(EvictionAutoScaler.Namespace, EvictionAutoScaler.Spec.TargetName, metrics.ScaleUpAction).Inc()
+		metrics.ActualScalingCounter.WithLabelValues(EvictionAutoScaler.Namespace, targetName, metrics.ScaleUpAction).Inc()
+		metrics.ScaleUpCounter.WithLabelValues(EvictionAutoScaler.Namespace, strings.ToLower(targetKind)).Inc()
+		metrics.ScaleUpReplicasCounter.WithLabelValues(EvictionAutoScaler.Namespace, strings.ToLower(targetKind)).Add(float64(newReplicas - EvictionAutoScaler.Status.MinReplicas))
+		r.setSurge(ctx, req.NamespacedName, newReplicas-EvictionAutoScaler.Status.MinReplicas)
 
 		// Log the scaling action
-		logger.Info(fmt.Sprintf("Scaled up %s %s/%s to %d replicas", EvictionAutoScaler.Spec.TargetKind, target.Obj().GetNamespace(), target.Obj().GetName(), newReplicas))
+		logger.Info(fmt.Sprintf("Scaled up %s %s/%s to %d replicas", targetKind, target.Obj().GetNamespace(), target.Obj().GetName(), newReplicas))
+		events.Eventf(r.Recorder, EvictionAutoScaler, corev1.EventTypeNormal, events.ReasonSurgeScaledUp,
+			"Scaled up %s %s to %d replicas for eviction of pod %s", targetKind, target.Obj().GetName(), newReplicas, EvictionAutoScaler.Status.LastEviction.PodName)
 		logger.Info(fmt.Sprintf("TargetGeneration moving from %d->%d", EvictionAutoScaler.Status.TargetGeneration, target.Obj().GetGeneration()))
 		// Save ResourceVersion to EvictionAutoScaler status this will cause another reconcile.
 		EvictionAutoScaler.Status.TargetGeneration = target.Obj().GetGeneration()
-		//Do not update EvictionAutoScaler.Status.LastEviction because we need to keep reconciling till scale down
+		EvictionAutoScaler.Status.SurgeReplicas = newReplicas
+		EvictionAutoScaler.Status.LastScaleTime = metav1.Now()
+		surgePending(EvictionAutoScaler, targetKind, targetName, newReplicas)
+		//Do not update EvictionAutoScaler.Status.HandledEviction because we need to keep reconciling till scale down
+		setCondition(&EvictionAutoScaler.Status.Conditions, ConditionScalingUp, metav1.ConditionTrue, "Surged",
+			fmt.Sprintf("surged %s %s from %d to %d replicas for eviction of pod %s", targetKind, targetName, EvictionAutoScaler.Status.MinReplicas, newReplicas, EvictionAutoScaler.Status.LastEviction.PodName))
+		setCondition(&EvictionAutoScaler.Status.Conditions, ConditionIdle, metav1.ConditionFalse, "Surged", fmt.Sprintf("surged to %d replicas", newReplicas))
 		ready(&EvictionAutoScaler.Status.Conditions, "Reconciled", "eviction with scale up")
-		return ctrl.Result{RequeueAfter: cooldown}, r.Status().Update(ctx, EvictionAutoScaler)
+		return ctrl.Result{RequeueAfter: r.Config.CooldownFor(EvictionAutoScaler)}, r.updateScaleStatus(ctx, EvictionAutoScaler)
 	}
 
 	//what if we're allowed disruptions >0 and minreplicas == replicas? Could argue that we should mark the eviction as handled
 	//BUT maybe PDB is slow to update? so just letting it requeue anyways
 
+	if target.GetReplicas() > EvictionAutoScaler.Status.MinReplicas {
+		statusChanged = r.updateSurgeReady(EvictionAutoScaler, target, targetKind, targetName) || statusChanged
+	}
+
+	// a surge that can't schedule leaves the drain blocked while looking handled. Say so, or give the replicas back.
+	if !stale && target.GetReplicas() > EvictionAutoScaler.Status.MinReplicas {
+		pending, scheduled, err := r.unschedulableSurgePod(ctx, EvictionAutoScaler, pdb, target)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		if pending != nil {
+			message := fmt.Sprintf("surge pod %s has been unschedulable for over %s: %s", pending.Name, surgeScheduleTimeoutFor(EvictionAutoScaler), scheduled.Message)
+			logger.Info(message, "targetname", targetName)
+			if EvictionAutoScaler.Spec.RevertUnschedulableSurge {
+				events.Eventf(r.Recorder, EvictionAutoScaler, corev1.EventTypeWarning, events.ReasonSurgeUnschedulable, message)
+				return r.revertUnschedulableSurge(ctx, EvictionAutoScaler, target, targetKind, targetName)
+			}
+			if setCondition(&EvictionAutoScaler.Status.Conditions, ConditionSurgeUnschedulable, metav1.ConditionTrue, "PodsPending", message) {
+				events.Eventf(r.Recorder, EvictionAutoScaler, corev1.EventTypeWarning, events.ReasonSurgeUnschedulable, message)
+				statusChanged = true
+			}
+		} else {
+			// a cluster autoscaler added a node, say
+			statusChanged = clearCondition(&EvictionAutoScaler.Status.Conditions, ConditionSurgeUnschedulable, "Scheduled", "surge pods are scheduled") || statusChanged
+		}
+	}
+
+	// a cordoned node still has pods for us so hold the surge till they are gone or the node is deleted.
+	if !stale && len(EvictionAutoScaler.Status.DrainingNodes) > 0 && target.GetReplicas() > EvictionAutoScaler.Status.MinReplicas {
+		logger.Info(fmt.Sprintf("Holding %s/%s surge for draining nodes %v", target.Obj().GetNamespace(), target.Obj().GetName(), EvictionAutoScaler.Status.DrainingNodes))
+		if EvictionAutoScaler.Spec.DrainDeadlineSurge {
+			if result, surged, err := r.surgeForDrainDeadline(ctx, EvictionAutoScaler, target, targetKind, targetName); surged || err != nil {
+				return result, err
+			}
+		}
+		tracing.Decide(ctx, "hold-draining")
+		if setCondition(&EvictionAutoScaler.Status.Conditions, ConditionCoolingDown, metav1.ConditionTrue, "NodesDraining",
+			fmt.Sprintf("holding the surge till draining nodes %v are done", EvictionAutoScaler.Status.DrainingNodes)) || statusChanged {
+			return ctrl.Result{RequeueAfter: r.Config.CooldownFor(EvictionAutoScaler)}, r.updateStatus(ctx, EvictionAutoScaler)
+		}
+		return ctrl.Result{RequeueAfter: r.Config.CooldownFor(EvictionAutoScaler)}, nil
+	}
+
 	//Cool down time makes sure we're not still getting more evictions
 	//we could substantially reduce this if we looked at pods and knew that none remaining (not already evicted) had been an eviction target but that means tracking more data in EvictionAutoScaler
 	// or using pod conditons which we're not doing.....yet
-	if time.Since(EvictionAutoScaler.Spec.LastEviction.EvictionTime.Time) < cooldown {
-		logger.Info(fmt.Sprintf("Giving %s/%s cooldown of  %s after last eviction %s ", target.Obj().GetNamespace(), target.Obj().GetName(), cooldown, EvictionAutoScaler.Spec.LastEviction.EvictionTime))
-		return ctrl.Result{RequeueAfter: cooldown}, nil
+	if !stale && time.Since(EvictionAutoScaler.Status.LastEviction.EvictionTime.Time) < r.Config.CooldownFor(EvictionAutoScaler) {
+		logger.Info(fmt.Sprintf("Giving %s/%s cooldown of  %s after last eviction %s ", target.Obj().GetNamespace(), target.Obj().GetName(), r.Config.CooldownFor(EvictionAutoScaler), EvictionAutoScaler.Status.LastEviction.EvictionTime))
+		tracing.Decide(ctx, "cooldown")
+		if setCondition(&EvictionAutoScaler.Status.Conditions, ConditionCoolingDown, metav1.ConditionTrue, "RecentEviction",
+			fmt.Sprintf("last eviction at %s, scaling down after %s without more", EvictionAutoScaler.Status.LastEviction.EvictionTime.UTC().Format(time.RFC3339), r.Config.CooldownFor(EvictionAutoScaler))) || statusChanged {
+			return ctrl.Result{RequeueAfter: r.Config.CooldownFor(EvictionAutoScaler)}, r.updateStatus(ctx, EvictionAutoScaler)
+		}
+		return ctrl.Result{RequeueAfter: r.Config.CooldownFor(EvictionAutoScaler)}, nil
+	}
+
+	// the last draining node just finished. Give a node cordoned right after the stabilization window before scaling down.
+	if target.GetReplicas() > EvictionAutoScaler.Status.MinReplicas {
+		if remaining := stabilizationFor(EvictionAutoScaler) - time.Since(EvictionAutoScaler.Status.DrainedTime.Time); remaining > 0 {
+			logger.Info(fmt.Sprintf("Stabilizing %s/%s for %s after drain finished at %s", target.Obj().GetNamespace(), target.Obj().GetName(), remaining.Round(time.Second), EvictionAutoScaler.Status.DrainedTime))
+			tracing.Decide(ctx, "stabilizing")
+			if setCondition(&EvictionAutoScaler.Status.Conditions, ConditionCoolingDown, metav1.ConditionTrue, "Stabilizing",
+				fmt.Sprintf("drain finished at %s, scaling down after %s without another", EvictionAutoScaler.Status.DrainedTime.UTC().Format(time.RFC3339), stabilizationFor(EvictionAutoScaler))) || statusChanged {
+				return ctrl.Result{RequeueAfter: remaining}, r.updateStatus(ctx, EvictionAutoScaler)
+			}
+			return ctrl.Result{RequeueAfter: remaining}, nil
+		}
 	}
 
 	//still at a scaled out state check if we can scale back down
 	if target.GetReplicas() > EvictionAutoScaler.Status.MinReplicas { //would we ever be below min replicas
+		// another node may still be draining pods of the target that the node controller hasn't recorded on us
+		if !stale {
+			node, err := r.drainingNodeWithPods(ctx, pdb)
+			if err != nil {
+				return ctrl.Result{}, err
+			}
+			if node != "" {
+				return r.holdForDrainingNode(ctx, EvictionAutoScaler, pdb, node, statusChanged)
+			}
+		}
+		if EvictionAutoScaler.Status.ScaleDownBlockingNode != "" {
+			EvictionAutoScaler.Status.ScaleDownBlockingNode = ""
+			statusChanged = true
+		}
 
 		// Track scaling opportunity
-		metrics.ScalingOpportunityCounter.WithLabelValues(EvictionAutoScaler.Namespace, EvictionAutoScaler.Spec.TargetName, metrics.ScaleDownAction, metrics.CooldownElapsedSignal).Inc()
-
-		//okay we aren't at allowed disruptions Revert Target to the original state
-		target.SetReplicas(EvictionAutoScaler.Status.MinReplicas)
-		target.RemoveAnnotation(EvictionSurgeReplicasAnnotationKey)
-		err = r.Update(ctx, target.Obj())
+		metrics.ScalingOpportunityCounter.WithLabelValues(EvictionAutoScaler.Namespace, targetName, metrics.ScaleDownAction, metrics.CooldownElapsedSignal).Inc()
+
+		//okay we aren't at allowed disruptions Revert Target to the original state (or the floor if that is higher)
+		scaleDownReplicas, floored := scaleDownTo(EvictionAutoScaler, target.GetReplicas())
+		stepping := false
+		if EvictionAutoScaler.Spec.ScaleDownPolicy == myappsv1.ScaleDownPolicyStepped {
+			next, wait, paused, err := r.scaleDownStep(EvictionAutoScaler, pdb, target.GetReplicas(), scaleDownReplicas)
+			if err != nil {
+				return ctrl.Result{}, err
+			}
+			if wait > 0 {
+				tracing.Decide(ctx, "step-down-wait")
+				if statusChanged {
+					return ctrl.Result{RequeueAfter: wait}, r.updateStatus(ctx, EvictionAutoScaler)
+				}
+				return ctrl.Result{RequeueAfter: wait}, nil
+			}
+			if paused != "" {
+				logger.Info(paused)
+				tracing.Decide(ctx, "step-down-paused")
+				if setCondition(&EvictionAutoScaler.Status.Conditions, ConditionScaleDownPaused, metav1.ConditionTrue, "PDBWouldBlock", paused) {
+					events.Eventf(r.Recorder, EvictionAutoScaler, corev1.EventTypeWarning, events.ReasonScaleDownPaused, paused)
+				} else if !statusChanged {
+					return ctrl.Result{RequeueAfter: r.Config.CooldownFor(EvictionAutoScaler)}, nil
+				}
+				return ctrl.Result{RequeueAfter: r.Config.CooldownFor(EvictionAutoScaler)}, r.updateStatus(ctx, EvictionAutoScaler)
+			}
+			clearCondition(&EvictionAutoScaler.Status.Conditions, ConditionScaleDownPaused, "PDBAllows", fmt.Sprintf("pdb allows scaling down to %d replicas", next))
+			stepping = next > scaleDownReplicas
+			scaleDownReplicas = next
+		}
+		unsafePod, err := r.unsafeSurgeOrdinal(ctx, EvictionAutoScaler, target, scaleDownReplicas)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		if unsafePod != "" {
+			// keeping the extra replicas is safe, the operator has to sort out which pod should go
+			message := fmt.Sprintf("scaling %s %s down to %d replicas would remove pod %s which was running before the surge", targetKind, targetName, scaleDownReplicas, unsafePod)
+			logger.Info(message)
+			tracing.Decide(ctx, "surge-ordinal-unsafe")
+			if setCondition(&EvictionAutoScaler.Status.Conditions, ConditionSurgeOrdinalUnsafe, metav1.ConditionTrue, "PodPredatesSurge", message) {
+				events.Eventf(r.Recorder, EvictionAutoScaler, corev1.EventTypeWarning, events.ReasonSurgeOrdinalUnsafe, message)
+			} else if !statusChanged {
+				return ctrl.Result{RequeueAfter: r.Config.CooldownFor(EvictionAutoScaler)}, nil
+			}
+			return ctrl.Result{RequeueAfter: r.Config.CooldownFor(EvictionAutoScaler)}, r.updateStatus(ctx, EvictionAutoScaler)
+		}
+		target.SetReplicas(scaleDownReplicas)
+		if stepping {
+			target.AddAnnotation(EvictionSurgeReplicasAnnotationKey, strconv.FormatInt(int64(scaleDownReplicas), 10))
+		} else {
+			target.RemoveAnnotation(EvictionSurgeReplicasAnnotationKey)
+		}
+		tracing.Decide(ctx, "scale-down")
+		if r.DryRun {
+			events.DryRun(ctx, r.Recorder, EvictionAutoScaler, metrics.DryRunScaleTarget, "scale down %s %s to %d replicas",
+				targetKind, target.Obj().GetName(), scaleDownReplicas)
+			return ctrl.Result{}, nil
+		}
+		err = r.updateTarget(ctx, target)
 		if err != nil {
 			return ctrl.Result{}, err
 		}
 
 		// Track actual scaling action
-		metrics.ActualScalingCounter.WithLabelValues(EvictionAutoScaler.Namespace, EvictionAutoScaler.Spec.TargetName, metrics.ScaleDownAction).Inc()
+		metrics.ActualScalingCounter.WithLabelValues(EvictionAutoScaler.Namespace, targetName, metrics.ScaleDownAction).Inc()
+		metrics.ScaleDownCounter.WithLabelValues(EvictionAutoScaler.Namespace, strings.ToLower(targetKind)).Inc()
+		if EvictionAutoScaler.Spec.ScaleDownPolicy == myappsv1.ScaleDownPolicyStepped {
+			EvictionAutoScaler.Status.LastScaleDownTime = metav1.Now()
+		}
+		if stepping {
+			return r.steppedDown(ctx, EvictionAutoScaler, target, targetKind, targetName)
+		}
 
 		// Log the scaling action
-		logger.Info(fmt.Sprintf("Scaled down %s %s/%s to %d replicas", EvictionAutoScaler.Spec.TargetKind, target.Obj().GetNamespace(), target.Obj().GetName(), target.GetReplicas()))
+		logger.Info(fmt.Sprintf("Scaled down %s %s/%s to %d replicas", targetKind, target.Obj().GetNamespace(), target.Obj().GetName(), target.GetReplicas()))
+		events.Eventf(r.Recorder, EvictionAutoScaler, corev1.EventTypeNormal, events.ReasonSurgeScaledDown,
+			"Scaled down %s %s to %d replicas after cooldown", targetKind, target.Obj().GetName(), target.GetReplicas())
 		// Save ResourceVersion to EvictionAutoScaler status this will cause another reconcile.
 		logger.Info(fmt.Sprintf("TargetGeneration moving from %d->%d", EvictionAutoScaler.Status.TargetGeneration, target.Obj().GetGeneration()))
 		EvictionAutoScaler.Status.TargetGeneration = target.Obj().GetGeneration()
-		EvictionAutoScaler.Status.LastEviction = EvictionAutoScaler.Spec.LastEviction //we could still keep a log here if thats useful
-		logger.Info(fmt.Sprintf("Handled eviction %s", EvictionAutoScaler.Spec.LastEviction))
-
-		ready(&EvictionAutoScaler.Status.Conditions, "Reconciled", "evictions hit cooldown so scaled down")
-		return ctrl.Result{}, r.Status().Update(ctx, EvictionAutoScaler)
+		EvictionAutoScaler.Status.SurgeReplicas = 0
+		EvictionAutoScaler.Status.HandledEviction = EvictionAutoScaler.Status.LastEviction //we could still keep a log here if thats useful
+		logger.Info(fmt.Sprintf("Handled eviction %s", EvictionAutoScaler.Status.LastEviction))
+
+		surgeGone(EvictionAutoScaler, "ScaledDown", fmt.Sprintf("scaled down to %d replicas", scaleDownReplicas))
+		meta.RemoveStatusCondition(&EvictionAutoScaler.Status.Conditions, "SurgeLimited")
+		clearCondition(&EvictionAutoScaler.Status.Conditions, ConditionScalingUp, "ScaledDown", fmt.Sprintf("scaled down to %d replicas", scaleDownReplicas))
+		clearCondition(&EvictionAutoScaler.Status.Conditions, ConditionSurgeOrdinalUnsafe, "ScaledDown", fmt.Sprintf("scaled down to %d replicas", scaleDownReplicas))
+		clearCondition(&EvictionAutoScaler.Status.Conditions, ConditionSurgeUnschedulable, "ScaledDown", fmt.Sprintf("scaled down to %d replicas", scaleDownReplicas))
+		clearCondition(&EvictionAutoScaler.Status.Conditions, ConditionCoolingDown, "CooldownElapsed", "no evictions for "+r.Config.CooldownFor(EvictionAutoScaler).String())
+		setCondition(&EvictionAutoScaler.Status.Conditions, ConditionIdle, metav1.ConditionTrue, "ScaledDown", fmt.Sprintf("back at %d replicas", scaleDownReplicas))
+		if floored {
+			// the floor is the new baseline so the next surge starts from it
+			ready(&EvictionAutoScaler.Status.Conditions, "ScaledDownToFloor",
+				fmt.Sprintf("evictions hit cooldown so scaled down to spec.minReplicas %d instead of baseline %d", scaleDownReplicas, EvictionAutoScaler.Status.MinReplicas))
+			EvictionAutoScaler.Status.MinReplicas = scaleDownReplicas
+		} else {
+			ready(&EvictionAutoScaler.Status.Conditions, "Reconciled", "evictions hit cooldown so scaled down")
+		}
+		r.setSurge(ctx, req.NamespacedName, scaleDownReplicas-EvictionAutoScaler.Status.MinReplicas)
+		return ctrl.Result{}, r.updateScaleStatus(ctx, EvictionAutoScaler)
 	}
 
 	//could get here if a scale up/down was not needed because we never hit allowed diruptios == 0.
-	EvictionAutoScaler.Status.LastEviction = EvictionAutoScaler.Spec.LastEviction //we could still keep a log here if thats useful
+	EvictionAutoScaler.Status.HandledEviction = EvictionAutoScaler.Status.LastEviction //we could still keep a log here if thats useful
+	clearCondition(&EvictionAutoScaler.Status.Conditions, ConditionCoolingDown, "CooldownElapsed", "no evictions for "+r.Config.CooldownFor(EvictionAutoScaler).String())
 	ready(&EvictionAutoScaler.Status.Conditions, "Reconciled", "last eviction did not need scaling")
-	logger.Info(fmt.Sprintf("Handled eviction %s", EvictionAutoScaler.Spec.LastEviction))
-	return ctrl.Result{}, r.Status().Update(ctx, EvictionAutoScaler) //should we go rety in case there is also an eviction or just wait till the next eviction
+	tracing.Decide(ctx, "handled")
+	logger.Info(fmt.Sprintf("Handled eviction %s", EvictionAutoScaler.Status.LastEviction))
+	return ctrl.Result{}, r.updateStatus(ctx, EvictionAutoScaler) //should we go rety in case there is also an eviction or just wait till the next eviction
+}
+
+// pdbMissing reports that the EvictionAutoScaler's pdb is gone and once it has been gone for
+// PDBMissingGracePeriod deletes or suspends it. Status is kept till then since the pdb may just be getting recreated.
+func (r *EvictionAutoScalerReconciler) pdbMissing(ctx context.Context, EvictionAutoScaler *myappsv1.EvictionAutoScaler) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+	tracing.Decide(ctx, "pdb-missing")
+	conditions := &EvictionAutoScaler.Status.Conditions
+	degraded(conditions, "NoPdb", fmt.Sprintf("PDB %s not found", EvictionAutoScaler.PDBName()))
+	setCondition(conditions, ConditionPDBMissing, metav1.ConditionTrue, "NotFound", fmt.Sprintf("no pdb %s/%s", EvictionAutoScaler.Namespace, EvictionAutoScaler.PDBName()))
+	// LastTransitionTime only moves when the pdb first goes missing
+	missingFor := time.Since(meta.FindStatusCondition(*conditions, ConditionPDBMissing).LastTransitionTime.Time)
+	logger.Info("no matching pdb", "namespace", EvictionAutoScaler.Namespace, "name", EvictionAutoScaler.PDBName(), "missingFor", missingFor)
+
+	if r.PDBMissingAction == "" {
+		return ctrl.Result{}, r.updateStatus(ctx, EvictionAutoScaler)
+	}
+	if missingFor < r.PDBMissingGracePeriod {
+		return ctrl.Result{RequeueAfter: r.PDBMissingGracePeriod - missingFor}, r.updateStatus(ctx, EvictionAutoScaler)
+	}
+
+	switch r.PDBMissingAction {
+	case PDBMissingDelete:
+		if r.DryRun {
+			events.DryRun(ctx, r.Recorder, EvictionAutoScaler, metrics.DryRunDelete, "delete EvictionAutoScaler %s whose pdb has been missing for %s",
+				EvictionAutoScaler.Name, missingFor.Round(time.Second))
+			return ctrl.Result{}, nil
+		}
+		logger.Info("Deleting EvictionAutoScaler whose pdb is gone", "missingFor", missingFor)
+		return ctrl.Result{}, client.IgnoreNotFound(r.Delete(ctx, EvictionAutoScaler))
+	case PDBMissingSuspend:
+		meta.SetStatusCondition(conditions, metav1.Condition{
+			Type:               ConditionSuspended,
+			Status:             metav1.ConditionTrue,
+			Reason:             "PDBMissing",
+			Message:            fmt.Sprintf("pdb missing for more than %s, nothing is scaled till it is back", r.PDBMissingGracePeriod),
+			LastTransitionTime: metav1.Now(),
+		})
+	}
+	return ctrl.Result{}, r.updateStatus(ctx, EvictionAutoScaler)
+}
+
+// targetKindAndName is what we log and label metrics with for either kind of target.
+func targetKindAndName(EvictionAutoScaler *myappsv1.EvictionAutoScaler) (string, string) {
+	if ref := EvictionAutoScaler.Spec.TargetRef; ref != nil {
+		return ref.Kind, ref.Name
+	}
+	return EvictionAutoScaler.Spec.TargetKind, EvictionAutoScaler.Spec.TargetName
+}
+
+// EvictionTargetKind is the target_kind label of EvictionAutoScaler's evictions, the discovered target's kind if it
+// names none. Evictions are counted where they are recorded, the node controller and the eviction webhook.
+func EvictionTargetKind(EvictionAutoScaler *myappsv1.EvictionAutoScaler) string {
+	kind, name := targetKindAndName(EvictionAutoScaler)
+	if name == "" {
+		kind, _, _ = strings.Cut(EvictionAutoScaler.Status.ResolvedTarget, "/")
+	}
+	return metrics.TargetKindLabel(kind)
+}
+
+// pausedTarget doesn't surge a paused target and says so in status since the drain stays blocked on the pause.
+// With spec.pausedPolicy Defer the eviction is kept and we poll till the target is unpaused to surge for it.
+func (r *EvictionAutoScalerReconciler) pausedTarget(ctx context.Context, EvictionAutoScaler *myappsv1.EvictionAutoScaler, targetKind, targetName string) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+	tracing.Decide(ctx, "target-paused")
+	message := fmt.Sprintf("%s %s is paused so a surge would add no pods, unpause it to unblock the drain", targetKind, targetName)
+	reason := "SurgeSkipped"
+	if EvictionAutoScaler.Spec.PausedPolicy == myappsv1.PausedPolicyDefer {
+		message = fmt.Sprintf("%s %s is paused, surging once it is unpaused", targetKind, targetName)
+		reason = "SurgeDeferred"
+	} else {
+		EvictionAutoScaler.Status.HandledEviction = EvictionAutoScaler.Status.LastEviction
+	}
+	logger.Info(message, "lastEviction", EvictionAutoScaler.Status.LastEviction)
+	degraded(&EvictionAutoScaler.Status.Conditions, "TargetPaused", message)
+	if setCondition(&EvictionAutoScaler.Status.Conditions, ConditionTargetPaused, metav1.ConditionTrue, reason, message) {
+		events.Eventf(r.Recorder, EvictionAutoScaler, corev1.EventTypeWarning, events.ReasonTargetPaused, message)
+	}
+	if reason == "SurgeDeferred" {
+		return ctrl.Result{RequeueAfter: r.Config.CooldownFor(EvictionAutoScaler)}, r.updateStatus(ctx, EvictionAutoScaler)
+	}
+	return ctrl.Result{}, r.updateStatus(ctx, EvictionAutoScaler)
+}
+
+// stabilizationFor returns how long to hold a surge after the last draining node is released.
+func stabilizationFor(EvictionAutoScaler *myappsv1.EvictionAutoScaler) time.Duration {
+	if EvictionAutoScaler.Spec.ScaleDownStabilizationSeconds == nil {
+		return 0
+	}
+	return time.Duration(*EvictionAutoScaler.Spec.ScaleDownStabilizationSeconds) * time.Second
+}
+
+// expectedReplicas returns the replicas we last left the target at: its surge, or the baseline if it isn't surged.
+func expectedReplicas(EvictionAutoScaler *myappsv1.EvictionAutoScaler) int32 {
+	if EvictionAutoScaler.Status.SurgeReplicas > 0 {
+		return EvictionAutoScaler.Status.SurgeReplicas
+	}
+	return EvictionAutoScaler.Status.MinReplicas
+}
+
+// scaleDownTo returns the replicas to restore the target to after a surge: the baseline or spec.minReplicas
+// if that is higher, but never more than current. floored is true when spec.minReplicas won over the baseline.
+func scaleDownTo(EvictionAutoScaler *myappsv1.EvictionAutoScaler, current int32) (replicas int32, floored bool) {
+	replicas = EvictionAutoScaler.Status.MinReplicas
+	if floor := EvictionAutoScaler.Spec.MinReplicas; floor != nil && *floor > replicas {
+		replicas, floored = *floor, true
+	}
+	if replicas > current {
+		replicas = current
+	}
+	return replicas, floored
+}
+
+// migrateSpecEviction folds evictions written to the deprecated spec.lastEviction (by objects from before
+// lastEviction moved to status or by older node controllers/webhooks) into status.
+// Returns true if status changed and needs to be written.
+func migrateSpecEviction(EvictionAutoScaler *myappsv1.EvictionAutoScaler) bool {
+	specEviction := EvictionAutoScaler.Spec.LastEviction
+	status := &EvictionAutoScaler.Status
+	if specEviction.EvictionTime.IsZero() || specEviction == status.MigratedEviction {
+		return false
+	}
+	if status.MigratedEviction.EvictionTime.IsZero() && status.HandledEviction.EvictionTime.IsZero() {
+		// before handledEviction existed status.lastEviction was the last eviction we processed.
+		status.HandledEviction = status.LastEviction
+	}
+	// prefer status unless the spec eviction is newer
+	if specEviction.EvictionTime.After(status.LastEviction.EvictionTime.Time) {
+		status.LastEviction = specEviction
+		if status.LastEviction.Source == "" {
+			status.LastEviction.Source = myappsv1.EvictionSourceManual
+		}
+	}
+	status.MigratedEviction = specEviction
+	return true
 }
 
 func ready(conditions *[]metav1.Condition, reason string, message string) {
@@ -224,47 +831,108 @@ func ready(conditions *[]metav1.Condition, reason string, message string) {
 	meta.RemoveStatusCondition(conditions, "Degraded")
 }
 
-func degraded(conditions *[]metav1.Condition, reason string, message string) {
+// surgeLimited marks that maxReplicas kept us from surging as much as we wanted.
+func surgeLimited(conditions *[]metav1.Condition, message string) {
 	meta.SetStatusCondition(conditions, metav1.Condition{
-		Type:               "Degraded",
+		Type:               "SurgeLimited",
 		Status:             metav1.ConditionTrue,
-		Reason:             reason,
+		Reason:             "MaxReplicas",
 		Message:            message,
 		LastTransitionTime: metav1.Now(),
 	})
 }
 
-func (r *EvictionAutoScalerReconciler) SetupWithManager(mgr ctrl.Manager) error {
-	return ctrl.NewControllerManagedBy(mgr).
-		For(&myappsv1.EvictionAutoScaler{}).
-		WithEventFilter(predicate.Funcs{
-			// ignore status updates as we make those.
-			UpdateFunc: func(ue event.UpdateEvent) bool {
-				return ue.ObjectOld.GetGeneration() != ue.ObjectNew.GetGeneration()
-			},
-		}).
-		Complete(r)
+// updateStatus writes status once this reconcile has acted on the spec so observedGeneration can be waited on.
+// Status written before that, like migrating spec.lastEviction, goes through r.Status().Update directly.
+func (r *EvictionAutoScalerReconciler) updateStatus(ctx context.Context, EvictionAutoScaler *myappsv1.EvictionAutoScaler) error {
+	EvictionAutoScaler.Status.ObservedGeneration = EvictionAutoScaler.Generation
+	setScaleStatus(&EvictionAutoScaler.Status)
+	setSummaryStatus(EvictionAutoScaler)
+	return tracing.Span(ctx, "UpdateStatus", func(ctx context.Context) error {
+		return r.Status().Update(ctx, EvictionAutoScaler)
+	})
 }
 
-// TODO Unittest
-func calculateSurge(ctx context.Context, target Surger, minrepicas int32) int32 {
+// setCondition sets conditionType and reports if that changed anything worth a status update.
+func setCondition(conditions *[]metav1.Condition, conditionType string, status metav1.ConditionStatus, reason string, message string) bool {
+	return meta.SetStatusCondition(conditions, metav1.Condition{
+		Type:               conditionType,
+		Status:             status,
+		Reason:             reason,
+		Message:            message,
+		LastTransitionTime: metav1.Now(),
+	})
+}
 
-	surge := target.GetMaxSurge()
-	if surge.Type == intstr.Int {
-		return minrepicas + surge.IntVal
+// clearCondition flips conditionType to False if it was True and reports if it did.
+func clearCondition(conditions *[]metav1.Condition, conditionType string, reason string, message string) bool {
+	if !meta.IsStatusConditionTrue(*conditions, conditionType) {
+		return false
 	}
+	return setCondition(conditions, conditionType, metav1.ConditionFalse, reason, message)
+}
 
-	if surge.Type == intstr.String {
-		percentageStr := strings.TrimSuffix(surge.StrVal, "%")
-		percentage, err := strconv.Atoi(percentageStr)
-		if err != nil {
-			//return an error? so we can set degraded?
-			log.FromContext(ctx).Error(err, "invalid surge")
-			return minrepicas
+func degraded(conditions *[]metav1.Condition, reason string, message string) {
+	meta.SetStatusCondition(conditions, metav1.Condition{
+		Type:               "Degraded",
+		Status:             metav1.ConditionTrue,
+		Reason:             reason,
+		Message:            message,
+		LastTransitionTime: metav1.Now(),
+	})
+}
+
+// pdbChanged passes pdb updates that can change whether a surge is needed: spec changes such as the selector or
+// minAvailable, and disruptionsAllowed crossing zero, which eviction_autoscaler_monitored_pdbs_blocked follows too.
+// A change of the target annotation passes too, as does expectedPods crossing zero for NoPodsSelected. Other status
+// churn, like currentHealthy moving while disruptions stay allowed, is dropped.
+var pdbChanged = predicate.Funcs{
+	UpdateFunc: func(ue event.UpdateEvent) bool {
+		if ue.ObjectOld.GetGeneration() != ue.ObjectNew.GetGeneration() ||
+			ue.ObjectOld.GetAnnotations()[TargetAnnotationKey] != ue.ObjectNew.GetAnnotations()[TargetAnnotationKey] {
+			return true
 		}
-		return minrepicas + int32(math.Ceil((float64(minrepicas)*float64(percentage))/100.0))
-	}
+		oldPDB, oldOk := ue.ObjectOld.(*policyv1.PodDisruptionBudget)
+		newPDB, newOk := ue.ObjectNew.(*policyv1.PodDisruptionBudget)
+		return oldOk && newOk && ((oldPDB.Status.DisruptionsAllowed == 0) != (newPDB.Status.DisruptionsAllowed == 0) ||
+			(oldPDB.Status.ExpectedPods == 0) != (newPDB.Status.ExpectedPods == 0))
+	},
+}
 
-	panic("must be string or int")
+func (r *EvictionAutoScalerReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	if err := mgr.GetFieldIndexer().IndexField(context.TODO(), &myappsv1.EvictionAutoScaler{}, TargetIndex, evictionAutoScalerTarget); err != nil {
+		return err
+	}
+	if err := mgr.GetFieldIndexer().IndexField(context.TODO(), &myappsv1.EvictionAutoScaler{}, PDBIndex, evictionAutoScalerPDB); err != nil {
+		return err
+	}
 
+	scalerController := ctrl.NewControllerManagedBy(mgr).
+		For(&myappsv1.EvictionAutoScaler{}, builder.WithPredicates(predicate.Funcs{
+			// ignore status updates as we make those. Except evictions which are signaled through status.
+			UpdateFunc: func(ue event.UpdateEvent) bool {
+				if ue.ObjectOld.GetGeneration() != ue.ObjectNew.GetGeneration() {
+					return true
+				}
+				oldScaler, oldOk := ue.ObjectOld.(*myappsv1.EvictionAutoScaler)
+				newScaler, newOk := ue.ObjectNew.(*myappsv1.EvictionAutoScaler)
+				return oldOk && newOk && (oldScaler.Status.LastEviction != newScaler.Status.LastEviction ||
+					!slices.Equal(oldScaler.Status.DrainingNodes, newScaler.Status.DrainingNodes) ||
+					exceededDrains(&oldScaler.Status) != exceededDrains(&newScaler.Status) ||
+					meta.IsStatusConditionTrue(oldScaler.Status.Conditions, ConditionScalingUp) != meta.IsStatusConditionTrue(newScaler.Status.Conditions, ConditionScalingUp))
+			},
+		})).
+		WithOptions(controller.Options{MaxConcurrentReconciles: r.MaxConcurrentReconciles}).
+		// pick up pdbs that come back (or go away) under the EvictionAutoScaler pointing at them,
+		// have their selector or budget changed, or start or stop blocking every eviction.
+		Watches(&policyv1.PodDisruptionBudget{}, handler.EnqueueRequestsFromMapFunc(r.evictionAutoScalersForPDB), builder.WithPredicates(pdbChanged)).
+		// re-evaluate restoring the baseline as soon as a surged target settles, or someone scales it.
+		Watches(&appsv1.Deployment{}, handler.EnqueueRequestsFromMapFunc(r.evictionAutoScalersForTarget(deploymentKind)), builder.WithPredicates(targetChanged)).
+		Watches(&appsv1.StatefulSet{}, handler.EnqueueRequestsFromMapFunc(r.evictionAutoScalersForTarget(statefulSetKind)), builder.WithPredicates(targetChanged)).
+		Watches(&appsv1.ReplicaSet{}, handler.EnqueueRequestsFromMapFunc(r.evictionAutoScalersForTarget(replicaSetKind)), builder.WithPredicates(targetChanged))
+	if r.SurgeBudget != nil {
+		r.budgetReleased = make(chan event.GenericEvent, 1024)
+		scalerController = scalerController.WatchesRawSource(source.Channel(r.budgetReleased, &handler.EnqueueRequestForObject{}))
+	}
+	return scalerController.Complete(r)
 }
@@ -5,15 +5,23 @@ import (
 	"time"
 
 	v1 "github.com/azure/eviction-autoscaler/api/v1"
+	"github.com/azure/eviction-autoscaler/internal/events"
+	"github.com/azure/eviction-autoscaler/internal/evictionutil"
+	"github.com/azure/eviction-autoscaler/internal/metrics"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
 	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
 	corev1 "k8s.io/api/core/v1" // Import corev1 package
 	policyv1 "k8s.io/api/policy/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 )
 
@@ -124,6 +132,36 @@ var _ = Describe("EvictionAutoScaler Controller", func() {
 		AfterEach(func() {
 		})
 
+		It("should count its pdb as blocked only while it allows no disruptions", func() {
+			controllerReconciler := &EvictionAutoScalerReconciler{
+				Client: k8sClient,
+				Scheme: k8sClient.Scheme(),
+			}
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(gaugeValue(metrics.MonitoredPDBsBlockedGauge, namespace)).To(Equal(1.0))
+
+			pdb := &policyv1.PodDisruptionBudget{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, pdb)).To(Succeed())
+			pdb.Status.DisruptionsAllowed = 1
+			Expect(k8sClient.Status().Update(ctx, pdb)).To(Succeed())
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(gaugeValue(metrics.MonitoredPDBsBlockedGauge, namespace)).To(Equal(0.0))
+
+			pdb.Status.DisruptionsAllowed = 0
+			Expect(k8sClient.Status().Update(ctx, pdb)).To(Succeed())
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(gaugeValue(metrics.MonitoredPDBsBlockedGauge, namespace)).To(Equal(1.0))
+
+			By("deleting the EvictionAutoScaler")
+			Expect(k8sClient.Delete(ctx, &v1.EvictionAutoScaler{ObjectMeta: metav1.ObjectMeta{Name: resourceName, Namespace: namespace}})).To(Succeed())
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(gaugeValue(metrics.MonitoredPDBsBlockedGauge, namespace)).To(Equal(0.0))
+		})
+
 		It("should successfully reconcile the resource", func() {
 			By("reconciling the created resource")
 			controllerReconciler := &EvictionAutoScalerReconciler{
@@ -185,11 +223,11 @@ var _ = Describe("EvictionAutoScaler Controller", func() {
 			EvictionAutoScaler := &v1.EvictionAutoScaler{}
 			err = k8sClient.Get(ctx, typeNamespacedName, EvictionAutoScaler)
 			Expect(err).NotTo(HaveOccurred())
-			EvictionAutoScaler.Spec.LastEviction = v1.Eviction{
+			EvictionAutoScaler.Status.LastEviction = v1.Eviction{
 				PodName:      "somepod", //
 				EvictionTime: metav1.Now(),
 			}
-			Expect(k8sClient.Update(ctx, EvictionAutoScaler)).To(Succeed())
+			Expect(k8sClient.Status().Update(ctx, EvictionAutoScaler)).To(Succeed())
 
 			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{
 				NamespacedName: typeNamespacedName,
@@ -199,9 +237,9 @@ var _ = Describe("EvictionAutoScaler Controller", func() {
 			// Verify EvictionAutoScaler resource
 			err = k8sClient.Get(ctx, typeNamespacedName, EvictionAutoScaler)
 			Expect(err).NotTo(HaveOccurred())
-			Expect(EvictionAutoScaler.Spec.LastEviction.PodName).To(Equal("somepod"))
-			//we don't update status of last eviction till
-			Expect(EvictionAutoScaler.Spec.LastEviction.EvictionTime).ToNot(Equal(EvictionAutoScaler.Status.LastEviction.EvictionTime))
+			Expect(EvictionAutoScaler.Status.LastEviction.PodName).To(Equal("somepod"))
+			//we don't update handled eviction till we scale back down
+			Expect(EvictionAutoScaler.Status.LastEviction.EvictionTime).ToNot(Equal(EvictionAutoScaler.Status.HandledEviction.EvictionTime))
 
 			// Verify Deployment scaling if necessary
 			deployment := &appsv1.Deployment{}
@@ -210,6 +248,200 @@ var _ = Describe("EvictionAutoScaler Controller", func() {
 			Expect(*deployment.Spec.Replicas).To(Equal(int32(2))) // Change as needed to verify scaling
 		})
 
+		It("should surge a targetRef through the scale subresource", func() {
+			scales, err := NewScalesGetter(cfg, k8sClient.RESTMapper())
+			Expect(err).NotTo(HaveOccurred())
+			controllerReconciler := &EvictionAutoScalerReconciler{
+				Client: k8sClient,
+				Scheme: k8sClient.Scheme(),
+				Scales: scales,
+			}
+
+			EvictionAutoScaler := &v1.EvictionAutoScaler{}
+			err = k8sClient.Get(ctx, typeNamespacedName, EvictionAutoScaler)
+			Expect(err).NotTo(HaveOccurred())
+			EvictionAutoScaler.Spec.TargetName = "" // targetRef wins anyways
+			EvictionAutoScaler.Spec.TargetRef = &v1.TargetReference{APIVersion: "apps/v1", Kind: "Deployment", Name: deploymentName}
+			Expect(k8sClient.Update(ctx, EvictionAutoScaler)).To(Succeed())
+
+			// run it once to populate target genration
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			err = k8sClient.Get(ctx, typeNamespacedName, EvictionAutoScaler)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(EvictionAutoScaler.Status.MinReplicas).To(Equal(int32(1)))
+			EvictionAutoScaler.Status.LastEviction = v1.Eviction{
+				PodName:      "somepod",
+				EvictionTime: metav1.Now(),
+			}
+			Expect(k8sClient.Status().Update(ctx, EvictionAutoScaler)).To(Succeed())
+
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			deployment := &appsv1.Deployment{}
+			err = k8sClient.Get(ctx, deploymentNamespacedName, deployment)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(*deployment.Spec.Replicas).To(Equal(int32(2)))
+
+			err = k8sClient.Get(ctx, typeNamespacedName, EvictionAutoScaler)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(EvictionAutoScaler.Status.TargetGeneration).To(Equal(deployment.Generation))
+		})
+
+		It("should not surge past maxReplicas", func() {
+			recorder := record.NewFakeRecorder(10)
+			controllerReconciler := &EvictionAutoScalerReconciler{
+				Client:   k8sClient,
+				Scheme:   k8sClient.Scheme(),
+				Recorder: recorder,
+			}
+
+			// run it once to populate target genration
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			EvictionAutoScaler := &v1.EvictionAutoScaler{}
+			err = k8sClient.Get(ctx, typeNamespacedName, EvictionAutoScaler)
+			Expect(err).NotTo(HaveOccurred())
+			EvictionAutoScaler.Spec.MaxReplicas = int32Ptr(1)
+			Expect(k8sClient.Update(ctx, EvictionAutoScaler)).To(Succeed())
+			EvictionAutoScaler.Status.LastEviction = v1.Eviction{
+				PodName:      "somepod",
+				EvictionTime: metav1.Now(),
+			}
+			Expect(k8sClient.Status().Update(ctx, EvictionAutoScaler)).To(Succeed())
+
+			result, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.RequeueAfter).To(Equal(DefaultCooldown))
+
+			deployment := &appsv1.Deployment{}
+			err = k8sClient.Get(ctx, deploymentNamespacedName, deployment)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(*deployment.Spec.Replicas).To(Equal(int32(1)))
+
+			err = k8sClient.Get(ctx, typeNamespacedName, EvictionAutoScaler)
+			Expect(err).NotTo(HaveOccurred())
+			limited := meta.FindStatusCondition(EvictionAutoScaler.Status.Conditions, "SurgeLimited")
+			Expect(limited).NotTo(BeNil())
+			Expect(limited.Status).To(Equal(metav1.ConditionTrue))
+			Expect(recorder.Events).To(Receive(HavePrefix("Warning " + events.ReasonSurgeLimited)))
+		})
+
+		It("should be degraded when maxReplicas is below the baseline", func() {
+			controllerReconciler := &EvictionAutoScalerReconciler{
+				Client: k8sClient,
+				Scheme: k8sClient.Scheme(),
+			}
+
+			deployment := &appsv1.Deployment{}
+			err := k8sClient.Get(ctx, deploymentNamespacedName, deployment)
+			Expect(err).NotTo(HaveOccurred())
+			deployment.Spec.Replicas = int32Ptr(3)
+			Expect(k8sClient.Update(ctx, deployment)).To(Succeed())
+
+			EvictionAutoScaler := &v1.EvictionAutoScaler{}
+			err = k8sClient.Get(ctx, typeNamespacedName, EvictionAutoScaler)
+			Expect(err).NotTo(HaveOccurred())
+			EvictionAutoScaler.Spec.MaxReplicas = int32Ptr(2)
+			Expect(k8sClient.Update(ctx, EvictionAutoScaler)).To(Succeed())
+
+			// first picks up the baseline, second validates against it
+			for range 2 {
+				_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{
+					NamespacedName: typeNamespacedName,
+				})
+				Expect(err).NotTo(HaveOccurred())
+			}
+
+			err = k8sClient.Get(ctx, typeNamespacedName, EvictionAutoScaler)
+			Expect(err).NotTo(HaveOccurred())
+			invalid := meta.FindStatusCondition(EvictionAutoScaler.Status.Conditions, "Degraded")
+			Expect(invalid).NotTo(BeNil())
+			Expect(invalid.Reason).To(Equal("InvalidMaxReplicas"))
+		})
+
+		It("should surge enough to unblock a percentage pdb", func() {
+			controllerReconciler := &EvictionAutoScalerReconciler{
+				Client: k8sClient,
+				Scheme: k8sClient.Scheme(),
+			}
+
+			// ceil(75% of 2) is 2 but ceil(75% of 4) is 3 so one replica doesn't do it
+			deployment := &appsv1.Deployment{}
+			err := k8sClient.Get(ctx, deploymentNamespacedName, deployment)
+			Expect(err).NotTo(HaveOccurred())
+			deployment.Spec.Replicas = int32Ptr(2)
+			Expect(k8sClient.Update(ctx, deployment)).To(Succeed())
+			pdb := &policyv1.PodDisruptionBudget{}
+			err = k8sClient.Get(ctx, typeNamespacedName, pdb)
+			Expect(err).NotTo(HaveOccurred())
+			minAvailable := intstr.FromString("75%")
+			pdb.Spec.MinAvailable = &minAvailable
+			Expect(k8sClient.Update(ctx, pdb)).To(Succeed())
+
+			// run it once to populate target genration
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			_, err = evictionutil.RecordEviction(ctx, k8sClient, typeNamespacedName, v1.EvictionRecord{PodName: "somepod", EvictionTime: metav1.Now(), Source: v1.EvictionSourceEvictionAPI})
+			Expect(err).NotTo(HaveOccurred())
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			err = k8sClient.Get(ctx, deploymentNamespacedName, deployment)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(*deployment.Spec.Replicas).To(Equal(int32(4)))
+		})
+
+		It("should not surge when no number of replicas unblocks the pdb", func() {
+			controllerReconciler := &EvictionAutoScalerReconciler{
+				Client: k8sClient,
+				Scheme: k8sClient.Scheme(),
+			}
+
+			pdb := &policyv1.PodDisruptionBudget{}
+			err := k8sClient.Get(ctx, typeNamespacedName, pdb)
+			Expect(err).NotTo(HaveOccurred())
+			minAvailable := intstr.FromString("100%")
+			pdb.Spec.MinAvailable = &minAvailable
+			Expect(k8sClient.Update(ctx, pdb)).To(Succeed())
+
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			_, err = evictionutil.RecordEviction(ctx, k8sClient, typeNamespacedName, v1.EvictionRecord{PodName: "somepod", EvictionTime: metav1.Now(), Source: v1.EvictionSourceEvictionAPI})
+			Expect(err).NotTo(HaveOccurred())
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			deployment := &appsv1.Deployment{}
+			err = k8sClient.Get(ctx, deploymentNamespacedName, deployment)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(*deployment.Spec.Replicas).To(Equal(int32(1)))
+			EvictionAutoScaler := &v1.EvictionAutoScaler{}
+			err = k8sClient.Get(ctx, typeNamespacedName, EvictionAutoScaler)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(meta.FindStatusCondition(EvictionAutoScaler.Status.Conditions, "Degraded").Reason).To(Equal("SurgeCannotUnblock"))
+			Expect(EvictionAutoScaler.Status.HandledEviction).To(Equal(EvictionAutoScaler.Status.LastEviction))
+		})
+
 		It("should deal with an eviction when allowedDisruptions == 0 for statefulset!", func() {
 
 			By("creating a Deployment resource")
@@ -248,6 +480,10 @@ var _ = Describe("EvictionAutoScaler Controller", func() {
 				},
 			}
 			Expect(k8sClient.Create(ctx, statefulSet)).To(Succeed())
+			// no statefulset controller in envtest, its ordinal is ready so an OrderedReady surge can be created
+			statefulSet.Status.Replicas = 1
+			statefulSet.Status.ReadyReplicas = 1
+			Expect(k8sClient.Status().Update(ctx, statefulSet)).To(Succeed())
 
 			EvictionAutoScaler := &v1.EvictionAutoScaler{}
 			err := k8sClient.Get(ctx, typeNamespacedName, EvictionAutoScaler)
@@ -271,11 +507,11 @@ var _ = Describe("EvictionAutoScaler Controller", func() {
 			// Log an eviction (webhook would do this in e2e)
 			err = k8sClient.Get(ctx, typeNamespacedName, EvictionAutoScaler)
 			Expect(err).NotTo(HaveOccurred())
-			EvictionAutoScaler.Spec.LastEviction = v1.Eviction{
+			EvictionAutoScaler.Status.LastEviction = v1.Eviction{
 				PodName:      "somepod", //
 				EvictionTime: metav1.Now(),
 			}
-			Expect(k8sClient.Update(ctx, EvictionAutoScaler)).To(Succeed())
+			Expect(k8sClient.Status().Update(ctx, EvictionAutoScaler)).To(Succeed())
 
 			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{
 				NamespacedName: typeNamespacedName,
@@ -285,8 +521,8 @@ var _ = Describe("EvictionAutoScaler Controller", func() {
 			// Verify EvictionAutoScaler resource
 			err = k8sClient.Get(ctx, typeNamespacedName, EvictionAutoScaler)
 			Expect(err).NotTo(HaveOccurred())
-			Expect(EvictionAutoScaler.Spec.LastEviction.PodName).To(Equal("somepod"))
-			Expect(EvictionAutoScaler.Spec.LastEviction.EvictionTime).To(Equal(EvictionAutoScaler.Spec.LastEviction.EvictionTime))
+			Expect(EvictionAutoScaler.Status.LastEviction.PodName).To(Equal("somepod"))
+			Expect(EvictionAutoScaler.Status.LastEviction.EvictionTime).ToNot(Equal(EvictionAutoScaler.Status.HandledEviction.EvictionTime))
 
 			// Verify Deployment scaling if necessary
 			err = k8sClient.Get(ctx, types.NamespacedName{Name: statefulSetName, Namespace: namespace}, statefulSet)
@@ -313,11 +549,10 @@ var _ = Describe("EvictionAutoScaler Controller", func() {
 			EvictionAutoScaler := &v1.EvictionAutoScaler{}
 			err = k8sClient.Get(ctx, typeNamespacedName, EvictionAutoScaler)
 			Expect(err).NotTo(HaveOccurred())
-			EvictionAutoScaler.Spec.LastEviction = v1.Eviction{
+			EvictionAutoScaler.Status.LastEviction = v1.Eviction{
 				PodName:      "somepod", //
 				EvictionTime: metav1.Now(),
 			}
-			Expect(k8sClient.Update(ctx, EvictionAutoScaler)).To(Succeed())
 			EvictionAutoScaler.Status.MinReplicas = 1
 			EvictionAutoScaler.Status.TargetGeneration = deployment.Generation
 			Expect(k8sClient.Status().Update(ctx, EvictionAutoScaler)).To(Succeed())
@@ -334,7 +569,7 @@ var _ = Describe("EvictionAutoScaler Controller", func() {
 				NamespacedName: typeNamespacedName,
 			})
 			Expect(err).NotTo(HaveOccurred())
-			Expect(result.RequeueAfter).To(Equal(cooldown))
+			Expect(result.RequeueAfter).To(Equal(DefaultCooldown))
 
 			// Deployment is not changed yet
 			err = k8sClient.Get(ctx, deploymentNamespacedName, deployment)
@@ -344,15 +579,14 @@ var _ = Describe("EvictionAutoScaler Controller", func() {
 			// Verify EvictionAutoScaler resource
 			err = k8sClient.Get(ctx, typeNamespacedName, EvictionAutoScaler)
 			Expect(err).NotTo(HaveOccurred())
-			Expect(EvictionAutoScaler.Spec.LastEviction.PodName).To(Equal("somepod"))
-			Expect(EvictionAutoScaler.Spec.LastEviction.EvictionTime).ToNot(Equal(EvictionAutoScaler.Status.LastEviction.EvictionTime))
+			Expect(EvictionAutoScaler.Status.LastEviction.PodName).To(Equal("somepod"))
+			Expect(EvictionAutoScaler.Status.LastEviction.EvictionTime).ToNot(Equal(EvictionAutoScaler.Status.HandledEviction.EvictionTime))
 
 			By("scaling down after cooldown")
 			//okay lets say the eviction is older though
-			//TODO make cooldown const/configurable
-			EvictionAutoScaler.Spec.LastEviction.EvictionTime = metav1.NewTime(time.Now().Add(-2 * cooldown))
-			Expect(k8sClient.Update(ctx, EvictionAutoScaler)).To(Succeed())
-			Expect(EvictionAutoScaler.Spec.LastEviction.EvictionTime).ToNot(Equal(EvictionAutoScaler.Status.LastEviction.EvictionTime))
+			EvictionAutoScaler.Status.LastEviction.EvictionTime = metav1.NewTime(time.Now().Add(-2 * DefaultCooldown))
+			Expect(k8sClient.Status().Update(ctx, EvictionAutoScaler)).To(Succeed())
+			Expect(EvictionAutoScaler.Status.LastEviction.EvictionTime).ToNot(Equal(EvictionAutoScaler.Status.HandledEviction.EvictionTime))
 
 			//second reconcile should scaledown.
 			result, err = controllerReconciler.Reconcile(ctx, reconcile.Request{
@@ -369,120 +603,620 @@ var _ = Describe("EvictionAutoScaler Controller", func() {
 			// EvictionAutoScaler should be ready and
 			err = k8sClient.Get(ctx, typeNamespacedName, EvictionAutoScaler)
 			Expect(err).NotTo(HaveOccurred())
-			Expect(EvictionAutoScaler.Spec.LastEviction.PodName).To(Equal("somepod"))
-			Expect(EvictionAutoScaler.Spec.LastEviction.EvictionTime).To(Equal(EvictionAutoScaler.Status.LastEviction.EvictionTime))
+			Expect(EvictionAutoScaler.Status.LastEviction.PodName).To(Equal("somepod"))
+			Expect(EvictionAutoScaler.Status.LastEviction.EvictionTime).To(Equal(EvictionAutoScaler.Status.HandledEviction.EvictionTime))
 			Expect(EvictionAutoScaler.Status.Conditions[0].Type).To(Equal("Ready"))
 			Expect(EvictionAutoScaler.Status.Conditions[0].Reason).To(Equal("Reconciled"))
 
 		})
 
-		//TODO reset on deployment change
-		It("should deal with deployment spec change", func() {
-			By("reseting min replicas and target generation")
+		It("should not scale down below spec.minReplicas", func() {
 			controllerReconciler := &EvictionAutoScalerReconciler{
 				Client: k8sClient,
 				Scheme: k8sClient.Scheme(),
 			}
 
-			// run it once to populate target genration
-			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
-				NamespacedName: typeNamespacedName,
-			})
-			Expect(err).NotTo(HaveOccurred())
-
-			// Verify EvictionAutoScaler resource
 			EvictionAutoScaler := &v1.EvictionAutoScaler{}
-			err = k8sClient.Get(ctx, typeNamespacedName, EvictionAutoScaler)
+			err := k8sClient.Get(ctx, typeNamespacedName, EvictionAutoScaler)
 			Expect(err).NotTo(HaveOccurred())
-			Expect(EvictionAutoScaler.Status.MinReplicas).To(Equal(int32(1)))
-			Expect(EvictionAutoScaler.Status.TargetGeneration).ToNot(BeZero())
-			firstGeneration := EvictionAutoScaler.Status.TargetGeneration
+			EvictionAutoScaler.Spec.MinReplicas = int32Ptr(2)
+			Expect(k8sClient.Update(ctx, EvictionAutoScaler)).To(Succeed())
 
-			// outside user changes deployment
+			// simulate previously surged from a baseline of 1
 			deployment := &appsv1.Deployment{}
 			err = k8sClient.Get(ctx, deploymentNamespacedName, deployment)
 			Expect(err).NotTo(HaveOccurred())
-			deployment.Spec.Replicas = int32Ptr(5)
+			deployment.Spec.Replicas = int32Ptr(3)
 			Expect(k8sClient.Update(ctx, deployment)).To(Succeed())
 
+			EvictionAutoScaler.Status.LastEviction = v1.Eviction{
+				PodName:      "somepod",
+				EvictionTime: metav1.NewTime(time.Now().Add(-2 * DefaultCooldown)),
+			}
+			EvictionAutoScaler.Status.MinReplicas = 1
+			EvictionAutoScaler.Status.TargetGeneration = deployment.Generation
+			Expect(k8sClient.Status().Update(ctx, EvictionAutoScaler)).To(Succeed())
+
 			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{
 				NamespacedName: typeNamespacedName,
 			})
 			Expect(err).NotTo(HaveOccurred())
 
-			// Verify EvictionAutoScaler resource reset min replicas and target genration
-			err = k8sClient.Get(ctx, typeNamespacedName, EvictionAutoScaler)
+			err = k8sClient.Get(ctx, deploymentNamespacedName, deployment)
 			Expect(err).NotTo(HaveOccurred())
-			Expect(EvictionAutoScaler.Status.MinReplicas).To(Equal(int32(5)))
-			Expect(EvictionAutoScaler.Status.TargetGeneration).ToNot(BeZero())
-			Expect(EvictionAutoScaler.Status.TargetGeneration).ToNot(Equal(firstGeneration))
+			Expect(*deployment.Spec.Replicas).To(Equal(int32(2)))
 
-			// Verify Deployment left alone?
-			err = k8sClient.Get(ctx, deploymentNamespacedName, deployment)
+			err = k8sClient.Get(ctx, typeNamespacedName, EvictionAutoScaler)
 			Expect(err).NotTo(HaveOccurred())
-			Expect(*deployment.Spec.Replicas).To(Equal(int32(5))) // Change as needed to verify scaling
+			Expect(EvictionAutoScaler.Status.MinReplicas).To(Equal(int32(2)))
+			Expect(meta.FindStatusCondition(EvictionAutoScaler.Status.Conditions, "Ready").Reason).To(Equal("ScaledDownToFloor"))
 		})
 
-		//TODO do noting on old eviction
-		//TODO test a statefulset.
-
-	})
-
-	Context("when reconciling bad crds", func() {
-		BeforeEach(func() {
-
-			namespaceObj := &corev1.Namespace{
-				ObjectMeta: metav1.ObjectMeta{
-					GenerateName: "test",
-				},
-			}
-
-			// create the namespace using the controller-runtime client
-			Expect(k8sClient.Create(ctx, namespaceObj)).To(Succeed())
-			namespace = namespaceObj.Name
-			typeNamespacedName = types.NamespacedName{Name: resourceName, Namespace: namespace}
+		It("should reject minReplicas above maxReplicas", func() {
+			EvictionAutoScaler := &v1.EvictionAutoScaler{}
+			err := k8sClient.Get(ctx, typeNamespacedName, EvictionAutoScaler)
+			Expect(err).NotTo(HaveOccurred())
+			EvictionAutoScaler.Spec.MinReplicas = int32Ptr(3)
+			EvictionAutoScaler.Spec.MaxReplicas = int32Ptr(2)
+			Expect(k8sClient.Update(ctx, EvictionAutoScaler)).NotTo(Succeed())
 		})
 
-		It("should deal with no pdb", func() {
-			By("by updating condition to degraded")
+		It("should hold the surge while a draining node is left", func() {
 			controllerReconciler := &EvictionAutoScalerReconciler{
 				Client: k8sClient,
 				Scheme: k8sClient.Scheme(),
 			}
 
-			EvictionAutoScaler := &v1.EvictionAutoScaler{
-				ObjectMeta: metav1.ObjectMeta{
-					Name:      resourceName,
-					Namespace: namespace,
-				},
-				Spec: v1.EvictionAutoScalerSpec{
-					TargetName: deploymentName,
-					TargetKind: "deployment",
-				},
-			}
-			Expect(k8sClient.Create(ctx, EvictionAutoScaler)).To(Succeed())
-
-			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
-				NamespacedName: typeNamespacedName,
-			})
+			// simulate previously scaled up for two cordoned nodes
+			deployment := &appsv1.Deployment{}
+			err := k8sClient.Get(ctx, deploymentNamespacedName, deployment)
 			Expect(err).NotTo(HaveOccurred())
+			deployment.Spec.Replicas = int32Ptr(2)
+			Expect(k8sClient.Update(ctx, deployment)).To(Succeed())
 
-			// Verify EvictionAutoScaler resource
+			EvictionAutoScaler := &v1.EvictionAutoScaler{}
 			err = k8sClient.Get(ctx, typeNamespacedName, EvictionAutoScaler)
 			Expect(err).NotTo(HaveOccurred())
-			Expect(EvictionAutoScaler.Status.Conditions).To(HaveLen(1))
-			Expect(EvictionAutoScaler.Status.Conditions[0].Type).To(Equal("Degraded"))
-			Expect(EvictionAutoScaler.Status.Conditions[0].Reason).To(Equal("NoPdb"))
-		})
-
-		It("should deal with no target ", func() {
-			By("by updating condition to degraded")
-			controllerReconciler := &EvictionAutoScalerReconciler{
-				Client: k8sClient,
-				Scheme: k8sClient.Scheme(),
+			EvictionAutoScaler.Status.LastEviction = v1.Eviction{
+				PodName:      "somepod",
+				EvictionTime: metav1.NewTime(time.Now().Add(-2 * DefaultCooldown)),
 			}
+			EvictionAutoScaler.Status.MinReplicas = 1
+			EvictionAutoScaler.Status.TargetGeneration = deployment.Generation
+			EvictionAutoScaler.Status.DrainingNodes = []string{"node-a", "node-b"}
+			Expect(k8sClient.Status().Update(ctx, EvictionAutoScaler)).To(Succeed())
 
-			EvictionAutoScaler := &v1.EvictionAutoScaler{
+			pdb := &policyv1.PodDisruptionBudget{}
+			err = k8sClient.Get(ctx, typeNamespacedName, pdb)
+			Expect(err).NotTo(HaveOccurred())
+			pdb.Status.DisruptionsAllowed = 1
+			Expect(k8sClient.Status().Update(ctx, pdb)).To(Succeed())
+
+			By("deleting one of the nodes")
+			_, err = evictionutil.ReleaseNode(ctx, k8sClient, typeNamespacedName, "node-a")
+			Expect(err).NotTo(HaveOccurred())
+
+			result, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.RequeueAfter).To(Equal(DefaultCooldown))
+
+			err = k8sClient.Get(ctx, deploymentNamespacedName, deployment)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(*deployment.Spec.Replicas).To(Equal(int32(2)))
+
+			By("deleting the last node")
+			_, err = evictionutil.ReleaseNode(ctx, k8sClient, typeNamespacedName, "node-b")
+			Expect(err).NotTo(HaveOccurred())
+
+			result, err = controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.RequeueAfter).To(Equal(time.Duration(0)))
+
+			err = k8sClient.Get(ctx, deploymentNamespacedName, deployment)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(*deployment.Spec.Replicas).To(Equal(int32(1)))
+		})
+
+		It("should migrate an eviction written to the deprecated spec field", func() {
+			controllerReconciler := &EvictionAutoScalerReconciler{
+				Client: k8sClient,
+				Scheme: k8sClient.Scheme(),
+			}
+
+			// run it once to populate target genration
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			// simulate an object from before lastEviction moved to status:
+			// status.lastEviction was the handled eviction and spec has a newer unhandled one.
+			EvictionAutoScaler := &v1.EvictionAutoScaler{}
+			err = k8sClient.Get(ctx, typeNamespacedName, EvictionAutoScaler)
+			Expect(err).NotTo(HaveOccurred())
+			handled := v1.Eviction{PodName: "oldpod", EvictionTime: metav1.NewTime(time.Now().Add(-time.Hour))}
+			EvictionAutoScaler.Status.LastEviction = handled
+			Expect(k8sClient.Status().Update(ctx, EvictionAutoScaler)).To(Succeed())
+			EvictionAutoScaler.Spec.LastEviction = v1.Eviction{PodName: "somepod", EvictionTime: metav1.Now()}
+			Expect(k8sClient.Update(ctx, EvictionAutoScaler)).To(Succeed())
+
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			err = k8sClient.Get(ctx, typeNamespacedName, EvictionAutoScaler)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(EvictionAutoScaler.Status.LastEviction.PodName).To(Equal("somepod"))
+			Expect(EvictionAutoScaler.Status.LastEviction.Source).To(Equal(v1.EvictionSourceManual))
+			Expect(EvictionAutoScaler.Status.HandledEviction.PodName).To(Equal("oldpod"))
+
+			// the unhandled eviction from spec is acted on
+			deployment := &appsv1.Deployment{}
+			err = k8sClient.Get(ctx, deploymentNamespacedName, deployment)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(*deployment.Spec.Replicas).To(Equal(int32(2)))
+		})
+
+		//TODO reset on deployment change
+		It("should deal with deployment spec change", func() {
+			By("reseting min replicas and target generation")
+			controllerReconciler := &EvictionAutoScalerReconciler{
+				Client: k8sClient,
+				Scheme: k8sClient.Scheme(),
+			}
+
+			// run it once to populate target genration
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			// Verify EvictionAutoScaler resource
+			EvictionAutoScaler := &v1.EvictionAutoScaler{}
+			err = k8sClient.Get(ctx, typeNamespacedName, EvictionAutoScaler)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(EvictionAutoScaler.Status.MinReplicas).To(Equal(int32(1)))
+			Expect(EvictionAutoScaler.Status.TargetGeneration).ToNot(BeZero())
+			firstGeneration := EvictionAutoScaler.Status.TargetGeneration
+
+			// outside user changes deployment
+			deployment := &appsv1.Deployment{}
+			err = k8sClient.Get(ctx, deploymentNamespacedName, deployment)
+			Expect(err).NotTo(HaveOccurred())
+			deployment.Spec.Replicas = int32Ptr(5)
+			Expect(k8sClient.Update(ctx, deployment)).To(Succeed())
+
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			// Verify EvictionAutoScaler resource reset min replicas and target genration
+			err = k8sClient.Get(ctx, typeNamespacedName, EvictionAutoScaler)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(EvictionAutoScaler.Status.MinReplicas).To(Equal(int32(5)))
+			Expect(EvictionAutoScaler.Status.TargetGeneration).ToNot(BeZero())
+			Expect(EvictionAutoScaler.Status.TargetGeneration).ToNot(Equal(firstGeneration))
+
+			// Verify Deployment left alone?
+			err = k8sClient.Get(ctx, deploymentNamespacedName, deployment)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(*deployment.Spec.Replicas).To(Equal(int32(5))) // Change as needed to verify scaling
+		})
+
+		It("should set ScalingUp and CoolingDown through a surge and flip them back after", func() {
+			controllerReconciler := &EvictionAutoScalerReconciler{
+				Client: k8sClient,
+				Scheme: k8sClient.Scheme(),
+			}
+			reconcileAndGet := func() *v1.EvictionAutoScaler {
+				_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
+					NamespacedName: typeNamespacedName,
+				})
+				Expect(err).NotTo(HaveOccurred())
+				EvictionAutoScaler := &v1.EvictionAutoScaler{}
+				Expect(k8sClient.Get(ctx, typeNamespacedName, EvictionAutoScaler)).To(Succeed())
+				return EvictionAutoScaler
+			}
+
+			// run it once to populate target genration
+			EvictionAutoScaler := reconcileAndGet()
+			Expect(meta.FindStatusCondition(EvictionAutoScaler.Status.Conditions, ConditionScalingUp)).To(BeNil())
+
+			By("surging on an eviction")
+			EvictionAutoScaler.Status.LastEviction = v1.Eviction{
+				PodName:      "somepod",
+				EvictionTime: metav1.Now(),
+			}
+			Expect(k8sClient.Status().Update(ctx, EvictionAutoScaler)).To(Succeed())
+			EvictionAutoScaler = reconcileAndGet()
+			scalingUp := meta.FindStatusCondition(EvictionAutoScaler.Status.Conditions, ConditionScalingUp)
+			Expect(scalingUp.Status).To(Equal(metav1.ConditionTrue))
+			Expect(scalingUp.Reason).To(Equal("Surged"))
+			Expect(scalingUp.Message).To(ContainSubstring("from 1 to 2 replicas"))
+			Expect(meta.IsStatusConditionFalse(EvictionAutoScaler.Status.Conditions, ConditionIdle)).To(BeTrue())
+			Expect(counterValue(metrics.ScaleUpCounter, namespace, "deployment")).To(Equal(1.0))
+			Expect(counterValue(metrics.ScaleUpReplicasCounter, namespace, "deployment")).To(Equal(1.0))
+			Expect(gaugeValue(metrics.SurgeReplicasGauge, namespace)).To(Equal(1.0))
+
+			By("cooling down while the eviction is recent")
+			EvictionAutoScaler = reconcileAndGet()
+			coolingDown := meta.FindStatusCondition(EvictionAutoScaler.Status.Conditions, ConditionCoolingDown)
+			Expect(coolingDown.Status).To(Equal(metav1.ConditionTrue))
+			Expect(coolingDown.Reason).To(Equal("RecentEviction"))
+			Expect(meta.IsStatusConditionTrue(EvictionAutoScaler.Status.Conditions, ConditionScalingUp)).To(BeTrue())
+
+			By("scaling down after cooldown")
+			EvictionAutoScaler.Status.LastEviction.EvictionTime = metav1.NewTime(time.Now().Add(-2 * DefaultCooldown))
+			Expect(k8sClient.Status().Update(ctx, EvictionAutoScaler)).To(Succeed())
+			EvictionAutoScaler = reconcileAndGet()
+			scalingUp = meta.FindStatusCondition(EvictionAutoScaler.Status.Conditions, ConditionScalingUp)
+			Expect(scalingUp.Status).To(Equal(metav1.ConditionFalse))
+			Expect(scalingUp.Reason).To(Equal("ScaledDown"))
+			coolingDown = meta.FindStatusCondition(EvictionAutoScaler.Status.Conditions, ConditionCoolingDown)
+			Expect(coolingDown.Status).To(Equal(metav1.ConditionFalse))
+			Expect(coolingDown.Reason).To(Equal("CooldownElapsed"))
+			idle := meta.FindStatusCondition(EvictionAutoScaler.Status.Conditions, ConditionIdle)
+			Expect(idle.Status).To(Equal(metav1.ConditionTrue))
+			Expect(idle.Reason).To(Equal("ScaledDown"))
+			Expect(counterValue(metrics.ScaleDownCounter, namespace, "deployment")).To(Equal(1.0))
+			Expect(gaugeValue(metrics.SurgeReplicasGauge, namespace)).To(BeZero())
+		})
+
+		It("should hold the surge for the stabilization window after the drain finishes", func() {
+			controllerReconciler := &EvictionAutoScalerReconciler{
+				Client: k8sClient,
+				Scheme: k8sClient.Scheme(),
+			}
+			reconcileAndGet := func() (*v1.EvictionAutoScaler, reconcile.Result) {
+				result, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
+					NamespacedName: typeNamespacedName,
+				})
+				Expect(err).NotTo(HaveOccurred())
+				EvictionAutoScaler := &v1.EvictionAutoScaler{}
+				Expect(k8sClient.Get(ctx, typeNamespacedName, EvictionAutoScaler)).To(Succeed())
+				return EvictionAutoScaler, result
+			}
+
+			EvictionAutoScaler := &v1.EvictionAutoScaler{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, EvictionAutoScaler)).To(Succeed())
+			EvictionAutoScaler.Spec.ScaleDownStabilizationSeconds = int32Ptr(300)
+			Expect(k8sClient.Update(ctx, EvictionAutoScaler)).To(Succeed())
+
+			// run it once to populate target genration
+			EvictionAutoScaler, _ = reconcileAndGet()
+
+			By("surging on an eviction from a draining node")
+			EvictionAutoScaler.Status.LastEviction = v1.Eviction{
+				PodName:      "somepod",
+				EvictionTime: metav1.Now(),
+			}
+			EvictionAutoScaler.Status.DrainingNodes = []string{"node1"}
+			Expect(k8sClient.Status().Update(ctx, EvictionAutoScaler)).To(Succeed())
+			EvictionAutoScaler, _ = reconcileAndGet()
+			Expect(meta.IsStatusConditionTrue(EvictionAutoScaler.Status.Conditions, ConditionScalingUp)).To(BeTrue())
+
+			By("stabilizing once the node is released and the cooldown is over")
+			EvictionAutoScaler.Status.LastEviction.EvictionTime = metav1.NewTime(time.Now().Add(-2 * DefaultCooldown))
+			EvictionAutoScaler.Status.DrainingNodes = nil
+			EvictionAutoScaler.Status.DrainedTime = metav1.NewTime(time.Now().Add(-time.Minute))
+			Expect(k8sClient.Status().Update(ctx, EvictionAutoScaler)).To(Succeed())
+			EvictionAutoScaler, result := reconcileAndGet()
+			coolingDown := meta.FindStatusCondition(EvictionAutoScaler.Status.Conditions, ConditionCoolingDown)
+			Expect(coolingDown.Status).To(Equal(metav1.ConditionTrue))
+			Expect(coolingDown.Reason).To(Equal("Stabilizing"))
+			Expect(result.RequeueAfter).To(BeNumerically("~", 4*time.Minute, 5*time.Second))
+			deployment := &appsv1.Deployment{}
+			Expect(k8sClient.Get(ctx, deploymentNamespacedName, deployment)).To(Succeed())
+			Expect(*deployment.Spec.Replicas).To(Equal(int32(2)))
+
+			By("scaling down after the window")
+			EvictionAutoScaler.Status.DrainedTime = metav1.NewTime(time.Now().Add(-10 * time.Minute))
+			Expect(k8sClient.Status().Update(ctx, EvictionAutoScaler)).To(Succeed())
+			EvictionAutoScaler, _ = reconcileAndGet()
+			Expect(meta.IsStatusConditionTrue(EvictionAutoScaler.Status.Conditions, ConditionIdle)).To(BeTrue())
+			Expect(k8sClient.Get(ctx, deploymentNamespacedName, deployment)).To(Succeed())
+			Expect(*deployment.Spec.Replicas).To(Equal(int32(1)))
+		})
+
+		It("should not surge a target scaled by an HPA by default", func() {
+			controllerReconciler := &EvictionAutoScalerReconciler{
+				Client: k8sClient,
+				Scheme: k8sClient.Scheme(),
+			}
+			hpa := &autoscalingv2.HorizontalPodAutoscaler{
+				ObjectMeta: metav1.ObjectMeta{Name: "example-hpa", Namespace: namespace},
+				Spec: autoscalingv2.HorizontalPodAutoscalerSpec{
+					ScaleTargetRef: autoscalingv2.CrossVersionObjectReference{APIVersion: "apps/v1", Kind: "Deployment", Name: deploymentName},
+					MinReplicas:    int32Ptr(1),
+					MaxReplicas:    10,
+				},
+			}
+			Expect(k8sClient.Create(ctx, hpa)).To(Succeed())
+
+			EvictionAutoScaler := &v1.EvictionAutoScaler{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, EvictionAutoScaler)).To(Succeed())
+			EvictionAutoScaler.Status.LastEviction = v1.Eviction{
+				PodName:      "somepod",
+				EvictionTime: metav1.Now(),
+			}
+			Expect(k8sClient.Status().Update(ctx, EvictionAutoScaler)).To(Succeed())
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(k8sClient.Get(ctx, typeNamespacedName, EvictionAutoScaler)).To(Succeed())
+			conflicting := meta.FindStatusCondition(EvictionAutoScaler.Status.Conditions, ConditionConflictingAutoscaler)
+			Expect(conflicting.Status).To(Equal(metav1.ConditionTrue))
+			Expect(conflicting.Message).To(ContainSubstring("example-hpa"))
+			Expect(EvictionAutoScaler.Status.HandledEviction).To(Equal(EvictionAutoScaler.Status.LastEviction))
+			deployment := &appsv1.Deployment{}
+			Expect(k8sClient.Get(ctx, deploymentNamespacedName, deployment)).To(Succeed())
+			Expect(*deployment.Spec.Replicas).To(Equal(int32(1)))
+
+			By("clearing the condition once the HPA is gone")
+			Expect(k8sClient.Delete(ctx, hpa)).To(Succeed())
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(k8sClient.Get(ctx, typeNamespacedName, EvictionAutoScaler)).To(Succeed())
+			Expect(meta.IsStatusConditionFalse(EvictionAutoScaler.Status.Conditions, ConditionConflictingAutoscaler)).To(BeTrue())
+		})
+
+		It("should raise and restore the HPA's minReplicas with hpaPolicy AdjustMinReplicas", func() {
+			controllerReconciler := &EvictionAutoScalerReconciler{
+				Client: k8sClient,
+				Scheme: k8sClient.Scheme(),
+			}
+			reconcileAndGet := func() *v1.EvictionAutoScaler {
+				_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
+					NamespacedName: typeNamespacedName,
+				})
+				Expect(err).NotTo(HaveOccurred())
+				EvictionAutoScaler := &v1.EvictionAutoScaler{}
+				Expect(k8sClient.Get(ctx, typeNamespacedName, EvictionAutoScaler)).To(Succeed())
+				return EvictionAutoScaler
+			}
+			hpa := &autoscalingv2.HorizontalPodAutoscaler{
+				ObjectMeta: metav1.ObjectMeta{Name: "example-hpa", Namespace: namespace},
+				Spec: autoscalingv2.HorizontalPodAutoscalerSpec{
+					ScaleTargetRef: autoscalingv2.CrossVersionObjectReference{APIVersion: "apps/v1", Kind: "Deployment", Name: deploymentName},
+					MaxReplicas:    10,
+				},
+			}
+			Expect(k8sClient.Create(ctx, hpa)).To(Succeed())
+			hpaNamespacedName := types.NamespacedName{Name: hpa.Name, Namespace: namespace}
+
+			EvictionAutoScaler := &v1.EvictionAutoScaler{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, EvictionAutoScaler)).To(Succeed())
+			EvictionAutoScaler.Spec.HPAPolicy = v1.HPAPolicyAdjustMinReplicas
+			Expect(k8sClient.Update(ctx, EvictionAutoScaler)).To(Succeed())
+
+			By("raising minReplicas on an eviction")
+			EvictionAutoScaler.Status.LastEviction = v1.Eviction{
+				PodName:      "somepod",
+				EvictionTime: metav1.Now(),
+			}
+			Expect(k8sClient.Status().Update(ctx, EvictionAutoScaler)).To(Succeed())
+			EvictionAutoScaler = reconcileAndGet()
+			Expect(EvictionAutoScaler.Status.AutoscalerSurge).NotTo(BeNil())
+			Expect(EvictionAutoScaler.Status.AutoscalerSurge.OriginalMinReplicas).To(BeNil())
+			Expect(meta.IsStatusConditionTrue(EvictionAutoScaler.Status.Conditions, ConditionScalingUp)).To(BeTrue())
+			Expect(k8sClient.Get(ctx, hpaNamespacedName, hpa)).To(Succeed())
+			Expect(*hpa.Spec.MinReplicas).To(Equal(int32(2)))
+			deployment := &appsv1.Deployment{}
+			Expect(k8sClient.Get(ctx, deploymentNamespacedName, deployment)).To(Succeed())
+			Expect(*deployment.Spec.Replicas).To(Equal(int32(1)), "the hpa scales the deployment, not us")
+
+			By("restoring it after cooldown")
+			EvictionAutoScaler.Status.LastEviction.EvictionTime = metav1.NewTime(time.Now().Add(-2 * DefaultCooldown))
+			Expect(k8sClient.Status().Update(ctx, EvictionAutoScaler)).To(Succeed())
+			EvictionAutoScaler = reconcileAndGet()
+			Expect(EvictionAutoScaler.Status.AutoscalerSurge).To(BeNil())
+			Expect(EvictionAutoScaler.Status.HandledEviction).To(Equal(EvictionAutoScaler.Status.LastEviction))
+			Expect(meta.IsStatusConditionTrue(EvictionAutoScaler.Status.Conditions, ConditionIdle)).To(BeTrue())
+			Expect(k8sClient.Get(ctx, hpaNamespacedName, hpa)).To(Succeed())
+			Expect((&HPAWrapper{obj: hpa}).MinReplicas()).To(Equal(int32(1)))
+
+			By("forgetting the surge if the HPA is deleted mid surge")
+			EvictionAutoScaler.Status.LastEviction = v1.Eviction{
+				PodName:      "otherpod",
+				EvictionTime: metav1.Now(),
+			}
+			Expect(k8sClient.Status().Update(ctx, EvictionAutoScaler)).To(Succeed())
+			EvictionAutoScaler = reconcileAndGet()
+			Expect(EvictionAutoScaler.Status.AutoscalerSurge).NotTo(BeNil())
+			Expect(k8sClient.Delete(ctx, hpa)).To(Succeed())
+			EvictionAutoScaler = reconcileAndGet()
+			Expect(EvictionAutoScaler.Status.AutoscalerSurge).To(BeNil())
+			Expect(meta.IsStatusConditionFalse(EvictionAutoScaler.Status.Conditions, ConditionScalingUp)).To(BeTrue())
+		})
+
+		It("should record observedGeneration once a spec change is acted on", func() {
+			controllerReconciler := &EvictionAutoScalerReconciler{
+				Client: k8sClient,
+				Scheme: k8sClient.Scheme(),
+			}
+
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			EvictionAutoScaler := &v1.EvictionAutoScaler{}
+			err = k8sClient.Get(ctx, typeNamespacedName, EvictionAutoScaler)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(EvictionAutoScaler.Status.ObservedGeneration).To(Equal(EvictionAutoScaler.Generation))
+
+			By("changing the spec")
+			EvictionAutoScaler.Spec.MaxReplicas = int32Ptr(3)
+			Expect(k8sClient.Update(ctx, EvictionAutoScaler)).To(Succeed())
+			Expect(EvictionAutoScaler.Status.ObservedGeneration).To(BeNumerically("<", EvictionAutoScaler.Generation))
+
+			By("recording an eviction like the node controller does")
+			_, err = evictionutil.RecordEviction(ctx, k8sClient, typeNamespacedName, v1.EvictionRecord{PodName: "somepod", Node: "somenode", EvictionTime: metav1.Now(), Source: v1.EvictionSourceNodeCordon})
+			Expect(err).NotTo(HaveOccurred())
+			err = k8sClient.Get(ctx, typeNamespacedName, EvictionAutoScaler)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(EvictionAutoScaler.Status.ObservedGeneration).To(Equal(EvictionAutoScaler.Generation - 1))
+
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			err = k8sClient.Get(ctx, typeNamespacedName, EvictionAutoScaler)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(EvictionAutoScaler.Status.ObservedGeneration).To(Equal(EvictionAutoScaler.Generation))
+		})
+
+		//TODO do noting on old eviction
+		//TODO test a statefulset.
+
+	})
+
+	Context("when reconciling bad crds", func() {
+		BeforeEach(func() {
+
+			namespaceObj := &corev1.Namespace{
+				ObjectMeta: metav1.ObjectMeta{
+					GenerateName: "test",
+				},
+			}
+
+			// create the namespace using the controller-runtime client
+			Expect(k8sClient.Create(ctx, namespaceObj)).To(Succeed())
+			namespace = namespaceObj.Name
+			typeNamespacedName = types.NamespacedName{Name: resourceName, Namespace: namespace}
+		})
+
+		It("should deal with no pdb", func() {
+			By("by updating condition to degraded")
+			controllerReconciler := &EvictionAutoScalerReconciler{
+				Client: k8sClient,
+				Scheme: k8sClient.Scheme(),
+			}
+
+			EvictionAutoScaler := &v1.EvictionAutoScaler{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      resourceName,
+					Namespace: namespace,
+				},
+				Spec: v1.EvictionAutoScalerSpec{
+					TargetName: deploymentName,
+					TargetKind: "deployment",
+				},
+			}
+			Expect(k8sClient.Create(ctx, EvictionAutoScaler)).To(Succeed())
+
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			// Verify EvictionAutoScaler resource
+			err = k8sClient.Get(ctx, typeNamespacedName, EvictionAutoScaler)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(meta.FindStatusCondition(EvictionAutoScaler.Status.Conditions, "Degraded").Reason).To(Equal("NoPdb"))
+			Expect(meta.IsStatusConditionTrue(EvictionAutoScaler.Status.Conditions, "PDBMissing")).To(BeTrue())
+		})
+
+		It("should keep an EvictionAutoScaler with no pdb through the grace period", func() {
+			controllerReconciler := &EvictionAutoScalerReconciler{
+				Client:                k8sClient,
+				Scheme:                k8sClient.Scheme(),
+				PDBMissingGracePeriod: time.Hour,
+				PDBMissingAction:      PDBMissingDelete,
+			}
+
+			EvictionAutoScaler := &v1.EvictionAutoScaler{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      resourceName,
+					Namespace: namespace,
+				},
+				Spec: v1.EvictionAutoScalerSpec{
+					TargetName: deploymentName,
+					TargetKind: "deployment",
+				},
+			}
+			Expect(k8sClient.Create(ctx, EvictionAutoScaler)).To(Succeed())
+
+			result, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.RequeueAfter).To(BeNumerically(">", 59*time.Minute))
+
+			By("the pdb coming back")
+			pdb := &policyv1.PodDisruptionBudget{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      resourceName,
+					Namespace: namespace,
+				},
+			}
+			Expect(k8sClient.Create(ctx, pdb)).To(Succeed())
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			err = k8sClient.Get(ctx, typeNamespacedName, EvictionAutoScaler)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(meta.FindStatusCondition(EvictionAutoScaler.Status.Conditions, ConditionPDBMissing).Status).To(Equal(metav1.ConditionFalse))
+			Expect(meta.FindStatusCondition(EvictionAutoScaler.Status.Conditions, ConditionPDBMissing).Reason).To(Equal("Found"))
+		})
+
+		It("should delete or suspend an EvictionAutoScaler once its pdb is missing past the grace period", func() {
+			for _, action := range []string{PDBMissingSuspend, PDBMissingDelete} {
+				controllerReconciler := &EvictionAutoScalerReconciler{
+					Client:           k8sClient,
+					Scheme:           k8sClient.Scheme(),
+					PDBMissingAction: action,
+				}
+
+				EvictionAutoScaler := &v1.EvictionAutoScaler{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      resourceName,
+						Namespace: namespace,
+					},
+					Spec: v1.EvictionAutoScalerSpec{
+						TargetName: deploymentName,
+						TargetKind: "deployment",
+					},
+				}
+				err := k8sClient.Get(ctx, typeNamespacedName, EvictionAutoScaler)
+				if errors.IsNotFound(err) {
+					Expect(k8sClient.Create(ctx, EvictionAutoScaler)).To(Succeed())
+				}
+
+				_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{
+					NamespacedName: typeNamespacedName,
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				err = k8sClient.Get(ctx, typeNamespacedName, EvictionAutoScaler)
+				if action == PDBMissingDelete {
+					Expect(errors.IsNotFound(err)).To(BeTrue())
+					continue
+				}
+				Expect(err).NotTo(HaveOccurred())
+				Expect(meta.FindStatusCondition(EvictionAutoScaler.Status.Conditions, "Suspended").Reason).To(Equal("PDBMissing"))
+			}
+		})
+
+		It("should fall back to a deployment named after the pdb with no target", func() {
+			By("by looking for that deployment")
+			controllerReconciler := &EvictionAutoScalerReconciler{
+				Client: k8sClient,
+				Scheme: k8sClient.Scheme(),
+			}
+
+			EvictionAutoScaler := &v1.EvictionAutoScaler{
 				ObjectMeta: metav1.ObjectMeta{
 					Name:      resourceName,
 					Namespace: namespace,
@@ -508,9 +1242,12 @@ var _ = Describe("EvictionAutoScaler Controller", func() {
 			// Verify EvictionAutoScaler resource
 			err = k8sClient.Get(ctx, typeNamespacedName, EvictionAutoScaler)
 			Expect(err).NotTo(HaveOccurred())
-			Expect(EvictionAutoScaler.Status.Conditions).To(HaveLen(1))
+			Expect(EvictionAutoScaler.Status.Conditions).To(HaveLen(2))
 			Expect(EvictionAutoScaler.Status.Conditions[0].Type).To(Equal("Degraded"))
-			Expect(EvictionAutoScaler.Status.Conditions[0].Reason).To(Equal("EmptyTarget"))
+			// there is no deployment named resourceName
+			Expect(EvictionAutoScaler.Status.Conditions[0].Reason).To(Equal("MissingTarget"))
+			Expect(EvictionAutoScaler.Status.Conditions[0].Message).To(ContainSubstring(resourceName))
+			Expect(meta.IsStatusConditionTrue(EvictionAutoScaler.Status.Conditions, ConditionTargetMissing)).To(BeTrue())
 		})
 
 		It("should deal with bad target kind", func() {
@@ -552,6 +1289,49 @@ var _ = Describe("EvictionAutoScaler Controller", func() {
 			Expect(EvictionAutoScaler.Status.Conditions[0].Reason).To(Equal("InvalidTarget"))
 		})
 
+		It("should deal with a targetRef that can't be resolved", func() {
+			By("by updating condition to degraded")
+			scales, err := NewScalesGetter(cfg, k8sClient.RESTMapper())
+			Expect(err).NotTo(HaveOccurred())
+			controllerReconciler := &EvictionAutoScalerReconciler{
+				Client: k8sClient,
+				Scheme: k8sClient.Scheme(),
+				Scales: scales,
+			}
+
+			EvictionAutoScaler := &v1.EvictionAutoScaler{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      resourceName,
+					Namespace: namespace,
+				},
+				Spec: v1.EvictionAutoScalerSpec{
+					TargetRef: &v1.TargetReference{APIVersion: "example.com/v1", Kind: "Rollout", Name: "somethingmissing"},
+				},
+			}
+			Expect(k8sClient.Create(ctx, EvictionAutoScaler)).To(Succeed())
+
+			pdb := &policyv1.PodDisruptionBudget{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      resourceName,
+					Namespace: namespace,
+				},
+			}
+			Expect(k8sClient.Create(ctx, pdb)).To(Succeed())
+
+			result, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.RequeueAfter).To(Equal(DefaultCooldown))
+
+			err = k8sClient.Get(ctx, typeNamespacedName, EvictionAutoScaler)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(EvictionAutoScaler.Status.Conditions).To(HaveLen(2))
+			Expect(EvictionAutoScaler.Status.Conditions[0].Type).To(Equal("Degraded"))
+			Expect(EvictionAutoScaler.Status.Conditions[0].Reason).To(Equal("TargetNotFound"))
+			Expect(meta.IsStatusConditionTrue(EvictionAutoScaler.Status.Conditions, ConditionTargetMissing)).To(BeTrue())
+		})
+
 		It("should deal with missing target", func() {
 			By("by updating condition to degraded")
 			controllerReconciler := &EvictionAutoScalerReconciler{
@@ -588,13 +1368,92 @@ var _ = Describe("EvictionAutoScaler Controller", func() {
 			// Verify EvictionAutoScaler resource
 			err = k8sClient.Get(ctx, typeNamespacedName, EvictionAutoScaler)
 			Expect(err).NotTo(HaveOccurred())
-			Expect(EvictionAutoScaler.Status.Conditions).To(HaveLen(1))
+			Expect(EvictionAutoScaler.Status.Conditions).To(HaveLen(2))
 			Expect(EvictionAutoScaler.Status.Conditions[0].Type).To(Equal("Degraded"))
 			Expect(EvictionAutoScaler.Status.Conditions[0].Reason).To(Equal("MissingTarget"))
+			Expect(meta.IsStatusConditionTrue(EvictionAutoScaler.Status.Conditions, ConditionTargetMissing)).To(BeTrue())
+		})
+
+		It("should flip TargetMissing back once the target shows up", func() {
+			controllerReconciler := &EvictionAutoScalerReconciler{
+				Client: k8sClient,
+				Scheme: k8sClient.Scheme(),
+			}
+
+			EvictionAutoScaler := &v1.EvictionAutoScaler{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      resourceName,
+					Namespace: namespace,
+				},
+				Spec: v1.EvictionAutoScalerSpec{
+					TargetName: deploymentName,
+					TargetKind: "deployment",
+				},
+			}
+			Expect(k8sClient.Create(ctx, EvictionAutoScaler)).To(Succeed())
+			pdb := &policyv1.PodDisruptionBudget{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      resourceName,
+					Namespace: namespace,
+				},
+			}
+			Expect(k8sClient.Create(ctx, pdb)).To(Succeed())
+
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			err = k8sClient.Get(ctx, typeNamespacedName, EvictionAutoScaler)
+			Expect(err).NotTo(HaveOccurred())
+			targetMissing := meta.FindStatusCondition(EvictionAutoScaler.Status.Conditions, ConditionTargetMissing)
+			Expect(targetMissing.Status).To(Equal(metav1.ConditionTrue))
+			Expect(targetMissing.Reason).To(Equal("MissingTarget"))
+
+			By("creating the deployment")
+			deployment := &appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      deploymentName,
+					Namespace: namespace,
+				},
+				Spec: appsv1.DeploymentSpec{
+					Replicas: int32Ptr(1),
+					Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "example"}},
+					Template: corev1.PodTemplateSpec{
+						ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"app": "example"}},
+						Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "nginx", Image: "nginx:latest"}}},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, deployment)).To(Succeed())
+
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			err = k8sClient.Get(ctx, typeNamespacedName, EvictionAutoScaler)
+			Expect(err).NotTo(HaveOccurred())
+			targetMissing = meta.FindStatusCondition(EvictionAutoScaler.Status.Conditions, ConditionTargetMissing)
+			Expect(targetMissing.Status).To(Equal(metav1.ConditionFalse))
+			Expect(targetMissing.Reason).To(Equal("Found"))
+			Expect(meta.IsStatusConditionTrue(EvictionAutoScaler.Status.Conditions, "Ready")).To(BeTrue())
 		})
 	})
 })
 
+// counterValue is the current value of counter's series for labels
+func counterValue(counter *prometheus.CounterVec, labels ...string) float64 {
+	m := &dto.Metric{}
+	Expect(counter.WithLabelValues(labels...).Write(m)).To(Succeed())
+	return m.GetCounter().GetValue()
+}
+
+// gaugeValue is the current value of gauge's series for labels
+func gaugeValue(gauge *prometheus.GaugeVec, labels ...string) float64 {
+	m := &dto.Metric{}
+	Expect(gauge.WithLabelValues(labels...).Write(m)).To(Succeed())
+	return m.GetGauge().GetValue()
+}
+
 func int32Ptr(i int32) *int32 {
 	return &i
 }
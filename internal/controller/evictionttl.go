@@ -0,0 +1,70 @@
+package controllers
+
+import (
+	"context"
+	"time"
+
+	myappsv1 "github.com/azure/eviction-autoscaler/api/v1"
+	"github.com/azure/eviction-autoscaler/internal/events"
+	"github.com/azure/eviction-autoscaler/internal/podutil"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// DefaultEvictionTTL is the compiled in eviction ttl of EvictionAutoScalers without spec.evictionTTLSeconds when
+// --default-eviction-ttl isn't set.
+const DefaultEvictionTTL = time.Hour
+
+// evictionTTLFor returns the EvictionAutoScaler's spec.evictionTTLSeconds, falling back to the configured eviction ttl
+// when unset or zero. Zero or less never expires evictions.
+func (c Config) evictionTTLFor(EvictionAutoScaler *myappsv1.EvictionAutoScaler) time.Duration {
+	if EvictionAutoScaler.Spec.EvictionTTLSeconds == nil || *EvictionAutoScaler.Spec.EvictionTTLSeconds <= 0 {
+		if c.EvictionTTL == 0 {
+			return DefaultEvictionTTL
+		}
+		return c.EvictionTTL
+	}
+	return time.Duration(*EvictionAutoScaler.Spec.EvictionTTLSeconds) * time.Second
+}
+
+// evictionExpired is true while the unhandled last eviction is stale and shouldn't surge or hold a surge.
+func evictionExpired(status *myappsv1.EvictionAutoScalerStatus) bool {
+	return status.LastEviction != status.HandledEviction && status.ExpiredEviction == status.LastEviction
+}
+
+// expireStaleEviction marks the unhandled last eviction expired once it is older than the ttl and its pod is still running,
+// since the drain that anticipated it was cancelled or the pod was fixed in place. It stays in status for history.
+// Returns true if status changed.
+func (r *EvictionAutoScalerReconciler) expireStaleEviction(ctx context.Context, EvictionAutoScaler *myappsv1.EvictionAutoScaler) (bool, error) {
+	status := &EvictionAutoScaler.Status
+	ttl := r.Config.evictionTTLFor(EvictionAutoScaler)
+	if ttl <= 0 || status.LastEviction == status.HandledEviction || evictionExpired(status) ||
+		time.Since(status.LastEviction.EvictionTime.Time) < ttl {
+		return false, nil
+	}
+	pod := &corev1.Pod{}
+	if err := r.Get(ctx, types.NamespacedName{Namespace: EvictionAutoScaler.Namespace, Name: status.LastEviction.PodName}, pod); err != nil {
+		if errors.IsNotFound(err) {
+			return false, nil // it was evicted
+		}
+		return false, err
+	}
+	if podutil.IsTerminating(pod) {
+		return false, nil
+	}
+
+	log.FromContext(ctx).Info("Last eviction is stale, its pod is still running", "podName", pod.Name,
+		"evictionTime", status.LastEviction.EvictionTime, "ttl", ttl)
+	status.ExpiredEviction = status.LastEviction
+	for i := range status.RecentEvictions {
+		if status.RecentEvictions[i].Matches(status.LastEviction) {
+			status.RecentEvictions[i].Expired = true
+		}
+	}
+	events.Eventf(r.Recorder, EvictionAutoScaler, corev1.EventTypeWarning, events.ReasonEvictionStale,
+		"Eviction of pod %s anticipated at %s never happened within %s, no longer holding a surge for it",
+		pod.Name, status.LastEviction.EvictionTime.UTC().Format(time.RFC3339), ttl)
+	return true, nil
+}
@@ -0,0 +1,113 @@
+package controllers
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	v1 "github.com/azure/eviction-autoscaler/api/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func TestStaleEvictionStopsHoldingSurge(t *testing.T) {
+	ctx := context.Background()
+	testScheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(testScheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := v1.AddToScheme(testScheme); err != nil {
+		t.Fatal(err)
+	}
+	key := types.NamespacedName{Namespace: "default", Name: "web"}
+	evicted := metav1.NewTime(time.Now().Add(-2 * time.Hour).Truncate(time.Second))
+	lastEviction := v1.Eviction{PodName: "web-1", EvictionTime: evicted}
+
+	tests := []struct {
+		name     string
+		podGone  bool
+		ttl      *int32
+		replicas int32 // deployment replicas after reconcile
+		expired  bool
+	}{
+		{name: "pod still running", replicas: 3, expired: true},
+		{name: "pod evicted", podGone: true, replicas: 4},
+		{name: "within ttl", ttl: ptr.To(int32(3 * 60 * 60)), replicas: 4},
+	}
+	for _, test := range tests {
+		objects := []client.Object{
+			&appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default", Generation: 2},
+				Spec:       appsv1.DeploymentSpec{Replicas: ptr.To(int32(4))},
+			},
+			&policyv1.PodDisruptionBudget{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"}},
+			&v1.EvictionAutoScaler{
+				ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+				Spec:       v1.EvictionAutoScalerSpec{TargetName: "web", TargetKind: deploymentKind, EvictionTTLSeconds: test.ttl},
+				Status: v1.EvictionAutoScalerStatus{
+					MinReplicas:      3,
+					TargetGeneration: 2,
+					LastEviction:     lastEviction,
+					DrainingNodes:    []string{"node-1"},
+					RecentEvictions: []v1.EvictionRecord{
+						{PodName: "web-0", Node: "node-1", EvictionTime: metav1.NewTime(evicted.Add(-time.Minute)), Source: v1.EvictionSourceNodeCordon},
+						{PodName: "web-1", Node: "node-1", EvictionTime: evicted, Source: v1.EvictionSourceNodeCordon},
+					},
+				},
+			},
+		}
+		if !test.podGone {
+			objects = append(objects, &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "web-1", Namespace: "default"}})
+		}
+		fakeClient := fake.NewClientBuilder().WithScheme(testScheme).
+			WithStatusSubresource(&v1.EvictionAutoScaler{}).WithObjects(objects...).Build()
+		recorder := record.NewFakeRecorder(10)
+		r := &EvictionAutoScalerReconciler{Client: fakeClient, Scheme: testScheme, Recorder: recorder}
+		if _, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: key}); err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+
+		deployment := &appsv1.Deployment{}
+		if err := fakeClient.Get(ctx, key, deployment); err != nil {
+			t.Fatal(err)
+		}
+		if *deployment.Spec.Replicas != test.replicas {
+			t.Errorf("%s: got %d replicas, want %d", test.name, *deployment.Spec.Replicas, test.replicas)
+		}
+		EvictionAutoScaler := &v1.EvictionAutoScaler{}
+		if err := fakeClient.Get(ctx, key, EvictionAutoScaler); err != nil {
+			t.Fatal(err)
+		}
+		status := EvictionAutoScaler.Status
+		if got := status.ExpiredEviction == lastEviction; got != test.expired {
+			t.Errorf("%s: got expiredEviction %+v, want expired %v", test.name, status.ExpiredEviction, test.expired)
+		}
+		// kept for history, only the stale one is marked
+		if len(status.RecentEvictions) != 2 || status.RecentEvictions[0].Expired || status.RecentEvictions[1].Expired != test.expired {
+			t.Errorf("%s: got recent evictions %+v", test.name, status.RecentEvictions)
+		}
+		if status.LastEviction != lastEviction {
+			t.Errorf("%s: lastEviction changed to %+v", test.name, status.LastEviction)
+		}
+		staleEvent := false
+		for len(recorder.Events) > 0 {
+			if strings.Contains(<-recorder.Events, "EvictionStale") {
+				staleEvent = true
+			}
+		}
+		if staleEvent != test.expired {
+			t.Errorf("%s: got EvictionStale event %v, want %v", test.name, staleEvent, test.expired)
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package controllers
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	myappsv1 "github.com/azure/eviction-autoscaler/api/v1"
+	"github.com/azure/eviction-autoscaler/internal/namespacefilter"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/utils/ptr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+)
+
+// GlobalConfig is the EvictionAutoScalerConfig the controllers apply over their flags, kept up to date by
+// EvictionAutoScalerConfigReconciler. A nil GlobalConfig has nothing set.
+type GlobalConfig struct {
+	mu         sync.RWMutex
+	spec       myappsv1.EvictionAutoScalerConfigSpec
+	generation int64
+}
+
+// Spec returns the spec of the EvictionAutoScalerConfig, empty without one. It is shared and must not be modified.
+func (g *GlobalConfig) Spec() myappsv1.EvictionAutoScalerConfigSpec {
+	if g == nil {
+		return myappsv1.EvictionAutoScalerConfigSpec{}
+	}
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.spec
+}
+
+// Generation returns the metadata.generation of the EvictionAutoScalerConfig applied, zero without one.
+func (g *GlobalConfig) Generation() int64 {
+	if g == nil {
+		return 0
+	}
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.generation
+}
+
+func (g *GlobalConfig) set(spec myappsv1.EvictionAutoScalerConfigSpec, generation int64) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.spec, g.generation = spec, generation
+}
+
+// EvictionAutoScalerConfigReconciler applies the EvictionAutoScalerConfig named myappsv1.EvictionAutoScalerConfigName
+// to Global and Namespaces as it changes, and goes back to the flags once it is deleted. It runs on every replica
+// since sharded node controllers and the webhooks read it too.
+type EvictionAutoScalerConfigReconciler struct {
+	client.Client
+	Global *GlobalConfig
+	// Namespaces has its flag lists overridden by the config's. Nil leaves namespaces to the flags.
+	Namespaces *namespacefilter.Filter
+}
+
+// +kubebuilder:rbac:groups=eviction-autoscaler.azure.com,resources=evictionautoscalerconfigs,verbs=get;list;watch
+
+func (r *EvictionAutoScalerConfigReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	config := &myappsv1.EvictionAutoScalerConfig{}
+	if err := r.Get(ctx, req.NamespacedName, config); err != nil {
+		if !errors.IsNotFound(err) {
+			return ctrl.Result{}, err
+		}
+		config = &myappsv1.EvictionAutoScalerConfig{}
+	}
+	r.Global.set(*config.Spec.DeepCopy(), config.Generation)
+	r.Namespaces.Override(config.Spec.NamespaceAllowlist, config.Spec.NamespaceDenylist)
+	log.FromContext(ctx).Info("Applied EvictionAutoScalerConfig", "name", req.Name, "generation", config.Generation)
+	return ctrl.Result{}, nil
+}
+
+func (r *EvictionAutoScalerConfigReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&myappsv1.EvictionAutoScalerConfig{}, builder.WithPredicates(predicate.NewPredicateFuncs(func(obj client.Object) bool {
+			return obj.GetName() == myappsv1.EvictionAutoScalerConfigName
+		}))).
+		WithOptions(controller.Options{NeedLeaderElection: ptr.To(false)}).
+		Complete(r)
+}
+
+// EffectiveConfig is what the node controller runs with, its flags with the EvictionAutoScalerConfig over them.
+type EffectiveConfig struct {
+	// Generation is the metadata.generation of the EvictionAutoScalerConfig applied, zero without one.
+	Generation                int64    `json:"generation,omitempty"`
+	CooldownSeconds           int32    `json:"cooldownSeconds"`
+	Surge                     string   `json:"surge"`
+	SurgePolicy               string   `json:"surgePolicy"`
+	NamespaceAllowlist        []string `json:"namespaceAllowlist,omitempty"`
+	NamespaceDenylist         []string `json:"namespaceDenylist,omitempty"`
+	MaxConcurrentNodeDrains   int      `json:"maxConcurrentNodeDrains"`
+	NodePodConcurrency        int      `json:"nodePodConcurrency"`
+	DisablePodConditionWrites bool     `json:"disablePodConditionWrites"`
+	NodeDrainProgress         bool     `json:"nodeDrainProgress"`
+}
+
+// effectiveConfig returns the config r runs with right now, for /debug/state.
+func (r *NodeReconciler) effectiveConfig() EffectiveConfig {
+	unset := &myappsv1.EvictionAutoScaler{}
+	surge := r.Config.surgeFor(unset)
+	effective := EffectiveConfig{
+		Generation:                r.Config.Global.Generation(),
+		CooldownSeconds:           int32(r.Config.cooldown() / time.Second),
+		Surge:                     surge.String(),
+		SurgePolicy:               r.Config.surgePolicyFor(unset),
+		MaxConcurrentNodeDrains:   r.maxConcurrentNodeDrains(),
+		NodePodConcurrency:        r.podConcurrency(),
+		DisablePodConditionWrites: r.PodConditionWrites.Off(),
+		NodeDrainProgress:         r.drainProgressEnabled(),
+	}
+	effective.NamespaceAllowlist, effective.NamespaceDenylist = r.Namespaces.Lists()
+	return effective
+}
+
+// maxConcurrentNodeDrains returns MaxConcurrentNodeDrains or the EvictionAutoScalerConfig's.
+func (r *NodeReconciler) maxConcurrentNodeDrains() int {
+	if limit := r.Config.Global.Spec().MaxConcurrentNodeDrains; limit != nil {
+		return int(*limit)
+	}
+	return r.MaxConcurrentNodeDrains
+}
+
+// podConcurrency returns PodConcurrency or the EvictionAutoScalerConfig's, at least one.
+func (r *NodeReconciler) podConcurrency() int {
+	if concurrency := r.Config.Global.Spec().NodePodConcurrency; concurrency != nil {
+		return max(int(*concurrency), 1)
+	}
+	return max(r.PodConcurrency, 1)
+}
+
+// drainProgressEnabled returns DrainProgress or the EvictionAutoScalerConfig's nodeDrainProgress.
+func (r *NodeReconciler) drainProgressEnabled() bool {
+	if enabled := r.Config.Global.Spec().NodeDrainProgress; enabled != nil {
+		return *enabled
+	}
+	return r.DrainProgress
+}
@@ -0,0 +1,170 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/workqueue"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+// DefaultMaintenanceNodeField and DefaultMaintenanceCompletedPhases fit medik8s' NodeMaintenance.
+const (
+	DefaultMaintenanceNodeField       = "spec.nodeName"
+	DefaultMaintenanceCompletedPhases = "Succeeded"
+)
+
+// maintenanceRetry is how often we look for the maintenance CRD again while it isn't installed.
+const maintenanceRetry = time.Minute
+
+// NodeMaintenance are the maintenance CRs, like medik8s' NodeMaintenance, a maintenance operator creates for a node
+// before cordoning it. A node one is open for is drained for from then on, so surge replicas have time to become
+// ready before the cordon lands. Once every CR for the node is deleted or completed it is let go like an uncordoned
+// one, unless it is cordoned or drain tainted by then.
+type NodeMaintenance struct {
+	// GVK is the kind of the CRs. They are watched as unstructured once the kind is served, so it needn't be
+	// installed when we start.
+	GVK schema.GroupVersionKind
+	// NodeField is the dotted path of the node name in a CR.
+	NodeField string
+	// CompletedPhases are the status.phase values of a CR whose maintenance is over.
+	CompletedPhases []string
+
+	mu sync.Mutex
+	// nodes is the node of every open CR by namespace/name.
+	nodes map[string]string
+}
+
+// ParseMaintenanceGVK parses a --maintenance-gvk of the form group/version/Kind, or version/Kind for the core group.
+func ParseMaintenanceGVK(value string) (schema.GroupVersionKind, error) {
+	i := strings.LastIndex(value, "/")
+	if i <= 0 || i == len(value)-1 {
+		return schema.GroupVersionKind{}, fmt.Errorf("%q is not group/version/Kind", value)
+	}
+	gv, err := schema.ParseGroupVersion(value[:i])
+	if err != nil {
+		return schema.GroupVersionKind{}, err
+	}
+	return gv.WithKind(value[i+1:]), nil
+}
+
+// active reports if a CR is open for node. A nil NodeMaintenance never has one.
+func (m *NodeMaintenance) active(node string) bool {
+	if m == nil {
+		return false
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, name := range m.nodes {
+		if name == node {
+			return true
+		}
+	}
+	return false
+}
+
+// observe records cr, gone if deleted, and returns the nodes whose maintenance that may have started or ended.
+func (m *NodeMaintenance) observe(cr *unstructured.Unstructured, deleted bool) []string {
+	key := types.NamespacedName{Namespace: cr.GetNamespace(), Name: cr.GetName()}.String()
+	node, _, _ := unstructured.NestedString(cr.Object, strings.Split(m.NodeField, ".")...)
+	phase, _, _ := unstructured.NestedString(cr.Object, "status", "phase")
+	open := !deleted && cr.GetDeletionTimestamp() == nil && node != "" && !slices.Contains(m.CompletedPhases, phase)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.nodes == nil {
+		m.nodes = map[string]string{}
+	}
+	previous := m.nodes[key]
+	delete(m.nodes, key)
+	if open {
+		m.nodes[key] = node
+	}
+	var nodes []string
+	for _, name := range []string{previous, node} {
+		if name != "" && !slices.Contains(nodes, name) {
+			nodes = append(nodes, name)
+		}
+	}
+	return nodes
+}
+
+// maintenanceHandler keeps NodeMaintenance up to date with the CRs and requeues our share of the nodes they name.
+func (r *NodeReconciler) maintenanceHandler() handler.TypedEventHandler[*unstructured.Unstructured, reconcile.Request] {
+	enqueue := func(cr *unstructured.Unstructured, deleted bool, queue workqueue.TypedRateLimitingInterface[reconcile.Request]) {
+		for _, node := range r.Maintenance.observe(cr, deleted) {
+			if r.Shard.Owns(node) {
+				queue.Add(reconcile.Request{NamespacedName: types.NamespacedName{Name: node}})
+			}
+		}
+	}
+	return handler.TypedFuncs[*unstructured.Unstructured, reconcile.Request]{
+		CreateFunc: func(_ context.Context, e event.TypedCreateEvent[*unstructured.Unstructured], queue workqueue.TypedRateLimitingInterface[reconcile.Request]) {
+			enqueue(e.Object, false, queue)
+		},
+		UpdateFunc: func(_ context.Context, e event.TypedUpdateEvent[*unstructured.Unstructured], queue workqueue.TypedRateLimitingInterface[reconcile.Request]) {
+			enqueue(e.ObjectNew, false, queue)
+		},
+		DeleteFunc: func(_ context.Context, e event.TypedDeleteEvent[*unstructured.Unstructured], queue workqueue.TypedRateLimitingInterface[reconcile.Request]) {
+			enqueue(e.Object, true, queue)
+		},
+	}
+}
+
+// maintenanceWatch adds the watch on NodeMaintenance CRs to the node controller once their kind is served, looking
+// again every maintenanceRetry till it is, so the CRD can be installed after us. It runs where the node controller does.
+type maintenanceWatch struct {
+	reconciler         *NodeReconciler
+	mgr                ctrl.Manager
+	controller         controller.Controller
+	needLeaderElection bool
+}
+
+func (w maintenanceWatch) Start(ctx context.Context) error {
+	logger := log.FromContext(ctx)
+	gvk := w.reconciler.Maintenance.GVK
+	for warned := false; ; warned = true {
+		_, err := w.mgr.GetRESTMapper().RESTMapping(gvk.GroupKind(), gvk.Version)
+		if err == nil {
+			break
+		}
+		if !meta.IsNoMatchError(err) {
+			logger.Error(err, "unable to look up maintenance kind", "gvk", gvk.String())
+		} else if !warned {
+			logger.Info("Maintenance kind is not installed, watching for it once it is", "gvk", gvk.String())
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(maintenanceRetry):
+		}
+	}
+	cr := &unstructured.Unstructured{}
+	cr.SetGroupVersionKind(gvk)
+	if err := w.controller.Watch(source.Kind(w.mgr.GetCache(), cr, w.reconciler.maintenanceHandler())); err != nil {
+		// drains are still assisted once the node is cordoned, not worth taking the manager down for
+		logger.Error(err, "unable to watch maintenance CRs", "gvk", gvk.String())
+		return nil
+	}
+	logger.Info("Watching maintenance CRs", "gvk", gvk.String())
+	<-ctx.Done()
+	return nil
+}
+
+func (w maintenanceWatch) NeedLeaderElection() bool {
+	return w.needLeaderElection
+}
@@ -0,0 +1,144 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	v1 "github.com/azure/eviction-autoscaler/api/v1"
+	"github.com/azure/eviction-autoscaler/internal/metrics"
+	"github.com/azure/eviction-autoscaler/internal/selectorcache"
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func nodeMaintenanceCR(name, node, phase string) *unstructured.Unstructured {
+	cr := &unstructured.Unstructured{Object: map[string]interface{}{
+		"spec":   map[string]interface{}{"nodeName": node},
+		"status": map[string]interface{}{"phase": phase},
+	}}
+	cr.SetGroupVersionKind(schema.GroupVersionKind{Group: "nodemaintenance.medik8s.io", Version: "v1beta1", Kind: "NodeMaintenance"})
+	cr.SetName(name)
+	return cr
+}
+
+func TestParseMaintenanceGVK(t *testing.T) {
+	for value, want := range map[string]schema.GroupVersionKind{
+		"nodemaintenance.medik8s.io/v1beta1/NodeMaintenance": {Group: "nodemaintenance.medik8s.io", Version: "v1beta1", Kind: "NodeMaintenance"},
+		"v1/ConfigMap": {Version: "v1", Kind: "ConfigMap"},
+	} {
+		if got, err := ParseMaintenanceGVK(value); err != nil || got != want {
+			t.Errorf("%s: got %v, %v, want %v", value, got, err, want)
+		}
+	}
+	for _, value := range []string{"", "NodeMaintenance", "nodemaintenance.medik8s.io/v1beta1/", "a/b/c/Kind"} {
+		if _, err := ParseMaintenanceGVK(value); err == nil {
+			t.Errorf("%q: got no error", value)
+		}
+	}
+}
+
+// TestMaintenanceDrain checks a node a maintenance CR is open for is drained for before it is cordoned, and let go
+// like an uncordoned one once the maintenance completes.
+func TestMaintenanceDrain(t *testing.T) {
+	ctx := context.Background()
+	testScheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(testScheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := v1.AddToScheme(testScheme); err != nil {
+		t.Fatal(err)
+	}
+	key := types.NamespacedName{Name: "web", Namespace: "maintenance"}
+	fakeClient := fake.NewClientBuilder().
+		WithScheme(testScheme).
+		WithIndex(&corev1.Pod{}, NodeNameIndex, podNodeName).
+		WithIndex(&v1.EvictionAutoScaler{}, PDBIndex, evictionAutoScalerPDB).
+		WithStatusSubresource(&corev1.Pod{}, &v1.EvictionAutoScaler{}).
+		WithInterceptorFuncs(interceptor.Funcs{SubResourcePatch: fakeApplyPodStatus()}).
+		WithObjects(
+			&v1.EvictionAutoScaler{
+				ObjectMeta: metav1.ObjectMeta{Name: key.Name, Namespace: key.Namespace},
+				Spec:       v1.EvictionAutoScalerSpec{TargetName: "web", TargetKind: deploymentKind},
+			},
+			&policyv1.PodDisruptionBudget{
+				ObjectMeta: metav1.ObjectMeta{Name: key.Name, Namespace: key.Namespace},
+				Spec: policyv1.PodDisruptionBudgetSpec{
+					Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}},
+				},
+			},
+			&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "upgrading"}},
+			&corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Name: "web-1", Namespace: key.Namespace, Labels: map[string]string{"app": "web"}},
+				Spec:       corev1.PodSpec{NodeName: "upgrading"},
+			},
+		).
+		Build()
+	maintenance := &NodeMaintenance{NodeField: DefaultMaintenanceNodeField, CompletedPhases: []string{"Succeeded"}}
+	nodeReconciler := &NodeReconciler{Client: fakeClient, Scheme: testScheme, Recorder: record.NewFakeRecorder(10),
+		Selectors: selectorcache.New(), Maintenance: maintenance}
+	queue := workqueue.NewTypedRateLimitingQueue(workqueue.DefaultTypedControllerRateLimiter[reconcile.Request]())
+	defer queue.ShutDown()
+	handler := nodeReconciler.maintenanceHandler()
+	request := reconcile.Request{NamespacedName: types.NamespacedName{Name: "upgrading"}}
+	drainingNodes := func() []string {
+		t.Helper()
+		if _, err := nodeReconciler.Reconcile(ctx, request); err != nil {
+			t.Fatal(err)
+		}
+		EvictionAutoScaler := &v1.EvictionAutoScaler{}
+		if err := fakeClient.Get(ctx, key, EvictionAutoScaler); err != nil {
+			t.Fatal(err)
+		}
+		return EvictionAutoScaler.Status.DrainingNodes
+	}
+
+	handler.Create(ctx, event.TypedCreateEvent[*unstructured.Unstructured]{Object: nodeMaintenanceCR("upgrade", "upgrading", "Running")}, queue)
+	if queue.Len() != 1 {
+		t.Fatalf("got %d nodes queued, want the maintained one", queue.Len())
+	}
+	if got := nodeReconciler.drainTrigger(&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "upgrading"}}); got != metrics.DrainTriggerMaintenance {
+		t.Errorf("got trigger %q, want %q", got, metrics.DrainTriggerMaintenance)
+	}
+	if got := drainingNodes(); len(got) != 1 || got[0] != "upgrading" {
+		t.Errorf("got draining nodes %v before the cordon, want the maintained node", got)
+	}
+
+	old := nodeMaintenanceCR("upgrade", "upgrading", "Running")
+	handler.Update(ctx, event.TypedUpdateEvent[*unstructured.Unstructured]{ObjectOld: old, ObjectNew: nodeMaintenanceCR("upgrade", "upgrading", "Succeeded")}, queue)
+	if got := nodeReconciler.drainTrigger(&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "upgrading"}}); got != "" {
+		t.Errorf("got trigger %q once the maintenance succeeded, want none", got)
+	}
+	if got := drainingNodes(); len(got) != 0 {
+		t.Errorf("got draining nodes %v once the maintenance succeeded, want it released", got)
+	}
+}
+
+func TestMaintenanceObserve(t *testing.T) {
+	maintenance := &NodeMaintenance{NodeField: DefaultMaintenanceNodeField, CompletedPhases: []string{"Succeeded"}}
+	maintenance.observe(nodeMaintenanceCR("a", "node-1", ""), false)
+	maintenance.observe(nodeMaintenanceCR("b", "node-1", "Running"), false)
+	// moving a CR to another node ends the maintenance of the old one for it
+	if got := maintenance.observe(nodeMaintenanceCR("a", "node-2", "Running"), false); len(got) != 2 {
+		t.Errorf("got nodes %v, want the old and new node requeued", got)
+	}
+	maintenance.observe(nodeMaintenanceCR("b", "node-1", "Running"), true)
+	if maintenance.active("node-1") || !maintenance.active("node-2") {
+		t.Errorf("got node-1 %v and node-2 %v active, want only node-2", maintenance.active("node-1"), maintenance.active("node-2"))
+	}
+	var none *NodeMaintenance
+	if none.active("node-2") {
+		t.Error("got a node under maintenance without a maintenance watch")
+	}
+}
@@ -2,24 +2,45 @@ package controllers
 
 import (
 	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
 	"time"
 
 	pdbautoscaler "github.com/azure/eviction-autoscaler/api/v1"
+	"github.com/azure/eviction-autoscaler/internal/events"
+	"github.com/azure/eviction-autoscaler/internal/evictionutil"
 	"github.com/azure/eviction-autoscaler/internal/metrics"
+	"github.com/azure/eviction-autoscaler/internal/namespacefilter"
 	"github.com/azure/eviction-autoscaler/internal/podutil"
+	"github.com/azure/eviction-autoscaler/internal/selectorcache"
+	"github.com/azure/eviction-autoscaler/internal/tracing"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/sync/errgroup"
 	corev1 "k8s.io/api/core/v1"
 	policyv1 "k8s.io/api/policy/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	corev1ac "k8s.io/client-go/applyconfigurations/core/v1"
 	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 )
 
 // EvictionAutoScalerReconciler reconciles a EvictionAutoScaler object
@@ -27,158 +48,1189 @@ type NodeReconciler struct {
 	client.Client
 	Scheme   *runtime.Scheme
 	Recorder record.EventRecorder
+	// DrainTaints are taint keys treated the same as a cordon. Nil means no taints are checked.
+	DrainTaints []string
+	// DrainAnnotations are node annotations treated the same as a cordon, set before anything cordons the node.
+	DrainAnnotations []DrainAnnotation
+	// DrainBlockingAnnotations are key=value pod annotations a drain won't evict the pod with, so we don't surge for it.
+	DrainBlockingAnnotations []string
+	// NodeSelector scopes which nodes we act on. Nil or empty means every node.
+	NodeSelector labels.Selector
+	// Namespaces excludes pods in namespaces we must not touch. Nil allows all.
+	Namespaces *namespacefilter.Filter
+	// Selectors caches compiled pdb selectors across reconciles. Nil compiles them every time.
+	Selectors *selectorcache.Cache
+	// DryRun logs and counts pod condition and eviction writes instead of making them.
+	DryRun bool
+	// PodConditionWrites can turn off setting and clearing the DisruptionTarget pod condition, for clusters that don't
+	// grant patch on pods/status. Evictions are still recorded and surged for. A Forbidden write turns it off by itself.
+	// Nil always writes.
+	PodConditionWrites *PodConditionWrites
+	// MaxConcurrentReconciles is how many nodes are reconciled at once. Zero reconciles one at a time.
+	MaxConcurrentReconciles int
+	// RetryBaseDelay and RetryMaxDelay bound the per node backoff of failed reconciles, doubling from the first to the
+	// second. Zero uses DefaultNodeRetryBaseDelay and DefaultNodeRetryMaxDelay.
+	RetryBaseDelay time.Duration
+	RetryMaxDelay  time.Duration
+	// PodConcurrency is how many pods of one node have their condition and eviction written at once, for nodes
+	// with hundreds of pods. Zero writes one at a time.
+	PodConcurrency int
+	// NodeFailureTriggers also treats nodes with the out-of-service taint or NotReady for NotReadyWindow as draining,
+	// since a failed node never gets cordoned.
+	NodeFailureTriggers bool
+	// NotReadyWindow is how long a node must stay NotReady before NodeFailureTriggers acts on it, so a flapping node doesn't.
+	NotReadyWindow time.Duration
+	// MaxDrainResync caps how far the drainResync of a node whose pods stay put is doubled. At or below drainResync it never backs off.
+	MaxDrainResync time.Duration
+	// StaleCordonThreshold is how long a cordoned node's drain may go without a pod leaving before we stand down
+	// from it till NodeRearmAnnotationKey is set. Zero never stands down.
+	StaleCordonThreshold time.Duration
+	// StuckTerminatingThreshold is how long past its grace period a pod on a draining node may be terminating before
+	// it is reported stuck. Zero never reports them.
+	StuckTerminatingThreshold time.Duration
+	// MaxConcurrentNodeDrains caps how many node drains we assist at once, so an upgrade cordoning fifty nodes doesn't surge
+	// fifty workloads while capacity is scarcest. Other cordoned nodes wait in order. Zero assists every node. Per shard.
+	MaxConcurrentNodeDrains int
+	Config                  Config
+	// DrainProgress keeps a NodeDrainProgress named after every node we assist the drain of, from its first pods
+	// for an EvictionAutoScaler till it is uncordoned or deleted. Not kept in DryRun, whose writes don't persist.
+	DrainProgress bool
+	// Maintenance also drains for nodes its maintenance CRs are open for, before they are cordoned. Nil doesn't watch any.
+	Maintenance *NodeMaintenance
+	// Shard limits us to our share of nodes when several replicas split them. Nil acts on every node.
+	// Sharded node controllers run on every replica instead of only the leader.
+	Shard *NodeShard
+	// drainStarts mirrors the DrainStartAnnotationKey of nodes so we can still observe a drain once the node is deleted.
+	drainStarts sync.Map
+	// assisted is the assistedNode of every node we last saw draining pods for an EvictionAutoScaler, for /debug/state.
+	assisted sync.Map
+	// progressCreated has the nodes we created a NodeDrainProgress for, so it is deleted even before the cache has it.
+	progressCreated sync.Map
+	// slots are the drains admitted under MaxConcurrentNodeDrains and the nodes queued for them.
+	slots drainSlots
+	// admitted requeues queued nodes once a slot frees up. Nil without MaxConcurrentNodeDrains, a GlobalConfig or a manager.
+	admitted chan event.GenericEvent
 }
 
 const NodeNameIndex = "spec.nodeName"
 
-// +kubebuilder:rbac:groups=core,resources=nodes,verbs=get;list;watch
+// DrainStartAnnotationKey records on the node when we first saw it cordoned with pods for an EvictionAutoScaler.
+// Persisted on the node so a controller restart mid drain doesn't lose or skew the drain duration.
+const DrainStartAnnotationKey = "eviction-autoscaler.azure.com/drain-start"
+
+// drainResync is how long a node with pods left for EvictionAutoScalers waits for another look when no pod leaves it,
+// in case a pod event was missed. Pods being deleted or finishing are what normally requeue it.
+const drainResync = 10 * time.Minute
+
+// FieldManager is the server-side apply field manager the DisruptionTarget pod conditions are written with.
+const FieldManager = "eviction-autoscaler"
+
+// NodeDisabledAnnotationKey set to "true" on a node means never surge for its pods, e.g. while it is cordoned for debugging.
+const NodeDisabledAnnotationKey = "eviction-autoscaler.azure.com/disabled"
+
+// StaleCordonAnnotationKey records on a node when we stood down from it after StaleCordonThreshold. Cleared once the
+// node is uncordoned or re-armed.
+const StaleCordonAnnotationKey = "eviction-autoscaler.azure.com/stale-cordon"
+
+// NodeRearmAnnotationKey set to "true" on a node starts assisting its drain over, even after a stale cordon. We remove it.
+const NodeRearmAnnotationKey = "eviction-autoscaler.azure.com/rearm"
+
+// DefaultDrainTaints are the taints cluster-autoscaler and karpenter put on a node before they drain it.
+var DefaultDrainTaints = []string{"ToBeDeletedByClusterAutoscaler", "karpenter.sh/disrupted"}
+
+// DefaultDrainBlockingAnnotations are the pod annotations cluster-autoscaler and karpenter won't evict a pod with.
+var DefaultDrainBlockingAnnotations = []string{"cluster-autoscaler.kubernetes.io/safe-to-evict=false", "karpenter.sh/do-not-disrupt=true"}
+
+// +kubebuilder:rbac:groups=core,resources=nodes,verbs=get;list;watch;patch
 // +kubebuilder:rbac:groups=core,resources=pods,verbs=watch;get;list
+// +kubebuilder:rbac:groups=core,resources=events,verbs=create;patch
+// +kubebuilder:rbac:groups=eviction-autoscaler.azure.com,resources=nodedrainprogresses,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=eviction-autoscaler.azure.com,resources=nodedrainprogresses/status,verbs=get;update;patch
 
 // Reconcile is the main loop of the controller. It will look for unschedulded nodes and for every pod on the node
 func (r *NodeReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	ctx, span := tracing.Tracer.Start(ctx, "NodeReconciler.Reconcile", trace.WithAttributes(tracing.NodeKey.String(req.Name)))
+	result, err := r.reconcileNode(ctx, req)
+	r.recordError(req.Name, err)
+	tracing.End(span, err)
+	if terminalNodeError(err) {
+		err = reconcile.TerminalError(err)
+	}
+	return result, err
+}
+
+func (r *NodeReconciler) reconcileNode(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	logger := log.FromContext(ctx)
 
+	// a requeue from before the shard count changed, the node's new owner picks it up.
+	if !r.Shard.Owns(req.Name) {
+		tracing.Decide(ctx, "other-shard")
+		return ctrl.Result{}, nil
+	}
+
 	// Fetch the EvictionAutoScaler instance
 	node := &corev1.Node{}
 	err := r.Get(ctx, req.NamespacedName, node)
 	if err != nil {
-		//should we use a finalizer to scale back down on deletion?
 		if errors.IsNotFound(err) {
-			return ctrl.Result{}, nil // EvictionAutoScaler not found, could be deleted, nothing to do
+			// a deleted node is drained, cluster-autoscaler deletes them right after the last pod leaves. Our pod
+			// conditions go with the pods, which the pod garbage collector deletes along with the node.
+			if start, found := r.drainStarts.LoadAndDelete(req.Name); found {
+				logger.Info("Draining node was deleted", "node", req.Name)
+				metrics.NodeDrainDuration.WithLabelValues(metrics.DrainOutcomeDeleted).Observe(time.Since(start.(time.Time)).Seconds())
+			}
+			r.assisted.Delete(req.Name)
+			r.releaseSlot(ctx, req.Name)
+			metrics.ForgetStuckTerminating(req.Name)
+			tracing.Decide(ctx, "release-deleted")
+			if err := r.deleteDrainProgress(ctx, req.Name); err != nil {
+				return ctrl.Result{}, err
+			}
+			// node is gone so let every EvictionAutoScaler it was draining for scale back down.
+			return ctrl.Result{}, r.releaseNode(ctx, req.Name, nil, false)
 		}
-		return ctrl.Result{}, err // Error fetching EvictionAutoScaler
+		return ctrl.Result{}, err // Error fetching Node
 	}
 
-	// Track node cordoning events
-	if node.Spec.Unschedulable {
-		metrics.NodeCordoningCounter.Inc()
+	if !r.selected(node) {
+		tracing.Decide(ctx, "not-selected")
+		metrics.SkippedNodeCounter.WithLabelValues(metrics.SkipNodeSelector).Inc()
+		logger.V(1).Info("Ignoring node not matching node selector", "node", node.Name)
+		return ctrl.Result{}, nil
 	}
 
-	if !node.Spec.Unschedulable {
-		return ctrl.Result{}, err
+	// a disabled node is let go like an uncordoned one so surges already made for it still scale back down.
+	if disabled(node) {
+		tracing.Decide(ctx, "disabled")
+		metrics.SkippedNodeCounter.WithLabelValues(metrics.SkipNodeDisabled).Inc()
+		logger.V(1).Info("Ignoring disabled node", "node", node.Name)
+		r.assisted.Delete(node.Name)
+		r.releaseSlot(ctx, node.Name)
+		metrics.ForgetStuckTerminating(node.Name)
+		if err := r.clearDisruptionTargets(ctx, node); err != nil {
+			return ctrl.Result{}, err
+		}
+		if err := r.finishDrain(ctx, node, metrics.DrainOutcomeDisabled); err != nil {
+			return ctrl.Result{}, err
+		}
+		if err := r.deleteDrainProgress(ctx, node.Name); err != nil {
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{}, r.releaseNode(ctx, node.Name, nil, true)
+	}
+
+	// uncordoned and no drain taint left is the same as never being cordoned.
+	trigger := r.drainTrigger(node)
+	if trigger == "" {
+		tracing.Decide(ctx, "release-uncordoned")
+		r.assisted.Delete(node.Name)
+		r.releaseSlot(ctx, node.Name)
+		metrics.ForgetStuckTerminating(node.Name)
+		if err := r.clearDisruptionTargets(ctx, node); err != nil {
+			return ctrl.Result{}, err
+		}
+		if err := r.finishDrain(ctx, node, metrics.DrainOutcomeUncordoned); err != nil {
+			return ctrl.Result{}, err
+		}
+		if err := r.deleteDrainProgress(ctx, node.Name); err != nil {
+			return ctrl.Result{}, err
+		}
+		// the drain was aborted, whatever is still on the node stays. Drop its evictions so the surge goes back down.
+		// look again once a NotReady node has been for the whole window, nothing else requeues it.
+		return ctrl.Result{RequeueAfter: r.notReadyWait(node)}, r.releaseNode(ctx, node.Name, nil, true)
 	}
 
-	logger.Info("Node is cordoned", "node", node.Name)
+	if rearmed(node) {
+		if err := r.rearm(ctx, node); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+	// a node cordoned and forgotten, say kept around for debugging, shouldn't hold surges forever. Only a plain cordon,
+	// drain taints and failed nodes are always on their way out.
+	if trigger == metrics.DrainTriggerCordon && r.StaleCordonThreshold > 0 {
+		if _, stood := node.Annotations[StaleCordonAnnotationKey]; stood {
+			tracing.Decide(ctx, "stale-cordon")
+			metrics.SkippedNodeCounter.WithLabelValues(metrics.SkipNodeStale).Inc()
+			logger.V(1).Info("Ignoring stale cordoned node", "node", node.Name)
+			return ctrl.Result{}, nil
+		}
+		if progressed, found := r.drainProgress(node); found && time.Since(progressed) >= r.StaleCordonThreshold {
+			tracing.Decide(ctx, "stand-down")
+			return ctrl.Result{}, r.standDown(ctx, node, progressed)
+		}
+	}
+
+	// a drain we were assisting before a restart keeps its slot even if that goes over the limit for now.
+	_, assisting := drainStart(node)
+	limit := r.maxConcurrentNodeDrains()
+	if admitted, position := r.slots.admit(node.Name, limit, assisting); !admitted {
+		tracing.Decide(ctx, "queued")
+		logger.Info("Queued node drain", "node", node.Name, "position", position)
+		events.Eventf(r.Recorder, node, corev1.EventTypeNormal, events.ReasonDrainQueued,
+			"Drain assist is queued at position %d, %d node drains are already being assisted", position, limit)
+		// a freed slot requeues us, the resync is in case we missed it
+		return ctrl.Result{RequeueAfter: drainResync}, nil
+	}
+
+	// Track node cordoning events
+	metrics.NodeCordoningCounter.Inc()
+	logger.Info("Node is cordoned", "node", node.Name, "unschedulable", node.Spec.Unschedulable, "trigger", trigger)
 
 	var podlist corev1.PodList
 	if err := r.List(ctx, &podlist, client.MatchingFields{NodeNameIndex: node.Name}); err != nil {
 		return ctrl.Result{}, err
 	}
 
-	podchanged := false
+	// group pods by namespace so we only list EvictionAutoScalers and fetch their pdbs once per namespace
+	var namespaces []string
+	podsByNamespace := map[string][]corev1.Pod{}
+	var terminating []*corev1.Pod
+	skipped := 0
 	for _, pod := range podlist.Items {
-		// TODO group pods by namespace to share list/get of EvictionAutoScalers/pdbs
-		// Also  could do this to avoid list/llooku up but need to measure if either helps
-		//if !possibleTarget(pod.GetOwnerReferences()) {
-		//	continue
-		//}
-
-		EvictionAutoScalerList := &pdbautoscaler.EvictionAutoScalerList{}
-		err = r.Client.List(ctx, EvictionAutoScalerList, &client.ListOptions{Namespace: pod.Namespace})
+		if r.Namespaces.Skip(ctx, pod.Namespace, "node") {
+			continue
+		}
+		// daemonset, static, mirror, job and finished pods can't be helped by scaling anything and ignored ones opted out
+		if reason := podutil.DrainSkipReason(&pod); reason != "" {
+			metrics.SkippedPodCounter.WithLabelValues(pod.Namespace, reason).Inc()
+			skipped++
+			continue
+		}
+		// terminating pods don't count as changed so a node with only those left stops requeuing
+		if podutil.IsTerminating(&pod) {
+			terminating = append(terminating, &pod)
+			continue
+		}
+		// the drain won't evict it till the annotation is gone, a surge would only hold capacity
+		if annotation := r.drainBlockingAnnotation(&pod); annotation != "" {
+			metrics.DrainBlockedPodCounter.WithLabelValues(pod.Namespace, metrics.DrainBlockerAnnotation).Inc()
+			events.Eventf(r.Recorder, &pod, corev1.EventTypeWarning, events.ReasonDrainBlockedByAnnotation,
+				"Drain of node %s is blocked by the pod's %s annotation, not its PodDisruptionBudget, so no surge is made for it", node.Name, annotation)
+			continue
+		}
+		if _, found := podsByNamespace[pod.Namespace]; !found {
+			namespaces = append(namespaces, pod.Namespace)
+		}
+		podsByNamespace[pod.Namespace] = append(podsByNamespace[pod.Namespace], pod)
+	}
+	if skipped > 0 {
+		logger.V(1).Info("Skipped pods not evicted by drain", "node", node.Name, "skipped", skipped)
+	}
+	stuck, untilStuck := r.stuckTerminating(terminating)
+	r.reportStuckTerminating(ctx, node, stuck)
+
+	remaining := map[string]int{}
+	conflicts := &selectorConflicts{}
+	var assists []podAssist
+	for _, namespace := range namespaces {
+		candidates, err := r.candidatesForNamespace(ctx, namespace)
 		if err != nil {
-			logger.Error(err, "Error: Unable to list EvictionAutoScalers")
 			return ctrl.Result{}, err
 		}
-		var applicableEvictionAutoScaler *pdbautoscaler.EvictionAutoScaler
-		for _, EvictionAutoScaler := range EvictionAutoScalerList.Items {
-			// Fetch the PDB using a 1:1 name mapping
-			pdb := &policyv1.PodDisruptionBudget{}
-			err = r.Get(ctx, types.NamespacedName{Name: EvictionAutoScaler.Name, Namespace: EvictionAutoScaler.Namespace}, pdb)
-			if err != nil {
-				if errors.IsNotFound(err) {
-					logger.Error(err, "no matching pdb", "namespace", EvictionAutoScaler.Namespace, "name", EvictionAutoScaler.Name)
-					continue
+		if len(candidates) == 0 {
+			continue
+		}
+		matched := map[*pdbautoscaler.EvictionAutoScaler][]*corev1.Pod{}
+		for i := range podsByNamespace[namespace] {
+			pod := &podsByNamespace[namespace][i]
+			// Also  could do this to avoid list/llooku up but need to measure if either helps
+			//if possibleTarget(pod.GetOwnerReferences()) == nil {
+			//	continue
+			//}
+			var matches []*pdbautoscaler.EvictionAutoScaler
+			for _, candidate := range candidates {
+				if candidate.selector.Matches(labels.Set(pod.Labels)) {
+					matches = append(matches, candidate.EvictionAutoScaler)
 				}
-				return ctrl.Result{}, err
 			}
+			applicableEvictionAutoScaler := evictionutil.Applicable(matches)
+			if applicableEvictionAutoScaler == nil {
+				continue
+			}
+			conflicts.add(pod, matches)
+			// spec.suspend leaves the pod alone, without falling through to another EvictionAutoScaler
+			if applicableEvictionAutoScaler.Spec.Suspend {
+				continue
+			}
+			matched[applicableEvictionAutoScaler] = append(matched[applicableEvictionAutoScaler], pod)
+		}
+		for _, candidate := range candidates {
+			pods := matched[candidate.EvictionAutoScaler]
+			if len(pods) == 0 {
+				continue
+			}
+			// the drain evicts these whatever the pdb's budget, like the ones it allows
+			if healthy := slices.DeleteFunc(pods, func(pod *corev1.Pod) bool {
+				return evictionutil.UnhealthyEvictionAllowed(candidate.pdb, pod)
+			}); len(healthy) < len(pods) {
+				unhealthy := len(pods) - len(healthy)
+				logger.V(1).Info("PDB allows evicting the node's unhealthy pods, not assisting them", "name", candidate.EvictionAutoScaler.Name,
+					"namespace", namespace, "node", node.Name, "pods", unhealthy)
+				metrics.SkippedPodCounter.WithLabelValues(namespace, metrics.SkipPodUnhealthyEvictionAllowed).Add(float64(unhealthy))
+				pods = healthy
+			}
+			if len(pods) == 0 {
+				continue
+			}
+			if candidate.evictionAllowed(node.Name, len(pods)) {
+				// the drain evicts these straight away, a DisruptionTarget and surge would only be noise
+				logger.V(1).Info("PDB allows evicting the node's pods, not assisting them", "name", candidate.EvictionAutoScaler.Name,
+					"namespace", namespace, "node", node.Name, "pods", len(pods), "disruptionsAllowed", candidate.pdb.Status.DisruptionsAllowed)
+				metrics.SkippedPodCounter.WithLabelValues(namespace, metrics.SkipPodEvictionAllowed).Add(float64(len(pods)))
+				continue
+			}
+			for _, pod := range pods {
+				remaining[client.ObjectKeyFromObject(candidate.EvictionAutoScaler).String()]++
+				if candidate.pdb != nil && candidate.pdb.Status.DisruptionsAllowed == 0 {
+					// a surge that is still coming up is why the pdb blocks, not a pdb too tight to ever let the pod go
+					blocker := metrics.DrainBlockerPDB
+					if meta.IsStatusConditionFalse(candidate.EvictionAutoScaler.Status.Conditions, ConditionSurgeReady) {
+						blocker = metrics.DrainBlockerSurgeNotReady
+					}
+					metrics.DrainBlockedPodCounter.WithLabelValues(pod.Namespace, blocker).Inc()
+				}
+				logger.Info("Found EvictionAutoScaler for pod", "name", candidate.EvictionAutoScaler.Name, "namespace", pod.Namespace, "podname", pod.Name, "node", node.Name)
+				assists = append(assists, podAssist{pod: pod.DeepCopy(), EvictionAutoScaler: candidate.EvictionAutoScaler})
+			}
+		}
+	}
+
+	// the api writes for each pod run on up to PodConcurrency workers. Writes to one EvictionAutoScaler are still
+	// made one at a time so its lastEviction only moves forward. A pod that fails doesn't stop the others, its error
+	// is returned with the rest once every pod was tried so the node is retried.
+	var mu sync.Mutex
+	var podErrs []error
+	podchanged := false
+	touched := map[types.NamespacedName]bool{}
+	recording := map[types.NamespacedName]*sync.Mutex{}
+	for _, assist := range assists {
+		if key := client.ObjectKeyFromObject(assist.EvictionAutoScaler); recording[key] == nil {
+			recording[key] = &sync.Mutex{}
+		}
+	}
+	workers := &errgroup.Group{}
+	workers.SetLimit(r.podConcurrency())
+	for _, assist := range assists {
+		workers.Go(func() error {
+			key := client.ObjectKeyFromObject(assist.EvictionAutoScaler)
+			changed, err := r.assistPod(ctx, node, assist, recording[key])
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				podErrs = append(podErrs, err)
+			}
+			if changed {
+				podchanged = true
+				touched[key] = true
+			}
+			return nil
+		})
+	}
+	_ = workers.Wait()
+	if len(podErrs) > 0 {
+		logger.Info("Some pods of the node couldn't be assisted, retrying", "node", node.Name, "failed", len(podErrs), "pods", len(assists))
+		return ctrl.Result{}, utilerrors.NewAggregate(podErrs)
+	}
+
+	if err := r.reportConflicts(ctx, conflicts); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	var draining []*pdbautoscaler.EvictionAutoScaler
+	for _, assist := range assists {
+		if touched[client.ObjectKeyFromObject(assist.EvictionAutoScaler)] && !slices.Contains(draining, assist.EvictionAutoScaler) {
+			draining = append(draining, assist.EvictionAutoScaler)
+		}
+	}
+	untilDeadline, err := r.checkDrainDeadlines(ctx, node, draining)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	// EvictionAutoScalers we drained for before but that have no pods left on this node can scale back down.
+	if err := r.releaseNode(ctx, node.Name, touched, false); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	resync := drainResync
+	if len(remaining) > 0 {
+		tracing.Decide(ctx, "drain")
+		var previous assistedNode
+		if last, found := r.assisted.Load(node.Name); found {
+			previous = last.(assistedNode)
+		}
+		resync = r.nextResync(previous, trigger, remaining)
+		progressed := previous.Progressed
+		if previous.PodsRemaining != nil && !maps.Equal(previous.PodsRemaining, remaining) {
+			progressed = time.Now()
+		}
+		r.assisted.Store(node.Name, assistedNode{PodsRemaining: remaining, Updated: time.Now(), Trigger: trigger, Resync: resync, Progressed: progressed})
+		// look again once the drain would be stale, in case no pod leaves till then
+		if trigger == metrics.DrainTriggerCordon && r.StaleCordonThreshold > 0 {
+			if last, found := r.drainProgress(node); found {
+				resync = min(resync, max(time.Until(last.Add(r.StaleCordonThreshold)), time.Second))
+			}
+		}
+	} else {
+		tracing.Decide(ctx, "no-pods")
+		r.assisted.Delete(node.Name)
+	}
+
+	// time the drain from the first pass with pods for an EvictionAutoScaler till the last one has left.
+	if len(touched) > 0 {
+		err = r.startDrain(ctx, node, trigger)
+	} else {
+		r.releaseSlot(ctx, node.Name)
+		err = r.finishDrain(ctx, node, metrics.DrainOutcomeDrained)
+	}
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if len(remaining) > 0 {
+		err = r.reportDrainProgress(ctx, node, trigger, assists)
+	} else {
+		err = r.completeDrainProgress(ctx, node)
+	}
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	// pods leaving requeue us through the pod watch till they are all off or node is uncordoned.
+	if !podchanged {
+		resync = 0
+	}
+	// a pod terminating never changes again once it is stuck and a drain can stall with nothing changing, look
+	// again when the next would be stuck or past its deadline
+	for _, wait := range []time.Duration{untilStuck, untilDeadline} {
+		if wait > 0 && (resync == 0 || wait < resync) {
+			resync = wait
+		}
+	}
+	return ctrl.Result{RequeueAfter: resync}, nil
+}
 
-			// Check if the PDB selector matches the evicted pod's labels
-			selector, err := metav1.LabelSelectorAsSelector(pdb.Spec.Selector)
+// nextResync doubles the last resync of a node whose pods haven't changed since, up to MaxDrainResync, so a node
+// cordoned and left alone doesn't have the same pod conditions and evictions rewritten every drainResync forever.
+// A pod leaving or the node draining for another trigger starts over from drainResync.
+func (r *NodeReconciler) nextResync(previous assistedNode, trigger string, remaining map[string]int) time.Duration {
+	if previous.Resync == 0 || previous.Trigger != trigger || !maps.Equal(previous.PodsRemaining, remaining) {
+		return drainResync
+	}
+	return max(min(2*previous.Resync, r.MaxDrainResync), drainResync)
+}
+
+// podAssist is a pod of a draining node and the EvictionAutoScaler it is recorded on.
+type podAssist struct {
+	pod                *corev1.Pod
+	EvictionAutoScaler *pdbautoscaler.EvictionAutoScaler
+}
+
+// assistPod sets the DisruptionTarget condition on the pod of assist and records its anticipated eviction, holding
+// recording while it records. Reports if the pod still counts towards the drain: false when the pod or EvictionAutoScaler
+// went away or changed under us and it was skipped.
+func (r *NodeReconciler) assistPod(ctx context.Context, node *corev1.Node, assist podAssist, recording *sync.Mutex) (bool, error) {
+	logger := log.FromContext(ctx)
+	pod, applicableEvictionAutoScaler := assist.pod, assist.EvictionAutoScaler
+	disruptionTarget := &corev1.PodCondition{
+		Type:    corev1.DisruptionTarget,
+		Status:  corev1.ConditionTrue,
+		Reason:  podutil.CordonDisruptionReason,
+		Message: podutil.CordonDisruptionMessage,
+	}
+	// the condition only tells the pod's owner what is coming, recording the eviction below is what surges
+	updatedpod := !r.PodConditionWrites.Off() && podutil.UpdatePodCondition(&pod.Status, disruptionTarget)
+	if updatedpod && r.DryRun {
+		events.DryRun(ctx, r.Recorder, pod, metrics.DryRunSetPodCondition,
+			"set DisruptionTarget on pod %s/%s for cordon of node %s", pod.Namespace, pod.Name, node.Name)
+	} else if updatedpod {
+		err := tracing.Span(ctx, "ApplyPodStatus", func(ctx context.Context) error {
+			_, err := r.applyPodConditions(ctx, pod, false, *disruptionTarget)
+			return err
+		}, tracing.NamespaceKey.String(pod.Namespace), tracing.PodKey.String(pod.Name))
+		switch {
+		case errors.IsForbidden(err):
+			r.PodConditionWrites.Forbid(ctx, err)
+		case errors.IsNotFound(err) || errors.IsConflict(err):
+			// pod went away or a real eviction's DisruptionTarget is there, don't hold up the rest of the node for it
+			logger.Error(err, "unable to set DisruptionTarget on pod, skipping", "podname", pod.Name)
+			metrics.PodConditionUpdateFailureCounter.WithLabelValues(pod.Namespace).Inc()
+			return false, nil
+		case err != nil:
+			logger.Error(err, "Error: Unable to update Pod status", "podname", pod.Name, "operation", metrics.PodOperationCondition)
+			metrics.NodePodErrorCounter.WithLabelValues(pod.Namespace, metrics.PodOperationCondition).Inc()
+			return false, fmt.Errorf("set DisruptionTarget on pod %s/%s: %w", pod.Namespace, pod.Name, err)
+		default:
+			events.Eventf(r.Recorder, pod, corev1.EventTypeNormal, events.ReasonDisruptionTarget,
+				"Node %s is cordoned, eviction anticipated for EvictionAutoScaler %s", node.Name, applicableEvictionAutoScaler.Name)
+		}
+	}
+
+	recording.Lock()
+	defer recording.Unlock()
+	// timed once we hold the EvictionAutoScaler so evictions are recorded in the order of their times
+	eviction := pdbautoscaler.EvictionRecord{
+		PodName:      pod.Name,
+		Node:         node.Name,
+		EvictionTime: metav1.Now(),
+		Source:       pdbautoscaler.EvictionSourceNodeCordon,
+	}
+	key := client.ObjectKeyFromObject(applicableEvictionAutoScaler)
+	recorded := false
+	if r.DryRun {
+		events.DryRun(ctx, r.Recorder, applicableEvictionAutoScaler, metrics.DryRunRecordEviction,
+			"record eviction of pod %s on node %s", pod.Name, node.Name)
+	} else if err := tracing.Span(ctx, "RecordEviction", func(ctx context.Context) error {
+		// the node is reconciled again on every pod event while it drains, only record the pod again once the
+		// cooldown has gone by so status isn't rewritten each time with nothing but a new timestamp
+		var err error
+		_, recorded, err = evictionutil.RecordEvictionUnlessRecent(ctx, r.Client, key, eviction, r.Config.CooldownFor(applicableEvictionAutoScaler))
+		return err
+	}, tracing.NamespaceKey.String(key.Namespace), tracing.EvictionAutoScalerKey.String(key.Name)); err != nil {
+		if errors.IsNotFound(err) || errors.IsConflict(err) {
+			// EvictionAutoScaler went away or kept changing under us keep going with the rest of the pods.
+			logger.Error(err, "unable to record eviction on EvictionAutoScaler, skipping", "name", applicableEvictionAutoScaler.Name)
+			return false, nil
+		}
+		logger.Error(err, "unable to update EvictionAutoScaler", "name", applicableEvictionAutoScaler.Name,
+			"podname", pod.Name, "operation", metrics.PodOperationRecord)
+		metrics.NodePodErrorCounter.WithLabelValues(pod.Namespace, metrics.PodOperationRecord).Inc()
+		return false, fmt.Errorf("record eviction of pod %s/%s on EvictionAutoScaler %s: %w", pod.Namespace, pod.Name, applicableEvictionAutoScaler.Name, err)
+	} else if recorded {
+		metrics.EvictionCounter.WithLabelValues(pod.Namespace, metrics.EvictionSourceLabel(eviction.Source),
+			EvictionTargetKind(applicableEvictionAutoScaler)).Inc()
+		events.Eventf(r.Recorder, applicableEvictionAutoScaler, corev1.EventTypeNormal, events.ReasonAnticipatedEviction,
+			"AnticipatedEviction pod %s on node %s", pod.Name, node.Name)
+	}
+	return true, nil
+}
+
+// candidate is an EvictionAutoScaler along with the compiled selector of its pdb
+type candidate struct {
+	EvictionAutoScaler *pdbautoscaler.EvictionAutoScaler
+	selector           labels.Selector
+	// pdb is the EvictionAutoScaler's pdb, nil for a spec.podSelector.
+	pdb *policyv1.PodDisruptionBudget
+}
+
+// evictionAllowed is true if the pdb lets all the pods of a node be evicted at once and no surge is up. While one
+// is, the disruptions it allows are the surge's and the node's pods have to hold it, as do those of a node in
+// status.drainingNodes till its drain is done.
+func (c candidate) evictionAllowed(nodeName string, pods int) bool {
+	return c.pdb != nil && int(c.pdb.Status.DisruptionsAllowed) >= pods &&
+		!meta.IsStatusConditionTrue(c.EvictionAutoScaler.Status.Conditions, ConditionScalingUp) &&
+		!slices.Contains(c.EvictionAutoScaler.Status.DrainingNodes, nodeName)
+}
+
+// candidatesForNamespace lists the EvictionAutoScalers in a namespace and pairs each with its pdb's selector,
+// or its spec.podSelector. EvictionAutoScalers without a pdb or with an invalid selector are skipped.
+func (r *NodeReconciler) candidatesForNamespace(ctx context.Context, namespace string) (candidates []candidate, err error) {
+	ctx, span := tracing.Tracer.Start(ctx, "MatchPDBs", trace.WithAttributes(tracing.NamespaceKey.String(namespace)))
+	defer func() { tracing.End(span, err) }()
+	logger := log.FromContext(ctx)
+	EvictionAutoScalerList := &pdbautoscaler.EvictionAutoScalerList{}
+	err = r.Client.List(ctx, EvictionAutoScalerList, &client.ListOptions{Namespace: namespace})
+	if err != nil {
+		logger.Error(err, "Error: Unable to list EvictionAutoScalers")
+		return nil, err
+	}
+	for i := range EvictionAutoScalerList.Items {
+		EvictionAutoScaler := &EvictionAutoScalerList.Items[i]
+		if pdb := evictionutil.PodSelectorPDB(EvictionAutoScaler); pdb != nil {
+			// matches pods directly, there is no pdb to be blocked by
+			selector, err := r.Selectors.Selector(pdb)
 			if err != nil {
-				logger.Error(err, "Error: Invalid PDB selector", "pdbname", EvictionAutoScaler.Name)
+				logger.Error(err, "Error: Invalid podSelector", "name", EvictionAutoScaler.Name)
+				continue
+			}
+			candidates = append(candidates, candidate{EvictionAutoScaler: EvictionAutoScaler, selector: selector})
+			continue
+		}
+		// Fetch the PDB, by default the one of the same name
+		pdb := &policyv1.PodDisruptionBudget{}
+		err = r.Get(ctx, types.NamespacedName{Name: EvictionAutoScaler.PDBName(), Namespace: EvictionAutoScaler.Namespace}, pdb)
+		if err != nil {
+			if errors.IsNotFound(err) {
+				// the EvictionAutoScaler controller reports this with a PDBMissing condition
+				logger.V(1).Info("no matching pdb", "namespace", EvictionAutoScaler.Namespace, "name", EvictionAutoScaler.PDBName())
 				continue
 			}
+			return nil, err
+		}
+
+		// Check if the PDB selector matches the evicted pod's labels
+		selector, err := r.Selectors.Selector(pdb)
+		if err != nil {
+			logger.Error(err, "Error: Invalid PDB selector", "pdbname", pdb.Name)
+			continue
+		}
+		// list items are already our own copy so updates here don't mutate the cache
+		candidates = append(candidates, candidate{EvictionAutoScaler: EvictionAutoScaler, selector: selector, pdb: pdb})
+	}
+	return candidates, nil
+}
+
+// selectorConflicts collects over one reconcile which EvictionAutoScalers' pdbs or podSelectors select the same pods.
+type selectorConflicts struct {
+	// messages are for EvictionAutoScalers that shared a pod, from the first pod they shared.
+	messages map[types.NamespacedName]string
+	// alone are EvictionAutoScalers that were the only match for a pod.
+	alone map[types.NamespacedName]*pdbautoscaler.EvictionAutoScaler
+	// byKey are all EvictionAutoScalers in messages.
+	byKey map[types.NamespacedName]*pdbautoscaler.EvictionAutoScaler
+}
+
+// add records the EvictionAutoScalers matching pod, sorted by evictionutil.Applicable.
+func (c *selectorConflicts) add(pod *corev1.Pod, matches []*pdbautoscaler.EvictionAutoScaler) {
+	if len(matches) == 1 {
+		if c.alone == nil {
+			c.alone = map[types.NamespacedName]*pdbautoscaler.EvictionAutoScaler{}
+		}
+		c.alone[client.ObjectKeyFromObject(matches[0])] = matches[0]
+		return
+	}
+	metrics.ConflictingSelectorsCounter.WithLabelValues(pod.Namespace).Inc()
+	if c.messages == nil {
+		c.messages = map[types.NamespacedName]string{}
+		c.byKey = map[types.NamespacedName]*pdbautoscaler.EvictionAutoScaler{}
+	}
+	names := make([]string, 0, len(matches))
+	for _, match := range matches {
+		names = append(names, match.Name)
+	}
+	message := fmt.Sprintf("EvictionAutoScalers %s all select pod %s, evictions are only recorded on %s (ones with a pdb before ones with a podSelector, then the oldest)",
+		strings.Join(names, ", "), pod.Name, matches[0].Name)
+	for _, match := range matches {
+		key := client.ObjectKeyFromObject(match)
+		if _, found := c.messages[key]; !found {
+			c.messages[key] = message
+			c.byKey[key] = match
+		}
+	}
+}
+
+// reportConflicts sets ConflictingSelectors with an event on every EvictionAutoScaler that shared a pod and
+// clears it on ones that only matched pods alone. EvictionAutoScalers not matched at all keep whatever they had.
+func (r *NodeReconciler) reportConflicts(ctx context.Context, conflicts *selectorConflicts) error {
+	logger := log.FromContext(ctx)
+	write := func(key types.NamespacedName, condition metav1.Condition) error {
+		if r.DryRun {
+			return nil
+		}
+		if _, err := evictionutil.SetCondition(ctx, r.Client, key, condition); err != nil && !errors.IsNotFound(err) {
+			logger.Error(err, "unable to update EvictionAutoScaler conditions", "name", key.Name)
+			return err
+		}
+		return nil
+	}
+	for key, message := range conflicts.messages {
+		EvictionAutoScaler := conflicts.byKey[key]
+		logger.Info("EvictionAutoScalers select the same pods", "name", key.Name, "namespace", key.Namespace, "conflict", message)
+		if !meta.IsStatusConditionTrue(EvictionAutoScaler.Status.Conditions, evictionutil.ConditionConflictingSelectors) {
+			events.Eventf(r.Recorder, EvictionAutoScaler, corev1.EventTypeWarning, events.ReasonConflictingSelectors, message)
+		}
+		if err := write(key, metav1.Condition{
+			Type:    evictionutil.ConditionConflictingSelectors,
+			Status:  metav1.ConditionTrue,
+			Reason:  "SharedPods",
+			Message: message,
+		}); err != nil {
+			return err
+		}
+	}
+	for key, EvictionAutoScaler := range conflicts.alone {
+		if _, found := conflicts.messages[key]; found ||
+			!meta.IsStatusConditionTrue(EvictionAutoScaler.Status.Conditions, evictionutil.ConditionConflictingSelectors) {
+			continue
+		}
+		if err := write(key, metav1.Condition{
+			Type:    evictionutil.ConditionConflictingSelectors,
+			Status:  metav1.ConditionFalse,
+			Reason:  "NoSharedPods",
+			Message: "selects pods no other EvictionAutoScaler does",
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
 
-			if selector.Matches(labels.Set(pod.Labels)) {
-				applicableEvictionAutoScaler = EvictionAutoScaler.DeepCopy()
-				break //should we keep going to ensure multiple EvictionAutoScalers don't match?
+// clearDisruptionTargets undoes the DisruptionTarget conditions we wrote on the node's pods while it was cordoned.
+func (r *NodeReconciler) clearDisruptionTargets(ctx context.Context, node *corev1.Node) error {
+	logger := log.FromContext(ctx)
+	if r.PodConditionWrites.Off() {
+		return nil
+	}
+	var podlist corev1.PodList
+	if err := r.List(ctx, &podlist, client.MatchingFields{NodeNameIndex: node.Name}); err != nil {
+		return err
+	}
+	for i := range podlist.Items {
+		pod := &podlist.Items[i]
+		if !podutil.IsCordonDisruptionTarget(&pod.Status) {
+			continue
+		}
+		logger.Info("Clearing disruption target on uncordoned node", "namespace", pod.Namespace, "podname", pod.Name, "node", node.Name)
+		// applying no conditions drops the one we own, leaving any a real eviction set since
+		applied, err := r.applyPodConditions(ctx, pod, false)
+		if err == nil && podutil.IsCordonDisruptionTarget(&applied.Status) {
+			// set with an update before we moved to apply so it isn't ours to drop, flip it to false instead
+			_, err = r.applyPodConditions(ctx, pod, true, podutil.UncordonedDisruptionTarget())
+		}
+		if errors.IsForbidden(err) {
+			r.PodConditionWrites.Forbid(ctx, err)
+			return nil
+		}
+		if err != nil {
+			if errors.IsNotFound(err) {
+				continue
 			}
+			logger.Error(err, "Error: Unable to update Pod status")
+			return err
 		}
-		if applicableEvictionAutoScaler == nil {
+	}
+	return nil
+}
+
+// applyPodConditions applies conditions to pod with ApplyPodConditions.
+func (r *NodeReconciler) applyPodConditions(ctx context.Context, pod *corev1.Pod, force bool, conditions ...corev1.PodCondition) (*corev1.Pod, error) {
+	return ApplyPodConditions(ctx, r.Client, pod, force, conditions...)
+}
+
+// ApplyPodConditions server-side applies conditions as the only pod conditions FieldManager owns on pod, so conditions
+// the kubelet or kube-controller-manager write in between are never overwritten and ones left out are removed
+// unless another manager shares them. force takes conditions over from other managers. Returns the pod as applied.
+// The node controller and the eviction webhook both write DisruptionTarget with it.
+func ApplyPodConditions(ctx context.Context, c client.Client, pod *corev1.Pod, force bool, conditions ...corev1.PodCondition) (*corev1.Pod, error) {
+	status := corev1ac.PodStatus()
+	for _, condition := range conditions {
+		status.WithConditions(corev1ac.PodCondition().WithType(condition.Type).WithStatus(condition.Status).
+			WithReason(condition.Reason).WithMessage(condition.Message).WithLastTransitionTime(condition.LastTransitionTime))
+	}
+	obj, err := runtime.DefaultUnstructuredConverter.ToUnstructured(corev1ac.Pod(pod.Name, pod.Namespace).WithStatus(status))
+	if err != nil {
+		return nil, err
+	}
+	applied := &unstructured.Unstructured{Object: obj}
+	opts := []client.SubResourcePatchOption{client.FieldOwner(FieldManager)}
+	if force {
+		opts = append(opts, client.ForceOwnership)
+	}
+	if err := c.Status().Patch(ctx, applied, client.Apply, opts...); err != nil {
+		return nil, err
+	}
+	appliedPod := &corev1.Pod{}
+	return appliedPod, runtime.DefaultUnstructuredConverter.FromUnstructured(applied.Object, appliedPod)
+}
+
+// startDrain stamps the node with DrainStartAnnotationKey unless it already has one and counts the drain by trigger.
+func (r *NodeReconciler) startDrain(ctx context.Context, node *corev1.Node, trigger string) error {
+	if start, found := drainStart(node); found {
+		r.drainStarts.Store(node.Name, start) // reload after a restart
+		return nil
+	}
+	now := time.Now()
+	patch := client.MergeFrom(node.DeepCopy())
+	node = node.DeepCopy()
+	if node.Annotations == nil {
+		node.Annotations = map[string]string{}
+	}
+	node.Annotations[DrainStartAnnotationKey] = now.UTC().Format(time.RFC3339)
+	if err := r.Patch(ctx, node, patch); err != nil {
+		log.FromContext(ctx).Error(err, "unable to annotate drain start", "node", node.Name)
+		return err
+	}
+	r.drainStarts.Store(node.Name, now)
+	metrics.NodeDrainTriggerCounter.WithLabelValues(trigger).Inc()
+	return nil
+}
+
+// finishDrain observes the drain duration with outcome and removes DrainStartAnnotationKey if the node has one.
+// A StaleCordonAnnotationKey goes with it so the next cordon is assisted from the start.
+func (r *NodeReconciler) finishDrain(ctx context.Context, node *corev1.Node, outcome string) error {
+	r.drainStarts.Delete(node.Name)
+	start, found := drainStart(node)
+	_, stale := node.Annotations[StaleCordonAnnotationKey]
+	if !found && !stale {
+		return nil
+	}
+	patch := client.MergeFrom(node.DeepCopy())
+	node = node.DeepCopy()
+	delete(node.Annotations, DrainStartAnnotationKey)
+	delete(node.Annotations, StaleCordonAnnotationKey)
+	if err := r.Patch(ctx, node, patch); err != nil {
+		if errors.IsNotFound(err) {
+			return nil
+		}
+		log.FromContext(ctx).Error(err, "unable to remove drain start annotation", "node", node.Name)
+		return err
+	}
+	if found {
+		metrics.NodeDrainDuration.WithLabelValues(outcome).Observe(time.Since(start).Seconds())
+	}
+	return nil
+}
+
+// releaseSlot frees nodeName's drain slot, or its place in the queue, and requeues the nodes admitted in its place.
+func (r *NodeReconciler) releaseSlot(ctx context.Context, nodeName string) {
+	for _, next := range r.slots.release(nodeName, r.maxConcurrentNodeDrains()) {
+		if r.admitted == nil {
 			continue
 		}
+		select {
+		case r.admitted <- event.GenericEvent{Object: &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: next}}}:
+		default:
+			// their drainResync requeues them instead
+			log.FromContext(ctx).V(1).Info("Unable to requeue admitted node drain", "node", next)
+		}
+	}
+}
 
-		// Track eviction and node drain events
-		metrics.EvictionCounter.WithLabelValues(pod.Namespace).Inc()
+// drainProgress returns when node's drain last moved: when a pod last left it, or else when the drain started.
+func (r *NodeReconciler) drainProgress(node *corev1.Node) (time.Time, bool) {
+	start, found := drainStart(node)
+	if last, ok := r.assisted.Load(node.Name); ok && last.(assistedNode).Progressed.After(start) {
+		return last.(assistedNode).Progressed, true
+	}
+	return start, found
+}
 
-		logger.Info("Found EvictionAutoScaler for pod", "name", applicableEvictionAutoScaler.Name, "namespace", pod.Namespace, "podname", pod.Name, "node", node.Name)
-		pod := pod.DeepCopy()
-		updatedpod := podutil.UpdatePodCondition(&pod.Status, &corev1.PodCondition{
-			Type:    corev1.DisruptionTarget,
-			Status:  corev1.ConditionTrue,
-			Reason:  "EvictionAttempt",
-			Message: "eviction attempt anticipated by node cordon",
-		})
-		if updatedpod {
-			if err := r.Client.Status().Update(ctx, pod); err != nil {
-				logger.Error(err, "Error: Unable to update Pod status")
-				return ctrl.Result{}, err
+// standDown stops assisting a cordoned node whose drain hasn't moved since progressed. Our pod conditions are
+// cleared and its evictions dropped like an uncordon so surges made for it scale back down, and the node is
+// stamped with StaleCordonAnnotationKey so we leave it be till it is uncordoned or re-armed.
+func (r *NodeReconciler) standDown(ctx context.Context, node *corev1.Node, progressed time.Time) error {
+	log.FromContext(ctx).Info("Standing down from stale cordoned node", "node", node.Name, "progressed", progressed)
+	if r.DryRun {
+		events.DryRun(ctx, r.Recorder, node, metrics.DryRunStandDown, "stand down from node %s cordoned without progress since %s", node.Name, progressed.UTC().Format(time.RFC3339))
+		return nil
+	}
+	r.assisted.Delete(node.Name)
+	r.releaseSlot(ctx, node.Name)
+	if err := r.clearDisruptionTargets(ctx, node); err != nil {
+		return err
+	}
+	if err := r.releaseNode(ctx, node.Name, nil, true); err != nil {
+		return err
+	}
+	if err := r.deleteDrainProgress(ctx, node.Name); err != nil {
+		return err
+	}
+	r.drainStarts.Delete(node.Name)
+	start, found := drainStart(node)
+	patch := client.MergeFrom(node.DeepCopy())
+	annotated := node.DeepCopy()
+	delete(annotated.Annotations, DrainStartAnnotationKey)
+	if annotated.Annotations == nil {
+		annotated.Annotations = map[string]string{}
+	}
+	annotated.Annotations[StaleCordonAnnotationKey] = time.Now().UTC().Format(time.RFC3339)
+	if err := r.Patch(ctx, annotated, patch); err != nil {
+		if errors.IsNotFound(err) {
+			return nil
+		}
+		log.FromContext(ctx).Error(err, "unable to annotate stale cordon", "node", node.Name)
+		return err
+	}
+	if found {
+		metrics.NodeDrainDuration.WithLabelValues(metrics.DrainOutcomeStale).Observe(time.Since(start).Seconds())
+	}
+	events.Eventf(r.Recorder, node, corev1.EventTypeNormal, events.ReasonStaleCordon,
+		"Node has been cordoned with no pod leaving since %s, longer than %s, so eviction-autoscaler stopped surging for it. Annotate it with %s=true to assist its drain again",
+		progressed.UTC().Format(time.RFC3339), r.StaleCordonThreshold, NodeRearmAnnotationKey)
+	return nil
+}
+
+// rearm removes NodeRearmAnnotationKey along with any stale cordon and drain start, so the drain is assisted
+// and timed from now. node is updated in place.
+func (r *NodeReconciler) rearm(ctx context.Context, node *corev1.Node) error {
+	if r.DryRun {
+		events.DryRun(ctx, r.Recorder, node, metrics.DryRunRearm, "re-arm drain of node %s", node.Name)
+		return nil
+	}
+	patch := client.MergeFrom(node.DeepCopy())
+	delete(node.Annotations, NodeRearmAnnotationKey)
+	delete(node.Annotations, StaleCordonAnnotationKey)
+	delete(node.Annotations, DrainStartAnnotationKey)
+	if err := r.Patch(ctx, node, patch); err != nil {
+		log.FromContext(ctx).Error(err, "unable to re-arm node", "node", node.Name)
+		return err
+	}
+	r.drainStarts.Delete(node.Name)
+	r.assisted.Delete(node.Name)
+	events.Eventf(r.Recorder, node, corev1.EventTypeNormal, events.ReasonDrainRearmed, "Assisting the drain of node again")
+	return nil
+}
+
+// rearmed returns true if node asks for its drain to be assisted again with NodeRearmAnnotationKey.
+func rearmed(node client.Object) bool {
+	return node.GetAnnotations()[NodeRearmAnnotationKey] == "true"
+}
+
+// drainStart parses DrainStartAnnotationKey. An unparsable value is treated as missing.
+func drainStart(node *corev1.Node) (time.Time, bool) {
+	value, found := node.Annotations[DrainStartAnnotationKey]
+	if !found {
+		return time.Time{}, false
+	}
+	start, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return start, true
+}
+
+// releaseNode removes nodeName from the draining nodes of every EvictionAutoScaler not in keep. aborted is for nodes
+// let go with pods still on them, uncordoned or disabled, whose anticipated evictions are dropped with evictionutil.AbortDrain.
+func (r *NodeReconciler) releaseNode(ctx context.Context, nodeName string, keep map[types.NamespacedName]bool, aborted bool) error {
+	logger := log.FromContext(ctx)
+	EvictionAutoScalerList := &pdbautoscaler.EvictionAutoScalerList{}
+	if err := r.Client.List(ctx, EvictionAutoScalerList); err != nil {
+		logger.Error(err, "Error: Unable to list EvictionAutoScalers")
+		return err
+	}
+	for i := range EvictionAutoScalerList.Items {
+		EvictionAutoScaler := &EvictionAutoScalerList.Items[i]
+		key := client.ObjectKeyFromObject(EvictionAutoScaler)
+		if keep[key] || !slices.Contains(EvictionAutoScaler.Status.DrainingNodes, nodeName) {
+			continue
+		}
+		logger.Info("Releasing node from EvictionAutoScaler", "name", EvictionAutoScaler.Name, "namespace", EvictionAutoScaler.Namespace, "node", nodeName, "aborted", aborted)
+		release := evictionutil.ReleaseNode
+		if aborted {
+			release = evictionutil.AbortDrain
+		}
+		if _, err := release(ctx, r.Client, key, nodeName); err != nil {
+			if errors.IsNotFound(err) {
+				continue
 			}
+			logger.Error(err, "unable to release node from EvictionAutoScaler", "name", EvictionAutoScaler.Name)
+			return err
 		}
+		if aborted {
+			events.Eventf(r.Recorder, EvictionAutoScaler, corev1.EventTypeNormal, events.ReasonDrainAborted,
+				"Drain of node %s was aborted, no longer holding the surge for its pods", nodeName)
+		}
+	}
+	return nil
+}
 
-		applicableEvictionAutoScaler.Spec.LastEviction = pdbautoscaler.Eviction{
-			PodName:      pod.Name,
-			EvictionTime: metav1.Now(),
+// releaseDeletedNodes releases nodes that no longer exist from every EvictionAutoScaler draining for them.
+// A node deleted while no controller was running never sends us the delete that would have released it.
+func (r *NodeReconciler) releaseDeletedNodes(ctx context.Context) error {
+	EvictionAutoScalerList := &pdbautoscaler.EvictionAutoScalerList{}
+	if err := r.Client.List(ctx, EvictionAutoScalerList); err != nil {
+		return err
+	}
+	deleted := map[string]bool{}
+	for _, EvictionAutoScaler := range EvictionAutoScalerList.Items {
+		for _, nodeName := range EvictionAutoScaler.Status.DrainingNodes {
+			if _, seen := deleted[nodeName]; seen || !r.Shard.Owns(nodeName) {
+				continue
+			}
+			err := r.Get(ctx, types.NamespacedName{Name: nodeName}, &corev1.Node{})
+			if err != nil && !errors.IsNotFound(err) {
+				return err
+			}
+			deleted[nodeName] = errors.IsNotFound(err)
 		}
-		if err := r.Update(ctx, applicableEvictionAutoScaler); err != nil {
-			logger.Error(err, "unable to update EvictionAutoScaler", "name", applicableEvictionAutoScaler.Name)
-			return ctrl.Result{}, err
+	}
+	for nodeName, gone := range deleted {
+		if !gone {
+			continue
+		}
+		log.FromContext(ctx).Info("Releasing node deleted while we weren't watching", "node", nodeName)
+		if err := r.releaseNode(ctx, nodeName, nil, false); err != nil {
+			return err
 		}
-		podchanged = true
 	}
+	return nil
+}
+
+// deletedNodeSweep runs releaseDeletedNodes once at startup, after the caches are synced. It runs where the
+// node controller does, on the leader unless sharded.
+type deletedNodeSweep struct {
+	reconciler         *NodeReconciler
+	needLeaderElection bool
+}
 
-	///if we updated requeue again so we keep updating (could ignore if there were no pods mathing pdbs)
-	// pods till they get off or node is uncordoned.
-	//TODO pull smallest cooldown from all EvictionAutoScalers if they allow defining it.
-	var cooldownNeeded time.Duration
-	if podchanged {
-		cooldownNeeded = cooldown
+func (s deletedNodeSweep) Start(ctx context.Context) error {
+	if err := s.reconciler.releaseDeletedNodes(ctx); err != nil {
+		// their surges are held till the next restart sweeps again, not worth taking the manager down for
+		log.FromContext(ctx).Error(err, "unable to release deleted nodes")
 	}
-	return ctrl.Result{RequeueAfter: cooldownNeeded}, nil
+	return nil
+}
+
+func (s deletedNodeSweep) NeedLeaderElection() bool {
+	return s.needLeaderElection
+}
+
+// selected returns true if the node matches NodeSelector.
+func (r *NodeReconciler) selected(node client.Object) bool {
+	return r.NodeSelector == nil || r.NodeSelector.Matches(labels.Set(node.GetLabels()))
+}
+
+// disabled returns true if node has opted out with NodeDisabledAnnotationKey.
+func disabled(node client.Object) bool {
+	return node.GetAnnotations()[NodeDisabledAnnotationKey] == "true"
+}
+
+// draining returns true if the node is cordoned, has one of the DrainTaints or DrainAnnotations, is under Maintenance
+// or, with NodeFailureTriggers, has failed.
+func (r *NodeReconciler) draining(node *corev1.Node) bool {
+	return r.drainTrigger(node) != ""
+}
+
+// drainTrigger returns the metrics.DrainTrigger constant for why node counts as draining, empty if it doesn't.
+func (r *NodeReconciler) drainTrigger(node *corev1.Node) string {
+	if node.Spec.Unschedulable {
+		return metrics.DrainTriggerCordon
+	}
+	for _, taint := range node.Spec.Taints {
+		if r.NodeFailureTriggers && taint.Key == corev1.TaintNodeOutOfService {
+			return metrics.DrainTriggerOutOfService
+		}
+		for _, key := range r.DrainTaints {
+			if taint.Key == key {
+				return metrics.DrainTriggerTaint
+			}
+		}
+	}
+	if since, found := r.notReadySince(node); found && time.Since(since) >= r.NotReadyWindow {
+		return metrics.DrainTriggerNotReady
+	}
+	if r.drainAnnotation(node) != "" {
+		return metrics.DrainTriggerAnnotation
+	}
+	if r.Maintenance.active(node.Name) {
+		return metrics.DrainTriggerMaintenance
+	}
+	return ""
+}
+
+// notReadySince returns when node's Ready condition last stopped being True, if it isn't now and NodeFailureTriggers is set.
+// A node that never reported Ready is still joining and isn't counted.
+func (r *NodeReconciler) notReadySince(node *corev1.Node) (time.Time, bool) {
+	if !r.NodeFailureTriggers {
+		return time.Time{}, false
+	}
+	for _, condition := range node.Status.Conditions {
+		if condition.Type == corev1.NodeReady {
+			return condition.LastTransitionTime.Time, condition.Status != corev1.ConditionTrue
+		}
+	}
+	return time.Time{}, false
+}
+
+// notReadyWait returns how long till node has been NotReady for the whole NotReadyWindow, zero if it isn't NotReady.
+func (r *NodeReconciler) notReadyWait(node *corev1.Node) time.Duration {
+	since, found := r.notReadySince(node)
+	if !found {
+		return 0
+	}
+	return max(r.NotReadyWindow-time.Since(since), time.Second)
 }
 
 func (r *NodeReconciler) SetupWithManager(mgr ctrl.Manager) error {
-	if err := mgr.GetFieldIndexer().IndexField(context.TODO(), &corev1.Pod{}, NodeNameIndex, func(rawObj client.Object) []string {
-		// Extract the spec.nodeName field
-		pod := rawObj.(*corev1.Pod)
-		if pod.Spec.NodeName == "" {
-			return nil // Don't index Pods without a NodeName
-		}
-		return []string{pod.Spec.NodeName}
-	}); err != nil {
+	if err := mgr.GetFieldIndexer().IndexField(context.TODO(), &corev1.Pod{}, NodeNameIndex, podNodeName); err != nil {
 		return err
 	}
 
-	return ctrl.NewControllerManagedBy(mgr).
-		For(&corev1.Node{}).
-		WithEventFilter(predicate.Funcs{
-			// ignore status updates as we only care about cordon.
-			UpdateFunc: func(ue event.UpdateEvent) bool {
-				oldNode := ue.ObjectOld.(*corev1.Node)
-				newNode := ue.ObjectNew.(*corev1.Node)
-				return oldNode.Spec.Unschedulable == newNode.Spec.Unschedulable
-			},
-		}).
-		Complete(r)
+	needLeaderElection := r.Shard == nil
+	if err := mgr.Add(deletedNodeSweep{reconciler: r, needLeaderElection: needLeaderElection}); err != nil {
+		return err
+	}
+	nodeController := ctrl.NewControllerManagedBy(mgr)
+	// the EvictionAutoScalerConfig can set a limit later
+	if r.MaxConcurrentNodeDrains > 0 || r.Config.Global != nil {
+		r.admitted = make(chan event.GenericEvent, 1024)
+		nodeController = nodeController.WatchesRawSource(source.Channel(r.admitted, &handler.EnqueueRequestForObject{}))
+	}
+	nodeController = nodeController.
+		For(&corev1.Node{}, builder.WithPredicates(
+			predicate.NewPredicateFuncs(func(obj client.Object) bool {
+				// other shards' nodes never make it to our queue.
+				if !r.Shard.Owns(obj.GetName()) {
+					return false
+				}
+				// nodes outside the node selector are ignored entirely.
+				if !r.selected(obj) {
+					metrics.SkippedNodeCounter.WithLabelValues(metrics.SkipNodeSelector).Inc()
+					return false
+				}
+				return true
+			}),
+			predicate.Funcs{
+				// ignore status updates as we only care about cordon, drain taints and drain annotations coming or going.
+				UpdateFunc: func(ue event.UpdateEvent) bool {
+					oldNode := ue.ObjectOld.(*corev1.Node)
+					newNode := ue.ObjectNew.(*corev1.Node)
+					if r.draining(oldNode) != r.draining(newNode) || disabled(oldNode) != disabled(newNode) || rearmed(oldNode) != rearmed(newNode) ||
+						r.drainAnnotation(oldNode) != r.drainAnnotation(newNode) {
+						return true
+					}
+					// the window starts from the transition, drainTrigger then waits it out.
+					_, oldNotReady := r.notReadySince(oldNode)
+					_, newNotReady := r.notReadySince(newNode)
+					return oldNotReady != newNotReady
+				},
+			})).
+		// drain progress requeues the node as soon as a pod leaves it instead of on a timer
+		Watches(&corev1.Pod{}, handler.EnqueueRequestsFromMapFunc(r.assistedPodNode), builder.WithPredicates(podLeft)).
+		// different nodes, even across shards, only share EvictionAutoScaler status which is written with retries on conflict
+		WithOptions(controller.Options{MaxConcurrentReconciles: r.MaxConcurrentReconciles, NeedLeaderElection: &needLeaderElection,
+			RateLimiter: r.nodeRateLimiter()})
+	built, err := nodeController.Build(r)
+	if err != nil || r.Maintenance == nil {
+		return err
+	}
+	return mgr.Add(maintenanceWatch{reconciler: r, mgr: mgr, controller: built, needLeaderElection: needLeaderElection})
 }
 
-/*
-func possibleTarget(owners []metav1.OwnerReference) bool {
-	//this kind of funny since a deployment pod will be owned by a replicaset
-	for _, owner := range owners {
-		if owner.Kind == "ReplicaSet" || owner.Kind == "StatefulSet" {
-			return true
+// podLeft passes pods being deleted, starting to terminate or finishing, the events that move a drain along.
+var podLeft = predicate.Funcs{
+	CreateFunc: func(event.CreateEvent) bool { return false },
+	UpdateFunc: func(ue event.UpdateEvent) bool {
+		oldPod, newPod := ue.ObjectOld.(*corev1.Pod), ue.ObjectNew.(*corev1.Pod)
+		return leaving(newPod) && !leaving(oldPod)
+	},
+	DeleteFunc:  func(event.DeleteEvent) bool { return true },
+	GenericFunc: func(event.GenericEvent) bool { return false },
+}
+
+// leaving returns true if pod is terminating or finished.
+func leaving(pod *corev1.Pod) bool {
+	return podutil.IsTerminating(pod) || pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed
+}
+
+// assistedPodNode enqueues pod's node if it is one we are draining pods for. Every other node, cordoned or not,
+// has nothing waiting on its pods so the pod events of the rest of the cluster never turn into node reconciles.
+func (r *NodeReconciler) assistedPodNode(_ context.Context, obj client.Object) []reconcile.Request {
+	pod := obj.(*corev1.Pod)
+	if pod.Spec.NodeName == "" {
+		return nil
+	}
+	if _, found := r.assisted.Load(pod.Spec.NodeName); !found {
+		return nil
+	}
+	return []reconcile.Request{{NamespacedName: types.NamespacedName{Name: pod.Spec.NodeName}}}
+}
+
+// drainBlockingAnnotation returns the first of DrainBlockingAnnotations pod has, as key=value, empty if none.
+func (r *NodeReconciler) drainBlockingAnnotation(pod *corev1.Pod) string {
+	for _, annotation := range r.DrainBlockingAnnotations {
+		key, value, _ := strings.Cut(annotation, "=")
+		if found, ok := pod.Annotations[key]; ok && found == value {
+			return annotation
 		}
 	}
-	return false
-}*/
+	return ""
+}
+
+// podNodeName extracts the spec.nodeName field for the NodeNameIndex
+func podNodeName(rawObj client.Object) []string {
+	pod := rawObj.(*corev1.Pod)
+	if pod.Spec.NodeName == "" {
+		return nil // Don't index Pods without a NodeName
+	}
+	return []string{pod.Spec.NodeName}
+}
@@ -2,22 +2,49 @@ package controllers
 
 import (
 	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
 	"time"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/apimachinery/pkg/util/rand"
+	corev1ac "k8s.io/client-go/applyconfigurations/core/v1"
 	"k8s.io/client-go/kubernetes/scheme"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+	"sigs.k8s.io/controller-runtime/pkg/event"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
+	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1" // Import corev1 package
 	policyv1 "k8s.io/api/policy/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	v1 "github.com/azure/eviction-autoscaler/api/v1"
+	"github.com/azure/eviction-autoscaler/internal/events"
+	"github.com/azure/eviction-autoscaler/internal/evictionutil"
+	"github.com/azure/eviction-autoscaler/internal/metrics"
+	"github.com/azure/eviction-autoscaler/internal/namespacefilter"
+	"github.com/azure/eviction-autoscaler/internal/podutil"
+	"github.com/azure/eviction-autoscaler/internal/selectorcache"
 )
 
 var _ = Describe("Node Controller", func() {
@@ -132,9 +159,11 @@ var _ = Describe("Node Controller", func() {
 		})
 
 		It("should handle cordon by updating pod and EvictionAutoScaler", func() {
+			recorder := record.NewFakeRecorder(10)
 			nodeReconciler := &NodeReconciler{
-				Client: k8sClient,
-				Scheme: scheme.Scheme,
+				Client:   k8sClient,
+				Scheme:   scheme.Scheme,
+				Recorder: recorder,
 			}
 			_, err := nodeReconciler.Reconcile(ctx, reconcile.Request{
 				NamespacedName: nodeNamespacedName,
@@ -152,7 +181,7 @@ var _ = Describe("Node Controller", func() {
 				NamespacedName: nodeNamespacedName,
 			})
 			Expect(err).NotTo(HaveOccurred())
-			Expect(result.RequeueAfter).To(Equal(cooldown))
+			Expect(result.RequeueAfter).To(Equal(drainResync))
 
 			By("checking pod condition ")
 			pod := &corev1.Pod{}
@@ -166,50 +195,2318 @@ var _ = Describe("Node Controller", func() {
 			EvictionAutoScaler := &v1.EvictionAutoScaler{}
 			err = k8sClient.Get(ctx, typeNamespacedName, EvictionAutoScaler)
 			Expect(err).NotTo(HaveOccurred())
-			Expect(EvictionAutoScaler.Spec.LastEviction.EvictionTime).ToNot(BeZero())
-			Expect(EvictionAutoScaler.Spec.LastEviction.PodName).To(Equal(podName))
+			Expect(EvictionAutoScaler.Status.LastEviction.EvictionTime).ToNot(BeZero())
+			Expect(EvictionAutoScaler.Status.LastEviction.PodName).To(Equal(podName))
 
+			By("checking events")
+			Expect(recorder.Events).To(Receive(HavePrefix("Normal " + events.ReasonDisruptionTarget)))
+			Expect(recorder.Events).To(Receive(HavePrefix("Normal " + events.ReasonAnticipatedEviction)))
 		})
 
-		It("should handle cordon with no targetable pod", func() {
+		It("should clear our disruption target when the node is uncordoned", func() {
 			nodeReconciler := &NodeReconciler{
 				Client: k8sClient,
 				Scheme: scheme.Scheme,
 			}
-			_, err := nodeReconciler.Reconcile(ctx, reconcile.Request{
+
+			node := &corev1.Node{}
+			err := k8sClient.Get(ctx, nodeNamespacedName, node)
+			Expect(err).NotTo(HaveOccurred())
+			node.Spec.Unschedulable = true
+			Expect(k8sClient.Update(ctx, node)).To(Succeed())
+			_, err = nodeReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: nodeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			By("adding a pod with a disruption target from a real eviction")
+			evictedPod := &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "example-evicted-pod",
+					Namespace: namespace,
+				},
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{
+							Name:  "nginx",
+							Image: "nginx:latest",
+						},
+					},
+					NodeName: nodeName,
+				},
+			}
+			Expect(k8sClient.Create(ctx, evictedPod)).To(Succeed())
+			evictedPod.Status = corev1.PodStatus{
+				Phase: corev1.PodRunning,
+				Conditions: []corev1.PodCondition{{
+					Type:    corev1.DisruptionTarget,
+					Status:  corev1.ConditionTrue,
+					Reason:  "EvictionByEvictionAPI",
+					Message: "Eviction API: evicting",
+				}},
+			}
+			Expect(k8sClient.Status().Update(ctx, evictedPod)).To(Succeed())
+
+			By("uncordoning the node")
+			err = k8sClient.Get(ctx, nodeNamespacedName, node)
+			Expect(err).NotTo(HaveOccurred())
+			node.Spec.Unschedulable = false
+			Expect(k8sClient.Update(ctx, node)).To(Succeed())
+			result, err := nodeReconciler.Reconcile(ctx, reconcile.Request{
 				NamespacedName: nodeNamespacedName,
 			})
 			Expect(err).NotTo(HaveOccurred())
+			Expect(result.RequeueAfter).To(Equal(time.Duration(0)))
 
 			pod := &corev1.Pod{}
 			err = k8sClient.Get(ctx, podNamespacedName, pod)
 			Expect(err).NotTo(HaveOccurred())
-			pod.Labels = map[string]string{
-				"app": "notexample",
+			// the condition we applied is dropped, the Ready one written by someone else stays
+			Expect(pod.Status.Conditions).To(HaveLen(1))
+			Expect(pod.Status.Conditions[0].Type).To(Equal(corev1.PodReady))
+
+			err = k8sClient.Get(ctx, client.ObjectKeyFromObject(evictedPod), evictedPod)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(evictedPod.Status.Conditions).To(HaveLen(1))
+			Expect(evictedPod.Status.Conditions[0].Status).To(Equal(corev1.ConditionTrue))
+		})
+
+		It("should leave conditions applied by another field manager alone", func() {
+			nodeReconciler := &NodeReconciler{
+				Client: k8sClient,
+				Scheme: scheme.Scheme,
 			}
-			err = k8sClient.Update(ctx, pod)
+			applyCondition := func(conditionType corev1.PodConditionType, status corev1.ConditionStatus) {
+				obj, err := runtime.DefaultUnstructuredConverter.ToUnstructured(corev1ac.Pod(podName, namespace).WithStatus(
+					corev1ac.PodStatus().WithConditions(corev1ac.PodCondition().WithType(conditionType).WithStatus(status))))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(k8sClient.Status().Patch(ctx, &unstructured.Unstructured{Object: obj}, client.Apply,
+					client.FieldOwner("kube-controller-manager"), client.ForceOwnership)).To(Succeed())
+			}
+			podConditions := func() map[corev1.PodConditionType]corev1.ConditionStatus {
+				pod := &corev1.Pod{}
+				Expect(k8sClient.Get(ctx, podNamespacedName, pod)).To(Succeed())
+				conditions := map[corev1.PodConditionType]corev1.ConditionStatus{}
+				for _, condition := range pod.Status.Conditions {
+					conditions[condition.Type] = condition.Status
+				}
+				return conditions
+			}
+			applyCondition(corev1.PodReadyToStartContainers, corev1.ConditionTrue)
+
+			By("cordoning the node")
+			node := &corev1.Node{}
+			Expect(k8sClient.Get(ctx, nodeNamespacedName, node)).To(Succeed())
+			node.Spec.Unschedulable = true
+			Expect(k8sClient.Update(ctx, node)).To(Succeed())
+			_, err := nodeReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: nodeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(podConditions()).To(Equal(map[corev1.PodConditionType]corev1.ConditionStatus{
+				corev1.PodReady:                  corev1.ConditionTrue,
+				corev1.PodReadyToStartContainers: corev1.ConditionTrue,
+				corev1.DisruptionTarget:          corev1.ConditionTrue,
+			}))
+
+			By("the other manager changing its condition")
+			applyCondition(corev1.PodReadyToStartContainers, corev1.ConditionFalse)
+			Expect(podConditions()).To(HaveKeyWithValue(corev1.DisruptionTarget, corev1.ConditionTrue))
+
+			By("uncordoning the node")
+			Expect(k8sClient.Get(ctx, nodeNamespacedName, node)).To(Succeed())
+			node.Spec.Unschedulable = false
+			Expect(k8sClient.Update(ctx, node)).To(Succeed())
+			_, err = nodeReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: nodeNamespacedName})
 			Expect(err).NotTo(HaveOccurred())
+			Expect(podConditions()).To(Equal(map[corev1.PodConditionType]corev1.ConditionStatus{
+				corev1.PodReady:                  corev1.ConditionTrue,
+				corev1.PodReadyToStartContainers: corev1.ConditionFalse,
+			}))
+		})
+
+		It("should ignore cordoned nodes outside the node selector", func() {
+			nodeReconciler := &NodeReconciler{
+				Client:       k8sClient,
+				Scheme:       scheme.Scheme,
+				NodeSelector: labels.SelectorFromSet(labels.Set{"agentpool": "user"}),
+			}
 
 			node := &corev1.Node{}
+			err := k8sClient.Get(ctx, nodeNamespacedName, node)
+			Expect(err).NotTo(HaveOccurred())
+			node.Spec.Unschedulable = true
+			Expect(k8sClient.Update(ctx, node)).To(Succeed())
+
+			result, err := nodeReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: nodeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.RequeueAfter).To(Equal(time.Duration(0)))
+
+			EvictionAutoScaler := &v1.EvictionAutoScaler{}
+			err = k8sClient.Get(ctx, typeNamespacedName, EvictionAutoScaler)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(EvictionAutoScaler.Status.LastEviction.PodName).To(BeEmpty())
+
+			By("labeling the node into scope")
 			err = k8sClient.Get(ctx, nodeNamespacedName, node)
 			Expect(err).NotTo(HaveOccurred())
+			node.Labels = map[string]string{"agentpool": "user"}
+			Expect(k8sClient.Update(ctx, node)).To(Succeed())
+
+			result, err = nodeReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: nodeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.RequeueAfter).To(Equal(drainResync))
+		})
+
+		It("should not write anything in dry run", func() {
+			recorder := record.NewFakeRecorder(10)
+			nodeReconciler := &NodeReconciler{
+				Client:   k8sClient,
+				Scheme:   scheme.Scheme,
+				Recorder: recorder,
+				DryRun:   true,
+			}
+
+			node := &corev1.Node{}
+			err := k8sClient.Get(ctx, nodeNamespacedName, node)
+			Expect(err).NotTo(HaveOccurred())
 			node.Spec.Unschedulable = true
+			Expect(k8sClient.Update(ctx, node)).To(Succeed())
 
-			err = k8sClient.Update(ctx, node)
+			_, err = nodeReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: nodeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			pod := &corev1.Pod{}
+			err = k8sClient.Get(ctx, podNamespacedName, pod)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(pod.Status.Conditions).To(HaveLen(1))
+
+			EvictionAutoScaler := &v1.EvictionAutoScaler{}
+			err = k8sClient.Get(ctx, typeNamespacedName, EvictionAutoScaler)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(EvictionAutoScaler.Status.LastEviction.PodName).To(BeEmpty())
+
+			Expect(recorder.Events).To(Receive(HavePrefix("Normal " + events.ReasonDryRun + " Would set DisruptionTarget")))
+			Expect(recorder.Events).To(Receive(HavePrefix("Normal " + events.ReasonDryRun + " Would record eviction")))
+		})
+
+		It("should skip pods in denied namespaces", func() {
+			nodeReconciler := &NodeReconciler{
+				Client:     k8sClient,
+				Scheme:     scheme.Scheme,
+				Namespaces: namespacefilter.New([]string{namespace}, []string{namespace}),
+			}
+
+			node := &corev1.Node{}
+			err := k8sClient.Get(ctx, nodeNamespacedName, node)
 			Expect(err).NotTo(HaveOccurred())
+			node.Spec.Unschedulable = true
+			Expect(k8sClient.Update(ctx, node)).To(Succeed())
+
 			result, err := nodeReconciler.Reconcile(ctx, reconcile.Request{
 				NamespacedName: nodeNamespacedName,
 			})
 			Expect(err).NotTo(HaveOccurred())
 			Expect(result.RequeueAfter).To(Equal(time.Duration(0)))
 
-			By("checking pod condition ")
+			pod := &corev1.Pod{}
 			err = k8sClient.Get(ctx, podNamespacedName, pod)
 			Expect(err).NotTo(HaveOccurred())
 			Expect(pod.Status.Conditions).To(HaveLen(1))
-			//First is still ready ignore it
-			Expect(pod.Status.Conditions[0].Type).To(Equal(corev1.PodReady))
 
+			EvictionAutoScaler := &v1.EvictionAutoScaler{}
+			err = k8sClient.Get(ctx, typeNamespacedName, EvictionAutoScaler)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(EvictionAutoScaler.Status.LastEviction.PodName).To(BeEmpty())
 		})
-	})
-})
+
+		It("should treat a drain taint as a cordon", func() {
+			nodeReconciler := &NodeReconciler{
+				Client:      k8sClient,
+				Scheme:      scheme.Scheme,
+				DrainTaints: DefaultDrainTaints,
+			}
+
+			node := &corev1.Node{}
+			err := k8sClient.Get(ctx, nodeNamespacedName, node)
+			Expect(err).NotTo(HaveOccurred())
+			node.Spec.Taints = []corev1.Taint{{
+				Key:    "ToBeDeletedByClusterAutoscaler",
+				Value:  "1700000000",
+				Effect: corev1.TaintEffectNoSchedule,
+			}}
+			Expect(k8sClient.Update(ctx, node)).To(Succeed())
+
+			result, err := nodeReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: nodeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.RequeueAfter).To(Equal(drainResync))
+
+			EvictionAutoScaler := &v1.EvictionAutoScaler{}
+			err = k8sClient.Get(ctx, typeNamespacedName, EvictionAutoScaler)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(EvictionAutoScaler.Status.LastEviction.PodName).To(Equal(podName))
+
+			By("removing the taint like an uncordon")
+			err = k8sClient.Get(ctx, nodeNamespacedName, node)
+			Expect(err).NotTo(HaveOccurred())
+			node.Spec.Taints = nil
+			Expect(k8sClient.Update(ctx, node)).To(Succeed())
+
+			result, err = nodeReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: nodeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.RequeueAfter).To(Equal(time.Duration(0)))
+		})
+
+		It("should release only the deleted node from the EvictionAutoScaler", func() {
+			nodeReconciler := &NodeReconciler{
+				Client: k8sClient,
+				Scheme: scheme.Scheme,
+			}
+
+			By("creating a second cordoned node with a pod for the same EvictionAutoScaler")
+			otherNodeName := rand.String(8)
+			otherNode := &corev1.Node{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: otherNodeName,
+				},
+				Spec: corev1.NodeSpec{Unschedulable: true},
+			}
+			Expect(k8sClient.Create(ctx, otherNode)).To(Succeed())
+			otherPod := &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "example-other-pod",
+					Namespace: namespace,
+					Labels: map[string]string{
+						"app": "example",
+					},
+				},
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{
+							Name:  "nginx",
+							Image: "nginx:latest",
+						},
+					},
+					NodeName: otherNodeName,
+				},
+			}
+			Expect(k8sClient.Create(ctx, otherPod)).To(Succeed())
+
+			node := &corev1.Node{}
+			err := k8sClient.Get(ctx, nodeNamespacedName, node)
+			Expect(err).NotTo(HaveOccurred())
+			node.Spec.Unschedulable = true
+			Expect(k8sClient.Update(ctx, node)).To(Succeed())
+
+			for _, name := range []string{nodeName, otherNodeName} {
+				_, err = nodeReconciler.Reconcile(ctx, reconcile.Request{
+					NamespacedName: types.NamespacedName{Name: name},
+				})
+				Expect(err).NotTo(HaveOccurred())
+			}
+
+			EvictionAutoScaler := &v1.EvictionAutoScaler{}
+			err = k8sClient.Get(ctx, typeNamespacedName, EvictionAutoScaler)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(EvictionAutoScaler.Status.DrainingNodes).To(ConsistOf(nodeName, otherNodeName))
+
+			By("deleting the first node")
+			Expect(k8sClient.Delete(ctx, node)).To(Succeed())
+			result, err := nodeReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: nodeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.RequeueAfter).To(Equal(time.Duration(0)))
+
+			err = k8sClient.Get(ctx, typeNamespacedName, EvictionAutoScaler)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(EvictionAutoScaler.Status.DrainingNodes).To(ConsistOf(otherNodeName))
+
+			By("moving the pod off the other node")
+			Expect(k8sClient.Delete(ctx, otherPod)).To(Succeed())
+			_, err = nodeReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: types.NamespacedName{Name: otherNodeName},
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			err = k8sClient.Get(ctx, typeNamespacedName, EvictionAutoScaler)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(EvictionAutoScaler.Status.DrainingNodes).To(BeEmpty())
+		})
+
+		It("should time the drain until the pods leave the node", func() {
+			nodeReconciler := &NodeReconciler{
+				Client: k8sClient,
+				Scheme: scheme.Scheme,
+			}
+			drained := drainObservations(metrics.DrainOutcomeDrained)
+
+			node := &corev1.Node{}
+			err := k8sClient.Get(ctx, nodeNamespacedName, node)
+			Expect(err).NotTo(HaveOccurred())
+			node.Spec.Unschedulable = true
+			Expect(k8sClient.Update(ctx, node)).To(Succeed())
+			_, err = nodeReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: nodeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			err = k8sClient.Get(ctx, nodeNamespacedName, node)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(node.Annotations).To(HaveKey(DrainStartAnnotationKey))
+
+			By("moving the pod off the node")
+			pod := &corev1.Pod{}
+			err = k8sClient.Get(ctx, podNamespacedName, pod)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(k8sClient.Delete(ctx, pod)).To(Succeed())
+			_, err = nodeReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: nodeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			err = k8sClient.Get(ctx, nodeNamespacedName, node)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(node.Annotations).NotTo(HaveKey(DrainStartAnnotationKey))
+			Expect(drainObservations(metrics.DrainOutcomeDrained)).To(Equal(drained + 1))
+		})
+
+		It("should only resync on the safety net however short the EvictionAutoScaler's cooldown", func() {
+			nodeReconciler := &NodeReconciler{
+				Client: k8sClient,
+				Scheme: scheme.Scheme,
+			}
+
+			EvictionAutoScaler := &v1.EvictionAutoScaler{}
+			err := k8sClient.Get(ctx, typeNamespacedName, EvictionAutoScaler)
+			Expect(err).NotTo(HaveOccurred())
+			EvictionAutoScaler.Spec.CooldownSeconds = int32Ptr(5)
+			Expect(k8sClient.Update(ctx, EvictionAutoScaler)).To(Succeed())
+
+			node := &corev1.Node{}
+			err = k8sClient.Get(ctx, nodeNamespacedName, node)
+			Expect(err).NotTo(HaveOccurred())
+			node.Spec.Unschedulable = true
+			Expect(k8sClient.Update(ctx, node)).To(Succeed())
+
+			result, err := nodeReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: nodeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.RequeueAfter).To(Equal(drainResync))
+		})
+
+		It("should not clobber concurrent changes when recording the last eviction", func() {
+			// simulate another writer changing the EvictionAutoScaler's status right before our first write
+			watchClient, err := client.NewWithWatch(cfg, client.Options{Scheme: scheme.Scheme})
+			Expect(err).NotTo(HaveOccurred())
+			raced := false
+			concurrentWriter := interceptor.NewClient(watchClient, interceptor.Funcs{
+				SubResourceUpdate: func(ctx context.Context, c client.Client, subResourceName string, obj client.Object, opts ...client.SubResourceUpdateOption) error {
+					if _, ok := obj.(*v1.EvictionAutoScaler); ok && !raced {
+						raced = true
+						current := &v1.EvictionAutoScaler{}
+						Expect(c.Get(ctx, client.ObjectKeyFromObject(obj), current)).To(Succeed())
+						current.Status.MinReplicas = 3
+						Expect(c.Status().Update(ctx, current)).To(Succeed())
+					}
+					return c.SubResource(subResourceName).Update(ctx, obj, opts...)
+				},
+			})
+			nodeReconciler := &NodeReconciler{
+				Client: concurrentWriter,
+				Scheme: scheme.Scheme,
+			}
+
+			node := &corev1.Node{}
+			err = k8sClient.Get(ctx, nodeNamespacedName, node)
+			Expect(err).NotTo(HaveOccurred())
+			node.Spec.Unschedulable = true
+			Expect(k8sClient.Update(ctx, node)).To(Succeed())
+
+			_, err = nodeReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: nodeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			EvictionAutoScaler := &v1.EvictionAutoScaler{}
+			err = k8sClient.Get(ctx, typeNamespacedName, EvictionAutoScaler)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(EvictionAutoScaler.Status.LastEviction.PodName).To(Equal(podName))
+			Expect(EvictionAutoScaler.Status.MinReplicas).To(Equal(int32(3)))
+		})
+
+		It("should reject a negative cooldown", func() {
+			EvictionAutoScaler := &v1.EvictionAutoScaler{}
+			err := k8sClient.Get(ctx, typeNamespacedName, EvictionAutoScaler)
+			Expect(err).NotTo(HaveOccurred())
+			EvictionAutoScaler.Spec.CooldownSeconds = int32Ptr(-1)
+			Expect(k8sClient.Update(ctx, EvictionAutoScaler)).NotTo(Succeed())
+		})
+
+		It("should handle cordon with no targetable pod", func() {
+			nodeReconciler := &NodeReconciler{
+				Client: k8sClient,
+				Scheme: scheme.Scheme,
+			}
+			_, err := nodeReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: nodeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			pod := &corev1.Pod{}
+			err = k8sClient.Get(ctx, podNamespacedName, pod)
+			Expect(err).NotTo(HaveOccurred())
+			pod.Labels = map[string]string{
+				"app": "notexample",
+			}
+			err = k8sClient.Update(ctx, pod)
+			Expect(err).NotTo(HaveOccurred())
+
+			node := &corev1.Node{}
+			err = k8sClient.Get(ctx, nodeNamespacedName, node)
+			Expect(err).NotTo(HaveOccurred())
+			node.Spec.Unschedulable = true
+
+			err = k8sClient.Update(ctx, node)
+			Expect(err).NotTo(HaveOccurred())
+			result, err := nodeReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: nodeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.RequeueAfter).To(Equal(time.Duration(0)))
+
+			By("checking pod condition ")
+			err = k8sClient.Get(ctx, podNamespacedName, pod)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(pod.Status.Conditions).To(HaveLen(1))
+			//First is still ready ignore it
+			Expect(pod.Status.Conditions[0].Type).To(Equal(corev1.PodReady))
+
+		})
+
+		It("should skip terminating pods on cordon", func() {
+			nodeReconciler := &NodeReconciler{
+				Client: k8sClient,
+				Scheme: scheme.Scheme,
+			}
+
+			By("deleting the pod while a finalizer holds it in terminating")
+			pod := &corev1.Pod{}
+			err := k8sClient.Get(ctx, podNamespacedName, pod)
+			Expect(err).NotTo(HaveOccurred())
+			pod.Finalizers = []string{"eviction-autoscaler.azure.com/test"}
+			Expect(k8sClient.Update(ctx, pod)).To(Succeed())
+			Expect(k8sClient.Delete(ctx, pod)).To(Succeed())
+			DeferCleanup(func() {
+				pod := &corev1.Pod{}
+				Expect(k8sClient.Get(ctx, podNamespacedName, pod)).To(Succeed())
+				pod.Finalizers = nil
+				Expect(k8sClient.Update(ctx, pod)).To(Succeed())
+			})
+
+			By("adding a pod that already succeeded")
+			donePod := &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "example-done-pod",
+					Namespace: namespace,
+					Labels: map[string]string{
+						"app": "example",
+					},
+				},
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{
+							Name:  "nginx",
+							Image: "nginx:latest",
+						},
+					},
+					NodeName: nodeName,
+				},
+			}
+			Expect(k8sClient.Create(ctx, donePod)).To(Succeed())
+			donePod.Status = corev1.PodStatus{Phase: corev1.PodSucceeded}
+			Expect(k8sClient.Status().Update(ctx, donePod)).To(Succeed())
+
+			node := &corev1.Node{}
+			err = k8sClient.Get(ctx, nodeNamespacedName, node)
+			Expect(err).NotTo(HaveOccurred())
+			node.Spec.Unschedulable = true
+			Expect(k8sClient.Update(ctx, node)).To(Succeed())
+
+			result, err := nodeReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: nodeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.RequeueAfter).To(Equal(time.Duration(0)))
+			Expect(counterValue(metrics.SkippedPodCounter, namespace, podutil.SkipReasonCompleted)).To(Equal(1.0))
+
+			EvictionAutoScaler := &v1.EvictionAutoScaler{}
+			err = k8sClient.Get(ctx, typeNamespacedName, EvictionAutoScaler)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(EvictionAutoScaler.Status.LastEviction.PodName).To(BeEmpty())
+		})
+
+		It("should skip pods annotated to be ignored on cordon", func() {
+			nodeReconciler := &NodeReconciler{
+				Client: k8sClient,
+				Scheme: scheme.Scheme,
+			}
+
+			pod := &corev1.Pod{}
+			err := k8sClient.Get(ctx, podNamespacedName, pod)
+			Expect(err).NotTo(HaveOccurred())
+			pod.Annotations = map[string]string{podutil.IgnoreAnnotationKey: "true"}
+			Expect(k8sClient.Update(ctx, pod)).To(Succeed())
+
+			node := &corev1.Node{}
+			err = k8sClient.Get(ctx, nodeNamespacedName, node)
+			Expect(err).NotTo(HaveOccurred())
+			node.Spec.Unschedulable = true
+			Expect(k8sClient.Update(ctx, node)).To(Succeed())
+
+			result, err := nodeReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: nodeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.RequeueAfter).To(Equal(time.Duration(0)))
+			Expect(counterValue(metrics.SkippedPodCounter, namespace, podutil.SkipReasonIgnored)).To(Equal(1.0))
+
+			err = k8sClient.Get(ctx, podNamespacedName, pod)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(pod.Status.Conditions).To(HaveLen(1))
+			EvictionAutoScaler := &v1.EvictionAutoScaler{}
+			err = k8sClient.Get(ctx, typeNamespacedName, EvictionAutoScaler)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(EvictionAutoScaler.Status.LastEviction.PodName).To(BeEmpty())
+		})
+
+		It("should skip job pods on cordon", func() {
+			nodeReconciler := &NodeReconciler{
+				Client: k8sClient,
+				Scheme: scheme.Scheme,
+			}
+
+			pod := &corev1.Pod{}
+			err := k8sClient.Get(ctx, podNamespacedName, pod)
+			Expect(err).NotTo(HaveOccurred())
+			pod.OwnerReferences = []metav1.OwnerReference{{
+				APIVersion: "batch/v1",
+				Kind:       "Job",
+				Name:       "example-job",
+				UID:        "example-job-uid",
+			}}
+			Expect(k8sClient.Update(ctx, pod)).To(Succeed())
+
+			node := &corev1.Node{}
+			err = k8sClient.Get(ctx, nodeNamespacedName, node)
+			Expect(err).NotTo(HaveOccurred())
+			node.Spec.Unschedulable = true
+			Expect(k8sClient.Update(ctx, node)).To(Succeed())
+
+			_, err = nodeReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: nodeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(counterValue(metrics.SkippedPodCounter, namespace, podutil.SkipReasonJob)).To(Equal(1.0))
+
+			err = k8sClient.Get(ctx, podNamespacedName, pod)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(pod.Status.Conditions).To(HaveLen(1))
+			EvictionAutoScaler := &v1.EvictionAutoScaler{}
+			err = k8sClient.Get(ctx, typeNamespacedName, EvictionAutoScaler)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(EvictionAutoScaler.Status.LastEviction.PodName).To(BeEmpty())
+		})
+
+		It("should record on the oldest EvictionAutoScaler when pdbs select the same pod", func() {
+			recorder := record.NewFakeRecorder(10)
+			nodeReconciler := &NodeReconciler{
+				Client:    k8sClient,
+				Scheme:    scheme.Scheme,
+				Recorder:  recorder,
+				Selectors: selectorcache.New(),
+			}
+
+			By("creating a newer EvictionAutoScaler whose pdb selects the same pod")
+			newerName := types.NamespacedName{Name: resourceName + "-newer", Namespace: namespace}
+			Expect(k8sClient.Create(ctx, &v1.EvictionAutoScaler{
+				ObjectMeta: metav1.ObjectMeta{Name: newerName.Name, Namespace: namespace},
+				Spec:       v1.EvictionAutoScalerSpec{TargetName: "exmple-whatever", TargetKind: "deployment"},
+			})).To(Succeed())
+			Expect(k8sClient.Create(ctx, &policyv1.PodDisruptionBudget{
+				ObjectMeta: metav1.ObjectMeta{Name: newerName.Name, Namespace: namespace},
+				Spec: policyv1.PodDisruptionBudgetSpec{
+					MinAvailable: &intstr.IntOrString{IntVal: 1},
+					Selector:     &metav1.LabelSelector{MatchLabels: map[string]string{"app": "example"}},
+				},
+			})).To(Succeed())
+
+			node := &corev1.Node{}
+			err := k8sClient.Get(ctx, nodeNamespacedName, node)
+			Expect(err).NotTo(HaveOccurred())
+			node.Spec.Unschedulable = true
+			Expect(k8sClient.Update(ctx, node)).To(Succeed())
+
+			_, err = nodeReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: nodeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(counterValue(metrics.ConflictingSelectorsCounter, namespace)).To(Equal(1.0))
+
+			By("recording the eviction only on the oldest")
+			oldest := &v1.EvictionAutoScaler{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, oldest)).To(Succeed())
+			Expect(oldest.Status.LastEviction.PodName).To(Equal(podName))
+			newer := &v1.EvictionAutoScaler{}
+			Expect(k8sClient.Get(ctx, newerName, newer)).To(Succeed())
+			Expect(newer.Status.LastEviction.PodName).To(BeEmpty())
+
+			By("setting ConflictingSelectors on both")
+			for _, EvictionAutoScaler := range []*v1.EvictionAutoScaler{oldest, newer} {
+				condition := meta.FindStatusCondition(EvictionAutoScaler.Status.Conditions, evictionutil.ConditionConflictingSelectors)
+				Expect(condition).NotTo(BeNil())
+				Expect(condition.Status).To(Equal(metav1.ConditionTrue))
+				Expect(condition.Message).To(ContainSubstring("oldest, " + resourceName))
+			}
+			var recorded []string
+			for len(recorder.Events) > 0 {
+				recorded = append(recorded, <-recorder.Events)
+			}
+			Expect(recorded).To(ContainElement(HavePrefix("Warning " + events.ReasonConflictingSelectors)))
+
+			By("clearing it once the newer pdb is gone")
+			Expect(k8sClient.Delete(ctx, &policyv1.PodDisruptionBudget{
+				ObjectMeta: metav1.ObjectMeta{Name: newerName.Name, Namespace: namespace},
+			})).To(Succeed())
+			_, err = nodeReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: nodeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(k8sClient.Get(ctx, typeNamespacedName, oldest)).To(Succeed())
+			Expect(meta.IsStatusConditionFalse(oldest.Status.Conditions, evictionutil.ConditionConflictingSelectors)).To(BeTrue())
+		})
+
+		It("should skip daemonset and mirror pods on cordon", func() {
+			nodeReconciler := &NodeReconciler{
+				Client: k8sClient,
+				Scheme: scheme.Scheme,
+			}
+
+			pod := &corev1.Pod{}
+			err := k8sClient.Get(ctx, podNamespacedName, pod)
+			Expect(err).NotTo(HaveOccurred())
+			pod.OwnerReferences = []metav1.OwnerReference{{
+				APIVersion: "apps/v1",
+				Kind:       "DaemonSet",
+				Name:       "example-ds",
+				UID:        "example-ds-uid",
+			}}
+			Expect(k8sClient.Update(ctx, pod)).To(Succeed())
+
+			By("creating a mirror pod on the same node")
+			mirrorPod := &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "example-mirror-pod",
+					Namespace: namespace,
+					Labels: map[string]string{
+						"app": "example",
+					},
+					Annotations: map[string]string{
+						corev1.MirrorPodAnnotationKey: "mirror",
+					},
+				},
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{
+							Name:  "nginx",
+							Image: "nginx:latest",
+						},
+					},
+					NodeName: nodeName,
+				},
+			}
+			Expect(k8sClient.Create(ctx, mirrorPod)).To(Succeed())
+
+			node := &corev1.Node{}
+			err = k8sClient.Get(ctx, nodeNamespacedName, node)
+			Expect(err).NotTo(HaveOccurred())
+			node.Spec.Unschedulable = true
+			Expect(k8sClient.Update(ctx, node)).To(Succeed())
+
+			result, err := nodeReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: nodeNamespacedName,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.RequeueAfter).To(Equal(time.Duration(0)))
+
+			By("checking neither pod got a disruption condition")
+			err = k8sClient.Get(ctx, podNamespacedName, pod)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(pod.Status.Conditions).To(HaveLen(1))
+			err = k8sClient.Get(ctx, client.ObjectKeyFromObject(mirrorPod), mirrorPod)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(mirrorPod.Status.Conditions).To(BeEmpty())
+
+			EvictionAutoScaler := &v1.EvictionAutoScaler{}
+			err = k8sClient.Get(ctx, typeNamespacedName, EvictionAutoScaler)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(EvictionAutoScaler.Status.LastEviction.PodName).To(BeEmpty())
+		})
+	})
+})
+
+// drainObservations is how many drain durations have been observed with outcome
+func drainObservations(outcome string) uint64 {
+	m := &dto.Metric{}
+	Expect(metrics.NodeDrainDuration.WithLabelValues(outcome).(prometheus.Histogram).Write(m)).To(Succeed())
+	return m.GetHistogram().GetSampleCount()
+}
+
+// TestNodeReconcileConcurrentNodes reconciles nodes draining pods of the same EvictionAutoScaler at once,
+// as --node-reconcile-concurrency does, and checks no node's status write is lost.
+func TestNodeReconcileConcurrentNodes(t *testing.T) {
+	const nodes = 8
+	ctx := context.Background()
+	testScheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(testScheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := v1.AddToScheme(testScheme); err != nil {
+		t.Fatal(err)
+	}
+	objs := []client.Object{
+		&v1.EvictionAutoScaler{
+			ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+			Spec:       v1.EvictionAutoScalerSpec{TargetName: "web", TargetKind: deploymentKind},
+		},
+		&policyv1.PodDisruptionBudget{
+			ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default", UID: "web-uid"},
+			Spec: policyv1.PodDisruptionBudgetSpec{
+				Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}},
+			},
+		},
+	}
+	for n := 0; n < nodes; n++ {
+		nodeName := fmt.Sprintf("node-%d", n)
+		objs = append(objs,
+			&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: nodeName}, Spec: corev1.NodeSpec{Unschedulable: true}},
+			&corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Name: "web-" + nodeName, Namespace: "default", Labels: map[string]string{"app": "web"}},
+				Spec:       corev1.PodSpec{NodeName: nodeName},
+			})
+	}
+	fakeClient := fake.NewClientBuilder().
+		WithScheme(testScheme).
+		WithIndex(&corev1.Pod{}, NodeNameIndex, podNodeName).
+		WithStatusSubresource(&corev1.Pod{}, &v1.EvictionAutoScaler{}).
+		WithInterceptorFuncs(interceptor.Funcs{SubResourcePatch: fakeApplyPodStatus()}).
+		WithObjects(objs...).
+		Build()
+	nodeReconciler := &NodeReconciler{Client: fakeClient, Scheme: testScheme, Selectors: selectorcache.New()}
+
+	errs := make(chan error, nodes)
+	for n := 0; n < nodes; n++ {
+		go func() {
+			_, err := nodeReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: types.NamespacedName{Name: fmt.Sprintf("node-%d", n)}})
+			errs <- err
+		}()
+	}
+	for n := 0; n < nodes; n++ {
+		if err := <-errs; err != nil {
+			t.Error(err)
+		}
+	}
+
+	EvictionAutoScaler := &v1.EvictionAutoScaler{}
+	if err := fakeClient.Get(ctx, types.NamespacedName{Name: "web", Namespace: "default"}, EvictionAutoScaler); err != nil {
+		t.Fatal(err)
+	}
+	if len(EvictionAutoScaler.Status.DrainingNodes) != nodes {
+		t.Errorf("got draining nodes %v, want all %d", EvictionAutoScaler.Status.DrainingNodes, nodes)
+	}
+}
+
+// TestNodePodConcurrency drains a node with the pods of two EvictionAutoScalers written on several workers. Every pod
+// gets its condition and is recorded once, in the order of the eviction times.
+func TestNodePodConcurrency(t *testing.T) {
+	const pods = 12
+	ctx := context.Background()
+	testScheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(testScheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := v1.AddToScheme(testScheme); err != nil {
+		t.Fatal(err)
+	}
+	objs := []client.Object{
+		&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "draining"}, Spec: corev1.NodeSpec{Unschedulable: true}},
+	}
+	apps := []string{"web", "api"}
+	for _, app := range apps {
+		objs = append(objs,
+			&v1.EvictionAutoScaler{
+				ObjectMeta: metav1.ObjectMeta{Name: app, Namespace: "busy"},
+				Spec:       v1.EvictionAutoScalerSpec{TargetName: app, TargetKind: deploymentKind},
+			},
+			&policyv1.PodDisruptionBudget{
+				ObjectMeta: metav1.ObjectMeta{Name: app, Namespace: "busy"},
+				Spec: policyv1.PodDisruptionBudgetSpec{
+					Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": app}},
+				},
+			})
+		for i := range pods {
+			objs = append(objs, &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("%s-%d", app, i), Namespace: "busy", Labels: map[string]string{"app": app}},
+				Spec:       corev1.PodSpec{NodeName: "draining"},
+			})
+		}
+	}
+	fakeClient := fake.NewClientBuilder().
+		WithScheme(testScheme).
+		WithIndex(&corev1.Pod{}, NodeNameIndex, podNodeName).
+		WithStatusSubresource(&corev1.Pod{}, &v1.EvictionAutoScaler{}).
+		WithInterceptorFuncs(interceptor.Funcs{SubResourcePatch: fakeApplyPodStatus()}).
+		WithObjects(objs...).
+		Build()
+	nodeReconciler := &NodeReconciler{Client: fakeClient, Scheme: testScheme, Selectors: selectorcache.New(), PodConcurrency: 8}
+	if _, err := nodeReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: types.NamespacedName{Name: "draining"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	podList := &corev1.PodList{}
+	if err := fakeClient.List(ctx, podList); err != nil {
+		t.Fatal(err)
+	}
+	for _, pod := range podList.Items {
+		if !slices.ContainsFunc(pod.Status.Conditions, func(condition corev1.PodCondition) bool {
+			return condition.Type == corev1.DisruptionTarget && condition.Status == corev1.ConditionTrue
+		}) {
+			t.Errorf("pod %s has no DisruptionTarget condition", pod.Name)
+		}
+	}
+	for _, app := range apps {
+		EvictionAutoScaler := &v1.EvictionAutoScaler{}
+		if err := fakeClient.Get(ctx, types.NamespacedName{Name: app, Namespace: "busy"}, EvictionAutoScaler); err != nil {
+			t.Fatal(err)
+		}
+		status := EvictionAutoScaler.Status
+		if !slices.Equal(status.DrainingNodes, []string{"draining"}) {
+			t.Errorf("%s: got draining nodes %v, want [draining]", app, status.DrainingNodes)
+		}
+		recorded := map[string]bool{}
+		for i, eviction := range status.RecentEvictions {
+			recorded[eviction.PodName] = true
+			if i > 0 && eviction.EvictionTime.Before(&status.RecentEvictions[i-1].EvictionTime) {
+				t.Errorf("%s: eviction %d of %s is older than the one before it", app, i, eviction.PodName)
+			}
+		}
+		if len(status.RecentEvictions) != pods || len(recorded) != pods {
+			t.Errorf("%s: got %d evictions of %d pods, want each of the %d pods once", app, len(status.RecentEvictions), len(recorded), pods)
+		}
+		if last := status.RecentEvictions[len(status.RecentEvictions)-1]; !last.Matches(status.LastEviction) {
+			t.Errorf("%s: got lastEviction %+v, want the newest recorded %+v", app, status.LastEviction, last)
+		}
+	}
+}
+
+// TestNodePodErrors fails the DisruptionTarget of one pod with an error that isn't skipped. The node's other pods are
+// still assisted and the error is returned for the node to be retried.
+func TestNodePodErrors(t *testing.T) {
+	ctx := context.Background()
+	testScheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(testScheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := v1.AddToScheme(testScheme); err != nil {
+		t.Fatal(err)
+	}
+	key := types.NamespacedName{Name: "web", Namespace: "pod-errors"}
+	objs := []client.Object{
+		&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "draining"}, Spec: corev1.NodeSpec{Unschedulable: true}},
+		&v1.EvictionAutoScaler{
+			ObjectMeta: metav1.ObjectMeta{Name: key.Name, Namespace: key.Namespace},
+			Spec:       v1.EvictionAutoScalerSpec{TargetName: "web", TargetKind: deploymentKind},
+		},
+		&policyv1.PodDisruptionBudget{
+			ObjectMeta: metav1.ObjectMeta{Name: key.Name, Namespace: key.Namespace},
+			Spec: policyv1.PodDisruptionBudgetSpec{
+				Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}},
+			},
+		},
+	}
+	for i := range 3 {
+		objs = append(objs, &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("web-%d", i), Namespace: key.Namespace, Labels: map[string]string{"app": "web"}},
+			Spec:       corev1.PodSpec{NodeName: "draining"},
+		})
+	}
+	apply := fakeApplyPodStatus()
+	fakeClient := fake.NewClientBuilder().
+		WithScheme(testScheme).
+		WithIndex(&corev1.Pod{}, NodeNameIndex, podNodeName).
+		WithStatusSubresource(&corev1.Pod{}, &v1.EvictionAutoScaler{}).
+		WithInterceptorFuncs(interceptor.Funcs{
+			SubResourcePatch: func(ctx context.Context, c client.Client, subResourceName string, obj client.Object, patch client.Patch, opts ...client.SubResourcePatchOption) error {
+				if obj.GetName() == "web-0" {
+					return errors.NewInternalError(fmt.Errorf("apiserver hiccup on %s", obj.GetName()))
+				}
+				return apply(ctx, c, subResourceName, obj, patch, opts...)
+			},
+		}).
+		WithObjects(objs...).
+		Build()
+	nodeReconciler := &NodeReconciler{Client: fakeClient, Scheme: testScheme, Selectors: selectorcache.New()}
+	_, err := nodeReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: types.NamespacedName{Name: "draining"}})
+	if err == nil || !strings.Contains(err.Error(), "web-0") {
+		t.Fatalf("got error %v, want the failure of web-0", err)
+	}
+
+	EvictionAutoScaler := &v1.EvictionAutoScaler{}
+	if err := fakeClient.Get(ctx, key, EvictionAutoScaler); err != nil {
+		t.Fatal(err)
+	}
+	var recorded []string
+	for _, eviction := range EvictionAutoScaler.Status.RecentEvictions {
+		recorded = append(recorded, eviction.PodName)
+	}
+	slices.Sort(recorded)
+	if !slices.Equal(recorded, []string{"web-1", "web-2"}) {
+		t.Errorf("got evictions of %v, want the pods that didn't fail", recorded)
+	}
+	m := &dto.Metric{}
+	if err := metrics.NodePodErrorCounter.WithLabelValues(key.Namespace, metrics.PodOperationCondition).Write(m); err != nil {
+		t.Fatal(err)
+	}
+	if got := m.GetCounter().GetValue(); got != 1 {
+		t.Errorf("got %v pod errors counted, want 1", got)
+	}
+}
+
+// TestPodConditionsForbidden checks a node's pods are still recorded for surging without patch on pods/status, and
+// that after the first Forbidden no more pod status writes are tried.
+func TestPodConditionsForbidden(t *testing.T) {
+	ctx := context.Background()
+	testScheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(testScheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := v1.AddToScheme(testScheme); err != nil {
+		t.Fatal(err)
+	}
+	for _, disabled := range []bool{false, true} {
+		key := types.NamespacedName{Name: "web", Namespace: "forbidden"}
+		objs := []client.Object{
+			&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "draining"}, Spec: corev1.NodeSpec{Unschedulable: true}},
+			&v1.EvictionAutoScaler{
+				ObjectMeta: metav1.ObjectMeta{Name: key.Name, Namespace: key.Namespace},
+				Spec:       v1.EvictionAutoScalerSpec{TargetName: "web", TargetKind: deploymentKind},
+			},
+			&policyv1.PodDisruptionBudget{
+				ObjectMeta: metav1.ObjectMeta{Name: key.Name, Namespace: key.Namespace},
+				Spec: policyv1.PodDisruptionBudgetSpec{
+					Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}},
+				},
+			},
+		}
+		for i := range 3 {
+			objs = append(objs, &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("web-%d", i), Namespace: key.Namespace, Labels: map[string]string{"app": "web"}},
+				Spec:       corev1.PodSpec{NodeName: "draining"},
+			})
+		}
+		patches := 0
+		fakeClient := fake.NewClientBuilder().
+			WithScheme(testScheme).
+			WithIndex(&corev1.Pod{}, NodeNameIndex, podNodeName).
+			WithStatusSubresource(&corev1.Pod{}, &v1.EvictionAutoScaler{}).
+			WithInterceptorFuncs(interceptor.Funcs{
+				SubResourcePatch: func(ctx context.Context, c client.Client, subResourceName string, obj client.Object, patch client.Patch, opts ...client.SubResourcePatchOption) error {
+					patches++
+					return errors.NewForbidden(corev1.Resource("pods/status"), obj.GetName(), fmt.Errorf("no patch on pods/status"))
+				},
+			}).
+			WithObjects(objs...).
+			Build()
+		nodeReconciler := &NodeReconciler{Client: fakeClient, Scheme: testScheme, Selectors: selectorcache.New(), PodConditionWrites: &PodConditionWrites{Disabled: disabled}}
+		if _, err := nodeReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: types.NamespacedName{Name: "draining"}}); err != nil {
+			t.Fatalf("disabled %v: got error %v, want the node assisted without pod conditions", disabled, err)
+		}
+
+		EvictionAutoScaler := &v1.EvictionAutoScaler{}
+		if err := fakeClient.Get(ctx, key, EvictionAutoScaler); err != nil {
+			t.Fatal(err)
+		}
+		if len(EvictionAutoScaler.Status.RecentEvictions) != 3 {
+			t.Errorf("disabled %v: got evictions %+v, want all 3 pods recorded", disabled, EvictionAutoScaler.Status.RecentEvictions)
+		}
+		if want := map[bool]int{false: 1, true: 0}[disabled]; patches != want {
+			t.Errorf("disabled %v: got %d pod status patches, want %d", disabled, patches, want)
+		}
+	}
+	m := &dto.Metric{}
+	if err := metrics.PodConditionWritesDisabledGauge.Write(m); err != nil {
+		t.Fatal(err)
+	}
+	if got := m.GetGauge().GetValue(); got != 1 {
+		t.Errorf("got eviction_autoscaler_pod_condition_writes_disabled %v, want 1 once pods/status was forbidden", got)
+	}
+}
+
+// TestEvictionAllowed checks a node's pods are only assisted when their pdb won't let the drain evict them all, or a
+// drain of the node was already being assisted. Pods that aren't Ready are left alone when the pdb's
+// unhealthyPodEvictionPolicy is AlwaysAllow, and assisted as usual when it is unset as on older api servers.
+func TestEvictionAllowed(t *testing.T) {
+	ctx := context.Background()
+	testScheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(testScheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := v1.AddToScheme(testScheme); err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		name          string
+		allowed       int32
+		drainingNodes []string
+		policy        *policyv1.UnhealthyPodEvictionPolicyType
+		unhealthy     bool
+		assisted      bool
+	}{
+		{name: "allowed", allowed: 2},
+		{name: "blocked", allowed: 1, assisted: true},
+		{name: "already-draining", allowed: 2, drainingNodes: []string{"draining"}, assisted: true},
+		{name: "unhealthy-always-allow", policy: ptr.To(policyv1.AlwaysAllow), unhealthy: true},
+		{name: "healthy-always-allow", policy: ptr.To(policyv1.AlwaysAllow), assisted: true},
+		{name: "unhealthy-policy-unset", unhealthy: true, assisted: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			key := types.NamespacedName{Name: "web", Namespace: "allowed-" + tc.name}
+			objs := []client.Object{
+				&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "draining"}, Spec: corev1.NodeSpec{Unschedulable: true}},
+				&v1.EvictionAutoScaler{
+					ObjectMeta: metav1.ObjectMeta{Name: key.Name, Namespace: key.Namespace},
+					Spec:       v1.EvictionAutoScalerSpec{TargetName: "web", TargetKind: deploymentKind},
+					Status:     v1.EvictionAutoScalerStatus{DrainingNodes: tc.drainingNodes},
+				},
+				&policyv1.PodDisruptionBudget{
+					ObjectMeta: metav1.ObjectMeta{Name: key.Name, Namespace: key.Namespace},
+					Spec: policyv1.PodDisruptionBudgetSpec{
+						Selector:                   &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}},
+						UnhealthyPodEvictionPolicy: tc.policy,
+					},
+					Status: policyv1.PodDisruptionBudgetStatus{DisruptionsAllowed: tc.allowed},
+				},
+			}
+			ready := corev1.ConditionTrue
+			if tc.unhealthy {
+				ready = corev1.ConditionFalse
+			}
+			for i := range 2 {
+				objs = append(objs, &corev1.Pod{
+					ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("web-%d", i), Namespace: key.Namespace, Labels: map[string]string{"app": "web"}},
+					Spec:       corev1.PodSpec{NodeName: "draining"},
+					Status:     corev1.PodStatus{Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: ready}}},
+				})
+			}
+			fakeClient := fake.NewClientBuilder().
+				WithScheme(testScheme).
+				WithIndex(&corev1.Pod{}, NodeNameIndex, podNodeName).
+				WithStatusSubresource(&corev1.Pod{}, &v1.EvictionAutoScaler{}).
+				WithInterceptorFuncs(interceptor.Funcs{SubResourcePatch: fakeApplyPodStatus()}).
+				WithObjects(objs...).
+				Build()
+			nodeReconciler := &NodeReconciler{Client: fakeClient, Scheme: testScheme, Selectors: selectorcache.New()}
+			if _, err := nodeReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: types.NamespacedName{Name: "draining"}}); err != nil {
+				t.Fatal(err)
+			}
+
+			podList := &corev1.PodList{}
+			if err := fakeClient.List(ctx, podList, client.InNamespace(key.Namespace)); err != nil {
+				t.Fatal(err)
+			}
+			for _, pod := range podList.Items {
+				if got := slices.ContainsFunc(pod.Status.Conditions, func(condition corev1.PodCondition) bool {
+					return condition.Type == corev1.DisruptionTarget
+				}); got != tc.assisted {
+					t.Errorf("pod %s got DisruptionTarget %v, want %v", pod.Name, got, tc.assisted)
+				}
+			}
+			EvictionAutoScaler := &v1.EvictionAutoScaler{}
+			if err := fakeClient.Get(ctx, key, EvictionAutoScaler); err != nil {
+				t.Fatal(err)
+			}
+			if recorded := len(EvictionAutoScaler.Status.RecentEvictions) > 0; recorded != tc.assisted {
+				t.Errorf("got evictions %v, want recorded %v", EvictionAutoScaler.Status.RecentEvictions, tc.assisted)
+			}
+			reason := metrics.SkipPodEvictionAllowed
+			if tc.policy != nil && tc.unhealthy {
+				reason = metrics.SkipPodUnhealthyEvictionAllowed
+			}
+			m := &dto.Metric{}
+			if err := metrics.SkippedPodCounter.WithLabelValues(key.Namespace, reason).Write(m); err != nil {
+				t.Fatal(err)
+			}
+			want := 0.0
+			if !tc.assisted {
+				want = 2
+			}
+			if m.GetCounter().GetValue() != want {
+				t.Errorf("got %v pods skipped for %s, want %v", m.GetCounter().GetValue(), reason, want)
+			}
+		})
+	}
+}
+
+// TestAssistedPodNode checks pods leaving a node we drain pods for requeue it and no other pod event does.
+func TestAssistedPodNode(t *testing.T) {
+	ctx := context.Background()
+	testScheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(testScheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := v1.AddToScheme(testScheme); err != nil {
+		t.Fatal(err)
+	}
+	web := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default", Labels: map[string]string{"app": "web"}},
+		Spec:       corev1.PodSpec{NodeName: "draining"},
+	}
+	other := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: "default"},
+		Spec:       corev1.PodSpec{NodeName: "cordoned"},
+	}
+	fakeClient := fake.NewClientBuilder().
+		WithScheme(testScheme).
+		WithIndex(&corev1.Pod{}, NodeNameIndex, podNodeName).
+		WithStatusSubresource(&corev1.Pod{}, &v1.EvictionAutoScaler{}).
+		WithInterceptorFuncs(interceptor.Funcs{SubResourcePatch: fakeApplyPodStatus()}).
+		WithObjects(
+			&v1.EvictionAutoScaler{
+				ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+				Spec:       v1.EvictionAutoScalerSpec{TargetName: "web", TargetKind: deploymentKind},
+			},
+			&policyv1.PodDisruptionBudget{
+				ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+				Spec: policyv1.PodDisruptionBudgetSpec{
+					Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}},
+				},
+			},
+			&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "draining"}, Spec: corev1.NodeSpec{Unschedulable: true}},
+			&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "cordoned"}, Spec: corev1.NodeSpec{Unschedulable: true}},
+			web, other,
+		).
+		Build()
+	nodeReconciler := &NodeReconciler{Client: fakeClient, Scheme: testScheme}
+	for _, name := range []string{"draining", "cordoned"} {
+		result, err := nodeReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: types.NamespacedName{Name: name}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := map[string]time.Duration{"draining": drainResync}[name]; result.RequeueAfter != want {
+			t.Errorf("node %s requeued after %s, want %s", name, result.RequeueAfter, want)
+		}
+	}
+
+	if got := nodeReconciler.assistedPodNode(ctx, web); len(got) != 1 || got[0].Name != "draining" {
+		t.Errorf("got %v for a pod on a node we drain pods for, want that node", got)
+	}
+	if got := nodeReconciler.assistedPodNode(ctx, other); len(got) != 0 {
+		t.Errorf("got %v for a pod on a cordoned node without pods for an EvictionAutoScaler", got)
+	}
+
+	terminating := web.DeepCopy()
+	terminating.DeletionTimestamp = &metav1.Time{Time: time.Now()}
+	succeeded := web.DeepCopy()
+	succeeded.Status.Phase = corev1.PodSucceeded
+	relabeled := web.DeepCopy()
+	relabeled.Labels = map[string]string{"app": "api"}
+	tests := []struct {
+		name string
+		pass bool
+		want bool
+	}{
+		{name: "create", pass: podLeft.Create(event.CreateEvent{Object: web})},
+		{name: "relabel", pass: podLeft.Update(event.UpdateEvent{ObjectOld: web, ObjectNew: relabeled})},
+		{name: "terminating", pass: podLeft.Update(event.UpdateEvent{ObjectOld: web, ObjectNew: terminating}), want: true},
+		{name: "still terminating", pass: podLeft.Update(event.UpdateEvent{ObjectOld: terminating, ObjectNew: terminating})},
+		{name: "succeeded", pass: podLeft.Update(event.UpdateEvent{ObjectOld: web, ObjectNew: succeeded}), want: true},
+		{name: "delete", pass: podLeft.Delete(event.DeleteEvent{Object: web}), want: true},
+	}
+	for _, test := range tests {
+		if test.pass != test.want {
+			t.Errorf("%s: got %v want %v", test.name, test.pass, test.want)
+		}
+	}
+}
+
+// TestDrainResyncBackoff checks a node whose pods stay put is rechecked less and less often, and from
+// drainResync again once a pod leaves or the node is uncordoned and cordoned again.
+func TestDrainResyncBackoff(t *testing.T) {
+	ctx := context.Background()
+	testScheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(testScheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := v1.AddToScheme(testScheme); err != nil {
+		t.Fatal(err)
+	}
+	pod := func(name string) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", Labels: map[string]string{"app": "web"}},
+			Spec:       corev1.PodSpec{NodeName: "stuck"},
+		}
+	}
+	fakeClient := fake.NewClientBuilder().
+		WithScheme(testScheme).
+		WithIndex(&corev1.Pod{}, NodeNameIndex, podNodeName).
+		WithStatusSubresource(&corev1.Pod{}, &v1.EvictionAutoScaler{}).
+		WithInterceptorFuncs(interceptor.Funcs{SubResourcePatch: fakeApplyPodStatus()}).
+		WithObjects(
+			&v1.EvictionAutoScaler{
+				ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+				Spec:       v1.EvictionAutoScalerSpec{TargetName: "web", TargetKind: deploymentKind},
+			},
+			&policyv1.PodDisruptionBudget{
+				ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+				Spec: policyv1.PodDisruptionBudgetSpec{
+					Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}},
+				},
+			},
+			&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "stuck"}, Spec: corev1.NodeSpec{Unschedulable: true}},
+			pod("web-1"), pod("web-2"),
+		).
+		Build()
+	nodeReconciler := &NodeReconciler{Client: fakeClient, Scheme: testScheme, MaxDrainResync: 3 * drainResync}
+	request := reconcile.Request{NamespacedName: types.NamespacedName{Name: "stuck"}}
+	expectResync := func(step string, want time.Duration) {
+		t.Helper()
+		result, err := nodeReconciler.Reconcile(ctx, request)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.RequeueAfter != want {
+			t.Errorf("%s: requeued after %s, want %s", step, result.RequeueAfter, want)
+		}
+	}
+	setCordon := func(unschedulable bool) {
+		t.Helper()
+		node := &corev1.Node{}
+		if err := fakeClient.Get(ctx, request.NamespacedName, node); err != nil {
+			t.Fatal(err)
+		}
+		node.Spec.Unschedulable = unschedulable
+		if err := fakeClient.Update(ctx, node); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	expectResync("first look", drainResync)
+	expectResync("no pod left", 2*drainResync)
+	expectResync("still no pod left", 3*drainResync) // capped
+	expectResync("capped", 3*drainResync)
+
+	if err := fakeClient.Delete(ctx, pod("web-2")); err != nil {
+		t.Fatal(err)
+	}
+	expectResync("pod left", drainResync)
+	expectResync("no pod left since", 2*drainResync)
+
+	setCordon(false)
+	expectResync("uncordoned", 0)
+	setCordon(true)
+	expectResync("cordoned again", drainResync)
+
+	nodeReconciler.MaxDrainResync = 0
+	expectResync("no backoff", drainResync)
+}
+
+// TestNodeDisabled checks a node annotated disabled mid drain is let go: its pods' conditions cleared
+// and the EvictionAutoScaler no longer holding its surge for it.
+func TestNodeDisabled(t *testing.T) {
+	ctx := context.Background()
+	testScheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(testScheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := v1.AddToScheme(testScheme); err != nil {
+		t.Fatal(err)
+	}
+	podKey := types.NamespacedName{Name: "web", Namespace: "default"}
+	fakeClient := fake.NewClientBuilder().
+		WithScheme(testScheme).
+		WithIndex(&corev1.Pod{}, NodeNameIndex, podNodeName).
+		WithStatusSubresource(&corev1.Pod{}, &v1.EvictionAutoScaler{}).
+		WithInterceptorFuncs(interceptor.Funcs{SubResourcePatch: fakeApplyPodStatus()}).
+		WithObjects(
+			&v1.EvictionAutoScaler{
+				ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+				Spec:       v1.EvictionAutoScalerSpec{TargetName: "web", TargetKind: deploymentKind},
+			},
+			&policyv1.PodDisruptionBudget{
+				ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+				Spec: policyv1.PodDisruptionBudgetSpec{
+					Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}},
+				},
+			},
+			&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "debug"}, Spec: corev1.NodeSpec{Unschedulable: true}},
+			&corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Name: podKey.Name, Namespace: podKey.Namespace, Labels: map[string]string{"app": "web"}},
+				Spec:       corev1.PodSpec{NodeName: "debug"},
+			},
+		).
+		Build()
+	nodeReconciler := &NodeReconciler{Client: fakeClient, Scheme: testScheme}
+	request := reconcile.Request{NamespacedName: types.NamespacedName{Name: "debug"}}
+	if _, err := nodeReconciler.Reconcile(ctx, request); err != nil {
+		t.Fatal(err)
+	}
+
+	node := &corev1.Node{}
+	if err := fakeClient.Get(ctx, request.NamespacedName, node); err != nil {
+		t.Fatal(err)
+	}
+	node.Annotations[NodeDisabledAnnotationKey] = "true" // the drain start annotation is already there
+	if err := fakeClient.Update(ctx, node); err != nil {
+		t.Fatal(err)
+	}
+	disabledSkips := func() float64 {
+		m := &dto.Metric{}
+		if err := metrics.SkippedNodeCounter.WithLabelValues(metrics.SkipNodeDisabled).Write(m); err != nil {
+			t.Fatal(err)
+		}
+		return m.GetCounter().GetValue()
+	}
+	skipped := disabledSkips()
+	result, err := nodeReconciler.Reconcile(ctx, request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.RequeueAfter != 0 {
+		t.Errorf("disabled node requeued after %s", result.RequeueAfter)
+	}
+	if got := disabledSkips(); got != skipped+1 {
+		t.Errorf("got %v disabled node skips, want %v", got, skipped+1)
+	}
+
+	pod := &corev1.Pod{}
+	if err := fakeClient.Get(ctx, podKey, pod); err != nil {
+		t.Fatal(err)
+	}
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == corev1.DisruptionTarget && condition.Status == corev1.ConditionTrue {
+			t.Errorf("pod kept condition %+v on a disabled node", condition)
+		}
+	}
+	EvictionAutoScaler := &v1.EvictionAutoScaler{}
+	if err := fakeClient.Get(ctx, types.NamespacedName{Name: "web", Namespace: "default"}, EvictionAutoScaler); err != nil {
+		t.Fatal(err)
+	}
+	if len(EvictionAutoScaler.Status.DrainingNodes) != 0 || EvictionAutoScaler.Status.DrainedTime.IsZero() {
+		t.Errorf("got draining nodes %v drained at %v, want the node released so the surge can scale down",
+			EvictionAutoScaler.Status.DrainingNodes, EvictionAutoScaler.Status.DrainedTime)
+	}
+	if err := fakeClient.Get(ctx, request.NamespacedName, node); err != nil {
+		t.Fatal(err)
+	}
+	if _, found := node.Annotations[DrainStartAnnotationKey]; found {
+		t.Error("disabled node kept its drain start")
+	}
+}
+
+func TestDrainTrigger(t *testing.T) {
+	ready := func(status corev1.ConditionStatus, age time.Duration) *corev1.Node {
+		return &corev1.Node{Status: corev1.NodeStatus{Conditions: []corev1.NodeCondition{{
+			Type: corev1.NodeReady, Status: status, LastTransitionTime: metav1.NewTime(time.Now().Add(-age)),
+		}}}}
+	}
+	outOfService := &corev1.Node{Spec: corev1.NodeSpec{Taints: []corev1.Taint{{Key: corev1.TaintNodeOutOfService, Effect: corev1.TaintEffectNoExecute}}}}
+	tests := []struct {
+		name     string
+		node     *corev1.Node
+		failures bool
+		want     string
+		wait     time.Duration
+	}{
+		{name: "cordoned", node: &corev1.Node{Spec: corev1.NodeSpec{Unschedulable: true}}, want: metrics.DrainTriggerCordon},
+		{name: "drain taint", node: &corev1.Node{Spec: corev1.NodeSpec{Taints: []corev1.Taint{{Key: "karpenter.sh/disrupted"}}}}, want: metrics.DrainTriggerTaint},
+		{name: "out of service without failure triggers", node: outOfService},
+		{name: "out of service", node: outOfService, failures: true, want: metrics.DrainTriggerOutOfService},
+		{name: "not ready without failure triggers", node: ready(corev1.ConditionFalse, time.Hour)},
+		{name: "not ready past the window", node: ready(corev1.ConditionFalse, 3*time.Minute), failures: true, want: metrics.DrainTriggerNotReady},
+		{name: "unknown past the window", node: ready(corev1.ConditionUnknown, 3*time.Minute), failures: true, want: metrics.DrainTriggerNotReady},
+		{name: "not ready within the window", node: ready(corev1.ConditionFalse, time.Minute), failures: true, wait: time.Minute},
+		{name: "ready", node: ready(corev1.ConditionTrue, time.Hour), failures: true},
+		{name: "never ready", node: &corev1.Node{}, failures: true},
+	}
+	for _, test := range tests {
+		r := &NodeReconciler{DrainTaints: DefaultDrainTaints, NodeFailureTriggers: test.failures, NotReadyWindow: 2 * time.Minute}
+		if got := r.drainTrigger(test.node); got != test.want {
+			t.Errorf("%s: got trigger %q want %q", test.name, got, test.want)
+		}
+		if wait := r.notReadyWait(test.node); test.wait != 0 && (wait > test.wait || wait < test.wait-5*time.Second) {
+			t.Errorf("%s: got wait %s want about %s", test.name, wait, test.wait)
+		}
+	}
+}
+
+// BenchmarkNodeReconcile reconciles a cordoned node with several hundred pods spread over a few namespaces
+// that each have many EvictionAutoScalers and reports how many gets and lists each reconcile costs.
+
+func BenchmarkNodeReconcile(b *testing.B) {
+	const (
+		nodeName            = "bench-node"
+		namespaces          = 4
+		podsPerNamespace    = 100
+		scalersPerNamespace = 30
+	)
+	ctx := context.Background()
+	benchScheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(benchScheme); err != nil {
+		b.Fatal(err)
+	}
+	if err := v1.AddToScheme(benchScheme); err != nil {
+		b.Fatal(err)
+	}
+
+	objs := []client.Object{&corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: nodeName},
+		Spec:       corev1.NodeSpec{Unschedulable: true},
+	}}
+	for n := 0; n < namespaces; n++ {
+		namespace := fmt.Sprintf("bench-%d", n)
+		for s := 0; s < scalersPerNamespace; s++ {
+			name := fmt.Sprintf("app-%d", s)
+			objs = append(objs,
+				&v1.EvictionAutoScaler{
+					ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+					Spec:       v1.EvictionAutoScalerSpec{TargetName: name, TargetKind: deploymentKind},
+				},
+				&policyv1.PodDisruptionBudget{
+					ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+					Spec: policyv1.PodDisruptionBudgetSpec{
+						Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": name}},
+					},
+				})
+		}
+		for p := 0; p < podsPerNamespace; p++ {
+			objs = append(objs, &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      fmt.Sprintf("pod-%d", p),
+					Namespace: namespace,
+					Labels:    map[string]string{"app": fmt.Sprintf("app-%d", p%scalersPerNamespace)},
+				},
+				Spec: corev1.PodSpec{NodeName: nodeName},
+			})
+		}
+	}
+
+	var gets, lists atomic.Int64
+	fakeClient := fake.NewClientBuilder().
+		WithScheme(benchScheme).
+		WithIndex(&corev1.Pod{}, NodeNameIndex, podNodeName).
+		WithStatusSubresource(&corev1.Pod{}, &v1.EvictionAutoScaler{}).
+		WithObjects(objs...).
+		WithInterceptorFuncs(interceptor.Funcs{
+			SubResourcePatch: fakeApplyPodStatus(),
+			Get: func(ctx context.Context, c client.WithWatch, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+				gets.Add(1)
+				return c.Get(ctx, key, obj, opts...)
+			},
+			List: func(ctx context.Context, c client.WithWatch, list client.ObjectList, opts ...client.ListOption) error {
+				lists.Add(1)
+				return c.List(ctx, list, opts...)
+			},
+		}).
+		Build()
+	nodeReconciler := &NodeReconciler{Client: fakeClient, Scheme: benchScheme}
+	req := reconcile.Request{NamespacedName: types.NamespacedName{Name: nodeName}}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := nodeReconciler.Reconcile(ctx, req); err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.ReportMetric(float64(gets.Load())/float64(b.N), "gets/op")
+	b.ReportMetric(float64(lists.Load())/float64(b.N), "lists/op")
+}
+
+// TestDrainBlockingAnnotations checks a pod that won't be evicted for its annotation gets no surge and is counted
+// apart from pods held by their pdb.
+func TestDrainBlockingAnnotations(t *testing.T) {
+	ctx := context.Background()
+	testScheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(testScheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := v1.AddToScheme(testScheme); err != nil {
+		t.Fatal(err)
+	}
+	pinned := types.NamespacedName{Name: "pinned", Namespace: "blockers"}
+	fakeClient := fake.NewClientBuilder().
+		WithScheme(testScheme).
+		WithIndex(&corev1.Pod{}, NodeNameIndex, podNodeName).
+		WithStatusSubresource(&corev1.Pod{}, &v1.EvictionAutoScaler{}).
+		WithInterceptorFuncs(interceptor.Funcs{SubResourcePatch: fakeApplyPodStatus()}).
+		WithObjects(
+			&v1.EvictionAutoScaler{
+				ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "blockers"},
+				Spec:       v1.EvictionAutoScalerSpec{TargetName: "web", TargetKind: deploymentKind},
+			},
+			&policyv1.PodDisruptionBudget{
+				ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "blockers"},
+				Spec: policyv1.PodDisruptionBudgetSpec{
+					Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}},
+				},
+			},
+			&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "draining"}, Spec: corev1.NodeSpec{Unschedulable: true}},
+			&corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Name: pinned.Name, Namespace: pinned.Namespace, Labels: map[string]string{"app": "web"},
+					Annotations: map[string]string{"karpenter.sh/do-not-disrupt": "true"}},
+				Spec: corev1.PodSpec{NodeName: "draining"},
+			},
+			&corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "blockers", Labels: map[string]string{"app": "web"}},
+				Spec:       corev1.PodSpec{NodeName: "draining"},
+			},
+		).
+		Build()
+	recorder := record.NewFakeRecorder(10)
+	nodeReconciler := &NodeReconciler{Client: fakeClient, Scheme: testScheme, Recorder: recorder,
+		Selectors: selectorcache.New(), DrainBlockingAnnotations: DefaultDrainBlockingAnnotations}
+	blocked := func(blocker string) float64 {
+		m := &dto.Metric{}
+		if err := metrics.DrainBlockedPodCounter.WithLabelValues("blockers", blocker).Write(m); err != nil {
+			t.Fatal(err)
+		}
+		return m.GetCounter().GetValue()
+	}
+	if _, err := nodeReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: types.NamespacedName{Name: "draining"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := blocked(metrics.DrainBlockerAnnotation); got != 1 {
+		t.Errorf("got %v pods blocked by annotation, want 1", got)
+	}
+	if got := blocked(metrics.DrainBlockerPDB); got != 1 {
+		t.Errorf("got %v pods blocked by pdb, want 1", got)
+	}
+	pod := &corev1.Pod{}
+	if err := fakeClient.Get(ctx, pinned, pod); err != nil {
+		t.Fatal(err)
+	}
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == corev1.DisruptionTarget {
+			t.Errorf("pod blocked by its annotation got condition %+v", condition)
+		}
+	}
+	EvictionAutoScaler := &v1.EvictionAutoScaler{}
+	if err := fakeClient.Get(ctx, types.NamespacedName{Name: "web", Namespace: "blockers"}, EvictionAutoScaler); err != nil {
+		t.Fatal(err)
+	}
+	if EvictionAutoScaler.Status.LastEviction.PodName != "web" {
+		t.Errorf("got last eviction %+v, want the pod without the annotation", EvictionAutoScaler.Status.LastEviction)
+	}
+	found := false
+	for len(recorder.Events) > 0 {
+		if strings.Contains(<-recorder.Events, events.ReasonDrainBlockedByAnnotation) {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("no %s event", events.ReasonDrainBlockedByAnnotation)
+	}
+}
+
+// TestPodConditionApply checks our DisruptionTarget condition leaves the conditions others write alone, a pod whose
+// DisruptionTarget is a real eviction's is skipped rather than failing the rest of the node, and uncordon only
+// drops the condition we own.
+func TestPodConditionApply(t *testing.T) {
+	ctx := context.Background()
+	testScheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(testScheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := v1.AddToScheme(testScheme); err != nil {
+		t.Fatal(err)
+	}
+	racy := types.NamespacedName{Name: "racy", Namespace: "conditions"}
+	evicted := types.NamespacedName{Name: "evicted", Namespace: "conditions"}
+	upgraded := types.NamespacedName{Name: "upgraded", Namespace: "conditions"}
+	objects := []client.Object{&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "draining"}, Spec: corev1.NodeSpec{Unschedulable: true}}}
+	for _, name := range []string{racy.Name, evicted.Name, upgraded.Name} {
+		objects = append(objects,
+			&v1.EvictionAutoScaler{
+				ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "conditions"},
+				Spec:       v1.EvictionAutoScalerSpec{TargetName: name, TargetKind: deploymentKind},
+			},
+			&policyv1.PodDisruptionBudget{
+				ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "conditions"},
+				Spec: policyv1.PodDisruptionBudgetSpec{
+					Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": name}},
+				},
+			})
+	}
+	objects = append(objects,
+		&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: racy.Name, Namespace: racy.Namespace, Labels: map[string]string{"app": racy.Name}},
+			Spec:       corev1.PodSpec{NodeName: "draining"},
+		},
+		&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: evicted.Name, Namespace: evicted.Namespace, Labels: map[string]string{"app": evicted.Name}},
+			Spec:       corev1.PodSpec{NodeName: "draining"},
+			Status: corev1.PodStatus{Conditions: []corev1.PodCondition{
+				{Type: corev1.DisruptionTarget, Status: corev1.ConditionTrue, Reason: "EvictionByEvictionAPI", Message: "Eviction API: evicting"},
+			}},
+		},
+		// written with an update before we moved to apply, so not owned by FieldManager
+		&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: upgraded.Name, Namespace: upgraded.Namespace},
+			Spec:       corev1.PodSpec{NodeName: "draining"},
+			Status: corev1.PodStatus{Conditions: []corev1.PodCondition{
+				{Type: corev1.DisruptionTarget, Status: corev1.ConditionTrue, Reason: podutil.CordonDisruptionReason, Message: podutil.CordonDisruptionMessage},
+			}},
+		})
+	apply := fakeApplyPodStatus()
+	raced := false
+	fakeClient := fake.NewClientBuilder().
+		WithScheme(testScheme).
+		WithIndex(&corev1.Pod{}, NodeNameIndex, podNodeName).
+		WithStatusSubresource(&corev1.Pod{}, &v1.EvictionAutoScaler{}).
+		WithObjects(objects...).
+		WithInterceptorFuncs(interceptor.Funcs{
+			SubResourcePatch: func(ctx context.Context, c client.Client, subResourceName string, obj client.Object, patch client.Patch, opts ...client.SubResourcePatchOption) error {
+				switch obj.GetName() {
+				case racy.Name:
+					// the kubelet writes the racy pod's status right before ours
+					if !raced {
+						raced = true
+						current := &corev1.Pod{}
+						if err := c.Get(ctx, racy, current); err != nil {
+							return err
+						}
+						current.Status.Conditions = append(current.Status.Conditions, corev1.PodCondition{Type: corev1.PodReady, Status: corev1.ConditionTrue})
+						if err := c.Status().Update(ctx, current); err != nil {
+							return err
+						}
+					}
+				case evicted.Name:
+					// kube-controller-manager owns the evicted pod's DisruptionTarget
+					return errors.NewConflict(corev1.Resource("pods"), evicted.Name, fmt.Errorf("conflict with %q", "kube-controller-manager"))
+				}
+				return apply(ctx, c, subResourceName, obj, patch, opts...)
+			},
+		}).
+		Build()
+	nodeReconciler := &NodeReconciler{Client: fakeClient, Scheme: testScheme, Selectors: selectorcache.New()}
+	failures := func() float64 {
+		m := &dto.Metric{}
+		if err := metrics.PodConditionUpdateFailureCounter.WithLabelValues("conditions").Write(m); err != nil {
+			t.Fatal(err)
+		}
+		return m.GetCounter().GetValue()
+	}
+	conditions := func(key types.NamespacedName) map[corev1.PodConditionType]corev1.PodCondition {
+		pod := &corev1.Pod{}
+		if err := fakeClient.Get(ctx, key, pod); err != nil {
+			t.Fatal(err)
+		}
+		byType := map[corev1.PodConditionType]corev1.PodCondition{}
+		for _, condition := range pod.Status.Conditions {
+			byType[condition.Type] = condition
+		}
+		return byType
+	}
+	request := reconcile.Request{NamespacedName: types.NamespacedName{Name: "draining"}}
+	if _, err := nodeReconciler.Reconcile(ctx, request); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := failures(); got != 1 {
+		t.Errorf("got %v pod condition update failures, want 1", got)
+	}
+	got := conditions(racy)
+	if got[corev1.DisruptionTarget].Reason != podutil.CordonDisruptionReason || got[corev1.PodReady].Status != corev1.ConditionTrue {
+		t.Errorf("got conditions %+v on the racy pod, want the kubelet's and ours", got)
+	}
+	EvictionAutoScaler := &v1.EvictionAutoScaler{}
+	if err := fakeClient.Get(ctx, racy, EvictionAutoScaler); err != nil {
+		t.Fatal(err)
+	}
+	if EvictionAutoScaler.Status.LastEviction.PodName != racy.Name {
+		t.Errorf("got last eviction %+v, want the racy pod", EvictionAutoScaler.Status.LastEviction)
+	}
+
+	node := &corev1.Node{}
+	if err := fakeClient.Get(ctx, request.NamespacedName, node); err != nil {
+		t.Fatal(err)
+	}
+	node.Spec.Unschedulable = false
+	if err := fakeClient.Update(ctx, node); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := nodeReconciler.Reconcile(ctx, request); err != nil {
+		t.Fatal(err)
+	}
+	if got := conditions(racy); len(got) != 1 || got[corev1.PodReady].Status != corev1.ConditionTrue {
+		t.Errorf("got conditions %+v on the racy pod after uncordon, want only the kubelet's", got)
+	}
+	if got := conditions(evicted)[corev1.DisruptionTarget]; got.Status != corev1.ConditionTrue {
+		t.Errorf("got %+v after uncordon, want the real eviction's DisruptionTarget kept", got)
+	}
+	if got := conditions(upgraded)[corev1.DisruptionTarget]; got.Status != corev1.ConditionFalse || got.Reason != podutil.UncordonReason {
+		t.Errorf("got %+v after uncordon, want the DisruptionTarget written before apply flipped to false", got)
+	}
+}
+
+// TestRecordEvictionOncePerCooldown checks reconciling a draining node again within the cooldown leaves the
+// EvictionAutoScaler alone instead of rewriting the same eviction with a new time, and counts it once, however often
+// the EvictionAutoScaler is requeued with it unhandled.
+func TestRecordEvictionOncePerCooldown(t *testing.T) {
+	ctx := context.Background()
+	testScheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(testScheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := v1.AddToScheme(testScheme); err != nil {
+		t.Fatal(err)
+	}
+	key := types.NamespacedName{Name: "web", Namespace: "requeues"}
+	fakeClient := fake.NewClientBuilder().
+		WithScheme(testScheme).
+		WithIndex(&corev1.Pod{}, NodeNameIndex, podNodeName).
+		WithStatusSubresource(&corev1.Pod{}, &v1.EvictionAutoScaler{}).
+		WithInterceptorFuncs(interceptor.Funcs{SubResourcePatch: fakeApplyPodStatus()}).
+		WithObjects(
+			&v1.EvictionAutoScaler{
+				ObjectMeta: metav1.ObjectMeta{Name: key.Name, Namespace: key.Namespace},
+				Spec:       v1.EvictionAutoScalerSpec{TargetName: "web", TargetKind: deploymentKind},
+			},
+			&policyv1.PodDisruptionBudget{
+				ObjectMeta: metav1.ObjectMeta{Name: key.Name, Namespace: key.Namespace},
+				Spec: policyv1.PodDisruptionBudgetSpec{
+					Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}},
+				},
+			},
+			&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "draining"}, Spec: corev1.NodeSpec{Unschedulable: true}},
+			&corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Name: "web-1", Namespace: key.Namespace, Labels: map[string]string{"app": "web"}},
+				Spec:       corev1.PodSpec{NodeName: "draining"},
+			},
+			&appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: key.Namespace, Generation: 2},
+				Spec:       appsv1.DeploymentSpec{Replicas: ptr.To(int32(3))},
+			},
+		).
+		Build()
+	recorder := record.NewFakeRecorder(10)
+	nodeReconciler := &NodeReconciler{Client: fakeClient, Scheme: testScheme, Recorder: recorder, Selectors: selectorcache.New()}
+	request := reconcile.Request{NamespacedName: types.NamespacedName{Name: "draining"}}
+	var resourceVersions []string
+	for range 3 {
+		if _, err := nodeReconciler.Reconcile(ctx, request); err != nil {
+			t.Fatal(err)
+		}
+		EvictionAutoScaler := &v1.EvictionAutoScaler{}
+		if err := fakeClient.Get(ctx, key, EvictionAutoScaler); err != nil {
+			t.Fatal(err)
+		}
+		resourceVersions = append(resourceVersions, EvictionAutoScaler.ResourceVersion)
+	}
+
+	if resourceVersions[1] != resourceVersions[0] || resourceVersions[2] != resourceVersions[0] {
+		t.Errorf("got resource versions %v, want the eviction written once", resourceVersions)
+	}
+	// a dry run never handles the eviction, so every reconcile requeues with it
+	evictionAutoScalerReconciler := &EvictionAutoScalerReconciler{Client: fakeClient, Scheme: testScheme, Recorder: record.NewFakeRecorder(10), DryRun: true}
+	for range 4 {
+		if _, err := evictionAutoScalerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: key}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	m := &dto.Metric{}
+	if err := metrics.EvictionCounter.WithLabelValues(key.Namespace, metrics.EvictionSourceCordon, "deployment").Write(m); err != nil {
+		t.Fatal(err)
+	}
+	if got := m.GetCounter().GetValue(); got != 1 {
+		t.Errorf("got %v evictions counted, want 1", got)
+	}
+	anticipated := 0
+	for len(recorder.Events) > 0 {
+		if strings.Contains(<-recorder.Events, events.ReasonAnticipatedEviction) {
+			anticipated++
+		}
+	}
+	if anticipated != 1 {
+		t.Errorf("got %d %s events, want 1", anticipated, events.ReasonAnticipatedEviction)
+	}
+}
+
+// TestDrainAborted uncordons one of two draining nodes and then the other. The surge is held for the node still
+// draining and scaled down straight after the second, without the cooldown of the evictions that never happened.
+func TestDrainAborted(t *testing.T) {
+	ctx := context.Background()
+	testScheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(testScheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := v1.AddToScheme(testScheme); err != nil {
+		t.Fatal(err)
+	}
+	key := types.NamespacedName{Name: "web", Namespace: "aborted"}
+	pod := func(name, node string) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: key.Namespace, Labels: map[string]string{"app": "web"}},
+			Spec:       corev1.PodSpec{NodeName: node},
+		}
+	}
+	surged := metav1.NewTime(time.Now().Add(-time.Minute))
+	fakeClient := fake.NewClientBuilder().
+		WithScheme(testScheme).
+		WithIndex(&corev1.Pod{}, NodeNameIndex, podNodeName).
+		WithStatusSubresource(&corev1.Pod{}, &v1.EvictionAutoScaler{}).
+		WithInterceptorFuncs(interceptor.Funcs{SubResourcePatch: fakeApplyPodStatus()}).
+		WithObjects(
+			&v1.EvictionAutoScaler{
+				ObjectMeta: metav1.ObjectMeta{Name: key.Name, Namespace: key.Namespace},
+				Spec:       v1.EvictionAutoScalerSpec{TargetName: "web", TargetKind: deploymentKind},
+				Status: v1.EvictionAutoScalerStatus{
+					MinReplicas:      3,
+					SurgeReplicas:    4,
+					TargetGeneration: 1,
+					Conditions:       []metav1.Condition{{Type: ConditionScalingUp, Status: metav1.ConditionTrue, Reason: "Surged", LastTransitionTime: surged}},
+				},
+			},
+			&appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: key.Namespace, Generation: 1},
+				Spec:       appsv1.DeploymentSpec{Replicas: ptr.To(int32(4))},
+			},
+			&policyv1.PodDisruptionBudget{
+				ObjectMeta: metav1.ObjectMeta{Name: key.Name, Namespace: key.Namespace},
+				Spec:       policyv1.PodDisruptionBudgetSpec{Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}}},
+				Status:     policyv1.PodDisruptionBudgetStatus{DisruptionsAllowed: 1},
+			},
+			&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-a"}, Spec: corev1.NodeSpec{Unschedulable: true}},
+			&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-b"}, Spec: corev1.NodeSpec{Unschedulable: true}},
+			pod("web-1", "node-a"),
+			pod("web-2", "node-b"),
+		).
+		Build()
+	recorder := record.NewFakeRecorder(20)
+	nodeReconciler := &NodeReconciler{Client: fakeClient, Scheme: testScheme, Recorder: recorder, Selectors: selectorcache.New()}
+	reconciler := &EvictionAutoScalerReconciler{Client: fakeClient, Scheme: testScheme}
+	reconcileNode := func(name string) {
+		if _, err := nodeReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: types.NamespacedName{Name: name}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	uncordon := func(name string) {
+		node := &corev1.Node{}
+		if err := fakeClient.Get(ctx, types.NamespacedName{Name: name}, node); err != nil {
+			t.Fatal(err)
+		}
+		node.Spec.Unschedulable = false
+		if err := fakeClient.Update(ctx, node); err != nil {
+			t.Fatal(err)
+		}
+		reconcileNode(name)
+	}
+	// reconciles the EvictionAutoScaler and returns it with the deployment's replicas
+	scale := func() (*v1.EvictionAutoScaler, int32) {
+		if _, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: key}); err != nil {
+			t.Fatal(err)
+		}
+		EvictionAutoScaler := &v1.EvictionAutoScaler{}
+		if err := fakeClient.Get(ctx, key, EvictionAutoScaler); err != nil {
+			t.Fatal(err)
+		}
+		deployment := &appsv1.Deployment{}
+		if err := fakeClient.Get(ctx, types.NamespacedName{Name: "web", Namespace: key.Namespace}, deployment); err != nil {
+			t.Fatal(err)
+		}
+		return EvictionAutoScaler, *deployment.Spec.Replicas
+	}
+
+	reconcileNode("node-a")
+	reconcileNode("node-b")
+	uncordon("node-a")
+	EvictionAutoScaler, replicas := scale()
+	if !slices.Equal(EvictionAutoScaler.Status.DrainingNodes, []string{"node-b"}) || replicas != 4 {
+		t.Errorf("got draining nodes %v and %d replicas after aborting node-a, want node-b still holding 4", EvictionAutoScaler.Status.DrainingNodes, replicas)
+	}
+	web1 := &corev1.Pod{}
+	if err := fakeClient.Get(ctx, types.NamespacedName{Name: "web-1", Namespace: key.Namespace}, web1); err != nil {
+		t.Fatal(err)
+	}
+	if len(web1.Status.Conditions) != 0 {
+		t.Errorf("got conditions %+v on web-1 after uncordon, want ours removed", web1.Status.Conditions)
+	}
+
+	uncordon("node-b")
+	EvictionAutoScaler, replicas = scale()
+	if replicas != 3 || EvictionAutoScaler.Status.HandledEviction != EvictionAutoScaler.Status.LastEviction {
+		t.Errorf("got %d replicas, handled %v after aborting both nodes, want scaled down to 3 without the cooldown",
+			replicas, EvictionAutoScaler.Status.HandledEviction == EvictionAutoScaler.Status.LastEviction)
+	}
+	aborted := 0
+	for len(recorder.Events) > 0 {
+		if strings.Contains(<-recorder.Events, events.ReasonDrainAborted) {
+			aborted++
+		}
+	}
+	if aborted != 2 {
+		t.Errorf("got %d %s events, want 2", aborted, events.ReasonDrainAborted)
+	}
+}
+
+// TestNodeDeleted deletes a draining node, once while we watch and once while we don't, and checks both are
+// released with their drain observed and nothing left about them in memory.
+func TestNodeDeleted(t *testing.T) {
+	ctx := context.Background()
+	testScheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(testScheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := v1.AddToScheme(testScheme); err != nil {
+		t.Fatal(err)
+	}
+	key := types.NamespacedName{Name: "web", Namespace: "deleted"}
+	fakeClient := fake.NewClientBuilder().
+		WithScheme(testScheme).
+		WithIndex(&corev1.Pod{}, NodeNameIndex, podNodeName).
+		WithStatusSubresource(&corev1.Pod{}, &v1.EvictionAutoScaler{}).
+		WithInterceptorFuncs(interceptor.Funcs{SubResourcePatch: fakeApplyPodStatus()}).
+		WithObjects(
+			&v1.EvictionAutoScaler{
+				ObjectMeta: metav1.ObjectMeta{Name: key.Name, Namespace: key.Namespace},
+				Spec:       v1.EvictionAutoScalerSpec{TargetName: "web", TargetKind: deploymentKind},
+				// node-gone was deleted before a restart
+				Status: v1.EvictionAutoScalerStatus{DrainingNodes: []string{"node-gone"}},
+			},
+			&policyv1.PodDisruptionBudget{
+				ObjectMeta: metav1.ObjectMeta{Name: key.Name, Namespace: key.Namespace},
+				Spec:       policyv1.PodDisruptionBudgetSpec{Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}}},
+			},
+			&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-a"}, Spec: corev1.NodeSpec{Unschedulable: true}},
+			&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-b"}, Spec: corev1.NodeSpec{Unschedulable: true}},
+			&corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Name: "web-1", Namespace: key.Namespace, Labels: map[string]string{"app": "web"}},
+				Spec:       corev1.PodSpec{NodeName: "node-a"},
+			},
+			&corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Name: "web-2", Namespace: key.Namespace, Labels: map[string]string{"app": "web"}},
+				Spec:       corev1.PodSpec{NodeName: "node-b"},
+			},
+		).
+		Build()
+	nodeReconciler := &NodeReconciler{Client: fakeClient, Scheme: testScheme, Selectors: selectorcache.New()}
+	drainingNodes := func() []string {
+		EvictionAutoScaler := &v1.EvictionAutoScaler{}
+		if err := fakeClient.Get(ctx, key, EvictionAutoScaler); err != nil {
+			t.Fatal(err)
+		}
+		return EvictionAutoScaler.Status.DrainingNodes
+	}
+	deletedDrains := func() uint64 {
+		m := &dto.Metric{}
+		if err := metrics.NodeDrainDuration.WithLabelValues(metrics.DrainOutcomeDeleted).(prometheus.Histogram).Write(m); err != nil {
+			t.Fatal(err)
+		}
+		return m.GetHistogram().GetSampleCount()
+	}
+	for _, name := range []string{"node-a", "node-b"} {
+		if _, err := nodeReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: types.NamespacedName{Name: name}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := drainingNodes(); !slices.Equal(got, []string{"node-gone", "node-a", "node-b"}) {
+		t.Fatalf("got draining nodes %v, want node-gone, node-a and node-b", got)
+	}
+
+	if err := nodeReconciler.releaseDeletedNodes(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if got := drainingNodes(); !slices.Equal(got, []string{"node-a", "node-b"}) {
+		t.Errorf("got draining nodes %v after the startup sweep, want node-gone released", got)
+	}
+
+	before := deletedDrains()
+	if err := fakeClient.Delete(ctx, &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-a"}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := nodeReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: types.NamespacedName{Name: "node-a"}}); err != nil {
+		t.Fatal(err)
+	}
+	if got := drainingNodes(); !slices.Equal(got, []string{"node-b"}) {
+		t.Errorf("got draining nodes %v after deleting node-a, want node-b", got)
+	}
+	if got := deletedDrains() - before; got != 1 {
+		t.Errorf("got %d drains observed as deleted, want 1", got)
+	}
+	if _, found := nodeReconciler.drainStarts.Load("node-a"); found {
+		t.Error("drain start of node-a kept after it was deleted")
+	}
+	if _, found := nodeReconciler.assisted.Load("node-a"); found {
+		t.Error("debug state of node-a kept after it was deleted")
+	}
+}
+
+// fakeApplyPodStatus stands in for server-side apply of pod status, which the fake client rejects. Applied conditions
+// are merged by type like the apiserver does and ones a field manager applied before and leaves out are removed.
+// Conflicts between field managers aren't tracked.
+func fakeApplyPodStatus() func(context.Context, client.Client, string, client.Object, client.Patch, ...client.SubResourcePatchOption) error {
+	var mu sync.Mutex
+	owned := map[string]map[corev1.PodConditionType]bool{} // by field manager and pod
+	return func(ctx context.Context, c client.Client, subResourceName string, obj client.Object, patch client.Patch, opts ...client.SubResourcePatchOption) error {
+		applyConfig, ok := obj.(*unstructured.Unstructured)
+		if patch.Type() != types.ApplyPatchType || !ok {
+			return c.SubResource(subResourceName).Patch(ctx, obj, patch, opts...)
+		}
+		applied := &corev1.Pod{}
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(applyConfig.Object, applied); err != nil {
+			return err
+		}
+		pod := &corev1.Pod{}
+		if err := c.Get(ctx, client.ObjectKeyFromObject(obj), pod); err != nil {
+			return err
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		key := (&client.SubResourcePatchOptions{}).ApplyOptions(opts).FieldManager + "/" + client.ObjectKeyFromObject(pod).String()
+		appliedTypes := map[corev1.PodConditionType]bool{}
+		for _, condition := range applied.Status.Conditions {
+			appliedTypes[condition.Type] = true
+		}
+		pod.Status.Conditions = slices.DeleteFunc(pod.Status.Conditions, func(condition corev1.PodCondition) bool {
+			return owned[key][condition.Type] && !appliedTypes[condition.Type]
+		})
+		owned[key] = appliedTypes
+		for _, condition := range applied.Status.Conditions {
+			if i := slices.IndexFunc(pod.Status.Conditions, func(c corev1.PodCondition) bool { return c.Type == condition.Type }); i >= 0 {
+				pod.Status.Conditions[i] = condition
+			} else {
+				pod.Status.Conditions = append(pod.Status.Conditions, condition)
+			}
+		}
+		if err := c.Status().Update(ctx, pod); err != nil {
+			return err
+		}
+		result, err := runtime.DefaultUnstructuredConverter.ToUnstructured(pod)
+		applyConfig.Object = result
+		return err
+	}
+}
+
+func TestStaleCordon(t *testing.T) {
+	ctx := context.Background()
+	testScheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(testScheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := v1.AddToScheme(testScheme); err != nil {
+		t.Fatal(err)
+	}
+	key := types.NamespacedName{Name: "web", Namespace: "stale"}
+	nodeKey := types.NamespacedName{Name: "node-a"}
+	cordoned := time.Now().Add(-2 * time.Hour).UTC().Format(time.RFC3339)
+	fakeClient := fake.NewClientBuilder().
+		WithScheme(testScheme).
+		WithIndex(&corev1.Pod{}, NodeNameIndex, podNodeName).
+		WithStatusSubresource(&corev1.Pod{}, &v1.EvictionAutoScaler{}).
+		WithInterceptorFuncs(interceptor.Funcs{SubResourcePatch: fakeApplyPodStatus()}).
+		WithObjects(
+			&v1.EvictionAutoScaler{
+				ObjectMeta: metav1.ObjectMeta{Name: key.Name, Namespace: key.Namespace},
+				Spec:       v1.EvictionAutoScalerSpec{TargetName: "web", TargetKind: deploymentKind},
+				Status:     v1.EvictionAutoScalerStatus{MinReplicas: 3, SurgeReplicas: 4, DrainingNodes: []string{"node-a"}},
+			},
+			&policyv1.PodDisruptionBudget{
+				ObjectMeta: metav1.ObjectMeta{Name: key.Name, Namespace: key.Namespace},
+				Spec:       policyv1.PodDisruptionBudgetSpec{Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}}},
+			},
+			&corev1.Node{
+				ObjectMeta: metav1.ObjectMeta{Name: nodeKey.Name, Annotations: map[string]string{DrainStartAnnotationKey: cordoned}},
+				Spec:       corev1.NodeSpec{Unschedulable: true},
+			},
+			&corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Name: "web-1", Namespace: key.Namespace, Labels: map[string]string{"app": "web"}},
+				Spec:       corev1.PodSpec{NodeName: nodeKey.Name},
+			},
+		).
+		Build()
+	recorder := record.NewFakeRecorder(20)
+	r := &NodeReconciler{Client: fakeClient, Scheme: testScheme, Recorder: recorder, Selectors: selectorcache.New(), StaleCordonThreshold: time.Hour}
+	// reconciles node-a and returns it, the EvictionAutoScaler and the events emitted
+	reconcileNode := func() (*corev1.Node, *v1.EvictionAutoScaler, string) {
+		if _, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: nodeKey}); err != nil {
+			t.Fatal(err)
+		}
+		node := &corev1.Node{}
+		if err := fakeClient.Get(ctx, nodeKey, node); err != nil {
+			t.Fatal(err)
+		}
+		EvictionAutoScaler := &v1.EvictionAutoScaler{}
+		if err := fakeClient.Get(ctx, key, EvictionAutoScaler); err != nil {
+			t.Fatal(err)
+		}
+		var emitted []string
+		for len(recorder.Events) > 0 {
+			emitted = append(emitted, <-recorder.Events)
+		}
+		return node, EvictionAutoScaler, strings.Join(emitted, "\n")
+	}
+	for i := 0; i < 2; i++ {
+		node, EvictionAutoScaler, emitted := reconcileNode()
+		if _, found := node.Annotations[StaleCordonAnnotationKey]; !found || node.Annotations[DrainStartAnnotationKey] != "" {
+			t.Errorf("reconcile %d: got annotations %v on stale node, want stale-cordon and no drain-start", i, node.Annotations)
+		}
+		if len(EvictionAutoScaler.Status.DrainingNodes) != 0 {
+			t.Errorf("reconcile %d: got draining nodes %v, want stale node released", i, EvictionAutoScaler.Status.DrainingNodes)
+		}
+		if stoodDown := strings.Contains(emitted, events.ReasonStaleCordon); stoodDown != (i == 0) {
+			t.Errorf("reconcile %d: got events %q, want StaleCordon only when standing down", i, emitted)
+		}
+		pod := &corev1.Pod{}
+		if err := fakeClient.Get(ctx, types.NamespacedName{Name: "web-1", Namespace: key.Namespace}, pod); err != nil {
+			t.Fatal(err)
+		}
+		if len(pod.Status.Conditions) != 0 {
+			t.Errorf("reconcile %d: got conditions %+v on pod of stale node, want none", i, pod.Status.Conditions)
+		}
+	}
+
+	node := &corev1.Node{}
+	if err := fakeClient.Get(ctx, nodeKey, node); err != nil {
+		t.Fatal(err)
+	}
+	node.Annotations[NodeRearmAnnotationKey] = "true"
+	if err := fakeClient.Update(ctx, node); err != nil {
+		t.Fatal(err)
+	}
+	node, EvictionAutoScaler, emitted := reconcileNode()
+	_, stale := node.Annotations[StaleCordonAnnotationKey]
+	_, rearm := node.Annotations[NodeRearmAnnotationKey]
+	start, started := drainStart(node)
+	if stale || rearm || !started || time.Since(start) > time.Minute {
+		t.Errorf("got annotations %v after re-arm, want only a new drain-start", node.Annotations)
+	}
+	if !slices.Equal(EvictionAutoScaler.Status.DrainingNodes, []string{"node-a"}) || !strings.Contains(emitted, events.ReasonDrainRearmed) {
+		t.Errorf("got draining nodes %v and events %q after re-arm, want node-a draining again with DrainRearmed", EvictionAutoScaler.Status.DrainingNodes, emitted)
+	}
+}
+
+func TestMaxConcurrentNodeDrains(t *testing.T) {
+	ctx := context.Background()
+	testScheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(testScheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := v1.AddToScheme(testScheme); err != nil {
+		t.Fatal(err)
+	}
+	key := types.NamespacedName{Name: "web", Namespace: "limited"}
+	objects := []client.Object{
+		&v1.EvictionAutoScaler{
+			ObjectMeta: metav1.ObjectMeta{Name: key.Name, Namespace: key.Namespace},
+			Spec:       v1.EvictionAutoScalerSpec{TargetName: "web", TargetKind: deploymentKind},
+		},
+		&policyv1.PodDisruptionBudget{
+			ObjectMeta: metav1.ObjectMeta{Name: key.Name, Namespace: key.Namespace},
+			Spec:       policyv1.PodDisruptionBudgetSpec{Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}}},
+		},
+	}
+	for _, name := range []string{"node-a", "node-b", "node-c"} {
+		objects = append(objects,
+			&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: name}, Spec: corev1.NodeSpec{Unschedulable: true}},
+			&corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Name: "web-" + name, Namespace: key.Namespace, Labels: map[string]string{"app": "web"}},
+				Spec:       corev1.PodSpec{NodeName: name},
+			})
+	}
+	fakeClient := fake.NewClientBuilder().
+		WithScheme(testScheme).
+		WithIndex(&corev1.Pod{}, NodeNameIndex, podNodeName).
+		WithStatusSubresource(&corev1.Pod{}, &v1.EvictionAutoScaler{}).
+		WithInterceptorFuncs(interceptor.Funcs{SubResourcePatch: fakeApplyPodStatus()}).
+		WithObjects(objects...).
+		Build()
+	recorder := record.NewFakeRecorder(50)
+	r := &NodeReconciler{Client: fakeClient, Scheme: testScheme, Recorder: recorder, Selectors: selectorcache.New(),
+		MaxConcurrentNodeDrains: 1, admitted: make(chan event.GenericEvent, 10)}
+	reconcileNode := func(name string) {
+		if _, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: types.NamespacedName{Name: name}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	drainingNodes := func() []string {
+		EvictionAutoScaler := &v1.EvictionAutoScaler{}
+		if err := fakeClient.Get(ctx, key, EvictionAutoScaler); err != nil {
+			t.Fatal(err)
+		}
+		return EvictionAutoScaler.Status.DrainingNodes
+	}
+
+	reconcileNode("node-a")
+	reconcileNode("node-b")
+	reconcileNode("node-c")
+	if draining := drainingNodes(); !slices.Equal(draining, []string{"node-a"}) {
+		t.Errorf("got draining nodes %v with a limit of 1, want only node-a", draining)
+	}
+	queued := 0
+	for len(recorder.Events) > 0 {
+		if event := <-recorder.Events; strings.Contains(event, events.ReasonDrainQueued) {
+			queued++
+			if strings.Contains(event, "position 2") != (queued == 2) {
+				t.Errorf("got %q for queued node %d, want its position", event, queued)
+			}
+		}
+	}
+	if queued != 2 {
+		t.Errorf("got %d DrainQueued events, want one for node-b and node-c", queued)
+	}
+	reconcileNode("node-c") // still queued behind node-b
+	if draining := drainingNodes(); !slices.Equal(draining, []string{"node-a"}) {
+		t.Errorf("got draining nodes %v, want node-c waiting its turn", draining)
+	}
+
+	node := &corev1.Node{}
+	if err := fakeClient.Get(ctx, types.NamespacedName{Name: "node-a"}, node); err != nil {
+		t.Fatal(err)
+	}
+	node.Spec.Unschedulable = false
+	if err := fakeClient.Update(ctx, node); err != nil {
+		t.Fatal(err)
+	}
+	reconcileNode("node-a")
+	select {
+	case admitted := <-r.admitted:
+		if admitted.Object.GetName() != "node-b" {
+			t.Errorf("got %s requeued after node-a was uncordoned, want node-b", admitted.Object.GetName())
+		}
+	default:
+		t.Fatal("got nothing requeued after node-a was uncordoned, want node-b")
+	}
+	reconcileNode("node-b")
+	if draining := drainingNodes(); !slices.Equal(draining, []string{"node-b"}) {
+		t.Errorf("got draining nodes %v after node-a was uncordoned, want node-b admitted", draining)
+	}
+	assists := func(state string) float64 {
+		m := &dto.Metric{}
+		if err := metrics.NodeDrainAssistsGauge.WithLabelValues(state).Write(m); err != nil {
+			t.Fatal(err)
+		}
+		return m.GetGauge().GetValue()
+	}
+	if active, queued := assists(metrics.DrainAssistActive), assists(metrics.DrainAssistQueued); active != 1 || queued != 1 {
+		t.Errorf("got %v active and %v queued drain assists, want 1 and 1", active, queued)
+	}
+}
@@ -0,0 +1,59 @@
+package controllers
+
+import (
+	"errors"
+	"time"
+
+	"github.com/azure/eviction-autoscaler/internal/metrics"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// DefaultNodeRetryBaseDelay and DefaultNodeRetryMaxDelay are the first and longest delay a failing node is retried
+// after, doubling in between.
+const (
+	DefaultNodeRetryBaseDelay = time.Second
+	DefaultNodeRetryMaxDelay  = 5 * time.Minute
+)
+
+// nodeRateLimiter backs failed node reconciles off per node, from RetryBaseDelay doubling up to RetryMaxDelay, and
+// records each delay. There is no overall limit on top like the default limiter's, so one node failing over and over
+// doesn't use up retries the others need.
+func (r *NodeReconciler) nodeRateLimiter() workqueue.TypedRateLimiter[reconcile.Request] {
+	base, maxDelay := r.RetryBaseDelay, r.RetryMaxDelay
+	if base <= 0 {
+		base = DefaultNodeRetryBaseDelay
+	}
+	if maxDelay <= 0 {
+		maxDelay = DefaultNodeRetryMaxDelay
+	}
+	return observedRateLimiter{workqueue.NewTypedItemExponentialFailureRateLimiter[reconcile.Request](base, max(base, maxDelay))}
+}
+
+type observedRateLimiter struct {
+	workqueue.TypedRateLimiter[reconcile.Request]
+}
+
+func (l observedRateLimiter) When(item reconcile.Request) time.Duration {
+	delay := l.TypedRateLimiter.When(item)
+	metrics.NodeRetryDelay.Observe(delay.Seconds())
+	return delay
+}
+
+// terminalNodeError reports if retrying err can't help because the api server rejected the request itself, for an
+// aggregate of pod errors only if all of them were. Those are returned as reconcile.TerminalError so the node isn't
+// retried till one of its events comes in.
+func terminalNodeError(err error) bool {
+	var aggregate utilerrors.Aggregate
+	if errors.As(err, &aggregate) {
+		for _, err := range aggregate.Errors() {
+			if !terminalNodeError(err) {
+				return false
+			}
+		}
+		return len(aggregate.Errors()) > 0
+	}
+	return apierrors.IsInvalid(err) || apierrors.IsBadRequest(err) || apierrors.IsMethodNotSupported(err) || apierrors.IsRequestEntityTooLargeError(err)
+}
@@ -0,0 +1,55 @@
+package controllers
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func TestNodeRateLimiter(t *testing.T) {
+	limiter := (&NodeReconciler{RetryBaseDelay: time.Second, RetryMaxDelay: 5 * time.Second}).nodeRateLimiter()
+	hot := reconcile.Request{NamespacedName: types.NamespacedName{Name: "hot"}}
+	other := reconcile.Request{NamespacedName: types.NamespacedName{Name: "other"}}
+	for i, want := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second} {
+		if got := limiter.When(hot); got != want {
+			t.Errorf("failure %d: got %s, want %s", i+1, got, want)
+		}
+	}
+	// one node backing off leaves the others alone
+	if got := limiter.When(other); got != time.Second {
+		t.Errorf("other node: got %s, want %s", got, time.Second)
+	}
+	limiter.Forget(hot)
+	if got := limiter.When(hot); got != time.Second {
+		t.Errorf("after success: got %s, want %s", got, time.Second)
+	}
+}
+
+func TestTerminalNodeError(t *testing.T) {
+	invalid := apierrors.NewInvalid(schema.GroupKind{Kind: "Pod"}, "web-0", nil)
+	conflict := apierrors.NewConflict(corev1.Resource("pods"), "web-1", fmt.Errorf("modified"))
+	tests := []struct {
+		name     string
+		err      error
+		terminal bool
+	}{
+		{name: "nil", err: nil},
+		{name: "invalid", err: invalid, terminal: true},
+		{name: "wrapped bad request", err: fmt.Errorf("set DisruptionTarget: %w", apierrors.NewBadRequest("rejected by webhook")), terminal: true},
+		{name: "conflict", err: conflict},
+		{name: "all pods invalid", err: utilerrors.NewAggregate([]error{invalid, invalid}), terminal: true},
+		{name: "one pod retryable", err: utilerrors.NewAggregate([]error{invalid, conflict})},
+	}
+	for _, test := range tests {
+		if got := terminalNodeError(test.err); got != test.terminal {
+			t.Errorf("%s: got terminal %v, want %v", test.name, got, test.terminal)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	myappsv1 "github.com/azure/eviction-autoscaler/api/v1"
+	"github.com/azure/eviction-autoscaler/internal/events"
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// ConditionNoPodsSelected is true while the pdb, or spec.podSelector, selects none of the namespace's pods though
+// the target has pods, most likely a typo in its labels or a renamed workload, so no drain is ever surged for.
+const ConditionNoPodsSelected = "NoPodsSelected"
+
+// checkPodsSelected sets ConditionNoPodsSelected on EvictionAutoScaler, with a warning event when it turns true, and
+// clears it once pdb selects a pod again. It runs on every reconcile, so a pdb or workload change catches the
+// misconfiguration before the first drain does. A target without pods of its own yet, scaled to zero or just
+// created, has none to select. Reports whether the condition changed.
+func (r *EvictionAutoScalerReconciler) checkPodsSelected(ctx context.Context, EvictionAutoScaler *myappsv1.EvictionAutoScaler,
+	pdb *policyv1.PodDisruptionBudget, target Surger) (bool, error) {
+	conditions := &EvictionAutoScaler.Status.Conditions
+	if !targetHasPods(target) {
+		return clearCondition(conditions, ConditionNoPodsSelected, "TargetHasNoPods", "target has no pods to select"), nil
+	}
+	// a null pdb selector selects nothing, which LabelSelectorAsSelector turns into labels.Nothing() too
+	selector, err := metav1.LabelSelectorAsSelector(pdb.Spec.Selector)
+	if err == nil {
+		pods := &corev1.PodList{}
+		if err := r.List(ctx, pods, client.InNamespace(EvictionAutoScaler.Namespace), client.MatchingLabelsSelector{Selector: selector},
+			client.Limit(1)); err != nil {
+			return false, err
+		}
+		if len(pods.Items) > 0 {
+			return clearCondition(conditions, ConditionNoPodsSelected, "PodsSelected", "selects the target's pods"), nil
+		}
+	}
+	message := fmt.Sprintf("pdb %s selects no pods in namespace %s, so drains are never surged for", pdb.Name, EvictionAutoScaler.Namespace)
+	if EvictionAutoScaler.Spec.PodSelector != nil {
+		message = fmt.Sprintf("spec.podSelector selects no pods in namespace %s, so drains are never surged for", EvictionAutoScaler.Namespace)
+	}
+	if err != nil {
+		message = fmt.Sprintf("%s: invalid selector: %s", message, err)
+	}
+	if !setCondition(conditions, ConditionNoPodsSelected, metav1.ConditionTrue, "NoPodsSelected", message) {
+		return false, nil
+	}
+	log.FromContext(ctx).Info("No pods selected", "pdb", pdb.Name, "selector", pdb.Spec.Selector)
+	events.Eventf(r.Recorder, EvictionAutoScaler, corev1.EventTypeWarning, events.ReasonNoPodsSelected, message)
+	return true, nil
+}
+
+// targetHasPods returns true if target reports pods of its own, available ones for the built in kinds and the ones
+// the scale subresource counts for targetRef targets.
+func targetHasPods(target Surger) bool {
+	if scaled, ok := target.(*ScaleWrapper); ok {
+		return scaled.scale.Status.Replicas > 0
+	}
+	_, available := TargetReplicas(target.Obj())
+	return available > 0
+}
@@ -0,0 +1,91 @@
+package controllers
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	v1 "github.com/azure/eviction-autoscaler/api/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// TestNoPodsSelected checks a pdb whose selector has a typo is flagged without any drain, and cleared once it is fixed.
+func TestNoPodsSelected(t *testing.T) {
+	ctx := context.Background()
+	testScheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(testScheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := v1.AddToScheme(testScheme); err != nil {
+		t.Fatal(err)
+	}
+	key := types.NamespacedName{Namespace: "default", Name: "web"}
+	pdb := &policyv1.PodDisruptionBudget{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+		Spec:       policyv1.PodDisruptionBudgetSpec{Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "wbe"}}},
+	}
+	fakeClient := fake.NewClientBuilder().WithScheme(testScheme).WithStatusSubresource(&v1.EvictionAutoScaler{}).WithObjects(
+		&appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default", Generation: 1},
+			Spec:       appsv1.DeploymentSpec{Replicas: ptr.To(int32(2))},
+			Status:     appsv1.DeploymentStatus{AvailableReplicas: 2},
+		},
+		&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "web-1", Namespace: "default", Labels: map[string]string{"app": "web"}}},
+		pdb,
+		&v1.EvictionAutoScaler{
+			ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+			Spec:       v1.EvictionAutoScalerSpec{TargetName: "web", TargetKind: deploymentKind},
+		},
+	).Build()
+	recorder := record.NewFakeRecorder(10)
+	r := &EvictionAutoScalerReconciler{Client: fakeClient, Scheme: testScheme, Recorder: recorder}
+	condition := func() *metav1.Condition {
+		t.Helper()
+		if _, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: key}); err != nil {
+			t.Fatal(err)
+		}
+		EvictionAutoScaler := &v1.EvictionAutoScaler{}
+		if err := fakeClient.Get(ctx, key, EvictionAutoScaler); err != nil {
+			t.Fatal(err)
+		}
+		return meta.FindStatusCondition(EvictionAutoScaler.Status.Conditions, ConditionNoPodsSelected)
+	}
+
+	if got := condition(); got == nil || got.Status != metav1.ConditionTrue {
+		t.Fatalf("got %s %+v with pdb selecting app=wbe, want it true", ConditionNoPodsSelected, got)
+	}
+	select {
+	case event := <-recorder.Events:
+		if !strings.Contains(event, "NoPodsSelected") {
+			t.Errorf("got event %q, want a NoPodsSelected warning", event)
+		}
+	default:
+		t.Error("no event for a pdb selecting no pods")
+	}
+	// reconciled again without a change, no second event
+	condition()
+	select {
+	case event := <-recorder.Events:
+		t.Errorf("got event %q with the condition already set", event)
+	default:
+	}
+
+	pdb.Spec.Selector.MatchLabels["app"] = "web"
+	if err := fakeClient.Update(ctx, pdb); err != nil {
+		t.Fatal(err)
+	}
+	if got := condition(); got == nil || got.Status != metav1.ConditionFalse || got.Reason != "PodsSelected" {
+		t.Errorf("got %s %+v once the selector was fixed, want it false with reason PodsSelected", ConditionNoPodsSelected, got)
+	}
+}
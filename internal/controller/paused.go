@@ -0,0 +1,11 @@
+package controllers
+
+// ConditionTargetPaused is true while the target is a paused Deployment and a surge of it would add no pods.
+const ConditionTargetPaused = "TargetPaused"
+
+// targetPaused is whether target is a paused Deployment. Scaling one only changes spec.replicas,
+// the deployment controller doesn't create pods for it till it is unpaused.
+func targetPaused(target Surger) bool {
+	deployment, ok := target.(*DeploymentWrapper)
+	return ok && deployment.obj.Spec.Paused
+}
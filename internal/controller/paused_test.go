@@ -0,0 +1,105 @@
+package controllers
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	v1 "github.com/azure/eviction-autoscaler/api/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func TestPausedTarget(t *testing.T) {
+	ctx := context.Background()
+	testScheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(testScheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := v1.AddToScheme(testScheme); err != nil {
+		t.Fatal(err)
+	}
+	key := types.NamespacedName{Namespace: "default", Name: "web"}
+	lastEviction := v1.Eviction{PodName: "web-1", EvictionTime: metav1.NewTime(time.Now().Add(-time.Second).Truncate(time.Second))}
+
+	tests := []struct {
+		name     string
+		paused   bool
+		policy   string
+		replicas int32  // deployment replicas after reconcile
+		handled  bool   // checked while paused
+		reason   string // of TargetPaused, empty for not set
+	}{
+		{name: "skip", paused: true, handled: true, replicas: 3, reason: "SurgeSkipped"},
+		{name: "defer", paused: true, policy: v1.PausedPolicyDefer, replicas: 3, reason: "SurgeDeferred"},
+		{name: "unpaused", policy: v1.PausedPolicyDefer, replicas: 4},
+	}
+	for _, test := range tests {
+		objects := []client.Object{
+			&appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default", Generation: 2},
+				Spec:       appsv1.DeploymentSpec{Replicas: ptr.To(int32(3)), Paused: test.paused},
+			},
+			&policyv1.PodDisruptionBudget{
+				ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+				Spec:       policyv1.PodDisruptionBudgetSpec{MinAvailable: ptr.To(intstr.FromInt32(3))},
+			},
+			&v1.EvictionAutoScaler{
+				ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+				Spec:       v1.EvictionAutoScalerSpec{TargetName: "web", TargetKind: deploymentKind, PausedPolicy: test.policy},
+				Status:     v1.EvictionAutoScalerStatus{MinReplicas: 3, TargetGeneration: 2, LastEviction: lastEviction},
+			},
+		}
+		fakeClient := fake.NewClientBuilder().WithScheme(testScheme).
+			WithStatusSubresource(&v1.EvictionAutoScaler{}).WithObjects(objects...).Build()
+		recorder := record.NewFakeRecorder(10)
+		r := &EvictionAutoScalerReconciler{Client: fakeClient, Scheme: testScheme, Recorder: recorder}
+		if _, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: key}); err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+
+		deployment := &appsv1.Deployment{}
+		if err := fakeClient.Get(ctx, key, deployment); err != nil {
+			t.Fatal(err)
+		}
+		if *deployment.Spec.Replicas != test.replicas {
+			t.Errorf("%s: got %d replicas, want %d", test.name, *deployment.Spec.Replicas, test.replicas)
+		}
+		EvictionAutoScaler := &v1.EvictionAutoScaler{}
+		if err := fakeClient.Get(ctx, key, EvictionAutoScaler); err != nil {
+			t.Fatal(err)
+		}
+		if handled := EvictionAutoScaler.Status.HandledEviction == lastEviction; test.paused && handled != test.handled {
+			t.Errorf("%s: got eviction handled %v, want %v", test.name, handled, test.handled)
+		}
+		condition := meta.FindStatusCondition(EvictionAutoScaler.Status.Conditions, ConditionTargetPaused)
+		if test.reason == "" {
+			if condition != nil && condition.Status == metav1.ConditionTrue {
+				t.Errorf("%s: got %s %+v", test.name, ConditionTargetPaused, condition)
+			}
+		} else if condition == nil || condition.Status != metav1.ConditionTrue || condition.Reason != test.reason {
+			t.Errorf("%s: got %s %+v, want reason %s", test.name, ConditionTargetPaused, condition, test.reason)
+		}
+		pausedEvent := false
+		for len(recorder.Events) > 0 {
+			if strings.Contains(<-recorder.Events, "TargetPaused") {
+				pausedEvent = true
+			}
+		}
+		if pausedEvent != test.paused {
+			t.Errorf("%s: got TargetPaused event %v, want %v", test.name, pausedEvent, test.paused)
+		}
+	}
+}
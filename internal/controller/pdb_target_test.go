@@ -0,0 +1,71 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	v1 "github.com/azure/eviction-autoscaler/api/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestDiscoverTarget(t *testing.T) {
+	ctx := context.Background()
+	testScheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(testScheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := v1.AddToScheme(testScheme); err != nil {
+		t.Fatal(err)
+	}
+	selector := &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}}
+	pdb := &policyv1.PodDisruptionBudget{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+		Spec:       policyv1.PodDisruptionBudgetSpec{Selector: selector},
+	}
+	owner := func(kind, name string) []metav1.OwnerReference {
+		return []metav1.OwnerReference{{APIVersion: "apps/v1", Kind: kind, Name: name, UID: "1234"}}
+	}
+
+	tests := []struct {
+		name       string
+		podOwners  []metav1.OwnerReference
+		replicaSet []metav1.OwnerReference // owners of the web-abc ReplicaSet, nil for a bare one
+		kind       string
+		target     string
+	}{
+		{name: "deployment owned", podOwners: owner("ReplicaSet", "web-abc"), replicaSet: owner("Deployment", "web"), kind: deploymentKind, target: "web"},
+		{name: "bare replicaset", podOwners: owner("ReplicaSet", "web-abc"), kind: replicaSetKind, target: "web-abc"},
+		{name: "replicaset owned by something else", podOwners: owner("ReplicaSet", "web-abc"),
+			replicaSet: []metav1.OwnerReference{{APIVersion: "argoproj.io/v1alpha1", Kind: "Rollout", Name: "web", UID: "1234"}}},
+		{name: "statefulset", podOwners: owner("StatefulSet", "web"), kind: statefulSetKind, target: "web"},
+		{name: "ownerless"},
+	}
+	for _, test := range tests {
+		objects := []client.Object{
+			pdb,
+			&appsv1.ReplicaSet{ObjectMeta: metav1.ObjectMeta{Name: "web-abc", Namespace: "default", OwnerReferences: test.replicaSet}},
+			&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "web-0", Namespace: "default", Labels: selector.MatchLabels, OwnerReferences: test.podOwners}},
+		}
+		r := &PDBToEvictionAutoScalerReconciler{Client: fake.NewClientBuilder().WithScheme(testScheme).WithObjects(objects...).Build(), Scheme: testScheme}
+		kind, target, err := r.discoverTarget(ctx, pdb)
+		if test.kind == "" {
+			if err != errOwnerNotFound {
+				t.Errorf("%s: got %s %s and error %v, want errOwnerNotFound", test.name, kind, target, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		if kind != test.kind || target != test.target {
+			t.Errorf("%s: got target %s %s, want %s %s", test.name, kind, target, test.kind, test.target)
+		}
+	}
+}
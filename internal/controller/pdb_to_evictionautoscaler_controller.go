@@ -6,6 +6,7 @@ import (
 
 	types "github.com/azure/eviction-autoscaler/api/v1"
 	"github.com/azure/eviction-autoscaler/internal/metrics"
+	"github.com/azure/eviction-autoscaler/internal/namespacefilter"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	policyv1 "k8s.io/api/policy/v1"
@@ -24,11 +25,22 @@ import (
 
 var errOwnerNotFound error = fmt.Errorf("owner not found")
 
+const (
+	// AutoCreatedLabelKey marks EvictionAutoScalers created for an existing pdb rather than written by hand.
+	AutoCreatedLabelKey = "eviction-autoscaler.azure.com/auto-created"
+	// OptOutAnnotationKey on a pdb means never create an EvictionAutoScaler for it.
+	OptOutAnnotationKey = "eviction-autoscaler.azure.com/opt-out"
+	// DoNotRecreateAnnotationKey on a pdb keeps an auto-created EvictionAutoScaler that was deleted on purpose from coming back.
+	DoNotRecreateAnnotationKey = "eviction-autoscaler.azure.com/do-not-recreate"
+)
+
 // PDBToEvictionAutoScalerReconciler reconciles a PodDisruptionBudget object.
 type PDBToEvictionAutoScalerReconciler struct {
 	client.Client
 	Scheme   *runtime.Scheme
 	Recorder record.EventRecorder
+	// Namespaces excludes pdbs we must not create EvictionAutoScalers for. Nil allows all.
+	Namespaces *namespacefilter.Filter
 }
 
 // +kubebuilder:rbac:groups=policy,resources=poddisruptionbudgets,verbs=get;list;create;watch;update
@@ -40,11 +52,15 @@ func (r *PDBToEvictionAutoScalerReconciler) Reconcile(ctx context.Context, req r
 	logger := log.FromContext(ctx)
 	logger.WithValues("pdb", req.Name, "namespace", req.Namespace)
 	ctx = log.IntoContext(ctx, logger)
+	if r.Namespaces.Skip(ctx, req.Namespace, "pdb") {
+		return reconcile.Result{}, nil
+	}
 	// Fetch the PodDisruptionBudget object based on the reconcile request
 	var pdb policyv1.PodDisruptionBudget
 	err := r.Get(ctx, req.NamespacedName, &pdb)
 	if err != nil {
-		return reconcile.Result{}, err
+		// gone along with the EvictionAutoScaler it owned
+		return reconcile.Result{}, client.IgnoreNotFound(err)
 	}
 
 	// Update PDB metrics to check if this PDB was created by our deployment controller
@@ -60,7 +76,23 @@ func (r *PDBToEvictionAutoScalerReconciler) Reconcile(ctx context.Context, req r
 			return ctrl.Result{}, err
 		}
 
-		deploymentName, e := r.discoverDeployment(ctx, &pdb)
+		if annotation, skip := AutoCreateDisabled(&pdb); skip {
+			logger.V(1).Info("Not creating EvictionAutoScaler for annotated pdb", "annotation", annotation)
+			return reconcile.Result{}, nil
+		}
+		// one of another name may already point at it with spec.targetPDBName
+		EvictionAutoScalerList := &types.EvictionAutoScalerList{}
+		if err := r.List(ctx, EvictionAutoScalerList, client.InNamespace(pdb.Namespace)); err != nil {
+			return reconcile.Result{}, err
+		}
+		for _, existing := range EvictionAutoScalerList.Items {
+			if existing.Spec.PodSelector == nil && existing.PDBName() == pdb.Name {
+				logger.V(1).Info("Not creating EvictionAutoScaler for pdb another one points at", "evictionAutoScaler", existing.Name)
+				return reconcile.Result{}, nil
+			}
+		}
+
+		targetKind, targetName, e := r.discoverTarget(ctx, &pdb)
 		if e != nil {
 			if e == errOwnerNotFound {
 				return reconcile.Result{}, nil
@@ -81,9 +113,12 @@ func (r *PDBToEvictionAutoScalerReconciler) Reconcile(ctx context.Context, req r
 			ObjectMeta: metav1.ObjectMeta{
 				Name:      pdb.Name,
 				Namespace: pdb.Namespace,
+				Labels: map[string]string{
+					AutoCreatedLabelKey: "true",
+				},
 				Annotations: map[string]string{
 					"createdBy": "PDBToEvictionAutoScalerController",
-					"target":    deploymentName,
+					"target":    targetName,
 				},
 				OwnerReferences: []metav1.OwnerReference{
 					{
@@ -97,8 +132,8 @@ func (r *PDBToEvictionAutoScalerReconciler) Reconcile(ctx context.Context, req r
 				},
 			},
 			Spec: types.EvictionAutoScalerSpec{
-				TargetName: deploymentName,
-				TargetKind: deploymentKind,
+				TargetName: targetName,
+				TargetKind: targetKind,
 			},
 		}
 
@@ -108,7 +143,7 @@ func (r *PDBToEvictionAutoScalerReconciler) Reconcile(ctx context.Context, req r
 		}
 
 		// Track EvictionAutoScaler creation
-		metrics.EvictionAutoScalerCreationCounter.WithLabelValues(pdb.Namespace, pdb.Name, deploymentName).Inc()
+		metrics.EvictionAutoScalerCreationCounter.WithLabelValues(pdb.Namespace, pdb.Name, targetName).Inc()
 
 		logger.Info("Created EvictionAutoScaler")
 	}
@@ -116,6 +151,16 @@ func (r *PDBToEvictionAutoScalerReconciler) Reconcile(ctx context.Context, req r
 	return reconcile.Result{}, nil
 }
 
+// AutoCreateDisabled returns the annotation keeping us from creating an EvictionAutoScaler for pdb, if any.
+func AutoCreateDisabled(pdb *policyv1.PodDisruptionBudget) (string, bool) {
+	for _, key := range []string{OptOutAnnotationKey, DoNotRecreateAnnotationKey} {
+		if value, found := pdb.Annotations[key]; found && value != "false" {
+			return key, true
+		}
+	}
+	return "", false
+}
+
 // SetupWithManager sets up the controller with the Manager.
 func (r *PDBToEvictionAutoScalerReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	// Set up the controller to watch Deployments and trigger the reconcile function
@@ -133,64 +178,88 @@ func (r *PDBToEvictionAutoScalerReconciler) SetupWithManager(mgr ctrl.Manager) e
 		Complete(r)
 }
 
-func (r *PDBToEvictionAutoScalerReconciler) discoverDeployment(ctx context.Context, pdb *policyv1.PodDisruptionBudget) (string, error) {
+// discoverTarget returns the kind and name of the Deployment, StatefulSet or bare ReplicaSet owning the pdb's pods.
+func (r *PDBToEvictionAutoScalerReconciler) discoverTarget(ctx context.Context, pdb *policyv1.PodDisruptionBudget) (string, string, error) {
 	logger := log.FromContext(ctx)
 
 	// Convert PDB label selector to Kubernetes selector
 	selector, err := metav1.LabelSelectorAsSelector(pdb.Spec.Selector)
 	if err != nil {
-		return "", fmt.Errorf("error converting label selector: %v", err)
+		return "", "", fmt.Errorf("error converting label selector: %v", err)
 	}
 	logger.Info("PDB Selector", "selector", pdb.Spec.Selector)
 
 	podList := &corev1.PodList{}
 	err = r.List(ctx, podList, &client.ListOptions{Namespace: pdb.Namespace, LabelSelector: selector})
 	if err != nil {
-		return "", fmt.Errorf("error listing pods: %v", err)
+		return "", "", fmt.Errorf("error listing pods: %v", err)
 	}
 	logger.Info("Number of pods found", "count", len(podList.Items))
 
 	if len(podList.Items) == 0 {
 		// TODO instead of an error which leads to a backoff retry quietly for a while then error?
-		return "", fmt.Errorf("no pods found matching the PDB selector %s; leaky pdb(?!)", pdb.Name)
+		return "", "", fmt.Errorf("no pods found matching the PDB selector %s; leaky pdb(?!)", pdb.Name)
 	}
 
-	// Iterate through each pod
 	for _, pod := range podList.Items {
-		// Check the OwnerReferences of each pod
-		for _, ownerRef := range pod.OwnerReferences {
-			if ownerRef.Kind == "ReplicaSet" {
-				replicaSet := &appsv1.ReplicaSet{}
-				err = r.Get(ctx, k8s_types.NamespacedName{Name: ownerRef.Name, Namespace: pdb.Namespace}, replicaSet)
-				if apierrors.IsNotFound(err) {
-					return "", fmt.Errorf("error fetching ReplicaSet: %v", err)
-				}
-
-				// Log ReplicaSet details
-				logger.Info("Found ReplicaSet", "replicaSet", replicaSet.Name)
-
-				// Look for the Deployment owner of the ReplicaSet
-				for _, rsOwnerRef := range replicaSet.OwnerReferences {
-					if rsOwnerRef.Kind == "Deployment" {
-						logger.Info("Found Deployment owner", "deployment", rsOwnerRef.Name)
-						return rsOwnerRef.Name, nil
-					}
-				}
-				// no replicaset owner just move on and see if any other pods have have something.
-			}
-			//// Optional: Handle StatefulSets if necessary
-			//if ownerRef.Kind == "StatefulSet" {
-			//	statefulSet := &appsv1.StatefulSet{}
-			//	err = r.Get(ctx, k8s_types.NamespacedName{Name: ownerRef.Name, Namespace: pdb.Namespace}, statefulSet)
-			//	if apierrors.IsNotFound(err) {
-			//		return "", fmt.Errorf("error fetching StatefulSet: %v", err)
-			//	}
-			//	logger.Info("Found StatefulSet owner", "statefulSet", statefulSet.Name)
-			//	// Handle StatefulSet logic if required
-			//}
+		kind, name, err := r.podTarget(ctx, &pod)
+		if err != nil {
+			return "", "", err
+		}
+		if kind != "" {
+			return kind, name, nil
+		}
+		// nothing we can scale owns this one, see if any other pods have something.
+	}
+	logger.Info("No Deployment, StatefulSet or ReplicaSet owner found")
+	return "", "", errOwnerNotFound
+}
+
+// possibleTarget returns the owner that could lead podTarget to something we scale, nil if there is none.
+// Kind of funny since a deployment pod will be owned by a replicaset, so that is the one returned either way.
+func possibleTarget(owners []metav1.OwnerReference) *metav1.OwnerReference {
+	for i, owner := range owners {
+		if owner.Kind == "ReplicaSet" || owner.Kind == "StatefulSet" {
+			return &owners[i]
+		}
+	}
+	return nil
+}
 
+// podTarget returns the kind and name of the workload we'd scale for pod, empty if there is none.
+// A ReplicaSet a Deployment owns resolves to the Deployment. One with no owner at all is scaled directly,
+// but one owned by anything else (an Argo Rollout, say) is left alone since its owner would undo the scaling.
+func (r *PDBToEvictionAutoScalerReconciler) podTarget(ctx context.Context, pod *corev1.Pod) (string, string, error) {
+	logger := log.FromContext(ctx)
+	owner := possibleTarget(pod.OwnerReferences)
+	if owner == nil {
+		return "", "", nil
+	}
+	// StatefulSets own their pods directly
+	if owner.Kind == "StatefulSet" {
+		logger.Info("Found StatefulSet owner", "statefulSet", owner.Name)
+		return statefulSetKind, owner.Name, nil
+	}
+
+	replicaSet := &appsv1.ReplicaSet{}
+	if err := r.Get(ctx, k8s_types.NamespacedName{Name: owner.Name, Namespace: pod.Namespace}, replicaSet); err != nil {
+		if apierrors.IsNotFound(err) {
+			return "", "", nil // going away with its pods
 		}
+		return "", "", fmt.Errorf("error fetching ReplicaSet: %v", err)
+	}
+	logger.Info("Found ReplicaSet", "replicaSet", replicaSet.Name)
+
+	// Look for the Deployment owner of the ReplicaSet
+	for _, rsOwnerRef := range replicaSet.OwnerReferences {
+		if rsOwnerRef.Kind == "Deployment" {
+			logger.Info("Found Deployment owner", "deployment", rsOwnerRef.Name)
+			return deploymentKind, rsOwnerRef.Name, nil
+		}
+	}
+	if len(replicaSet.OwnerReferences) == 0 {
+		logger.Info("Found bare ReplicaSet", "replicaSet", replicaSet.Name)
+		return replicaSetKind, replicaSet.Name, nil
 	}
-	logger.Info("No Deployment owner found")
-	return "", errOwnerNotFound
+	return "", "", nil
 }
@@ -2,6 +2,7 @@ package controllers
 
 import (
 	"context"
+	"fmt"
 
 	types "github.com/azure/eviction-autoscaler/api/v1"
 	. "github.com/onsi/ginkgo/v2"
@@ -9,6 +10,7 @@ import (
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	policyv1 "k8s.io/api/policy/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	machinery_types "k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/intstr"
@@ -212,6 +214,35 @@ var _ = Describe("PDBToEvictionAutoScalerReconciler", func() {
 			// Verify that the EvictionAutoScaler was created
 			err = k8sClient.Get(ctx, client.ObjectKey{Name: deploymentName, Namespace: namespace}, EvictionAutoScaler)
 			Expect(err).Should(Succeed()) // EvictionAutoScaler should now exist
+			Expect(EvictionAutoScaler.Labels).To(HaveKeyWithValue(AutoCreatedLabelKey, "true"))
+		})
+
+		It("should not create a EvictionAutoScaler for an annotated pdb", func() {
+			for i, annotation := range []string{OptOutAnnotationKey, DoNotRecreateAnnotationKey} {
+				name := fmt.Sprintf("%s-%d", deploymentName, i)
+				pdb := &policyv1.PodDisruptionBudget{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:        name,
+						Namespace:   namespace,
+						Annotations: map[string]string{annotation: "true"},
+					},
+					Spec: policyv1.PodDisruptionBudgetSpec{
+						Selector: &metav1.LabelSelector{MatchLabels: map[string]string{
+							"app": deploymentName,
+						},
+						},
+					},
+				}
+				Expect(k8sClient.Create(ctx, pdb)).Should(Succeed())
+
+				_, err := reconciler.Reconcile(ctx, reconcile.Request{
+					NamespacedName: client.ObjectKey{Name: name, Namespace: namespace},
+				})
+				Expect(err).ShouldNot(HaveOccurred())
+
+				err = k8sClient.Get(ctx, client.ObjectKey{Name: name, Namespace: namespace}, &types.EvictionAutoScaler{})
+				Expect(apierrors.IsNotFound(err)).To(BeTrue(), annotation)
+			}
 		})
 	})
 
@@ -0,0 +1,47 @@
+package controllers
+
+import (
+	"context"
+	"sync/atomic"
+
+	"github.com/azure/eviction-autoscaler/internal/metrics"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// PodConditionWrites decides if DisruptionTarget pod conditions are written. The node controller and the eviction
+// webhook share one, so the first Forbidden write from either stops both. A nil PodConditionWrites always writes.
+type PodConditionWrites struct {
+	// Disabled never sets or clears the DisruptionTarget pod condition, from --disable-pod-condition-writes, for
+	// clusters that don't grant patch on pods/status.
+	Disabled bool
+	// Global is the EvictionAutoScalerConfig, whose disablePodConditionWrites wins over Disabled. Nil has none.
+	Global *GlobalConfig
+	// forbidden is set once writing pods/status was forbidden and stops us trying again.
+	forbidden atomic.Bool
+}
+
+// Off reports if DisruptionTarget conditions are left alone, by Disabled, the EvictionAutoScalerConfig's in its
+// place, or since writing pods/status was forbidden.
+func (w *PodConditionWrites) Off() bool {
+	if w == nil {
+		return false
+	}
+	disabled := w.Disabled
+	if override := w.Global.Spec().DisablePodConditionWrites; override != nil {
+		disabled = *override
+	}
+	return disabled || w.forbidden.Load()
+}
+
+// Forbid stops DisruptionTarget writes for the rest of the process after err forbade one, logging it once, so a
+// cluster not granting patch on pods/status still gets its surges.
+func (w *PodConditionWrites) Forbid(ctx context.Context, err error) {
+	if w == nil {
+		log.FromContext(ctx).Error(err, "Not allowed to write pod status")
+		return
+	}
+	if w.forbidden.CompareAndSwap(false, true) {
+		log.FromContext(ctx).Error(err, "Not allowed to write pod status, no longer setting DisruptionTarget on pods, evictions are still surged for")
+		metrics.PodConditionWritesDisabledGauge.Set(1)
+	}
+}
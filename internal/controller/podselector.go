@@ -0,0 +1,27 @@
+package controllers
+
+import (
+	myappsv1 "github.com/azure/eviction-autoscaler/api/v1"
+)
+
+// podSelectorReplicas returns the replicas an EvictionAutoScaler with spec.podSelector wants for its evictions so far:
+// one more than the baseline for every pod evicted since the last handled eviction, capped by maxReplicas.
+// Zero for ones with a pdb, whose surge is sized by the pdb instead.
+func podSelectorReplicas(EvictionAutoScaler *myappsv1.EvictionAutoScaler) int32 {
+	if EvictionAutoScaler.Spec.PodSelector == nil {
+		return 0
+	}
+	handled := EvictionAutoScaler.Status.HandledEviction.EvictionTime
+	pods := map[string]bool{}
+	for _, eviction := range EvictionAutoScaler.Status.RecentEvictions {
+		// the node controller records a pod again on every resync so count pods, not records
+		if !eviction.Expired && eviction.EvictionTime.After(handled.Time) {
+			pods[eviction.PodName] = true
+		}
+	}
+	replicas := EvictionAutoScaler.Status.MinReplicas + int32(len(pods))
+	if maxReplicas := EvictionAutoScaler.Spec.MaxReplicas; maxReplicas != nil && replicas > *maxReplicas {
+		return *maxReplicas
+	}
+	return replicas
+}
@@ -0,0 +1,74 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	v1 "github.com/azure/eviction-autoscaler/api/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// TestPodSelector checks an EvictionAutoScaler without a pdb surges one replica per pod evicted, up to maxReplicas.
+func TestPodSelector(t *testing.T) {
+	ctx := context.Background()
+	testScheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(testScheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := v1.AddToScheme(testScheme); err != nil {
+		t.Fatal(err)
+	}
+	key := types.NamespacedName{Namespace: "default", Name: "web"}
+	evicted := metav1.NewTime(time.Now().Add(-time.Second).Truncate(time.Second))
+	recentEvictions := []v1.EvictionRecord{
+		{PodName: "web-1", EvictionTime: evicted},
+		{PodName: "web-2", EvictionTime: evicted},
+		{PodName: "web-1", EvictionTime: evicted}, // recorded again on resync
+		{PodName: "web-3", EvictionTime: evicted, Expired: true},
+	}
+
+	tests := []struct {
+		name        string
+		maxReplicas *int32
+		replicas    int32
+	}{
+		{name: "per pod", replicas: 5},
+		{name: "max replicas", maxReplicas: ptr.To(int32(4)), replicas: 4},
+	}
+	for _, test := range tests {
+		fakeClient := fake.NewClientBuilder().WithScheme(testScheme).WithStatusSubresource(&v1.EvictionAutoScaler{}).WithObjects(
+			&appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default", Generation: 2},
+				Spec:       appsv1.DeploymentSpec{Replicas: ptr.To(int32(3))},
+			},
+			&v1.EvictionAutoScaler{
+				ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+				Spec: v1.EvictionAutoScalerSpec{TargetName: "web", TargetKind: deploymentKind, MaxReplicas: test.maxReplicas,
+					PodSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}}},
+				Status: v1.EvictionAutoScalerStatus{MinReplicas: 3, TargetGeneration: 2,
+					LastEviction: recentEvictions[2].Eviction(), RecentEvictions: recentEvictions},
+			},
+		).Build()
+		r := &EvictionAutoScalerReconciler{Client: fakeClient, Scheme: testScheme, Recorder: record.NewFakeRecorder(10)}
+		if _, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: key}); err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+
+		deployment := &appsv1.Deployment{}
+		if err := fakeClient.Get(ctx, key, deployment); err != nil {
+			t.Fatal(err)
+		}
+		if *deployment.Spec.Replicas != test.replicas {
+			t.Errorf("%s: got %d replicas, want %d", test.name, *deployment.Spec.Replicas, test.replicas)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// ConditionQuotaExceeded is true while a surge wasn't made because a ResourceQuota would reject its pods.
+const ConditionQuotaExceeded = "QuotaExceeded"
+
+// podTemplate returns the pod template of target, nil for targetRef targets since we don't know where theirs lives.
+func podTemplate(target Surger) *corev1.PodTemplateSpec {
+	switch target := target.(type) {
+	case *DeploymentWrapper:
+		return &target.obj.Spec.Template
+	case *StatefulSetWrapper:
+		return &target.obj.Spec.Template
+	case *ReplicaSetWrapper:
+		return &target.obj.Spec.Template
+	}
+	return nil
+}
+
+// surgeQuotaExceeded returns why adding replicas pods of template would go over one of namespace's ResourceQuotas,
+// empty if they fit. The pods would only be rejected at admission, leaving a surge that never shows up.
+func (r *EvictionAutoScalerReconciler) surgeQuotaExceeded(ctx context.Context, namespace string, template *corev1.PodTemplateSpec, replicas int32) (string, error) {
+	if template == nil || replicas <= 0 {
+		return "", nil
+	}
+	quotaList := &corev1.ResourceQuotaList{}
+	if err := r.List(ctx, quotaList, client.InNamespace(namespace)); err != nil {
+		return "", err
+	}
+	return quotaExceeded(quotaList.Items, &template.Spec, replicas), nil
+}
+
+// quotaExceeded is surgeQuotaExceeded for the quotas listed. It is best effort: quotas with scopes are skipped since
+// whether they apply depends on the pod's priority class, termination and so on, and unknown resources are ignored.
+func quotaExceeded(quotas []corev1.ResourceQuota, spec *corev1.PodSpec, replicas int32) string {
+	for _, quota := range quotas {
+		if len(quota.Spec.Scopes) > 0 || quota.Spec.ScopeSelector != nil {
+			continue
+		}
+		for _, name := range slices.Sorted(maps.Keys(quota.Spec.Hard)) {
+			hard := quota.Spec.Hard[name]
+			perPod, ok := podUsage(spec, name)
+			if !ok {
+				continue
+			}
+			used := quota.Status.Used[name]
+			needed := used.DeepCopy()
+			for range replicas {
+				needed.Add(perPod)
+			}
+			if needed.Cmp(hard) > 0 {
+				return fmt.Sprintf("%d more pods would exceed %s of ResourceQuota %s: %s used of %s", replicas, name, quota.Name, used.String(), hard.String())
+			}
+		}
+	}
+	return ""
+}
+
+// podUsage returns what one pod of spec counts against the quota resource name, false for ones we don't evaluate.
+func podUsage(spec *corev1.PodSpec, name corev1.ResourceName) (resource.Quantity, bool) {
+	switch name {
+	case corev1.ResourcePods, "count/pods":
+		return resource.MustParse("1"), true
+	case corev1.ResourceCPU, corev1.ResourceMemory, corev1.ResourceEphemeralStorage:
+		return podResources(spec, name, func(c *corev1.Container) corev1.ResourceList { return c.Resources.Requests }), true
+	}
+	if requested, found := strings.CutPrefix(string(name), "requests."); found {
+		return podResources(spec, corev1.ResourceName(requested), func(c *corev1.Container) corev1.ResourceList { return c.Resources.Requests }), true
+	}
+	if limited, found := strings.CutPrefix(string(name), "limits."); found {
+		return podResources(spec, corev1.ResourceName(limited), func(c *corev1.Container) corev1.ResourceList { return c.Resources.Limits }), true
+	}
+	return resource.Quantity{}, false
+}
+
+// podResources sums name over spec's containers like the quota admission does: the larger of all containers
+// and the biggest init container, plus the pod overhead.
+func podResources(spec *corev1.PodSpec, name corev1.ResourceName, list func(*corev1.Container) corev1.ResourceList) resource.Quantity {
+	total := resource.Quantity{}
+	for i := range spec.Containers {
+		if quantity, found := list(&spec.Containers[i])[name]; found {
+			total.Add(quantity)
+		}
+	}
+	for i := range spec.InitContainers {
+		if quantity, found := list(&spec.InitContainers[i])[name]; found && quantity.Cmp(total) > 0 {
+			total = quantity.DeepCopy()
+		}
+	}
+	if overhead, found := spec.Overhead[name]; found {
+		total.Add(overhead)
+	}
+	return total
+}
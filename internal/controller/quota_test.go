@@ -0,0 +1,66 @@
+package controllers
+
+import (
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestQuotaExceeded(t *testing.T) {
+	spec := &corev1.PodSpec{
+		Containers: []corev1.Container{
+			{Resources: corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("250m"), corev1.ResourceMemory: resource.MustParse("256Mi")},
+				Limits:   corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("512Mi")},
+			}},
+			{Resources: corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("250m")},
+			}},
+		},
+		// bigger than the containers' 500m so it is what a pod counts
+		InitContainers: []corev1.Container{{Resources: corev1.ResourceRequirements{
+			Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("1")},
+		}}},
+	}
+	quota := func(name string, hard, used corev1.ResourceList, scopes ...corev1.ResourceQuotaScope) corev1.ResourceQuota {
+		return corev1.ResourceQuota{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec:       corev1.ResourceQuotaSpec{Hard: hard, Scopes: scopes},
+			Status:     corev1.ResourceQuotaStatus{Hard: hard, Used: used},
+		}
+	}
+	tests := []struct {
+		name     string
+		quota    corev1.ResourceQuota
+		replicas int32
+		want     string // substring, empty for fits
+	}{
+		{name: "cpu fits", replicas: 2,
+			quota: quota("compute", corev1.ResourceList{corev1.ResourceRequestsCPU: resource.MustParse("4")}, corev1.ResourceList{corev1.ResourceRequestsCPU: resource.MustParse("2")})},
+		{name: "cpu", replicas: 3, want: "requests.cpu of ResourceQuota compute: 2 used of 4",
+			quota: quota("compute", corev1.ResourceList{corev1.ResourceRequestsCPU: resource.MustParse("4")}, corev1.ResourceList{corev1.ResourceRequestsCPU: resource.MustParse("2")})},
+		{name: "bare cpu", replicas: 1, want: "cpu of ResourceQuota compute",
+			quota: quota("compute", corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("1500m")}, corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("1")})},
+		{name: "memory", replicas: 1, want: "requests.memory of ResourceQuota memory",
+			quota: quota("memory", corev1.ResourceList{corev1.ResourceRequestsMemory: resource.MustParse("1Gi")}, corev1.ResourceList{corev1.ResourceRequestsMemory: resource.MustParse("800Mi")})},
+		{name: "memory limits", replicas: 1, want: "limits.memory of ResourceQuota memory",
+			quota: quota("memory", corev1.ResourceList{corev1.ResourceLimitsMemory: resource.MustParse("1Gi")}, corev1.ResourceList{corev1.ResourceLimitsMemory: resource.MustParse("600Mi")})},
+		{name: "pods", replicas: 1, want: "pods of ResourceQuota objects: 10 used of 10",
+			quota: quota("objects", corev1.ResourceList{corev1.ResourcePods: resource.MustParse("10")}, corev1.ResourceList{corev1.ResourcePods: resource.MustParse("10")})},
+		{name: "count/pods", replicas: 2, want: "count/pods of ResourceQuota objects",
+			quota: quota("objects", corev1.ResourceList{"count/pods": resource.MustParse("10")}, corev1.ResourceList{"count/pods": resource.MustParse("9")})},
+		{name: "other objects", replicas: 1,
+			quota: quota("objects", corev1.ResourceList{"count/configmaps": resource.MustParse("1")}, corev1.ResourceList{"count/configmaps": resource.MustParse("1")})},
+		{name: "scoped", replicas: 1,
+			quota: quota("objects", corev1.ResourceList{corev1.ResourcePods: resource.MustParse("10")}, corev1.ResourceList{corev1.ResourcePods: resource.MustParse("10")}, corev1.ResourceQuotaScopeBestEffort)},
+	}
+	for _, test := range tests {
+		got := quotaExceeded([]corev1.ResourceQuota{test.quota}, spec, test.replicas)
+		if (got == "") != (test.want == "") || !strings.Contains(got, test.want) {
+			t.Errorf("%s: got %q, want %q", test.name, got, test.want)
+		}
+	}
+}
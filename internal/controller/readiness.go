@@ -0,0 +1,53 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/healthz"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// readyCheckTimeout keeps a check waiting on a cold cache under the kubelet's default one second probe timeout.
+const readyCheckTimeout = 500 * time.Millisecond
+
+// CacheSyncer is the part of the manager's cache CacheSyncChecker needs.
+type CacheSyncer interface {
+	WaitForCacheSync(ctx context.Context) bool
+}
+
+// CacheSyncChecker is unready until every informer the controllers started has synced,
+// so nothing is decided from a cold cache.
+func CacheSyncChecker(cache CacheSyncer) healthz.Checker {
+	return func(req *http.Request) error {
+		ctx, cancel := context.WithTimeout(req.Context(), readyCheckTimeout)
+		defer cancel()
+		if !cache.WaitForCacheSync(ctx) {
+			return unready(req.Context(), "informer caches", fmt.Errorf("not synced"))
+		}
+		return nil
+	}
+}
+
+// PodNodeIndexChecker is unready until pods can be listed by the NodeNameIndex the node controller relies on.
+func PodNodeIndexChecker(reader client.Reader) healthz.Checker {
+	return func(req *http.Request) error {
+		ctx, cancel := context.WithTimeout(req.Context(), readyCheckTimeout)
+		defer cancel()
+		if err := reader.List(ctx, &corev1.PodList{}, client.MatchingFields{NodeNameIndex: ""}, client.Limit(1)); err != nil {
+			return unready(req.Context(), "pod "+NodeNameIndex+" index", err)
+		}
+		return nil
+	}
+}
+
+// unready logs why component isn't ready since the readyz body only names the failing check.
+func unready(ctx context.Context, component string, err error) error {
+	err = fmt.Errorf("%s not ready: %w", component, err)
+	log.FromContext(ctx).Info("Readiness check failed", "component", component, "error", err.Error())
+	return err
+}
@@ -0,0 +1,44 @@
+package controllers
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/healthz"
+)
+
+type cacheSynced bool
+
+func (c cacheSynced) WaitForCacheSync(ctx context.Context) bool {
+	return bool(c)
+}
+
+func TestReadinessCheckers(t *testing.T) {
+	testScheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(testScheme); err != nil {
+		t.Fatal(err)
+	}
+	indexed := fake.NewClientBuilder().WithScheme(testScheme).WithIndex(&corev1.Pod{}, NodeNameIndex, podNodeName).Build()
+	unindexed := fake.NewClientBuilder().WithScheme(testScheme).Build()
+
+	tests := []struct {
+		name  string
+		check healthz.Checker
+		ready bool
+	}{
+		{name: "synced", check: CacheSyncChecker(cacheSynced(true)), ready: true},
+		{name: "not synced", check: CacheSyncChecker(cacheSynced(false))},
+		{name: "indexed", check: PodNodeIndexChecker(indexed), ready: true},
+		{name: "no index", check: PodNodeIndexChecker(unindexed)},
+	}
+	for _, test := range tests {
+		if err := test.check(httptest.NewRequest("GET", "/readyz", nil)); (err == nil) != test.ready {
+			t.Errorf("%s: got %v, want ready %v", test.name, err, test.ready)
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package controllers
+
+import (
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// ConditionRolloutInProgress is true while an Argo Rollout target is mid canary or blue-green update and we hold off scaling it.
+const ConditionRolloutInProgress = "RolloutInProgress"
+
+var rolloutGroupKind = schema.GroupKind{Group: "argoproj.io", Kind: "Rollout"}
+
+// rolloutInProgress returns the step an Argo Rollout targetRef target is in the middle of, empty if it isn't a Rollout
+// or its update is promoted. Changing replicas mid step can abort the rollout's analysis so neither a surge nor
+// a scale down is made till it is done. The Rollout is read unstructured so there is no dependency on Argo's types.
+func rolloutInProgress(target Surger) string {
+	scaled, ok := target.(*ScaleWrapper)
+	if !ok || scaled.obj.GroupVersionKind().GroupKind() != rolloutGroupKind {
+		return ""
+	}
+	obj := scaled.obj.Object
+	currentHash, _, _ := unstructured.NestedString(obj, "status", "currentPodHash")
+	stableHash, _, _ := unstructured.NestedString(obj, "status", "stableRS")
+	if currentHash == "" || currentHash == stableHash {
+		return ""
+	}
+	if steps, found, _ := unstructured.NestedSlice(obj, "spec", "strategy", "canary", "steps"); found {
+		step, _, _ := unstructured.NestedInt64(obj, "status", "currentStepIndex")
+		if int(step) >= len(steps) {
+			return fmt.Sprintf("canary of revision %s is being promoted", currentHash)
+		}
+		return fmt.Sprintf("canary of revision %s is at step %d of %d", currentHash, step+1, len(steps))
+	}
+	if _, found, _ := unstructured.NestedMap(obj, "spec", "strategy", "blueGreen"); found {
+		return fmt.Sprintf("blue-green revision %s is not promoted yet", currentHash)
+	}
+	return fmt.Sprintf("revision %s is not promoted yet", currentHash)
+}
@@ -0,0 +1,44 @@
+package controllers
+
+import (
+	"strings"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestRolloutInProgress(t *testing.T) {
+	rollout := func(strategy map[string]interface{}, status map[string]interface{}) Surger {
+		obj := &unstructured.Unstructured{Object: map[string]interface{}{
+			"spec":   map[string]interface{}{"strategy": strategy},
+			"status": status,
+		}}
+		obj.SetAPIVersion("argoproj.io/v1alpha1")
+		obj.SetKind("Rollout")
+		return &ScaleWrapper{obj: obj}
+	}
+	canary := map[string]interface{}{"canary": map[string]interface{}{"steps": []interface{}{
+		map[string]interface{}{"setWeight": int64(20)},
+		map[string]interface{}{"pause": map[string]interface{}{}},
+	}}}
+	blueGreen := map[string]interface{}{"blueGreen": map[string]interface{}{"activeService": "web"}}
+	tests := []struct {
+		name   string
+		target Surger
+		want   string // substring, empty for not in progress
+	}{
+		{name: "promoted canary", target: rollout(canary, map[string]interface{}{"currentPodHash": "abc", "stableRS": "abc", "currentStepIndex": int64(2)})},
+		{name: "canary step", target: rollout(canary, map[string]interface{}{"currentPodHash": "def", "stableRS": "abc", "currentStepIndex": int64(1)}), want: "step 2 of 2"},
+		{name: "canary steps done", target: rollout(canary, map[string]interface{}{"currentPodHash": "def", "stableRS": "abc", "currentStepIndex": int64(2)}), want: "promoted"},
+		{name: "blue-green preview", target: rollout(blueGreen, map[string]interface{}{"currentPodHash": "def", "stableRS": "abc"}), want: "blue-green"},
+		{name: "new rollout", target: rollout(blueGreen, map[string]interface{}{})},
+		{name: "deployment", target: &DeploymentWrapper{obj: &appsv1.Deployment{}}},
+	}
+	for _, test := range tests {
+		got := rolloutInProgress(test.target)
+		if (got == "") != (test.want == "") || !strings.Contains(got, test.want) {
+			t.Errorf("%s: got %q, want %q", test.name, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package controllers
+
+import (
+	"context"
+
+	myappsv1 "github.com/azure/eviction-autoscaler/api/v1"
+	"github.com/azure/eviction-autoscaler/internal/evictionutil"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// setScaleStatus derives status.baselineReplicas, targetReplicas and currentSurge from minReplicas and surgeReplicas
+// so they are written with every status update and never disagree with them.
+func setScaleStatus(status *myappsv1.EvictionAutoScalerStatus) {
+	status.BaselineReplicas = status.MinReplicas
+	status.TargetReplicas = status.MinReplicas
+	if status.SurgeReplicas > 0 {
+		status.TargetReplicas = status.SurgeReplicas
+	}
+	status.CurrentSurge = max(status.TargetReplicas-status.BaselineReplicas, 0)
+}
+
+// setSummaryStatus sets status.target and status.reason, the kubectl get columns along with baselineReplicas,
+// currentSurge and lastEviction, from the target this reconcile resolved into spec and the top level condition.
+// The eviction webhook and node controller only touch lastEviction and drains so they leave both as they were.
+func setSummaryStatus(EvictionAutoScaler *myappsv1.EvictionAutoScaler) {
+	status := &EvictionAutoScaler.Status
+	status.Target = summaryTarget(EvictionAutoScaler)
+	status.Reason = ""
+	if condition := meta.FindStatusCondition(status.Conditions, "Degraded"); condition != nil && condition.Status == metav1.ConditionTrue {
+		status.Reason = condition.Reason
+	} else if condition := meta.FindStatusCondition(status.Conditions, "Ready"); condition != nil {
+		status.Reason = condition.Reason
+	}
+}
+
+// summaryTarget is the status.target of EvictionAutoScaler, the surged target if none is resolved.
+func summaryTarget(EvictionAutoScaler *myappsv1.EvictionAutoScaler) string {
+	if kind, name := targetKindAndName(EvictionAutoScaler); name != "" {
+		return kind + "/" + name
+	}
+	return EvictionAutoScaler.Status.SurgedTarget
+}
+
+// updateScaleStatus is updateStatus for after the target was scaled. The scale already happened so losing its
+// status to a conflict would leave us restoring from stale replicas, and the eviction webhook and node controller
+// write status concurrently during a drain. On conflict it keeps our status on top of the latest object, taking
+// only the fields those other writers own from it, and tries again.
+func (r *EvictionAutoScalerReconciler) updateScaleStatus(ctx context.Context, EvictionAutoScaler *myappsv1.EvictionAutoScaler) error {
+	first := true
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		if !first {
+			latest := &myappsv1.EvictionAutoScaler{}
+			if err := r.Get(ctx, client.ObjectKeyFromObject(EvictionAutoScaler), latest); err != nil {
+				return err
+			}
+			EvictionAutoScaler.ResourceVersion = latest.ResourceVersion
+			mergeForeignStatus(&EvictionAutoScaler.Status, &latest.Status)
+		}
+		first = false
+		return r.updateStatus(ctx, EvictionAutoScaler)
+	})
+}
+
+// mergeForeignStatus copies what the eviction webhook and the node controller write from latest into status, the
+// rest of status being ours.
+func mergeForeignStatus(status, latest *myappsv1.EvictionAutoScalerStatus) {
+	status.LastEviction = latest.LastEviction
+	status.RecentEvictions = latest.RecentEvictions
+	status.DrainingNodes = latest.DrainingNodes
+	status.DrainedTime = latest.DrainedTime
+	status.ExpiredEviction = latest.ExpiredEviction
+	// deadlineSurged is ours, the rest of a node's drain is the node controller's
+	surged := map[string]bool{}
+	for _, drain := range status.NodeDrains {
+		surged[drain.Node] = drain.DeadlineSurged
+	}
+	status.NodeDrains = append([]myappsv1.NodeDrain(nil), latest.NodeDrains...)
+	for i := range status.NodeDrains {
+		status.NodeDrains[i].DeadlineSurged = status.NodeDrains[i].DeadlineSurged || surged[status.NodeDrains[i].Node]
+	}
+	for _, conditionType := range []string{evictionutil.ConditionExceededDrainDeadline, evictionutil.ConditionConflictingSelectors} {
+		if condition := meta.FindStatusCondition(latest.Conditions, conditionType); condition != nil {
+			meta.SetStatusCondition(&status.Conditions, *condition)
+		} else {
+			meta.RemoveStatusCondition(&status.Conditions, conditionType)
+		}
+	}
+}
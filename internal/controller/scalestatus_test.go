@@ -0,0 +1,139 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	v1 "github.com/azure/eviction-autoscaler/api/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// TestScaleStatusConflict checks a surge's status is still written when a drain is recorded between the scale and
+// its status write, and that the drain isn't lost to it.
+func TestScaleStatusConflict(t *testing.T) {
+	ctx := context.Background()
+	testScheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(testScheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := v1.AddToScheme(testScheme); err != nil {
+		t.Fatal(err)
+	}
+	key := types.NamespacedName{Namespace: "default", Name: "web"}
+	lastEviction := v1.Eviction{PodName: "web-1", EvictionTime: metav1.NewTime(time.Now().Add(-time.Second).Truncate(time.Second))}
+	raced := false
+	fakeClient := fake.NewClientBuilder().WithScheme(testScheme).
+		WithStatusSubresource(&v1.EvictionAutoScaler{}).
+		WithIndex(&v1.EvictionAutoScaler{}, PDBIndex, evictionAutoScalerPDB).
+		WithIndex(&v1.EvictionAutoScaler{}, TargetIndex, evictionAutoScalerTarget).
+		WithObjects(
+			&appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default", Generation: 2},
+				Spec:       appsv1.DeploymentSpec{Replicas: ptr.To(int32(3))},
+			},
+			&policyv1.PodDisruptionBudget{
+				ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+				Spec:       policyv1.PodDisruptionBudgetSpec{MinAvailable: ptr.To(intstr.FromInt32(3))},
+			},
+			&v1.EvictionAutoScaler{
+				ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+				Spec:       v1.EvictionAutoScalerSpec{TargetName: "web", TargetKind: deploymentKind},
+				Status:     v1.EvictionAutoScalerStatus{MinReplicas: 3, TargetGeneration: 2, LastEviction: lastEviction},
+			},
+		).
+		WithInterceptorFuncs(interceptor.Funcs{
+			SubResourceUpdate: func(ctx context.Context, c client.Client, subResourceName string, obj client.Object, opts ...client.SubResourceUpdateOption) error {
+				deployment := &appsv1.Deployment{}
+				if err := c.Get(ctx, key, deployment); err != nil {
+					return err
+				}
+				// the node controller records a drain right after the surge
+				if _, ok := obj.(*v1.EvictionAutoScaler); ok && !raced && *deployment.Spec.Replicas > 3 {
+					raced = true
+					current := &v1.EvictionAutoScaler{}
+					if err := c.Get(ctx, key, current); err != nil {
+						return err
+					}
+					current.Status.DrainingNodes = []string{"node-1"}
+					if err := c.Status().Update(ctx, current); err != nil {
+						return err
+					}
+				}
+				return c.SubResource(subResourceName).Update(ctx, obj, opts...)
+			},
+		}).Build()
+	r := &EvictionAutoScalerReconciler{Client: fakeClient, Scheme: testScheme, Recorder: record.NewFakeRecorder(20)}
+
+	if _, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: key}); err != nil {
+		t.Fatal(err)
+	}
+	if !raced {
+		t.Fatal("the target was never surged")
+	}
+	EvictionAutoScaler := &v1.EvictionAutoScaler{}
+	if err := fakeClient.Get(ctx, key, EvictionAutoScaler); err != nil {
+		t.Fatal(err)
+	}
+	status := EvictionAutoScaler.Status
+	if status.SurgeReplicas != 4 || status.BaselineReplicas != 3 || status.TargetReplicas != 4 || status.CurrentSurge != 1 || status.LastScaleTime.IsZero() {
+		t.Errorf("got surgeReplicas %d baselineReplicas %d targetReplicas %d currentSurge %d lastScaleTime %v, want the surge to 4 recorded",
+			status.SurgeReplicas, status.BaselineReplicas, status.TargetReplicas, status.CurrentSurge, status.LastScaleTime)
+	}
+	if len(status.DrainingNodes) != 1 || status.DrainingNodes[0] != "node-1" {
+		t.Errorf("got drainingNodes %v, want the concurrently recorded node-1 kept", status.DrainingNodes)
+	}
+}
+
+func TestSummaryStatus(t *testing.T) {
+	ready := metav1.Condition{Type: "Ready", Status: metav1.ConditionTrue, Reason: "Reconciled"}
+	degraded := metav1.Condition{Type: "Degraded", Status: metav1.ConditionTrue, Reason: "TargetNotFound"}
+	tests := []struct {
+		name       string
+		spec       v1.EvictionAutoScalerSpec
+		status     v1.EvictionAutoScalerStatus
+		wantTarget string
+		wantReason string
+	}{
+		{
+			name:       "named target",
+			spec:       v1.EvictionAutoScalerSpec{TargetKind: deploymentKind, TargetName: "web"},
+			status:     v1.EvictionAutoScalerStatus{Conditions: []metav1.Condition{ready}},
+			wantTarget: "deployment/web",
+			wantReason: "Reconciled",
+		},
+		{
+			name:       "targetRef",
+			spec:       v1.EvictionAutoScalerSpec{TargetRef: &v1.TargetReference{APIVersion: "argoproj.io/v1alpha1", Kind: "Rollout", Name: "web"}},
+			wantTarget: "Rollout/web",
+		},
+		{
+			name:       "undiscovered with a surge up",
+			status:     v1.EvictionAutoScalerStatus{SurgedTarget: "statefulset/db", Conditions: []metav1.Condition{ready, degraded}},
+			wantTarget: "statefulset/db",
+			wantReason: "TargetNotFound",
+		},
+	}
+	for _, test := range tests {
+		EvictionAutoScaler := &v1.EvictionAutoScaler{Spec: test.spec, Status: test.status}
+		setSummaryStatus(EvictionAutoScaler)
+		if got := EvictionAutoScaler.Status.Target; got != test.wantTarget {
+			t.Errorf("%s: got target %q, want %q", test.name, got, test.wantTarget)
+		}
+		if got := EvictionAutoScaler.Status.Reason; got != test.wantReason {
+			t.Errorf("%s: got reason %q, want %q", test.name, got, test.wantReason)
+		}
+	}
+}
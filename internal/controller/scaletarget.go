@@ -0,0 +1,88 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	myappsv1 "github.com/azure/eviction-autoscaler/api/v1"
+	"github.com/azure/eviction-autoscaler/internal/tracing"
+	"go.opentelemetry.io/otel/trace"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/scale"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// errBadTargetRef is a targetRef we can't resolve no matter how often we retry.
+var errBadTargetRef = errors.New("invalid targetRef")
+
+// NewScalesGetter builds the scale client used for spec.targetRef targets.
+func NewScalesGetter(cfg *rest.Config, mapper meta.RESTMapper) (scale.ScalesGetter, error) {
+	discoveryClient, err := discovery.NewDiscoveryClientForConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return scale.NewForConfig(cfg, mapper, dynamic.LegacyAPIPathResolverFunc, scale.NewDiscoveryScaleKindResolver(discoveryClient))
+}
+
+// getScaleTarget resolves ref with the RESTMapper and reads the target and its scale subresource.
+// The target is read too since scale doesn't carry the generation we track.
+func (r *EvictionAutoScalerReconciler) getScaleTarget(ctx context.Context, namespace string, ref *myappsv1.TargetReference) (*ScaleWrapper, error) {
+	if r.Scales == nil {
+		return nil, fmt.Errorf("no scale client to scale %s %s", ref.Kind, ref.Name)
+	}
+	gv, err := schema.ParseGroupVersion(ref.APIVersion)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errBadTargetRef, err)
+	}
+	mapping, err := r.RESTMapper().RESTMapping(gv.WithKind(ref.Kind).GroupKind(), gv.Version)
+	if err != nil {
+		return nil, err
+	}
+	if mapping.Scope.Name() != meta.RESTScopeNameNamespace {
+		return nil, fmt.Errorf("%w: %s is not namespaced", errBadTargetRef, mapping.GroupVersionKind.Kind)
+	}
+
+	obj := &unstructured.Unstructured{}
+	obj.SetGroupVersionKind(mapping.GroupVersionKind)
+	if err := r.Get(ctx, types.NamespacedName{Namespace: namespace, Name: ref.Name}, obj); err != nil {
+		return nil, err
+	}
+	resource := mapping.Resource.GroupResource()
+	targetScale, err := r.Scales.Scales(namespace).Get(ctx, resource, ref.Name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err // not found here means the kind has no scale subresource
+	}
+	return &ScaleWrapper{obj: obj, scale: targetScale, resource: resource}, nil
+}
+
+// updateTarget writes target's replicas, through the scale subresource for targetRef targets.
+func (r *EvictionAutoScalerReconciler) updateTarget(ctx context.Context, target Surger) (err error) {
+	ctx, span := tracing.Tracer.Start(ctx, "ScaleTarget", trace.WithAttributes(tracing.ReplicasKey.Int(int(target.GetReplicas()))))
+	defer func() { tracing.End(span, err) }()
+	scaled, ok := target.(*ScaleWrapper)
+	if !ok {
+		return r.Update(ctx, target.Obj())
+	}
+	updated, err := r.Scales.Scales(scaled.obj.GetNamespace()).Update(ctx, scaled.resource, scaled.scale, metav1.UpdateOptions{})
+	if err != nil {
+		return err
+	}
+	scaled.scale = updated
+	// pick up the generation the scale bumped
+	return r.Get(ctx, client.ObjectKeyFromObject(scaled.obj), scaled.obj)
+}
+
+// isTargetNotFound is true for targetRef errors that retrying won't fix until someone changes the
+// targetRef, installs the CRD or creates the target. Forbidden is included since it needs an RBAC change.
+func isTargetNotFound(err error) bool {
+	return errors.Is(err, errBadTargetRef) || meta.IsNoMatchError(err) || apierrors.IsNotFound(err) || apierrors.IsForbidden(err)
+}
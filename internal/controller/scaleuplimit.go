@@ -0,0 +1,107 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	myappsv1 "github.com/azure/eviction-autoscaler/api/v1"
+	"github.com/azure/eviction-autoscaler/internal/metrics"
+	"github.com/azure/eviction-autoscaler/internal/tracing"
+	"golang.org/x/time/rate"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// ConditionScaleUpThrottled is true while a surge waits for spec.scaleUpIntervalSeconds or --max-scaleups-per-minute to allow it.
+const ConditionScaleUpThrottled = "ScaleUpThrottled"
+
+// ScaleUpLimiter is a token bucket every scale-up in the process takes a token from, so a cluster upgrade
+// cordoning many nodes at once surges workloads a few at a time instead of all in the same minute.
+// A nil ScaleUpLimiter allows every scale-up.
+type ScaleUpLimiter struct {
+	mu      sync.Mutex
+	limiter *rate.Limiter
+	now     func() time.Time
+}
+
+// NewScaleUpLimiter allows perMinute scale-ups a minute, all at once after a quiet minute. Zero means no limit and returns nil.
+func NewScaleUpLimiter(perMinute int) *ScaleUpLimiter {
+	if perMinute <= 0 {
+		return nil
+	}
+	return &ScaleUpLimiter{limiter: rate.NewLimiter(rate.Limit(float64(perMinute)/60), perMinute), now: time.Now}
+}
+
+// Take takes a token and returns zero, or how long till one is available without taking it.
+func (l *ScaleUpLimiter) Take() time.Duration {
+	if l == nil {
+		return 0
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.now()
+	reservation := l.limiter.ReserveN(now, 1)
+	delay := reservation.DelayFrom(now)
+	if delay > 0 {
+		// whoever asks first once the token is there gets it, not whoever asked first
+		reservation.CancelAt(now)
+	}
+	return delay
+}
+
+// Tokens returns the scale-ups allowed right now, +Inf without a limit.
+func (l *ScaleUpLimiter) Tokens() float64 {
+	if l == nil {
+		return math.Inf(1)
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.limiter.TokensAt(l.now())
+}
+
+// Register makes l what eviction_autoscaler_scaleup_tokens reports.
+func (l *ScaleUpLimiter) Register() {
+	metrics.SetScaleUpTokens(l.Tokens)
+}
+
+// throttleScaleUp takes a scale-up token for EvictionAutoScaler once its spec.scaleUpIntervalSeconds is up. Till then,
+// or without a token, it marks it ScaleUpThrottled and returns how long to requeue for, jittered for tokens so the
+// throttled EvictionAutoScalers don't all come back for the same one. The eviction stays unhandled so the surge is
+// retried rather than dropped, sized for every eviction that came in while it waited.
+func (r *EvictionAutoScalerReconciler) throttleScaleUp(ctx context.Context, EvictionAutoScaler *myappsv1.EvictionAutoScaler, action string) time.Duration {
+	if interval := scaleUpIntervalFor(EvictionAutoScaler); interval > 0 && !EvictionAutoScaler.Status.LastScaleTime.IsZero() {
+		if delay := interval - time.Since(EvictionAutoScaler.Status.LastScaleTime.Time); delay > 0 {
+			message := fmt.Sprintf("waiting %s for spec.scaleUpIntervalSeconds to %s", delay.Round(time.Second), action)
+			log.FromContext(ctx).Info("Scale up waiting for interval", "evictionautoscaler", EvictionAutoScaler.Name, "delay", delay)
+			tracing.Decide(ctx, "scale-up-interval")
+			setCondition(&EvictionAutoScaler.Status.Conditions, ConditionScaleUpThrottled, metav1.ConditionTrue, "ScaleUpInterval", message)
+			return delay
+		}
+	}
+	// a dry run scales nothing, so it takes no token from the scale ups that do
+	var delay time.Duration
+	if !r.DryRun {
+		delay = r.ScaleUps.Take()
+	}
+	if delay == 0 {
+		clearCondition(&EvictionAutoScaler.Status.Conditions, ConditionScaleUpThrottled, "Allowed", "allowed to "+action)
+		return 0
+	}
+	message := fmt.Sprintf("waiting %s for --max-scaleups-per-minute to %s", delay.Round(time.Second), action)
+	log.FromContext(ctx).Info("Scale up throttled", "evictionautoscaler", EvictionAutoScaler.Name, "delay", delay)
+	tracing.Decide(ctx, "scale-up-throttled")
+	setCondition(&EvictionAutoScaler.Status.Conditions, ConditionScaleUpThrottled, metav1.ConditionTrue, "RateLimited", message)
+	return wait.Jitter(delay, 1)
+}
+
+// scaleUpIntervalFor returns spec.scaleUpIntervalSeconds, zero if unset.
+func scaleUpIntervalFor(EvictionAutoScaler *myappsv1.EvictionAutoScaler) time.Duration {
+	if interval := EvictionAutoScaler.Spec.ScaleUpIntervalSeconds; interval != nil && *interval > 0 {
+		return time.Duration(*interval) * time.Second
+	}
+	return 0
+}
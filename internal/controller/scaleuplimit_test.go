@@ -0,0 +1,195 @@
+package controllers
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	v1 "github.com/azure/eviction-autoscaler/api/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func TestScaleUpLimiter(t *testing.T) {
+	now := time.Now()
+	limiter := NewScaleUpLimiter(2)
+	limiter.now = func() time.Time { return now }
+
+	for i := 0; i < 2; i++ {
+		if delay := limiter.Take(); delay != 0 {
+			t.Fatalf("scale-up %d waited %s within the burst", i, delay)
+		}
+	}
+	if tokens := limiter.Tokens(); tokens != 0 {
+		t.Errorf("got %v tokens after the burst", tokens)
+	}
+	if delay := limiter.Take(); delay != 30*time.Second {
+		t.Errorf("got delay %s, want 30s for 2 a minute", delay)
+	}
+	if delay := limiter.Take(); delay != 30*time.Second {
+		t.Errorf("got delay %s after a throttled take, want it not to have reserved a token", delay)
+	}
+	now = now.Add(30 * time.Second)
+	if delay := limiter.Take(); delay != 0 {
+		t.Errorf("waited %s once a token was back", delay)
+	}
+
+	var unlimited *ScaleUpLimiter
+	if NewScaleUpLimiter(0) != nil || unlimited.Take() != 0 || !math.IsInf(unlimited.Tokens(), 1) {
+		t.Error("no limit should allow every scale-up")
+	}
+}
+
+func TestThrottleScaleUp(t *testing.T) {
+	r := &EvictionAutoScalerReconciler{ScaleUps: NewScaleUpLimiter(1)}
+	EvictionAutoScaler := &v1.EvictionAutoScaler{}
+	if delay := r.throttleScaleUp(context.Background(), EvictionAutoScaler, "scale up"); delay != 0 {
+		t.Fatalf("first scale-up waited %s", delay)
+	}
+	delay := r.throttleScaleUp(context.Background(), EvictionAutoScaler, "scale up")
+	if delay < time.Minute-time.Second || delay > 2*time.Minute {
+		t.Errorf("got requeue after %s, want a jittered minute", delay)
+	}
+	if !meta.IsStatusConditionTrue(EvictionAutoScaler.Status.Conditions, ConditionScaleUpThrottled) {
+		t.Errorf("got conditions %v, want %s", EvictionAutoScaler.Status.Conditions, ConditionScaleUpThrottled)
+	}
+
+	r.ScaleUps = nil
+	if delay := r.throttleScaleUp(context.Background(), EvictionAutoScaler, "scale up"); delay != 0 {
+		t.Fatalf("unlimited scale-up waited %s", delay)
+	}
+	if condition := meta.FindStatusCondition(EvictionAutoScaler.Status.Conditions, ConditionScaleUpThrottled); condition.Status != "False" {
+		t.Errorf("got %s %s once allowed", condition.Type, condition.Status)
+	}
+}
+
+// TestScaleUpInterval checks evictions within spec.scaleUpIntervalSeconds of a surge are surged for in one step after it.
+func TestScaleUpInterval(t *testing.T) {
+	ctx := context.Background()
+	testScheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(testScheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := v1.AddToScheme(testScheme); err != nil {
+		t.Fatal(err)
+	}
+	key := types.NamespacedName{Namespace: "default", Name: "web"}
+	evicted := func(podName string) v1.EvictionRecord {
+		return v1.EvictionRecord{PodName: podName, EvictionTime: metav1.NewTime(time.Now().Truncate(time.Second)), Source: v1.EvictionSourceNodeCordon}
+	}
+	first := evicted("web-1")
+	fakeClient := fake.NewClientBuilder().WithScheme(testScheme).WithStatusSubresource(&v1.EvictionAutoScaler{}).WithObjects(
+		&appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default", Generation: 2},
+			Spec:       appsv1.DeploymentSpec{Replicas: ptr.To(int32(3))},
+		},
+		&v1.EvictionAutoScaler{
+			ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+			Spec: v1.EvictionAutoScalerSpec{TargetName: "web", TargetKind: deploymentKind, ScaleUpIntervalSeconds: ptr.To(int32(60)),
+				PodSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}}},
+			Status: v1.EvictionAutoScalerStatus{MinReplicas: 3, TargetGeneration: 2,
+				LastEviction: first.Eviction(), RecentEvictions: []v1.EvictionRecord{first}},
+		},
+	).Build()
+	r := &EvictionAutoScalerReconciler{Client: fakeClient, Scheme: testScheme, Recorder: record.NewFakeRecorder(10)}
+	// reconciles and returns the EvictionAutoScaler and the deployment's replicas
+	reconcileWeb := func() (*v1.EvictionAutoScaler, int32) {
+		if _, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: key}); err != nil {
+			t.Fatal(err)
+		}
+		EvictionAutoScaler := &v1.EvictionAutoScaler{}
+		if err := fakeClient.Get(ctx, key, EvictionAutoScaler); err != nil {
+			t.Fatal(err)
+		}
+		deployment := &appsv1.Deployment{}
+		if err := fakeClient.Get(ctx, key, deployment); err != nil {
+			t.Fatal(err)
+		}
+		return EvictionAutoScaler, *deployment.Spec.Replicas
+	}
+
+	EvictionAutoScaler, replicas := reconcileWeb()
+	if replicas != 4 || EvictionAutoScaler.Status.LastScaleTime.IsZero() {
+		t.Fatalf("got %d replicas and lastScaleTime %v for the first eviction, want a surge to 4 recorded", replicas, EvictionAutoScaler.Status.LastScaleTime)
+	}
+
+	// two more pods go within the interval
+	for _, podName := range []string{"web-2", "web-3"} {
+		record := evicted(podName)
+		EvictionAutoScaler.Status.RecentEvictions = append(EvictionAutoScaler.Status.RecentEvictions, record)
+		EvictionAutoScaler.Status.LastEviction = record.Eviction()
+	}
+	if err := fakeClient.Status().Update(ctx, EvictionAutoScaler); err != nil {
+		t.Fatal(err)
+	}
+	EvictionAutoScaler, replicas = reconcileWeb()
+	condition := meta.FindStatusCondition(EvictionAutoScaler.Status.Conditions, ConditionScaleUpThrottled)
+	if replicas != 4 || condition == nil || condition.Status != metav1.ConditionTrue || condition.Reason != "ScaleUpInterval" {
+		t.Errorf("got %d replicas and %s %+v within the interval, want 4 held with reason ScaleUpInterval", replicas, ConditionScaleUpThrottled, condition)
+	}
+
+	EvictionAutoScaler.Status.LastScaleTime = metav1.NewTime(time.Now().Add(-2 * time.Minute))
+	if err := fakeClient.Status().Update(ctx, EvictionAutoScaler); err != nil {
+		t.Fatal(err)
+	}
+	if _, replicas = reconcileWeb(); replicas != 6 {
+		t.Errorf("got %d replicas once the interval was up, want both pods surged for at once to 6", replicas)
+	}
+}
+
+// TestDryRunScaleUpTakesNoToken checks a dry run surge, requeued with its eviction still unhandled, leaves the
+// --max-scaleups-per-minute tokens to real scale ups.
+func TestDryRunScaleUpTakesNoToken(t *testing.T) {
+	ctx := context.Background()
+	testScheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(testScheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := v1.AddToScheme(testScheme); err != nil {
+		t.Fatal(err)
+	}
+	key := types.NamespacedName{Namespace: "default", Name: "web"}
+	eviction := v1.EvictionRecord{PodName: "web-1", EvictionTime: metav1.NewTime(time.Now().Truncate(time.Second)), Source: v1.EvictionSourceNodeCordon}
+	fakeClient := fake.NewClientBuilder().WithScheme(testScheme).WithStatusSubresource(&v1.EvictionAutoScaler{}).WithObjects(
+		&appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default", Generation: 2},
+			Spec:       appsv1.DeploymentSpec{Replicas: ptr.To(int32(3))},
+		},
+		&v1.EvictionAutoScaler{
+			ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+			Spec: v1.EvictionAutoScalerSpec{TargetName: "web", TargetKind: deploymentKind,
+				PodSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}}},
+			Status: v1.EvictionAutoScalerStatus{MinReplicas: 3, TargetGeneration: 2,
+				LastEviction: eviction.Eviction(), RecentEvictions: []v1.EvictionRecord{eviction}},
+		},
+	).Build()
+	now := time.Now()
+	scaleUps := NewScaleUpLimiter(2)
+	scaleUps.now = func() time.Time { return now }
+	r := &EvictionAutoScalerReconciler{Client: fakeClient, Scheme: testScheme, Recorder: record.NewFakeRecorder(10),
+		ScaleUps: scaleUps, DryRun: true}
+	for range 3 {
+		if _, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: key}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	deployment := &appsv1.Deployment{}
+	if err := fakeClient.Get(ctx, key, deployment); err != nil {
+		t.Fatal(err)
+	}
+	if *deployment.Spec.Replicas != 3 {
+		t.Errorf("got %d replicas in a dry run, want 3", *deployment.Spec.Replicas)
+	}
+	if tokens := scaleUps.Tokens(); tokens != 2 {
+		t.Errorf("got %v scale up tokens after dry run surges, want all 2 left", tokens)
+	}
+}
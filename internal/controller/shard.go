@@ -0,0 +1,54 @@
+package controllers
+
+import (
+	"fmt"
+	"hash/fnv"
+)
+
+// NodeShard is the share of nodes one of several controller replicas acts on, set by --node-shards and --node-shard-index.
+// Each node is owned by the shard with the highest hash of shard and node name (rendezvous hashing), so every node has
+// exactly one owner once all replicas run the same shard count and changing the count only moves about one in Count nodes.
+// A nil NodeShard owns every node.
+type NodeShard struct {
+	Index int
+	Count int
+}
+
+// NewNodeShard returns the NodeShard for the flags, nil for a single shard.
+func NewNodeShard(index, count int) (*NodeShard, error) {
+	if count < 1 || index < 0 || index >= count {
+		return nil, fmt.Errorf("node shard index %d must be between 0 and %d shards", index, count)
+	}
+	if count == 1 {
+		return nil, nil
+	}
+	return &NodeShard{Index: index, Count: count}, nil
+}
+
+// Owns returns true if nodeName is in this shard.
+func (s *NodeShard) Owns(nodeName string) bool {
+	if s == nil {
+		return true
+	}
+	owner, best := 0, uint64(0)
+	for i := 0; i < s.Count; i++ {
+		if weight := shardWeight(i, nodeName); i == 0 || weight > best {
+			owner, best = i, weight
+		}
+	}
+	return owner == s.Index
+}
+
+// shardWeight hashes shard and nodeName. fnv alone barely changes for names that differ in a digit
+// so it is finished with splitmix64's mixer to spread nodes evenly.
+func shardWeight(shard int, nodeName string) uint64 {
+	h := fnv.New64a()
+	fmt.Fprintf(h, "%d/%s", shard, nodeName)
+	x := h.Sum64()
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
@@ -0,0 +1,77 @@
+package controllers
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestNewNodeShard(t *testing.T) {
+	tests := []struct {
+		index, count int
+		nilShard     bool
+		wantErr      bool
+	}{
+		{index: 0, count: 1, nilShard: true},
+		{index: 2, count: 3},
+		{index: 3, count: 3, wantErr: true},
+		{index: -1, count: 3, wantErr: true},
+		{index: 0, count: 0, wantErr: true},
+	}
+	for _, test := range tests {
+		shard, err := NewNodeShard(test.index, test.count)
+		if (err != nil) != test.wantErr {
+			t.Errorf("index %d of %d: got err %v want err %v", test.index, test.count, err, test.wantErr)
+		}
+		if !test.wantErr && (shard == nil) != test.nilShard {
+			t.Errorf("index %d of %d: got shard %v", test.index, test.count, shard)
+		}
+	}
+}
+
+func TestNodeShardOwns(t *testing.T) {
+	const nodes = 2000
+	owners := func(count int) []int {
+		var owners []int
+		for n := 0; n < nodes; n++ {
+			nodeName := fmt.Sprintf("aks-nodepool1-12345678-vmss%06d", n)
+			owner := -1
+			for i := 0; i < count; i++ {
+				if (&NodeShard{Index: i, Count: count}).Owns(nodeName) {
+					if owner != -1 {
+						t.Fatalf("%s owned by shards %d and %d of %d", nodeName, owner, i, count)
+					}
+					owner = i
+				}
+			}
+			if owner == -1 {
+				t.Fatalf("%s has no owner among %d shards", nodeName, count)
+			}
+			owners = append(owners, owner)
+		}
+		return owners
+	}
+
+	four := owners(4)
+	perShard := make([]int, 4)
+	for _, owner := range four {
+		perShard[owner]++
+	}
+	for i, owned := range perShard {
+		if owned < nodes/4*8/10 || owned > nodes/4*12/10 {
+			t.Errorf("shard %d owns %d of %d nodes, want about a quarter", i, owned, nodes)
+		}
+	}
+
+	moved := 0
+	for n, owner := range owners(5) {
+		if owner != four[n] {
+			if owner != 4 {
+				t.Errorf("node %d moved between existing shards %d and %d", n, four[n], owner)
+			}
+			moved++
+		}
+	}
+	if moved > nodes/5*12/10 {
+		t.Errorf("%d of %d nodes moved going from 4 to 5 shards, want about a fifth", moved, nodes)
+	}
+}
@@ -0,0 +1,87 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	myappsv1 "github.com/azure/eviction-autoscaler/api/v1"
+	"github.com/azure/eviction-autoscaler/internal/events"
+	"github.com/azure/eviction-autoscaler/internal/tracing"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// ConditionSurgeOrdinalUnsafe is true while scaling a StatefulSet back down would remove a pod the surge didn't add.
+const ConditionSurgeOrdinalUnsafe = "SurgeOrdinalUnsafe"
+
+// ConditionSurgeIneffective is true while surging a StatefulSet couldn't unblock its pdb so, with spec.strategy
+// Surge, it isn't done.
+const ConditionSurgeIneffective = "SurgeIneffective"
+
+// ineffectiveSurge returns why adding replicas to a StatefulSet wouldn't unblock its pdb, empty if it would or the
+// target isn't a StatefulSet. With OrderedReady, the default pod management, the new ordinal isn't created till
+// every lower one is Running and Ready. A pdb blocked with one of them unready stays blocked till it is ready
+// either way, and then the surge isn't needed. Parallel StatefulSets create it right away like a Deployment would.
+func ineffectiveSurge(target Surger) string {
+	statefulSet, ok := target.(*StatefulSetWrapper)
+	if !ok {
+		return ""
+	}
+	if statefulSet.obj.Spec.PodManagementPolicy == appsv1.ParallelPodManagement {
+		return ""
+	}
+	if ready := statefulSet.obj.Status.ReadyReplicas; ready < statefulSet.GetReplicas() {
+		return fmt.Sprintf("statefulset %s has OrderedReady pod management and %d of its %d replicas ready, a new ordinal isn't created till all of them are",
+			statefulSet.obj.Name, ready, statefulSet.GetReplicas())
+	}
+	return ""
+}
+
+// surgeIneffective doesn't surge a StatefulSet that can't add the replicas in time to help. The eviction is kept and
+// we poll so it is surged for once the surge would help, by when the pdb has likely unblocked on its own.
+func (r *EvictionAutoScalerReconciler) surgeIneffective(ctx context.Context, EvictionAutoScaler *myappsv1.EvictionAutoScaler, message string) (ctrl.Result, error) {
+	tracing.Decide(ctx, "surge-ineffective")
+	log.FromContext(ctx).Info("Not surging, it wouldn't unblock the pdb", "reason", message, "lastEviction", EvictionAutoScaler.Status.LastEviction)
+	if setCondition(&EvictionAutoScaler.Status.Conditions, ConditionSurgeIneffective, metav1.ConditionTrue, "OrderedReady", message) {
+		events.Eventf(r.Recorder, EvictionAutoScaler, corev1.EventTypeWarning, events.ReasonSurgeIneffective, "Not surging: %s", message)
+	}
+	return ctrl.Result{RequeueAfter: r.Config.CooldownFor(EvictionAutoScaler)}, r.updateStatus(ctx, EvictionAutoScaler)
+}
+
+// unsafeSurgeOrdinal returns the pod scaling target down to replicas would remove that was already running before the surge,
+// or empty if there is none. A StatefulSet always removes its highest ordinals so those need to be the ones the surge added,
+// not a pod with state we'd be throwing away. Other targets just return empty.
+func (r *EvictionAutoScalerReconciler) unsafeSurgeOrdinal(ctx context.Context, EvictionAutoScaler *myappsv1.EvictionAutoScaler, target Surger, replicas int32) (string, error) {
+	statefulSet, ok := target.(*StatefulSetWrapper)
+	if !ok {
+		return "", nil
+	}
+	surged := meta.FindStatusCondition(EvictionAutoScaler.Status.Conditions, ConditionScalingUp)
+	if surged == nil || surged.Status != metav1.ConditionTrue {
+		return "", nil
+	}
+	var start int32
+	if ordinals := statefulSet.obj.Spec.Ordinals; ordinals != nil {
+		start = ordinals.Start
+	}
+	for ordinal := start + replicas; ordinal < start+statefulSet.GetReplicas(); ordinal++ {
+		pod := &corev1.Pod{}
+		name := fmt.Sprintf("%s-%d", statefulSet.obj.Name, ordinal)
+		if err := r.Get(ctx, types.NamespacedName{Namespace: statefulSet.obj.Namespace, Name: name}, pod); err != nil {
+			if errors.IsNotFound(err) {
+				continue // not created yet, nothing to lose
+			}
+			return "", err
+		}
+		if pod.CreationTimestamp.Before(&surged.LastTransitionTime) {
+			return name, nil
+		}
+	}
+	return "", nil
+}
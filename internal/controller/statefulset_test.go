@@ -0,0 +1,179 @@
+package controllers
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	v1 "github.com/azure/eviction-autoscaler/api/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func TestSurgeOrdinalUnsafe(t *testing.T) {
+	ctx := context.Background()
+	testScheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(testScheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := v1.AddToScheme(testScheme); err != nil {
+		t.Fatal(err)
+	}
+	key := types.NamespacedName{Namespace: "default", Name: "db"}
+	evicted := metav1.NewTime(time.Now().Add(-2 * time.Hour).Truncate(time.Second))
+	surged := metav1.NewTime(evicted.Add(time.Minute))
+
+	tests := []struct {
+		name     string
+		created  time.Time // of db-3, the ordinal a scale down removes
+		replicas int32     // statefulset replicas after reconcile
+		unsafe   bool
+	}{
+		{name: "pod added by the surge", created: surged.Add(time.Second), replicas: 3},
+		{name: "pod from before the surge", created: surged.Add(-time.Hour), replicas: 4, unsafe: true},
+	}
+	for _, test := range tests {
+		objects := []client.Object{
+			&appsv1.StatefulSet{
+				ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "default", Generation: 2},
+				Spec:       appsv1.StatefulSetSpec{Replicas: ptr.To(int32(4))},
+			},
+			&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "db-3", Namespace: "default", CreationTimestamp: metav1.NewTime(test.created)}},
+			&policyv1.PodDisruptionBudget{ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "default"}},
+			&v1.EvictionAutoScaler{
+				ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "default"},
+				Spec:       v1.EvictionAutoScalerSpec{TargetName: "db", TargetKind: statefulSetKind},
+				Status: v1.EvictionAutoScalerStatus{
+					MinReplicas:      3,
+					TargetGeneration: 2,
+					LastEviction:     v1.Eviction{PodName: "db-1", EvictionTime: evicted},
+					Conditions: []metav1.Condition{{
+						Type: ConditionScalingUp, Status: metav1.ConditionTrue, Reason: "EvictionBlocked", LastTransitionTime: surged,
+					}},
+				},
+			},
+		}
+		fakeClient := fake.NewClientBuilder().WithScheme(testScheme).
+			WithStatusSubresource(&v1.EvictionAutoScaler{}).WithObjects(objects...).Build()
+		recorder := record.NewFakeRecorder(10)
+		r := &EvictionAutoScalerReconciler{Client: fakeClient, Scheme: testScheme, Recorder: recorder}
+		if _, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: key}); err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+
+		statefulSet := &appsv1.StatefulSet{}
+		if err := fakeClient.Get(ctx, key, statefulSet); err != nil {
+			t.Fatal(err)
+		}
+		if *statefulSet.Spec.Replicas != test.replicas {
+			t.Errorf("%s: got %d replicas, want %d", test.name, *statefulSet.Spec.Replicas, test.replicas)
+		}
+		EvictionAutoScaler := &v1.EvictionAutoScaler{}
+		if err := fakeClient.Get(ctx, key, EvictionAutoScaler); err != nil {
+			t.Fatal(err)
+		}
+		if got := meta.IsStatusConditionTrue(EvictionAutoScaler.Status.Conditions, ConditionSurgeOrdinalUnsafe); got != test.unsafe {
+			t.Errorf("%s: got %s %v, want %v", test.name, ConditionSurgeOrdinalUnsafe, got, test.unsafe)
+		}
+		unsafeEvent := false
+		for len(recorder.Events) > 0 {
+			if strings.Contains(<-recorder.Events, "SurgeOrdinalUnsafe") {
+				unsafeEvent = true
+			}
+		}
+		if unsafeEvent != test.unsafe {
+			t.Errorf("%s: got SurgeOrdinalUnsafe event %v, want %v", test.name, unsafeEvent, test.unsafe)
+		}
+	}
+}
+
+func TestSurgeIneffective(t *testing.T) {
+	ctx := context.Background()
+	testScheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(testScheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := v1.AddToScheme(testScheme); err != nil {
+		t.Fatal(err)
+	}
+	key := types.NamespacedName{Namespace: "default", Name: "db"}
+	evicted := metav1.NewTime(time.Now().Add(-time.Second).Truncate(time.Second))
+
+	tests := []struct {
+		name         string
+		policy       appsv1.PodManagementPolicyType
+		minAvailable intstr.IntOrString
+		ready        int32
+		strategy     string
+		surged       bool
+		ineffective  bool
+	}{
+		{name: "ordered ready with an unready ordinal", policy: appsv1.OrderedReadyPodManagement, minAvailable: intstr.FromInt32(3), ready: 2, ineffective: true},
+		{name: "ordered ready by default, percentage", minAvailable: intstr.FromString("67%"), ready: 2, ineffective: true},
+		{name: "ordered ready with every ordinal ready", policy: appsv1.OrderedReadyPodManagement, minAvailable: intstr.FromInt32(3), ready: 3, surged: true},
+		{name: "parallel", policy: appsv1.ParallelPodManagement, minAvailable: intstr.FromInt32(3), ready: 2, surged: true},
+		{name: "parallel, percentage", policy: appsv1.ParallelPodManagement, minAvailable: intstr.FromString("67%"), ready: 2, surged: true},
+		{name: "surge always", minAvailable: intstr.FromInt32(3), ready: 2, strategy: v1.StrategySurgeAlways, surged: true, ineffective: true},
+	}
+	for _, test := range tests {
+		objects := []client.Object{
+			&appsv1.StatefulSet{
+				ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "default", Generation: 2},
+				Spec:       appsv1.StatefulSetSpec{Replicas: ptr.To(int32(3)), PodManagementPolicy: test.policy},
+				Status:     appsv1.StatefulSetStatus{ReadyReplicas: test.ready, AvailableReplicas: test.ready},
+			},
+			&policyv1.PodDisruptionBudget{
+				ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "default"},
+				Spec:       policyv1.PodDisruptionBudgetSpec{MinAvailable: ptr.To(test.minAvailable)},
+				Status:     policyv1.PodDisruptionBudgetStatus{ExpectedPods: 3, CurrentHealthy: test.ready, DesiredHealthy: 3},
+			},
+			&v1.EvictionAutoScaler{
+				ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "default"},
+				Spec:       v1.EvictionAutoScalerSpec{TargetName: "db", TargetKind: statefulSetKind, Strategy: test.strategy},
+				Status: v1.EvictionAutoScalerStatus{
+					MinReplicas:      3,
+					TargetGeneration: 2,
+					LastEviction:     v1.Eviction{PodName: "db-0", EvictionTime: evicted},
+				},
+			},
+		}
+		fakeClient := fake.NewClientBuilder().WithScheme(testScheme).
+			WithStatusSubresource(&v1.EvictionAutoScaler{}).WithObjects(objects...).Build()
+		recorder := record.NewFakeRecorder(10)
+		r := &EvictionAutoScalerReconciler{Client: fakeClient, Scheme: testScheme, Recorder: recorder}
+		if _, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: key}); err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+
+		statefulSet := &appsv1.StatefulSet{}
+		if err := fakeClient.Get(ctx, key, statefulSet); err != nil {
+			t.Fatal(err)
+		}
+		if surged := *statefulSet.Spec.Replicas > 3; surged != test.surged {
+			t.Errorf("%s: got %d replicas, want surged %v", test.name, *statefulSet.Spec.Replicas, test.surged)
+		}
+		EvictionAutoScaler := &v1.EvictionAutoScaler{}
+		if err := fakeClient.Get(ctx, key, EvictionAutoScaler); err != nil {
+			t.Fatal(err)
+		}
+		if got := meta.IsStatusConditionTrue(EvictionAutoScaler.Status.Conditions, ConditionSurgeIneffective); got != test.ineffective {
+			t.Errorf("%s: got %s %v, want %v", test.name, ConditionSurgeIneffective, got, test.ineffective)
+		}
+		if !test.surged && EvictionAutoScaler.Status.HandledEviction == EvictionAutoScaler.Status.LastEviction {
+			t.Errorf("%s: got the eviction handled, want it kept to surge for once the surge would help", test.name)
+		}
+	}
+}
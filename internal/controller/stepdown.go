@@ -0,0 +1,73 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	myappsv1 "github.com/azure/eviction-autoscaler/api/v1"
+	"github.com/azure/eviction-autoscaler/internal/events"
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// ConditionScaleDownPaused is true while the pdb holds back the next step of a spec.scaleDownPolicy Stepped scale
+// down because it would leave fewer healthy pods than the pdb wants.
+const ConditionScaleDownPaused = "ScaleDownPaused"
+
+// scaleDownStepInterval is the least time between two steps of a stepped scale down: the cooldown, or the
+// stabilization window if that is longer.
+func (r *EvictionAutoScalerReconciler) scaleDownStepInterval(EvictionAutoScaler *myappsv1.EvictionAutoScaler) time.Duration {
+	return max(r.Config.CooldownFor(EvictionAutoScaler), stabilizationFor(EvictionAutoScaler))
+}
+
+// scaleDownStep returns the replicas the next step of a stepped scale down from current to the baseline to leaves
+// the target at, spec.surge fewer resolved against current like a surge is but never below to. wait is how long till
+// the step is due after the last one. paused says why pdb holds it back, empty if it doesn't. The step is assumed to
+// remove healthy pods, though the workload controller removes unready ones first.
+func (r *EvictionAutoScalerReconciler) scaleDownStep(EvictionAutoScaler *myappsv1.EvictionAutoScaler, pdb *policyv1.PodDisruptionBudget,
+	current, to int32) (next int32, wait time.Duration, paused string, err error) {
+	if elapsed := time.Since(EvictionAutoScaler.Status.LastScaleDownTime.Time); elapsed < r.scaleDownStepInterval(EvictionAutoScaler) {
+		return current, r.scaleDownStepInterval(EvictionAutoScaler) - elapsed, "", nil
+	}
+	surged, err := calculateSurge(r.Config.surgeFor(EvictionAutoScaler), current)
+	if err != nil {
+		return current, 0, "", err
+	}
+	next = max(current-max(surged-current, 1), to)
+	if pdb.Status.ExpectedPods == 0 {
+		return next, 0, "", nil // a spec.podSelector stand-in, or a status not filled in yet, has nothing to check
+	}
+	removed := current - next
+	desired, err := desiredHealthy(pdb, max(pdb.Status.ExpectedPods-removed, 0))
+	if err != nil {
+		return current, 0, "", err
+	}
+	if healthy := pdb.Status.CurrentHealthy - removed; healthy < desired || pdb.Status.DisruptionsAllowed < removed {
+		return current, 0, fmt.Sprintf("scaling down %d replicas to %d would leave pdb %s %d healthy pods with %d disruptions allowed, it wants %d",
+			removed, next, pdb.Name, max(healthy, 0), pdb.Status.DisruptionsAllowed, desired), nil
+	}
+	return next, 0, "", nil
+}
+
+// steppedDown records a step of a stepped scale down short of the baseline. The surge is still up at the target's
+// new replicas, so it is held and the eviction not handled till the last step.
+func (r *EvictionAutoScalerReconciler) steppedDown(ctx context.Context, EvictionAutoScaler *myappsv1.EvictionAutoScaler, target Surger,
+	targetKind, targetName string) (ctrl.Result, error) {
+	status := &EvictionAutoScaler.Status
+	replicas := target.GetReplicas()
+	message := fmt.Sprintf("stepped %s %s down to %d replicas, %d above the baseline", targetKind, targetName, replicas, replicas-status.MinReplicas)
+	log.FromContext(ctx).Info(message)
+	events.Eventf(r.Recorder, EvictionAutoScaler, corev1.EventTypeNormal, events.ReasonSurgeSteppedDown,
+		"Stepped %s %s down to %d replicas, the next step in %s", targetKind, targetName, replicas, r.scaleDownStepInterval(EvictionAutoScaler))
+	r.setSurge(ctx, client.ObjectKeyFromObject(EvictionAutoScaler), replicas-status.MinReplicas)
+	status.TargetGeneration = target.Obj().GetGeneration()
+	status.SurgeReplicas = replicas
+	setCondition(&status.Conditions, ConditionCoolingDown, metav1.ConditionTrue, "SteppingDown", message)
+	ready(&status.Conditions, "SteppingDown", message)
+	return ctrl.Result{RequeueAfter: r.scaleDownStepInterval(EvictionAutoScaler)}, r.updateScaleStatus(ctx, EvictionAutoScaler)
+}
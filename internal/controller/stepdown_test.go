@@ -0,0 +1,118 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	v1 "github.com/azure/eviction-autoscaler/api/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// TestSteppedScaleDown checks spec.scaleDownPolicy Stepped gives a surge back spec.surge replicas per cooldown and
+// waits while the pdb wouldn't have enough healthy pods left.
+func TestSteppedScaleDown(t *testing.T) {
+	ctx := context.Background()
+	testScheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(testScheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := v1.AddToScheme(testScheme); err != nil {
+		t.Fatal(err)
+	}
+	key := types.NamespacedName{Namespace: "default", Name: "web"}
+	lastEviction := v1.Eviction{PodName: "web-1", EvictionTime: metav1.NewTime(time.Now().Add(-10 * time.Minute).Truncate(time.Second))}
+	pdb := &policyv1.PodDisruptionBudget{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+		Spec:       policyv1.PodDisruptionBudgetSpec{MinAvailable: ptr.To(intstr.FromInt32(3))},
+		Status:     policyv1.PodDisruptionBudgetStatus{ExpectedPods: 6, CurrentHealthy: 6, DesiredHealthy: 3, DisruptionsAllowed: 3},
+	}
+	fakeClient := fake.NewClientBuilder().WithScheme(testScheme).
+		WithStatusSubresource(&v1.EvictionAutoScaler{}, &policyv1.PodDisruptionBudget{}).
+		WithIndex(&v1.EvictionAutoScaler{}, PDBIndex, evictionAutoScalerPDB).
+		WithIndex(&v1.EvictionAutoScaler{}, TargetIndex, evictionAutoScalerTarget).
+		WithObjects(
+			&appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default", Generation: 2},
+				Spec:       appsv1.DeploymentSpec{Replicas: ptr.To(int32(6))},
+			},
+			pdb,
+			&v1.EvictionAutoScaler{
+				ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+				Spec: v1.EvictionAutoScalerSpec{TargetName: "web", TargetKind: deploymentKind, CooldownSeconds: ptr.To(int32(60)),
+					Surge: ptr.To(intstr.FromInt32(2)), ScaleDownPolicy: v1.ScaleDownPolicyStepped},
+				Status: v1.EvictionAutoScalerStatus{MinReplicas: 3, SurgeReplicas: 6, TargetGeneration: 2, LastEviction: lastEviction},
+			},
+		).Build()
+	r := &EvictionAutoScalerReconciler{Client: fakeClient, Scheme: testScheme, Recorder: record.NewFakeRecorder(20)}
+	reconcileStatus := func() (int32, *v1.EvictionAutoScaler) {
+		if _, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: key}); err != nil {
+			t.Fatal(err)
+		}
+		deployment := &appsv1.Deployment{}
+		if err := fakeClient.Get(ctx, key, deployment); err != nil {
+			t.Fatal(err)
+		}
+		EvictionAutoScaler := &v1.EvictionAutoScaler{}
+		if err := fakeClient.Get(ctx, key, EvictionAutoScaler); err != nil {
+			t.Fatal(err)
+		}
+		return *deployment.Spec.Replicas, EvictionAutoScaler
+	}
+	setPDBStatus := func(expected, healthy, allowed int32) {
+		pdb.Status = policyv1.PodDisruptionBudgetStatus{ExpectedPods: expected, CurrentHealthy: healthy, DesiredHealthy: 3, DisruptionsAllowed: allowed}
+		if err := fakeClient.Status().Update(ctx, pdb); err != nil {
+			t.Fatal(err)
+		}
+	}
+	stepDue := func() {
+		EvictionAutoScaler := &v1.EvictionAutoScaler{}
+		if err := fakeClient.Get(ctx, key, EvictionAutoScaler); err != nil {
+			t.Fatal(err)
+		}
+		EvictionAutoScaler.Status.LastScaleDownTime = metav1.NewTime(time.Now().Add(-2 * time.Minute))
+		if err := fakeClient.Status().Update(ctx, EvictionAutoScaler); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	replicas, EvictionAutoScaler := reconcileStatus()
+	if condition := meta.FindStatusCondition(EvictionAutoScaler.Status.Conditions, ConditionCoolingDown); replicas != 4 ||
+		EvictionAutoScaler.Status.SurgeReplicas != 4 || condition == nil || condition.Reason != "SteppingDown" {
+		t.Errorf("got %d replicas, surgeReplicas %d and %s %+v, want one step of 2 down to 4 with the surge still held",
+			replicas, EvictionAutoScaler.Status.SurgeReplicas, ConditionCoolingDown, condition)
+	}
+
+	// the next step waits for the cooldown
+	if replicas, _ = reconcileStatus(); replicas != 4 {
+		t.Errorf("got %d replicas, want 4 till the cooldown after the last step", replicas)
+	}
+
+	// one of the 4 pods isn't healthy so going to 3 leaves the pdb short
+	setPDBStatus(4, 3, 0)
+	stepDue()
+	replicas, EvictionAutoScaler = reconcileStatus()
+	if replicas != 4 || !meta.IsStatusConditionTrue(EvictionAutoScaler.Status.Conditions, ConditionScaleDownPaused) {
+		t.Errorf("got %d replicas and %s %+v, want 4 and paused", replicas, ConditionScaleDownPaused,
+			meta.FindStatusCondition(EvictionAutoScaler.Status.Conditions, ConditionScaleDownPaused))
+	}
+
+	setPDBStatus(4, 4, 1)
+	replicas, EvictionAutoScaler = reconcileStatus()
+	if replicas != 3 || EvictionAutoScaler.Status.SurgeReplicas != 0 || meta.IsStatusConditionTrue(EvictionAutoScaler.Status.Conditions, ConditionScaleDownPaused) ||
+		!meta.IsStatusConditionTrue(EvictionAutoScaler.Status.Conditions, ConditionIdle) {
+		t.Errorf("got %d replicas, surgeReplicas %d and conditions %+v, want the last step to the baseline of 3", replicas,
+			EvictionAutoScaler.Status.SurgeReplicas, EvictionAutoScaler.Status.Conditions)
+	}
+}
@@ -0,0 +1,59 @@
+package controllers
+
+import (
+	"context"
+	"time"
+
+	"github.com/azure/eviction-autoscaler/internal/events"
+	"github.com/azure/eviction-autoscaler/internal/metrics"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// DefaultStuckTerminatingThreshold is how long past its grace period a pod may still be terminating before it is
+// reported stuck, when --stuck-terminating-threshold is unset.
+const DefaultStuckTerminatingThreshold = 5 * time.Minute
+
+// stuckTerminating returns the pods deleted more than StuckTerminatingThreshold past their grace period, which the
+// deletionTimestamp already has added, and how long till the next of the others is. Zero if none will be.
+func (r *NodeReconciler) stuckTerminating(terminating []*corev1.Pod) ([]*corev1.Pod, time.Duration) {
+	if r.StuckTerminatingThreshold <= 0 {
+		return nil, 0
+	}
+	var stuck []*corev1.Pod
+	var next time.Duration
+	for _, pod := range terminating {
+		if pod.DeletionTimestamp == nil {
+			continue // finished, not deleted
+		}
+		wait := time.Until(pod.DeletionTimestamp.Add(r.StuckTerminatingThreshold))
+		if wait <= 0 {
+			stuck = append(stuck, pod)
+		} else if next == 0 || wait < next {
+			next = wait
+		}
+	}
+	return stuck, next
+}
+
+// reportStuckTerminating sets metrics.PodsStuckTerminatingGauge for node and emits a PodStuckTerminating Warning on
+// each of the stuck pods and on the node. Nothing is deleted, a stuck finalizer or runtime is for someone to look at.
+func (r *NodeReconciler) reportStuckTerminating(ctx context.Context, node *corev1.Node, stuck []*corev1.Pod) {
+	metrics.ForgetStuckTerminating(node.Name)
+	if len(stuck) == 0 {
+		return
+	}
+	byNamespace := map[string]int{}
+	for _, pod := range stuck {
+		byNamespace[pod.Namespace]++
+		over := time.Since(pod.DeletionTimestamp.Time).Truncate(time.Second)
+		events.Eventf(r.Recorder, pod, corev1.EventTypeWarning, events.ReasonPodStuckTerminating,
+			"Pod is still terminating %s past its grace period, holding up the drain of node %s", over, node.Name)
+	}
+	for namespace, count := range byNamespace {
+		metrics.PodsStuckTerminatingGauge.WithLabelValues(node.Name, namespace).Set(float64(count))
+	}
+	log.FromContext(ctx).Info("Pods stuck terminating past their grace period", "node", node.Name, "pods", len(stuck))
+	events.Eventf(r.Recorder, node, corev1.EventTypeWarning, events.ReasonPodStuckTerminating,
+		"%d pods are still terminating past their grace period, such as %s/%s", len(stuck), stuck[0].Namespace, stuck[0].Name)
+}
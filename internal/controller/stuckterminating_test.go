@@ -0,0 +1,103 @@
+package controllers
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	v1 "github.com/azure/eviction-autoscaler/api/v1"
+	"github.com/azure/eviction-autoscaler/internal/events"
+	"github.com/azure/eviction-autoscaler/internal/metrics"
+	"github.com/azure/eviction-autoscaler/internal/selectorcache"
+	dto "github.com/prometheus/client_model/go"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// TestStuckTerminating drains a node with a pod terminating long past its grace period and one that only just
+// started. The first is reported on the gauge and with events, the node is looked at again when the second would be
+// stuck and uncordoning drops the gauge.
+func TestStuckTerminating(t *testing.T) {
+	ctx := context.Background()
+	testScheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(testScheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := v1.AddToScheme(testScheme); err != nil {
+		t.Fatal(err)
+	}
+	const namespace = "stuck"
+	terminating := func(name string, deleted time.Duration) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: name, Namespace: namespace,
+				DeletionTimestamp: &metav1.Time{Time: time.Now().Add(-deleted)},
+				Finalizers:        []string{"example.com/never-done"},
+			},
+			Spec: corev1.PodSpec{NodeName: "draining"},
+		}
+	}
+	fakeClient := fake.NewClientBuilder().
+		WithScheme(testScheme).
+		WithIndex(&corev1.Pod{}, NodeNameIndex, podNodeName).
+		WithObjects(
+			&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "draining"}, Spec: corev1.NodeSpec{Unschedulable: true}},
+			terminating("stuck", 10*time.Minute),
+			terminating("finishing", time.Minute),
+		).
+		Build()
+	recorder := record.NewFakeRecorder(10)
+	nodeReconciler := &NodeReconciler{Client: fakeClient, Scheme: testScheme, Recorder: recorder, Selectors: selectorcache.New(),
+		StuckTerminatingThreshold: 5 * time.Minute}
+	request := reconcile.Request{NamespacedName: types.NamespacedName{Name: "draining"}}
+	stuckGauge := func() float64 {
+		m := &dto.Metric{}
+		if err := metrics.PodsStuckTerminatingGauge.WithLabelValues("draining", namespace).Write(m); err != nil {
+			t.Fatal(err)
+		}
+		return m.GetGauge().GetValue()
+	}
+
+	result, err := nodeReconciler.Reconcile(ctx, request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := stuckGauge(); got != 1 {
+		t.Errorf("got %v pods stuck terminating, want 1", got)
+	}
+	if result.RequeueAfter <= 3*time.Minute || result.RequeueAfter > 4*time.Minute {
+		t.Errorf("got requeue after %v, want about 4m when the other pod would be stuck", result.RequeueAfter)
+	}
+	var stuckEvents []string
+	for len(recorder.Events) > 0 {
+		if event := <-recorder.Events; strings.Contains(event, events.ReasonPodStuckTerminating) {
+			stuckEvents = append(stuckEvents, event)
+		}
+	}
+	if len(stuckEvents) != 2 || !strings.Contains(stuckEvents[1], namespace+"/stuck") {
+		t.Errorf("got %s events %v, want one on the pod and one on the node naming it", events.ReasonPodStuckTerminating, stuckEvents)
+	}
+
+	node := &corev1.Node{}
+	if err := fakeClient.Get(ctx, client.ObjectKey{Name: "draining"}, node); err != nil {
+		t.Fatal(err)
+	}
+	node.Spec.Unschedulable = false
+	if err := fakeClient.Update(ctx, node); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := nodeReconciler.Reconcile(ctx, request); err != nil {
+		t.Fatal(err)
+	}
+	if got := stuckGauge(); got != 0 {
+		t.Errorf("got %v pods stuck terminating after uncordon, want the gauge dropped", got)
+	}
+}
@@ -0,0 +1,112 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	myappsv1 "github.com/azure/eviction-autoscaler/api/v1"
+	"github.com/azure/eviction-autoscaler/internal/podutil"
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// maxUnblockSurge bounds the search for how many replicas unblock a pdb. A pdb needing more,
+// like minAvailable: 99% of a single replica, is treated as one surging can't unblock.
+const maxUnblockSurge = 1000
+
+// replicasToUnblock returns how many replicas have to be added, assuming they all become healthy,
+// before pdb allows disruptions disruptions, or just one if no number of replicas allows that many, like a
+// maxUnavailable count. ok is false when no number of replicas helps at all, like minAvailable: 100%,
+// maxUnavailable: 0 or a maxUnavailable count already used up by unhealthy pods.
+func replicasToUnblock(pdb *policyv1.PodDisruptionBudget, replicas, disruptions int32) (surge int32, ok bool) {
+	expected, healthy := pdb.Status.ExpectedPods, pdb.Status.CurrentHealthy
+	if expected == 0 {
+		// status not filled in yet so assume every replica is healthy
+		expected, healthy = replicas, replicas
+	}
+	for surge = 0; surge <= maxUnblockSurge; surge++ {
+		desired, err := desiredHealthy(pdb, expected+surge)
+		if err != nil {
+			return 0, false // the disruption controller won't allow anything either
+		}
+		if healthy+surge-desired >= max(disruptions, 1) {
+			return surge, true
+		}
+	}
+	if disruptions > 1 {
+		return replicasToUnblock(pdb, replicas, 1)
+	}
+	return 0, false
+}
+
+// SurgeToUnblock is replicasToUnblock for replicas all healthy, whatever pdb's status says, for judging a pdb
+// before its pods exist. blocked is false if pdb already allows a disruption with them.
+func SurgeToUnblock(pdb *policyv1.PodDisruptionBudget, replicas int32) (surge int32, blocked bool, ok bool) {
+	pdb = pdb.DeepCopy()
+	pdb.Status = policyv1.PodDisruptionBudgetStatus{}
+	surge, ok = replicasToUnblock(pdb, replicas, 1)
+	return surge, !ok || surge > 0, ok
+}
+
+// disruptionsNeeded returns how many disruptions a surge should get pdb to allow. One for the default
+// spec.surgePolicy, so the drain gets going, and for PDBGap one for every pod of pdb still on a draining node
+// so they can all be evicted without the next eviction blocking again.
+func (r *EvictionAutoScalerReconciler) disruptionsNeeded(ctx context.Context, EvictionAutoScaler *myappsv1.EvictionAutoScaler,
+	pdb *policyv1.PodDisruptionBudget) (int32, error) {
+	draining := EvictionAutoScaler.Status.DrainingNodes
+	if r.Config.surgePolicyFor(EvictionAutoScaler) != myappsv1.SurgePolicyPDBGap || len(draining) == 0 {
+		return 1, nil
+	}
+	selector, err := metav1.LabelSelectorAsSelector(pdb.Spec.Selector)
+	if err != nil {
+		return 1, nil // invalid selectors never match anything
+	}
+	podList := &corev1.PodList{}
+	if err := r.List(ctx, podList, &client.ListOptions{Namespace: pdb.Namespace, LabelSelector: selector}); err != nil {
+		return 0, err
+	}
+	var pods int32
+	for i := range podList.Items {
+		if pod := &podList.Items[i]; slices.Contains(draining, pod.Spec.NodeName) && !podutil.IsTerminating(pod) {
+			pods++
+		}
+	}
+	return max(pods, 1), nil
+}
+
+// desiredHealthy is how many healthy pods pdb wants out of expected, rounded like the disruption controller:
+// percentages round up, so minAvailable asks for more pods and maxUnavailable allows more to be missing.
+func desiredHealthy(pdb *policyv1.PodDisruptionBudget, expected int32) (int32, error) {
+	switch {
+	case pdb.Spec.MaxUnavailable != nil:
+		maxUnavailable, err := intstr.GetScaledValueFromIntOrPercent(pdb.Spec.MaxUnavailable, int(expected), true)
+		if err != nil {
+			return 0, err
+		}
+		return max(expected-int32(maxUnavailable), 0), nil
+	case pdb.Spec.MinAvailable != nil:
+		minAvailable, err := intstr.GetScaledValueFromIntOrPercent(pdb.Spec.MinAvailable, int(expected), true)
+		if err != nil {
+			return 0, err
+		}
+		return int32(minAvailable), nil
+	}
+	return 0, nil
+}
+
+// calculateSurge returns replicas plus spec.surge, a count or a percentage of replicas rounded up
+// like a rolling update's maxSurge so 10% of 5 replicas still adds one.
+func calculateSurge(surge intstr.IntOrString, replicas int32) (int32, error) {
+	step, err := intstr.GetScaledValueFromIntOrPercent(&surge, int(replicas), true)
+	if err != nil {
+		return replicas, err
+	}
+	if step < 0 {
+		return replicas, fmt.Errorf("surge %s must not be negative", surge.String())
+	}
+	return replicas + int32(step), nil
+}
@@ -0,0 +1,191 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	v1 "github.com/azure/eviction-autoscaler/api/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func TestReplicasToUnblock(t *testing.T) {
+	minAvailable := func(v intstr.IntOrString) policyv1.PodDisruptionBudgetSpec {
+		return policyv1.PodDisruptionBudgetSpec{MinAvailable: &v}
+	}
+	maxUnavailable := func(v intstr.IntOrString) policyv1.PodDisruptionBudgetSpec {
+		return policyv1.PodDisruptionBudgetSpec{MaxUnavailable: &v}
+	}
+	tests := []struct {
+		name        string
+		spec        policyv1.PodDisruptionBudgetSpec
+		status      policyv1.PodDisruptionBudgetStatus
+		replicas    int32
+		disruptions int32
+		surge       int32
+		ok          bool
+	}{
+		{name: "minAvailable equal to replicas", spec: minAvailable(intstr.FromInt32(3)), replicas: 3, surge: 1, ok: true},
+		{name: "minAvailable below replicas", spec: minAvailable(intstr.FromInt32(2)), replicas: 3, surge: 0, ok: true},
+		{name: "minAvailable above replicas", spec: minAvailable(intstr.FromInt32(5)), replicas: 3, surge: 3, ok: true},
+		{name: "minAvailable 100%", spec: minAvailable(intstr.FromString("100%")), replicas: 3, ok: false},
+		{name: "minAvailable 80% of 5", spec: minAvailable(intstr.FromString("80%")), replicas: 5, surge: 0, ok: true},
+		// ceil(80% of 4) is 4 so nothing is allowed, ceil(80% of 5) is 4
+		{name: "minAvailable 80% of 4", spec: minAvailable(intstr.FromString("80%")), replicas: 4, surge: 1, ok: true},
+		// ceil(90% of n) is n up to 10 replicas
+		{name: "minAvailable 90% of 2", spec: minAvailable(intstr.FromString("90%")), replicas: 2, surge: 8, ok: true},
+		{name: "minAvailable 50% of 1", spec: minAvailable(intstr.FromString("50%")), replicas: 1, surge: 1, ok: true},
+		{name: "maxUnavailable 0", spec: maxUnavailable(intstr.FromInt32(0)), replicas: 3, ok: false},
+		{name: "maxUnavailable 0%", spec: maxUnavailable(intstr.FromString("0%")), replicas: 3, ok: false},
+		{name: "maxUnavailable 1", spec: maxUnavailable(intstr.FromInt32(1)), replicas: 3, surge: 0, ok: true},
+		// a count used up by unhealthy pods stays used up however many we add
+		{name: "maxUnavailable 1 with an unhealthy pod", spec: maxUnavailable(intstr.FromInt32(1)),
+			status: policyv1.PodDisruptionBudgetStatus{ExpectedPods: 3, CurrentHealthy: 2}, replicas: 3, ok: false},
+		// a percentage grows with the replicas, ceil(10% of 11) is 2
+		{name: "maxUnavailable 10% with an unhealthy pod", spec: maxUnavailable(intstr.FromString("10%")),
+			status: policyv1.PodDisruptionBudgetStatus{ExpectedPods: 5, CurrentHealthy: 4}, replicas: 5, surge: 6, ok: true},
+		{name: "minAvailable with unhealthy pods", spec: minAvailable(intstr.FromInt32(3)),
+			status: policyv1.PodDisruptionBudgetStatus{ExpectedPods: 3, CurrentHealthy: 1}, replicas: 3, surge: 3, ok: true},
+		{name: "invalid percentage", spec: minAvailable(intstr.FromString("lots")), replicas: 3, ok: false},
+		// PDBGap asks for a disruption per pod on a draining node
+		{name: "minAvailable equal to replicas for 3 pods", spec: minAvailable(intstr.FromInt32(10)),
+			status: policyv1.PodDisruptionBudgetStatus{ExpectedPods: 10, CurrentHealthy: 10}, replicas: 10, disruptions: 3, surge: 3, ok: true},
+		{name: "minAvailable below replicas for 3 pods", spec: minAvailable(intstr.FromInt32(8)), replicas: 10, disruptions: 3, surge: 1, ok: true},
+		// 20 - ceil(80% of 20) is 4, 19 - ceil(80% of 19) only 3
+		{name: "minAvailable 80% of 10 for 4 pods", spec: minAvailable(intstr.FromString("80%")), replicas: 10, disruptions: 4, surge: 10, ok: true},
+		// ceil(25% of 9) is 3
+		{name: "maxUnavailable 25% of 4 for 3 pods", spec: maxUnavailable(intstr.FromString("25%")), replicas: 4, disruptions: 3, surge: 5, ok: true},
+		// a count never allows more than itself, so only one disruption is asked for
+		{name: "maxUnavailable 1 for 3 pods", spec: maxUnavailable(intstr.FromInt32(1)), replicas: 3, disruptions: 3, surge: 0, ok: true},
+		{name: "minAvailable with unhealthy pods for 3 pods", spec: minAvailable(intstr.FromInt32(3)),
+			status: policyv1.PodDisruptionBudgetStatus{ExpectedPods: 3, CurrentHealthy: 1}, replicas: 3, disruptions: 3, surge: 5, ok: true},
+		{name: "minAvailable 100% for 3 pods", spec: minAvailable(intstr.FromString("100%")), replicas: 3, disruptions: 3, ok: false},
+	}
+	for _, test := range tests {
+		pdb := &policyv1.PodDisruptionBudget{Spec: test.spec, Status: test.status}
+		surge, ok := replicasToUnblock(pdb, test.replicas, test.disruptions)
+		if ok != test.ok || surge != test.surge {
+			t.Errorf("%s: got surge %d ok %v want %d %v", test.name, surge, ok, test.surge, test.ok)
+		}
+	}
+}
+
+// TestSurgePolicyPDBGap checks PDBGap surges enough for every pod on a draining node to be evicted while Step
+// only unblocks one.
+func TestSurgePolicyPDBGap(t *testing.T) {
+	ctx := context.Background()
+	testScheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(testScheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := v1.AddToScheme(testScheme); err != nil {
+		t.Fatal(err)
+	}
+	key := types.NamespacedName{Namespace: "default", Name: "web"}
+	lastEviction := v1.Eviction{PodName: "web-0", EvictionTime: metav1.NewTime(time.Now().Add(-time.Second).Truncate(time.Second))}
+
+	tests := []struct {
+		name         string
+		policy       string
+		minAvailable intstr.IntOrString
+		maxReplicas  *int32
+		replicas     int32
+	}{
+		{name: "step", policy: v1.SurgePolicyStep, minAvailable: intstr.FromInt32(10), replicas: 11},
+		{name: "gap", policy: v1.SurgePolicyPDBGap, minAvailable: intstr.FromInt32(10), replicas: 13},
+		{name: "gap capped", policy: v1.SurgePolicyPDBGap, minAvailable: intstr.FromInt32(10), maxReplicas: ptr.To(int32(12)), replicas: 12},
+		// 15 - ceil(80% of 15) is 3
+		{name: "gap percentage", policy: v1.SurgePolicyPDBGap, minAvailable: intstr.FromString("80%"), replicas: 15},
+		// 11 - ceil(80% of 11) is 2, enough for one
+		{name: "step percentage", policy: v1.SurgePolicyStep, minAvailable: intstr.FromString("80%"), replicas: 11},
+	}
+	for _, test := range tests {
+		objects := []client.Object{
+			&appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default", Generation: 2},
+				Spec:       appsv1.DeploymentSpec{Replicas: ptr.To(int32(10))},
+			},
+			&policyv1.PodDisruptionBudget{
+				ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+				Spec: policyv1.PodDisruptionBudgetSpec{MinAvailable: ptr.To(test.minAvailable),
+					Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}}},
+				Status: policyv1.PodDisruptionBudgetStatus{ExpectedPods: 10, CurrentHealthy: 10, DesiredHealthy: 10},
+			},
+			&v1.EvictionAutoScaler{
+				ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+				Spec:       v1.EvictionAutoScalerSpec{TargetName: "web", TargetKind: deploymentKind, SurgePolicy: test.policy, MaxReplicas: test.maxReplicas},
+				Status:     v1.EvictionAutoScalerStatus{MinReplicas: 10, TargetGeneration: 2, LastEviction: lastEviction, DrainingNodes: []string{"draining"}},
+			},
+		}
+		for i := range 10 {
+			node := "draining"
+			if i >= 3 {
+				node = "healthy"
+			}
+			objects = append(objects, &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("web-%d", i), Namespace: "default", Labels: map[string]string{"app": "web"}},
+				Spec:       corev1.PodSpec{NodeName: node},
+			})
+		}
+		fakeClient := fake.NewClientBuilder().WithScheme(testScheme).
+			WithStatusSubresource(&v1.EvictionAutoScaler{}).WithObjects(objects...).Build()
+		r := &EvictionAutoScalerReconciler{Client: fakeClient, Scheme: testScheme, Recorder: record.NewFakeRecorder(10)}
+		if _, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: key}); err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+
+		deployment := &appsv1.Deployment{}
+		if err := fakeClient.Get(ctx, key, deployment); err != nil {
+			t.Fatal(err)
+		}
+		if *deployment.Spec.Replicas != test.replicas {
+			t.Errorf("%s: got %d replicas, want %d", test.name, *deployment.Spec.Replicas, test.replicas)
+		}
+	}
+}
+
+func TestCalculateSurge(t *testing.T) {
+	tests := []struct {
+		surge    intstr.IntOrString
+		replicas int32
+		want     int32
+		err      bool
+	}{
+		{surge: intstr.FromInt32(1), replicas: 3, want: 4},
+		{surge: intstr.FromInt32(5), replicas: 50, want: 55},
+		{surge: intstr.FromInt32(0), replicas: 3, want: 3},
+		{surge: intstr.FromString("10%"), replicas: 5, want: 6},
+		{surge: intstr.FromString("10%"), replicas: 50, want: 55},
+		{surge: intstr.FromString("50%"), replicas: 3, want: 5},
+		{surge: intstr.FromString("100%"), replicas: 2, want: 4},
+		{surge: intstr.FromString("10%"), replicas: 0, want: 0},
+		{surge: intstr.FromInt32(-1), replicas: 3, err: true},
+		{surge: intstr.FromString("5"), replicas: 3, err: true},
+		{surge: intstr.FromString("lots%"), replicas: 3, err: true},
+	}
+	for _, test := range tests {
+		got, err := calculateSurge(test.surge, test.replicas)
+		if test.err {
+			if err == nil {
+				t.Errorf("surge %s of %d: got %d want an error", test.surge.String(), test.replicas, got)
+			}
+			continue
+		}
+		if err != nil || got != test.want {
+			t.Errorf("surge %s of %d: got %d, %v want %d", test.surge.String(), test.replicas, got, err, test.want)
+		}
+	}
+}
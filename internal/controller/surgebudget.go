@@ -0,0 +1,190 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	myappsv1 "github.com/azure/eviction-autoscaler/api/v1"
+	"github.com/azure/eviction-autoscaler/internal/events"
+	"github.com/azure/eviction-autoscaler/internal/metrics"
+	"github.com/azure/eviction-autoscaler/internal/tracing"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// ConditionSurgeBudgetExhausted is true while a surge waits for --max-total-surge-replicas to have room for it.
+const ConditionSurgeBudgetExhausted = "SurgeBudgetExhausted"
+
+// SurgeBudget caps the replicas all EvictionAutoScalers hold above their baselines together, whatever their own
+// maxReplicas allow, so drains can't take more than a fixed amount of extra capacity. Every scale-up reserves its
+// surge before scaling and every reconcile reports what its target really holds, so replicas are given back as
+// surges go down. A nil SurgeBudget allows every surge.
+type SurgeBudget struct {
+	mu    sync.Mutex
+	limit int32
+	held  map[types.NamespacedName]int32
+	// waiting are the EvictionAutoScalers refused a reservation, requeued once replicas are given back.
+	waiting map[types.NamespacedName]bool
+	// rebuilt is set once held has been filled in from every EvictionAutoScaler's status.currentSurge.
+	rebuilt bool
+}
+
+// NewSurgeBudget allows limit surge replicas across the cluster. Zero means no limit and returns nil.
+func NewSurgeBudget(limit int) *SurgeBudget {
+	if limit <= 0 {
+		return nil
+	}
+	b := &SurgeBudget{limit: int32(limit), held: map[types.NamespacedName]int32{}, waiting: map[types.NamespacedName]bool{}}
+	b.observe()
+	return b
+}
+
+// Reserve records that key is about to hold replicas above its baseline if that fits the budget along with every
+// other EvictionAutoScaler's surge, else returns false and how many replicas are free. Checking and spending happen
+// under one lock so two reconciles can't both take the last replicas. The first reservation after a restart lists
+// the EvictionAutoScalers with c to account for the surges made before it.
+func (b *SurgeBudget) Reserve(ctx context.Context, c client.Reader, key types.NamespacedName, replicas int32) (bool, int32, error) {
+	if b == nil {
+		return true, 0, nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.rebuild(ctx, c); err != nil {
+		return false, 0, err
+	}
+	free := b.limit - b.used() + b.held[key]
+	if replicas > b.held[key] && replicas > free {
+		b.waiting[key] = true
+		return false, max(free, 0), nil
+	}
+	delete(b.waiting, key)
+	b.held[key] = replicas
+	b.observe()
+	return true, 0, nil
+}
+
+// Set records that key holds replicas above its baseline, as read from its target, and returns the waiting
+// EvictionAutoScalers to requeue if that gave replicas back.
+func (b *SurgeBudget) Set(key types.NamespacedName, replicas int32) []types.NamespacedName {
+	if b == nil {
+		return nil
+	}
+	replicas = max(replicas, 0)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	previous, found := b.held[key]
+	if replicas == 0 && b.rebuilt {
+		delete(b.held, key)
+	} else {
+		// kept at zero till the rebuild so it doesn't bring back a surge we already saw go
+		b.held[key] = replicas
+	}
+	delete(b.waiting, key)
+	b.observe()
+	if !found || replicas >= previous || len(b.waiting) == 0 {
+		return nil
+	}
+	// the freed replicas go to whoever reserves first, the rest wait again
+	var requeue []types.NamespacedName
+	for waiting := range b.waiting {
+		requeue = append(requeue, waiting)
+	}
+	clear(b.waiting)
+	return requeue
+}
+
+// Used returns the surge replicas held across the cluster.
+func (b *SurgeBudget) Used() int32 {
+	if b == nil {
+		return 0
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.used()
+}
+
+// rebuild fills in held from status.currentSurge of the EvictionAutoScalers not yet reported, once, with mu held.
+func (b *SurgeBudget) rebuild(ctx context.Context, c client.Reader) error {
+	if b.rebuilt {
+		return nil
+	}
+	list := &myappsv1.EvictionAutoScalerList{}
+	if err := c.List(ctx, list); err != nil {
+		return err
+	}
+	for _, EvictionAutoScaler := range list.Items {
+		key := client.ObjectKeyFromObject(&EvictionAutoScaler)
+		if _, found := b.held[key]; !found && EvictionAutoScaler.Status.CurrentSurge > 0 {
+			b.held[key] = EvictionAutoScaler.Status.CurrentSurge
+		}
+	}
+	for key, replicas := range b.held {
+		if replicas == 0 {
+			delete(b.held, key)
+		}
+	}
+	b.rebuilt = true
+	log.FromContext(ctx).Info("Rebuilt surge budget", "used", b.used(), "limit", b.limit)
+	b.observe()
+	return nil
+}
+
+// used sums held, with mu held.
+func (b *SurgeBudget) used() int32 {
+	var used int32
+	for _, replicas := range b.held {
+		used += replicas
+	}
+	return used
+}
+
+// observe sets metrics.SurgeBudgetUsedGauge and metrics.SurgeBudgetRemainingGauge, with mu held.
+func (b *SurgeBudget) observe() {
+	used := b.used()
+	metrics.SurgeBudgetUsedGauge.Set(float64(used))
+	metrics.SurgeBudgetRemainingGauge.Set(float64(max(b.limit-used, 0)))
+}
+
+// setSurge reports that the EvictionAutoScaler key holds replicas above its target's baseline to the surge metrics
+// and SurgeBudget, requeueing the EvictionAutoScalers waiting on the budget if that gave replicas back.
+func (r *EvictionAutoScalerReconciler) setSurge(ctx context.Context, key types.NamespacedName, replicas int32) {
+	metrics.SetSurgeReplicas(key, replicas)
+	for _, waiting := range r.SurgeBudget.Set(key, replicas) {
+		if r.budgetReleased == nil {
+			return
+		}
+		select {
+		case r.budgetReleased <- event.GenericEvent{Object: &myappsv1.EvictionAutoScaler{ObjectMeta: metav1.ObjectMeta{Namespace: waiting.Namespace, Name: waiting.Name}}}:
+		default:
+			// the cooldown requeue picks it up instead
+			log.FromContext(ctx).V(1).Info("Unable to requeue EvictionAutoScaler waiting on the surge budget", "evictionautoscaler", waiting)
+		}
+	}
+}
+
+// spendSurgeBudget reserves surge replicas above its baseline for EvictionAutoScaler out of --max-total-surge-replicas
+// before it scales. Without room it marks it SurgeBudgetExhausted and returns false so the eviction stays unhandled
+// and the surge is retried once another EvictionAutoScaler gives replicas back.
+func (r *EvictionAutoScalerReconciler) spendSurgeBudget(ctx context.Context, EvictionAutoScaler *myappsv1.EvictionAutoScaler, surge int32, action string) (bool, error) {
+	fits, free, err := r.SurgeBudget.Reserve(ctx, r.Client, client.ObjectKeyFromObject(EvictionAutoScaler), surge)
+	if err != nil {
+		return false, err
+	}
+	if fits {
+		clearCondition(&EvictionAutoScaler.Status.Conditions, ConditionSurgeBudgetExhausted, "WithinBudget", "--max-total-surge-replicas has room to "+action)
+		return true, nil
+	}
+	message := fmt.Sprintf("waiting for --max-total-surge-replicas to %s, %d of %d surge replicas are in use and %d free", action,
+		r.SurgeBudget.Used(), r.SurgeBudget.limit, free)
+	log.FromContext(ctx).Info("Surge budget exhausted", "evictionautoscaler", EvictionAutoScaler.Name, "surge", surge, "free", free)
+	tracing.Decide(ctx, "surge-budget-exhausted")
+	if setCondition(&EvictionAutoScaler.Status.Conditions, ConditionSurgeBudgetExhausted, metav1.ConditionTrue, "BudgetExhausted", message) {
+		events.Eventf(r.Recorder, EvictionAutoScaler, corev1.EventTypeWarning, events.ReasonSurgeBudgetExhausted, message)
+	}
+	return false, nil
+}
@@ -0,0 +1,215 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	v1 "github.com/azure/eviction-autoscaler/api/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func TestSurgeBudget(t *testing.T) {
+	ctx := context.Background()
+	testScheme := runtime.NewScheme()
+	if err := v1.AddToScheme(testScheme); err != nil {
+		t.Fatal(err)
+	}
+	// surged before a restart
+	fakeClient := fake.NewClientBuilder().WithScheme(testScheme).WithObjects(&v1.EvictionAutoScaler{
+		ObjectMeta: metav1.ObjectMeta{Name: "old", Namespace: "default"},
+		Status:     v1.EvictionAutoScalerStatus{CurrentSurge: 2},
+	}).Build()
+	old := types.NamespacedName{Namespace: "default", Name: "old"}
+	web := types.NamespacedName{Namespace: "default", Name: "web"}
+	api := types.NamespacedName{Namespace: "default", Name: "api"}
+
+	budget := NewSurgeBudget(5)
+	if fits, _, err := budget.Reserve(ctx, fakeClient, web, 3); err != nil || !fits {
+		t.Fatalf("got fits %v err %v reserving 3 of 5 with 2 held, want it to fit", fits, err)
+	}
+	if used := budget.Used(); used != 5 {
+		t.Errorf("got %d used, want the 2 surged before the restart counted", used)
+	}
+	if fits, free, _ := budget.Reserve(ctx, fakeClient, api, 1); fits || free != 0 {
+		t.Errorf("got fits %v with %d free reserving past the budget", fits, free)
+	}
+	if fits, _, _ := budget.Reserve(ctx, fakeClient, web, 2); !fits {
+		t.Error("shrinking a reservation should always fit")
+	}
+	if requeue := budget.Set(old, 0); len(requeue) != 1 || requeue[0] != api {
+		t.Errorf("got requeue %v once old scaled back down, want %v", requeue, api)
+	}
+	if fits, _, _ := budget.Reserve(ctx, fakeClient, api, 3); !fits || budget.Used() != 5 {
+		t.Errorf("got fits %v with %d used once replicas were given back", fits, budget.Used())
+	}
+	if requeue := budget.Set(web, 2); requeue != nil {
+		t.Errorf("got requeue %v without replicas given back", requeue)
+	}
+
+	var unlimited *SurgeBudget
+	if NewSurgeBudget(0) != nil || unlimited.Set(web, 10) != nil {
+		t.Error("no budget should track nothing")
+	}
+	if fits, _, err := unlimited.Reserve(ctx, fakeClient, web, 100); !fits || err != nil {
+		t.Error("no budget should allow every surge")
+	}
+}
+
+// TestSurgeBudgetConcurrentReserve checks reconciles racing for the last replicas can't overspend the budget.
+func TestSurgeBudgetConcurrentReserve(t *testing.T) {
+	testScheme := runtime.NewScheme()
+	if err := v1.AddToScheme(testScheme); err != nil {
+		t.Fatal(err)
+	}
+	fakeClient := fake.NewClientBuilder().WithScheme(testScheme).Build()
+	budget := NewSurgeBudget(5)
+	var fit atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			key := types.NamespacedName{Namespace: "default", Name: fmt.Sprintf("web-%d", i)}
+			if fits, _, err := budget.Reserve(context.Background(), fakeClient, key, 1); err != nil {
+				t.Error(err)
+			} else if fits {
+				fit.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+	if got := fit.Load(); got != 5 || budget.Used() != 5 {
+		t.Errorf("got %d reservations and %d used of a budget of 5", got, budget.Used())
+	}
+}
+
+// TestSurgeBudgetExhausted checks a surge that doesn't fit waits with SurgeBudgetExhausted and goes ahead once
+// another EvictionAutoScaler gives its replicas back.
+func TestSurgeBudgetExhausted(t *testing.T) {
+	ctx := context.Background()
+	testScheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(testScheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := v1.AddToScheme(testScheme); err != nil {
+		t.Fatal(err)
+	}
+	key := types.NamespacedName{Namespace: "default", Name: "api"}
+	eviction := v1.EvictionRecord{PodName: "api-1", EvictionTime: metav1.NewTime(time.Now().Truncate(time.Second)), Source: v1.EvictionSourceNodeCordon}
+	fakeClient := fake.NewClientBuilder().WithScheme(testScheme).WithStatusSubresource(&v1.EvictionAutoScaler{}).WithObjects(
+		&appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "api", Namespace: "default", Generation: 2},
+			Spec:       appsv1.DeploymentSpec{Replicas: ptr.To(int32(3))},
+		},
+		&v1.EvictionAutoScaler{
+			ObjectMeta: metav1.ObjectMeta{Name: "api", Namespace: "default"},
+			Spec: v1.EvictionAutoScalerSpec{TargetName: "api", TargetKind: deploymentKind,
+				PodSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "api"}}},
+			Status: v1.EvictionAutoScalerStatus{MinReplicas: 3, TargetGeneration: 2,
+				LastEviction: eviction.Eviction(), RecentEvictions: []v1.EvictionRecord{eviction}},
+		},
+		// holds the whole budget
+		&v1.EvictionAutoScaler{
+			ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+			Status:     v1.EvictionAutoScalerStatus{MinReplicas: 3, SurgeReplicas: 5, CurrentSurge: 2},
+		},
+	).Build()
+	released := make(chan event.GenericEvent, 1)
+	r := &EvictionAutoScalerReconciler{Client: fakeClient, Scheme: testScheme, Recorder: record.NewFakeRecorder(10),
+		SurgeBudget: NewSurgeBudget(2), budgetReleased: released}
+	reconcileAPI := func() (*v1.EvictionAutoScaler, int32) {
+		t.Helper()
+		if _, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: key}); err != nil {
+			t.Fatal(err)
+		}
+		EvictionAutoScaler := &v1.EvictionAutoScaler{}
+		if err := fakeClient.Get(ctx, key, EvictionAutoScaler); err != nil {
+			t.Fatal(err)
+		}
+		deployment := &appsv1.Deployment{}
+		if err := fakeClient.Get(ctx, key, deployment); err != nil {
+			t.Fatal(err)
+		}
+		return EvictionAutoScaler, *deployment.Spec.Replicas
+	}
+
+	EvictionAutoScaler, replicas := reconcileAPI()
+	condition := meta.FindStatusCondition(EvictionAutoScaler.Status.Conditions, ConditionSurgeBudgetExhausted)
+	if replicas != 3 || condition == nil || condition.Status != metav1.ConditionTrue {
+		t.Fatalf("got %d replicas and %s %+v with web holding the budget, want 3 held back", replicas, ConditionSurgeBudgetExhausted, condition)
+	}
+
+	// web scales back down
+	r.setSurge(ctx, types.NamespacedName{Namespace: "default", Name: "web"}, 0)
+	select {
+	case requeued := <-released:
+		if requeued.Object.GetName() != "api" {
+			t.Errorf("requeued %s, want api", requeued.Object.GetName())
+		}
+	default:
+		t.Error("api wasn't requeued once web gave its replicas back")
+	}
+	EvictionAutoScaler, replicas = reconcileAPI()
+	if replicas != 4 || meta.IsStatusConditionTrue(EvictionAutoScaler.Status.Conditions, ConditionSurgeBudgetExhausted) {
+		t.Errorf("got %d replicas and conditions %v once the budget had room, want a surge to 4", replicas, EvictionAutoScaler.Status.Conditions)
+	}
+	if used := r.SurgeBudget.Used(); used != 1 {
+		t.Errorf("got %d of the budget used, want api's surge of 1", used)
+	}
+}
+
+// TestSurgeBudgetDryRun checks a dry run surge gives back the budget it reserved, since it scales nothing.
+func TestSurgeBudgetDryRun(t *testing.T) {
+	ctx := context.Background()
+	testScheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(testScheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := v1.AddToScheme(testScheme); err != nil {
+		t.Fatal(err)
+	}
+	key := types.NamespacedName{Namespace: "default", Name: "api"}
+	eviction := v1.EvictionRecord{PodName: "api-1", EvictionTime: metav1.NewTime(time.Now().Truncate(time.Second)), Source: v1.EvictionSourceNodeCordon}
+	fakeClient := fake.NewClientBuilder().WithScheme(testScheme).WithStatusSubresource(&v1.EvictionAutoScaler{}).WithObjects(
+		&appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "api", Namespace: "default", Generation: 2},
+			Spec:       appsv1.DeploymentSpec{Replicas: ptr.To(int32(3))},
+		},
+		&v1.EvictionAutoScaler{
+			ObjectMeta: metav1.ObjectMeta{Name: "api", Namespace: "default"},
+			Spec: v1.EvictionAutoScalerSpec{TargetName: "api", TargetKind: deploymentKind,
+				PodSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "api"}}},
+			Status: v1.EvictionAutoScalerStatus{MinReplicas: 3, TargetGeneration: 2,
+				LastEviction: eviction.Eviction(), RecentEvictions: []v1.EvictionRecord{eviction}},
+		},
+	).Build()
+	r := &EvictionAutoScalerReconciler{Client: fakeClient, Scheme: testScheme, Recorder: record.NewFakeRecorder(10),
+		SurgeBudget: NewSurgeBudget(2), DryRun: true}
+	if _, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: key}); err != nil {
+		t.Fatal(err)
+	}
+	deployment := &appsv1.Deployment{}
+	if err := fakeClient.Get(ctx, key, deployment); err != nil {
+		t.Fatal(err)
+	}
+	if *deployment.Spec.Replicas != 3 {
+		t.Errorf("got %d replicas in a dry run, want 3", *deployment.Spec.Replicas)
+	}
+	if used := r.SurgeBudget.Used(); used != 0 {
+		t.Errorf("got %d of the budget used after a dry run surge, want none", used)
+	}
+}
@@ -0,0 +1,67 @@
+package controllers
+
+import (
+	"fmt"
+
+	myappsv1 "github.com/azure/eviction-autoscaler/api/v1"
+	"github.com/azure/eviction-autoscaler/internal/events"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ConditionSurgeReady is true once the replicas a surge added are available, false with why while they aren't.
+const ConditionSurgeReady = "SurgeReady"
+
+// surgePending marks a surge of targetKind targetName to replicas as just made, its pods can't be available yet.
+func surgePending(EvictionAutoScaler *myappsv1.EvictionAutoScaler, targetKind, targetName string, replicas int32) {
+	EvictionAutoScaler.Status.SurgeReady = false
+	if EvictionAutoScaler.Spec.TargetRef == nil {
+		EvictionAutoScaler.Status.SurgedTarget = targetKind + "/" + targetName
+	}
+	setCondition(&EvictionAutoScaler.Status.Conditions, ConditionSurgeReady, metav1.ConditionFalse, "SurgePending",
+		fmt.Sprintf("waiting for %d replicas of %s %s to be available", replicas, targetKind, targetName))
+}
+
+// updateSurgeReady sets status.surgeReady and the SurgeReady condition from the available replicas of a surged target
+// and reports if either changed. A surge that was ready and lost replicas goes back to false with an event so a
+// drain that stays slow, say behind a crashlooping pod, says why. targetRef targets have no status we know how to read.
+func (r *EvictionAutoScalerReconciler) updateSurgeReady(EvictionAutoScaler *myappsv1.EvictionAutoScaler, target Surger, targetKind, targetName string) bool {
+	status := &EvictionAutoScaler.Status
+	if status.SurgeReplicas <= 0 {
+		return false
+	}
+	if _, scaled := target.(*ScaleWrapper); scaled {
+		return setCondition(&status.Conditions, ConditionSurgeReady, metav1.ConditionUnknown, "AvailabilityUnknown",
+			fmt.Sprintf("%s %s has no available replicas we can read", targetKind, targetName))
+	}
+	_, available := TargetReplicas(target.Obj())
+	message := fmt.Sprintf("%d of %d replicas of %s %s available", available, status.SurgeReplicas, targetKind, targetName)
+	if available >= status.SurgeReplicas {
+		changed := !status.SurgeReady
+		status.SurgeReady = true
+		return setCondition(&status.Conditions, ConditionSurgeReady, metav1.ConditionTrue, "ReplicasAvailable", message) || changed
+	}
+	// still coming up, or no longer available if it was ready before
+	reason := "SurgePending"
+	if condition := meta.FindStatusCondition(status.Conditions, ConditionSurgeReady); status.SurgeReady || (condition != nil && condition.Reason == "ReplicasUnavailable") {
+		reason = "ReplicasUnavailable"
+	}
+	if status.SurgeReady {
+		status.SurgeReady = false
+		setCondition(&status.Conditions, ConditionSurgeReady, metav1.ConditionFalse, reason, message)
+		events.Eventf(r.Recorder, EvictionAutoScaler, corev1.EventTypeWarning, events.ReasonSurgeUnavailable,
+			"Surge of %s %s is no longer available: %s", targetKind, targetName, message)
+		return true
+	}
+	return setCondition(&status.Conditions, ConditionSurgeReady, metav1.ConditionFalse, reason, message)
+}
+
+// surgeGone resets surge readiness and the surged target once the target is back at a baseline.
+func surgeGone(EvictionAutoScaler *myappsv1.EvictionAutoScaler, reason, message string) {
+	EvictionAutoScaler.Status.SurgeReady = false
+	EvictionAutoScaler.Status.SurgedTarget = ""
+	if meta.FindStatusCondition(EvictionAutoScaler.Status.Conditions, ConditionSurgeReady) != nil {
+		setCondition(&EvictionAutoScaler.Status.Conditions, ConditionSurgeReady, metav1.ConditionFalse, reason, message)
+	}
+}
@@ -0,0 +1,104 @@
+package controllers
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	v1 "github.com/azure/eviction-autoscaler/api/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func TestSurgeReady(t *testing.T) {
+	ctx := context.Background()
+	testScheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(testScheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := v1.AddToScheme(testScheme); err != nil {
+		t.Fatal(err)
+	}
+	key := types.NamespacedName{Namespace: "default", Name: "web"}
+	surged := metav1.NewTime(time.Now().Add(-time.Minute).Truncate(time.Second))
+	EvictionAutoScaler := &v1.EvictionAutoScaler{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+		Spec:       v1.EvictionAutoScalerSpec{TargetName: "web", TargetKind: deploymentKind},
+		Status: v1.EvictionAutoScalerStatus{
+			MinReplicas:      3,
+			SurgeReplicas:    4,
+			TargetGeneration: 2,
+			LastEviction:     v1.Eviction{PodName: "web-1", EvictionTime: surged},
+			DrainingNodes:    []string{"node-1"}, // holds the surge
+			Conditions: []metav1.Condition{
+				{Type: ConditionScalingUp, Status: metav1.ConditionTrue, Reason: "Surged", LastTransitionTime: surged},
+				{Type: ConditionSurgeReady, Status: metav1.ConditionFalse, Reason: "SurgePending", LastTransitionTime: surged},
+			},
+		},
+	}
+	deployment := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default", Generation: 2},
+		Spec:       appsv1.DeploymentSpec{Replicas: ptr.To(int32(4))},
+	}
+	pdb := &policyv1.PodDisruptionBudget{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+		Spec:       policyv1.PodDisruptionBudgetSpec{Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}}},
+	}
+	fakeClient := fake.NewClientBuilder().WithScheme(testScheme).
+		WithStatusSubresource(&v1.EvictionAutoScaler{}, &appsv1.Deployment{}).WithObjects(EvictionAutoScaler, deployment, pdb).Build()
+	recorder := record.NewFakeRecorder(10)
+	r := &EvictionAutoScalerReconciler{Client: fakeClient, Scheme: testScheme, Recorder: recorder}
+
+	// the surge pod comes up, crashloops and comes back
+	steps := []struct {
+		available int32
+		ready     bool
+		reason    string
+		event     bool
+	}{
+		{available: 3, reason: "SurgePending"},
+		{available: 4, ready: true, reason: "ReplicasAvailable"},
+		{available: 3, reason: "ReplicasUnavailable", event: true},
+		{available: 3, reason: "ReplicasUnavailable"},
+		{available: 4, ready: true, reason: "ReplicasAvailable"},
+	}
+	for i, step := range steps {
+		if err := fakeClient.Get(ctx, key, deployment); err != nil {
+			t.Fatal(err)
+		}
+		deployment.Status.AvailableReplicas = step.available
+		if err := fakeClient.Status().Update(ctx, deployment); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: key}); err != nil {
+			t.Fatalf("step %d: %v", i, err)
+		}
+		if err := fakeClient.Get(ctx, key, EvictionAutoScaler); err != nil {
+			t.Fatal(err)
+		}
+		condition := meta.FindStatusCondition(EvictionAutoScaler.Status.Conditions, ConditionSurgeReady)
+		if EvictionAutoScaler.Status.SurgeReady != step.ready || condition == nil ||
+			(condition.Status == metav1.ConditionTrue) != step.ready || condition.Reason != step.reason {
+			t.Errorf("step %d: got surgeReady %v %s %+v, want %v reason %s", i, EvictionAutoScaler.Status.SurgeReady, ConditionSurgeReady, condition, step.ready, step.reason)
+		}
+		unavailableEvent := false
+		for len(recorder.Events) > 0 {
+			if event := <-recorder.Events; strings.Contains(event, "SurgeUnavailable") {
+				unavailableEvent = true
+			}
+		}
+		if unavailableEvent != step.event {
+			t.Errorf("step %d: got SurgeUnavailable event %v, want %v", i, unavailableEvent, step.event)
+		}
+	}
+}
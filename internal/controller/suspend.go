@@ -0,0 +1,44 @@
+package controllers
+
+import (
+	"context"
+
+	myappsv1 "github.com/azure/eviction-autoscaler/api/v1"
+	"github.com/azure/eviction-autoscaler/internal/tracing"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// ConditionSuspended is true while nothing is scaled for the EvictionAutoScaler, because of spec.suspend or,
+// with --pdb-missing-action=suspend, a missing pdb.
+const ConditionSuspended = "Suspended"
+
+// suspended leaves the target as it is while spec.suspend is set. Evictions the webhook or node controller recorded
+// before they saw the suspend are handled unsurged so unsuspending doesn't replay them hours later.
+func (r *EvictionAutoScalerReconciler) suspended(ctx context.Context, EvictionAutoScaler *myappsv1.EvictionAutoScaler) (ctrl.Result, error) {
+	tracing.Decide(ctx, "suspended")
+	status := &EvictionAutoScaler.Status
+	changed := setCondition(&status.Conditions, ConditionSuspended, metav1.ConditionTrue, "SpecSuspend",
+		"spec.suspend is set, nothing is recorded or scaled till it is unset")
+	if status.LastEviction != status.HandledEviction {
+		log.FromContext(ctx).Info("Dropping eviction of suspended EvictionAutoScaler", "lastEviction", status.LastEviction)
+		status.HandledEviction = status.LastEviction
+		changed = true
+	}
+	if !changed && status.ObservedGeneration == EvictionAutoScaler.Generation {
+		return ctrl.Result{}, nil
+	}
+	return ctrl.Result{}, r.updateStatus(ctx, EvictionAutoScaler)
+}
+
+// resumed flips a Suspended condition spec.suspend set back to false, reporting if it did. A suspend for a missing
+// pdb is left to pdbMissing.
+func resumed(EvictionAutoScaler *myappsv1.EvictionAutoScaler) bool {
+	condition := meta.FindStatusCondition(EvictionAutoScaler.Status.Conditions, ConditionSuspended)
+	if condition == nil || condition.Reason != "SpecSuspend" {
+		return false
+	}
+	return clearCondition(&EvictionAutoScaler.Status.Conditions, ConditionSuspended, "Resumed", "spec.suspend was unset")
+}
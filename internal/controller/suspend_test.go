@@ -0,0 +1,92 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	v1 "github.com/azure/eviction-autoscaler/api/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func TestSuspend(t *testing.T) {
+	ctx := context.Background()
+	testScheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(testScheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := v1.AddToScheme(testScheme); err != nil {
+		t.Fatal(err)
+	}
+	key := types.NamespacedName{Namespace: "default", Name: "web"}
+	evicted := v1.Eviction{PodName: "web-1", EvictionTime: metav1.NewTime(time.Now().Add(-time.Hour).Truncate(time.Second))}
+	EvictionAutoScaler := &v1.EvictionAutoScaler{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+		Spec:       v1.EvictionAutoScalerSpec{TargetName: "web", TargetKind: deploymentKind, Suspend: true},
+		Status:     v1.EvictionAutoScalerStatus{MinReplicas: 3, TargetGeneration: 1, LastEviction: evicted},
+	}
+	deployment := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default", Generation: 1},
+		Spec:       appsv1.DeploymentSpec{Replicas: ptr.To(int32(3))},
+	}
+	pdb := &policyv1.PodDisruptionBudget{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+		Spec:       policyv1.PodDisruptionBudgetSpec{Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}}},
+		Status:     policyv1.PodDisruptionBudgetStatus{DisruptionsAllowed: 0},
+	}
+	fakeClient := fake.NewClientBuilder().WithScheme(testScheme).
+		WithStatusSubresource(&v1.EvictionAutoScaler{}).WithObjects(EvictionAutoScaler, deployment, pdb).Build()
+	r := &EvictionAutoScalerReconciler{Client: fakeClient, Scheme: testScheme, Recorder: record.NewFakeRecorder(10)}
+	// reconciles and returns the EvictionAutoScaler, its Suspended condition and the deployment's replicas
+	reconcileWeb := func() (*v1.EvictionAutoScaler, *metav1.Condition, int32) {
+		if _, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: key}); err != nil {
+			t.Fatal(err)
+		}
+		if err := fakeClient.Get(ctx, key, EvictionAutoScaler); err != nil {
+			t.Fatal(err)
+		}
+		if err := fakeClient.Get(ctx, key, deployment); err != nil {
+			t.Fatal(err)
+		}
+		return EvictionAutoScaler, meta.FindStatusCondition(EvictionAutoScaler.Status.Conditions, ConditionSuspended), *deployment.Spec.Replicas
+	}
+
+	suspended, condition, replicas := reconcileWeb()
+	if condition == nil || condition.Status != metav1.ConditionTrue || condition.Reason != "SpecSuspend" {
+		t.Errorf("got %s condition %+v while suspended, want true SpecSuspend", ConditionSuspended, condition)
+	}
+	if replicas != 3 || suspended.Status.HandledEviction != evicted {
+		t.Errorf("got %d replicas and handled eviction %v while suspended, want 3 and the eviction dropped", replicas, suspended.Status.HandledEviction)
+	}
+
+	suspended.Spec.Suspend = false
+	if err := fakeClient.Update(ctx, suspended); err != nil {
+		t.Fatal(err)
+	}
+	_, condition, replicas = reconcileWeb()
+	if condition == nil || condition.Status != metav1.ConditionFalse || condition.Reason != "Resumed" {
+		t.Errorf("got %s condition %+v after unsuspending, want false Resumed", ConditionSuspended, condition)
+	}
+	if replicas != 3 {
+		t.Errorf("got %d replicas after unsuspending, want the eviction from before not surged for", replicas)
+	}
+
+	// evictions after unsuspending are surged for again
+	EvictionAutoScaler.Status.LastEviction = v1.Eviction{PodName: "web-2", EvictionTime: metav1.NewTime(time.Now().Truncate(time.Second))}
+	if err := fakeClient.Status().Update(ctx, EvictionAutoScaler); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, replicas = reconcileWeb(); replicas != 4 {
+		t.Errorf("got %d replicas for an eviction after unsuspending, want a surge to 4", replicas)
+	}
+}
@@ -0,0 +1,64 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	myappsv1 "github.com/azure/eviction-autoscaler/api/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// TargetAnnotationKey on a pdb names the workload to surge for it as Kind/name, Deployment/frontend-v2 say, for a
+// pdb whose workload isn't named after it. It overrides spec.targetKind and spec.targetName but not spec.targetRef.
+const TargetAnnotationKey = "eviction-autoscaler.azure.com/target"
+
+// ConditionInvalidTargetAnnotation is true while the pdb's target annotation can't be parsed, nothing is scaled till it is fixed.
+const ConditionInvalidTargetAnnotation = "InvalidTargetAnnotation"
+
+// parseTarget splits a Kind/name target into a target kind GetSurger knows, matched case insensitively, and name.
+func parseTarget(value string) (string, string, error) {
+	kind, name, found := strings.Cut(value, "/")
+	if !found || name == "" {
+		return "", "", fmt.Errorf("%q isn't Kind/name", value)
+	}
+	kind = strings.ToLower(kind)
+	if _, err := GetSurger(kind); err != nil {
+		return "", "", fmt.Errorf("unsupported kind %q in %q, want Deployment, StatefulSet or ReplicaSet", kind, value)
+	}
+	if errs := validation.IsDNS1123Subdomain(name); len(errs) > 0 {
+		return "", "", fmt.Errorf("invalid name in %q: %s", value, strings.Join(errs, ", "))
+	}
+	return kind, name, nil
+}
+
+// applyTargetAnnotation points spec.targetKind and spec.targetName, in memory only, at the workload pdb's target
+// annotation names. While a surge is up they point at status.surgedTarget instead so the surge is scaled back down on
+// the workload that was scaled, and the annotation takes over after. spec.targetRef targets are left alone.
+func applyTargetAnnotation(ctx context.Context, EvictionAutoScaler *myappsv1.EvictionAutoScaler, pdb *policyv1.PodDisruptionBudget) error {
+	spec := &EvictionAutoScaler.Spec
+	if spec.TargetRef != nil {
+		return nil
+	}
+	if value, ok := pdb.Annotations[TargetAnnotationKey]; ok {
+		kind, name, err := parseTarget(value)
+		if err != nil {
+			return err
+		}
+		spec.TargetKind, spec.TargetName = kind, name
+	}
+	surged := EvictionAutoScaler.Status.SurgedTarget
+	if surged == "" || EvictionAutoScaler.Status.SurgeReplicas <= 0 || surged == spec.TargetKind+"/"+spec.TargetName {
+		return nil
+	}
+	kind, name, err := parseTarget(surged)
+	if err != nil {
+		return nil // only we write it, but don't get stuck on a bad one
+	}
+	log.FromContext(ctx).Info("Target changed during a surge, restoring the surged target first",
+		"surgedTarget", surged, "target", spec.TargetKind+"/"+spec.TargetName)
+	spec.TargetKind, spec.TargetName = kind, name
+	return nil
+}
@@ -0,0 +1,150 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	v1 "github.com/azure/eviction-autoscaler/api/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func TestParseTarget(t *testing.T) {
+	tests := []struct {
+		value     string
+		kind      string
+		name      string
+		wantError bool
+	}{
+		{value: "Deployment/frontend-v2", kind: deploymentKind, name: "frontend-v2"},
+		{value: "statefulset/db", kind: statefulSetKind, name: "db"},
+		{value: "ReplicaSet/web-6d4f", kind: replicaSetKind, name: "web-6d4f"},
+		{value: "frontend-v2", wantError: true},
+		{value: "Deployment/", wantError: true},
+		{value: "CronJob/backup", wantError: true},
+		{value: "Deployment/Frontend_V2", wantError: true},
+		{value: "Deployment/a/b", wantError: true},
+	}
+	for _, test := range tests {
+		kind, name, err := parseTarget(test.value)
+		if (err != nil) != test.wantError || kind != test.kind || name != test.name {
+			t.Errorf("%s: got %s %s %v, want %s %s error %v", test.value, kind, name, err, test.kind, test.name, test.wantError)
+		}
+	}
+}
+
+// TestTargetAnnotation checks the pdb's target annotation picks the workload surged, an invalid one is reported and
+// a surge is scaled back down on the workload it was made on after the annotation moves.
+func TestTargetAnnotation(t *testing.T) {
+	ctx := context.Background()
+	testScheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(testScheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := v1.AddToScheme(testScheme); err != nil {
+		t.Fatal(err)
+	}
+	key := types.NamespacedName{Namespace: "default", Name: "web"}
+	frontendKey := types.NamespacedName{Namespace: "default", Name: "frontend-v2"}
+	lastEviction := v1.Eviction{PodName: "frontend-v2-1", EvictionTime: metav1.NewTime(time.Now().Add(-time.Second).Truncate(time.Second))}
+	pdb := &policyv1.PodDisruptionBudget{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default", Annotations: map[string]string{TargetAnnotationKey: "CronJob/frontend-v2"}},
+		Spec:       policyv1.PodDisruptionBudgetSpec{MinAvailable: ptr.To(intstr.FromInt32(3))},
+	}
+	fakeClient := fake.NewClientBuilder().WithScheme(testScheme).
+		WithStatusSubresource(&v1.EvictionAutoScaler{}).
+		WithIndex(&v1.EvictionAutoScaler{}, PDBIndex, evictionAutoScalerPDB).
+		WithIndex(&v1.EvictionAutoScaler{}, TargetIndex, evictionAutoScalerTarget).
+		WithObjects(
+			&appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default", Generation: 2},
+				Spec:       appsv1.DeploymentSpec{Replicas: ptr.To(int32(3))},
+			},
+			&appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{Name: "frontend-v2", Namespace: "default", Generation: 2},
+				Spec:       appsv1.DeploymentSpec{Replicas: ptr.To(int32(3))},
+			},
+			pdb,
+			&v1.EvictionAutoScaler{
+				ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+				Spec:       v1.EvictionAutoScalerSpec{TargetName: "web", TargetKind: deploymentKind},
+				Status:     v1.EvictionAutoScalerStatus{MinReplicas: 3, TargetGeneration: 2, LastEviction: lastEviction},
+			},
+		).Build()
+	r := &EvictionAutoScalerReconciler{Client: fakeClient, Scheme: testScheme, Recorder: record.NewFakeRecorder(20)}
+	replicas := func(key types.NamespacedName) int32 {
+		deployment := &appsv1.Deployment{}
+		if err := fakeClient.Get(ctx, key, deployment); err != nil {
+			t.Fatal(err)
+		}
+		return *deployment.Spec.Replicas
+	}
+	annotate := func(value string) {
+		if err := fakeClient.Get(ctx, key, pdb); err != nil {
+			t.Fatal(err)
+		}
+		pdb.Annotations[TargetAnnotationKey] = value
+		if err := fakeClient.Update(ctx, pdb); err != nil {
+			t.Fatal(err)
+		}
+	}
+	reconcileStatus := func() *v1.EvictionAutoScalerStatus {
+		if _, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: key}); err != nil {
+			t.Fatal(err)
+		}
+		EvictionAutoScaler := &v1.EvictionAutoScaler{}
+		if err := fakeClient.Get(ctx, key, EvictionAutoScaler); err != nil {
+			t.Fatal(err)
+		}
+		return &EvictionAutoScaler.Status
+	}
+
+	status := reconcileStatus()
+	if !meta.IsStatusConditionTrue(status.Conditions, ConditionInvalidTargetAnnotation) || replicas(key) != 3 || replicas(frontendKey) != 3 {
+		t.Errorf("got %s %+v and replicas %d %d, want it true and nothing scaled", ConditionInvalidTargetAnnotation,
+			meta.FindStatusCondition(status.Conditions, ConditionInvalidTargetAnnotation), replicas(key), replicas(frontendKey))
+	}
+
+	annotate("Deployment/frontend-v2")
+	status = reconcileStatus()
+	if meta.IsStatusConditionTrue(status.Conditions, ConditionInvalidTargetAnnotation) || replicas(frontendKey) != 4 || replicas(key) != 3 {
+		t.Errorf("got replicas web %d frontend-v2 %d, want frontend-v2 surged to 4", replicas(key), replicas(frontendKey))
+	}
+	if status.SurgedTarget != deploymentKind+"/frontend-v2" {
+		t.Errorf("got surgedTarget %q, want %s/frontend-v2", status.SurgedTarget, deploymentKind)
+	}
+	requests := r.evictionAutoScalersForTarget(deploymentKind)(ctx, &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "frontend-v2"}})
+	if len(requests) != 1 || requests[0].NamespacedName != key {
+		t.Errorf("got %v for frontend-v2, want the EvictionAutoScaler of the pdb naming it", requests)
+	}
+
+	// the annotation moves mid-surge, the cooldown then passes
+	annotate("Deployment/web")
+	EvictionAutoScaler := &v1.EvictionAutoScaler{}
+	if err := fakeClient.Get(ctx, key, EvictionAutoScaler); err != nil {
+		t.Fatal(err)
+	}
+	EvictionAutoScaler.Status.LastScaleTime = metav1.NewTime(time.Now().Add(-time.Hour))
+	EvictionAutoScaler.Status.LastEviction.EvictionTime = metav1.NewTime(time.Now().Add(-time.Hour))
+	if err := fakeClient.Status().Update(ctx, EvictionAutoScaler); err != nil {
+		t.Fatal(err)
+	}
+	status = reconcileStatus()
+	if replicas(frontendKey) != 3 || replicas(key) != 3 {
+		t.Errorf("got replicas web %d frontend-v2 %d, want frontend-v2 scaled back to 3 and web untouched", replicas(key), replicas(frontendKey))
+	}
+	if status.SurgedTarget != "" || status.SurgeReplicas != 0 {
+		t.Errorf("got surgedTarget %q surgeReplicas %d, want both cleared", status.SurgedTarget, status.SurgeReplicas)
+	}
+}
@@ -0,0 +1,106 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	myappsv1 "github.com/azure/eviction-autoscaler/api/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// ConditionAmbiguousTarget is true while the pdb's selector matches the pod templates of several workloads, so
+// none is surged till targetName or the target annotation picks one.
+const ConditionAmbiguousTarget = "AmbiguousTarget"
+
+// podTemplateLabels returns the pod template labels of a Deployment, StatefulSet or ReplicaSet.
+func podTemplateLabels(obj client.Object) map[string]string {
+	switch workload := obj.(type) {
+	case *appsv1.Deployment:
+		return workload.Spec.Template.Labels
+	case *appsv1.StatefulSet:
+		return workload.Spec.Template.Labels
+	case *appsv1.ReplicaSet:
+		return workload.Spec.Template.Labels
+	}
+	return nil
+}
+
+// matchingWorkloads returns the kind/name, sorted, of the Deployments and StatefulSets in pdb's namespace whose pod
+// template labels its selector matches.
+func (r *EvictionAutoScalerReconciler) matchingWorkloads(ctx context.Context, pdb *policyv1.PodDisruptionBudget) ([]string, error) {
+	selector, err := metav1.LabelSelectorAsSelector(pdb.Spec.Selector)
+	if err != nil {
+		return nil, nil // invalid selectors never match anything
+	}
+	var matches []string
+	deployments := &appsv1.DeploymentList{}
+	if err := r.List(ctx, deployments, client.InNamespace(pdb.Namespace)); err != nil {
+		return nil, err
+	}
+	for i := range deployments.Items {
+		if selector.Matches(labels.Set(deployments.Items[i].Spec.Template.Labels)) {
+			matches = append(matches, deploymentKind+"/"+deployments.Items[i].Name)
+		}
+	}
+	statefulSets := &appsv1.StatefulSetList{}
+	if err := r.List(ctx, statefulSets, client.InNamespace(pdb.Namespace)); err != nil {
+		return nil, err
+	}
+	for i := range statefulSets.Items {
+		if selector.Matches(labels.Set(statefulSets.Items[i].Spec.Template.Labels)) {
+			matches = append(matches, statefulSetKind+"/"+statefulSets.Items[i].Name)
+		}
+	}
+	slices.Sort(matches)
+	return matches, nil
+}
+
+// discoverTarget points spec.targetKind and spec.targetName, in memory only, at the one Deployment or StatefulSet
+// whose pod template pdb's selector matches, for an EvictionAutoScaler naming no target, and keeps it in
+// status.resolvedTarget. It is looked up again every reconcile so a changed selector or template is followed.
+// No match or several are reported with the TargetMissing or AmbiguousTarget condition rather than guessing, and
+// it returns false so nothing is scaled, unless a surge is up which still goes back down on status.surgedTarget.
+// The first result says whether status changed.
+func (r *EvictionAutoScalerReconciler) discoverTarget(ctx context.Context, EvictionAutoScaler *myappsv1.EvictionAutoScaler, pdb *policyv1.PodDisruptionBudget) (bool, bool, error) {
+	logger := log.FromContext(ctx)
+	status := &EvictionAutoScaler.Status
+	matches, err := r.matchingWorkloads(ctx, pdb)
+	if err != nil {
+		return false, false, err
+	}
+	if len(matches) == 1 {
+		kind, name, _ := strings.Cut(matches[0], "/")
+		EvictionAutoScaler.Spec.TargetKind, EvictionAutoScaler.Spec.TargetName = kind, name
+		changed := clearCondition(&status.Conditions, ConditionAmbiguousTarget, "Resolved", fmt.Sprintf("pdb %s selects %s", pdb.Name, matches[0]))
+		if status.ResolvedTarget != matches[0] {
+			logger.Info("Discovered target from the pdb selector", "target", matches[0], "previous", status.ResolvedTarget)
+			status.ResolvedTarget = matches[0]
+			changed = true
+		}
+		return changed, true, nil
+	}
+	if status.SurgeReplicas > 0 && status.SurgedTarget != "" {
+		logger.Info("Can't discover the target, restoring the surged one first", "matches", matches, "surgedTarget", status.SurgedTarget)
+		return false, true, nil
+	}
+	status.ResolvedTarget = ""
+	if len(matches) == 0 {
+		message := fmt.Sprintf("no Deployment or StatefulSet pod template matches the selector of pdb %s, set targetName or the %s annotation", pdb.Name, TargetAnnotationKey)
+		clearCondition(&status.Conditions, ConditionAmbiguousTarget, "NoMatch", message)
+		setCondition(&status.Conditions, ConditionTargetMissing, metav1.ConditionTrue, "TargetNotFound", message)
+		degraded(&status.Conditions, "TargetNotFound", message)
+		return true, false, nil
+	}
+	message := fmt.Sprintf("the selector of pdb %s matches the pod templates of %s, set targetName or the %s annotation to pick one",
+		pdb.Name, strings.Join(matches, ", "), TargetAnnotationKey)
+	setCondition(&status.Conditions, ConditionAmbiguousTarget, metav1.ConditionTrue, "MultipleMatches", message)
+	degraded(&status.Conditions, "AmbiguousTarget", message)
+	return true, false, nil
+}
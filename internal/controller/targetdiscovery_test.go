@@ -0,0 +1,122 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	v1 "github.com/azure/eviction-autoscaler/api/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// TestTargetDiscovery checks an EvictionAutoScaler naming no target surges the one workload whose pod template its
+// pdb selects, and reports several or none instead of picking one.
+func TestTargetDiscovery(t *testing.T) {
+	ctx := context.Background()
+	testScheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(testScheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := v1.AddToScheme(testScheme); err != nil {
+		t.Fatal(err)
+	}
+	key := types.NamespacedName{Namespace: "default", Name: "web-pdb"}
+	noneKey := types.NamespacedName{Namespace: "default", Name: "none"}
+	lastEviction := v1.Eviction{PodName: "frontend-1", EvictionTime: metav1.NewTime(time.Now().Add(-time.Second).Truncate(time.Second))}
+	template := func(app string) corev1.PodTemplateSpec {
+		return corev1.PodTemplateSpec{ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"app": app}}}
+	}
+	pdb := func(name, app string) *policyv1.PodDisruptionBudget {
+		return &policyv1.PodDisruptionBudget{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+			Spec: policyv1.PodDisruptionBudgetSpec{MinAvailable: ptr.To(intstr.FromInt32(3)),
+				Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": app}}},
+		}
+	}
+	statefulSet := &appsv1.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "web-db", Namespace: "default", Generation: 1},
+		Spec:       appsv1.StatefulSetSpec{Replicas: ptr.To(int32(3)), Template: template("web")},
+	}
+	fakeClient := fake.NewClientBuilder().WithScheme(testScheme).
+		WithStatusSubresource(&v1.EvictionAutoScaler{}).
+		WithIndex(&v1.EvictionAutoScaler{}, PDBIndex, evictionAutoScalerPDB).
+		WithIndex(&v1.EvictionAutoScaler{}, TargetIndex, evictionAutoScalerTarget).
+		WithObjects(
+			&appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{Name: "frontend", Namespace: "default", Generation: 2},
+				Spec:       appsv1.DeploymentSpec{Replicas: ptr.To(int32(3)), Template: template("web")},
+			},
+			&appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{Name: "web-pdb", Namespace: "default", Generation: 2},
+				Spec:       appsv1.DeploymentSpec{Replicas: ptr.To(int32(3)), Template: template("other")},
+			},
+			statefulSet,
+			pdb("web-pdb", "web"),
+			pdb("none", "none"),
+			&v1.EvictionAutoScaler{
+				ObjectMeta: metav1.ObjectMeta{Name: "web-pdb", Namespace: "default"},
+				Status:     v1.EvictionAutoScalerStatus{MinReplicas: 3, TargetGeneration: 2, LastEviction: lastEviction},
+			},
+			&v1.EvictionAutoScaler{ObjectMeta: metav1.ObjectMeta{Name: "none", Namespace: "default"}},
+		).Build()
+	r := &EvictionAutoScalerReconciler{Client: fakeClient, Scheme: testScheme, Recorder: record.NewFakeRecorder(20)}
+	replicas := func(name string) int32 {
+		deployment := &appsv1.Deployment{}
+		if err := fakeClient.Get(ctx, types.NamespacedName{Namespace: "default", Name: name}, deployment); err != nil {
+			t.Fatal(err)
+		}
+		return *deployment.Spec.Replicas
+	}
+	reconcileStatus := func(key types.NamespacedName) *v1.EvictionAutoScalerStatus {
+		if _, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: key}); err != nil {
+			t.Fatal(err)
+		}
+		EvictionAutoScaler := &v1.EvictionAutoScaler{}
+		if err := fakeClient.Get(ctx, key, EvictionAutoScaler); err != nil {
+			t.Fatal(err)
+		}
+		return &EvictionAutoScaler.Status
+	}
+
+	status := reconcileStatus(key)
+	if !meta.IsStatusConditionTrue(status.Conditions, ConditionAmbiguousTarget) || status.ResolvedTarget != "" || replicas("frontend") != 3 {
+		t.Errorf("got %s %+v, resolvedTarget %q and frontend at %d, want it true and nothing scaled", ConditionAmbiguousTarget,
+			meta.FindStatusCondition(status.Conditions, ConditionAmbiguousTarget), status.ResolvedTarget, replicas("frontend"))
+	}
+
+	// relabeling the statefulset leaves only frontend, its event maps to the EvictionAutoScaler through the pdb selector
+	requests := r.evictionAutoScalersForTarget(statefulSetKind)(ctx, statefulSet)
+	if len(requests) != 1 || requests[0].NamespacedName != key {
+		t.Errorf("got %v for the statefulset, want the EvictionAutoScaler whose pdb selects its pods", requests)
+	}
+	statefulSet.Spec.Template = template("db")
+	if err := fakeClient.Update(ctx, statefulSet); err != nil {
+		t.Fatal(err)
+	}
+	status = reconcileStatus(key)
+	if meta.IsStatusConditionTrue(status.Conditions, ConditionAmbiguousTarget) || status.ResolvedTarget != deploymentKind+"/frontend" {
+		t.Errorf("got %s %+v and resolvedTarget %q, want it false and %s/frontend", ConditionAmbiguousTarget,
+			meta.FindStatusCondition(status.Conditions, ConditionAmbiguousTarget), status.ResolvedTarget, deploymentKind)
+	}
+	if replicas("frontend") != 4 || replicas("web-pdb") != 3 {
+		t.Errorf("got frontend %d web-pdb %d, want frontend surged to 4 rather than the deployment named after the pdb", replicas("frontend"), replicas("web-pdb"))
+	}
+
+	status = reconcileStatus(noneKey)
+	if condition := meta.FindStatusCondition(status.Conditions, ConditionTargetMissing); condition == nil ||
+		condition.Status != metav1.ConditionTrue || condition.Reason != "TargetNotFound" {
+		t.Errorf("got %s %+v, want it true with reason TargetNotFound for a pdb selecting no workload", ConditionTargetMissing, condition)
+	}
+}
@@ -0,0 +1,77 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	v1 "github.com/azure/eviction-autoscaler/api/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// TestTargetPDBName checks an EvictionAutoScaler surges for the pdb named in spec.targetPDBName and
+// no second one is created for that pdb.
+func TestTargetPDBName(t *testing.T) {
+	ctx := context.Background()
+	testScheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(testScheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := v1.AddToScheme(testScheme); err != nil {
+		t.Fatal(err)
+	}
+	key := types.NamespacedName{Namespace: "default", Name: "web"}
+	pdbKey := types.NamespacedName{Namespace: "default", Name: "web-chart-pdb"}
+	lastEviction := v1.Eviction{PodName: "web-1", EvictionTime: metav1.NewTime(time.Now().Add(-time.Second).Truncate(time.Second))}
+	fakeClient := fake.NewClientBuilder().WithScheme(testScheme).
+		WithStatusSubresource(&v1.EvictionAutoScaler{}).
+		WithIndex(&v1.EvictionAutoScaler{}, PDBIndex, evictionAutoScalerPDB).
+		WithObjects(
+			&appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default", Generation: 2},
+				Spec:       appsv1.DeploymentSpec{Replicas: ptr.To(int32(3))},
+			},
+			&policyv1.PodDisruptionBudget{
+				ObjectMeta: metav1.ObjectMeta{Name: pdbKey.Name, Namespace: pdbKey.Namespace},
+				Spec:       policyv1.PodDisruptionBudgetSpec{MinAvailable: ptr.To(intstr.FromInt32(3))},
+			},
+			&v1.EvictionAutoScaler{
+				ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+				Spec:       v1.EvictionAutoScalerSpec{TargetName: "web", TargetKind: deploymentKind, TargetPDBName: pdbKey.Name},
+				Status:     v1.EvictionAutoScalerStatus{MinReplicas: 3, TargetGeneration: 2, LastEviction: lastEviction},
+			},
+		).Build()
+
+	r := &EvictionAutoScalerReconciler{Client: fakeClient, Scheme: testScheme}
+	if requests := r.evictionAutoScalersForPDB(ctx, &policyv1.PodDisruptionBudget{ObjectMeta: metav1.ObjectMeta{Name: pdbKey.Name, Namespace: pdbKey.Namespace}}); len(requests) != 1 || requests[0].NamespacedName != key {
+		t.Errorf("got %v for the pdb, want the EvictionAutoScaler pointing at it", requests)
+	}
+	if _, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: key}); err != nil {
+		t.Fatal(err)
+	}
+	deployment := &appsv1.Deployment{}
+	if err := fakeClient.Get(ctx, key, deployment); err != nil {
+		t.Fatal(err)
+	}
+	if *deployment.Spec.Replicas != 4 {
+		t.Errorf("got %d replicas, want the surge to 4 for pdb %s", *deployment.Spec.Replicas, pdbKey.Name)
+	}
+
+	pdbReconciler := &PDBToEvictionAutoScalerReconciler{Client: fakeClient, Scheme: testScheme}
+	if _, err := pdbReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: pdbKey}); err != nil {
+		t.Fatal(err)
+	}
+	if err := fakeClient.Get(ctx, pdbKey, &v1.EvictionAutoScaler{}); !errors.IsNotFound(err) {
+		t.Errorf("got %v getting an EvictionAutoScaler named after the pdb, want none created", err)
+	}
+}
@@ -0,0 +1,140 @@
+package controllers
+
+import (
+	"context"
+	"slices"
+
+	myappsv1 "github.com/azure/eviction-autoscaler/api/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// TargetIndex indexes EvictionAutoScalers by the kind/name of their targetName and targetKind target.
+const TargetIndex = "spec.target"
+
+// PDBIndex indexes EvictionAutoScalers by the name of the pdb they act on.
+const PDBIndex = "spec.targetPDBName"
+
+// evictionAutoScalerPDB extracts the pdb name for the PDBIndex. spec.podSelector ones have no pdb.
+func evictionAutoScalerPDB(rawObj client.Object) []string {
+	EvictionAutoScaler := rawObj.(*myappsv1.EvictionAutoScaler)
+	if EvictionAutoScaler.Spec.PodSelector != nil {
+		return nil
+	}
+	return []string{EvictionAutoScaler.PDBName()}
+}
+
+// evictionAutoScalerTarget extracts kind/name for the TargetIndex, status.resolvedTarget if no target is named.
+// targetRef targets aren't indexed since they can be any kind and we don't watch them.
+func evictionAutoScalerTarget(rawObj client.Object) []string {
+	EvictionAutoScaler := rawObj.(*myappsv1.EvictionAutoScaler)
+	if EvictionAutoScaler.Spec.TargetRef != nil {
+		return nil
+	}
+	var keys []string
+	if name := EvictionAutoScaler.Spec.TargetName; name != "" {
+		keys = append(keys, EvictionAutoScaler.Spec.TargetKind+"/"+name)
+	} else if resolved := EvictionAutoScaler.Status.ResolvedTarget; resolved != "" {
+		keys = append(keys, resolved)
+	}
+	// a surge on a workload the pdb's target annotation named is watched till it is scaled back down
+	if surged := EvictionAutoScaler.Status.SurgedTarget; surged != "" && !slices.Contains(keys, surged) {
+		keys = append(keys, surged)
+	}
+	return keys
+}
+
+// evictionAutoScalersForTarget maps a target of kind to the EvictionAutoScalers surging it so their
+// scale down is driven by the target settling rather than only by the next eviction or requeue.
+// Those of pdbs whose target annotation names it, or without one whose selector matches its pod template, are
+// included too so target discovery follows workloads being created or relabeled.
+func (r *EvictionAutoScalerReconciler) evictionAutoScalersForTarget(kind string) handler.MapFunc {
+	return func(ctx context.Context, obj client.Object) []reconcile.Request {
+		EvictionAutoScalerList := &myappsv1.EvictionAutoScalerList{}
+		if err := r.List(ctx, EvictionAutoScalerList, client.InNamespace(obj.GetNamespace()),
+			client.MatchingFields{TargetIndex: kind + "/" + obj.GetName()}); err != nil {
+			log.FromContext(ctx).Error(err, "Unable to list EvictionAutoScalers for target", "kind", kind, "namespace", obj.GetNamespace(), "name", obj.GetName())
+			return nil
+		}
+		requests := make([]reconcile.Request, 0, len(EvictionAutoScalerList.Items))
+		for _, EvictionAutoScaler := range EvictionAutoScalerList.Items {
+			requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&EvictionAutoScaler)})
+		}
+		pdbList := &policyv1.PodDisruptionBudgetList{}
+		if err := r.List(ctx, pdbList, client.InNamespace(obj.GetNamespace())); err != nil {
+			log.FromContext(ctx).Error(err, "Unable to list pdbs for target", "kind", kind, "namespace", obj.GetNamespace(), "name", obj.GetName())
+			return requests
+		}
+		templateLabels := labels.Set(podTemplateLabels(obj))
+		for i := range pdbList.Items {
+			pdb := &pdbList.Items[i]
+			if value, ok := pdb.Annotations[TargetAnnotationKey]; ok {
+				if annotatedKind, name, err := parseTarget(value); err != nil || annotatedKind != kind || name != obj.GetName() {
+					continue
+				}
+			} else if selector, err := metav1.LabelSelectorAsSelector(pdb.Spec.Selector); err != nil || !selector.Matches(templateLabels) {
+				continue
+			}
+			for _, request := range r.evictionAutoScalersForPDB(ctx, pdb) {
+				if !slices.Contains(requests, request) {
+					requests = append(requests, request)
+				}
+			}
+		}
+		return requests
+	}
+}
+
+// evictionAutoScalersForPDB maps a pdb to the EvictionAutoScalers pointing at it through spec.targetPDBName or their name.
+func (r *EvictionAutoScalerReconciler) evictionAutoScalersForPDB(ctx context.Context, obj client.Object) []reconcile.Request {
+	EvictionAutoScalerList := &myappsv1.EvictionAutoScalerList{}
+	if err := r.List(ctx, EvictionAutoScalerList, client.InNamespace(obj.GetNamespace()),
+		client.MatchingFields{PDBIndex: obj.GetName()}); err != nil {
+		log.FromContext(ctx).Error(err, "Unable to list EvictionAutoScalers for pdb", "namespace", obj.GetNamespace(), "name", obj.GetName())
+		return nil
+	}
+	requests := make([]reconcile.Request, 0, len(EvictionAutoScalerList.Items))
+	for _, EvictionAutoScaler := range EvictionAutoScalerList.Items {
+		requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&EvictionAutoScaler)})
+	}
+	return requests
+}
+
+// TargetReplicas returns the replicas and available replicas of a Deployment, StatefulSet or ReplicaSet.
+func TargetReplicas(obj client.Object) (int32, int32) {
+	var replicas *int32
+	var available int32
+	switch target := obj.(type) {
+	case *appsv1.Deployment:
+		replicas, available = target.Spec.Replicas, target.Status.AvailableReplicas
+	case *appsv1.StatefulSet:
+		replicas, available = target.Spec.Replicas, target.Status.AvailableReplicas
+	case *appsv1.ReplicaSet:
+		replicas, available = target.Spec.Replicas, target.Status.AvailableReplicas
+	}
+	if replicas == nil {
+		return 1, available // Default value in Kubernetes if not set
+	}
+	return *replicas, available
+}
+
+// targetChanged passes target updates that could change whether it should go back to its baseline.
+// Status churn like observedGeneration or updatedReplicas during a rollout is dropped.
+var targetChanged = predicate.Funcs{
+	UpdateFunc: func(ue event.UpdateEvent) bool {
+		if ue.ObjectOld.GetGeneration() != ue.ObjectNew.GetGeneration() {
+			return true
+		}
+		oldReplicas, oldAvailable := TargetReplicas(ue.ObjectOld)
+		newReplicas, newAvailable := TargetReplicas(ue.ObjectNew)
+		return oldReplicas != newReplicas || oldAvailable != newAvailable
+	},
+}
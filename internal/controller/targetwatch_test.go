@@ -0,0 +1,113 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	v1 "github.com/azure/eviction-autoscaler/api/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+)
+
+func TestEvictionAutoScalersForTarget(t *testing.T) {
+	ctx := context.Background()
+	testScheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(testScheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := v1.AddToScheme(testScheme); err != nil {
+		t.Fatal(err)
+	}
+	EvictionAutoScaler := func(namespace, name string, spec v1.EvictionAutoScalerSpec) *v1.EvictionAutoScaler {
+		return &v1.EvictionAutoScaler{ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name}, Spec: spec}
+	}
+	discovered := EvictionAutoScaler("default", "web", v1.EvictionAutoScalerSpec{})
+	discovered.Status.ResolvedTarget = deploymentKind + "/web"
+	fakeClient := fake.NewClientBuilder().WithScheme(testScheme).
+		WithIndex(&v1.EvictionAutoScaler{}, TargetIndex, evictionAutoScalerTarget).
+		WithObjects(
+			discovered,
+			EvictionAutoScaler("default", "web-pdb", v1.EvictionAutoScalerSpec{TargetName: "web", TargetKind: deploymentKind}),
+			EvictionAutoScaler("default", "db", v1.EvictionAutoScalerSpec{TargetName: "web", TargetKind: statefulSetKind}),
+			EvictionAutoScaler("default", "rollout", v1.EvictionAutoScalerSpec{TargetRef: &v1.TargetReference{APIVersion: "argoproj.io/v1alpha1", Kind: "Rollout", Name: "web"}}),
+			EvictionAutoScaler("other", "web", v1.EvictionAutoScalerSpec{}),
+		).Build()
+	r := &EvictionAutoScalerReconciler{Client: fakeClient, Scheme: testScheme}
+
+	deployment := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "web"}}
+	var got []types.NamespacedName
+	for _, request := range r.evictionAutoScalersForTarget(deploymentKind)(ctx, deployment) {
+		got = append(got, request.NamespacedName)
+	}
+	want := []types.NamespacedName{{Namespace: "default", Name: "web"}, {Namespace: "default", Name: "web-pdb"}}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestTargetChanged(t *testing.T) {
+	deployment := func(generation int64, replicas, available int32, updated int32) *appsv1.Deployment {
+		return &appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Generation: generation},
+			Spec:       appsv1.DeploymentSpec{Replicas: ptr.To(replicas)},
+			Status:     appsv1.DeploymentStatus{AvailableReplicas: available, UpdatedReplicas: updated},
+		}
+	}
+	old := deployment(1, 4, 3, 3)
+	tests := []struct {
+		name string
+		new  *appsv1.Deployment
+		want bool
+	}{
+		{name: "scaled", new: deployment(2, 3, 3, 3), want: true},
+		{name: "surge became available", new: deployment(1, 4, 4, 3), want: true},
+		{name: "status churn", new: deployment(1, 4, 3, 4)},
+	}
+	for _, test := range tests {
+		if got := targetChanged.Update(event.UpdateEvent{ObjectOld: old, ObjectNew: test.new}); got != test.want {
+			t.Errorf("%s: got %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestPDBChanged(t *testing.T) {
+	pdb := func(generation int64, allowed, healthy int32) *policyv1.PodDisruptionBudget {
+		return &policyv1.PodDisruptionBudget{
+			ObjectMeta: metav1.ObjectMeta{Generation: generation},
+			Status:     policyv1.PodDisruptionBudgetStatus{DisruptionsAllowed: allowed, CurrentHealthy: healthy},
+		}
+	}
+	old := pdb(1, 1, 3)
+	tests := []struct {
+		name string
+		new  *policyv1.PodDisruptionBudget
+		want bool
+	}{
+		{name: "selector or budget changed", new: pdb(2, 1, 3), want: true},
+		{name: "stopped allowing disruptions", new: pdb(1, 0, 2), want: true},
+		{name: "still allowing disruptions", new: pdb(1, 2, 4)},
+		{name: "resync", new: pdb(1, 1, 3)},
+		{name: "target annotation changed", new: func() *policyv1.PodDisruptionBudget {
+			retargeted := pdb(1, 1, 3)
+			retargeted.Annotations = map[string]string{TargetAnnotationKey: "Deployment/frontend-v2"}
+			return retargeted
+		}(), want: true},
+		{name: "started selecting pods", new: func() *policyv1.PodDisruptionBudget {
+			selecting := pdb(1, 1, 3)
+			selecting.Status.ExpectedPods = 3
+			return selecting
+		}(), want: true},
+	}
+	for _, test := range tests {
+		if got := pdbChanged.Update(event.UpdateEvent{ObjectOld: old, ObjectNew: test.new}); got != test.want {
+			t.Errorf("%s: got %v, want %v", test.name, got, test.want)
+		}
+	}
+}
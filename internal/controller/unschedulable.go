@@ -0,0 +1,104 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	myappsv1 "github.com/azure/eviction-autoscaler/api/v1"
+	"github.com/azure/eviction-autoscaler/internal/events"
+	"github.com/azure/eviction-autoscaler/internal/metrics"
+	"github.com/azure/eviction-autoscaler/internal/tracing"
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// ConditionSurgeUnschedulable is true while pods a surge added are stuck Pending, so the drain is still blocked.
+const ConditionSurgeUnschedulable = "SurgeUnschedulable"
+
+// DefaultSurgeScheduleTimeout is how long surge pods may stay unschedulable when spec.surgeScheduleTimeoutSeconds is unset.
+// Long enough for a cluster autoscaler to add a node.
+const DefaultSurgeScheduleTimeout = 5 * time.Minute
+
+// surgeScheduleTimeoutFor returns how long after a surge its pods should be scheduled.
+func surgeScheduleTimeoutFor(EvictionAutoScaler *myappsv1.EvictionAutoScaler) time.Duration {
+	if timeout := EvictionAutoScaler.Spec.SurgeScheduleTimeoutSeconds; timeout != nil && *timeout > 0 {
+		return time.Duration(*timeout) * time.Second
+	}
+	return DefaultSurgeScheduleTimeout
+}
+
+// unschedulableSurgePod returns a pod the current surge added that the scheduler still can't place after the
+// schedule timeout, nil if the surge is available or there is none. Pods are the pdb's, created since the surge.
+func (r *EvictionAutoScalerReconciler) unschedulableSurgePod(ctx context.Context, EvictionAutoScaler *myappsv1.EvictionAutoScaler,
+	pdb *policyv1.PodDisruptionBudget, target Surger) (*corev1.Pod, *corev1.PodCondition, error) {
+	surged := meta.FindStatusCondition(EvictionAutoScaler.Status.Conditions, ConditionScalingUp)
+	if surged == nil || surged.Status != metav1.ConditionTrue || time.Since(surged.LastTransitionTime.Time) < surgeScheduleTimeoutFor(EvictionAutoScaler) {
+		return nil, nil, nil
+	}
+	// targetRef targets have no status we know how to read so they are only judged by their pods
+	if _, scaled := target.(*ScaleWrapper); !scaled {
+		if _, available := TargetReplicas(target.Obj()); available >= target.GetReplicas() {
+			return nil, nil, nil
+		}
+	}
+	selector, err := metav1.LabelSelectorAsSelector(pdb.Spec.Selector)
+	if err != nil {
+		return nil, nil, nil // invalid selectors never match anything
+	}
+	podList := &corev1.PodList{}
+	if err := r.List(ctx, podList, &client.ListOptions{Namespace: pdb.Namespace, LabelSelector: selector}); err != nil {
+		return nil, nil, err
+	}
+	for i := range podList.Items {
+		pod := &podList.Items[i]
+		if pod.Status.Phase != corev1.PodPending || pod.CreationTimestamp.Before(&surged.LastTransitionTime) {
+			continue
+		}
+		for j, condition := range pod.Status.Conditions {
+			if condition.Type == corev1.PodScheduled && condition.Status == corev1.ConditionFalse && condition.Reason == corev1.PodReasonUnschedulable {
+				return pod, &pod.Status.Conditions[j], nil
+			}
+		}
+	}
+	return nil, nil, nil
+}
+
+// revertUnschedulableSurge scales target back to its baseline since the surge isn't adding capacity and only holds quota.
+// The eviction is handled so the next one surges again, by when a cluster autoscaler may have added a node.
+func (r *EvictionAutoScalerReconciler) revertUnschedulableSurge(ctx context.Context, EvictionAutoScaler *myappsv1.EvictionAutoScaler,
+	target Surger, targetKind, targetName string) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+	replicas, floored := scaleDownTo(EvictionAutoScaler, target.GetReplicas())
+	target.SetReplicas(replicas)
+	target.RemoveAnnotation(EvictionSurgeReplicasAnnotationKey)
+	tracing.Decide(ctx, "revert-unschedulable")
+	if r.DryRun {
+		events.DryRun(ctx, r.Recorder, EvictionAutoScaler, metrics.DryRunScaleTarget, "revert unschedulable surge of %s %s to %d replicas", targetKind, targetName, replicas)
+		return ctrl.Result{}, nil
+	}
+	if err := r.updateTarget(ctx, target); err != nil {
+		return ctrl.Result{}, err
+	}
+	logger.Info(fmt.Sprintf("Reverted unschedulable surge of %s %s to %d replicas", targetKind, targetName, replicas))
+	message := fmt.Sprintf("surge pods couldn't be scheduled so scaled back to %d replicas", replicas)
+	events.Eventf(r.Recorder, EvictionAutoScaler, corev1.EventTypeNormal, events.ReasonSurgeScaledDown, "Scaled %s %s back down to %d replicas, the surge couldn't be scheduled", targetKind, targetName, replicas)
+	EvictionAutoScaler.Status.TargetGeneration = target.Obj().GetGeneration()
+	EvictionAutoScaler.Status.SurgeReplicas = 0
+	surgeGone(EvictionAutoScaler, "SurgeUnschedulable", message)
+	if floored {
+		EvictionAutoScaler.Status.MinReplicas = replicas
+	}
+	EvictionAutoScaler.Status.HandledEviction = EvictionAutoScaler.Status.LastEviction
+	setCondition(&EvictionAutoScaler.Status.Conditions, ConditionSurgeUnschedulable, metav1.ConditionTrue, "SurgeReverted", message)
+	clearCondition(&EvictionAutoScaler.Status.Conditions, ConditionScalingUp, "SurgeUnschedulable", message)
+	clearCondition(&EvictionAutoScaler.Status.Conditions, ConditionCoolingDown, "SurgeUnschedulable", message)
+	setCondition(&EvictionAutoScaler.Status.Conditions, ConditionIdle, metav1.ConditionTrue, "SurgeUnschedulable", fmt.Sprintf("back at %d replicas", replicas))
+	degraded(&EvictionAutoScaler.Status.Conditions, "SurgeUnschedulable", message)
+	return ctrl.Result{}, r.updateScaleStatus(ctx, EvictionAutoScaler)
+}
@@ -0,0 +1,133 @@
+package controllers
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	v1 "github.com/azure/eviction-autoscaler/api/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func TestSurgeUnschedulable(t *testing.T) {
+	ctx := context.Background()
+	testScheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(testScheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := v1.AddToScheme(testScheme); err != nil {
+		t.Fatal(err)
+	}
+	key := types.NamespacedName{Namespace: "default", Name: "web"}
+	surged := metav1.NewTime(time.Now().Add(-10 * time.Minute).Truncate(time.Second))
+	lastEviction := v1.Eviction{PodName: "web-1", EvictionTime: surged}
+	labels := map[string]string{"app": "web"}
+	unschedulable := corev1.PodStatus{Phase: corev1.PodPending, Conditions: []corev1.PodCondition{{
+		Type: corev1.PodScheduled, Status: corev1.ConditionFalse, Reason: corev1.PodReasonUnschedulable,
+		Message: "0/3 nodes are available: 3 Insufficient cpu.",
+	}}}
+
+	tests := []struct {
+		name      string
+		timeout   *int32
+		revert    bool
+		available int32            // of the deployment
+		pod       corev1.PodStatus // of the surge pod
+		wasSet    bool             // SurgeUnschedulable already true
+		replicas  int32            // deployment replicas after reconcile
+		reason    string           // of a true SurgeUnschedulable, empty for not true
+		handled   bool
+	}{
+		{name: "unschedulable", available: 3, pod: unschedulable, replicas: 4, reason: "PodsPending"},
+		{name: "within timeout", timeout: ptr.To(int32(3600)), available: 3, pod: unschedulable, replicas: 4},
+		{name: "reverted", revert: true, available: 3, pod: unschedulable, replicas: 3, reason: "SurgeReverted", handled: true},
+		{name: "node added", available: 4, pod: corev1.PodStatus{Phase: corev1.PodRunning}, wasSet: true, replicas: 4},
+	}
+	for _, test := range tests {
+		var conditions []metav1.Condition
+		conditions = append(conditions, metav1.Condition{Type: ConditionScalingUp, Status: metav1.ConditionTrue, Reason: "Surged", LastTransitionTime: surged})
+		if test.wasSet {
+			conditions = append(conditions, metav1.Condition{Type: ConditionSurgeUnschedulable, Status: metav1.ConditionTrue, Reason: "PodsPending", LastTransitionTime: surged})
+		}
+		objects := []client.Object{
+			&appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default", Generation: 2},
+				Spec:       appsv1.DeploymentSpec{Replicas: ptr.To(int32(4))},
+				Status:     appsv1.DeploymentStatus{AvailableReplicas: test.available},
+			},
+			&corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Name: "web-surge", Namespace: "default", Labels: labels, CreationTimestamp: metav1.NewTime(surged.Add(time.Second))},
+				Status:     test.pod,
+			},
+			&policyv1.PodDisruptionBudget{
+				ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+				Spec:       policyv1.PodDisruptionBudgetSpec{Selector: &metav1.LabelSelector{MatchLabels: labels}},
+			},
+			&v1.EvictionAutoScaler{
+				ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+				Spec: v1.EvictionAutoScalerSpec{TargetName: "web", TargetKind: deploymentKind,
+					SurgeScheduleTimeoutSeconds: test.timeout, RevertUnschedulableSurge: test.revert},
+				Status: v1.EvictionAutoScalerStatus{
+					MinReplicas:      3,
+					TargetGeneration: 2,
+					LastEviction:     lastEviction,
+					DrainingNodes:    []string{"node-1"}, // holds the surge
+					Conditions:       conditions,
+				},
+			},
+		}
+		fakeClient := fake.NewClientBuilder().WithScheme(testScheme).
+			WithStatusSubresource(&v1.EvictionAutoScaler{}).WithObjects(objects...).Build()
+		recorder := record.NewFakeRecorder(10)
+		r := &EvictionAutoScalerReconciler{Client: fakeClient, Scheme: testScheme, Recorder: recorder}
+		if _, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: key}); err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+
+		deployment := &appsv1.Deployment{}
+		if err := fakeClient.Get(ctx, key, deployment); err != nil {
+			t.Fatal(err)
+		}
+		if *deployment.Spec.Replicas != test.replicas {
+			t.Errorf("%s: got %d replicas, want %d", test.name, *deployment.Spec.Replicas, test.replicas)
+		}
+		EvictionAutoScaler := &v1.EvictionAutoScaler{}
+		if err := fakeClient.Get(ctx, key, EvictionAutoScaler); err != nil {
+			t.Fatal(err)
+		}
+		condition := meta.FindStatusCondition(EvictionAutoScaler.Status.Conditions, ConditionSurgeUnschedulable)
+		if test.reason == "" {
+			if condition != nil && condition.Status == metav1.ConditionTrue {
+				t.Errorf("%s: got %s %+v", test.name, ConditionSurgeUnschedulable, condition)
+			}
+		} else if condition == nil || condition.Status != metav1.ConditionTrue || condition.Reason != test.reason {
+			t.Errorf("%s: got %s %+v, want reason %s", test.name, ConditionSurgeUnschedulable, condition, test.reason)
+		}
+		if handled := EvictionAutoScaler.Status.HandledEviction == lastEviction; handled != test.handled {
+			t.Errorf("%s: got eviction handled %v, want %v", test.name, handled, test.handled)
+		}
+		// the event carries the scheduler's reason
+		unschedulableEvent := false
+		for len(recorder.Events) > 0 {
+			if event := <-recorder.Events; strings.Contains(event, "SurgeUnschedulable") && strings.Contains(event, "Insufficient cpu") {
+				unschedulableEvent = true
+			}
+		}
+		if unschedulableEvent != (test.reason != "") {
+			t.Errorf("%s: got SurgeUnschedulable event %v, want %v", test.name, unschedulableEvent, test.reason != "")
+		}
+	}
+}
@@ -4,14 +4,16 @@ import (
 	"fmt"
 
 	v1 "k8s.io/api/apps/v1"
-	"k8s.io/apimachinery/pkg/util/intstr"
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
 type Surger interface {
 	GetReplicas() int32
 	SetReplicas(int32)
-	GetMaxSurge() intstr.IntOrString
 	Obj() client.Object
 	AddAnnotation(string, string)
 	RemoveAnnotation(string)
@@ -21,6 +23,7 @@ type Surger interface {
 const (
 	deploymentKind  = "deployment"
 	statefulSetKind = "statefulset"
+	replicaSetKind  = "replicaset"
 )
 
 type DeploymentWrapper struct {
@@ -45,13 +48,6 @@ func (d *DeploymentWrapper) SetReplicas(replicas int32) {
 	d.obj.Spec.Replicas = &replicas
 }
 
-func (d *DeploymentWrapper) GetMaxSurge() intstr.IntOrString {
-	if d.obj.Spec.Strategy.RollingUpdate != nil && d.obj.Spec.Strategy.RollingUpdate.MaxSurge != nil {
-		return *d.obj.Spec.Strategy.RollingUpdate.MaxSurge
-	}
-	return intstr.FromInt(0)
-}
-
 // AddAnnotation  add new status annotation
 func (d *DeploymentWrapper) AddAnnotation(status, newReplicas string) {
 	if d.obj.Annotations == nil {
@@ -90,15 +86,13 @@ func (s *StatefulSetWrapper) SetReplicas(replicas int32) {
 	s.obj.Spec.Replicas = &replicas
 }
 
-func (s *StatefulSetWrapper) GetMaxSurge() intstr.IntOrString {
-	return intstr.FromString("10%") //there is no max surge for stateful sets.
-}
-
 func GetSurger(kind string) (Surger, error) {
 	if kind == deploymentKind {
 		return &DeploymentWrapper{obj: &v1.Deployment{}}, nil
 	} else if kind == statefulSetKind {
 		return &StatefulSetWrapper{obj: &v1.StatefulSet{}}, nil
+	} else if kind == replicaSetKind {
+		return &ReplicaSetWrapper{obj: &v1.ReplicaSet{}}, nil
 	} else {
 		return nil, fmt.Errorf("unknown target kind %s", kind) //be good to enforce this with admission policy
 	}
@@ -119,3 +113,205 @@ func (s *StatefulSetWrapper) RemoveAnnotation(status string) {
 		delete(s.obj.Annotations, status)
 	}
 }
+
+// ReplicaSetWrapper surges a bare ReplicaSet, one no Deployment owns.
+type ReplicaSetWrapper struct {
+	obj *v1.ReplicaSet
+}
+
+var _ Surger = &ReplicaSetWrapper{}
+
+func (r *ReplicaSetWrapper) Obj() client.Object {
+	return r.obj
+}
+
+func (r *ReplicaSetWrapper) GetReplicas() int32 {
+	if r.obj.Spec.Replicas == nil {
+		return 1 // Default value in Kubernetes if not set
+	}
+	return *r.obj.Spec.Replicas
+}
+
+func (r *ReplicaSetWrapper) SetReplicas(replicas int32) {
+	r.obj = r.obj.DeepCopy() //don't mutate the cache
+	r.obj.Spec.Replicas = &replicas
+}
+
+func (r *ReplicaSetWrapper) AddAnnotation(status, newReplicas string) {
+	if r.obj.Annotations == nil {
+		r.obj.Annotations = make(map[string]string)
+	}
+	r.obj.Annotations[status] = newReplicas
+}
+
+func (r *ReplicaSetWrapper) RemoveAnnotation(status string) {
+	if r.obj.Annotations != nil {
+		delete(r.obj.Annotations, status)
+	}
+}
+
+// ScaleWrapper surges a spec.targetRef target through its scale subresource.
+// obj is only read, for the generation, all writes go through scale.
+type ScaleWrapper struct {
+	obj      *unstructured.Unstructured
+	scale    *autoscalingv1.Scale
+	resource schema.GroupResource
+}
+
+var _ Surger = &ScaleWrapper{}
+
+func (s *ScaleWrapper) Obj() client.Object {
+	return s.obj
+}
+
+func (s *ScaleWrapper) GetReplicas() int32 {
+	return s.scale.Spec.Replicas
+}
+
+func (s *ScaleWrapper) SetReplicas(replicas int32) {
+	s.scale = s.scale.DeepCopy()
+	s.scale.Spec.Replicas = replicas
+}
+
+// AddAnnotation is a noop. We can only write scale and the annotation is only read for deployments.
+func (s *ScaleWrapper) AddAnnotation(string, string) {}
+
+// RemoveAnnotation is a noop for the same reason.
+func (s *ScaleWrapper) RemoveAnnotation(string) {}
+
+// Autoscaler is an HPA or KEDA ScaledObject scaling a target. It would revert a surge of the target's
+// replicas so we raise its minimum replicas instead.
+type Autoscaler interface {
+	Obj() client.Object
+	Kind() string
+	ScaleTargetRef() (apiVersion, kind, name string)
+	// GetMinReplicas is the minimum as written, nil if unset. MinReplicas applies the default.
+	GetMinReplicas() *int32
+	SetMinReplicas(*int32)
+	MinReplicas() int32
+	MaxReplicas() int32
+}
+
+const (
+	hpaKind          = "HorizontalPodAutoscaler"
+	scaledObjectKind = "ScaledObject"
+)
+
+var scaledObjectGVK = schema.GroupVersionKind{Group: "keda.sh", Version: "v1alpha1", Kind: scaledObjectKind}
+
+// newAutoscaler returns an empty Autoscaler of kind to read into.
+func newAutoscaler(kind string) (Autoscaler, error) {
+	switch kind {
+	case hpaKind:
+		return &HPAWrapper{obj: &autoscalingv2.HorizontalPodAutoscaler{}}, nil
+	case scaledObjectKind:
+		obj := &unstructured.Unstructured{}
+		obj.SetGroupVersionKind(scaledObjectGVK)
+		return &ScaledObjectWrapper{obj: obj}, nil
+	default:
+		return nil, fmt.Errorf("unknown autoscaler kind %s", kind)
+	}
+}
+
+type HPAWrapper struct {
+	obj *autoscalingv2.HorizontalPodAutoscaler
+}
+
+var _ Autoscaler = &HPAWrapper{}
+
+func (h *HPAWrapper) Obj() client.Object {
+	return h.obj
+}
+
+func (h *HPAWrapper) Kind() string {
+	return hpaKind
+}
+
+func (h *HPAWrapper) ScaleTargetRef() (string, string, string) {
+	ref := h.obj.Spec.ScaleTargetRef
+	return ref.APIVersion, ref.Kind, ref.Name
+}
+
+func (h *HPAWrapper) GetMinReplicas() *int32 {
+	return h.obj.Spec.MinReplicas
+}
+
+func (h *HPAWrapper) SetMinReplicas(minReplicas *int32) {
+	h.obj = h.obj.DeepCopy() //don't mutate the cache
+	h.obj.Spec.MinReplicas = minReplicas
+}
+
+func (h *HPAWrapper) MinReplicas() int32 {
+	if h.obj.Spec.MinReplicas == nil {
+		return 1 // Default value in Kubernetes if not set
+	}
+	return *h.obj.Spec.MinReplicas
+}
+
+func (h *HPAWrapper) MaxReplicas() int32 {
+	return h.obj.Spec.MaxReplicas
+}
+
+// ScaledObjectWrapper reads and writes a KEDA ScaledObject as unstructured so we don't depend on KEDA's types.
+type ScaledObjectWrapper struct {
+	obj *unstructured.Unstructured
+}
+
+var _ Autoscaler = &ScaledObjectWrapper{}
+
+func (s *ScaledObjectWrapper) Obj() client.Object {
+	return s.obj
+}
+
+func (s *ScaledObjectWrapper) Kind() string {
+	return scaledObjectKind
+}
+
+// ScaleTargetRef applies KEDA's defaults of apps/v1 Deployment.
+func (s *ScaledObjectWrapper) ScaleTargetRef() (string, string, string) {
+	apiVersion, _, _ := unstructured.NestedString(s.obj.Object, "spec", "scaleTargetRef", "apiVersion")
+	kind, _, _ := unstructured.NestedString(s.obj.Object, "spec", "scaleTargetRef", "kind")
+	name, _, _ := unstructured.NestedString(s.obj.Object, "spec", "scaleTargetRef", "name")
+	if apiVersion == "" {
+		apiVersion = "apps/v1"
+	}
+	if kind == "" {
+		kind = "Deployment"
+	}
+	return apiVersion, kind, name
+}
+
+func (s *ScaledObjectWrapper) GetMinReplicas() *int32 {
+	minReplicaCount, found, err := unstructured.NestedInt64(s.obj.Object, "spec", "minReplicaCount")
+	if !found || err != nil {
+		return nil
+	}
+	minReplicas := int32(minReplicaCount)
+	return &minReplicas
+}
+
+func (s *ScaledObjectWrapper) SetMinReplicas(minReplicas *int32) {
+	s.obj = s.obj.DeepCopy() //don't mutate the cache
+	if minReplicas == nil {
+		unstructured.RemoveNestedField(s.obj.Object, "spec", "minReplicaCount")
+		return
+	}
+	_ = unstructured.SetNestedField(s.obj.Object, int64(*minReplicas), "spec", "minReplicaCount")
+}
+
+// MinReplicas applies KEDA's default of 0.
+func (s *ScaledObjectWrapper) MinReplicas() int32 {
+	if minReplicas := s.GetMinReplicas(); minReplicas != nil {
+		return *minReplicas
+	}
+	return 0
+}
+
+// MaxReplicas applies KEDA's default of 100.
+func (s *ScaledObjectWrapper) MaxReplicas() int32 {
+	maxReplicaCount, found, err := unstructured.NestedInt64(s.obj.Object, "spec", "maxReplicaCount")
+	if !found || err != nil {
+		return 100
+	}
+	return int32(maxReplicaCount)
+}
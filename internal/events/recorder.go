@@ -0,0 +1,162 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/azure/eviction-autoscaler/internal/metrics"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// Event reasons are stable so users can alert on them.
+const (
+	// ReasonAnticipatedEviction is emitted on an EvictionAutoScaler when a cordon records an eviction for one of its pods.
+	ReasonAnticipatedEviction = "AnticipatedEviction"
+	// ReasonDisruptionTarget is emitted on a pod when we set its DisruptionTarget condition.
+	ReasonDisruptionTarget = "DisruptionTargetSet"
+	// ReasonDrainBlockedByAnnotation is emitted on a pod of a draining node whose annotations keep the drain from evicting it.
+	ReasonDrainBlockedByAnnotation = "DrainBlockedByAnnotation"
+	// ReasonSurgeScaledUp is emitted on an EvictionAutoScaler when its target is surged.
+	ReasonSurgeScaledUp = "SurgeScaledUp"
+	// ReasonSurgeScaledDown is emitted on an EvictionAutoScaler when its target goes back to min replicas.
+	ReasonSurgeScaledDown = "SurgeScaledDown"
+	// ReasonSurgeSteppedDown is emitted on an EvictionAutoScaler for each step of a stepped scale down short of its baseline.
+	ReasonSurgeSteppedDown = "SurgeSteppedDown"
+	// ReasonScaleDownPaused is emitted on an EvictionAutoScaler when its pdb holds back the next step of a stepped scale down.
+	ReasonScaleDownPaused = "ScaleDownPaused"
+	// ReasonBaselineAdopted is emitted on an EvictionAutoScaler when someone else scaled its target and those replicas become the baseline.
+	ReasonBaselineAdopted = "BaselineAdopted"
+	// ReasonSurgeLimited is emitted on an EvictionAutoScaler when maxReplicas stops a surge.
+	ReasonSurgeLimited = "SurgeLimited"
+	// ReasonSurgeOrdinalUnsafe is emitted on an EvictionAutoScaler when scaling its StatefulSet down would remove a pod from before the surge.
+	ReasonSurgeOrdinalUnsafe = "SurgeOrdinalUnsafe"
+	// ReasonSurgeIneffective is emitted on an EvictionAutoScaler when a surge of its StatefulSet couldn't unblock the drain so it isn't made.
+	ReasonSurgeIneffective = "SurgeIneffective"
+	// ReasonSurgeUnschedulable is emitted on an EvictionAutoScaler when pods its surge added stay unschedulable.
+	ReasonSurgeUnschedulable = "SurgeUnschedulable"
+	// ReasonSurgeUnavailable is emitted on an EvictionAutoScaler when replicas of a surge that was ready stop being available.
+	ReasonSurgeUnavailable = "SurgeUnavailable"
+	// ReasonDrainAborted is emitted on an EvictionAutoScaler when a node it was draining for is uncordoned or disabled with its pods still there.
+	ReasonDrainAborted = "DrainAborted"
+	// ReasonStaleCordon is emitted on a node cordoned so long without its drain moving that we stop surging for it.
+	ReasonStaleCordon = "StaleCordon"
+	// ReasonDrainRearmed is emitted on a node whose drain is assisted again after a stale cordon because it was annotated to re-arm.
+	ReasonDrainRearmed = "DrainRearmed"
+	// ReasonDrainQueued is emitted on a cordoned node waiting for one of the --max-concurrent-node-drains drains to finish.
+	ReasonDrainQueued = "DrainQueued"
+	// ReasonPodStuckTerminating is emitted on a pod of a draining node, and on the node, when it is still terminating well past its grace period.
+	ReasonPodStuckTerminating = "PodStuckTerminating"
+	// ReasonDrainDeadlineExceeded is emitted on a node and the EvictionAutoScaler it drains for once the drain is past spec.maxDrainDurationSeconds.
+	ReasonDrainDeadlineExceeded = "DrainDeadlineExceeded"
+	// ReasonQuotaExceeded is emitted on an EvictionAutoScaler when a ResourceQuota has no room for its surge.
+	ReasonQuotaExceeded = "QuotaExceeded"
+	// ReasonSurgeBudgetExhausted is emitted on an EvictionAutoScaler when --max-total-surge-replicas has no room for its surge.
+	ReasonSurgeBudgetExhausted = "SurgeBudgetExhausted"
+	// ReasonNoPodsSelected is emitted on an EvictionAutoScaler when its pdb or spec.podSelector stops selecting any pods.
+	ReasonNoPodsSelected = "NoPodsSelected"
+	// ReasonTargetPaused is emitted on an EvictionAutoScaler when it doesn't surge its target because the Deployment is paused.
+	ReasonTargetPaused = "TargetPaused"
+	// ReasonConflictingSelectors is emitted on each EvictionAutoScaler whose pdb selects the same pod as another's.
+	ReasonConflictingSelectors = "ConflictingSelectors"
+	// ReasonEvictionStale is emitted on an EvictionAutoScaler when its last eviction outlived the eviction ttl with the pod still running.
+	ReasonEvictionStale = "EvictionStale"
+	// ReasonDryRun is emitted instead of any of the above when --dry-run skipped the action.
+	ReasonDryRun = "DryRunDecision"
+)
+
+// DefaultInterval is how often the same reason is emitted for the same object.
+const DefaultInterval = 5 * time.Minute
+
+// maxTracked bounds how many object/reason pairs we remember before pruning expired ones.
+const maxTracked = 10000
+
+type eventKey struct {
+	uid    types.UID
+	reason string
+}
+
+// RateLimitedRecorder drops events for an object and reason emitted again within interval
+// so a long drain doesn't create hundreds of near duplicate events.
+type RateLimitedRecorder struct {
+	record.EventRecorder
+	interval time.Duration
+	now      func() time.Time
+
+	mu   sync.Mutex
+	last map[eventKey]time.Time
+}
+
+// NewRateLimitedRecorder wraps recorder so each object/reason pair is emitted at most once per interval.
+func NewRateLimitedRecorder(recorder record.EventRecorder, interval time.Duration) *RateLimitedRecorder {
+	return &RateLimitedRecorder{
+		EventRecorder: recorder,
+		interval:      interval,
+		now:           time.Now,
+		last:          map[eventKey]time.Time{},
+	}
+}
+
+func (r *RateLimitedRecorder) Event(object runtime.Object, eventtype, reason, message string) {
+	if r.allow(object, reason) {
+		r.EventRecorder.Event(object, eventtype, reason, message)
+	}
+}
+
+func (r *RateLimitedRecorder) Eventf(object runtime.Object, eventtype, reason, messageFmt string, args ...interface{}) {
+	if r.allow(object, reason) {
+		r.EventRecorder.Eventf(object, eventtype, reason, messageFmt, args...)
+	}
+}
+
+func (r *RateLimitedRecorder) AnnotatedEventf(object runtime.Object, annotations map[string]string, eventtype, reason, messageFmt string, args ...interface{}) {
+	if r.allow(object, reason) {
+		r.EventRecorder.AnnotatedEventf(object, annotations, eventtype, reason, messageFmt, args...)
+	}
+}
+
+func (r *RateLimitedRecorder) allow(object runtime.Object, reason string) bool {
+	accessor, err := meta.Accessor(object)
+	if err != nil {
+		return true // can't tell objects apart so let the recorder deal with it
+	}
+	key := eventKey{uid: accessor.GetUID(), reason: reason}
+	now := r.now()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if last, found := r.last[key]; found && now.Sub(last) < r.interval {
+		return false
+	}
+	if len(r.last) >= maxTracked {
+		for k, t := range r.last {
+			if now.Sub(t) >= r.interval {
+				delete(r.last, k)
+			}
+		}
+	}
+	r.last[key] = now
+	return true
+}
+
+// DryRun logs, counts and emits an event for an action that --dry-run skipped.
+func DryRun(ctx context.Context, recorder record.EventRecorder, object runtime.Object, action, messageFmt string, args ...interface{}) {
+	message := fmt.Sprintf(messageFmt, args...)
+	log.FromContext(ctx).Info("[dry-run] would "+message, "action", action)
+	metrics.DryRunDecisionCounter.WithLabelValues(action).Inc()
+	Eventf(recorder, object, corev1.EventTypeNormal, ReasonDryRun, "Would %s", message)
+}
+
+// Eventf emits through recorder if there is one. Reconcilers built in tests often have none.
+func Eventf(recorder record.EventRecorder, object runtime.Object, eventtype, reason, messageFmt string, args ...interface{}) {
+	if recorder == nil {
+		return
+	}
+	recorder.Eventf(object, eventtype, reason, messageFmt, args...)
+}
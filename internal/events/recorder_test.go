@@ -0,0 +1,52 @@
+package events
+
+import (
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+func TestRateLimitedRecorder(t *testing.T) {
+	fake := record.NewFakeRecorder(10)
+	recorder := NewRateLimitedRecorder(fake, time.Minute)
+	now := time.Now()
+	recorder.now = func() time.Time { return now }
+
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "a", UID: "a-uid"}}
+	other := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "b", UID: "b-uid"}}
+
+	recorder.Eventf(pod, corev1.EventTypeNormal, ReasonDisruptionTarget, "first")
+	recorder.Eventf(pod, corev1.EventTypeNormal, ReasonDisruptionTarget, "duplicate")
+	recorder.Eventf(pod, corev1.EventTypeNormal, ReasonAnticipatedEviction, "other reason")
+	recorder.Eventf(other, corev1.EventTypeNormal, ReasonDisruptionTarget, "other object")
+	now = now.Add(time.Minute)
+	recorder.Eventf(pod, corev1.EventTypeNormal, ReasonDisruptionTarget, "after interval")
+
+	close(fake.Events)
+	var got []string
+	for e := range fake.Events {
+		got = append(got, e)
+	}
+	want := []string{
+		"Normal DisruptionTargetSet first",
+		"Normal AnticipatedEviction other reason",
+		"Normal DisruptionTargetSet other object",
+		"Normal DisruptionTargetSet after interval",
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got events %v want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("event %d: got %q want %q", i, got[i], want[i])
+		}
+	}
+}
+
+func TestEventfNilRecorder(t *testing.T) {
+	// should not panic
+	Eventf(nil, &corev1.Pod{}, corev1.EventTypeNormal, ReasonDisruptionTarget, "ignored")
+}
@@ -0,0 +1,46 @@
+package evictionutil
+
+import (
+	"cmp"
+	"context"
+	"slices"
+
+	pdbautoscaler "github.com/azure/eviction-autoscaler/api/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// ConditionConflictingSelectors is true on EvictionAutoScalers whose pdb or podSelector selects a pod another EvictionAutoScaler selects too.
+const ConditionConflictingSelectors = "ConflictingSelectors"
+
+// Applicable sorts EvictionAutoScalers matching the same pod by precedence and returns the first. Ones going through
+// a pdb come before ones with spec.podSelector, since the pdb is what actually blocks the pod's eviction, then the
+// oldest first, by name when created in the same second. The node controller and eviction webhook both record
+// evictions on it so they agree.
+func Applicable(matches []*pdbautoscaler.EvictionAutoScaler) *pdbautoscaler.EvictionAutoScaler {
+	if len(matches) == 0 {
+		return nil
+	}
+	slices.SortFunc(matches, func(a, b *pdbautoscaler.EvictionAutoScaler) int {
+		if (a.Spec.PodSelector == nil) != (b.Spec.PodSelector == nil) {
+			if a.Spec.PodSelector == nil {
+				return -1
+			}
+			return 1
+		}
+		if c := a.CreationTimestamp.Compare(b.CreationTimestamp.Time); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.Name, b.Name)
+	})
+	return matches[0]
+}
+
+// SetCondition writes condition to the EvictionAutoScaler's status conditions if it changes them.
+func SetCondition(ctx context.Context, c client.Client, key types.NamespacedName, condition metav1.Condition) (*pdbautoscaler.EvictionAutoScaler, error) {
+	return updateStatus(ctx, c, key, func(status *pdbautoscaler.EvictionAutoScalerStatus) bool {
+		return meta.SetStatusCondition(&status.Conditions, condition)
+	})
+}
@@ -0,0 +1,38 @@
+package evictionutil
+
+import (
+	"testing"
+	"time"
+
+	pdbautoscaler "github.com/azure/eviction-autoscaler/api/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestApplicable(t *testing.T) {
+	created := metav1.NewTime(time.Now().Truncate(time.Second))
+	scaler := func(name string, age time.Duration, podSelector bool) *pdbautoscaler.EvictionAutoScaler {
+		EvictionAutoScaler := &pdbautoscaler.EvictionAutoScaler{ObjectMeta: metav1.ObjectMeta{Name: name, CreationTimestamp: metav1.NewTime(created.Add(-age))}}
+		if podSelector {
+			EvictionAutoScaler.Spec.PodSelector = &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}}
+		}
+		return EvictionAutoScaler
+	}
+	tests := []struct {
+		name    string
+		matches []*pdbautoscaler.EvictionAutoScaler
+		want    string
+	}{
+		{name: "oldest", matches: []*pdbautoscaler.EvictionAutoScaler{scaler("new", 0, false), scaler("old", time.Hour, false)}, want: "old"},
+		{name: "same second", matches: []*pdbautoscaler.EvictionAutoScaler{scaler("b", 0, false), scaler("a", 0, false)}, want: "a"},
+		{name: "pdb before podSelector", matches: []*pdbautoscaler.EvictionAutoScaler{scaler("selector", time.Hour, true), scaler("pdb", 0, false)}, want: "pdb"},
+		{name: "oldest podSelector", matches: []*pdbautoscaler.EvictionAutoScaler{scaler("new", 0, true), scaler("old", time.Hour, true)}, want: "old"},
+	}
+	for _, test := range tests {
+		if got := Applicable(test.matches); got == nil || got.Name != test.want {
+			t.Errorf("%s: got %v want %s", test.name, got, test.want)
+		}
+	}
+	if got := Applicable(nil); got != nil {
+		t.Errorf("got %s for no matches", got.Name)
+	}
+}
@@ -0,0 +1,20 @@
+package evictionutil
+
+import (
+	pdbautoscaler "github.com/azure/eviction-autoscaler/api/v1"
+	policyv1 "k8s.io/api/policy/v1"
+)
+
+// PodSelectorPDB stands in for the pdb of an EvictionAutoScaler with spec.podSelector, nil for ones with a real pdb.
+// It selects the same pods and never allows a disruption, so every eviction anticipated for them counts as blocked.
+// It takes the EvictionAutoScaler's UID and generation so its selector is cached like a pdb's.
+func PodSelectorPDB(EvictionAutoScaler *pdbautoscaler.EvictionAutoScaler) *policyv1.PodDisruptionBudget {
+	if EvictionAutoScaler.Spec.PodSelector == nil {
+		return nil
+	}
+	pdb := &policyv1.PodDisruptionBudget{}
+	pdb.Name, pdb.Namespace = EvictionAutoScaler.Name, EvictionAutoScaler.Namespace
+	pdb.UID, pdb.Generation = EvictionAutoScaler.UID, EvictionAutoScaler.Generation
+	pdb.Spec.Selector = EvictionAutoScaler.Spec.PodSelector
+	return pdb
+}
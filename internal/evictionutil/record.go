@@ -0,0 +1,177 @@
+package evictionutil
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	pdbautoscaler "github.com/azure/eviction-autoscaler/api/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// ConditionExceededDrainDeadline is true on EvictionAutoScalers with a node draining for longer than spec.maxDrainDurationSeconds.
+const ConditionExceededDrainDeadline = "ExceededDrainDeadline"
+
+// RecordEviction appends record to the EvictionAutoScaler's status.recentEvictions, dropping the oldest past
+// MaxRecentEvictions, and mirrors it to status.lastEviction.
+// It re-gets the EvictionAutoScaler and retries on conflict so concurrent status writers
+// (the EvictionAutoScaler controller, webhook, other nodes) are never clobbered.
+// Records from the node controller also add their node to status.drainingNodes and status.nodeDrains.
+func RecordEviction(ctx context.Context, c client.Client, key types.NamespacedName, record pdbautoscaler.EvictionRecord) (*pdbautoscaler.EvictionAutoScaler, error) {
+	return updateStatus(ctx, c, key, func(status *pdbautoscaler.EvictionAutoScalerStatus) bool {
+		recordEviction(status, record)
+		return true
+	})
+}
+
+// RecordEvictionUnlessRecent is RecordEviction unless the same pod was already recorded from the same node and source
+// less than window before record, so a node reconciled over and over while it drains doesn't rewrite status every time.
+// Returns false if it wasn't recorded.
+func RecordEvictionUnlessRecent(ctx context.Context, c client.Client, key types.NamespacedName, record pdbautoscaler.EvictionRecord,
+	window time.Duration) (*pdbautoscaler.EvictionAutoScaler, bool, error) {
+	recorded := false
+	EvictionAutoScaler, err := updateStatus(ctx, c, key, func(status *pdbautoscaler.EvictionAutoScalerStatus) bool {
+		if recorded = !recordedWithin(status, record, window); recorded {
+			recordEviction(status, record)
+		}
+		return recorded
+	})
+	return EvictionAutoScaler, recorded, err
+}
+
+// recordEviction is RecordEviction on status.
+func recordEviction(status *pdbautoscaler.EvictionAutoScalerStatus, record pdbautoscaler.EvictionRecord) {
+	status.LastEviction = record.Eviction()
+	status.RecentEvictions = append(status.RecentEvictions, record)
+	if extra := len(status.RecentEvictions) - pdbautoscaler.MaxRecentEvictions; extra > 0 {
+		status.RecentEvictions = slices.Delete(status.RecentEvictions, 0, extra)
+	}
+	if record.Source == pdbautoscaler.EvictionSourceNodeCordon && record.Node != "" && !slices.Contains(status.DrainingNodes, record.Node) {
+		status.DrainingNodes = append(status.DrainingNodes, record.Node)
+	}
+	// nodes draining from before nodeDrains existed start from their next record
+	if record.Source == pdbautoscaler.EvictionSourceNodeCordon && record.Node != "" && NodeDrainOf(status, record.Node) == nil {
+		status.NodeDrains = append(status.NodeDrains, pdbautoscaler.NodeDrain{Node: record.Node, StartTime: record.EvictionTime})
+	}
+}
+
+// NodeDrainOf returns the status.nodeDrains entry of nodeName, nil if it has none.
+func NodeDrainOf(status *pdbautoscaler.EvictionAutoScalerStatus, nodeName string) *pdbautoscaler.NodeDrain {
+	if i := slices.IndexFunc(status.NodeDrains, func(drain pdbautoscaler.NodeDrain) bool { return drain.Node == nodeName }); i >= 0 {
+		return &status.NodeDrains[i]
+	}
+	return nil
+}
+
+// ExceedDrainDeadline marks the drain of nodeName as past spec.maxDrainDurationSeconds and sets the
+// ExceededDrainDeadline condition with message. Returns false if it already was or the node isn't draining.
+func ExceedDrainDeadline(ctx context.Context, c client.Client, key types.NamespacedName, nodeName, message string) (*pdbautoscaler.EvictionAutoScaler, bool, error) {
+	exceeded := false
+	EvictionAutoScaler, err := updateStatus(ctx, c, key, func(status *pdbautoscaler.EvictionAutoScalerStatus) bool {
+		drain := NodeDrainOf(status, nodeName)
+		if exceeded = drain != nil && !drain.DeadlineExceeded; !exceeded {
+			return false
+		}
+		drain.DeadlineExceeded = true
+		meta.SetStatusCondition(&status.Conditions, metav1.Condition{
+			Type:    ConditionExceededDrainDeadline,
+			Status:  metav1.ConditionTrue,
+			Reason:  "DrainDeadlineExceeded",
+			Message: message,
+		})
+		return true
+	})
+	return EvictionAutoScaler, exceeded, err
+}
+
+// recordedWithin is true if the newest record of record's pod in status is from the same node and source less than
+// window before it, so recording again would change nothing but the time. Expired ones and nodes since released
+// are recorded again.
+func recordedWithin(status *pdbautoscaler.EvictionAutoScalerStatus, record pdbautoscaler.EvictionRecord, window time.Duration) bool {
+	for i := len(status.RecentEvictions) - 1; i >= 0; i-- {
+		previous := status.RecentEvictions[i]
+		if previous.PodName != record.PodName {
+			continue
+		}
+		if previous.Node != record.Node || previous.Source != record.Source || previous.Expired ||
+			record.EvictionTime.Sub(previous.EvictionTime.Time) >= window {
+			return false
+		}
+		return record.Source != pdbautoscaler.EvictionSourceNodeCordon || slices.Contains(status.DrainingNodes, record.Node)
+	}
+	return false
+}
+
+// ReleaseNode removes nodeName from the EvictionAutoScaler's status.drainingNodes
+// so it can scale back down once no other draining node is left. Releasing the last one sets status.drainedTime.
+func ReleaseNode(ctx context.Context, c client.Client, key types.NamespacedName, nodeName string) (*pdbautoscaler.EvictionAutoScaler, error) {
+	return updateStatus(ctx, c, key, func(status *pdbautoscaler.EvictionAutoScalerStatus) bool {
+		return releaseNode(status, nodeName)
+	})
+}
+
+// AbortDrain is ReleaseNode for a node uncordoned while it still had pods for the EvictionAutoScaler. The evictions
+// recorded for its pods won't happen so they are marked expired, and when the last eviction is one of them and no
+// other node is draining so is status.lastEviction, letting the surge go after the stabilization window rather than
+// the cooldown. Another draining node, or an eviction the webhook saw, keeps holding the surge.
+func AbortDrain(ctx context.Context, c client.Client, key types.NamespacedName, nodeName string) (*pdbautoscaler.EvictionAutoScaler, error) {
+	return updateStatus(ctx, c, key, func(status *pdbautoscaler.EvictionAutoScalerStatus) bool {
+		if !releaseNode(status, nodeName) {
+			return false
+		}
+		last := false
+		for i := range status.RecentEvictions {
+			record := &status.RecentEvictions[i]
+			if record.Source == pdbautoscaler.EvictionSourceNodeCordon && record.Node == nodeName {
+				record.Expired = true
+				last = last || record.Matches(status.LastEviction)
+			}
+		}
+		if last && len(status.DrainingNodes) == 0 && status.LastEviction != status.HandledEviction {
+			status.ExpiredEviction = status.LastEviction
+		}
+		return true
+	})
+}
+
+// releaseNode is ReleaseNode on status, false if nodeName wasn't draining.
+func releaseNode(status *pdbautoscaler.EvictionAutoScalerStatus, nodeName string) bool {
+	if !slices.Contains(status.DrainingNodes, nodeName) {
+		return false
+	}
+	status.DrainingNodes = slices.DeleteFunc(status.DrainingNodes, func(n string) bool { return n == nodeName })
+	status.NodeDrains = slices.DeleteFunc(status.NodeDrains, func(drain pdbautoscaler.NodeDrain) bool { return drain.Node == nodeName })
+	if len(status.DrainingNodes) == 0 {
+		status.DrainedTime = metav1.Now()
+	}
+	if !slices.ContainsFunc(status.NodeDrains, func(drain pdbautoscaler.NodeDrain) bool { return drain.DeadlineExceeded }) &&
+		meta.IsStatusConditionTrue(status.Conditions, ConditionExceededDrainDeadline) {
+		meta.SetStatusCondition(&status.Conditions, metav1.Condition{
+			Type:    ConditionExceededDrainDeadline,
+			Status:  metav1.ConditionFalse,
+			Reason:  "DrainsFinished",
+			Message: "no node is draining past spec.maxDrainDurationSeconds",
+		})
+	}
+	return true
+}
+
+// updateStatus applies mutate to a fresh copy of the EvictionAutoScaler and writes status if mutate returns true.
+func updateStatus(ctx context.Context, c client.Client, key types.NamespacedName, mutate func(*pdbautoscaler.EvictionAutoScalerStatus) bool) (*pdbautoscaler.EvictionAutoScaler, error) {
+	EvictionAutoScaler := &pdbautoscaler.EvictionAutoScaler{}
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		if err := c.Get(ctx, key, EvictionAutoScaler); err != nil {
+			return err
+		}
+		EvictionAutoScaler = EvictionAutoScaler.DeepCopy() //don't mutate the cache
+		if !mutate(&EvictionAutoScaler.Status) {
+			return nil
+		}
+		return c.Status().Update(ctx, EvictionAutoScaler)
+	})
+	return EvictionAutoScaler, err
+}
@@ -0,0 +1,160 @@
+package evictionutil
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	pdbautoscaler "github.com/azure/eviction-autoscaler/api/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestRecordEvictionKeepsRecentEvictions(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = pdbautoscaler.AddToScheme(scheme)
+	EvictionAutoScaler := &pdbautoscaler.EvictionAutoScaler{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"}}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(EvictionAutoScaler).
+		WithStatusSubresource(&pdbautoscaler.EvictionAutoScaler{}).Build()
+	key := types.NamespacedName{Namespace: "default", Name: "web"}
+
+	start := time.Now().Truncate(time.Second)
+	total := pdbautoscaler.MaxRecentEvictions + 5
+	for i := range total {
+		record := pdbautoscaler.EvictionRecord{
+			PodName:      fmt.Sprintf("web-%d", i),
+			Node:         "node-1",
+			EvictionTime: metav1.NewTime(start.Add(time.Duration(i) * time.Second)),
+			Source:       pdbautoscaler.EvictionSourceEvictionAPI,
+		}
+		if i%2 == 0 {
+			record.Node = fmt.Sprintf("node-%d", i)
+			record.Source = pdbautoscaler.EvictionSourceNodeCordon
+		}
+		var err error
+		EvictionAutoScaler, err = RecordEviction(context.Background(), c, key, record)
+		if err != nil {
+			t.Fatalf("recording eviction %d: %v", i, err)
+		}
+	}
+
+	status := EvictionAutoScaler.Status
+	if len(status.RecentEvictions) != pdbautoscaler.MaxRecentEvictions {
+		t.Fatalf("got %d recent evictions, want %d", len(status.RecentEvictions), pdbautoscaler.MaxRecentEvictions)
+	}
+	if oldest := status.RecentEvictions[0].PodName; oldest != "web-5" {
+		t.Errorf("got oldest recent eviction %s, want web-5", oldest)
+	}
+	newest := status.RecentEvictions[len(status.RecentEvictions)-1]
+	if newest.PodName != fmt.Sprintf("web-%d", total-1) || !newest.Matches(status.LastEviction) ||
+		status.LastEviction.Source != newest.Source || status.LastEviction.Node != newest.Node {
+		t.Errorf("got lastEviction %+v, want it to mirror the newest recent eviction %+v", status.LastEviction, newest)
+	}
+	// only node controller records drain their node, webhook records just remember where the pod was
+	if len(status.DrainingNodes) != (total+1)/2 {
+		t.Errorf("got draining nodes %v, want the %d nodes the node controller recorded", status.DrainingNodes, (total+1)/2)
+	}
+}
+
+func TestRecordEvictionUnlessRecent(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = pdbautoscaler.AddToScheme(scheme)
+	start := time.Now().Truncate(time.Second)
+	evicted := pdbautoscaler.EvictionRecord{PodName: "web-1", Node: "node-1", EvictionTime: metav1.NewTime(start), Source: pdbautoscaler.EvictionSourceNodeCordon}
+	later := func(record pdbautoscaler.EvictionRecord, after time.Duration) pdbautoscaler.EvictionRecord {
+		record.EvictionTime = metav1.NewTime(record.EvictionTime.Add(after))
+		return record
+	}
+	tests := []struct {
+		name     string
+		status   pdbautoscaler.EvictionAutoScalerStatus
+		record   pdbautoscaler.EvictionRecord
+		recorded bool
+	}{
+		{name: "first", record: evicted, recorded: true},
+		{name: "within window", status: pdbautoscaler.EvictionAutoScalerStatus{RecentEvictions: []pdbautoscaler.EvictionRecord{evicted}, DrainingNodes: []string{"node-1"}},
+			record: later(evicted, 30*time.Second)},
+		{name: "past window", status: pdbautoscaler.EvictionAutoScalerStatus{RecentEvictions: []pdbautoscaler.EvictionRecord{evicted}, DrainingNodes: []string{"node-1"}},
+			record: later(evicted, time.Minute), recorded: true},
+		{name: "other pod since", status: pdbautoscaler.EvictionAutoScalerStatus{DrainingNodes: []string{"node-1"},
+			RecentEvictions: []pdbautoscaler.EvictionRecord{evicted, {PodName: "web-2", Node: "node-1", EvictionTime: metav1.NewTime(start), Source: pdbautoscaler.EvictionSourceNodeCordon}}},
+			record: later(evicted, 30*time.Second)},
+		{name: "other node", status: pdbautoscaler.EvictionAutoScalerStatus{RecentEvictions: []pdbautoscaler.EvictionRecord{evicted}, DrainingNodes: []string{"node-1", "node-2"}},
+			record: pdbautoscaler.EvictionRecord{PodName: "web-1", Node: "node-2", EvictionTime: metav1.NewTime(start), Source: pdbautoscaler.EvictionSourceNodeCordon}, recorded: true},
+		{name: "node released", status: pdbautoscaler.EvictionAutoScalerStatus{RecentEvictions: []pdbautoscaler.EvictionRecord{evicted}},
+			record: later(evicted, 30*time.Second), recorded: true},
+		{name: "expired", status: pdbautoscaler.EvictionAutoScalerStatus{RecentEvictions: []pdbautoscaler.EvictionRecord{{PodName: "web-1", Node: "node-1",
+			EvictionTime: metav1.NewTime(start), Source: pdbautoscaler.EvictionSourceNodeCordon, Expired: true}}, DrainingNodes: []string{"node-1"}},
+			record: later(evicted, 30*time.Second), recorded: true},
+	}
+	for _, test := range tests {
+		EvictionAutoScaler := &pdbautoscaler.EvictionAutoScaler{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"}, Status: test.status}
+		c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(EvictionAutoScaler).
+			WithStatusSubresource(&pdbautoscaler.EvictionAutoScaler{}).Build()
+		key := types.NamespacedName{Namespace: "default", Name: "web"}
+		before := &pdbautoscaler.EvictionAutoScaler{}
+		if err := c.Get(context.Background(), key, before); err != nil {
+			t.Fatal(err)
+		}
+
+		after, recorded, err := RecordEvictionUnlessRecent(context.Background(), c, key, test.record, time.Minute)
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		if recorded != test.recorded {
+			t.Errorf("%s: got recorded %v, want %v", test.name, recorded, test.recorded)
+		}
+		if written := after.ResourceVersion != before.ResourceVersion; written != test.recorded {
+			t.Errorf("%s: got status written %v, want %v", test.name, written, test.recorded)
+		}
+	}
+}
+
+func TestAbortDrain(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = pdbautoscaler.AddToScheme(scheme)
+	now := metav1.NewTime(time.Now().Truncate(time.Second))
+	onA := pdbautoscaler.EvictionRecord{PodName: "web-1", Node: "node-a", EvictionTime: now, Source: pdbautoscaler.EvictionSourceNodeCordon}
+	onB := pdbautoscaler.EvictionRecord{PodName: "web-2", Node: "node-b", EvictionTime: now, Source: pdbautoscaler.EvictionSourceNodeCordon}
+	webhook := pdbautoscaler.EvictionRecord{PodName: "web-3", Node: "node-a", EvictionTime: now, Source: pdbautoscaler.EvictionSourceEvictionAPI}
+	tests := []struct {
+		name     string
+		recorded []pdbautoscaler.EvictionRecord // in order, the last is status.lastEviction
+		draining []string
+		expired  []bool // of each recorded after the abort of node-a
+		last     bool   // status.lastEviction expired
+	}{
+		{name: "only node", recorded: []pdbautoscaler.EvictionRecord{onA}, draining: []string{"node-a"}, expired: []bool{true}, last: true},
+		{name: "other node draining", recorded: []pdbautoscaler.EvictionRecord{onB, onA}, draining: []string{"node-a", "node-b"}, expired: []bool{false, true}},
+		{name: "other node's eviction last", recorded: []pdbautoscaler.EvictionRecord{onA, onB}, draining: []string{"node-a"}, expired: []bool{true, false}},
+		{name: "webhook eviction last", recorded: []pdbautoscaler.EvictionRecord{onA, webhook}, draining: []string{"node-a"}, expired: []bool{true, false}},
+		{name: "not draining", recorded: []pdbautoscaler.EvictionRecord{onA}, expired: []bool{false}},
+	}
+	for _, test := range tests {
+		status := pdbautoscaler.EvictionAutoScalerStatus{RecentEvictions: test.recorded, DrainingNodes: test.draining,
+			LastEviction: test.recorded[len(test.recorded)-1].Eviction()}
+		EvictionAutoScaler := &pdbautoscaler.EvictionAutoScaler{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"}, Status: status}
+		c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(EvictionAutoScaler).
+			WithStatusSubresource(&pdbautoscaler.EvictionAutoScaler{}).Build()
+
+		after, err := AbortDrain(context.Background(), c, types.NamespacedName{Namespace: "default", Name: "web"}, "node-a")
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		if slices.Contains(after.Status.DrainingNodes, "node-a") {
+			t.Errorf("%s: got draining nodes %v, want node-a released", test.name, after.Status.DrainingNodes)
+		}
+		for i, record := range after.Status.RecentEvictions {
+			if record.Expired != test.expired[i] {
+				t.Errorf("%s: got eviction of %s expired %v, want %v", test.name, record.PodName, record.Expired, test.expired[i])
+			}
+		}
+		if last := after.Status.ExpiredEviction == after.Status.LastEviction; last != test.last {
+			t.Errorf("%s: got last eviction expired %v, want %v", test.name, last, test.last)
+		}
+	}
+}
@@ -0,0 +1,21 @@
+package evictionutil
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+)
+
+// UnhealthyEvictionAllowed returns true if pdb lets pod be evicted whatever its disruptionsAllowed, because pod isn't
+// Ready and pdb's unhealthyPodEvictionPolicy is AlwaysAllow. Surging for such a pod only holds capacity the drain
+// never waits on. API servers without the field drop it, which reads as unset and the default IfHealthyBudget.
+func UnhealthyEvictionAllowed(pdb *policyv1.PodDisruptionBudget, pod *corev1.Pod) bool {
+	if pdb == nil || pdb.Spec.UnhealthyPodEvictionPolicy == nil || *pdb.Spec.UnhealthyPodEvictionPolicy != policyv1.AlwaysAllow {
+		return false
+	}
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == corev1.PodReady {
+			return condition.Status != corev1.ConditionTrue
+		}
+	}
+	return true
+}
@@ -0,0 +1,44 @@
+package metrics
+
+import (
+	"sync"
+
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// blocked is the EvictionAutoScalers whose pdb last allowed no disruptions, with a count per namespace.
+// Like surges it starts empty after a restart and is refilled as every EvictionAutoScaler is reconciled.
+var blocked = struct {
+	sync.Mutex
+	keys       map[types.NamespacedName]bool
+	namespaces map[string]int
+}{keys: map[types.NamespacedName]bool{}, namespaces: map[string]int{}}
+
+// SetPDBBlocked records whether the pdb of the EvictionAutoScaler key allows no disruptions.
+func SetPDBBlocked(key types.NamespacedName, isBlocked bool) {
+	blocked.Lock()
+	defer blocked.Unlock()
+	if blocked.keys[key] == isBlocked {
+		return
+	}
+	if isBlocked {
+		blocked.keys[key] = true
+		blocked.namespaces[key.Namespace]++
+		MonitoredPDBsBlockedGauge.WithLabelValues(key.Namespace).Set(float64(blocked.namespaces[key.Namespace]))
+		return
+	}
+	delete(blocked.keys, key)
+	blocked.namespaces[key.Namespace]--
+	if blocked.namespaces[key.Namespace] > 0 {
+		MonitoredPDBsBlockedGauge.WithLabelValues(key.Namespace).Set(float64(blocked.namespaces[key.Namespace]))
+		return
+	}
+	// drop the series so namespaces that are gone don't linger on dashboards
+	delete(blocked.namespaces, key.Namespace)
+	MonitoredPDBsBlockedGauge.DeleteLabelValues(key.Namespace)
+}
+
+// ForgetPDBBlocked drops a deleted or skipped EvictionAutoScaler from MonitoredPDBsBlockedGauge.
+func ForgetPDBBlocked(key types.NamespacedName) {
+	SetPDBBlocked(key, false)
+}
@@ -0,0 +1,54 @@
+package metrics
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestSetPDBBlocked(t *testing.T) {
+	a := types.NamespacedName{Namespace: "blocked-a", Name: "web"}
+	b := types.NamespacedName{Namespace: "blocked-a", Name: "db"}
+	c := types.NamespacedName{Namespace: "blocked-b", Name: "web"}
+
+	SetPDBBlocked(a, true)
+	SetPDBBlocked(b, true)
+	SetPDBBlocked(c, true)
+	SetPDBBlocked(a, true) // same again changes nothing
+	if got := gaugeValue(t, MonitoredPDBsBlockedGauge.WithLabelValues("blocked-a")); got != 2 {
+		t.Errorf("blocked-a: got %v want 2", got)
+	}
+
+	SetPDBBlocked(a, false)
+	if got := gaugeValue(t, MonitoredPDBsBlockedGauge.WithLabelValues("blocked-a")); got != 1 {
+		t.Errorf("blocked-a: got %v want 1 once a pdb relaxed", got)
+	}
+	ForgetPDBBlocked(b)
+	ForgetPDBBlocked(c)
+	ForgetPDBBlocked(c)
+	families, err := gatherBlocked()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(families) != 0 {
+		t.Errorf("got series %v, want none once nothing is blocked", families)
+	}
+}
+
+// gatherBlocked returns the series of MonitoredPDBsBlockedGauge.
+func gatherBlocked() ([]*dto.Metric, error) {
+	ch := make(chan prometheus.Metric, 10)
+	MonitoredPDBsBlockedGauge.Collect(ch)
+	close(ch)
+	var series []*dto.Metric
+	for metric := range ch {
+		m := &dto.Metric{}
+		if err := metric.Write(m); err != nil {
+			return nil, err
+		}
+		series = append(series, m)
+	}
+	return series, nil
+}
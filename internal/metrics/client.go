@@ -0,0 +1,165 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/scale"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// InstrumentClient counts every call c makes in APIRequestSuccessesCounter or APIRequestErrorsCounter, with status
+// and other subresource calls counted under resource/subresource.
+func InstrumentClient(c client.Client) client.Client {
+	return &instrumentedClient{Client: c}
+}
+
+// InstrumentScales counts the scale subresource calls of scales like InstrumentClient, as resource/scale.
+func InstrumentScales(scales scale.ScalesGetter) scale.ScalesGetter {
+	return instrumentedScales{scales}
+}
+
+// observeRequest counts a call of verb on resource that returned err and passes err on.
+func observeRequest(verb, resource string, err error) error {
+	if err == nil {
+		APIRequestSuccessesCounter.WithLabelValues(verb, resource).Inc()
+		return nil
+	}
+	code := "<error>"
+	var status apierrors.APIStatus
+	if errors.As(err, &status) && status.Status().Code != 0 {
+		code = strconv.Itoa(int(status.Status().Code))
+	}
+	APIRequestErrorsCounter.WithLabelValues(verb, resource, code).Inc()
+	return err
+}
+
+type instrumentedClient struct {
+	client.Client
+}
+
+// resource returns the plural resource of obj, a list counting as the resource it lists, guessed from its kind if
+// the RESTMapper doesn't know it.
+func (c *instrumentedClient) resource(obj runtime.Object) string {
+	gvk, err := c.GroupVersionKindFor(obj)
+	if err != nil {
+		return "unknown"
+	}
+	if _, isList := obj.(client.ObjectList); isList {
+		gvk.Kind = strings.TrimSuffix(gvk.Kind, "List")
+	}
+	mapping, err := c.RESTMapper().RESTMapping(gvk.GroupKind(), gvk.Version)
+	if err != nil {
+		plural, _ := meta.UnsafeGuessKindToResource(gvk)
+		return plural.Resource
+	}
+	return mapping.Resource.Resource
+}
+
+func (c *instrumentedClient) Get(ctx context.Context, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+	return observeRequest("get", c.resource(obj), c.Client.Get(ctx, key, obj, opts...))
+}
+
+func (c *instrumentedClient) List(ctx context.Context, list client.ObjectList, opts ...client.ListOption) error {
+	return observeRequest("list", c.resource(list), c.Client.List(ctx, list, opts...))
+}
+
+func (c *instrumentedClient) Create(ctx context.Context, obj client.Object, opts ...client.CreateOption) error {
+	return observeRequest("create", c.resource(obj), c.Client.Create(ctx, obj, opts...))
+}
+
+func (c *instrumentedClient) Delete(ctx context.Context, obj client.Object, opts ...client.DeleteOption) error {
+	return observeRequest("delete", c.resource(obj), c.Client.Delete(ctx, obj, opts...))
+}
+
+func (c *instrumentedClient) Update(ctx context.Context, obj client.Object, opts ...client.UpdateOption) error {
+	return observeRequest("update", c.resource(obj), c.Client.Update(ctx, obj, opts...))
+}
+
+func (c *instrumentedClient) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+	return observeRequest("patch", c.resource(obj), c.Client.Patch(ctx, obj, patch, opts...))
+}
+
+func (c *instrumentedClient) DeleteAllOf(ctx context.Context, obj client.Object, opts ...client.DeleteAllOfOption) error {
+	return observeRequest("deletecollection", c.resource(obj), c.Client.DeleteAllOf(ctx, obj, opts...))
+}
+
+func (c *instrumentedClient) Status() client.SubResourceWriter {
+	return &instrumentedSubResourceWriter{client: c, name: "status", writer: c.Client.Status()}
+}
+
+func (c *instrumentedClient) SubResource(subResource string) client.SubResourceClient {
+	sub := c.Client.SubResource(subResource)
+	return &instrumentedSubResourceClient{
+		instrumentedSubResourceWriter: instrumentedSubResourceWriter{client: c, name: subResource, writer: sub},
+		reader:                        sub,
+	}
+}
+
+type instrumentedSubResourceWriter struct {
+	client *instrumentedClient
+	name   string
+	writer client.SubResourceWriter
+}
+
+func (w *instrumentedSubResourceWriter) resource(obj client.Object) string {
+	return w.client.resource(obj) + "/" + w.name
+}
+
+func (w *instrumentedSubResourceWriter) Create(ctx context.Context, obj client.Object, subResource client.Object, opts ...client.SubResourceCreateOption) error {
+	return observeRequest("create", w.resource(obj), w.writer.Create(ctx, obj, subResource, opts...))
+}
+
+func (w *instrumentedSubResourceWriter) Update(ctx context.Context, obj client.Object, opts ...client.SubResourceUpdateOption) error {
+	return observeRequest("update", w.resource(obj), w.writer.Update(ctx, obj, opts...))
+}
+
+func (w *instrumentedSubResourceWriter) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.SubResourcePatchOption) error {
+	return observeRequest("patch", w.resource(obj), w.writer.Patch(ctx, obj, patch, opts...))
+}
+
+type instrumentedSubResourceClient struct {
+	instrumentedSubResourceWriter
+	reader client.SubResourceReader
+}
+
+func (c *instrumentedSubResourceClient) Get(ctx context.Context, obj client.Object, subResource client.Object, opts ...client.SubResourceGetOption) error {
+	return observeRequest("get", c.resource(obj), c.reader.Get(ctx, obj, subResource, opts...))
+}
+
+type instrumentedScales struct {
+	scale.ScalesGetter
+}
+
+func (s instrumentedScales) Scales(namespace string) scale.ScaleInterface {
+	return instrumentedScale{s.ScalesGetter.Scales(namespace)}
+}
+
+type instrumentedScale struct {
+	scale.ScaleInterface
+}
+
+func (s instrumentedScale) Get(ctx context.Context, resource schema.GroupResource, name string, opts metav1.GetOptions) (*autoscalingv1.Scale, error) {
+	result, err := s.ScaleInterface.Get(ctx, resource, name, opts)
+	return result, observeRequest("get", resource.Resource+"/scale", err)
+}
+
+func (s instrumentedScale) Update(ctx context.Context, resource schema.GroupResource, scale *autoscalingv1.Scale, opts metav1.UpdateOptions) (*autoscalingv1.Scale, error) {
+	result, err := s.ScaleInterface.Update(ctx, resource, scale, opts)
+	return result, observeRequest("update", resource.Resource+"/scale", err)
+}
+
+func (s instrumentedScale) Patch(ctx context.Context, gvr schema.GroupVersionResource, name string, pt types.PatchType, data []byte,
+	opts metav1.PatchOptions) (*autoscalingv1.Scale, error) {
+	result, err := s.ScaleInterface.Patch(ctx, gvr, name, pt, data, opts)
+	return result, observeRequest("patch", gvr.Resource+"/scale", err)
+}
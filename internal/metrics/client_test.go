@@ -0,0 +1,58 @@
+package metrics
+
+import (
+	"context"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestInstrumentClient(t *testing.T) {
+	ctx := context.Background()
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "web-0", Namespace: "default"}}
+	c := InstrumentClient(fake.NewClientBuilder().WithScheme(scheme).WithObjects(pod).WithStatusSubresource(pod).Build())
+
+	if err := c.Get(ctx, client.ObjectKey{Namespace: "default", Name: "missing"}, &corev1.Pod{}); err == nil {
+		t.Fatal("got no error for a missing pod")
+	}
+	if err := c.List(ctx, &corev1.PodList{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Status().Update(ctx, pod); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Update(ctx, &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "missing", Namespace: "default"}}); err == nil {
+		t.Fatal("got no error updating a missing pod")
+	}
+
+	for _, test := range []struct {
+		counter prometheus.Counter
+		want    float64
+	}{
+		{APIRequestErrorsCounter.WithLabelValues("get", "pods", "404"), 1},
+		{APIRequestErrorsCounter.WithLabelValues("update", "pods", "404"), 1},
+		{APIRequestSuccessesCounter.WithLabelValues("list", "pods"), 1},
+		// the status subresource is counted on its own
+		{APIRequestSuccessesCounter.WithLabelValues("update", "pods/status"), 1},
+		{APIRequestSuccessesCounter.WithLabelValues("update", "pods"), 0},
+	} {
+		m := &dto.Metric{}
+		if err := test.counter.Write(m); err != nil {
+			t.Fatal(err)
+		}
+		if got := m.GetCounter().GetValue(); got != test.want {
+			t.Errorf("%s: got %v want %v", test.counter.Desc(), got, test.want)
+		}
+	}
+}
@@ -17,6 +17,9 @@ limitations under the License.
 package metrics
 
 import (
+	"strings"
+
+	v1 "github.com/azure/eviction-autoscaler/api/v1"
 	"github.com/prometheus/client_golang/prometheus"
 	policyv1 "k8s.io/api/policy/v1"
 	ctrlmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
@@ -43,14 +46,17 @@ var (
 		[]string{"namespace", "created_by_us", "max_unavailable_zero", "min_available_equals_replicas"},
 	)
 
-	// EvictionCounter tracks how often the eviction-autoscaler notices an eviction
-	// Labels: namespace
+	// EvictionCounter counts each eviction once, when the node controller or the eviction webhook records it
+	// Labels: namespace, source, target_kind
+	// source is cordon (the node controller draining a node), eviction_api (the eviction webhook) or unknown and
+	// target_kind is the lowercased kind of the surged workload, so there are at most a dozen series for each namespace
+	// with evictions.
 	EvictionCounter = prometheus.NewCounterVec(
 		prometheus.CounterOpts{
 			Name: "eviction_autoscaler_evictions_total",
-			Help: "Total number of evictions noticed by the eviction autoscaler",
+			Help: "Total number of evictions noticed by the eviction autoscaler, by namespace, source and target kind",
 		},
-		[]string{"namespace"},
+		[]string{"namespace", "source", "target_kind"},
 	)
 
 	// BlockedEvictionCounter tracks how often evictions are blocked by PDBs
@@ -83,6 +89,110 @@ var (
 		[]string{"namespace", "deployment_name", "action"},
 	)
 
+	// ScaleUpCounter tracks target surges, counted once the scale write succeeded
+	// Labels: namespace, target_kind
+	ScaleUpCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "eviction_autoscaler_scaleup_total",
+			Help: "Total number of times a target was surged",
+		},
+		[]string{"namespace", "target_kind"},
+	)
+
+	// ScaleUpReplicasCounter tracks how many replicas surges added
+	// Labels: namespace, target_kind
+	ScaleUpReplicasCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "eviction_autoscaler_scaleup_replicas_total",
+			Help: "Total number of replicas added to targets by surges",
+		},
+		[]string{"namespace", "target_kind"},
+	)
+
+	// ScaleDownCounter tracks targets restored to their baseline after a surge
+	// Labels: namespace, target_kind
+	ScaleDownCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "eviction_autoscaler_scaledown_total",
+			Help: "Total number of times a surged target was scaled back down",
+		},
+		[]string{"namespace", "target_kind"},
+	)
+
+	// SurgeReplicasGauge tracks how many replicas EvictionAutoScalers currently hold above their targets' baselines
+	// Labels: namespace
+	SurgeReplicasGauge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "eviction_autoscaler_surge_replicas",
+			Help: "Replicas currently surged above target baselines",
+		},
+		[]string{"namespace"},
+	)
+
+	// ClusterSurgeReplicasGauge is SurgeReplicasGauge summed over all namespaces
+	ClusterSurgeReplicasGauge = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "eviction_autoscaler_cluster_surge_replicas",
+			Help: "Replicas currently surged above target baselines across the cluster",
+		},
+	)
+
+	// SurgeBudgetUsedGauge is how many replicas of --max-total-surge-replicas EvictionAutoScalers hold
+	SurgeBudgetUsedGauge = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "eviction_autoscaler_surge_budget_used_replicas",
+			Help: "Surge replicas held against the cluster-wide surge budget",
+		},
+	)
+
+	// SurgeBudgetRemainingGauge is how many replicas of --max-total-surge-replicas are free, +Inf without a budget
+	SurgeBudgetRemainingGauge = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "eviction_autoscaler_surge_budget_remaining_replicas",
+			Help: "Surge replicas the cluster-wide surge budget has room for",
+		},
+	)
+
+	// MonitoredPDBsBlockedGauge tracks the pdbs of EvictionAutoScalers currently allowing no disruptions
+	// Labels: namespace
+	MonitoredPDBsBlockedGauge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "eviction_autoscaler_monitored_pdbs_blocked",
+			Help: "PDBs of EvictionAutoScalers currently allowing no disruptions",
+		},
+		[]string{"namespace"},
+	)
+
+	// NodeDrainAssistsGauge tracks the node drains we are assisting and the ones waiting on --max-concurrent-node-drains
+	// Labels: state
+	NodeDrainAssistsGauge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "eviction_autoscaler_node_drain_assists",
+			Help: "Node drains being assisted or queued for a free slot",
+		},
+		[]string{"state"},
+	)
+
+	// PodsStuckTerminatingGauge tracks pods on draining nodes still terminating past their grace period, which hold
+	// up the drain however the pdb is surged
+	// Labels: node, namespace
+	PodsStuckTerminatingGauge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "eviction_autoscaler_pods_stuck_terminating",
+			Help: "Pods on draining nodes terminating past their grace period by --stuck-terminating-threshold",
+		},
+		[]string{"node", "namespace"},
+	)
+
+	// ScaleUpTokensGauge is how many scale-ups --max-scaleups-per-minute allows right now, +Inf without a limit
+	ScaleUpTokensGauge = prometheus.NewGaugeFunc(
+		prometheus.GaugeOpts{
+			Name: "eviction_autoscaler_scaleup_tokens",
+			Help: "Scale-ups the cluster-wide rate limit allows right now",
+		},
+		func() float64 { return scaleUpTokens.Load().(func() float64)() },
+	)
+
 	// PDBCreationCounter tracks PDB creation events
 	// Labels: namespace, deployment_name
 	PDBCreationCounter = prometheus.NewCounterVec(
@@ -111,6 +221,219 @@ var (
 		},
 	)
 
+	// NodeDrainTriggerCounter tracks drains of nodes with pods for an EvictionAutoScaler by what made us treat the node as draining
+	// Labels: trigger (cordon/drain_taint/out_of_service/not_ready)
+	NodeDrainTriggerCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "eviction_autoscaler_node_drain_triggers_total",
+			Help: "Total number of node drains with pods for an EvictionAutoScaler by what triggered them",
+		},
+		[]string{"trigger"},
+	)
+
+	// SkippedPodCounter tracks pods on cordoned nodes that were skipped because a drain won't evict them or they opted out
+	// Labels: namespace, reason (ignored/daemonset/mirror_pod/node_owned/job/completed/eviction_allowed/unhealthy_eviction_allowed)
+	SkippedPodCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "eviction_autoscaler_skipped_pods_total",
+			Help: "Total number of pods on cordoned nodes skipped because scaling can't help evict them",
+		},
+		[]string{"namespace", "reason"},
+	)
+
+	// DrainBlockedPodCounter tracks pods on draining nodes a drain can't evict yet, by what keeps it from evicting them
+	// so upgrade stalls on do-not-disrupt style annotations aren't mistaken for pdbs.
+	// Labels: namespace, blocker (annotation/pdb/surge_not_ready)
+	DrainBlockedPodCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "eviction_autoscaler_drain_blocked_pods_total",
+			Help: "Total number of pods on draining nodes blocked from eviction by an annotation or a pdb allowing no disruptions",
+		},
+		[]string{"namespace", "blocker"},
+	)
+
+	// PodConditionUpdateFailureCounter tracks pods whose DisruptionTarget condition couldn't be written, say because
+	// the kubelet kept updating their status, and were skipped so the rest of the node's pods still get theirs
+	// Labels: namespace
+	PodConditionUpdateFailureCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "eviction_autoscaler_pod_condition_update_failures_total",
+			Help: "Total number of pods on draining or uncordoned nodes whose DisruptionTarget condition couldn't be updated",
+		},
+		[]string{"namespace"},
+	)
+
+	// PodConditionWritesDisabledGauge is 1 while DisruptionTarget pod conditions aren't written, by
+	// --disable-pod-condition-writes or because writing pods/status was forbidden. Surges are unaffected.
+	PodConditionWritesDisabledGauge = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "eviction_autoscaler_pod_condition_writes_disabled",
+			Help: "1 while DisruptionTarget pod conditions aren't written, by flag or because pods/status is forbidden",
+		},
+	)
+
+	// APIRequestErrorsCounter tracks failed api server calls of our reconcilers, the status and scale subresources
+	// counted as their own resources like evictionautoscalers/status since those are the ones RBAC most often denies.
+	// code is the HTTP status, <error> when there was none like a timeout.
+	// Labels: verb, resource, code
+	APIRequestErrorsCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "eviction_autoscaler_apiserver_request_errors_total",
+			Help: "Total number of failed api server calls of the controllers by verb, resource and status code",
+		},
+		[]string{"verb", "resource", "code"},
+	)
+
+	// APIRequestSuccessesCounter tracks the api server calls of our reconcilers that succeeded, for error rates
+	// Labels: verb, resource
+	APIRequestSuccessesCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "eviction_autoscaler_apiserver_request_successes_total",
+			Help: "Total number of successful api server calls of the controllers by verb and resource",
+		},
+		[]string{"verb", "resource"},
+	)
+
+	// NodePodErrorCounter tracks pods of draining nodes that failed with an error the node is retried for, by the
+	// operation that failed. The node's other pods are still assisted.
+	// Labels: namespace, operation
+	NodePodErrorCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "eviction_autoscaler_node_pod_errors_total",
+			Help: "Total number of pods on draining nodes that couldn't be assisted, by failed operation",
+		},
+		[]string{"namespace", "operation"},
+	)
+
+	// DrainDeadlineExceededCounter tracks node drains that went past an EvictionAutoScaler's spec.maxDrainDurationSeconds
+	// Labels: namespace, node
+	DrainDeadlineExceededCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "eviction_autoscaler_drain_deadline_exceeded_total",
+			Help: "Total number of node drains still blocked for an EvictionAutoScaler past its maxDrainDurationSeconds",
+		},
+		[]string{"namespace", "node"},
+	)
+
+	// SkippedNodeCounter tracks node events ignored because the node doesn't match --node-label-selector or opted out
+	// Labels: reason (node_selector/disabled)
+	SkippedNodeCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "eviction_autoscaler_skipped_nodes_total",
+			Help: "Total number of node events ignored because the node does not match the node label selector or is disabled",
+		},
+		[]string{"reason"},
+	)
+
+	// SkippedNamespaceCounter tracks objects skipped because their namespace is excluded by the namespace allowlist/denylist
+	// or annotated eviction-autoscaler.azure.com/ignore
+	// Labels: namespace, component, reason (excluded/ignored)
+	SkippedNamespaceCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "eviction_autoscaler_skipped_namespace_total",
+			Help: "Total number of objects skipped because their namespace is excluded or ignored",
+		},
+		[]string{"namespace", "component", "reason"},
+	)
+
+	// ConflictingSelectorsCounter tracks pods matched by the pdbs of more than one EvictionAutoScaler
+	// Labels: namespace
+	ConflictingSelectorsCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "eviction_autoscaler_conflicting_selectors_total",
+			Help: "Total number of pods selected by the pdbs of more than one EvictionAutoScaler",
+		},
+		[]string{"namespace"},
+	)
+
+	// EvictionDelayedCounter tracks evictions the eviction webhook denied while their surge wasn't available yet,
+	// and ones it let through because the surge took longer than --eviction-webhook-wait-for-surge
+	// Labels: namespace, outcome (delayed/timed_out)
+	EvictionDelayedCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "eviction_autoscaler_evictions_delayed_total",
+			Help: "Total number of evictions held back by the eviction webhook until their surge is available",
+		},
+		[]string{"namespace", "outcome"},
+	)
+
+	// WebhookEvictionCounter tracks every eviction the eviction webhook saw by what it made of it. Evictions of pods
+	// with an EvictionAutoScaler are passed (the pdb allows them), blocked (the pdb doesn't, so it is recorded for a
+	// surge), delayed (held back by --eviction-webhook-wait-for-surge) or unhealthy (a not Ready pod an AlwaysAllow
+	// unhealthyPodEvictionPolicy lets through), the rest are unmanaged, skipped or error
+	// Labels: namespace, outcome (passed/blocked/delayed/unhealthy/unmanaged/skipped/error)
+	WebhookEvictionCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "eviction_autoscaler_webhook_evictions_total",
+			Help: "Total number of eviction requests seen by the eviction webhook by outcome",
+		},
+		[]string{"namespace", "outcome"},
+	)
+
+	// SurgePodsSteeredCounter tracks pods created during a surge that the surge pod affinity webhook steered away from draining nodes
+	// Labels: namespace
+	SurgePodsSteeredCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "eviction_autoscaler_surge_pods_steered_total",
+			Help: "Total number of pods created during a surge given node affinity away from cordoned and drain tainted nodes",
+		},
+		[]string{"namespace"},
+	)
+
+	// PDBWarningCounter tracks pdb writes the pdb webhook warned about for allowing no disruptions
+	// Labels: namespace, reason
+	PDBWarningCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "eviction_autoscaler_pdb_warnings_total",
+			Help: "Total number of pdb creates and updates warned about for allowing no disruption with their workloads' replicas",
+		},
+		[]string{"namespace", "reason"},
+	)
+
+	// DryRunDecisionCounter tracks actions skipped because of --dry-run
+	// Labels: action (set_pod_condition/record_eviction/scale_target/delete_evictionautoscaler/delay_eviction)
+	DryRunDecisionCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "eviction_autoscaler_decision_dryrun_total",
+			Help: "Total number of actions the eviction autoscaler would have taken if not in dry-run mode",
+		},
+		[]string{"action"},
+	)
+
+	// NodeRetryDelay tracks the delays node reconciles that failed are retried after, backing off per node, so a node
+	// failing over and over shows up as delays near the cap
+	NodeRetryDelay = prometheus.NewHistogram(
+		prometheus.HistogramOpts{
+			Name:    "eviction_autoscaler_node_retry_delay_seconds",
+			Help:    "Delay before a node whose reconcile failed is retried",
+			Buckets: prometheus.ExponentialBuckets(0.5, 2, 12), // 0.5s to ~17m
+		},
+	)
+
+	// NodeDrainDuration tracks how long from first seeing a cordoned node with pods for an EvictionAutoScaler
+	// until the last of them left the node
+	// Labels: outcome (drained/uncordoned/deleted)
+	NodeDrainDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "eviction_autoscaler_node_drain_duration_seconds",
+			Help:    "Time from a node being cordoned with pods for an EvictionAutoScaler until those pods are gone",
+			Buckets: prometheus.ExponentialBuckets(15, 2, 10), // 15s to ~2h
+		},
+		[]string{"outcome"},
+	)
+
+	// WebhookEvictionDuration tracks how long the eviction webhook takes to answer an eviction, which every drain
+	// waits on, bounded by its timeout
+	// Labels: outcome (as WebhookEvictionCounter)
+	WebhookEvictionDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "eviction_autoscaler_webhook_eviction_duration_seconds",
+			Help:    "Time the eviction webhook took to answer an eviction request",
+			Buckets: prometheus.ExponentialBuckets(0.00025, 2, 13), // 0.25ms to ~1s
+		},
+		[]string{"outcome"},
+	)
+
 	// PDBInfoGauge tracks various PDB-related metrics
 	// Labels: namespace, pdb_name, target_name, metric_type
 	// todo:chnage with PDBGauge instead of separate gauges per PDB
@@ -160,6 +483,97 @@ const (
 	ScaleDownAction = "scale_down"
 )
 
+// Constants for dry-run actions
+const (
+	DryRunSetPodCondition = "set_pod_condition"
+	DryRunRecordEviction  = "record_eviction"
+	DryRunScaleTarget     = "scale_target"
+	DryRunDelete          = "delete_evictionautoscaler"
+	DryRunDelayEviction   = "delay_eviction"
+	DryRunStandDown       = "stand_down"
+	DryRunRearm           = "rearm"
+)
+
+// Constants for eviction source labels
+const (
+	EvictionSourceCordon      = "cordon"
+	EvictionSourceEvictionAPI = "eviction_api"
+	EvictionSourceUnknown     = "unknown"
+)
+
+// TargetKindUnknown is the target_kind label of evictions whose target isn't resolved yet.
+const TargetKindUnknown = "unknown"
+
+// Constants for delayed eviction outcomes
+const (
+	EvictionDelayed         = "delayed"
+	EvictionDelayedTimedOut = "timed_out"
+)
+
+// Constants for eviction webhook outcomes
+const (
+	WebhookEvictionPassed    = "passed"
+	WebhookEvictionBlocked   = "blocked"
+	WebhookEvictionDelayed   = "delayed"
+	WebhookEvictionUnhealthy = "unhealthy"
+	WebhookEvictionUnmanaged = "unmanaged"
+	WebhookEvictionSkipped   = "skipped"
+	WebhookEvictionError     = "error"
+)
+
+// Constants for node drain outcomes
+const (
+	DrainOutcomeDrained    = "drained"
+	DrainOutcomeUncordoned = "uncordoned"
+	DrainOutcomeDeleted    = "deleted"
+	DrainOutcomeDisabled   = "disabled"
+	DrainOutcomeStale      = "stale_cordon"
+)
+
+// SkippedPodCounter reasons of pods their pdb lets the drain evict without a surge, the other reasons are podutil's.
+const (
+	SkipPodEvictionAllowed = "eviction_allowed"
+	// SkipPodUnhealthyEvictionAllowed pods aren't Ready and their pdb's unhealthyPodEvictionPolicy is AlwaysAllow.
+	SkipPodUnhealthyEvictionAllowed = "unhealthy_eviction_allowed"
+)
+
+// Constants for the operation of NodePodErrorCounter
+const (
+	PodOperationCondition = "set_condition"
+	PodOperationRecord    = "record_eviction"
+)
+
+// Constants for the state of a node drain assist
+const (
+	DrainAssistActive = "active"
+	DrainAssistQueued = "queued"
+)
+
+// Constants for why a node was skipped
+const (
+	SkipNodeSelector = "node_selector"
+	SkipNodeDisabled = "disabled"
+	SkipNodeStale    = "stale_cordon"
+)
+
+// Constants for what blocks a pod on a draining node from being evicted
+const (
+	DrainBlockerAnnotation = "annotation"
+	DrainBlockerPDB        = "pdb"
+	// DrainBlockerSurgeNotReady is a pdb blocking because the surge for the drain isn't available yet.
+	DrainBlockerSurgeNotReady = "surge_not_ready"
+)
+
+// Constants for what made a node count as draining
+const (
+	DrainTriggerCordon       = "cordon"
+	DrainTriggerTaint        = "drain_taint"
+	DrainTriggerOutOfService = "out_of_service"
+	DrainTriggerNotReady     = "not_ready"
+	DrainTriggerMaintenance  = "maintenance"
+	DrainTriggerAnnotation   = "drain_annotation"
+)
+
 // Constants for scaling opportunity signals
 const (
 	PDBBlockedSignal                = "pdb_blocked"
@@ -178,6 +592,33 @@ func GetPDBCreatedByUsLabel(annotations map[string]string) string {
 	return PDBNotCreatedByUsStr
 }
 
+// EvictionSourceLabel is the source label of an eviction's source, unknown for ones recorded before sources were or
+// written by hand.
+func EvictionSourceLabel(source v1.EvictionSource) string {
+	switch source {
+	case v1.EvictionSourceNodeCordon:
+		return EvictionSourceCordon
+	case v1.EvictionSourceEvictionAPI:
+		return EvictionSourceEvictionAPI
+	default:
+		return EvictionSourceUnknown
+	}
+}
+
+// TargetKindLabel is the target_kind label of a workload kind, unknown while the target isn't known yet.
+func TargetKindLabel(kind string) string {
+	if kind == "" {
+		return TargetKindUnknown
+	}
+	return strings.ToLower(kind)
+}
+
+// ForgetStuckTerminating drops the PodsStuckTerminatingGauge series of node, once it is no longer draining or its stuck
+// pods are counted again.
+func ForgetStuckTerminating(node string) {
+	PodsStuckTerminatingGauge.DeletePartialMatch(prometheus.Labels{"node": node})
+}
+
 // GetScalingSignal determines the appropriate signal label for scaling opportunities
 func GetScalingSignal(pdb *policyv1.PodDisruptionBudget) string {
 	// TODO: Could implement later for proactive scaling logic
@@ -196,9 +637,40 @@ func init() {
 		BlockedEvictionCounter,
 		ScalingOpportunityCounter,
 		ActualScalingCounter,
+		ScaleUpCounter,
+		ScaleUpReplicasCounter,
+		ScaleDownCounter,
+		SurgeReplicasGauge,
+		ClusterSurgeReplicasGauge,
+		SurgeBudgetUsedGauge,
+		SurgeBudgetRemainingGauge,
+		MonitoredPDBsBlockedGauge,
+		ScaleUpTokensGauge,
 		PDBCreationCounter,
 		EvictionAutoScalerCreationCounter,
 		NodeCordoningCounter,
+		NodeDrainTriggerCounter,
+		NodeDrainAssistsGauge,
+		PodsStuckTerminatingGauge,
+		SkippedPodCounter,
+		DrainBlockedPodCounter,
+		PodConditionUpdateFailureCounter,
+		PodConditionWritesDisabledGauge,
+		APIRequestErrorsCounter,
+		NodeRetryDelay,
+		APIRequestSuccessesCounter,
+		NodePodErrorCounter,
+		DrainDeadlineExceededCounter,
+		SkippedNodeCounter,
+		SkippedNamespaceCounter,
+		ConflictingSelectorsCounter,
+		EvictionDelayedCounter,
+		WebhookEvictionCounter,
+		SurgePodsSteeredCounter,
+		PDBWarningCounter,
+		DryRunDecisionCounter,
+		NodeDrainDuration,
+		WebhookEvictionDuration,
 		PDBInfoGauge,
 		PDBCounter,
 	)
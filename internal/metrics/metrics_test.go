@@ -0,0 +1,28 @@
+package metrics
+
+import (
+	"testing"
+
+	v1 "github.com/azure/eviction-autoscaler/api/v1"
+)
+
+func TestEvictionSourceLabel(t *testing.T) {
+	for source, want := range map[v1.EvictionSource]string{
+		v1.EvictionSourceNodeCordon:  "cordon",
+		v1.EvictionSourceEvictionAPI: "eviction_api",
+		v1.EvictionSourceManual:      "unknown",
+		"":                           "unknown",
+	} {
+		if got := EvictionSourceLabel(source); got != want {
+			t.Errorf("%q: got %q want %q", source, got, want)
+		}
+	}
+}
+
+func TestTargetKindLabel(t *testing.T) {
+	for kind, want := range map[string]string{"Deployment": "deployment", "StatefulSet": "statefulset", "": "unknown"} {
+		if got := TargetKindLabel(kind); got != want {
+			t.Errorf("%q: got %q want %q", kind, got, want)
+		}
+	}
+}
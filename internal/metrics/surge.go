@@ -0,0 +1,61 @@
+package metrics
+
+import (
+	"math"
+	"sync"
+	"sync/atomic"
+
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// surges is what each EvictionAutoScaler last reported so the gauges can be adjusted by the difference.
+// It starts empty after a restart and is refilled as every EvictionAutoScaler is reconciled from its status.
+var surges = struct {
+	sync.Mutex
+	replicas map[types.NamespacedName]int32
+}{replicas: map[types.NamespacedName]int32{}}
+
+// SetSurgeReplicas records that the EvictionAutoScaler key holds replicas above its target's baseline.
+func SetSurgeReplicas(key types.NamespacedName, replicas int32) {
+	if replicas < 0 {
+		replicas = 0 // scaled below the baseline by someone else, we hold nothing
+	}
+	surges.Lock()
+	defer surges.Unlock()
+	delta := float64(replicas - surges.replicas[key])
+	if replicas == 0 {
+		delete(surges.replicas, key)
+	} else {
+		surges.replicas[key] = replicas
+	}
+	if delta != 0 {
+		SurgeReplicasGauge.WithLabelValues(key.Namespace).Add(delta)
+		ClusterSurgeReplicasGauge.Add(delta)
+	}
+}
+
+// ForgetSurgeReplicas drops a deleted EvictionAutoScaler from the gauges.
+func ForgetSurgeReplicas(key types.NamespacedName) {
+	SetSurgeReplicas(key, 0)
+}
+
+// SurgeReplicas returns what the EvictionAutoScaler key last reported holding above its baseline.
+func SurgeReplicas(key types.NamespacedName) int32 {
+	surges.Lock()
+	defer surges.Unlock()
+	return surges.replicas[key]
+}
+
+// scaleUpTokens reports the tokens left in the scale-up limiter for ScaleUpTokensGauge.
+var scaleUpTokens atomic.Value
+
+func init() {
+	SetScaleUpTokens(func() float64 { return math.Inf(1) })
+	// till a surge budget reports otherwise
+	SurgeBudgetRemainingGauge.Set(math.Inf(1))
+}
+
+// SetScaleUpTokens makes tokens what ScaleUpTokensGauge reports on each scrape.
+func SetScaleUpTokens(tokens func() float64) {
+	scaleUpTokens.Store(tokens)
+}
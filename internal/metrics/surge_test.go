@@ -0,0 +1,47 @@
+package metrics
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func gaugeValue(t *testing.T, gauge prometheus.Gauge) float64 {
+	m := &dto.Metric{}
+	if err := gauge.Write(m); err != nil {
+		t.Fatal(err)
+	}
+	return m.GetGauge().GetValue()
+}
+
+func TestSetSurgeReplicas(t *testing.T) {
+	a := types.NamespacedName{Namespace: "surge-a", Name: "web"}
+	b := types.NamespacedName{Namespace: "surge-a", Name: "db"}
+	c := types.NamespacedName{Namespace: "surge-b", Name: "web"}
+	cluster := gaugeValue(t, ClusterSurgeReplicasGauge)
+
+	SetSurgeReplicas(a, 2)
+	SetSurgeReplicas(b, 1)
+	SetSurgeReplicas(c, 3)
+	SetSurgeReplicas(a, 2) // same again changes nothing
+	if got := gaugeValue(t, SurgeReplicasGauge.WithLabelValues("surge-a")); got != 3 {
+		t.Errorf("surge-a: got %v want 3", got)
+	}
+	if got := gaugeValue(t, ClusterSurgeReplicasGauge) - cluster; got != 6 {
+		t.Errorf("cluster: got %v want 6", got)
+	}
+
+	SetSurgeReplicas(a, 0)
+	SetSurgeReplicas(b, -1)
+	ForgetSurgeReplicas(c)
+	for _, namespace := range []string{"surge-a", "surge-b"} {
+		if got := gaugeValue(t, SurgeReplicasGauge.WithLabelValues(namespace)); got != 0 {
+			t.Errorf("%s: got %v want 0 once restored", namespace, got)
+		}
+	}
+	if got := gaugeValue(t, ClusterSurgeReplicasGauge); got != cluster {
+		t.Errorf("cluster: got %v want %v once restored", got, cluster)
+	}
+}
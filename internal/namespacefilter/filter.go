@@ -0,0 +1,127 @@
+package namespacefilter
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"sync"
+
+	"github.com/azure/eviction-autoscaler/internal/metrics"
+	"github.com/azure/eviction-autoscaler/internal/podutil"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// Reasons a namespace is skipped.
+const (
+	SkipReasonExcluded = "excluded"
+	SkipReasonIgnored  = podutil.SkipReasonIgnored
+)
+
+// Filter decides which namespaces the controllers may touch.
+// A nil Filter allows every namespace.
+type Filter struct {
+	mu    sync.RWMutex
+	allow map[string]bool
+	deny  map[string]bool
+	// flagAllow and flagDeny are the lists from the flags, which Override falls back to.
+	flagAllow, flagDeny []string
+	// Reader looks up namespaces for podutil.IgnoreAnnotationKey, it should be the informer cache. Nil doesn't look.
+	Reader client.Reader
+}
+
+// +kubebuilder:rbac:groups=core,resources=namespaces,verbs=get;list;watch
+
+// New returns a Filter for the --namespace-allowlist and --namespace-denylist flags.
+// An empty allowlist allows every namespace not on the denylist and the denylist wins when both list a namespace.
+func New(allowlist, denylist []string) *Filter {
+	f := &Filter{flagAllow: allowlist, flagDeny: denylist}
+	f.set(allowlist, denylist)
+	return f
+}
+
+// Override replaces the flag lists while the controllers run, for an EvictionAutoScalerConfig. A nil list goes back
+// to its flag's. A nil Filter can't be overridden.
+func (f *Filter) Override(allowlist, denylist []string) {
+	if f == nil {
+		return
+	}
+	if allowlist == nil {
+		allowlist = f.flagAllow
+	}
+	if denylist == nil {
+		denylist = f.flagDeny
+	}
+	f.set(allowlist, denylist)
+}
+
+// Lists returns the allowlist and denylist in effect.
+func (f *Filter) Lists() (allowlist, denylist []string) {
+	if f == nil {
+		return nil, nil
+	}
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return slices.Sorted(maps.Keys(f.allow)), slices.Sorted(maps.Keys(f.deny))
+}
+
+func (f *Filter) set(allowlist, denylist []string) {
+	allow, deny := map[string]bool{}, map[string]bool{}
+	for _, ns := range allowlist {
+		allow[ns] = true
+	}
+	for _, ns := range denylist {
+		deny[ns] = true
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.allow, f.deny = allow, deny
+}
+
+// Allowed returns true if namespace may be touched.
+func (f *Filter) Allowed(namespace string) bool {
+	if f == nil {
+		return true
+	}
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	if f.deny[namespace] {
+		return false
+	}
+	return len(f.allow) == 0 || f.allow[namespace]
+}
+
+// Skip returns true if namespace is excluded or annotated with podutil.IgnoreAnnotationKey,
+// logging and counting the skip by reason for component.
+func (f *Filter) Skip(ctx context.Context, namespace, component string) bool {
+	reason := ""
+	if !f.Allowed(namespace) {
+		reason = SkipReasonExcluded
+	} else if f.ignored(ctx, namespace) {
+		reason = SkipReasonIgnored
+	}
+	if reason == "" {
+		return false
+	}
+	metrics.SkippedNamespaceCounter.WithLabelValues(namespace, component, reason).Inc()
+	log.FromContext(ctx).V(1).Info("Skipping namespace", "namespace", namespace, "component", component, "reason", reason)
+	return true
+}
+
+// ignored returns true if namespace opted out. A namespace we can't read isn't.
+func (f *Filter) ignored(ctx context.Context, namespace string) bool {
+	if f == nil || f.Reader == nil || namespace == "" {
+		return false
+	}
+	ns := &corev1.Namespace{}
+	if err := f.Reader.Get(ctx, types.NamespacedName{Name: namespace}, ns); err != nil {
+		if !errors.IsNotFound(err) {
+			log.FromContext(ctx).Error(err, "unable to read namespace for opt-out", "namespace", namespace)
+		}
+		return false
+	}
+	return ns.Annotations[podutil.IgnoreAnnotationKey] == "true"
+}
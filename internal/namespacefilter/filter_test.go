@@ -0,0 +1,100 @@
+package namespacefilter
+
+import (
+	"context"
+	"testing"
+
+	"github.com/azure/eviction-autoscaler/internal/metrics"
+	"github.com/azure/eviction-autoscaler/internal/podutil"
+	dto "github.com/prometheus/client_model/go"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestAllowed(t *testing.T) {
+	tests := []struct {
+		name      string
+		filter    *Filter
+		namespace string
+		want      bool
+	}{
+		{name: "nil filter", filter: nil, namespace: "kube-system", want: true},
+		{name: "empty lists", filter: New(nil, nil), namespace: "default", want: true},
+		{name: "denied", filter: New(nil, []string{"kube-system"}), namespace: "kube-system", want: false},
+		{name: "not denied", filter: New(nil, []string{"kube-system"}), namespace: "default", want: true},
+		{name: "allowed", filter: New([]string{"tenant-a"}, nil), namespace: "tenant-a", want: true},
+		{name: "not allowed", filter: New([]string{"tenant-a"}, nil), namespace: "tenant-b", want: false},
+		{name: "deny wins", filter: New([]string{"tenant-a"}, []string{"tenant-a"}), namespace: "tenant-a", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.filter.Allowed(tt.namespace); got != tt.want {
+				t.Errorf("Allowed(%q) = %v, want %v", tt.namespace, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSkipIgnoredNamespace(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	reader := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "soak", Annotations: map[string]string{podutil.IgnoreAnnotationKey: "true"}}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "default", Annotations: map[string]string{podutil.IgnoreAnnotationKey: "false"}}},
+	).Build()
+	filter := New(nil, []string{"kube-system"})
+	filter.Reader = reader
+
+	tests := []struct {
+		namespace string
+		want      bool
+		reason    string
+	}{
+		{namespace: "soak", want: true, reason: SkipReasonIgnored},
+		{namespace: "default"},
+		{namespace: "missing"},
+		{namespace: "kube-system", want: true, reason: SkipReasonExcluded},
+	}
+	for _, tt := range tests {
+		before := skips(t, tt.namespace, tt.reason)
+		if got := filter.Skip(context.Background(), tt.namespace, "node"); got != tt.want {
+			t.Errorf("Skip(%q) = %v, want %v", tt.namespace, got, tt.want)
+		}
+		if tt.want && skips(t, tt.namespace, tt.reason) != before+1 {
+			t.Errorf("Skip(%q) didn't count a %s skip", tt.namespace, tt.reason)
+		}
+	}
+}
+
+// skips is the skipped namespace count of namespace for the node component and reason.
+func skips(t *testing.T, namespace, reason string) float64 {
+	m := &dto.Metric{}
+	if err := metrics.SkippedNamespaceCounter.WithLabelValues(namespace, "node", reason).Write(m); err != nil {
+		t.Fatal(err)
+	}
+	return m.GetCounter().GetValue()
+}
+
+func TestOverride(t *testing.T) {
+	f := New(nil, []string{"kube-system"})
+	f.Override([]string{"tenant-a"}, nil)
+	if f.Allowed("tenant-b") || f.Allowed("kube-system") || !f.Allowed("tenant-a") {
+		t.Error("got the override's allowlist not applied over the flag's denylist")
+	}
+	// an empty list is an override too, allowing every namespace
+	f.Override([]string{}, []string{})
+	if !f.Allowed("kube-system") {
+		t.Error("got kube-system still denied by the flag with an empty denylist override")
+	}
+	f.Override(nil, nil)
+	if allow, deny := f.Lists(); len(allow) != 0 || len(deny) != 1 || deny[0] != "kube-system" {
+		t.Errorf("got lists %v, %v after the override went away, want the flag's", allow, deny)
+	}
+	var none *Filter
+	none.Override([]string{"tenant-a"}, nil)
+}
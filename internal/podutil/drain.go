@@ -0,0 +1,51 @@
+package podutil
+
+import (
+	v1 "k8s.io/api/core/v1"
+)
+
+// IgnoreAnnotationKey set to "true" on a pod, or on a namespace for all its pods and EvictionAutoScalers, opts it out
+// without deleting its EvictionAutoScaler.
+const IgnoreAnnotationKey = "eviction-autoscaler.azure.com/ignore"
+
+// Reasons a pod on a cordoned node is skipped because a drain will never evict it, or it opted out.
+const (
+	SkipReasonIgnored   = "ignored"
+	SkipReasonDaemonSet = "daemonset"
+	SkipReasonMirrorPod = "mirror_pod"
+	SkipReasonNodeOwned = "node_owned"
+	SkipReasonJob       = "job"
+	SkipReasonCompleted = "completed"
+)
+
+// DrainSkipReason returns why a drain would leave this pod alone, or that it opted out, or "" if it is a normal evictable pod.
+// DaemonSet pods get recreated on the same node and static/mirror pods are managed by the kubelet,
+// so scaling a Deployment can never move them. Job pods run to completion and finished pods have nothing left to move.
+func DrainSkipReason(pod *v1.Pod) string {
+	if pod.Annotations[IgnoreAnnotationKey] == "true" {
+		return SkipReasonIgnored
+	}
+	if pod.Status.Phase == v1.PodSucceeded || pod.Status.Phase == v1.PodFailed {
+		return SkipReasonCompleted
+	}
+	if _, found := pod.Annotations[v1.MirrorPodAnnotationKey]; found {
+		return SkipReasonMirrorPod
+	}
+	for _, owner := range pod.OwnerReferences {
+		switch owner.Kind {
+		case "DaemonSet":
+			return SkipReasonDaemonSet
+		case "Node":
+			return SkipReasonNodeOwned
+		case "Job":
+			return SkipReasonJob
+		}
+	}
+	return ""
+}
+
+// IsTerminating returns true if the pod is already going away or has finished running.
+// Recording evictions for these would keep surging for pods that are on their way out anyway.
+func IsTerminating(pod *v1.Pod) bool {
+	return pod.DeletionTimestamp != nil || pod.Status.Phase == v1.PodSucceeded || pod.Status.Phase == v1.PodFailed
+}
@@ -5,6 +5,32 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
+// The DisruptionTarget reason and message the node controller writes on pods of cordoned nodes.
+// They mark the condition as ours so uncordon only clears what we set and not a real eviction's.
+const (
+	CordonDisruptionReason  = "EvictionAttempt"
+	CordonDisruptionMessage = "eviction attempt anticipated by node cordon"
+	UncordonReason          = "NodeUncordoned"
+)
+
+// IsCordonDisruptionTarget returns true if status has the DisruptionTarget condition we set for a cordon.
+func IsCordonDisruptionTarget(status *v1.PodStatus) bool {
+	_, condition := getPodCondition(status, v1.DisruptionTarget)
+	return condition != nil && condition.Status == v1.ConditionTrue &&
+		condition.Reason == CordonDisruptionReason && condition.Message == CordonDisruptionMessage
+}
+
+// UncordonedDisruptionTarget is the DisruptionTarget condition we set for a cordon flipped back to false.
+func UncordonedDisruptionTarget() v1.PodCondition {
+	return v1.PodCondition{
+		Type:               v1.DisruptionTarget,
+		Status:             v1.ConditionFalse,
+		Reason:             UncordonReason,
+		Message:            "node was uncordoned",
+		LastTransitionTime: metav1.Now(),
+	}
+}
+
 func UpdatePodCondition(status *v1.PodStatus, condition *v1.PodCondition) bool {
 	condition.LastTransitionTime = metav1.Now()
 	// Try to find this pod condition.
@@ -0,0 +1,51 @@
+package selectorcache
+
+import (
+	"sync"
+
+	policyv1 "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// maxEntries bounds the cache since nothing tells us a pdb was deleted. It is dropped wholesale when full.
+const maxEntries = 10000
+
+// Cache holds compiled pdb selectors keyed by pdb UID so every pod on a cordoned node and every eviction
+// doesn't recompile them. An entry is recompiled once the pdb's generation moves on.
+// A nil Cache compiles every time. It is safe for concurrent use.
+type Cache struct {
+	mu      sync.Mutex
+	entries map[types.UID]entry
+}
+
+type entry struct {
+	generation int64
+	selector   labels.Selector
+	err        error
+}
+
+// New returns an empty Cache.
+func New() *Cache {
+	return &Cache{entries: map[types.UID]entry{}}
+}
+
+// Selector returns pdb's compiled selector, compiling it on a miss or a new generation.
+// pdbs without a UID, as in some tests, are compiled and not stored.
+func (c *Cache) Selector(pdb *policyv1.PodDisruptionBudget) (labels.Selector, error) {
+	if c == nil || pdb.UID == "" {
+		return metav1.LabelSelectorAsSelector(pdb.Spec.Selector)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if cached, found := c.entries[pdb.UID]; found && cached.generation == pdb.Generation {
+		return cached.selector, cached.err
+	}
+	selector, err := metav1.LabelSelectorAsSelector(pdb.Spec.Selector)
+	if len(c.entries) >= maxEntries {
+		c.entries = map[types.UID]entry{}
+	}
+	c.entries[pdb.UID] = entry{generation: pdb.Generation, selector: selector, err: err}
+	return selector, err
+}
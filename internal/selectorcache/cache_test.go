@@ -0,0 +1,96 @@
+package selectorcache
+
+import (
+	"fmt"
+	"testing"
+
+	policyv1 "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func pdb(uid string, generation int64, app string) *policyv1.PodDisruptionBudget {
+	return &policyv1.PodDisruptionBudget{
+		ObjectMeta: metav1.ObjectMeta{UID: types.UID(uid), Generation: generation},
+		Spec:       policyv1.PodDisruptionBudgetSpec{Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": app}}},
+	}
+}
+
+func TestSelector(t *testing.T) {
+	web := labels.Set{"app": "web"}
+	tests := []struct {
+		name    string
+		cache   *Cache
+		pdbs    []*policyv1.PodDisruptionBudget // selectors fetched in order, the last is checked
+		matches bool
+		entries int
+	}{
+		{name: "nil cache", pdbs: []*policyv1.PodDisruptionBudget{pdb("a", 1, "web")}, matches: true},
+		{name: "miss", cache: New(), pdbs: []*policyv1.PodDisruptionBudget{pdb("a", 1, "web")}, matches: true, entries: 1},
+		{name: "hit", cache: New(), pdbs: []*policyv1.PodDisruptionBudget{pdb("a", 1, "web"), pdb("a", 1, "web")}, matches: true, entries: 1},
+		{name: "new generation", cache: New(), pdbs: []*policyv1.PodDisruptionBudget{pdb("a", 1, "web"), pdb("a", 2, "db")}, entries: 1},
+		{name: "recreated pdb", cache: New(), pdbs: []*policyv1.PodDisruptionBudget{pdb("a", 1, "db"), pdb("b", 1, "web")}, matches: true, entries: 2},
+		{name: "no uid", cache: New(), pdbs: []*policyv1.PodDisruptionBudget{pdb("", 1, "web")}, matches: true},
+	}
+	for _, test := range tests {
+		var selector labels.Selector
+		for _, p := range test.pdbs {
+			var err error
+			if selector, err = test.cache.Selector(p); err != nil {
+				t.Fatalf("%s: %v", test.name, err)
+			}
+		}
+		if got := selector.Matches(web); got != test.matches {
+			t.Errorf("%s: selector %s matches app=web %v want %v", test.name, selector, got, test.matches)
+		}
+		if test.cache != nil && len(test.cache.entries) != test.entries {
+			t.Errorf("%s: %d entries want %d", test.name, len(test.cache.entries), test.entries)
+		}
+	}
+}
+
+func TestSelectorInvalid(t *testing.T) {
+	cache := New()
+	invalid := pdb("a", 1, "web")
+	invalid.Spec.Selector.MatchExpressions = []metav1.LabelSelectorRequirement{{Key: "app", Operator: "Bogus"}}
+	for range 2 {
+		if _, err := cache.Selector(invalid); err == nil {
+			t.Error("invalid selector compiled")
+		}
+	}
+}
+
+// BenchmarkSelectors matches 500 pods against 50 pdbs, compiling every selector each time versus through the cache.
+func BenchmarkSelectors(b *testing.B) {
+	var pdbs []*policyv1.PodDisruptionBudget
+	for i := range 50 {
+		p := pdb(fmt.Sprintf("uid-%d", i), 1, fmt.Sprintf("app-%d", i))
+		p.Spec.Selector.MatchExpressions = []metav1.LabelSelectorRequirement{
+			{Key: "tier", Operator: metav1.LabelSelectorOpIn, Values: []string{"frontend", "backend"}},
+		}
+		pdbs = append(pdbs, p)
+	}
+	var pods []labels.Set
+	for i := range 500 {
+		pods = append(pods, labels.Set{"app": fmt.Sprintf("app-%d", i%50), "tier": "backend"})
+	}
+	for _, bench := range []struct {
+		name  string
+		cache *Cache
+	}{
+		{name: "uncached"},
+		{name: "cached", cache: New()},
+	} {
+		b.Run(bench.name, func(b *testing.B) {
+			for range b.N {
+				for _, pod := range pods {
+					for _, p := range pdbs {
+						selector, _ := bench.cache.Selector(p)
+						selector.Matches(pod)
+					}
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,74 @@
+package tracing
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Tracer starts every span of the controllers and webhooks. It is a no-op till Setup installs an exporter.
+var Tracer = otel.Tracer("github.com/azure/eviction-autoscaler")
+
+// Attributes on our spans.
+const (
+	NodeKey               = attribute.Key("node")
+	NamespaceKey          = attribute.Key("namespace")
+	EvictionAutoScalerKey = attribute.Key("evictionautoscaler")
+	PodKey                = attribute.Key("pod")
+	ReplicasKey           = attribute.Key("replicas")
+	// DecisionKey is what a reconcile decided to do, e.g. scale-up or cooldown.
+	DecisionKey = attribute.Key("decision")
+)
+
+// Setup exports spans to the otlp grpc endpoint, keeping samplingRatio of the traces we start.
+// The returned shutdown flushes what is left. An empty endpoint leaves tracing a no-op.
+func Setup(ctx context.Context, endpoint string, insecure bool, samplingRatio float64) (func(context.Context) error, error) {
+	if endpoint == "" {
+		return func(context.Context) error { return nil }, nil
+	}
+	options := []otlptracegrpc.Option{otlptracegrpc.WithEndpoint(endpoint)}
+	if insecure {
+		options = append(options, otlptracegrpc.WithInsecure())
+	}
+	exporter, err := otlptracegrpc.New(ctx, options...)
+	if err != nil {
+		return nil, err
+	}
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(samplingRatio))),
+		sdktrace.WithResource(resource.NewSchemaless(attribute.String("service.name", "eviction-autoscaler"))),
+	)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	return provider.Shutdown, nil
+}
+
+// Span runs fn in a child span of ctx named name and records its error.
+func Span(ctx context.Context, name string, fn func(ctx context.Context) error, attributes ...attribute.KeyValue) error {
+	ctx, span := Tracer.Start(ctx, name, trace.WithAttributes(attributes...))
+	err := fn(ctx)
+	End(span, err)
+	return err
+}
+
+// End ends span, marking it failed if err is set.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// Decide records decision on the span in ctx.
+func Decide(ctx context.Context, decision string) {
+	trace.SpanFromContext(ctx).SetAttributes(DecisionKey.String(decision))
+}
@@ -0,0 +1,63 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestSpan(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	defer otel.SetTracerProvider(previous)
+
+	ctx, parent := Tracer.Start(context.Background(), "Reconcile")
+	Decide(ctx, "scale-up")
+	failed := errors.New("conflict")
+	if err := Span(ctx, "ScaleTarget", func(context.Context) error { return failed }, ReplicasKey.Int(3)); err != failed {
+		t.Errorf("got %v from Span, want fn's error", err)
+	}
+	End(parent, nil)
+
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("got %d spans, want 2", len(spans))
+	}
+	child, root := spans[0], spans[1]
+	if child.Name() != "ScaleTarget" || child.Parent().SpanID() != root.SpanContext().SpanID() {
+		t.Errorf("got child %s with parent %s, want ScaleTarget under %s", child.Name(), child.Parent().SpanID(), root.SpanContext().SpanID())
+	}
+	if child.Status().Code != codes.Error || len(child.Events()) != 1 {
+		t.Errorf("got status %v and %d events, want the error recorded", child.Status(), len(child.Events()))
+	}
+	if got := child.Attributes(); len(got) != 1 || got[0] != ReplicasKey.Int(3) {
+		t.Errorf("got attributes %v on ScaleTarget", got)
+	}
+	if got := root.Attributes(); len(got) != 1 || got[0] != DecisionKey.String("scale-up") {
+		t.Errorf("got attributes %v on Reconcile, want the decision", got)
+	}
+	if root.Status().Code != codes.Unset {
+		t.Errorf("got status %v on a span that didn't fail", root.Status())
+	}
+}
+
+func TestSetupWithoutEndpoint(t *testing.T) {
+	previous := otel.GetTracerProvider()
+	shutdown, err := Setup(context.Background(), "", false, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if otel.GetTracerProvider() != previous {
+		t.Error("Setup without an endpoint replaced the tracer provider")
+	}
+	if err := shutdown(context.Background()); err != nil {
+		t.Error(err)
+	}
+}
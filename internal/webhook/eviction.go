@@ -2,12 +2,23 @@ package webhook
 
 import (
 	"context"
+	"fmt"
 	"net/http"
+	"time"
 
 	pdbautoscaler "github.com/azure/eviction-autoscaler/api/v1"
+	controllers "github.com/azure/eviction-autoscaler/internal/controller"
+	"github.com/azure/eviction-autoscaler/internal/events"
+	"github.com/azure/eviction-autoscaler/internal/evictionutil"
+	"github.com/azure/eviction-autoscaler/internal/metrics"
+	"github.com/azure/eviction-autoscaler/internal/namespacefilter"
 	"github.com/azure/eviction-autoscaler/internal/podutil"
+	"github.com/azure/eviction-autoscaler/internal/selectorcache"
+	admissionv1 "k8s.io/api/admission/v1"
 	corev1 "k8s.io/api/core/v1"
 	policyv1 "k8s.io/api/policy/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	"k8s.io/apimachinery/pkg/labels"
@@ -17,101 +28,263 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
 )
 
+// DefaultEvictionTimeout is how long an eviction may wait on us before we let it through unrecorded.
+const DefaultEvictionTimeout = time.Second
+
 type EvictionHandler struct {
-	Client  client.Client
+	Client client.Client
+	// Namespaces excludes evictions in namespaces we must not touch. Nil allows all.
+	Namespaces *namespacefilter.Filter
+	// Selectors caches compiled pdb selectors across evictions. Nil compiles them every time.
+	Selectors *selectorcache.Cache
+	// DryRun logs and counts pod condition and eviction writes instead of making them.
+	DryRun bool
+	// PodConditionWrites is shared with the node controller, leaving DisruptionTarget alone when pod condition writes
+	// are disabled or were forbidden. Nil always writes.
+	PodConditionWrites *controllers.PodConditionWrites
+	// Timeout bounds how long we hold up an eviction. Zero uses DefaultEvictionTimeout.
+	Timeout time.Duration
+	// WaitForSurge denies evictions with a 429 while the matching EvictionAutoScaler's surge isn't available yet,
+	// for at most this long after it surged so a surge that never comes up can't wedge a drain. Zero only watches.
+	WaitForSurge time.Duration
+	// DefaultCooldown is the cooldown of EvictionAutoScalers without spec.cooldownSeconds, from --cooldown.
+	// It is the Retry-After of delayed evictions. Zero uses the controller's default.
+	DefaultCooldown time.Duration
+	// Global is the EvictionAutoScalerConfig, whose cooldown wins over DefaultCooldown. Nil has none.
+	Global  *controllers.GlobalConfig
 	decoder *admission.Decoder
 }
 
-// this webhook updates the EvictionAutoScaler's spec if there is a newish (configurable) eviction to cause a reconcile and see if we need to scale up
+// Handle records evictions the pdb blocks (or will block) in the matching EvictionAutoScaler's status.lastEviction
+// which requeues it to surge. This catches drains that never cordon, like the descheduler or kubectl evict.
+// Evictions are allowed, the api server's pdb check decides if they go through, we only watch. The exception is
+// WaitForSurge which holds them back till the surge they triggered is available.
 func (e *EvictionHandler) Handle(ctx context.Context, req admission.Request) admission.Response {
+	start := time.Now()
+	resp, outcome := e.handle(ctx, req)
+	// the only evictions we deny are the ones WaitForSurge holds back
+	if !resp.Allowed {
+		outcome = metrics.WebhookEvictionDelayed
+	}
+	metrics.WebhookEvictionCounter.WithLabelValues(req.Namespace, outcome).Inc()
+	metrics.WebhookEvictionDuration.WithLabelValues(outcome).Observe(time.Since(start).Seconds())
+	return resp
+}
 
+// handle decides on the eviction in req for Handle, returning the outcome label of the metrics along with it.
+func (e *EvictionHandler) handle(ctx context.Context, req admission.Request) (admission.Response, string) {
 	logger := log.FromContext(ctx)
 
 	logger.Info("Received eviction request", "namespace", req.Namespace, "podname", req.Name)
 
-	currentEviction := pdbautoscaler.Eviction{
+	if e.Namespaces.Skip(ctx, req.Namespace, "webhook") {
+		return admission.Allowed("namespace excluded"), metrics.WebhookEvictionSkipped
+	}
+	if req.DryRun != nil && *req.DryRun {
+		return admission.Allowed("dry run eviction"), metrics.WebhookEvictionSkipped
+	}
+
+	timeout := e.Timeout
+	if timeout <= 0 {
+		timeout = DefaultEvictionTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	currentEviction := pdbautoscaler.EvictionRecord{
 		PodName:      req.Name,
 		EvictionTime: metav1.Now(),
+		Source:       pdbautoscaler.EvictionSourceEvictionAPI,
 	}
 
 	// Fetch the pod to get its labels
 	pod := &corev1.Pod{}
 	err := e.Client.Get(ctx, types.NamespacedName{Namespace: req.Namespace, Name: req.Name}, pod)
 	if err != nil {
-		logger.Error(err, "Error: Unable to fetch Pod")
-		return admission.Errored(http.StatusInternalServerError, err)
+		logger.Error(err, "Error: Unable to fetch Pod, not recording eviction")
+		return admission.Allowed("pod not found"), metrics.WebhookEvictionError
 	}
 
 	podObj := pod.DeepCopy()
 
-	// List all EvictionAutoScalers in the namespace. Is this expensive for every eviction are we cacching this list and pdbs?
-	EvictionAutoScalerList := &pdbautoscaler.EvictionAutoScalerList{}
-	err = e.Client.List(ctx, EvictionAutoScalerList, &client.ListOptions{Namespace: req.Namespace})
+	applicableEvictionAutoScaler, applicablePDB, matches, err := matchEvictionAutoScaler(ctx, e.Client, e.Selectors, pod)
 	if err != nil {
-		logger.Error(err, "Error: Unable to list EvictionAutoScalers")
-		return admission.Errored(http.StatusInternalServerError, err)
+		logger.Error(err, "Error: Unable to list EvictionAutoScalers, not recording eviction")
+		return admission.Allowed("unable to list EvictionAutoScalers"), metrics.WebhookEvictionError
 	}
-
-	// Find the applicable EvictionAutoScaler
-	var applicableEvictionAutoScaler *pdbautoscaler.EvictionAutoScaler
-	for _, EvictionAutoScaler := range EvictionAutoScalerList.Items {
-		// Fetch the associated PDB
-		pdb := &policyv1.PodDisruptionBudget{}
-		err := e.Client.Get(ctx, types.NamespacedName{Name: EvictionAutoScaler.Name, Namespace: EvictionAutoScaler.Namespace}, pdb)
-		if err != nil {
-			logger.Error(err, "Error: Unable to fetch PDB:", "pdbname", EvictionAutoScaler.Name)
-			return admission.Errored(http.StatusInternalServerError, err)
-		}
-
-		// Check if the PDB selector matches the evicted pod's labels
-		selector, err := metav1.LabelSelectorAsSelector(pdb.Spec.Selector)
-		if err != nil {
-			logger.Error(err, "Error: Invalid PDB selector", "pdbname", EvictionAutoScaler.Name)
-			continue
-		}
-
-		if selector.Matches(labels.Set(pod.Labels)) {
-			applicableEvictionAutoScaler = &EvictionAutoScaler
-			break
-		}
-	}
-
 	if applicableEvictionAutoScaler == nil {
 		logger.Info("No applicable EvictionAutoScaler found")
-		return admission.Allowed("no applicable EvictionAutoScaler")
+		return admission.Allowed("no applicable EvictionAutoScaler"), metrics.WebhookEvictionUnmanaged
+	}
+	if applicableEvictionAutoScaler.Spec.Suspend {
+		logger.V(1).Info("EvictionAutoScaler is suspended", "name", applicableEvictionAutoScaler.Name)
+		return admission.Allowed("EvictionAutoScaler is suspended"), metrics.WebhookEvictionUnmanaged
+	}
+	// the node controller sets ConflictingSelectors, we are too short on time to write it here
+	if matches > 1 {
+		metrics.ConflictingSelectorsCounter.WithLabelValues(pod.Namespace).Inc()
+		logger.Info("Several EvictionAutoScalers select the evicted pod, recording on the first by precedence", "matches", matches, "name", applicableEvictionAutoScaler.Name)
 	}
 
 	logger.Info("Found EvictionAutoScaler", "name", applicableEvictionAutoScaler.Name)
 
-	updatedpod := podutil.UpdatePodCondition(&podObj.Status, &corev1.PodCondition{
+	// the api server lets it go whatever disruptionsAllowed says, a surge for it would be wasted
+	if evictionutil.UnhealthyEvictionAllowed(applicablePDB, pod) {
+		logger.V(1).Info("PDB allows evicting the unhealthy pod", "pdbname", applicablePDB.Name)
+		return admission.Allowed("unhealthy pod eviction allowed by pdb"), metrics.WebhookEvictionUnhealthy
+	}
+
+	// evictions the pdb lets through don't need a surge. Still record them while one is in flight
+	// (an eviction not yet handled) so the cooldown runs from the last eviction of the drain.
+	blocked := applicablePDB.Status.DisruptionsAllowed == 0
+	surging := applicableEvictionAutoScaler.Status.LastEviction != applicableEvictionAutoScaler.Status.HandledEviction
+	outcome := metrics.WebhookEvictionPassed
+	if blocked {
+		outcome = metrics.WebhookEvictionBlocked
+	}
+	if !blocked && !surging {
+		logger.V(1).Info("Eviction not blocked by pdb", "pdbname", applicablePDB.Name, "disruptionsAllowed", applicablePDB.Status.DisruptionsAllowed)
+		return e.allowOrDelay(ctx, applicableEvictionAutoScaler, pod, "eviction not blocked"), outcome
+	}
+
+	disruptionTarget := &corev1.PodCondition{
 		Type:    corev1.DisruptionTarget,
 		Status:  corev1.ConditionTrue,
 		Reason:  "EvictionAttempt",
 		Message: "eviction attempt recorded by eviction webhook",
-	})
-	if updatedpod {
-		if err := e.Client.Status().Update(ctx, podObj); err != nil {
+	}
+	updatedpod := !e.PodConditionWrites.Off() && podutil.UpdatePodCondition(&podObj.Status, disruptionTarget)
+	if updatedpod && e.DryRun {
+		events.DryRun(ctx, nil, podObj, metrics.DryRunSetPodCondition, "set DisruptionTarget on pod %s/%s for eviction", podObj.Namespace, podObj.Name)
+	} else if updatedpod {
+		_, err := controllers.ApplyPodConditions(ctx, e.Client, podObj, false, *disruptionTarget)
+		if apierrors.IsForbidden(err) {
+			e.PodConditionWrites.Forbid(ctx, err)
+		} else if apierrors.IsConflict(err) {
+			// a real eviction's DisruptionTarget is there already
+			logger.V(1).Info("DisruptionTarget owned by another manager, leaving it", "podName", podObj.Name)
+		} else if err != nil {
 			logger.Error(err, "Error: Unable to update Pod status")
 			//don't fail yet still want to try and update the EvictionAutoScaler
 		}
 	}
 
 	// want to rate limit on mass evictions but also if we slow down too much we may miss last eviction and not scale down.
-	//if applicableEvictionAutoScaler.Spec.LastEviction.EvictionTime.Time.Sub(currentEviction.EvictionTime.Time) < time.Second {
+	//if applicableEvictionAutoScaler.Status.LastEviction.EvictionTime.Time.Sub(currentEviction.EvictionTime.Time) < time.Second {
 	//	return admission.Allowed("eviction allowed")
 	//}
 
-	applicableEvictionAutoScaler.Spec.LastEviction = currentEviction
+	if e.DryRun {
+		events.DryRun(ctx, nil, applicableEvictionAutoScaler, metrics.DryRunRecordEviction, "record eviction of pod %s", req.Name)
+		return e.allowOrDelay(ctx, applicableEvictionAutoScaler, pod, "eviction allowed"), outcome
+	}
 
-	err = e.Client.Update(ctx, applicableEvictionAutoScaler)
+	currentEviction.Node = pod.Spec.NodeName
+	_, err = evictionutil.RecordEviction(ctx, e.Client, client.ObjectKeyFromObject(applicableEvictionAutoScaler), currentEviction)
 	if err != nil {
-		//handle conflicts when many evictions happen in parallel? or doesn't matter if we lose the conficts
+		// the EvictionAutoScaler may be gone or we ran out of time. Either way don't hold up the eviction,
+		// the next one will be recorded.
 		logger.Error(err, "Unable to update EvictionAutoScaler status")
-		return admission.Errored(http.StatusInternalServerError, err) //Is this a problem if webhook doesn't ignore failures?
+		return e.allowOrDelay(ctx, applicableEvictionAutoScaler, pod, "eviction not recorded"), outcome
+	}
+
+	metrics.EvictionCounter.WithLabelValues(pod.Namespace, metrics.EvictionSourceLabel(currentEviction.Source),
+		controllers.EvictionTargetKind(applicableEvictionAutoScaler)).Inc()
+	logger.Info("Eviction logged successfully", "podName", req.Name, "evictionTime", currentEviction.EvictionTime, "blocked", blocked)
+	return e.allowOrDelay(ctx, applicableEvictionAutoScaler, pod, "eviction allowed"), outcome
+}
+
+// matchEvictionAutoScaler returns the EvictionAutoScaler whose pdb, or spec.podSelector, selects pod along with
+// that pdb, by evictionutil.Applicable's precedence if several do, and how many did. Nil if none do.
+func matchEvictionAutoScaler(ctx context.Context, c client.Client, selectors *selectorcache.Cache, pod *corev1.Pod) (*pdbautoscaler.EvictionAutoScaler, *policyv1.PodDisruptionBudget, int, error) {
+	logger := log.FromContext(ctx)
+	// List all EvictionAutoScalers in the namespace. Is this expensive for every eviction are we cacching this list and pdbs?
+	EvictionAutoScalerList := &pdbautoscaler.EvictionAutoScalerList{}
+	if err := c.List(ctx, EvictionAutoScalerList, &client.ListOptions{Namespace: pod.Namespace}); err != nil {
+		return nil, nil, 0, err
+	}
+
+	var matches []*pdbautoscaler.EvictionAutoScaler
+	pdbs := map[string]*policyv1.PodDisruptionBudget{}
+	for i := range EvictionAutoScalerList.Items {
+		EvictionAutoScaler := &EvictionAutoScalerList.Items[i]
+		// Fetch the associated PDB, spec.podSelector goes without one
+		pdb := evictionutil.PodSelectorPDB(EvictionAutoScaler)
+		if pdb == nil {
+			pdb = &policyv1.PodDisruptionBudget{}
+			if err := c.Get(ctx, types.NamespacedName{Name: EvictionAutoScaler.PDBName(), Namespace: EvictionAutoScaler.Namespace}, pdb); err != nil {
+				// no pdb is reported by the EvictionAutoScaler controller, anything else and we are out of time anyways
+				logger.V(1).Info("Unable to fetch PDB", "pdbname", EvictionAutoScaler.PDBName(), "error", err.Error())
+				continue
+			}
+		}
+
+		// Check if the PDB selector matches the pod's labels
+		selector, err := selectors.Selector(pdb)
+		if err != nil {
+			logger.Error(err, "Error: Invalid PDB selector", "pdbname", pdb.Name)
+			continue
+		}
+
+		if selector.Matches(labels.Set(pod.Labels)) {
+			matches = append(matches, EvictionAutoScaler)
+			pdbs[EvictionAutoScaler.Name] = pdb
+		}
+	}
+
+	applicable := evictionutil.Applicable(matches)
+	if applicable == nil {
+		return nil, nil, 0, nil
 	}
+	return applicable, pdbs[applicable.Name], len(matches), nil
+}
 
-	logger.Info("Eviction logged successfully", "podName", req.Name, "evictionTime", currentEviction.EvictionTime)
-	return admission.Allowed("eviction allowed")
+// allowOrDelay allows the eviction of pod with reason unless WaitForSurge holds it back, in which case it is denied
+// like the api server does when the pdb blocks it: a 429 with a Retry-After, so drains keep retrying it.
+func (e *EvictionHandler) allowOrDelay(ctx context.Context, EvictionAutoScaler *pdbautoscaler.EvictionAutoScaler, pod *corev1.Pod, reason string) admission.Response {
+	retryAfter, message := e.surgePending(ctx, EvictionAutoScaler)
+	if retryAfter <= 0 {
+		return admission.Allowed(reason)
+	}
+	if e.DryRun {
+		events.DryRun(ctx, nil, EvictionAutoScaler, metrics.DryRunDelayEviction, "delay eviction of pod %s, %s", pod.Name, message)
+		return admission.Allowed(reason)
+	}
+	metrics.EvictionDelayedCounter.WithLabelValues(pod.Namespace, metrics.EvictionDelayed).Inc()
+	log.FromContext(ctx).Info("Delaying eviction till the surge is available", "podName", pod.Name, "name", EvictionAutoScaler.Name, "retryAfter", retryAfter)
+	seconds := int32((retryAfter + time.Second - 1) / time.Second)
+	return admission.Response{AdmissionResponse: admissionv1.AdmissionResponse{
+		Allowed: false,
+		Result: &metav1.Status{
+			Status:  metav1.StatusFailure,
+			Code:    http.StatusTooManyRequests,
+			Reason:  metav1.StatusReasonTooManyRequests,
+			Message: fmt.Sprintf("Cannot evict pod as EvictionAutoScaler %s is waiting for its surge: %s.", EvictionAutoScaler.Name, message),
+			Details: &metav1.StatusDetails{RetryAfterSeconds: seconds},
+		},
+	}}
+}
+
+// surgePending returns how long an eviction should wait on EvictionAutoScaler's surge and why, zero if it shouldn't:
+// WaitForSurge is off, there is no surge, the controller has seen it available (status.surgeReady) or it surged longer
+// than WaitForSurge ago. Surges the controller can't judge, SurgeReady Unknown for targetRef targets, don't wait.
+// The wait is the cooldown, the controller's requeue, cut short by the WaitForSurge deadline.
+func (e *EvictionHandler) surgePending(ctx context.Context, EvictionAutoScaler *pdbautoscaler.EvictionAutoScaler) (time.Duration, string) {
+	status := &EvictionAutoScaler.Status
+	surged := meta.FindStatusCondition(status.Conditions, controllers.ConditionScalingUp)
+	pending := meta.FindStatusCondition(status.Conditions, controllers.ConditionSurgeReady)
+	if e.WaitForSurge <= 0 || surged == nil || surged.Status != metav1.ConditionTrue || status.SurgeReplicas <= 0 ||
+		status.SurgeReady || pending == nil || pending.Status != metav1.ConditionFalse {
+		return 0, ""
+	}
+	if remaining := e.WaitForSurge - time.Since(surged.LastTransitionTime.Time); remaining > 0 {
+		cooldown := controllers.Config{Cooldown: e.DefaultCooldown, Global: e.Global}.CooldownFor(EvictionAutoScaler)
+		return min(cooldown, remaining), pending.Message
+	}
+	metrics.EvictionDelayedCounter.WithLabelValues(EvictionAutoScaler.Namespace, metrics.EvictionDelayedTimedOut).Inc()
+	log.FromContext(ctx).Info("Surge not available in time, no longer delaying evictions", "name", EvictionAutoScaler.Name,
+		"waitForSurge", e.WaitForSurge, "reason", pending.Reason, "message", pending.Message)
+	return 0, ""
 }
 
 // what the heck does this do
@@ -0,0 +1,350 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"testing"
+	"time"
+
+	pdbautoscaler "github.com/azure/eviction-autoscaler/api/v1"
+	controllers "github.com/azure/eviction-autoscaler/internal/controller"
+	"github.com/azure/eviction-autoscaler/internal/metrics"
+	"github.com/azure/eviction-autoscaler/internal/podutil"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+func TestEvictionHandlerRecordsBlockedEvictions(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = pdbautoscaler.AddToScheme(scheme)
+
+	pod := func(name, app string) *corev1.Pod {
+		return &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", Labels: map[string]string{"app": app}}}
+	}
+	pdb := func(name, app string, disruptionsAllowed int32) *policyv1.PodDisruptionBudget {
+		return &policyv1.PodDisruptionBudget{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+			Spec:       policyv1.PodDisruptionBudgetSpec{Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": app}}},
+			Status:     policyv1.PodDisruptionBudgetStatus{DisruptionsAllowed: disruptionsAllowed},
+		}
+	}
+	scaler := func(name string) *pdbautoscaler.EvictionAutoScaler {
+		return &pdbautoscaler.EvictionAutoScaler{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"}}
+	}
+	// both pdbs select pod shared-1, the oldest EvictionAutoScaler wins even though it sorts last by name
+	created := func(EvictionAutoScaler *pdbautoscaler.EvictionAutoScaler, age time.Duration) *pdbautoscaler.EvictionAutoScaler {
+		EvictionAutoScaler.CreationTimestamp = metav1.NewTime(time.Now().Add(-age))
+		return EvictionAutoScaler
+	}
+	// crashlooping, which its pdb lets go even while it blocks the healthy ones
+	unhealthy := pod("worker-1", "worker")
+	unhealthy.Status.Conditions = []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionFalse}}
+	alwaysAllow := pdb("worker", "worker", 0)
+	alwaysAllow.Spec.UnhealthyPodEvictionPolicy = ptr.To(policyv1.AlwaysAllow)
+	dryRun := true
+
+	tests := []struct {
+		name     string
+		pod      string
+		dryRun   *bool
+		recorded string // EvictionAutoScaler that should have the eviction, empty for none
+		outcome  string
+	}{
+		{name: "blocked", pod: "web-1", recorded: "web", outcome: metrics.WebhookEvictionBlocked},
+		{name: "allowed by pdb", pod: "db-1", outcome: metrics.WebhookEvictionPassed},
+		{name: "no EvictionAutoScaler", pod: "cache-1", outcome: metrics.WebhookEvictionUnmanaged},
+		{name: "pod gone", pod: "missing", outcome: metrics.WebhookEvictionError},
+		{name: "dry run eviction", pod: "web-1", dryRun: &dryRun, outcome: metrics.WebhookEvictionSkipped},
+		{name: "conflicting selectors", pod: "shared-1", recorded: "z-old", outcome: metrics.WebhookEvictionBlocked},
+		{name: "unhealthy pod always allowed", pod: "worker-1", outcome: metrics.WebhookEvictionUnhealthy},
+	}
+	for _, test := range tests {
+		c := fake.NewClientBuilder().WithScheme(scheme).
+			WithObjects(pod("web-1", "web"), pod("db-1", "db"), pod("cache-1", "cache"),
+				pdb("web", "web", 0), pdb("db", "db", 1), pdb("cache", "cache", 0), scaler("web"), scaler("db"),
+				pod("shared-1", "shared"), pdb("a-new", "shared", 0), pdb("z-old", "shared", 0),
+				created(scaler("a-new"), time.Minute), created(scaler("z-old"), time.Hour),
+				unhealthy, alwaysAllow, scaler("worker")).
+			WithStatusSubresource(&corev1.Pod{}, &pdbautoscaler.EvictionAutoScaler{}).
+			Build()
+		handler := &EvictionHandler{Client: c}
+		evictions, answered := webhookEvictions(t, test.outcome)
+		counted := recordedEvictions(t)
+		resp := handler.Handle(context.Background(), admission.Request{AdmissionRequest: admissionv1.AdmissionRequest{
+			Operation: admissionv1.Create,
+			Namespace: "default",
+			Name:      test.pod,
+			DryRun:    test.dryRun,
+		}})
+		if !resp.Allowed {
+			t.Errorf("%s: eviction denied %v", test.name, resp.Result)
+		}
+		if gotEvictions, gotAnswered := webhookEvictions(t, test.outcome); gotEvictions != evictions+1 || gotAnswered != answered+1 {
+			t.Errorf("%s: got %v evictions and %d timings with outcome %s, want one more of each than %v and %d",
+				test.name, gotEvictions, gotAnswered, test.outcome, evictions, answered)
+		}
+		// counted once recorded, not again when the EvictionAutoScaler reconciles it
+		want := counted
+		if test.recorded != "" {
+			want++
+		}
+		if got := recordedEvictions(t); got != want {
+			t.Errorf("%s: got %v evictions counted, want %v", test.name, got, want)
+		}
+		for _, name := range []string{"web", "db", "a-new", "z-old", "worker"} {
+			EvictionAutoScaler := &pdbautoscaler.EvictionAutoScaler{}
+			if err := c.Get(context.Background(), client.ObjectKey{Namespace: "default", Name: name}, EvictionAutoScaler); err != nil {
+				t.Fatal(err)
+			}
+			got := EvictionAutoScaler.Status.LastEviction.PodName
+			if name == test.recorded && got != test.pod {
+				t.Errorf("%s: %s lastEviction %q want %q", test.name, name, got, test.pod)
+			} else if name != test.recorded && got != "" {
+				t.Errorf("%s: %s recorded unexpected eviction of %q", test.name, name, got)
+			}
+		}
+	}
+}
+
+func TestEvictionHandlerWaitsForSurge(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = pdbautoscaler.AddToScheme(scheme)
+
+	cooldown := int32(30)
+	tests := []struct {
+		name         string
+		waitForSurge time.Duration
+		surgedAgo    time.Duration
+		surgeReady   metav1.ConditionStatus
+		dryRun       bool
+		retryAfter   int32 // zero for allowed
+	}{
+		{name: "surge pending", waitForSurge: 5 * time.Minute, surgedAgo: 10 * time.Second, surgeReady: metav1.ConditionFalse, retryAfter: 30},
+		{name: "surge ready", waitForSurge: 5 * time.Minute, surgedAgo: 10 * time.Second, surgeReady: metav1.ConditionTrue},
+		{name: "availability unknown", waitForSurge: 5 * time.Minute, surgedAgo: 10 * time.Second, surgeReady: metav1.ConditionUnknown},
+		{name: "near timeout", waitForSurge: 5 * time.Minute, surgedAgo: 4*time.Minute + 50*time.Second, surgeReady: metav1.ConditionFalse, retryAfter: 10},
+		{name: "timed out", waitForSurge: 5 * time.Minute, surgedAgo: 10 * time.Minute, surgeReady: metav1.ConditionFalse},
+		{name: "disabled", surgedAgo: 10 * time.Second, surgeReady: metav1.ConditionFalse},
+		{name: "dry run", waitForSurge: 5 * time.Minute, surgedAgo: 10 * time.Second, surgeReady: metav1.ConditionFalse, dryRun: true},
+	}
+	for _, test := range tests {
+		pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "web-1", Namespace: "default", Labels: map[string]string{"app": "web"}}}
+		pdb := &policyv1.PodDisruptionBudget{
+			ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+			Spec:       policyv1.PodDisruptionBudgetSpec{Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}}},
+		}
+		EvictionAutoScaler := &pdbautoscaler.EvictionAutoScaler{
+			ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+			Spec:       pdbautoscaler.EvictionAutoScalerSpec{CooldownSeconds: &cooldown},
+			Status: pdbautoscaler.EvictionAutoScalerStatus{
+				MinReplicas:   3,
+				SurgeReplicas: 4,
+				SurgeReady:    test.surgeReady == metav1.ConditionTrue,
+				Conditions: []metav1.Condition{{Type: "ScalingUp", Status: metav1.ConditionTrue, Reason: "Surged",
+					LastTransitionTime: metav1.NewTime(time.Now().Add(-test.surgedAgo))},
+					{Type: "SurgeReady", Status: test.surgeReady, Reason: "SurgePending", Message: "3 of 4 replicas of deployment web available"}},
+			},
+		}
+		c := fake.NewClientBuilder().WithScheme(scheme).
+			WithObjects(pod, pdb, EvictionAutoScaler).
+			WithStatusSubresource(&corev1.Pod{}, &pdbautoscaler.EvictionAutoScaler{}).
+			Build()
+		handler := &EvictionHandler{Client: c, WaitForSurge: test.waitForSurge, DryRun: test.dryRun}
+		delayed, _ := webhookEvictions(t, metrics.WebhookEvictionDelayed)
+		resp := handler.Handle(context.Background(), admission.Request{AdmissionRequest: admissionv1.AdmissionRequest{
+			Operation: admissionv1.Create,
+			Namespace: "default",
+			Name:      "web-1",
+		}})
+		if test.retryAfter == 0 {
+			if !resp.Allowed {
+				t.Errorf("%s: eviction denied %v", test.name, resp.Result)
+			}
+			continue
+		}
+		if resp.Allowed || resp.Result == nil || resp.Result.Code != http.StatusTooManyRequests || resp.Result.Reason != metav1.StatusReasonTooManyRequests {
+			t.Fatalf("%s: got allowed %v result %v want a 429", test.name, resp.Allowed, resp.Result)
+		}
+		if got, _ := webhookEvictions(t, metrics.WebhookEvictionDelayed); got != delayed+1 {
+			t.Errorf("%s: got %v delayed evictions want %v", test.name, got, delayed+1)
+		}
+		if got := resp.Result.Details.RetryAfterSeconds; got < test.retryAfter-1 || got > test.retryAfter {
+			t.Errorf("%s: retry after %ds want %ds", test.name, got, test.retryAfter)
+		}
+		// the denied eviction is still recorded so the cooldown runs from it
+		if err := c.Get(context.Background(), client.ObjectKeyFromObject(EvictionAutoScaler), EvictionAutoScaler); err != nil {
+			t.Fatal(err)
+		}
+		if EvictionAutoScaler.Status.LastEviction.PodName != "web-1" {
+			t.Errorf("%s: eviction not recorded", test.name)
+		}
+	}
+}
+
+// TestEvictionHandlerPodConditionWrites checks the webhook applies DisruptionTarget as the node controller does,
+// honours the pod condition writes it shares with it, stopping them after the first Forbidden one, and leaves a
+// DisruptionTarget another manager owns alone.
+func TestEvictionHandlerPodConditionWrites(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = pdbautoscaler.AddToScheme(scheme)
+
+	for _, test := range []struct {
+		name      string
+		disabled  bool
+		err       func(name string) error // returned by the first pod status apply
+		writes    int
+		condition bool // on web-1, whose write the error is for
+		off       bool
+	}{
+		{name: "applied", writes: 2, condition: true},
+		{name: "forbidden", err: func(name string) error {
+			return apierrors.NewForbidden(corev1.Resource("pods/status"), name, fmt.Errorf("no patch on pods/status"))
+		}, writes: 1, off: true},
+		{name: "disabled", disabled: true, off: true},
+		{name: "owned by another manager", err: func(name string) error {
+			return apierrors.NewConflict(corev1.Resource("pods"), name, fmt.Errorf("conflict with \"kube-controller-manager\""))
+		}, writes: 2},
+	} {
+		pod := func(name string) *corev1.Pod {
+			return &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", Labels: map[string]string{"app": "web"}}}
+		}
+		writes := 0
+		c := fake.NewClientBuilder().WithScheme(scheme).
+			WithObjects(pod("web-1"), pod("web-2"),
+				&policyv1.PodDisruptionBudget{
+					ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+					Spec:       policyv1.PodDisruptionBudgetSpec{Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}}},
+				},
+				&pdbautoscaler.EvictionAutoScaler{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"}}).
+			WithStatusSubresource(&corev1.Pod{}, &pdbautoscaler.EvictionAutoScaler{}).
+			WithInterceptorFuncs(interceptor.Funcs{
+				SubResourceUpdate: func(ctx context.Context, c client.Client, subResourceName string, obj client.Object, opts ...client.SubResourceUpdateOption) error {
+					if _, isPod := obj.(*corev1.Pod); isPod {
+						t.Errorf("%s: pod status of %s updated, want it applied", test.name, obj.GetName())
+					}
+					return c.SubResource(subResourceName).Update(ctx, obj, opts...)
+				},
+				SubResourcePatch: func(ctx context.Context, c client.Client, subResourceName string, obj client.Object, patch client.Patch, opts ...client.SubResourcePatchOption) error {
+					writes++
+					if writes == 1 && test.err != nil {
+						return test.err(obj.GetName())
+					}
+					return applyPodStatus(t, ctx, c, obj, patch, opts...)
+				},
+			}).
+			Build()
+		podConditionWrites := &controllers.PodConditionWrites{Disabled: test.disabled}
+		handler := &EvictionHandler{Client: c, PodConditionWrites: podConditionWrites}
+		for _, name := range []string{"web-1", "web-2"} {
+			resp := handler.Handle(context.Background(), admission.Request{AdmissionRequest: admissionv1.AdmissionRequest{
+				Operation: admissionv1.Create,
+				Namespace: "default",
+				Name:      name,
+			}})
+			if !resp.Allowed {
+				t.Errorf("%s: eviction of %s denied %v", test.name, name, resp.Result)
+			}
+		}
+		if writes != test.writes {
+			t.Errorf("%s: got %d pod status writes, want %d", test.name, writes, test.writes)
+		}
+		if podConditionWrites.Off() != test.off {
+			t.Errorf("%s: got pod condition writes off %v, want %v", test.name, podConditionWrites.Off(), test.off)
+		}
+		written := &corev1.Pod{}
+		if err := c.Get(context.Background(), client.ObjectKey{Namespace: "default", Name: "web-1"}, written); err != nil {
+			t.Fatal(err)
+		}
+		if got := slices.ContainsFunc(written.Status.Conditions, func(condition corev1.PodCondition) bool {
+			return condition.Type == corev1.DisruptionTarget
+		}); got != test.condition {
+			t.Errorf("%s: got DisruptionTarget %v on web-1, want %v", test.name, got, test.condition)
+		}
+		EvictionAutoScaler := &pdbautoscaler.EvictionAutoScaler{}
+		if err := c.Get(context.Background(), client.ObjectKey{Namespace: "default", Name: "web"}, EvictionAutoScaler); err != nil {
+			t.Fatal(err)
+		}
+		if EvictionAutoScaler.Status.LastEviction.PodName != "web-2" {
+			t.Errorf("%s: got last eviction %q, want evictions still recorded", test.name, EvictionAutoScaler.Status.LastEviction.PodName)
+		}
+	}
+}
+
+// applyPodStatus stands in for the server-side apply of pod status the fake client can't do, setting the applied
+// conditions on the pod by type. It checks they are applied as the node controller's field manager, the
+// DisruptionTarget condition alone.
+func applyPodStatus(t *testing.T, ctx context.Context, c client.Client, obj client.Object, patch client.Patch, opts ...client.SubResourcePatchOption) error {
+	t.Helper()
+	applyConfig, ok := obj.(*unstructured.Unstructured)
+	if patch.Type() != types.ApplyPatchType || !ok {
+		t.Fatalf("got a %s patch of %s, want an apply", patch.Type(), obj.GetName())
+	}
+	if manager := (&client.SubResourcePatchOptions{}).ApplyOptions(opts).FieldManager; manager != controllers.FieldManager {
+		t.Errorf("got field manager %q, want %q", manager, controllers.FieldManager)
+	}
+	applied := &corev1.Pod{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(applyConfig.Object, applied); err != nil {
+		return err
+	}
+	if len(applied.Status.Conditions) != 1 || applied.Status.Conditions[0].Type != corev1.DisruptionTarget {
+		t.Errorf("got applied conditions %+v, want DisruptionTarget alone", applied.Status.Conditions)
+	}
+	pod := &corev1.Pod{}
+	if err := c.Get(ctx, client.ObjectKeyFromObject(obj), pod); err != nil {
+		return err
+	}
+	for _, condition := range applied.Status.Conditions {
+		podutil.UpdatePodCondition(&pod.Status, &condition)
+	}
+	if err := c.Status().Update(ctx, pod); err != nil {
+		return err
+	}
+	result, err := runtime.DefaultUnstructuredConverter.ToUnstructured(pod)
+	applyConfig.Object = result
+	return err
+}
+
+// recordedEvictions returns the evictions_total the webhook counted in namespace default for EvictionAutoScalers
+// without a target.
+func recordedEvictions(t *testing.T) float64 {
+	t.Helper()
+	counter := &dto.Metric{}
+	if err := metrics.EvictionCounter.WithLabelValues("default", metrics.EvictionSourceEvictionAPI,
+		metrics.TargetKindUnknown).Write(counter); err != nil {
+		t.Fatal(err)
+	}
+	return counter.GetCounter().GetValue()
+}
+
+// webhookEvictions returns the evictions in namespace default counted with outcome and how many of them were timed.
+func webhookEvictions(t *testing.T, outcome string) (float64, uint64) {
+	t.Helper()
+	counter := &dto.Metric{}
+	if err := metrics.WebhookEvictionCounter.WithLabelValues("default", outcome).Write(counter); err != nil {
+		t.Fatal(err)
+	}
+	histogram := &dto.Metric{}
+	if err := metrics.WebhookEvictionDuration.WithLabelValues(outcome).(prometheus.Histogram).Write(histogram); err != nil {
+		t.Fatal(err)
+	}
+	return counter.GetCounter().GetValue(), histogram.GetHistogram().GetSampleCount()
+}
@@ -147,8 +147,9 @@ var _ = Describe("Evictions webhook", func() {
 			EvictionAutoScaler := &v1.EvictionAutoScaler{}
 			err = k8sClient.Get(ctx, typeNamespacedName, EvictionAutoScaler)
 			Expect(err).NotTo(HaveOccurred())
-			Expect(EvictionAutoScaler.Spec.LastEviction.EvictionTime).ToNot(BeZero())
-			Expect(EvictionAutoScaler.Spec.LastEviction.PodName).To(Equal(podName))
+			Expect(EvictionAutoScaler.Status.LastEviction.EvictionTime).ToNot(BeZero())
+			Expect(EvictionAutoScaler.Status.LastEviction.PodName).To(Equal(podName))
+			Expect(EvictionAutoScaler.Status.LastEviction.Source).To(Equal(v1.EvictionSourceEvictionAPI))
 
 			By("checking pod condition ")
 
@@ -0,0 +1,229 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	pdbautoscaler "github.com/azure/eviction-autoscaler/api/v1"
+	admissionv1 "k8s.io/api/admission/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/client-go/discovery"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// maxClockSkew is how far in the future an eviction time may be before we call it broken.
+const maxClockSkew = time.Minute
+
+// EvictionAutoScalerValidator rejects EvictionAutoScalers that would only ever show up as reconcile errors.
+type EvictionAutoScalerValidator struct {
+	Client client.Client
+	// Discovery tells us if a targetRef kind has a scale subresource. Nil skips that check.
+	Discovery discovery.ServerResourcesInterface
+}
+
+func (v *EvictionAutoScalerValidator) Handle(ctx context.Context, req admission.Request) admission.Response {
+	logger := log.FromContext(ctx)
+	if req.Operation != admissionv1.Create && req.Operation != admissionv1.Update {
+		return admission.Allowed("")
+	}
+
+	EvictionAutoScaler := &pdbautoscaler.EvictionAutoScaler{}
+	if err := json.Unmarshal(req.Object.Raw, EvictionAutoScaler); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+
+	errs, err := v.validate(ctx, EvictionAutoScaler)
+	if err != nil {
+		logger.Error(err, "Unable to validate EvictionAutoScaler", "namespace", req.Namespace, "name", req.Name)
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+	if len(errs) > 0 {
+		logger.Info("Rejected EvictionAutoScaler", "namespace", req.Namespace, "name", req.Name, "errors", errs)
+		return admission.Denied(errs.ToAggregate().Error())
+	}
+	return admission.Allowed("")
+}
+
+// validate returns what is wrong with EvictionAutoScaler. err is only for failing to look things up.
+func (v *EvictionAutoScalerValidator) validate(ctx context.Context, EvictionAutoScaler *pdbautoscaler.EvictionAutoScaler) (field.ErrorList, error) {
+	var errs field.ErrorList
+	spec := EvictionAutoScaler.Spec
+	specPath := field.NewPath("spec")
+
+	if spec.CooldownSeconds != nil && *spec.CooldownSeconds < 0 {
+		errs = append(errs, field.Invalid(specPath.Child("cooldownSeconds"), *spec.CooldownSeconds, "must not be negative"))
+	}
+	if spec.ScaleDownStabilizationSeconds != nil && *spec.ScaleDownStabilizationSeconds < 0 {
+		errs = append(errs, field.Invalid(specPath.Child("scaleDownStabilizationSeconds"), *spec.ScaleDownStabilizationSeconds, "must not be negative"))
+	}
+	if spec.EvictionTTLSeconds != nil && *spec.EvictionTTLSeconds < 0 {
+		errs = append(errs, field.Invalid(specPath.Child("evictionTTLSeconds"), *spec.EvictionTTLSeconds, "must not be negative"))
+	}
+	if spec.SurgeScheduleTimeoutSeconds != nil && *spec.SurgeScheduleTimeoutSeconds < 0 {
+		errs = append(errs, field.Invalid(specPath.Child("surgeScheduleTimeoutSeconds"), *spec.SurgeScheduleTimeoutSeconds, "must not be negative"))
+	}
+	if spec.MinReplicas != nil && spec.MaxReplicas != nil && *spec.MaxReplicas < *spec.MinReplicas {
+		errs = append(errs, field.Invalid(specPath.Child("maxReplicas"), *spec.MaxReplicas,
+			fmt.Sprintf("must not be lower than spec.minReplicas %d", *spec.MinReplicas)))
+	}
+	if surge := spec.Surge; surge != nil {
+		if step, err := intstr.GetScaledValueFromIntOrPercent(surge, 100, true); err != nil {
+			errs = append(errs, field.Invalid(specPath.Child("surge"), surge.String(), "must be a count or a percentage like 10%"))
+		} else if step < 0 {
+			errs = append(errs, field.Invalid(specPath.Child("surge"), surge.String(), "must not be negative"))
+		}
+	}
+	if evictionTime := spec.LastEviction.EvictionTime; evictionTime.After(time.Now().Add(maxClockSkew)) {
+		errs = append(errs, field.Invalid(specPath.Child("lastEviction", "evictionTime"), evictionTime.UTC().Format(time.RFC3339), "must not be in the future"))
+	}
+
+	if spec.PodSelector != nil {
+		if _, err := metav1.LabelSelectorAsSelector(spec.PodSelector); err != nil {
+			errs = append(errs, field.Invalid(specPath.Child("podSelector"), spec.PodSelector.String(), err.Error()))
+		}
+	}
+	if ref := spec.TargetRef; ref != nil {
+		refErr, err := v.validateTargetRef(ref, specPath.Child("targetRef"))
+		if err != nil {
+			return nil, err
+		}
+		if refErr != nil {
+			errs = append(errs, refErr)
+		}
+	}
+
+	dupErr, err := v.validateUniquePDB(ctx, EvictionAutoScaler)
+	if err != nil {
+		return nil, err
+	}
+	if dupErr != nil {
+		errs = append(errs, dupErr)
+	}
+	return errs, nil
+}
+
+// validateTargetRef rejects a targetRef kind we know about that has no scale subresource.
+// Unknown kinds are let through since their CRD may just not be installed yet.
+func (v *EvictionAutoScalerValidator) validateTargetRef(ref *pdbautoscaler.TargetReference, path *field.Path) (*field.Error, error) {
+	gv, err := schema.ParseGroupVersion(ref.APIVersion)
+	if err != nil {
+		return field.Invalid(path.Child("apiVersion"), ref.APIVersion, err.Error()), nil
+	}
+	if v.Discovery == nil {
+		return nil, nil
+	}
+	mapping, err := v.Client.RESTMapper().RESTMapping(schema.GroupKind{Group: gv.Group, Kind: ref.Kind}, gv.Version)
+	if err != nil {
+		if meta.IsNoMatchError(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	resources, err := v.Discovery.ServerResourcesForGroupVersion(mapping.Resource.GroupVersion().String())
+	if err != nil {
+		return nil, err
+	}
+	for _, resource := range resources.APIResources {
+		if resource.Name == mapping.Resource.Resource+"/scale" {
+			return nil, nil
+		}
+	}
+	return field.Invalid(path.Child("kind"), ref.Kind, fmt.Sprintf("%s does not expose the scale subresource", mapping.Resource.GroupResource())), nil
+}
+
+// validateUniquePDB rejects an EvictionAutoScaler pointing at the same pdb as another one, or at a pdb selecting the
+// same pods as another EvictionAutoScaler's pdb. The node controller and eviction webhook only act on the oldest match
+// so the newer one would silently do nothing. spec.podSelector ones have no pdb and are allowed to overlap,
+// pods they share with a pdb are left to the pdb's EvictionAutoScaler.
+func (v *EvictionAutoScalerValidator) validateUniquePDB(ctx context.Context, EvictionAutoScaler *pdbautoscaler.EvictionAutoScaler) (*field.Error, error) {
+	if EvictionAutoScaler.Spec.PodSelector != nil {
+		return nil, nil
+	}
+	EvictionAutoScalerList := &pdbautoscaler.EvictionAutoScalerList{}
+	if err := v.Client.List(ctx, EvictionAutoScalerList, &client.ListOptions{Namespace: EvictionAutoScaler.Namespace}); err != nil {
+		return nil, err
+	}
+	EvictionAutoScalerList.Items = slices.DeleteFunc(EvictionAutoScalerList.Items, func(other pdbautoscaler.EvictionAutoScaler) bool {
+		return other.Name == EvictionAutoScaler.Name || other.Spec.PodSelector != nil
+	})
+	for _, other := range EvictionAutoScalerList.Items {
+		if other.PDBName() == EvictionAutoScaler.PDBName() {
+			return field.Duplicate(pdbNamePath(EvictionAutoScaler), EvictionAutoScaler.PDBName()), nil
+		}
+	}
+
+	selector, err := v.pdbSelector(ctx, types.NamespacedName{Namespace: EvictionAutoScaler.Namespace, Name: EvictionAutoScaler.PDBName()})
+	if err != nil || selector == "" {
+		return nil, err // no pdb yet, the controller reports that
+	}
+	for _, other := range EvictionAutoScalerList.Items {
+		otherSelector, err := v.pdbSelector(ctx, types.NamespacedName{Namespace: other.Namespace, Name: other.PDBName()})
+		if err != nil {
+			return nil, err
+		}
+		if otherSelector == selector {
+			return field.Invalid(pdbNamePath(EvictionAutoScaler), EvictionAutoScaler.PDBName(),
+				fmt.Sprintf("pdb selects the same pods (%s) as EvictionAutoScaler %s's pdb %s", selector, other.Name, other.PDBName())), nil
+		}
+	}
+	return nil, nil
+}
+
+// pdbNamePath is where EvictionAutoScaler's pdb name comes from, for errors about its pdb.
+func pdbNamePath(EvictionAutoScaler *pdbautoscaler.EvictionAutoScaler) *field.Path {
+	if EvictionAutoScaler.Spec.TargetPDBName != "" {
+		return field.NewPath("spec", "targetPDBName")
+	}
+	return field.NewPath("metadata", "name")
+}
+
+// pdbSelector returns the canonical selector of the pdb named key, empty if there is none.
+func (v *EvictionAutoScalerValidator) pdbSelector(ctx context.Context, key types.NamespacedName) (string, error) {
+	pdb := &policyv1.PodDisruptionBudget{}
+	if err := v.Client.Get(ctx, key, pdb); err != nil {
+		if apierrors.IsNotFound(err) {
+			return "", nil
+		}
+		return "", err
+	}
+	selector, err := metav1.LabelSelectorAsSelector(pdb.Spec.Selector)
+	if err != nil {
+		return "", nil // invalid selectors never match anything
+	}
+	return selector.String(), nil
+}
+
+// EvictionAutoScalerDefaulter writes the defaults the controller assumes into new EvictionAutoScalers so they show up in the spec.
+// The cooldown, surge and surge policy are left unset so the controller resolves them from its current config.
+type EvictionAutoScalerDefaulter struct{}
+
+func (d *EvictionAutoScalerDefaulter) Handle(ctx context.Context, req admission.Request) admission.Response {
+	if req.Operation != admissionv1.Create {
+		return admission.Allowed("")
+	}
+
+	EvictionAutoScaler := &pdbautoscaler.EvictionAutoScaler{}
+	if err := json.Unmarshal(req.Object.Raw, EvictionAutoScaler); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+	EvictionAutoScaler.SetStaticDefaults()
+
+	defaulted, err := json.Marshal(EvictionAutoScaler)
+	if err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+	return admission.PatchResponseFromRaw(req.Object.Raw, defaulted)
+}
@@ -0,0 +1,158 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	pdbautoscaler "github.com/azure/eviction-autoscaler/api/v1"
+	admissionv1 "k8s.io/api/admission/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	discoveryfake "k8s.io/client-go/discovery/fake"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	clienttesting "k8s.io/client-go/testing"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+func TestEvictionAutoScalerValidator(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = pdbautoscaler.AddToScheme(scheme)
+
+	pdb := func(name string, app string) *policyv1.PodDisruptionBudget {
+		return &policyv1.PodDisruptionBudget{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+			Spec:       policyv1.PodDisruptionBudgetSpec{Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": app}}},
+		}
+	}
+	existing := &pdbautoscaler.EvictionAutoScaler{ObjectMeta: metav1.ObjectMeta{Name: "existing", Namespace: "default"}}
+	mapper := meta.NewDefaultRESTMapper(nil)
+	mapper.Add(appsv1.SchemeGroupVersion.WithKind("Deployment"), meta.RESTScopeNamespace)
+	mapper.Add(appsv1.SchemeGroupVersion.WithKind("DaemonSet"), meta.RESTScopeNamespace)
+	c := fake.NewClientBuilder().WithScheme(scheme).WithRESTMapper(mapper).WithObjects(pdb("existing", "web"), pdb("dup", "web"), pdb("other", "db"), existing).Build()
+	discovery := &discoveryfake.FakeDiscovery{Fake: &clienttesting.Fake{Resources: []*metav1.APIResourceList{{
+		GroupVersion: "apps/v1",
+		APIResources: []metav1.APIResource{{Name: "deployments"}, {Name: "deployments/scale"}, {Name: "daemonsets"}},
+	}}}}
+	validator := &EvictionAutoScalerValidator{Client: c, Discovery: discovery}
+
+	int32Ptr := func(i int32) *int32 { return &i }
+	intOrStringPtr := func(i intstr.IntOrString) *intstr.IntOrString { return &i }
+	tests := []struct {
+		name  string
+		spec  pdbautoscaler.EvictionAutoScalerSpec
+		field string // empty means allowed
+	}{
+		{name: "other", spec: pdbautoscaler.EvictionAutoScalerSpec{TargetName: "db", TargetKind: "deployment"}},
+		{name: "other", spec: pdbautoscaler.EvictionAutoScalerSpec{CooldownSeconds: int32Ptr(-1)}, field: "spec.cooldownSeconds"},
+		{name: "other", spec: pdbautoscaler.EvictionAutoScalerSpec{ScaleDownStabilizationSeconds: int32Ptr(-1)}, field: "spec.scaleDownStabilizationSeconds"},
+		{name: "other", spec: pdbautoscaler.EvictionAutoScalerSpec{EvictionTTLSeconds: int32Ptr(-1)}, field: "spec.evictionTTLSeconds"},
+		{name: "other", spec: pdbautoscaler.EvictionAutoScalerSpec{SurgeScheduleTimeoutSeconds: int32Ptr(-1)}, field: "spec.surgeScheduleTimeoutSeconds"},
+		{name: "other", spec: pdbautoscaler.EvictionAutoScalerSpec{MinReplicas: int32Ptr(3), MaxReplicas: int32Ptr(2)}, field: "spec.maxReplicas"},
+		{name: "other", spec: pdbautoscaler.EvictionAutoScalerSpec{Surge: intOrStringPtr(intstr.FromString("10%"))}},
+		{name: "other", spec: pdbautoscaler.EvictionAutoScalerSpec{Surge: intOrStringPtr(intstr.FromString("ten"))}, field: "spec.surge"},
+		{name: "other", spec: pdbautoscaler.EvictionAutoScalerSpec{Surge: intOrStringPtr(intstr.FromInt32(-2))}, field: "spec.surge"},
+		{name: "other", spec: pdbautoscaler.EvictionAutoScalerSpec{
+			LastEviction: pdbautoscaler.Eviction{EvictionTime: metav1.NewTime(time.Now().Add(time.Hour))}}, field: "spec.lastEviction.evictionTime"},
+		{name: "other", spec: pdbautoscaler.EvictionAutoScalerSpec{
+			TargetRef: &pdbautoscaler.TargetReference{APIVersion: "apps/v1", Kind: "Deployment", Name: "db"}}},
+		{name: "other", spec: pdbautoscaler.EvictionAutoScalerSpec{
+			TargetRef: &pdbautoscaler.TargetReference{APIVersion: "apps/v1", Kind: "DaemonSet", Name: "db"}}, field: "spec.targetRef.kind"},
+		{name: "other", spec: pdbautoscaler.EvictionAutoScalerSpec{
+			TargetRef: &pdbautoscaler.TargetReference{APIVersion: "example.com/v1", Kind: "NotInstalledYet", Name: "db"}}},
+		{name: "dup", spec: pdbautoscaler.EvictionAutoScalerSpec{TargetName: "web", TargetKind: "deployment"}, field: "metadata.name"},
+		{name: "existing", spec: pdbautoscaler.EvictionAutoScalerSpec{TargetName: "web", TargetKind: "deployment"}},
+		{name: "chart", spec: pdbautoscaler.EvictionAutoScalerSpec{TargetPDBName: "other"}},
+		{name: "chart", spec: pdbautoscaler.EvictionAutoScalerSpec{TargetPDBName: "existing"}, field: "spec.targetPDBName"},
+		{name: "chart", spec: pdbautoscaler.EvictionAutoScalerSpec{TargetPDBName: "dup"}, field: "spec.targetPDBName"},
+		{name: "dup", spec: pdbautoscaler.EvictionAutoScalerSpec{PodSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}}}},
+		{name: "other", spec: pdbautoscaler.EvictionAutoScalerSpec{PodSelector: &metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{
+			{Key: "app", Operator: "Near"}}}}, field: "spec.podSelector"},
+		{name: "nopdb", spec: pdbautoscaler.EvictionAutoScalerSpec{TargetName: "web", TargetKind: "deployment"}},
+	}
+	for _, test := range tests {
+		raw, err := json.Marshal(&pdbautoscaler.EvictionAutoScaler{
+			ObjectMeta: metav1.ObjectMeta{Name: test.name, Namespace: "default"},
+			Spec:       test.spec,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp := validator.Handle(context.Background(), admission.Request{AdmissionRequest: admissionv1.AdmissionRequest{
+			Operation: admissionv1.Create,
+			Namespace: "default",
+			Name:      test.name,
+			Object:    runtime.RawExtension{Raw: raw},
+		}})
+		if test.field == "" {
+			if !resp.Allowed {
+				t.Errorf("%s %+v: got denied %s", test.name, test.spec, resp.Result.Message)
+			}
+			continue
+		}
+		if resp.Allowed {
+			t.Errorf("%s %+v: got allowed want %s rejected", test.name, test.spec, test.field)
+		} else if !strings.Contains(resp.Result.Message, test.field) {
+			t.Errorf("%s %+v: got %q want it to name %s", test.name, test.spec, resp.Result.Message, test.field)
+		}
+	}
+}
+
+func TestEvictionAutoScalerDefaulter(t *testing.T) {
+	defaulter := &EvictionAutoScalerDefaulter{}
+	int32Ptr := func(i int32) *int32 { return &i }
+	tests := []struct {
+		spec    pdbautoscaler.EvictionAutoScalerSpec
+		patched []string
+	}{
+		// the cooldown, surge and surge policy come from the controller's config when it reconciles
+		{spec: pdbautoscaler.EvictionAutoScalerSpec{},
+			patched: []string{"/spec/hpaPolicy", "/spec/pausedPolicy", "/spec/scaleDownPolicy", "/spec/strategy", "/spec/targetPDBName"}}, // the target is discovered
+		{spec: pdbautoscaler.EvictionAutoScalerSpec{TargetName: "web", TargetKind: "statefulset", CooldownSeconds: int32Ptr(30)},
+			patched: []string{"/spec/hpaPolicy", "/spec/pausedPolicy", "/spec/scaleDownPolicy", "/spec/strategy", "/spec/targetPDBName"}},
+		{spec: pdbautoscaler.EvictionAutoScalerSpec{TargetRef: &pdbautoscaler.TargetReference{APIVersion: "apps/v1", Kind: "Deployment", Name: "web"}},
+			patched: []string{"/spec/hpaPolicy", "/spec/pausedPolicy", "/spec/scaleDownPolicy", "/spec/strategy", "/spec/targetPDBName"}},
+	}
+	for _, test := range tests {
+		raw, err := json.Marshal(&pdbautoscaler.EvictionAutoScaler{
+			ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+			Spec:       test.spec,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp := defaulter.Handle(context.Background(), admission.Request{AdmissionRequest: admissionv1.AdmissionRequest{
+			Operation: admissionv1.Create,
+			Namespace: "default",
+			Name:      "web",
+			Object:    runtime.RawExtension{Raw: raw},
+		}})
+		if !resp.Allowed {
+			t.Fatalf("%+v: got denied %s", test.spec, resp.Result.Message)
+		}
+		var got []string
+		for _, patch := range resp.Patches {
+			got = append(got, patch.Path)
+		}
+		sort.Strings(got) // patch order isn't stable
+		if strings.Join(got, ",") != strings.Join(test.patched, ",") {
+			t.Errorf("%+v: got patches %v want %v", test.spec, resp.Patches, test.patched)
+		}
+	}
+
+	defaulted := &pdbautoscaler.EvictionAutoScaler{ObjectMeta: metav1.ObjectMeta{Name: "web"}}
+	defaulted.SetStaticDefaults()
+	if defaulted.Spec.CooldownSeconds != nil || defaulted.Spec.Surge != nil || defaulted.Spec.SurgePolicy != "" || defaulted.Spec.TargetName != "" ||
+		defaulted.Spec.TargetKind != "" || defaulted.Spec.HPAPolicy != pdbautoscaler.HPAPolicySkip || defaulted.Spec.TargetPDBName != "web" {
+		t.Errorf("got defaults %+v", defaulted.Spec)
+	}
+}
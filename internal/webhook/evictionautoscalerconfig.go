@@ -0,0 +1,85 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	pdbautoscaler "github.com/azure/eviction-autoscaler/api/v1"
+	admissionv1 "k8s.io/api/admission/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// EvictionAutoScalerConfigValidator rejects EvictionAutoScalerConfigs the controllers would ignore or couldn't apply,
+// since a bad one changes how every EvictionAutoScaler behaves at once.
+type EvictionAutoScalerConfigValidator struct{}
+
+func (v *EvictionAutoScalerConfigValidator) Handle(ctx context.Context, req admission.Request) admission.Response {
+	if req.Operation != admissionv1.Create && req.Operation != admissionv1.Update {
+		return admission.Allowed("")
+	}
+
+	config := &pdbautoscaler.EvictionAutoScalerConfig{}
+	if err := json.Unmarshal(req.Object.Raw, config); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+	if errs := validateConfig(config); len(errs) > 0 {
+		log.FromContext(ctx).Info("Rejected EvictionAutoScalerConfig", "name", req.Name, "errors", errs)
+		return admission.Denied(errs.ToAggregate().Error())
+	}
+	return admission.Allowed("")
+}
+
+// validateConfig returns what is wrong with config.
+func validateConfig(config *pdbautoscaler.EvictionAutoScalerConfig) field.ErrorList {
+	var errs field.ErrorList
+	spec := config.Spec
+	specPath := field.NewPath("spec")
+
+	if config.Name != pdbautoscaler.EvictionAutoScalerConfigName {
+		errs = append(errs, field.Invalid(field.NewPath("metadata", "name"), config.Name,
+			"only the EvictionAutoScalerConfig named "+pdbautoscaler.EvictionAutoScalerConfigName+" is read"))
+	}
+	if spec.CooldownSeconds != nil && *spec.CooldownSeconds < 0 {
+		errs = append(errs, field.Invalid(specPath.Child("cooldownSeconds"), *spec.CooldownSeconds, "must not be negative"))
+	}
+	if surge := spec.Surge; surge != nil {
+		if step, err := intstr.GetScaledValueFromIntOrPercent(surge, 100, true); err != nil {
+			errs = append(errs, field.Invalid(specPath.Child("surge"), surge.String(), "must be a count or a percentage like 10%"))
+		} else if step < 0 {
+			errs = append(errs, field.Invalid(specPath.Child("surge"), surge.String(), "must not be negative"))
+		}
+	}
+	switch spec.SurgePolicy {
+	case "", pdbautoscaler.SurgePolicyStep, pdbautoscaler.SurgePolicyPDBGap:
+	default:
+		errs = append(errs, field.NotSupported(specPath.Child("surgePolicy"), spec.SurgePolicy,
+			[]string{pdbautoscaler.SurgePolicyStep, pdbautoscaler.SurgePolicyPDBGap}))
+	}
+	for i, namespace := range spec.NamespaceAllowlist {
+		errs = append(errs, validateNamespace(specPath.Child("namespaceAllowlist").Index(i), namespace)...)
+	}
+	for i, namespace := range spec.NamespaceDenylist {
+		errs = append(errs, validateNamespace(specPath.Child("namespaceDenylist").Index(i), namespace)...)
+	}
+	if spec.MaxConcurrentNodeDrains != nil && *spec.MaxConcurrentNodeDrains < 0 {
+		errs = append(errs, field.Invalid(specPath.Child("maxConcurrentNodeDrains"), *spec.MaxConcurrentNodeDrains, "must not be negative"))
+	}
+	if spec.NodePodConcurrency != nil && *spec.NodePodConcurrency < 1 {
+		errs = append(errs, field.Invalid(specPath.Child("nodePodConcurrency"), *spec.NodePodConcurrency, "must be at least 1"))
+	}
+	return errs
+}
+
+// validateNamespace rejects a namespace list entry that can't be a namespace name, most likely a typo.
+func validateNamespace(path *field.Path, namespace string) field.ErrorList {
+	var errs field.ErrorList
+	for _, msg := range validation.IsDNS1123Label(namespace) {
+		errs = append(errs, field.Invalid(path, namespace, msg))
+	}
+	return errs
+}
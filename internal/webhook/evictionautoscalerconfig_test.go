@@ -0,0 +1,59 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	pdbautoscaler "github.com/azure/eviction-autoscaler/api/v1"
+	admissionv1 "k8s.io/api/admission/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+func TestEvictionAutoScalerConfigValidator(t *testing.T) {
+	validator := &EvictionAutoScalerConfigValidator{}
+	tests := []struct {
+		name  string
+		spec  pdbautoscaler.EvictionAutoScalerConfigSpec
+		field string // empty means allowed
+	}{
+		{name: "default", spec: pdbautoscaler.EvictionAutoScalerConfigSpec{CooldownSeconds: ptr.To(int32(300)), Surge: ptr.To(intstr.FromString("10%")),
+			SurgePolicy: pdbautoscaler.SurgePolicyPDBGap, NamespaceDenylist: []string{"kube-system"}, MaxConcurrentNodeDrains: ptr.To(int32(0))}},
+		{name: "other", field: "metadata.name"},
+		{name: "default", spec: pdbautoscaler.EvictionAutoScalerConfigSpec{CooldownSeconds: ptr.To(int32(-1))}, field: "spec.cooldownSeconds"},
+		{name: "default", spec: pdbautoscaler.EvictionAutoScalerConfigSpec{Surge: ptr.To(intstr.FromString("ten"))}, field: "spec.surge"},
+		{name: "default", spec: pdbautoscaler.EvictionAutoScalerConfigSpec{Surge: ptr.To(intstr.FromInt32(-2))}, field: "spec.surge"},
+		{name: "default", spec: pdbautoscaler.EvictionAutoScalerConfigSpec{SurgePolicy: "Everything"}, field: "spec.surgePolicy"},
+		{name: "default", spec: pdbautoscaler.EvictionAutoScalerConfigSpec{NamespaceAllowlist: []string{"tenant-a", "Tenant_B"}}, field: "spec.namespaceAllowlist[1]"},
+		{name: "default", spec: pdbautoscaler.EvictionAutoScalerConfigSpec{NamespaceDenylist: []string{""}}, field: "spec.namespaceDenylist[0]"},
+		{name: "default", spec: pdbautoscaler.EvictionAutoScalerConfigSpec{MaxConcurrentNodeDrains: ptr.To(int32(-1))}, field: "spec.maxConcurrentNodeDrains"},
+		{name: "default", spec: pdbautoscaler.EvictionAutoScalerConfigSpec{NodePodConcurrency: ptr.To(int32(0))}, field: "spec.nodePodConcurrency"},
+	}
+	for _, test := range tests {
+		raw, err := json.Marshal(&pdbautoscaler.EvictionAutoScalerConfig{ObjectMeta: metav1.ObjectMeta{Name: test.name}, Spec: test.spec})
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp := validator.Handle(context.Background(), admission.Request{AdmissionRequest: admissionv1.AdmissionRequest{
+			Operation: admissionv1.Update,
+			Name:      test.name,
+			Object:    runtime.RawExtension{Raw: raw},
+		}})
+		if test.field == "" {
+			if !resp.Allowed {
+				t.Errorf("%s %+v: got denied %s", test.name, test.spec, resp.Result.Message)
+			}
+			continue
+		}
+		if resp.Allowed {
+			t.Errorf("%s %+v: got allowed want %s rejected", test.name, test.spec, test.field)
+		} else if !strings.Contains(resp.Result.Message, test.field) {
+			t.Errorf("%s %+v: got %q want it to name %s", test.name, test.spec, resp.Result.Message, test.field)
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	pdbautoscaler "github.com/azure/eviction-autoscaler/api/v1"
+	controllers "github.com/azure/eviction-autoscaler/internal/controller"
+	"github.com/azure/eviction-autoscaler/internal/metrics"
+	"github.com/azure/eviction-autoscaler/internal/namespacefilter"
+	admissionv1 "k8s.io/api/admission/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// Constants for the reason label of PDBWarningCounter
+const (
+	PDBWarningSurgeNeeded   = "surge_needed"
+	PDBWarningUnsatisfiable = "unsatisfiable"
+)
+
+// PDBValidator warns on pdb creates and updates that allow no disruption with the replicas of the workloads they
+// select, like minAvailable: 100% or minAvailable: 1 of a single replica, since every drain of their nodes hangs on
+// them. It never rejects a pdb, and pdbs selecting no Deployment or StatefulSet yet get no warning.
+type PDBValidator struct {
+	Client client.Client
+	// Namespaces excludes pdbs in namespaces we must not touch. Nil allows all.
+	Namespaces *namespacefilter.Filter
+	// AutoCreate is --auto-create-evictionautoscalers, which creates the EvictionAutoScaler a surge needs for
+	// pdbs not opted out, so only the pdbs a surge can't help are warned about.
+	AutoCreate bool
+}
+
+func (v *PDBValidator) Handle(ctx context.Context, req admission.Request) admission.Response {
+	logger := log.FromContext(ctx)
+	if req.Operation != admissionv1.Create && req.Operation != admissionv1.Update {
+		return admission.Allowed("")
+	}
+	if v.Namespaces.Skip(ctx, req.Namespace, "pdb-webhook") {
+		return admission.Allowed("namespace excluded")
+	}
+	pdb := &policyv1.PodDisruptionBudget{}
+	if err := json.Unmarshal(req.Object.Raw, pdb); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+	if pdb.Namespace == "" {
+		pdb.Namespace = req.Namespace
+	}
+
+	// a warning is only advice so failing to work it out never fails the pdb write
+	warning, reason, err := v.warning(ctx, pdb)
+	if err != nil {
+		logger.Error(err, "Unable to check if pdb allows disruptions", "namespace", pdb.Namespace, "name", pdb.Name)
+		return admission.Allowed("unable to list workloads")
+	}
+	if warning == "" {
+		return admission.Allowed("")
+	}
+	logger.Info("Warning about pdb allowing no disruptions", "namespace", pdb.Namespace, "name", pdb.Name, "warning", warning)
+	metrics.PDBWarningCounter.WithLabelValues(pdb.Namespace, reason).Inc()
+	return admission.Allowed("").WithWarnings(warning)
+}
+
+// warning says what is wrong with pdb, with the reason label it is counted under, or nothing if it allows a
+// disruption with the replicas of the workloads it selects or selects none.
+func (v *PDBValidator) warning(ctx context.Context, pdb *policyv1.PodDisruptionBudget) (string, string, error) {
+	workloads, replicas, err := v.selectedReplicas(ctx, pdb)
+	if err != nil || replicas == 0 {
+		return "", "", err
+	}
+	surge, blocked, ok := controllers.SurgeToUnblock(pdb, replicas)
+	if !blocked {
+		return "", "", nil
+	}
+	selected := strings.Join(workloads, ", ")
+	if !ok {
+		return fmt.Sprintf("pdb %s allows no disruption with any number of replicas of %s, so every drain of their nodes will hang "+
+			"till it is relaxed, for example with a maxUnavailable of at least 1", pdb.Name, selected), PDBWarningUnsatisfiable, nil
+	}
+	if _, optedOut := controllers.AutoCreateDisabled(pdb); v.AutoCreate && !optedOut {
+		return "", "", nil
+	}
+	covered, err := v.hasEvictionAutoScaler(ctx, pdb)
+	if err != nil || covered {
+		return "", "", err
+	}
+	return fmt.Sprintf("pdb %s allows no disruption with the %d replicas of %s, so every drain of their nodes will hang. "+
+			"An EvictionAutoScaler named %s would surge them by %d during a drain to unblock it", pdb.Name, replicas, selected, pdb.Name, surge),
+		PDBWarningSurgeNeeded, nil
+}
+
+// selectedReplicas returns the kind/name of the Deployments and StatefulSets whose pod template pdb's selector matches,
+// and their replicas added up since the pdb counts all their pods.
+func (v *PDBValidator) selectedReplicas(ctx context.Context, pdb *policyv1.PodDisruptionBudget) ([]string, int32, error) {
+	selector, err := metav1.LabelSelectorAsSelector(pdb.Spec.Selector)
+	if err != nil {
+		return nil, 0, nil // invalid selectors never match anything
+	}
+	var workloads []string
+	var replicas int32
+	deployments := &appsv1.DeploymentList{}
+	if err := v.Client.List(ctx, deployments, client.InNamespace(pdb.Namespace)); err != nil {
+		return nil, 0, err
+	}
+	for _, deployment := range deployments.Items {
+		if selector.Matches(labels.Set(deployment.Spec.Template.Labels)) {
+			workloads = append(workloads, "deployment/"+deployment.Name)
+			replicas += specReplicas(deployment.Spec.Replicas)
+		}
+	}
+	statefulSets := &appsv1.StatefulSetList{}
+	if err := v.Client.List(ctx, statefulSets, client.InNamespace(pdb.Namespace)); err != nil {
+		return nil, 0, err
+	}
+	for _, statefulSet := range statefulSets.Items {
+		if selector.Matches(labels.Set(statefulSet.Spec.Template.Labels)) {
+			workloads = append(workloads, "statefulset/"+statefulSet.Name)
+			replicas += specReplicas(statefulSet.Spec.Replicas)
+		}
+	}
+	return workloads, replicas, nil
+}
+
+// hasEvictionAutoScaler reports if an EvictionAutoScaler already surges for pdb.
+func (v *PDBValidator) hasEvictionAutoScaler(ctx context.Context, pdb *policyv1.PodDisruptionBudget) (bool, error) {
+	list := &pdbautoscaler.EvictionAutoScalerList{}
+	if err := v.Client.List(ctx, list, client.InNamespace(pdb.Namespace)); err != nil {
+		return false, err
+	}
+	for i := range list.Items {
+		if list.Items[i].PDBName() == pdb.Name {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// specReplicas is a workload's spec.replicas, defaulted to 1 like the api server does.
+func specReplicas(replicas *int32) int32 {
+	if replicas == nil {
+		return 1
+	}
+	return *replicas
+}
@@ -0,0 +1,94 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	pdbautoscaler "github.com/azure/eviction-autoscaler/api/v1"
+	controllers "github.com/azure/eviction-autoscaler/internal/controller"
+	admissionv1 "k8s.io/api/admission/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+func TestPDBValidator(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = pdbautoscaler.AddToScheme(scheme)
+
+	deployment := func(name string, replicas int32) *appsv1.Deployment {
+		return &appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+			Spec: appsv1.DeploymentSpec{
+				Replicas: ptr.To(replicas),
+				Template: corev1.PodTemplateSpec{ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"app": name}}},
+			},
+		}
+	}
+	covered := &pdbautoscaler.EvictionAutoScaler{ObjectMeta: metav1.ObjectMeta{Name: "covered", Namespace: "default"}}
+	c := fake.NewClientBuilder().WithScheme(scheme).
+		WithObjects(deployment("single", 1), deployment("web", 3), deployment("covered", 1), covered).Build()
+
+	minAvailable := func(value intstr.IntOrString) policyv1.PodDisruptionBudgetSpec {
+		return policyv1.PodDisruptionBudgetSpec{MinAvailable: &value}
+	}
+	tests := []struct {
+		name       string
+		app        string
+		spec       policyv1.PodDisruptionBudgetSpec
+		autoCreate bool
+		optOut     bool
+		warning    string
+	}{
+		{name: "minAvailable 100%", app: "web", spec: minAvailable(intstr.FromString("100%")), warning: "any number of replicas"},
+		{name: "maxUnavailable 0", app: "web", spec: policyv1.PodDisruptionBudgetSpec{MaxUnavailable: ptr.To(intstr.FromInt32(0))},
+			warning: "any number of replicas"},
+		{name: "minAvailable 1 of one replica", app: "single", spec: minAvailable(intstr.FromInt32(1)), warning: "surge them by 1"},
+		{name: "minAvailable 1 of three replicas", app: "web", spec: minAvailable(intstr.FromInt32(1))},
+		{name: "already has an EvictionAutoScaler", app: "covered", spec: minAvailable(intstr.FromInt32(1))},
+		{name: "auto created", app: "single", spec: minAvailable(intstr.FromInt32(1)), autoCreate: true},
+		{name: "opted out of auto create", app: "single", spec: minAvailable(intstr.FromInt32(1)), autoCreate: true, optOut: true,
+			warning: "surge them by 1"},
+		// pdbs are often created before their workload so it is too soon to tell
+		{name: "no matching workload", app: "missing", spec: minAvailable(intstr.FromString("100%"))},
+	}
+	for _, test := range tests {
+		pdb := &policyv1.PodDisruptionBudget{
+			ObjectMeta: metav1.ObjectMeta{Name: test.app},
+			Spec:       test.spec,
+		}
+		pdb.Spec.Selector = &metav1.LabelSelector{MatchLabels: map[string]string{"app": test.app}}
+		if test.optOut {
+			pdb.Annotations = map[string]string{controllers.OptOutAnnotationKey: "true"}
+		}
+		raw, err := json.Marshal(pdb)
+		if err != nil {
+			t.Fatal(err)
+		}
+		validator := &PDBValidator{Client: c, AutoCreate: test.autoCreate}
+		resp := validator.Handle(context.Background(), admission.Request{AdmissionRequest: admissionv1.AdmissionRequest{
+			Operation: admissionv1.Create,
+			Namespace: "default",
+			Object:    runtime.RawExtension{Raw: raw},
+		}})
+		if !resp.Allowed {
+			t.Fatalf("%s: got denied %s, want only warnings", test.name, resp.Result.Message)
+		}
+		switch {
+		case test.warning == "" && len(resp.Warnings) > 0:
+			t.Errorf("%s: got warnings %v, want none", test.name, resp.Warnings)
+		case test.warning != "" && (len(resp.Warnings) != 1 || !strings.Contains(resp.Warnings[0], test.warning)):
+			t.Errorf("%s: got warnings %v, want one containing %q", test.name, resp.Warnings, test.warning)
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"slices"
+
+	controllers "github.com/azure/eviction-autoscaler/internal/controller"
+	"github.com/azure/eviction-autoscaler/internal/metrics"
+	"github.com/azure/eviction-autoscaler/internal/namespacefilter"
+	"github.com/azure/eviction-autoscaler/internal/selectorcache"
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// SurgeAffinityAnnotationKey is set on pods given node affinity away from draining nodes, to the name of the
+// EvictionAutoScaler whose surge they were created during. It identifies the injected term, whose only match
+// field is metadata.name NotIn those nodes.
+const SurgeAffinityAnnotationKey = "eviction-autoscaler.azure.com/surge-affinity"
+
+// surgeAffinityWeight is the weight of the injected preferred term, the highest so it outweighs spreading.
+const surgeAffinityWeight = 100
+
+// SurgePodAffinityMutator steers pods created while their EvictionAutoScaler is surged away from nodes that are
+// cordoned or carry one of DrainTaints, so in a rolling pool upgrade a surge replica doesn't land on the next node
+// to drain and need surging for again. Pods outside a surge are left alone.
+type SurgePodAffinityMutator struct {
+	Client client.Client
+	// Namespaces excludes pods in namespaces we must not touch. Nil allows all.
+	Namespaces *namespacefilter.Filter
+	// Selectors caches compiled pdb selectors across pods. Nil compiles them every time.
+	Selectors *selectorcache.Cache
+	// DrainTaints are taint keys treated the same as a cordon, from --drain-taints.
+	DrainTaints []string
+	// Required injects a required term instead of a preferred one. Pods then stay pending while every node is draining.
+	Required bool
+}
+
+func (m *SurgePodAffinityMutator) Handle(ctx context.Context, req admission.Request) admission.Response {
+	logger := log.FromContext(ctx)
+	if req.Operation != admissionv1.Create {
+		return admission.Allowed("")
+	}
+	if m.Namespaces.Skip(ctx, req.Namespace, "surge-affinity") {
+		return admission.Allowed("namespace excluded")
+	}
+	pod := &corev1.Pod{}
+	if err := json.Unmarshal(req.Object.Raw, pod); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+	if pod.Namespace == "" {
+		pod.Namespace = req.Namespace
+	}
+	if pod.Spec.NodeName != "" || pod.Annotations[SurgeAffinityAnnotationKey] != "" {
+		return admission.Allowed("already placed")
+	}
+
+	// never fail the pod create, it just schedules like it would without us
+	EvictionAutoScaler, _, _, err := matchEvictionAutoScaler(ctx, m.Client, m.Selectors, pod)
+	if err != nil {
+		logger.Error(err, "Unable to list EvictionAutoScalers, not steering pod")
+		return admission.Allowed("unable to list EvictionAutoScalers")
+	}
+	if EvictionAutoScaler == nil || EvictionAutoScaler.Spec.Suspend ||
+		!meta.IsStatusConditionTrue(EvictionAutoScaler.Status.Conditions, controllers.ConditionScalingUp) {
+		return admission.Allowed("not surging")
+	}
+	avoid, err := m.drainingNodes(ctx)
+	if err != nil {
+		logger.Error(err, "Unable to list nodes, not steering pod")
+		return admission.Allowed("unable to list nodes")
+	}
+	if len(avoid) == 0 {
+		return admission.Allowed("no draining nodes")
+	}
+
+	avoidDraining(pod, avoid, m.Required)
+	if pod.Annotations == nil {
+		pod.Annotations = map[string]string{}
+	}
+	pod.Annotations[SurgeAffinityAnnotationKey] = EvictionAutoScaler.Name
+	metrics.SurgePodsSteeredCounter.WithLabelValues(pod.Namespace).Inc()
+	logger.V(1).Info("Steering surge pod away from draining nodes", "evictionAutoScaler", EvictionAutoScaler.Name, "nodes", avoid, "required", m.Required)
+
+	mutated, err := json.Marshal(pod)
+	if err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+	return admission.PatchResponseFromRaw(req.Object.Raw, mutated)
+}
+
+// drainingNodes returns the sorted names of the nodes that are cordoned or carry one of DrainTaints.
+func (m *SurgePodAffinityMutator) drainingNodes(ctx context.Context) ([]string, error) {
+	nodes := &corev1.NodeList{}
+	if err := m.Client.List(ctx, nodes); err != nil {
+		return nil, err
+	}
+	var draining []string
+	for i := range nodes.Items {
+		node := &nodes.Items[i]
+		if node.Spec.Unschedulable || slices.ContainsFunc(node.Spec.Taints, func(taint corev1.Taint) bool {
+			return slices.Contains(m.DrainTaints, taint.Key)
+		}) {
+			draining = append(draining, node.Name)
+		}
+	}
+	slices.Sort(draining)
+	return draining, nil
+}
+
+// avoidDraining adds node affinity keeping pod off the nodes in avoid. A preferred term is its own, a required one
+// has to be added to every existing term since they are ORed.
+func avoidDraining(pod *corev1.Pod, avoid []string, required bool) {
+	requirement := corev1.NodeSelectorRequirement{Key: "metadata.name", Operator: corev1.NodeSelectorOpNotIn, Values: avoid}
+	if pod.Spec.Affinity == nil {
+		pod.Spec.Affinity = &corev1.Affinity{}
+	}
+	if pod.Spec.Affinity.NodeAffinity == nil {
+		pod.Spec.Affinity.NodeAffinity = &corev1.NodeAffinity{}
+	}
+	nodeAffinity := pod.Spec.Affinity.NodeAffinity
+	if !required {
+		nodeAffinity.PreferredDuringSchedulingIgnoredDuringExecution = append(nodeAffinity.PreferredDuringSchedulingIgnoredDuringExecution,
+			corev1.PreferredSchedulingTerm{Weight: surgeAffinityWeight, Preference: corev1.NodeSelectorTerm{MatchFields: []corev1.NodeSelectorRequirement{requirement}}})
+		return
+	}
+	if nodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution == nil {
+		nodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution = &corev1.NodeSelector{}
+	}
+	terms := &nodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms
+	if len(*terms) == 0 {
+		*terms = append(*terms, corev1.NodeSelectorTerm{})
+	}
+	for i := range *terms {
+		(*terms)[i].MatchFields = append((*terms)[i].MatchFields, requirement)
+	}
+}
@@ -0,0 +1,109 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	pdbautoscaler "github.com/azure/eviction-autoscaler/api/v1"
+	controllers "github.com/azure/eviction-autoscaler/internal/controller"
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+func TestSurgePodAffinityMutator(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = pdbautoscaler.AddToScheme(scheme)
+
+	pdb := func(name, app string) *policyv1.PodDisruptionBudget {
+		return &policyv1.PodDisruptionBudget{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+			Spec:       policyv1.PodDisruptionBudgetSpec{Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": app}}},
+		}
+	}
+	surging := &pdbautoscaler.EvictionAutoScaler{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+		Status: pdbautoscaler.EvictionAutoScalerStatus{Conditions: []metav1.Condition{
+			{Type: controllers.ConditionScalingUp, Status: metav1.ConditionTrue, Reason: "Surged"},
+		}},
+	}
+	idle := &pdbautoscaler.EvictionAutoScaler{ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "default"}}
+	nodes := []*corev1.Node{
+		{ObjectMeta: metav1.ObjectMeta{Name: "cordoned"}, Spec: corev1.NodeSpec{Unschedulable: true}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "tainted"}, Spec: corev1.NodeSpec{Taints: []corev1.Taint{{Key: "karpenter.sh/disrupted", Effect: corev1.TaintEffectNoSchedule}}}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "healthy"}},
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).
+		WithObjects(pdb("web", "web"), pdb("db", "db"), surging, idle, nodes[0], nodes[1], nodes[2]).Build()
+
+	tests := []struct {
+		name     string
+		app      string
+		required bool
+		steered  bool
+	}{
+		{name: "surging, preferred", app: "web", steered: true},
+		{name: "surging, required", app: "web", required: true, steered: true},
+		{name: "not surging", app: "db"},
+		{name: "no EvictionAutoScaler", app: "other"},
+	}
+	for _, test := range tests {
+		mutator := &SurgePodAffinityMutator{Client: c, DrainTaints: controllers.DefaultDrainTaints, Required: test.required}
+		raw, err := json.Marshal(&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "web-1", Labels: map[string]string{"app": test.app}}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp := mutator.Handle(context.Background(), admission.Request{AdmissionRequest: admissionv1.AdmissionRequest{
+			Operation: admissionv1.Create,
+			Namespace: "default",
+			Object:    runtime.RawExtension{Raw: raw},
+		}})
+		if !resp.Allowed {
+			t.Fatalf("%s: got denied %s", test.name, resp.Result.Message)
+		}
+		var affinity, annotated bool
+		for _, patch := range resp.Patches {
+			affinity = affinity || patch.Path == "/spec/affinity"
+			annotated = annotated || patch.Path == "/metadata/annotations"
+		}
+		if affinity != test.steered || annotated != test.steered {
+			t.Errorf("%s: got patches %v, want affinity and annotation %v", test.name, resp.Patches, test.steered)
+		}
+	}
+}
+
+func TestAvoidDraining(t *testing.T) {
+	avoid := []string{"cordoned", "tainted"}
+	pod := &corev1.Pod{}
+	avoidDraining(pod, avoid, false)
+	preferred := pod.Spec.Affinity.NodeAffinity.PreferredDuringSchedulingIgnoredDuringExecution
+	// preferred so pods still schedule when every node drains
+	if len(preferred) != 1 || preferred[0].Weight != surgeAffinityWeight || pod.Spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution != nil {
+		t.Errorf("got %+v, want one preferred term", pod.Spec.Affinity.NodeAffinity)
+	}
+	if fields := preferred[0].Preference.MatchFields; len(fields) != 1 || fields[0].Key != "metadata.name" ||
+		fields[0].Operator != corev1.NodeSelectorOpNotIn || len(fields[0].Values) != 2 {
+		t.Errorf("got match fields %+v, want metadata.name NotIn %v", fields, avoid)
+	}
+
+	// each of the pod's own required terms keeps its expressions and gets the nodes to avoid
+	pod = &corev1.Pod{Spec: corev1.PodSpec{Affinity: &corev1.Affinity{NodeAffinity: &corev1.NodeAffinity{
+		RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{NodeSelectorTerms: []corev1.NodeSelectorTerm{
+			{MatchExpressions: []corev1.NodeSelectorRequirement{{Key: "zone", Operator: corev1.NodeSelectorOpIn, Values: []string{"a"}}}},
+			{MatchExpressions: []corev1.NodeSelectorRequirement{{Key: "zone", Operator: corev1.NodeSelectorOpIn, Values: []string{"b"}}}},
+		}},
+	}}}}
+	avoidDraining(pod, avoid, true)
+	for i, term := range pod.Spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms {
+		if len(term.MatchExpressions) != 1 || len(term.MatchFields) != 1 {
+			t.Errorf("term %d: got %+v, want its zone expression and the nodes to avoid", i, term)
+		}
+	}
+}